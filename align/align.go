@@ -0,0 +1,298 @@
+/*
+Package align provides pairwise sequence alignment.
+
+Aligning two sequences lines them up so that as many of their characters as
+possible match, inserting gaps ("-") where one sequence has characters the
+other doesn't. How good an alignment is depends on a Scoring: a substitution
+matrix scoring each pair of aligned characters and penalties for opening and
+extending gaps.
+
+NeedlemanWunsch finds the best global alignment, covering both sequences from
+end to end, which is what you want when comparing two versions of the same
+gene or plasmid. SmithWaterman finds the best local alignment, the highest
+scoring pair of regions, which is what you want when looking for a part inside
+a larger sequence. NeedlemanWunschBanded finds global alignments of long,
+nearly identical sequences, like a Sanger read and its plasmid map, in linear
+memory. MSA aligns more than two sequences at once by progressive alignment.
+BuildPair, BuildClustal, BuildStockholm and friends write alignments out in
+the formats other tools read.
+
+All of them use Gotoh's affine gap algorithm, so a gap of length n costs
+GapOpen + (n-1)*GapExtend. This makes a single long gap cheaper than many
+short ones, which is how insertions and deletions actually happen.
+*/
+package align
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Scoring configures how alignments are scored.
+type Scoring struct {
+	Matrix    *SubstitutionMatrix
+	GapOpen   int // penalty, as a positive number, for the first character of a gap.
+	GapExtend int // penalty, as a positive number, for every character of a gap after the first.
+}
+
+// NewNucleotideScoring returns a scoring for DNA and RNA close to the EMBOSS
+// defaults: +5 for a match, -4 for a mismatch and gaps that cost 10 to open
+// and 1 to extend.
+func NewNucleotideScoring() Scoring {
+	return Scoring{Matrix: NewNucleotideMatrix(5, -4), GapOpen: 10, GapExtend: 1}
+}
+
+// NewProteinScoring returns the scoring BLAST uses for proteins by default:
+// BLOSUM62 with gaps that cost 11 to open and 1 to extend.
+func NewProteinScoring() Scoring {
+	return Scoring{Matrix: BLOSUM62, GapOpen: 11, GapExtend: 1}
+}
+
+// Alignment is the result of aligning sequence a to sequence b.
+type Alignment struct {
+	AlignedA string `json:"aligned_a"` // the aligned part of a with gaps written as "-".
+	AlignedB string `json:"aligned_b"` // the aligned part of b with gaps written as "-".
+	Score    int    `json:"score"`
+	// StartA, EndA, StartB and EndB are the 0-based, half-open regions of a
+	// and b that were aligned. Global alignments always cover the whole of
+	// both sequences.
+	StartA int `json:"start_a"`
+	EndA   int `json:"end_a"`
+	StartB int `json:"start_b"`
+	EndB   int `json:"end_b"`
+}
+
+// ErrInvalidScoring is returned when a Scoring has no matrix or has negative gap penalties.
+var ErrInvalidScoring = errors.New("invalid scoring")
+
+// Identity returns the fraction of the alignment's columns that hold identical characters.
+func (alignment Alignment) Identity() float64 {
+	if len(alignment.AlignedA) == 0 {
+		return 0
+	}
+	return float64(alignment.Matches()) / float64(len(alignment.AlignedA))
+}
+
+// Matches returns the number of columns that hold identical characters, ignoring case.
+func (alignment Alignment) Matches() int {
+	matches := 0
+	for index := 0; index < len(alignment.AlignedA) && index < len(alignment.AlignedB); index++ {
+		a, b := alignment.AlignedA[index], alignment.AlignedB[index]
+		if a != '-' && strings.EqualFold(string(a), string(b)) {
+			matches++
+		}
+	}
+	return matches
+}
+
+// Gaps returns the number of columns that hold a gap.
+func (alignment Alignment) Gaps() int {
+	return strings.Count(alignment.AlignedA, "-") + strings.Count(alignment.AlignedB, "-")
+}
+
+// String prints an alignment in blocks of 60 columns with the matching
+// characters of a and b marked by a | between them.
+func (alignment Alignment) String() string {
+	const width = 60
+	var printed strings.Builder
+	printed.WriteString(fmt.Sprintf("Score: %d\n", alignment.Score))
+	for start := 0; start < len(alignment.AlignedA); start += width {
+		end := start + width
+		if end > len(alignment.AlignedA) {
+			end = len(alignment.AlignedA)
+		}
+		blockA, blockB := alignment.AlignedA[start:end], alignment.AlignedB[start:end]
+		var markers strings.Builder
+		for index := range blockA {
+			if blockA[index] != '-' && strings.EqualFold(blockA[index:index+1], blockB[index:index+1]) {
+				markers.WriteByte('|')
+			} else {
+				markers.WriteByte(' ')
+			}
+		}
+		printed.WriteString("\n" + blockA + "\n" + markers.String() + "\n" + blockB + "\n")
+	}
+	return printed.String()
+}
+
+/******************************************************************************
+
+Dynamic programming begins here.
+
+Three matrices are filled in: match holds the best score of alignments ending
+with a[i] aligned to b[j], gapA the best ending with a[i] aligned to a gap and
+gapB the best ending with a gap aligned to b[j]. Only the current and previous
+rows of scores are kept while a traceback matrix records which matrix each
+cell's score came from.
+
+******************************************************************************/
+
+// states of the traceback.
+const (
+	matchState byte = iota
+	gapAState
+	gapBState
+	startState // the start of a local alignment.
+)
+
+// negativeInfinity is low enough to never be chosen but high enough not to overflow when penalties are subtracted from it.
+const negativeInfinity = math.MinInt32 / 2
+
+// NeedlemanWunsch returns the best global alignment of a and b.
+func NeedlemanWunsch(a, b string, scoring Scoring) (Alignment, error) {
+	return align(a, b, scoring, false)
+}
+
+// SmithWaterman returns the best local alignment of a and b. If nothing in
+// the two sequences scores above 0 the alignment is empty.
+func SmithWaterman(a, b string, scoring Scoring) (Alignment, error) {
+	return align(a, b, scoring, true)
+}
+
+// align fills in the dynamic programming matrices and traces the best alignment back through them.
+func align(a, b string, scoring Scoring, local bool) (Alignment, error) {
+	if scoring.Matrix == nil || scoring.GapOpen < 0 || scoring.GapExtend < 0 {
+		return Alignment{}, fmt.Errorf("%w: scoring needs a substitution matrix and gap penalties of 0 or more", ErrInvalidScoring)
+	}
+	indicesA, err := scoring.Matrix.indices(a)
+	if err != nil {
+		return Alignment{}, fmt.Errorf("sequence a: %w", err)
+	}
+	indicesB, err := scoring.Matrix.indices(b)
+	if err != nil {
+		return Alignment{}, fmt.Errorf("sequence b: %w", err)
+	}
+
+	rows, columns := len(a)+1, len(b)+1
+	// traceback[i][j] packs the previous state of the match, gapA and gapB matrices into 2 bits each.
+	traceback := make([][]byte, rows)
+	for i := range traceback {
+		traceback[i] = make([]byte, columns)
+	}
+
+	previousMatch, previousGapA, previousGapB := make([]int, columns), make([]int, columns), make([]int, columns)
+	match, gapA, gapB := make([]int, columns), make([]int, columns), make([]int, columns)
+
+	previousMatch[0], previousGapA[0], previousGapB[0] = 0, negativeInfinity, negativeInfinity
+	for j := 1; j < columns; j++ {
+		previousMatch[j], previousGapA[j] = negativeInfinity, negativeInfinity
+		previousGapB[j] = -scoring.GapOpen - (j-1)*scoring.GapExtend
+		if local {
+			previousGapB[j] = negativeInfinity
+		}
+		traceback[0][j] = gapBState << 4
+	}
+
+	best, bestI, bestJ := 0, 0, 0
+	for i := 1; i < rows; i++ {
+		match[0], gapB[0] = negativeInfinity, negativeInfinity
+		gapA[0] = -scoring.GapOpen - (i-1)*scoring.GapExtend
+		if local {
+			gapA[0] = negativeInfinity
+		}
+		traceback[i][0] = gapAState << 2
+		for j := 1; j < columns; j++ {
+			// a[i-1] aligned to b[j-1].
+			matchFrom, matchScore := bestOf(previousMatch[j-1], previousGapA[j-1], previousGapB[j-1])
+			if local && matchScore <= 0 {
+				matchFrom, matchScore = startState, 0
+			}
+			match[j] = matchScore + scoring.Matrix.scores[indicesA[i-1]][indicesB[j-1]]
+
+			// a[i-1] aligned to a gap.
+			gapAFrom, gapAScore := bestOf(previousMatch[j]-scoring.GapOpen, previousGapA[j]-scoring.GapExtend, previousGapB[j]-scoring.GapOpen)
+			gapA[j] = gapAScore
+
+			// a gap aligned to b[j-1].
+			gapBFrom, gapBScore := bestOf(match[j-1]-scoring.GapOpen, gapA[j-1]-scoring.GapOpen, gapB[j-1]-scoring.GapExtend)
+			gapB[j] = gapBScore
+
+			traceback[i][j] = matchFrom | gapAFrom<<2 | gapBFrom<<4
+			if local && match[j] > best {
+				best, bestI, bestJ = match[j], i, j
+			}
+		}
+		previousMatch, match = match, previousMatch
+		previousGapA, gapA = gapA, previousGapA
+		previousGapB, gapB = gapB, previousGapB
+	}
+
+	// after the last swap the previous rows hold the last row of the matrices.
+	i, j := len(a), len(b)
+	var state byte
+	var score int
+	if local {
+		if best == 0 {
+			return Alignment{}, nil
+		}
+		i, j, state, score = bestI, bestJ, matchState, best
+	} else {
+		state, score = bestOf(previousMatch[j], previousGapA[j], previousGapB[j])
+	}
+
+	alignment := traceBack(a, b, traceback, i, j, state, local)
+	alignment.Score = score
+	return alignment, nil
+}
+
+// bestOf returns which of the three matrices has the highest score, preferring
+// matches over gaps when they tie, along with that score.
+func bestOf(matchScore, gapAScore, gapBScore int) (byte, int) {
+	state, score := matchState, matchScore
+	if gapAScore > score {
+		state, score = gapAState, gapAScore
+	}
+	if gapBScore > score {
+		state, score = gapBState, gapBScore
+	}
+	return state, score
+}
+
+// traceBack follows the traceback matrix from a[i-1] and b[j-1] back to the start of the alignment.
+func traceBack(a, b string, traceback [][]byte, i, j int, state byte, local bool) Alignment {
+	alignment := Alignment{EndA: i, EndB: j}
+	var alignedA, alignedB []byte
+	for i > 0 || j > 0 {
+		previous := traceback[i][j] >> (2 * state) & 3
+		switch state {
+		case matchState:
+			alignedA = append(alignedA, a[i-1])
+			alignedB = append(alignedB, b[j-1])
+			i, j = i-1, j-1
+		case gapAState:
+			alignedA = append(alignedA, a[i-1])
+			alignedB = append(alignedB, '-')
+			i--
+		case gapBState:
+			alignedA = append(alignedA, '-')
+			alignedB = append(alignedB, b[j-1])
+			j--
+		}
+		if previous == startState {
+			break
+		}
+		state = previous
+	}
+	alignment.StartA, alignment.StartB = i, j
+	if !local {
+		alignment.StartA, alignment.StartB = 0, 0
+	}
+	alignment.AlignedA, alignment.AlignedB = reverse(alignedA), reverse(alignedB)
+	return alignment
+}
+
+// reverse returns the bytes of a traceback as a string in the right order.
+func reverse(sequence []byte) string {
+	for left, right := 0, len(sequence)-1; left < right; left, right = left+1, right-1 {
+		sequence[left], sequence[right] = sequence[right], sequence[left]
+	}
+	return string(sequence)
+}
+
+/******************************************************************************
+
+Dynamic programming ends here.
+
+******************************************************************************/
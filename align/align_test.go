@@ -0,0 +1,333 @@
+package align_test
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/TimothyStiles/poly/align"
+	"github.com/TimothyStiles/poly/io/fasta"
+)
+
+func TestNeedlemanWunsch(t *testing.T) {
+	scoring := align.Scoring{Matrix: align.NewNucleotideMatrix(1, -1), GapOpen: 1, GapExtend: 1}
+	tests := []struct {
+		a, b     string
+		alignedA string
+		alignedB string
+		score    int
+	}{
+		{"ACGT", "ACGT", "ACGT", "ACGT", 4},
+		{"ACGT", "AGT", "ACGT", "A-GT", 2},
+		{"AGT", "ACGT", "A-GT", "ACGT", 2},
+		{"", "ACG", "---", "ACG", -3},
+		{"acgu", "ACGT", "acgu", "ACGT", 4},
+	}
+	for _, test := range tests {
+		alignment, err := align.NeedlemanWunsch(test.a, test.b, scoring)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if alignment.AlignedA != test.alignedA || alignment.AlignedB != test.alignedB || alignment.Score != test.score {
+			t.Errorf("aligning %s to %s: expected %s/%s with score %d, got %s/%s with score %d", test.a, test.b, test.alignedA, test.alignedB, test.score, alignment.AlignedA, alignment.AlignedB, alignment.Score)
+		}
+		if alignment.StartA != 0 || alignment.EndA != len(test.a) || alignment.StartB != 0 || alignment.EndB != len(test.b) {
+			t.Errorf("aligning %s to %s: expected a global alignment to cover both sequences, got %+v", test.a, test.b, alignment)
+		}
+	}
+}
+
+func TestAffineGaps(t *testing.T) {
+	// with a high opening penalty a single gap of 3 beats three gaps of 1.
+	scoring := align.Scoring{Matrix: align.NewNucleotideMatrix(5, -4), GapOpen: 10, GapExtend: 1}
+	alignment, err := align.NeedlemanWunsch("AAACCCGGGTTT", "AAAGGGTTT", scoring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alignment.AlignedB != "AAA---GGGTTT" || alignment.Score != 9*5-10-2 {
+		t.Errorf("expected a single gap of 3, got %s with score %d", alignment.AlignedB, alignment.Score)
+	}
+	if alignment.Gaps() != 3 || alignment.Matches() != 9 || alignment.Identity() != 0.75 {
+		t.Errorf("unexpected gaps %d, matches %d and identity %f", alignment.Gaps(), alignment.Matches(), alignment.Identity())
+	}
+}
+
+func TestSmithWaterman(t *testing.T) {
+	scoring := align.NewNucleotideScoring()
+	part := "GGTCTCAATGCGTAAACGG"
+	plasmid := strings.Repeat("TTTT", 10) + part + strings.Repeat("CCCC", 10)
+	alignment, err := align.SmithWaterman(plasmid, part, scoring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alignment.AlignedA != part || alignment.StartA != 40 || alignment.EndA != 40+len(part) || alignment.StartB != 0 || alignment.EndB != len(part) {
+		t.Errorf("expected to find the part at 40, got %+v", alignment)
+	}
+	if plasmid[alignment.StartA:alignment.EndA] != strings.ReplaceAll(alignment.AlignedA, "-", "") {
+		t.Errorf("StartA and EndA don't match the aligned sequence %s", alignment.AlignedA)
+	}
+
+	empty, err := align.SmithWaterman("AAAA", "CCCC", scoring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.AlignedA != "" || empty.Score != 0 {
+		t.Errorf("expected no local alignment between unrelated sequences, got %+v", empty)
+	}
+}
+
+func TestAlignErrors(t *testing.T) {
+	if _, err := align.NeedlemanWunsch("ACGT", "ACJT", align.NewNucleotideScoring()); !errors.Is(err, align.ErrUnknownCharacter) {
+		t.Errorf("expected an unknown character error, got %v", err)
+	}
+	if _, err := align.SmithWaterman("ACGT", "ACGT", align.Scoring{GapOpen: 1}); !errors.Is(err, align.ErrInvalidScoring) {
+		t.Errorf("expected an invalid scoring error without a matrix, got %v", err)
+	}
+	if _, err := align.NeedlemanWunsch("ACGT", "ACGT", align.Scoring{Matrix: align.BLOSUM62, GapOpen: -1}); !errors.Is(err, align.ErrInvalidScoring) {
+		t.Errorf("expected an invalid scoring error with a negative gap penalty, got %v", err)
+	}
+}
+
+func TestMatrices(t *testing.T) {
+	for name, matrix := range map[string]*align.SubstitutionMatrix{"BLOSUM62": align.BLOSUM62, "PAM250": align.PAM250} {
+		alphabet := matrix.Alphabet()
+		if alphabet != "ARNDCQEGHILKMFPSTWYVBZX*" {
+			t.Errorf("%s: unexpected alphabet %s", name, alphabet)
+		}
+		for i := 0; i < len(alphabet); i++ {
+			for j := 0; j < len(alphabet); j++ {
+				ij, _ := matrix.Score(alphabet[i], alphabet[j])
+				ji, _ := matrix.Score(alphabet[j], alphabet[i])
+				if ij != ji {
+					t.Errorf("%s is not symmetric at %c%c: %d and %d", name, alphabet[i], alphabet[j], ij, ji)
+				}
+			}
+		}
+	}
+	if score, _ := align.BLOSUM62.Score('w', 'W'); score != 11 {
+		t.Errorf("expected BLOSUM62 to score WW as 11, got %d", score)
+	}
+	if score, _ := align.PAM250.Score('C', 'C'); score != 12 {
+		t.Errorf("expected PAM250 to score CC as 12, got %d", score)
+	}
+
+	matrix, err := align.ParseMatrix([]byte("# a tiny matrix\n   A  B\nA  1 -1\nB -1  2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score, _ := matrix.Score('B', 'B'); score != 2 {
+		t.Errorf("expected a parsed BB score of 2, got %d", score)
+	}
+	for _, bad := range []string{"A B\nB 1 1\nA 1 1\n", "A B\nA 1 x\nB 1 1\n", "A B\nA 1\nB 1 1\n", "AB C\n"} {
+		if _, err := align.ParseMatrix([]byte(bad)); err == nil {
+			t.Errorf("expected an error parsing %q", bad)
+		}
+	}
+}
+
+func TestNeedlemanWunschBanded(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	randomSequence := func(length int) string {
+		sequence := make([]byte, length)
+		for index := range sequence {
+			sequence[index] = "ACGT"[random.Intn(4)]
+		}
+		return string(sequence)
+	}
+	scorings := []align.Scoring{
+		align.NewNucleotideScoring(),
+		{Matrix: align.NewNucleotideMatrix(1, -1), GapOpen: 2, GapExtend: 1},
+		{Matrix: align.NewNucleotideMatrix(2, -3), GapOpen: 1, GapExtend: 1},
+	}
+
+	// without a band the linear space alignment should score the same as the full matrix.
+	for trial := 0; trial < 200; trial++ {
+		a, b := randomSequence(random.Intn(30)), randomSequence(random.Intn(30))
+		scoring := scorings[trial%len(scorings)]
+		full, err := align.NeedlemanWunsch(a, b, scoring)
+		if err != nil {
+			t.Fatal(err)
+		}
+		linear, err := align.NeedlemanWunschBanded(a, b, scoring, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if linear.Score != full.Score {
+			t.Fatalf("aligning %s to %s: expected a score of %d, got %d:\n%s", a, b, full.Score, linear.Score, linear)
+		}
+		if strings.ReplaceAll(linear.AlignedA, "-", "") != a || strings.ReplaceAll(linear.AlignedB, "-", "") != b {
+			t.Fatalf("aligning %s to %s: aligned sequences don't match the originals:\n%s", a, b, linear)
+		}
+	}
+
+	// a band wider than the differences between two sequences finds the same alignment as no band.
+	a := randomSequence(300)
+	b := a[:100] + "TTT" + a[100:200] + a[205:]
+	full, _ := align.NeedlemanWunsch(a, b, align.NewNucleotideScoring())
+	banded, err := align.NeedlemanWunschBanded(a, b, align.NewNucleotideScoring(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if banded.Score != full.Score {
+		t.Errorf("expected a banded score of %d, got %d", full.Score, banded.Score)
+	}
+
+	if _, err := align.NeedlemanWunschBanded(a, b, align.Scoring{Matrix: align.BLOSUM62, GapOpen: 1, GapExtend: 2}, 10); !errors.Is(err, align.ErrInvalidScoring) {
+		t.Errorf("expected an invalid scoring error when GapExtend is more than GapOpen, got %v", err)
+	}
+}
+
+func TestNeedlemanWunschBandedLong(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping a 100kb alignment in short mode")
+	}
+	random := rand.New(rand.NewSource(2))
+	reference := make([]byte, 100000)
+	for index := range reference {
+		reference[index] = "ACGT"[random.Intn(4)]
+	}
+	// a read with a few SNPs, an insertion and a deletion.
+	read := []byte(string(reference[:20000]) + "GATTACA" + string(reference[20000:60000]) + string(reference[60010:]))
+	for _, position := range []int{5000, 45000, 80000} {
+		read[position] = "CATG"[strings.IndexByte("ACGT", read[position])]
+	}
+
+	alignment, err := align.NeedlemanWunschBanded(string(reference), string(read), align.NewNucleotideScoring(), 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alignment.Gaps() != 17 || alignment.Matches() != len(reference)-13 {
+		t.Errorf("expected 17 gaps and %d matches, got %d and %d", len(reference)-13, alignment.Gaps(), alignment.Matches())
+	}
+}
+
+func TestMSA(t *testing.T) {
+	sequences := []fasta.Fasta{
+		{Name: "human", Sequence: "MKTAYIAKQRQISFVKSHFSRQLEERLGLIEVQ"},
+		{Name: "mouse", Sequence: "MKTAYIAKQRQISFVKSHFSRQDEERLGLIEVQ"},
+		{Name: "fly", Sequence: "MKTAYIAKQRISFVKSHFSRQLEERLGLIEV"},
+		{Name: "yeast", Sequence: "MSTAYIAKQRQISFVKAHFSRQLEERLGLIEVQAPIL"},
+	}
+	alignment, err := align.MSA(sequences, align.NewProteinScoring())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, row := range alignment.Rows {
+		if alignment.Names[index] != sequences[index].Name {
+			t.Errorf("expected row %d to be %s, got %s", index, sequences[index].Name, alignment.Names[index])
+		}
+		if len(row) != alignment.Length() {
+			t.Errorf("expected every row to have %d columns, %s has %d", alignment.Length(), alignment.Names[index], len(row))
+		}
+		if strings.ReplaceAll(row, "-", "") != sequences[index].Sequence {
+			t.Errorf("row %s doesn't match its sequence: %s", alignment.Names[index], row)
+		}
+	}
+	// the sequences share the motif SFVKSHFSRQ (or SFVKAHFSRQ) so it should line up in a single block.
+	start := strings.Index(alignment.Rows[0], "SFVKSHFSRQ")
+	for index, row := range alignment.Rows {
+		if strings.ReplaceAll(row[start:start+10], "A", "S") != "SFVKSHFSRQ" {
+			t.Errorf("expected %s to line up with the shared motif, got %s", alignment.Names[index], row)
+		}
+	}
+	if column := alignment.Column(0); column != "MMMM" {
+		t.Errorf("expected the first column to be MMMM, got %s", column)
+	}
+
+	consensus, err := fasta.Consensus(alignment.Fasta(), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(consensus, "MKTAYIAKQRQISFVKSHFSRQ") {
+		t.Errorf("unexpected consensus %s", consensus)
+	}
+
+	single, err := align.MSA(sequences[:1], align.NewProteinScoring())
+	if err != nil || single.Rows[0] != sequences[0].Sequence {
+		t.Errorf("expected a single sequence to align to itself, got %v (%v)", single, err)
+	}
+	if _, err := align.MSA(nil, align.NewProteinScoring()); err == nil {
+		t.Error("expected an error aligning no sequences")
+	}
+	if _, err := align.MSA([]fasta.Fasta{{Name: "bad", Sequence: "MK1"}}, align.NewProteinScoring()); !errors.Is(err, align.ErrUnknownCharacter) {
+		t.Errorf("expected an unknown character error, got %v", err)
+	}
+}
+
+func TestBuildPair(t *testing.T) {
+	scoring := align.NewNucleotideScoring()
+	alignment, err := align.NeedlemanWunsch("GATTACAGATTACA", "GATTAGATTACA", scoring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, err := align.BuildPair(alignment, "first", "second", scoring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"# 1: first\n",
+		"# Identity:       12/14 (85.7%)\n",
+		"# Gaps:            2/14 (14.3%)\n",
+		"first              1 GATTACAGATTACA     14\n                     ||||  ||||||||\nsecond             1 GATT--AGATTACA     12\n",
+	} {
+		if !strings.Contains(string(pair), expected) {
+			t.Errorf("expected pair output to contain %q, got:\n%s", expected, pair)
+		}
+	}
+
+	markx, err := align.BuildMarkx0(alignment, "first", "second", scoring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedMarkx := "               10\nfirst  GATTACAGATTACA\n       ::::  ::::::::\nsecond GATT--AGATTACA\n                 10\n\n"
+	if string(markx) != expectedMarkx {
+		t.Errorf("unexpected markx0 output:\n%s", markx)
+	}
+
+	if _, err := align.BuildPair(align.Alignment{AlignedA: "AC", AlignedB: "A"}, "a", "b", scoring); !errors.Is(err, align.ErrInvalidAlignment) {
+		t.Errorf("expected an invalid alignment error for rows of different lengths, got %v", err)
+	}
+}
+
+func TestBuildMultiple(t *testing.T) {
+	alignment := align.MultipleAlignment{Names: []string{"a", "long_name"}, Rows: []string{"ACG-T", "ACGGT"}}
+	stockholm, err := align.BuildStockholm(alignment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "# STOCKHOLM 1.0\n\na         ACG-T\nlong_name ACGGT\n//\n"; string(stockholm) != expected {
+		t.Errorf("unexpected Stockholm output:\n%s", stockholm)
+	}
+
+	clustal, err := align.BuildClustal(alignment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "CLUSTAL W multiple sequence alignment\n\n\na              ACG-T 4\nlong_name      ACGGT 5\n               *** *\n"; string(clustal) != expected {
+		t.Errorf("unexpected Clustal output:\n%s", clustal)
+	}
+
+	alignedFasta, err := align.BuildAlignedFasta(alignment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := fasta.Parse(strings.NewReader(string(alignedFasta)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Sequence != "ACG-T" || records[1].Name != "long_name" {
+		t.Errorf("unexpected aligned fasta records %v", records)
+	}
+
+	invalid := []align.MultipleAlignment{
+		{Names: []string{"a"}, Rows: []string{"ACGT", "ACG"}},
+		{Names: []string{"a", "b"}, Rows: []string{"ACGT", "ACG"}},
+		{Names: []string{"a b", "c"}, Rows: []string{"ACGT", "ACGT"}},
+	}
+	for _, alignment := range invalid {
+		if _, err := align.BuildClustal(alignment); !errors.Is(err, align.ErrInvalidAlignment) {
+			t.Errorf("expected an invalid alignment error for %v, got %v", alignment, err)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package align
+
+import (
+	"fmt"
+)
+
+/******************************************************************************
+
+Banded, linear space alignment begins here.
+
+NeedlemanWunsch keeps a traceback cell for every pair of characters, which is
+fine for genes but not for aligning a 100kb plasmid map to its sequencing
+results. Two tricks fix that for sequences that are nearly identical:
+
+A band only fills in cells within bandwidth diagonals of the main diagonal,
+since the alignment of two similar sequences never strays far from it. That
+turns O(n*m) time into O(n*bandwidth).
+
+Hirschberg's divide and conquer, extended to affine gaps by Myers and Miller,
+never keeps a traceback at all. It scores the top half of the matrix forwards
+and the bottom half backwards to find where the best alignment crosses the
+middle row, then recurses on the two halves. That turns O(n*m) memory into
+O(n+m) at the cost of roughly doubling the time.
+
+Myers, E. W. and Miller, W. (1988) Optimal alignments in linear space.
+Computer Applications in the Biosciences 4(1) 11-17.
+
+******************************************************************************/
+
+// NeedlemanWunschBanded returns the best global alignment of a and b that
+// stays within bandwidth diagonals of the diagonal running from the start of
+// both sequences to their ends, using memory proportional to the length of the
+// sequences rather than their product. A negative bandwidth doesn't restrict
+// the alignment at all, giving the same score as NeedlemanWunsch in linear
+// memory. GapOpen has to be at least GapExtend.
+func NeedlemanWunschBanded(a, b string, scoring Scoring, bandwidth int) (Alignment, error) {
+	if scoring.Matrix == nil || scoring.GapOpen < scoring.GapExtend || scoring.GapExtend < 0 {
+		return Alignment{}, fmt.Errorf("%w: banded alignment needs a substitution matrix and 0 <= GapExtend <= GapOpen", ErrInvalidScoring)
+	}
+	indicesA, err := scoring.Matrix.indices(a)
+	if err != nil {
+		return Alignment{}, fmt.Errorf("sequence a: %w", err)
+	}
+	indicesB, err := scoring.Matrix.indices(b)
+	if err != nil {
+		return Alignment{}, fmt.Errorf("sequence b: %w", err)
+	}
+
+	// diagonals are numbered j-i, so the band always holds both the start (0)
+	// and the end (len(b)-len(a)) of the alignment.
+	low, high := 0, len(b)-len(a)
+	if high < low {
+		low, high = high, low
+	}
+	if bandwidth < 0 {
+		low, high = -len(a), len(b)
+	} else {
+		low, high = low-bandwidth, high+bandwidth
+	}
+
+	aligner := &linearAligner{
+		a: a, b: b, indicesA: indicesA, indicesB: indicesB,
+		scores:  scoring.Matrix.scores,
+		open:    scoring.GapOpen - scoring.GapExtend,
+		extend:  scoring.GapExtend,
+		low:     low,
+		high:    high,
+		forward: make([]int, len(b)+1), forwardGap: make([]int, len(b)+1),
+		reverse: make([]int, len(b)+1), reverseGap: make([]int, len(b)+1),
+	}
+	aligner.diff(0, len(a), 0, len(b), aligner.open, aligner.open)
+
+	alignment := Alignment{
+		AlignedA: string(aligner.alignedA),
+		AlignedB: string(aligner.alignedB),
+		EndA:     len(a),
+		EndB:     len(b),
+	}
+	alignment.Score = score(aligner.alignedA, aligner.alignedB, scoring)
+	return alignment, nil
+}
+
+// linearAligner holds the state of a Myers-Miller alignment. Gaps of length n
+// score -(open + n*extend), so open is GapOpen - GapExtend.
+type linearAligner struct {
+	a, b                string
+	indicesA, indicesB  []int
+	scores              [][]int
+	open, extend        int
+	low, high           int // the diagonals, numbered j-i, of the band.
+	forward, forwardGap []int
+	reverse, reverseGap []int
+	alignedA, alignedB  []byte
+}
+
+// gap returns the score of a gap of length.
+func (aligner *linearAligner) gap(length int) int {
+	if length <= 0 {
+		return 0
+	}
+	return -(aligner.open + length*aligner.extend)
+}
+
+// columns returns the range of columns of b, relative to startB, that row i
+// of a, relative to startA, has inside the band, clipped to [0, lengthB].
+func (aligner *linearAligner) columns(startA, startB, i, lengthB int) (int, int) {
+	first, last := startA+i+aligner.low-startB, startA+i+aligner.high-startB
+	if first < 0 {
+		first = 0
+	}
+	if last > lengthB {
+		last = lengthB
+	}
+	return first, last
+}
+
+// insert aligns b[startB:startB+length] to gaps.
+func (aligner *linearAligner) insert(startB, length int) {
+	for j := startB; j < startB+length; j++ {
+		aligner.alignedA = append(aligner.alignedA, '-')
+		aligner.alignedB = append(aligner.alignedB, aligner.b[j])
+	}
+}
+
+// delete aligns a[startA:startA+length] to gaps.
+func (aligner *linearAligner) delete(startA, length int) {
+	for i := startA; i < startA+length; i++ {
+		aligner.alignedA = append(aligner.alignedA, aligner.a[i])
+		aligner.alignedB = append(aligner.alignedB, '-')
+	}
+}
+
+// diff aligns a[startA:startA+lengthA] to b[startB:startB+lengthB].
+// openStart and openEnd are what it costs to open a gap in b at the start and
+// end of the region, which is 0 rather than open where the gap carries on
+// from a neighbouring region.
+func (aligner *linearAligner) diff(startA, lengthA, startB, lengthB, openStart, openEnd int) {
+	switch {
+	case lengthB == 0:
+		aligner.delete(startA, lengthA)
+		return
+	case lengthA == 0:
+		aligner.insert(startB, lengthB)
+		return
+	case lengthA == 1:
+		aligner.alignOne(startA, startB, lengthB, openStart, openEnd)
+		return
+	}
+
+	middle := lengthA / 2
+	aligner.scoreForward(startA, middle, startB, lengthB, openStart)
+	aligner.scoreReverse(startA, lengthA, middle, startB, lengthB, openEnd)
+
+	// find where the best alignment crosses the middle row: either between
+	// two characters of b or in the middle of a gap in b.
+	first, last := aligner.columns(startA, startB, middle, lengthB)
+	bestScore, bestColumn, inGap := negativeInfinity, first, false
+	for j := first; j <= last; j++ {
+		if crossing := aligner.forward[j] + aligner.reverse[j]; crossing > bestScore {
+			bestScore, bestColumn, inGap = crossing, j, false
+		}
+	}
+	for j := first; j <= last; j++ {
+		// both halves paid to open the gap, so one of them is given back.
+		if crossing := aligner.forwardGap[j] + aligner.reverseGap[j] + aligner.open; crossing > bestScore {
+			bestScore, bestColumn, inGap = crossing, j, true
+		}
+	}
+
+	if !inGap {
+		aligner.diff(startA, middle, startB, bestColumn, openStart, aligner.open)
+		aligner.diff(startA+middle, lengthA-middle, startB+bestColumn, lengthB-bestColumn, aligner.open, openEnd)
+		return
+	}
+	aligner.diff(startA, middle-1, startB, bestColumn, openStart, 0)
+	aligner.delete(startA+middle-1, 2)
+	aligner.diff(startA+middle+1, lengthA-middle-1, startB+bestColumn, lengthB-bestColumn, 0, openEnd)
+}
+
+// alignOne aligns a single character of a to b[startB:startB+lengthB], either
+// to one of the characters of b or to a gap.
+func (aligner *linearAligner) alignOne(startA, startB, lengthB, openStart, openEnd int) {
+	// deleting the character can join a gap on either side of the region.
+	deleteFirst := openStart <= openEnd
+	cheapestOpen := openStart
+	if !deleteFirst {
+		cheapestOpen = openEnd
+	}
+	bestScore, bestColumn := -(cheapestOpen+aligner.extend)+aligner.gap(lengthB), -1
+
+	first, last := aligner.columns(startA, startB, 1, lengthB)
+	if first < 1 {
+		first = 1
+	}
+	for j := first; j <= last; j++ {
+		score := aligner.gap(j-1) + aligner.scores[aligner.indicesA[startA]][aligner.indicesB[startB+j-1]] + aligner.gap(lengthB-j)
+		if score > bestScore {
+			bestScore, bestColumn = score, j
+		}
+	}
+
+	switch {
+	case bestColumn < 0 && deleteFirst:
+		aligner.delete(startA, 1)
+		aligner.insert(startB, lengthB)
+	case bestColumn < 0:
+		aligner.insert(startB, lengthB)
+		aligner.delete(startA, 1)
+	default:
+		aligner.insert(startB, bestColumn-1)
+		aligner.alignedA = append(aligner.alignedA, aligner.a[startA])
+		aligner.alignedB = append(aligner.alignedB, aligner.b[startB+bestColumn-1])
+		aligner.insert(startB+bestColumn, lengthB-bestColumn)
+	}
+}
+
+// scoreForward scores the alignments of a[startA:startA+rows] to every prefix
+// of b[startB:startB+lengthB]. forward[j] ends up holding the best score of
+// aligning to the first j characters and forwardGap[j] the best score of those
+// that end with a gap in b.
+func (aligner *linearAligner) scoreForward(startA, rows, startB, lengthB, openStart int) {
+	forward, forwardGap := aligner.forward[:lengthB+1], aligner.forwardGap[:lengthB+1]
+	for j := range forward {
+		forward[j], forwardGap[j] = negativeInfinity, negativeInfinity
+	}
+	_, last := aligner.columns(startA, startB, 0, lengthB)
+	forward[0] = 0
+	for j := 1; j <= last; j++ {
+		forward[j] = aligner.gap(j)
+		forwardGap[j] = forward[j] - aligner.open
+	}
+
+	for i := 1; i <= rows; i++ {
+		first, last := aligner.columns(startA, startB, i, lengthB)
+		characterA := aligner.indicesA[startA+i-1]
+		diagonal := negativeInfinity // forward[j-1] of the previous row.
+		current, gapA := negativeInfinity, negativeInfinity
+		if first == 0 {
+			diagonal = forward[0]
+			forward[0] = -(openStart + i*aligner.extend)
+			current, gapA = forward[0], forward[0]-aligner.open
+			forwardGap[0] = forward[0]
+			first = 1
+		} else {
+			diagonal = forward[first-1]
+			forward[first-1], forwardGap[first-1] = negativeInfinity, negativeInfinity
+		}
+		for j := first; j <= last; j++ {
+			// a gap in a, carried along the row.
+			gapA = maximum(current-aligner.open-aligner.extend, gapA-aligner.extend)
+			// a gap in b, carried down the column.
+			forwardGap[j] = maximum(forward[j]-aligner.open-aligner.extend, forwardGap[j]-aligner.extend)
+			match := diagonal + aligner.scores[characterA][aligner.indicesB[startB+j-1]]
+			diagonal = forward[j]
+			current = maximum(match, maximum(gapA, forwardGap[j]))
+			forward[j] = current
+		}
+		if last+1 <= lengthB {
+			forward[last+1], forwardGap[last+1] = negativeInfinity, negativeInfinity
+		}
+	}
+}
+
+// scoreReverse scores the alignments of a[startA+middle:startA+lengthA] to
+// every suffix of b[startB:startB+lengthB], the mirror image of scoreForward.
+// reverse[j] ends up holding the best score of aligning to b from j onwards
+// and reverseGap[j] the best score of those that start with a gap in b.
+func (aligner *linearAligner) scoreReverse(startA, lengthA, middle, startB, lengthB, openEnd int) {
+	reverse, reverseGap := aligner.reverse[:lengthB+1], aligner.reverseGap[:lengthB+1]
+	for j := range reverse {
+		reverse[j], reverseGap[j] = negativeInfinity, negativeInfinity
+	}
+	first, _ := aligner.columns(startA, startB, lengthA, lengthB)
+	reverse[lengthB] = 0
+	for j := lengthB - 1; j >= first; j-- {
+		reverse[j] = aligner.gap(lengthB - j)
+		reverseGap[j] = reverse[j] - aligner.open
+	}
+
+	for i := lengthA - 1; i >= middle; i-- {
+		first, last := aligner.columns(startA, startB, i, lengthB)
+		characterA := aligner.indicesA[startA+i]
+		diagonal := negativeInfinity // reverse[j+1] of the previous row.
+		current, gapA := negativeInfinity, negativeInfinity
+		if last == lengthB {
+			diagonal = reverse[lengthB]
+			reverse[lengthB] = -(openEnd + (lengthA-i)*aligner.extend)
+			current, gapA = reverse[lengthB], reverse[lengthB]-aligner.open
+			reverseGap[lengthB] = reverse[lengthB]
+			last = lengthB - 1
+		} else {
+			diagonal = reverse[last+1]
+			reverse[last+1], reverseGap[last+1] = negativeInfinity, negativeInfinity
+		}
+		for j := last; j >= first; j-- {
+			gapA = maximum(current-aligner.open-aligner.extend, gapA-aligner.extend)
+			reverseGap[j] = maximum(reverse[j]-aligner.open-aligner.extend, reverseGap[j]-aligner.extend)
+			match := diagonal + aligner.scores[characterA][aligner.indicesB[startB+j]]
+			diagonal = reverse[j]
+			current = maximum(match, maximum(gapA, reverseGap[j]))
+			reverse[j] = current
+		}
+		if first-1 >= 0 {
+			reverse[first-1], reverseGap[first-1] = negativeInfinity, negativeInfinity
+		}
+	}
+}
+
+// maximum returns the larger of two scores.
+func maximum(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// score rescores an alignment with affine gaps.
+func score(alignedA, alignedB []byte, scoring Scoring) int {
+	total := 0
+	inGapA, inGapB := false, false
+	for column := range alignedA {
+		switch {
+		case alignedA[column] == '-':
+			if inGapA {
+				total -= scoring.GapExtend
+			} else {
+				total -= scoring.GapOpen
+			}
+			inGapA, inGapB = true, false
+		case alignedB[column] == '-':
+			if inGapB {
+				total -= scoring.GapExtend
+			} else {
+				total -= scoring.GapOpen
+			}
+			inGapA, inGapB = false, true
+		default:
+			substitution, _ := scoring.Matrix.Score(alignedA[column], alignedB[column])
+			total += substitution
+			inGapA, inGapB = false, false
+		}
+	}
+	return total
+}
+
+/******************************************************************************
+
+Banded, linear space alignment ends here.
+
+******************************************************************************/
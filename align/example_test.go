@@ -0,0 +1,54 @@
+package align_test
+
+import (
+	"fmt"
+
+	"github.com/TimothyStiles/poly/align"
+	"github.com/TimothyStiles/poly/io/fasta"
+)
+
+func ExampleNeedlemanWunsch() {
+	alignment, _ := align.NeedlemanWunsch("GATTACAGATTACA", "GATTAGATTACA", align.NewNucleotideScoring())
+
+	fmt.Println(alignment.AlignedA)
+	fmt.Println(alignment.AlignedB)
+	// Output:
+	// GATTACAGATTACA
+	// GATT--AGATTACA
+}
+
+func ExampleSmithWaterman() {
+	alignment, _ := align.SmithWaterman("MKTAYIAKQRQISFVKSHFSRQ", "AYIAKQRQ", align.NewProteinScoring())
+
+	fmt.Println(alignment.AlignedA, alignment.StartA, alignment.EndA, alignment.Score)
+	// Output: AYIAKQRQ 3 11 39
+}
+
+func ExampleAlignment_String() {
+	alignment, _ := align.NeedlemanWunsch("ACGTACGT", "ACGAACGT", align.NewNucleotideScoring())
+
+	fmt.Print(alignment)
+	// Output:
+	// Score: 31
+	//
+	// ACGTACGT
+	// ||| ||||
+	// ACGAACGT
+}
+
+func ExampleMSA() {
+	sequences := []fasta.Fasta{
+		{Name: "a", Sequence: "ATGCGTACGTTAGC"},
+		{Name: "b", Sequence: "ATGCGTACGTAGC"},
+		{Name: "c", Sequence: "ATGCGAACGTTAGC"},
+	}
+	alignment, _ := align.MSA(sequences, align.NewNucleotideScoring())
+
+	for index, row := range alignment.Rows {
+		fmt.Println(alignment.Names[index], row)
+	}
+	// Output:
+	// a ATGCGTACGTTAGC
+	// b ATGCGTACG-TAGC
+	// c ATGCGAACGTTAGC
+}
@@ -0,0 +1,300 @@
+package align
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/TimothyStiles/poly/io/fasta"
+)
+
+/******************************************************************************
+
+Alignment writers begin here.
+
+These write alignments out in the formats other tools expect: EMBOSS pair and
+markx0 for pairwise alignments and Clustal, Stockholm and aligned FASTA for
+multiple alignments. Tree building tools like RAxML, IQ-TREE and PhyML read at
+least one of the multiple alignment formats.
+
+******************************************************************************/
+
+// ErrInvalidAlignment is returned when an alignment can't be written because its rows aren't the same length.
+var ErrInvalidAlignment = errors.New("invalid alignment")
+
+// BuildPair writes a pairwise alignment in EMBOSS's pair format, the default
+// output of needle and water, with a header of statistics followed by the
+// alignment in blocks of 50 columns. The markup line between the sequences
+// shows identical characters with |, similar ones (those that score above 0)
+// with : and mismatches with a dot.
+func BuildPair(alignment Alignment, nameA, nameB string, scoring Scoring) ([]byte, error) {
+	if err := checkPairwise(alignment, scoring); err != nil {
+		return nil, err
+	}
+	length := len(alignment.AlignedA)
+	similar := 0
+	markup := make([]byte, length)
+	for column := 0; column < length; column++ {
+		markup[column] = pairMarkup(alignment.AlignedA[column], alignment.AlignedB[column], scoring.Matrix)
+		if markup[column] == '|' || markup[column] == ':' {
+			similar++
+		}
+	}
+
+	var pair bytes.Buffer
+	pair.WriteString("########################################\n")
+	pair.WriteString("# Aligned_sequences: 2\n")
+	pair.WriteString(fmt.Sprintf("# 1: %s\n# 2: %s\n", nameA, nameB))
+	pair.WriteString(fmt.Sprintf("# Gap_penalty: %d\n# Extend_penalty: %d\n#\n", scoring.GapOpen, scoring.GapExtend))
+	pair.WriteString(fmt.Sprintf("# Length: %d\n", length))
+	pair.WriteString(fmt.Sprintf("# Identity:   %9s (%s)\n", fmt.Sprintf("%d/%d", alignment.Matches(), length), percentage(alignment.Matches(), length)))
+	pair.WriteString(fmt.Sprintf("# Similarity: %9s (%s)\n", fmt.Sprintf("%d/%d", similar, length), percentage(similar, length)))
+	pair.WriteString(fmt.Sprintf("# Gaps:       %9s (%s)\n", fmt.Sprintf("%d/%d", alignment.Gaps(), length), percentage(alignment.Gaps(), length)))
+	pair.WriteString(fmt.Sprintf("# Score: %d\n#\n#\n", alignment.Score))
+	pair.WriteString("#=======================================\n\n")
+
+	const width = 50
+	positionA, positionB := alignment.StartA, alignment.StartB
+	for start := 0; start < length; start += width {
+		end := start + width
+		if end > length {
+			end = length
+		}
+		blockA, blockB := alignment.AlignedA[start:end], alignment.AlignedB[start:end]
+		nextA, nextB := positionA+len(blockA)-strings.Count(blockA, "-"), positionB+len(blockB)-strings.Count(blockB, "-")
+		pair.WriteString(fmt.Sprintf("%-13.13s%7d %s%7d\n", nameA, blockStart(positionA, nextA), blockA, nextA))
+		pair.WriteString(fmt.Sprintf("%21s%s\n", "", markup[start:end]))
+		pair.WriteString(fmt.Sprintf("%-13.13s%7d %s%7d\n\n", nameB, blockStart(positionB, nextB), blockB, nextB))
+		positionA, positionB = nextA, nextB
+	}
+	pair.WriteString("\n#---------------------------------------\n#---------------------------------------\n")
+	return pair.Bytes(), nil
+}
+
+// BuildMarkx0 writes a pairwise alignment in the markx0 format EMBOSS
+// borrowed from FASTA, in blocks of 50 columns with a ruler marking every
+// tenth character of each sequence. Identical characters are marked with :
+// and similar ones with a dot.
+func BuildMarkx0(alignment Alignment, nameA, nameB string, scoring Scoring) ([]byte, error) {
+	if err := checkPairwise(alignment, scoring); err != nil {
+		return nil, err
+	}
+	const width = 50
+	var markx bytes.Buffer
+	length := len(alignment.AlignedA)
+	positionA, positionB := alignment.StartA, alignment.StartB
+	for start := 0; start < length; start += width {
+		end := start + width
+		if end > length {
+			end = length
+		}
+		blockA, blockB := alignment.AlignedA[start:end], alignment.AlignedB[start:end]
+		markup := make([]byte, len(blockA))
+		for column := range markup {
+			switch pairMarkup(blockA[column], blockB[column], scoring.Matrix) {
+			case '|':
+				markup[column] = ':'
+			case ':':
+				markup[column] = '.'
+			default:
+				markup[column] = ' '
+			}
+		}
+		markx.WriteString(ruler(blockA, positionA) + "\n")
+		markx.WriteString(fmt.Sprintf("%-6.6s %s\n", nameA, blockA))
+		markx.WriteString(fmt.Sprintf("%7s%s\n", "", strings.TrimRight(string(markup), " ")))
+		markx.WriteString(fmt.Sprintf("%-6.6s %s\n", nameB, blockB))
+		markx.WriteString(ruler(blockB, positionB) + "\n\n")
+		positionA += len(blockA) - strings.Count(blockA, "-")
+		positionB += len(blockB) - strings.Count(blockB, "-")
+	}
+	return markx.Bytes(), nil
+}
+
+// ruler numbers every tenth character of a block of an aligned sequence,
+// with each number ending above or below the character it counts.
+func ruler(block string, position int) string {
+	line := []byte(strings.Repeat(" ", 7+len(block)))
+	for column := 0; column < len(block); column++ {
+		if block[column] == '-' {
+			continue
+		}
+		position++
+		if position%10 != 0 {
+			continue
+		}
+		number := fmt.Sprint(position)
+		if end := 7 + column + 1; end-len(number) >= 0 {
+			copy(line[end-len(number):end], number)
+		}
+	}
+	return strings.TrimRight(string(line), " ")
+}
+
+// checkPairwise checks a pairwise alignment can be written with scoring.
+func checkPairwise(alignment Alignment, scoring Scoring) error {
+	if scoring.Matrix == nil {
+		return fmt.Errorf("%w: scoring needs a substitution matrix", ErrInvalidScoring)
+	}
+	if len(alignment.AlignedA) != len(alignment.AlignedB) {
+		return fmt.Errorf("%w: aligned sequences have different lengths %d and %d", ErrInvalidAlignment, len(alignment.AlignedA), len(alignment.AlignedB))
+	}
+	return nil
+}
+
+// pairMarkup returns the EMBOSS pair markup of an aligned pair of characters.
+func pairMarkup(a, b byte, matrix *SubstitutionMatrix) byte {
+	if a == '-' || b == '-' {
+		return ' '
+	}
+	if strings.EqualFold(string(a), string(b)) {
+		return '|'
+	}
+	if score, err := matrix.Score(a, b); err == nil && score > 0 {
+		return ':'
+	}
+	return '.'
+}
+
+// blockStart returns the number of the first character of a block, which
+// EMBOSS writes as the number of the last character before it if the block is all gaps.
+func blockStart(position, next int) int {
+	if next == position {
+		return position
+	}
+	return position + 1
+}
+
+// percentage formats count/total as a percentage with one decimal place.
+func percentage(count, total int) string {
+	if total == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(count)/float64(total))
+}
+
+// checkMultiple checks that every row of a multiple alignment has a name and the same length.
+func checkMultiple(alignment MultipleAlignment) error {
+	if len(alignment.Names) != len(alignment.Rows) {
+		return fmt.Errorf("%w: %d names for %d rows", ErrInvalidAlignment, len(alignment.Names), len(alignment.Rows))
+	}
+	for index, row := range alignment.Rows {
+		if len(row) != alignment.Length() {
+			return fmt.Errorf("%w: row %s has %d columns rather than %d", ErrInvalidAlignment, alignment.Names[index], len(row), alignment.Length())
+		}
+		if strings.ContainsAny(alignment.Names[index], " \t\n") || alignment.Names[index] == "" {
+			return fmt.Errorf("%w: row %d has the name %q, which can't be empty or contain whitespace", ErrInvalidAlignment, index, alignment.Names[index])
+		}
+	}
+	return nil
+}
+
+// clustalStrongGroups and clustalWeakGroups are the groups of amino acids
+// Clustal marks with : and . when every character of a column is in the same group.
+var (
+	clustalStrongGroups = []string{"STA", "NEQK", "NHQK", "NDEQ", "QHRK", "MILV", "MILF", "HY", "FYW"}
+	clustalWeakGroups   = []string{"CSA", "ATV", "SAG", "STNK", "STPA", "SGND", "SNDEQK", "NDEQHK", "NEQHRK", "FVLIM", "HFY"}
+)
+
+// conservation returns the Clustal conservation mark of a column: * if every
+// character is identical, : or . if they're all in a strong or weak group and
+// a space otherwise, including whenever the column has a gap.
+func conservation(column string) byte {
+	column = strings.ToUpper(column)
+	if strings.Contains(column, "-") || strings.Contains(column, ".") {
+		return ' '
+	}
+	if strings.Count(column, column[:1]) == len(column) {
+		return '*'
+	}
+	inGroup := func(groups []string) bool {
+		for _, group := range groups {
+			if strings.Trim(column, group) == "" {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case inGroup(clustalStrongGroups):
+		return ':'
+	case inGroup(clustalWeakGroups):
+		return '.'
+	}
+	return ' '
+}
+
+// BuildClustal writes a multiple alignment in Clustal format, in blocks of 60
+// columns with the number of characters of each row so far at the end of each
+// line and a line of conservation marks under each block.
+func BuildClustal(alignment MultipleAlignment) ([]byte, error) {
+	if err := checkMultiple(alignment); err != nil {
+		return nil, err
+	}
+	nameWidth := 0
+	for _, name := range alignment.Names {
+		if len(name) > nameWidth {
+			nameWidth = len(name)
+		}
+	}
+	nameWidth += 6
+
+	const width = 60
+	var clustal bytes.Buffer
+	clustal.WriteString("CLUSTAL W multiple sequence alignment\n\n")
+	counts := make([]int, len(alignment.Rows))
+	for start := 0; start < alignment.Length(); start += width {
+		end := start + width
+		if end > alignment.Length() {
+			end = alignment.Length()
+		}
+		clustal.WriteString("\n")
+		for index, row := range alignment.Rows {
+			block := row[start:end]
+			counts[index] += len(block) - strings.Count(block, "-") - strings.Count(block, ".")
+			clustal.WriteString(fmt.Sprintf("%-*s%s %d\n", nameWidth, alignment.Names[index], block, counts[index]))
+		}
+		marks := make([]byte, end-start)
+		for column := start; column < end; column++ {
+			marks[column-start] = conservation(alignment.Column(column))
+		}
+		clustal.WriteString(strings.TrimRight(strings.Repeat(" ", nameWidth)+string(marks), " ") + "\n")
+	}
+	return clustal.Bytes(), nil
+}
+
+// BuildStockholm writes a multiple alignment in Stockholm format, the format
+// of Pfam and Rfam, with each row on a single line.
+func BuildStockholm(alignment MultipleAlignment) ([]byte, error) {
+	if err := checkMultiple(alignment); err != nil {
+		return nil, err
+	}
+	nameWidth := 0
+	for _, name := range alignment.Names {
+		if len(name) > nameWidth {
+			nameWidth = len(name)
+		}
+	}
+	var stockholm bytes.Buffer
+	stockholm.WriteString("# STOCKHOLM 1.0\n\n")
+	for index, row := range alignment.Rows {
+		stockholm.WriteString(fmt.Sprintf("%-*s %s\n", nameWidth, alignment.Names[index], row))
+	}
+	stockholm.WriteString("//\n")
+	return stockholm.Bytes(), nil
+}
+
+// BuildAlignedFasta writes a multiple alignment as FASTA records with gaps left in.
+func BuildAlignedFasta(alignment MultipleAlignment) ([]byte, error) {
+	if err := checkMultiple(alignment); err != nil {
+		return nil, err
+	}
+	return fasta.Build(alignment.Fasta())
+}
+
+/******************************************************************************
+
+Alignment writers end here.
+
+******************************************************************************/
@@ -0,0 +1,220 @@
+package align
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/******************************************************************************
+
+Substitution matrices begin here.
+
+A substitution matrix scores how likely one residue is to be swapped for
+another over evolutionary time. Nucleotides usually get a flat match and
+mismatch score while proteins use matrices like BLOSUM62, derived from blocks
+of conserved protein sequences, or PAM250, derived from closely related
+proteins extrapolated out to a distance of 250 accepted point mutations.
+
+******************************************************************************/
+
+// SubstitutionMatrix holds the score of aligning every pair of characters of an alphabet.
+// Scores are looked up without regard to case.
+type SubstitutionMatrix struct {
+	alphabet string
+	scores   [][]int
+	index    [256]int // the row of each character, or -1 if it isn't in the alphabet.
+}
+
+// ErrUnknownCharacter is returned when a sequence holds a character that isn't in the alphabet of a substitution matrix.
+var ErrUnknownCharacter = errors.New("character is not in the substitution matrix")
+
+// NewSubstitutionMatrix makes a substitution matrix where scores[i][j] is the
+// score of aligning the i-th character of alphabet to its j-th character.
+func NewSubstitutionMatrix(alphabet string, scores [][]int) (*SubstitutionMatrix, error) {
+	if len(scores) != len(alphabet) {
+		return nil, fmt.Errorf("substitution matrix has %d rows for an alphabet of %d characters", len(scores), len(alphabet))
+	}
+	matrix := &SubstitutionMatrix{alphabet: strings.ToUpper(alphabet), scores: scores}
+	for index := range matrix.index {
+		matrix.index[index] = -1
+	}
+	for row, character := range []byte(matrix.alphabet) {
+		if len(scores[row]) != len(alphabet) {
+			return nil, fmt.Errorf("row %c of substitution matrix has %d scores for an alphabet of %d characters", character, len(scores[row]), len(alphabet))
+		}
+		if matrix.index[character] >= 0 {
+			return nil, fmt.Errorf("character %c is in the substitution matrix alphabet twice", character)
+		}
+		matrix.index[character] = row
+		if lower := bytes.ToLower([]byte{character})[0]; lower != character {
+			matrix.index[lower] = row
+		}
+	}
+	return matrix, nil
+}
+
+// NewNucleotideMatrix makes a substitution matrix for DNA and RNA that scores
+// identical bases as match and everything else as mismatch. It covers every
+// IUPAC code, though ambiguous codes only match themselves.
+func NewNucleotideMatrix(match, mismatch int) *SubstitutionMatrix {
+	const alphabet = "ACGTURYSWKMBDHVN"
+	scores := make([][]int, len(alphabet))
+	for row := range scores {
+		scores[row] = make([]int, len(alphabet))
+		for column := range scores[row] {
+			scores[row][column] = mismatch
+		}
+		scores[row][row] = match
+	}
+	// T and U are the same base.
+	scores[3][4], scores[4][3] = match, match
+	matrix, _ := NewSubstitutionMatrix(alphabet, scores)
+	return matrix
+}
+
+// Alphabet returns the characters a substitution matrix can score, in upper case.
+func (matrix *SubstitutionMatrix) Alphabet() string {
+	return matrix.alphabet
+}
+
+// Score returns the score of aligning a to b.
+func (matrix *SubstitutionMatrix) Score(a, b byte) (int, error) {
+	row, column := matrix.index[a], matrix.index[b]
+	if row < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCharacter, a)
+	}
+	if column < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCharacter, b)
+	}
+	return matrix.scores[row][column], nil
+}
+
+// indices converts a sequence into rows of the matrix so that scores can be looked up without checking characters again.
+func (matrix *SubstitutionMatrix) indices(sequence string) ([]int, error) {
+	indices := make([]int, len(sequence))
+	for position := 0; position < len(sequence); position++ {
+		index := matrix.index[sequence[position]]
+		if index < 0 {
+			return nil, fmt.Errorf("%w: %q at position %d", ErrUnknownCharacter, sequence[position], position)
+		}
+		indices[position] = index
+	}
+	return indices, nil
+}
+
+// ParseMatrix parses a substitution matrix in the format NCBI distributes
+// them in, which is a header line of the alphabet followed by one row per
+// character starting with that character. Lines starting with # are comments.
+func ParseMatrix(file []byte) (*SubstitutionMatrix, error) {
+	var alphabet string
+	var scores [][]int
+	scanner := bufio.NewScanner(bytes.NewReader(file))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if alphabet == "" {
+			alphabet = strings.Join(fields, "")
+			if len(alphabet) != len(fields) {
+				return nil, fmt.Errorf("substitution matrix header %q should be single characters", scanner.Text())
+			}
+			continue
+		}
+		if len(fields[0]) != 1 || len(scores) >= len(alphabet) || fields[0][0] != alphabet[len(scores)] {
+			return nil, fmt.Errorf("substitution matrix row %q is out of order with the header %s", fields[0], alphabet)
+		}
+		row := make([]int, len(fields)-1)
+		for index, field := range fields[1:] {
+			score, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("substitution matrix row %s has a score of %q: %w", fields[0], field, err)
+			}
+			row[index] = score
+		}
+		scores = append(scores, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewSubstitutionMatrix(alphabet, scores)
+}
+
+// mustParseMatrix parses the matrices built into the package, which are known to be valid.
+func mustParseMatrix(text string) *SubstitutionMatrix {
+	matrix, err := ParseMatrix([]byte(text))
+	if err != nil {
+		panic(err)
+	}
+	return matrix
+}
+
+// BLOSUM62 is the BLOSUM62 protein substitution matrix, the default of BLAST.
+var BLOSUM62 = mustParseMatrix(`
+#  Matrix made by matblas from blosum62.iij
+   A  R  N  D  C  Q  E  G  H  I  L  K  M  F  P  S  T  W  Y  V  B  Z  X  *
+A  4 -1 -2 -2  0 -1 -1  0 -2 -1 -1 -1 -1 -2 -1  1  0 -3 -2  0 -2 -1  0 -4
+R -1  5  0 -2 -3  1  0 -2  0 -3 -2  2 -1 -3 -2 -1 -1 -3 -2 -3 -1  0 -1 -4
+N -2  0  6  1 -3  0  0  0  1 -3 -3  0 -2 -3 -2  1  0 -4 -2 -3  3  0 -1 -4
+D -2 -2  1  6 -3  0  2 -1 -1 -3 -4 -1 -3 -3 -1  0 -1 -4 -3 -3  4  1 -1 -4
+C  0 -3 -3 -3  9 -3 -4 -3 -3 -1 -1 -3 -1 -2 -3 -1 -1 -2 -2 -1 -3 -3 -2 -4
+Q -1  1  0  0 -3  5  2 -2  0 -3 -2  1  0 -3 -1  0 -1 -2 -1 -2  0  3 -1 -4
+E -1  0  0  2 -4  2  5 -2  0 -3 -3  1 -2 -3 -1  0 -1 -3 -2 -2  1  4 -1 -4
+G  0 -2  0 -1 -3 -2 -2  6 -2 -4 -4 -2 -3 -3 -2  0 -2 -2 -3 -3 -1 -2 -1 -4
+H -2  0  1 -1 -3  0  0 -2  8 -3 -3 -1 -2 -1 -2 -1 -2 -2  2 -3  0  0 -1 -4
+I -1 -3 -3 -3 -1 -3 -3 -4 -3  4  2 -3  1  0 -3 -2 -1 -3 -1  3 -3 -3 -1 -4
+L -1 -2 -3 -4 -1 -2 -3 -4 -3  2  4 -2  2  0 -3 -2 -1 -2 -1  1 -4 -3 -1 -4
+K -1  2  0 -1 -3  1  1 -2 -1 -3 -2  5 -1 -3 -1  0 -1 -3 -2 -2  0  1 -1 -4
+M -1 -1 -2 -3 -1  0 -2 -3 -2  1  2 -1  5  0 -2 -1 -1 -1 -1  1 -3 -1 -1 -4
+F -2 -3 -3 -3 -2 -3 -3 -3 -1  0  0 -3  0  6 -4 -2 -2  1  3 -1 -3 -3 -1 -4
+P -1 -2 -2 -1 -3 -1 -1 -2 -2 -3 -3 -1 -2 -4  7 -1 -1 -4 -3 -2 -2 -1 -2 -4
+S  1 -1  1  0 -1  0  0  0 -1 -2 -2  0 -1 -2 -1  4  1 -3 -2 -2  0  0  0 -4
+T  0 -1  0 -1 -1 -1 -1 -2 -2 -1 -1 -1 -1 -2 -1  1  5 -2 -2  0 -1 -1  0 -4
+W -3 -3 -4 -4 -2 -2 -3 -2 -2 -3 -2 -3 -1  1 -4 -3 -2 11  2 -3 -4 -3 -2 -4
+Y -2 -2 -2 -3 -2 -1 -2 -3  2 -1 -1 -2 -1  3 -3 -2 -2  2  7 -1 -3 -2 -1 -4
+V  0 -3 -3 -3 -1 -2 -2 -3 -3  3  1 -2  1 -1 -2 -2  0 -3 -1  4 -3 -2 -1 -4
+B -2 -1  3  4 -3  0  1 -1  0 -3 -4  0 -3 -3 -2  0 -1 -4 -3 -3  4  1 -1 -4
+Z -1  0  0  1 -3  3  4 -2  0 -3 -3  1 -1 -3 -1  0 -1 -3 -2 -2  1  4 -1 -4
+X  0 -1 -1 -1 -2 -1 -1 -1 -1 -1 -1 -1 -1 -1 -2  0  0 -2 -1 -1 -1 -1 -1 -4
+* -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4  1
+`)
+
+// PAM250 is the PAM250 protein substitution matrix, suited to distantly related proteins.
+var PAM250 = mustParseMatrix(`
+#  PAM 250 substitution matrix, scale = ln(2)/3 = 0.231049
+   A  R  N  D  C  Q  E  G  H  I  L  K  M  F  P  S  T  W  Y  V  B  Z  X  *
+A  2 -2  0  0 -2  0  0  1 -1 -1 -2 -1 -1 -3  1  1  1 -6 -3  0  0  0  0 -8
+R -2  6  0 -1 -4  1 -1 -3  2 -2 -3  3  0 -4  0  0 -1  2 -4 -2 -1  0 -1 -8
+N  0  0  2  2 -4  1  1  0  2 -2 -3  1 -2 -3  0  1  0 -4 -2 -2  2  1  0 -8
+D  0 -1  2  4 -5  2  3  1  1 -2 -4  0 -3 -6 -1  0  0 -7 -4 -2  3  3 -1 -8
+C -2 -4 -4 -5 12 -5 -5 -3 -3 -2 -6 -5 -5 -4 -3  0 -2 -8  0 -2 -4 -5 -3 -8
+Q  0  1  1  2 -5  4  2 -1  3 -2 -2  1 -1 -5  0 -1 -1 -5 -4 -2  1  3 -1 -8
+E  0 -1  1  3 -5  2  4  0  1 -2 -3  0 -2 -5 -1  0  0 -7 -4 -2  3  3 -1 -8
+G  1 -3  0  1 -3 -1  0  5 -2 -3 -4 -2 -3 -5  0  1  0 -7 -5 -1  0  0 -1 -8
+H -1  2  2  1 -3  3  1 -2  6 -2 -2  0 -2 -2  0 -1 -1 -3  0 -2  1  2 -1 -8
+I -1 -2 -2 -2 -2 -2 -2 -3 -2  5  2 -2  2  1 -2 -1  0 -5 -1  4 -2 -2 -1 -8
+L -2 -3 -3 -4 -6 -2 -3 -4 -2  2  6 -3  4  2 -3 -3 -2 -2 -1  2 -3 -3 -1 -8
+K -1  3  1  0 -5  1  0 -2  0 -2 -3  5  0 -5 -1  0  0 -3 -4 -2  1  0 -1 -8
+M -1  0 -2 -3 -5 -1 -2 -3 -2  2  4  0  6  0 -2 -2 -1 -4 -2  2 -2 -2 -1 -8
+F -3 -4 -3 -6 -4 -5 -5 -5 -2  1  2 -5  0  9 -5 -3 -3  0  7 -1 -4 -5 -2 -8
+P  1  0  0 -1 -3  0 -1  0  0 -2 -3 -1 -2 -5  6  1  0 -6 -5 -1 -1  0 -1 -8
+S  1  0  1  0  0 -1  0  1 -1 -1 -3  0 -2 -3  1  2  1 -2 -3 -1  0  0  0 -8
+T  1 -1  0  0 -2 -1  0  0 -1  0 -2  0 -1 -3  0  1  3 -5 -3  0  0 -1  0 -8
+W -6  2 -4 -7 -8 -5 -7 -7 -3 -5 -2 -3 -4  0 -6 -2 -5 17  0 -6 -5 -6 -4 -8
+Y -3 -4 -2 -4  0 -4 -4 -5  0 -1 -1 -4 -2  7 -5 -3 -3  0 10 -2 -3 -4 -2 -8
+V  0 -2 -2 -2 -2 -2 -2 -1 -2  4  2 -2  2 -1 -1 -1  0 -6 -2  4 -2 -2 -1 -8
+B  0 -1  2  3 -4  1  3  0  1 -2 -3  1 -2 -4 -1  0  0 -5 -3 -2  3  2 -1 -8
+Z  0  0  1  3 -5  3  3  0  2 -2 -3  0 -2 -5  0  0 -1 -6 -4 -2  2  3 -1 -8
+X  0 -1  0 -1 -3 -1 -1 -1 -1 -1 -1 -1 -1 -2 -1  0  0 -4 -2 -1 -1 -1 -1 -8
+* -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8  1
+`)
+
+/******************************************************************************
+
+Substitution matrices end here.
+
+******************************************************************************/
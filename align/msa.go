@@ -0,0 +1,295 @@
+package align
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/TimothyStiles/poly/io/fasta"
+	"github.com/TimothyStiles/poly/transform"
+)
+
+/******************************************************************************
+
+Multiple sequence alignment begins here.
+
+MSA builds a progressive alignment the same way ClustalW and MUSCLE's first
+stage do:
+
+1. Every pair of sequences is given a distance from their k-mer profiles,
+   which is far quicker than aligning every pair and good enough to decide
+   which sequences are most alike.
+
+2. A guide tree is built from those distances with UPGMA, which repeatedly
+   joins the two closest clusters.
+
+3. Following the tree from its leaves, each pair of clusters is aligned as two
+   profiles, with each column scored as the average substitution score of
+   every pair of characters between them, and merged into one alignment.
+
+Gaps added when two clusters are merged are never taken out again ("once a gap,
+always a gap"), which is what makes progressive alignment fast but also means
+it isn't guaranteed to find the best possible multiple alignment.
+
+******************************************************************************/
+
+// MultipleAlignment is an alignment of several sequences. Every row has the
+// same length, with gaps written as "-", so that the characters of a column
+// are aligned to each other.
+type MultipleAlignment struct {
+	Names []string `json:"names"`
+	Rows  []string `json:"rows"`
+}
+
+// Length returns the number of columns of the alignment.
+func (alignment MultipleAlignment) Length() int {
+	if len(alignment.Rows) == 0 {
+		return 0
+	}
+	return len(alignment.Rows[0])
+}
+
+// Column returns the characters of every row at a column of the alignment.
+func (alignment MultipleAlignment) Column(index int) string {
+	column := make([]byte, len(alignment.Rows))
+	for row, sequence := range alignment.Rows {
+		column[row] = sequence[index]
+	}
+	return string(column)
+}
+
+// Fasta returns the rows of the alignment as aligned fasta records, ready for
+// fasta.Write or fasta.Consensus.
+func (alignment MultipleAlignment) Fasta() []fasta.Fasta {
+	records := make([]fasta.Fasta, len(alignment.Rows))
+	for index, row := range alignment.Rows {
+		records[index] = fasta.Fasta{Name: alignment.Names[index], Sequence: row}
+	}
+	return records
+}
+
+// guideTreeKmerLength is the k used to compare sequences when building the guide tree.
+const guideTreeKmerLength = 3
+
+// MSA aligns several sequences progressively along a guide tree built from
+// their k-mer distances. The rows of the result are in the same order as
+// sequences.
+func MSA(sequences []fasta.Fasta, scoring Scoring) (MultipleAlignment, error) {
+	if scoring.Matrix == nil || scoring.GapOpen < 0 || scoring.GapExtend < 0 {
+		return MultipleAlignment{}, fmt.Errorf("%w: scoring needs a substitution matrix and gap penalties of 0 or more", ErrInvalidScoring)
+	}
+	if len(sequences) == 0 {
+		return MultipleAlignment{}, errors.New("no sequences to align")
+	}
+
+	alignment := MultipleAlignment{Names: make([]string, len(sequences)), Rows: make([]string, len(sequences))}
+	profiles := make([]map[string]int, len(sequences))
+	clusters := make([]*cluster, len(sequences))
+	for index, sequence := range sequences {
+		if _, err := scoring.Matrix.indices(sequence.Sequence); err != nil {
+			return MultipleAlignment{}, fmt.Errorf("sequence %s: %w", sequence.Name, err)
+		}
+		profiles[index], _ = transform.KmerProfile(sequence.Sequence, guideTreeKmerLength)
+		clusters[index] = &cluster{members: []int{index}, rows: []string{sequence.Sequence}}
+		alignment.Names[index] = sequence.Name
+	}
+
+	distances := make([][]float64, len(sequences))
+	for i := range distances {
+		distances[i] = make([]float64, len(sequences))
+		for j := 0; j < i; j++ {
+			distances[i][j] = transform.KmerDistance(profiles[i], profiles[j])
+			distances[j][i] = distances[i][j]
+		}
+	}
+
+	root := upgma(clusters, distances, func(a, b *cluster) *cluster {
+		return mergeClusters(a, b, scoring)
+	})
+	for index, member := range root.members {
+		alignment.Rows[member] = root.rows[index]
+	}
+	return alignment, nil
+}
+
+// cluster is a group of sequences that have been aligned to each other.
+type cluster struct {
+	members []int    // the indices of the cluster's sequences in the input.
+	rows    []string // the aligned sequences, in the same order as members.
+}
+
+// upgma joins the closest pair of clusters until only one is left, calling
+// merge on each pair to make the joined cluster. The distance from a joined
+// cluster to the others is the average of its two halves weighted by their size.
+func upgma(clusters []*cluster, distances [][]float64, merge func(a, b *cluster) *cluster) *cluster {
+	active := make([]bool, len(clusters))
+	for index := range active {
+		active[index] = true
+	}
+	for remaining := len(clusters); remaining > 1; remaining-- {
+		closestI, closestJ := -1, -1
+		for i := range clusters {
+			for j := i + 1; j < len(clusters); j++ {
+				if active[i] && active[j] && (closestI < 0 || distances[i][j] < distances[closestI][closestJ]) {
+					closestI, closestJ = i, j
+				}
+			}
+		}
+		sizeI, sizeJ := float64(len(clusters[closestI].members)), float64(len(clusters[closestJ].members))
+		for k := range clusters {
+			if active[k] && k != closestI && k != closestJ {
+				distances[closestI][k] = (distances[closestI][k]*sizeI + distances[closestJ][k]*sizeJ) / (sizeI + sizeJ)
+				distances[k][closestI] = distances[closestI][k]
+			}
+		}
+		clusters[closestI] = merge(clusters[closestI], clusters[closestJ])
+		active[closestJ] = false
+	}
+	for index, isActive := range active {
+		if isActive {
+			return clusters[index]
+		}
+	}
+	return nil
+}
+
+// profileColumn holds, for one column of a cluster, how many of its rows have
+// each character of the substitution matrix's alphabet.
+type profileColumn []float64
+
+// makeProfile counts the characters of each column of a cluster.
+func makeProfile(rows []string, matrix *SubstitutionMatrix) []profileColumn {
+	profile := make([]profileColumn, len(rows[0]))
+	for column := range profile {
+		profile[column] = make(profileColumn, len(matrix.alphabet))
+		for _, row := range rows {
+			if row[column] != '-' {
+				profile[column][matrix.index[row[column]]]++
+			}
+		}
+	}
+	return profile
+}
+
+// mergeClusters aligns two clusters as profiles using Gotoh's algorithm and
+// returns the merged cluster.
+func mergeClusters(a, b *cluster, scoring Scoring) *cluster {
+	profileA, profileB := makeProfile(a.rows, scoring.Matrix), makeProfile(b.rows, scoring.Matrix)
+	pairs := float64(len(a.rows) * len(b.rows))
+
+	// expected[i][y] is the score of profileA's column i against a single character y.
+	expected := make([][]float64, len(profileA))
+	for i, column := range profileA {
+		expected[i] = make([]float64, len(scoring.Matrix.alphabet))
+		for x, count := range column {
+			if count == 0 {
+				continue
+			}
+			for y := range expected[i] {
+				expected[i][y] += count * float64(scoring.Matrix.scores[x][y])
+			}
+		}
+	}
+	columnScore := func(i, j int) float64 {
+		var total float64
+		for y, count := range profileB[j] {
+			if count != 0 {
+				total += count * expected[i][y]
+			}
+		}
+		return total / pairs
+	}
+
+	rows, columns := len(profileA)+1, len(profileB)+1
+	open, extend := float64(scoring.GapOpen), float64(scoring.GapExtend)
+	traceback := make([][]byte, rows)
+	for i := range traceback {
+		traceback[i] = make([]byte, columns)
+	}
+	previousMatch, previousGapA, previousGapB := make([]float64, columns), make([]float64, columns), make([]float64, columns)
+	match, gapA, gapB := make([]float64, columns), make([]float64, columns), make([]float64, columns)
+	previousGapA[0], previousGapB[0] = negativeInfinity, negativeInfinity
+	for j := 1; j < columns; j++ {
+		previousMatch[j], previousGapA[j] = negativeInfinity, negativeInfinity
+		previousGapB[j] = -open - float64(j-1)*extend
+		traceback[0][j] = gapBState << 4
+	}
+	for i := 1; i < rows; i++ {
+		match[0], gapB[0] = negativeInfinity, negativeInfinity
+		gapA[0] = -open - float64(i-1)*extend
+		traceback[i][0] = gapAState << 2
+		for j := 1; j < columns; j++ {
+			matchFrom, matchScore := bestOfFloat(previousMatch[j-1], previousGapA[j-1], previousGapB[j-1])
+			match[j] = matchScore + columnScore(i-1, j-1)
+			gapAFrom, gapAScore := bestOfFloat(previousMatch[j]-open, previousGapA[j]-extend, previousGapB[j]-open)
+			gapA[j] = gapAScore
+			gapBFrom, gapBScore := bestOfFloat(match[j-1]-open, gapA[j-1]-open, gapB[j-1]-extend)
+			gapB[j] = gapBScore
+			traceback[i][j] = matchFrom | gapAFrom<<2 | gapBFrom<<4
+		}
+		previousMatch, match = match, previousMatch
+		previousGapA, gapA = gapA, previousGapA
+		previousGapB, gapB = gapB, previousGapB
+	}
+
+	// walk back through the traceback, recording which columns of a and b make up each merged column.
+	i, j := len(profileA), len(profileB)
+	state, _ := bestOfFloat(previousMatch[j], previousGapA[j], previousGapB[j])
+	var columnsA, columnsB []int // -1 for a column of gaps.
+	for i > 0 || j > 0 {
+		previous := traceback[i][j] >> (2 * state) & 3
+		switch state {
+		case matchState:
+			columnsA, columnsB = append(columnsA, i-1), append(columnsB, j-1)
+			i, j = i-1, j-1
+		case gapAState:
+			columnsA, columnsB = append(columnsA, i-1), append(columnsB, -1)
+			i--
+		case gapBState:
+			columnsA, columnsB = append(columnsA, -1), append(columnsB, j-1)
+			j--
+		}
+		state = previous
+	}
+
+	merged := &cluster{members: append(append([]int{}, a.members...), b.members...)}
+	for _, row := range a.rows {
+		merged.rows = append(merged.rows, gappedRow(row, columnsA))
+	}
+	for _, row := range b.rows {
+		merged.rows = append(merged.rows, gappedRow(row, columnsB))
+	}
+	return merged
+}
+
+// bestOfFloat is bestOf for the fractional scores of profiles.
+func bestOfFloat(matchScore, gapAScore, gapBScore float64) (byte, float64) {
+	state, score := matchState, matchScore
+	if gapAScore > score {
+		state, score = gapAState, gapAScore
+	}
+	if gapBScore > score {
+		state, score = gapBState, gapBScore
+	}
+	return state, score
+}
+
+// gappedRow builds a merged row from the reversed list of columns of its
+// cluster that make up each merged column.
+func gappedRow(row string, columns []int) string {
+	var gapped strings.Builder
+	for index := len(columns) - 1; index >= 0; index-- {
+		if columns[index] < 0 {
+			gapped.WriteByte('-')
+		} else {
+			gapped.WriteByte(row[columns[index]])
+		}
+	}
+	return gapped.String()
+}
+
+/******************************************************************************
+
+Multiple sequence alignment ends here.
+
+******************************************************************************/
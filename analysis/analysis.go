@@ -0,0 +1,204 @@
+/*
+Package analysis calculates statistics of DNA sequences.
+
+These are the numbers you look at to get a feel for a sequence before doing
+anything else with it: its GC content, how G and C (or A and T) are split
+between the two strands along its length, and which k-mers it's made of.
+
+Every function takes a plain string, so sequences read with any of the io
+packages can be passed in with their GetSequence method. Case is ignored.
+
+GC skew, (G-C)/(G+C), flips sign at the origin and terminus of replication of
+most bacterial chromosomes because the leading strand is richer in G than the
+lagging strand. Summing the skew of each window along the chromosome gives a
+cumulative skew that bottoms out at the origin and peaks at the terminus,
+which is how ReplicationOrigin predicts them [Grigoriev A (1998) Nucleic Acids
+Res, doi:10.1093/nar/26.10.2286].
+
+TmProfile does the same sliding window scan with melting temperatures and
+free energies, giving the stability landscape of a sequence for picking
+primers or spotting regions that will be hard to synthesize.
+*/
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/TimothyStiles/poly/checks"
+	"github.com/TimothyStiles/poly/primers"
+	"github.com/TimothyStiles/poly/transform"
+)
+
+// GCContent returns the fraction of a sequence that is G or C, like
+// checks.GcContent except that an empty sequence has a GC content of 0.
+func GCContent(sequence string) float64 {
+	if len(sequence) == 0 {
+		return 0
+	}
+	return checks.GcContent(sequence)
+}
+
+// GCContentWindows returns the GC content of every window bases long window of
+// a sequence, moving step bases along the sequence between windows. A trailing
+// stretch shorter than window is left out. It is checks.GCContentWindows,
+// gathered here with the other sequence statistics.
+func GCContentWindows(sequence string, window, step int) ([]float64, error) {
+	return checks.GCContentWindows(sequence, window, step)
+}
+
+// GCSkew returns the GC skew, (G-C)/(G+C), of every window of a sequence.
+// Windows are taken like GCContentWindows and windows without any G or C have
+// a skew of 0, as calculated by checks.GCSkew.
+func GCSkew(sequence string, window, step int) ([]float64, error) {
+	return checks.GCSkew(sequence, window, step)
+}
+
+// ATSkew returns the AT skew, (A-T)/(A+T), of every window of a sequence.
+// Windows are taken like GCContentWindows, U is counted as T and windows
+// without any A or T have a skew of 0.
+func ATSkew(sequence string, window, step int) ([]float64, error) {
+	// the AT skew of a sequence is the GC skew of the same sequence with A and T
+	// standing in for G and C.
+	return checks.GCSkew(strings.Map(func(base rune) rune {
+		switch unicode.ToUpper(base) {
+		case 'A':
+			return 'G'
+		case 'T', 'U':
+			return 'C'
+		}
+		return 'N'
+	}, sequence), window, step)
+}
+
+// CumulativeSkew returns the running total of a skew profile from GCSkew or
+// ATSkew, so the value at each window is the sum of its skew and the skew of
+// every window before it.
+func CumulativeSkew(skews []float64) []float64 {
+	cumulative := make([]float64, len(skews))
+	var total float64
+	for index, skew := range skews {
+		total += skew
+		cumulative[index] = total
+	}
+	return cumulative
+}
+
+// ReplicationOrigin predicts the origin and terminus of replication of a
+// bacterial chromosome from where its cumulative GC skew is lowest and highest.
+// Both are returned as the 0-based position of the end of the window where the
+// extreme is reached. Windows of a few kilobases with a step of a few hundred
+// bases work well for whole chromosomes.
+func ReplicationOrigin(sequence string, window, step int) (origin int, terminus int, err error) {
+	skews, err := GCSkew(sequence, window, step)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var minimumIndex, maximumIndex int
+	cumulative := CumulativeSkew(skews)
+	for index, total := range cumulative {
+		if total < cumulative[minimumIndex] {
+			minimumIndex = index
+		}
+		if total > cumulative[maximumIndex] {
+			maximumIndex = index
+		}
+	}
+	return minimumIndex*step + window, maximumIndex*step + window, nil
+}
+
+// KmerCounts counts how many times each k-mer (substring of length k) occurs in
+// a sequence. K-mers are counted by transform.KmerProfile, except that U is
+// counted as T, k-mers with anything other than A, C, G or T in them, like N or
+// a gap, are skipped and a sequence shorter than k has no k-mers. If canonical
+// is true each k-mer is counted under whichever of itself and its reverse
+// complement sorts first, so a sequence and its reverse complement have the
+// same counts.
+func KmerCounts(sequence string, k int, canonical bool) (map[string]int, error) {
+	// a sequence shorter than k has no k-mers rather than being an error.
+	if k > len(sequence) {
+		return map[string]int{}, nil
+	}
+	counts, err := transform.KmerProfile(strings.ReplaceAll(strings.ToUpper(sequence), "U", "T"), k, canonical)
+	if err != nil {
+		return nil, err
+	}
+	for kmer := range counts {
+		if strings.Trim(kmer, "ACGT") != "" {
+			delete(counts, kmer)
+		}
+	}
+	return counts, nil
+}
+
+// KmerFrequencies returns the fraction of a sequence's k-mers that each k-mer
+// makes up. K-mers are counted like KmerCounts.
+func KmerFrequencies(sequence string, k int, canonical bool) (map[string]float64, error) {
+	counts, err := KmerCounts(sequence, k, canonical)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int
+	for _, count := range counts {
+		total += count
+	}
+	frequencies := make(map[string]float64, len(counts))
+	for kmer, count := range counts {
+		frequencies[kmer] = float64(count) / float64(total)
+	}
+	return frequencies, nil
+}
+
+// TmWindow is the melting temperature and stability of one window of a TmProfile.
+type TmWindow struct {
+	Start       int     // 0-based position of the first base of the window.
+	MeltingTemp float64 // melting temperature in °C.
+	DeltaG      float64 // nearest neighbor free energy of hybridization in kcal/mol at 37°C.
+}
+
+// TmProfile returns the melting temperature and free energy of every window
+// bases long window of a sequence, moving step bases along the sequence between
+// windows, to show which stretches of a long sequence are stable enough to
+// prime from and which are too AT or GC rich. Melting temperatures are
+// calculated by primers.MeltingTempWithOptions under the given conditions while
+// DeltaG always uses SantaLucia's nearest neighbor parameters, and the more
+// negative it is the more stable the window. Windows with anything other than
+// A, C, G or T in them are left out, so check Start rather than assuming
+// windows are evenly spaced.
+func TmProfile(sequence string, window, step int, options primers.TmOptions) ([]TmWindow, error) {
+	if window < 2 || step <= 0 {
+		return nil, fmt.Errorf("window (%d) must be at least 2 and step (%d) positive", window, step)
+	}
+	if window > len(sequence) {
+		return nil, fmt.Errorf("window of %d is longer than the sequence of length %d", window, len(sequence))
+	}
+	// the same default sodium concentration as primers.MeltingTempWithOptions.
+	saltConcentration := options.SaltConcentration
+	if saltConcentration == 0 {
+		saltConcentration = 50e-3
+	}
+
+	sequence = strings.ToUpper(sequence)
+	var profile []TmWindow
+	for start := 0; start+window <= len(sequence); start += step {
+		windowSequence := sequence[start : start+window]
+		meltingTemp, err := primers.MeltingTempWithOptions(windowSequence, options)
+		if err != nil {
+			continue
+		}
+		// dG is independent of primer concentration, which only matters for Tm.
+		_, dH, dS := primers.SantaLucia(windowSequence, 1, saltConcentration, options.MagnesiumConcentration)
+		profile = append(profile, TmWindow{
+			Start:       start,
+			MeltingTemp: meltingTemp,
+			DeltaG:      dH - bodyTemperature*dS/1000,
+		})
+	}
+	return profile, nil
+}
+
+// bodyTemperature is 37°C in kelvin, the temperature free energies are given at.
+const bodyTemperature = 310.15
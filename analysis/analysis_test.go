@@ -0,0 +1,145 @@
+package analysis_test
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/TimothyStiles/poly/analysis"
+	"github.com/TimothyStiles/poly/primers"
+	"github.com/TimothyStiles/poly/transform"
+)
+
+func TestGCContent(t *testing.T) {
+	if content := analysis.GCContent("ggTATC"); content != 0.5 {
+		t.Errorf("expected 0.5, got %f", content)
+	}
+	if content := analysis.GCContent(""); content != 0 {
+		t.Errorf("expected an empty sequence to have a GC content of 0, got %f", content)
+	}
+
+	content, err := analysis.GCContentWindows("GGGGATATCCCCAT", 4, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(content) != "[1 0 1]" {
+		t.Errorf("expected GC content [1 0 1], got %v", content)
+	}
+}
+
+func TestSkew(t *testing.T) {
+	gcSkew, err := analysis.GCSkew("ggggATATCCCC", 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(gcSkew) != "[1 1 0 -1 -1]" {
+		t.Errorf("expected GC skew [1 1 0 -1 -1], got %v", gcSkew)
+	}
+
+	// U counts as T.
+	atSkew, err := analysis.ATSkew("AAAUGCGCTTTA", 4, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(atSkew) != "[0.5 0 -0.5]" {
+		t.Errorf("expected AT skew [0.5 0 -0.5], got %v", atSkew)
+	}
+
+	for _, test := range []struct{ window, step int }{{0, 1}, {1, 0}, {-1, 1}, {13, 1}} {
+		if _, err := analysis.ATSkew("ggggATATCCCC", test.window, test.step); err == nil {
+			t.Errorf("expected an error for window %d and step %d", test.window, test.step)
+		}
+	}
+
+	if cumulative := analysis.CumulativeSkew([]float64{1, -0.5, -1, 0.5}); fmt.Sprint(cumulative) != "[1 0.5 -0.5 0]" {
+		t.Errorf("expected cumulative skew [1 0.5 -0.5 0], got %v", cumulative)
+	}
+}
+
+func TestReplicationOrigin(t *testing.T) {
+	// a chromosome whose leading strands are G rich, with the origin at 300
+	// and the terminus at 900.
+	leading := strings.Repeat("GGGCATAT", 75)
+	lagging := transform.ReverseComplement(leading)
+	chromosome := lagging[:300] + leading + lagging[300:]
+
+	origin, terminus, err := analysis.ReplicationOrigin(chromosome, 100, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if origin != 300 || terminus != 900 {
+		t.Errorf("expected an origin at 300 and terminus at 900, got %d and %d", origin, terminus)
+	}
+
+	if _, _, err := analysis.ReplicationOrigin("GGGC", 100, 50); err == nil {
+		t.Errorf("expected an error for a window longer than the sequence")
+	}
+}
+
+func TestKmerCounts(t *testing.T) {
+	counts, err := analysis.KmerCounts("acgtNacgu", 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the N splits the sequence and U counts as T.
+	expected := map[string]int{"AC": 2, "CG": 2, "GT": 2}
+	if fmt.Sprint(counts) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, counts)
+	}
+
+	// a sequence and its reverse complement have the same canonical counts.
+	sequence := "GATTACAGGCATTT"
+	forward, _ := analysis.KmerCounts(sequence, 3, true)
+	reverse, _ := analysis.KmerCounts(transform.ReverseComplement(sequence), 3, true)
+	if fmt.Sprint(forward) != fmt.Sprint(reverse) {
+		t.Errorf("expected canonical counts %v and %v to match", forward, reverse)
+	}
+
+	if counts, _ := analysis.KmerCounts("ACG", 4, false); len(counts) != 0 {
+		t.Errorf("expected no k-mers longer than the sequence, got %v", counts)
+	}
+	if _, err := analysis.KmerCounts("ACG", 0, false); err == nil {
+		t.Errorf("expected an error for a k of 0")
+	}
+}
+
+func TestKmerFrequencies(t *testing.T) {
+	frequencies, err := analysis.KmerFrequencies("AAAT", 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(frequencies["AA"]-2.0/3) > 1e-9 || math.Abs(frequencies["AT"]-1.0/3) > 1e-9 {
+		t.Errorf("expected AA at 2/3 and AT at 1/3, got %v", frequencies)
+	}
+
+	if _, err := analysis.KmerFrequencies("AAAT", -1, true); err == nil {
+		t.Errorf("expected an error for a negative k")
+	}
+}
+
+func TestTmProfile(t *testing.T) {
+	sequence := "ATATATATATATATATATATNNGCGCGGCCGCGGCGCCGCGCGG"
+	profile, err := analysis.TmProfile(sequence, 20, 2, primers.TmOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// windows 2 to 20 overlap the Ns.
+	if len(profile) != 3 || profile[0].Start != 0 || profile[1].Start != 22 || profile[2].Start != 24 {
+		t.Fatalf("expected windows at 0, 22 and 24, got %v", profile)
+	}
+	if profile[1].MeltingTemp <= profile[0].MeltingTemp || profile[1].DeltaG >= profile[0].DeltaG {
+		t.Errorf("expected the GC rich window to be more stable than the AT rich one, got %v", profile)
+	}
+
+	// melting temperatures should match primers.MeltingTemp with default options.
+	if expected := primers.MeltingTemp(sequence[24:44]); math.Abs(profile[2].MeltingTemp-expected) > 1e-9 {
+		t.Errorf("expected a melting temperature of %f, got %f", expected, profile[2].MeltingTemp)
+	}
+
+	for _, test := range []struct{ window, step int }{{1, 1}, {2, 0}, {45, 1}} {
+		if _, err := analysis.TmProfile(sequence, test.window, test.step, primers.TmOptions{}); err == nil {
+			t.Errorf("expected an error for window %d and step %d", test.window, test.step)
+		}
+	}
+}
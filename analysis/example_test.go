@@ -0,0 +1,55 @@
+package analysis_test
+
+import (
+	"fmt"
+
+	"github.com/TimothyStiles/poly/analysis"
+	"github.com/TimothyStiles/poly/io/genbank"
+	"github.com/TimothyStiles/poly/primers"
+)
+
+// This example shows how to get statistics of a sequence read from a file.
+func Example_basic() {
+	puc19, _ := genbank.Read("../data/puc19.gbk")
+	sequence := puc19.GetSequence()
+
+	counts, _ := analysis.KmerCounts(sequence, 6, true)
+
+	fmt.Printf("%.3f %d\n", analysis.GCContent(sequence), counts["GAATTC"])
+	// Output: 0.506 1
+}
+
+func ExampleGCSkew() {
+	skew, _ := analysis.GCSkew("GGGCAAAACCCG", 4, 4)
+
+	fmt.Println(skew)
+	// Output: [0.5 0 -0.5]
+}
+
+func ExampleCumulativeSkew() {
+	skew, _ := analysis.GCSkew("CCCGAAAAGGGCGGGG", 4, 4)
+
+	fmt.Println(analysis.CumulativeSkew(skew))
+	// Output: [-0.5 -0.5 0 1]
+}
+
+func ExampleKmerFrequencies() {
+	// AT and TA are both their own reverse complements so stay apart.
+	frequencies, _ := analysis.KmerFrequencies("ATAT", 2, true)
+
+	fmt.Printf("%.2f %.2f\n", frequencies["AT"], frequencies["TA"])
+	// Output: 0.67 0.33
+}
+
+func ExampleTmProfile() {
+	sequence := "ATATATATATATATATATATGCGCGGCCGCGGCGCCGCGCGG"
+	profile, _ := analysis.TmProfile(sequence, 20, 11, primers.TmOptions{})
+
+	for _, window := range profile {
+		fmt.Printf("%d %.1f °C %.1f kcal/mol\n", window.Start, window.MeltingTemp, window.DeltaG)
+	}
+	// Output:
+	// 0 28.4 °C -5.1 kcal/mol
+	// 11 57.9 °C -20.1 kcal/mol
+	// 22 78.7 °C -31.4 kcal/mol
+}
@@ -4,6 +4,7 @@ Package checks provides utilities to check for certain properties of a sequence.
 package checks
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/TimothyStiles/poly/transform"
@@ -23,3 +24,44 @@ func GcContent(sequence string) float64 {
 	GuanineAndCytosinePercentage := float64(GuanineCount+CytosineCount) / float64(len(sequence))
 	return GuanineAndCytosinePercentage
 }
+
+// GCContentWindows returns the GC content of every window bases long window
+// of a sequence, moving step bases along the sequence between windows. A
+// trailing stretch shorter than window is left out.
+func GCContentWindows(sequence string, window, step int) ([]float64, error) {
+	return slidingWindows(sequence, window, step, GcContent)
+}
+
+// GCSkew returns the GC skew, (G-C)/(G+C), of every window bases long window
+// of a sequence, moving step bases along the sequence between windows. Windows
+// without any G or C have a skew of 0.
+//
+// GC skew flips sign at the origin and terminus of replication in most
+// bacteria, so summing the skew of each window into a cumulative skew gives a
+// curve whose minimum and maximum point at the origin and terminus.
+func GCSkew(sequence string, window, step int) ([]float64, error) {
+	return slidingWindows(sequence, window, step, func(windowSequence string) float64 {
+		guanineCount := strings.Count(windowSequence, "G")
+		cytosineCount := strings.Count(windowSequence, "C")
+		if guanineCount+cytosineCount == 0 {
+			return 0
+		}
+		return float64(guanineCount-cytosineCount) / float64(guanineCount+cytosineCount)
+	})
+}
+
+// slidingWindows applies a function to every window of an uppercased sequence.
+func slidingWindows(sequence string, window, step int, function func(string) float64) ([]float64, error) {
+	if window <= 0 || step <= 0 {
+		return nil, fmt.Errorf("window (%d) and step (%d) must both be positive", window, step)
+	}
+	if window > len(sequence) {
+		return nil, fmt.Errorf("window of %d is longer than the sequence of length %d", window, len(sequence))
+	}
+	sequence = strings.ToUpper(sequence)
+	values := make([]float64, 0, (len(sequence)-window)/step+1)
+	for start := 0; start+window <= len(sequence); start += step {
+		values = append(values, function(sequence[start:start+window]))
+	}
+	return values, nil
+}
@@ -1,6 +1,7 @@
 package checks_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/TimothyStiles/poly/checks"
@@ -24,3 +25,43 @@ func TestGcContent(t *testing.T) {
 		t.Errorf("GcContent did not properly calculate GC content")
 	}
 }
+
+func ExampleGCSkew() {
+	skew, _ := checks.GCSkew("GGGCAAAACCCG", 4, 4)
+
+	fmt.Println(skew)
+	// Output: [0.5 0 -0.5]
+}
+
+func TestGCSkew(t *testing.T) {
+	skew, err := checks.GCSkew("ggggATATCCCC", 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []float64{1, 1, 0, -1, -1}
+	if fmt.Sprint(skew) != fmt.Sprint(expected) {
+		t.Errorf("expected skew %v, got %v", expected, skew)
+	}
+
+	for _, test := range []struct{ window, step int }{{0, 1}, {1, 0}, {-1, 1}, {13, 1}} {
+		if _, err := checks.GCSkew("ggggATATCCCC", test.window, test.step); err == nil {
+			t.Errorf("expected an error for window %d and step %d", test.window, test.step)
+		}
+	}
+}
+
+func TestGCContentWindows(t *testing.T) {
+	// the trailing 2 bases don't fill a window and are left out.
+	content, err := checks.GCContentWindows("GGGGATATCCCCAT", 4, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []float64{1, 0, 1}
+	if fmt.Sprint(content) != fmt.Sprint(expected) {
+		t.Errorf("expected GC content %v, got %v", expected, content)
+	}
+
+	if _, err := checks.GCContentWindows("GGGG", 5, 1); err == nil {
+		t.Errorf("expected an error for a window longer than the sequence")
+	}
+}
@@ -70,10 +70,14 @@ type Overhang struct {
 }
 
 // Fragment is a struct that represents linear DNA sequences with sticky ends.
+// The overhang types tell 5' and 3' overhangs with the same bases apart, since
+// they can't be ligated to each other. Ends without an overhang are Blunt.
 type Fragment struct {
-	Sequence        string
-	ForwardOverhang string
-	ReverseOverhang string
+	Sequence            string
+	ForwardOverhang     string
+	ReverseOverhang     string
+	ForwardOverhangType OverhangType
+	ReverseOverhangType OverhangType
 }
 
 // Enzyme is a struct that represents restriction enzymes.
@@ -84,6 +88,47 @@ type Enzyme struct {
 	Skip            int
 	OverhangLen     int
 	RecognitionSite string
+	TopCut          int // cut position on the top strand, relative to the start of the recognition site
+	BottomCut       int // cut position on the bottom strand, in top strand coordinates relative to the start of the recognition site
+}
+
+// OverhangType is the kind of end an enzyme leaves.
+type OverhangType int
+
+const (
+	Blunt              OverhangType = iota // both strands are cut at the same place.
+	FivePrimeOverhang                      // the top strand is cut before the bottom strand, leaving the 5' end single stranded.
+	ThreePrimeOverhang                     // the top strand is cut after the bottom strand, leaving the 3' end single stranded.
+)
+
+// String returns the name of an overhang type.
+func (overhangType OverhangType) String() string {
+	switch overhangType {
+	case FivePrimeOverhang:
+		return "5' overhang"
+	case ThreePrimeOverhang:
+		return "3' overhang"
+	}
+	return "blunt"
+}
+
+// OverhangType returns the kind of end the enzyme leaves.
+func (enzyme Enzyme) OverhangType() OverhangType {
+	switch {
+	case enzyme.TopCut < enzyme.BottomCut:
+		return FivePrimeOverhang
+	case enzyme.TopCut > enzyme.BottomCut:
+		return ThreePrimeOverhang
+	}
+	return Blunt
+}
+
+// OverhangLength returns the length of the single stranded overhang the enzyme leaves. It is 0 for blunt cutters.
+func (enzyme Enzyme) OverhangLength() int {
+	if enzyme.TopCut > enzyme.BottomCut {
+		return enzyme.TopCut - enzyme.BottomCut
+	}
+	return enzyme.BottomCut - enzyme.TopCut
 }
 
 /******************************************************************************
@@ -98,9 +143,9 @@ func getBaseRestrictionEnzymes() map[string]Enzyme {
 	enzymeMap := make(map[string]Enzyme)
 
 	// Build default enzymes
-	enzymeMap["BsaI"] = Enzyme{"BsaI", regexp.MustCompile("GGTCTC"), regexp.MustCompile("GAGACC"), 1, 4, "GGTCTC"}
-	enzymeMap["BbsI"] = Enzyme{"BbsI", regexp.MustCompile("GAAGAC"), regexp.MustCompile("GTCTTC"), 2, 4, "GAAGAC"}
-	enzymeMap["BtgZI"] = Enzyme{"BtgZI", regexp.MustCompile("GCGATG"), regexp.MustCompile("CATCGC"), 10, 4, "GCGATG"}
+	enzymeMap["BsaI"] = Enzyme{"BsaI", regexp.MustCompile("GGTCTC"), regexp.MustCompile("GAGACC"), 1, 4, "GGTCTC", 7, 11}
+	enzymeMap["BbsI"] = Enzyme{"BbsI", regexp.MustCompile("GAAGAC"), regexp.MustCompile("GTCTTC"), 2, 4, "GAAGAC", 8, 12}
+	enzymeMap["BtgZI"] = Enzyme{"BtgZI", regexp.MustCompile("GCGATG"), regexp.MustCompile("CATCGC"), 10, 4, "GCGATG", 16, 20}
 
 	// Return EnzymeMap
 	return enzymeMap
@@ -131,6 +176,12 @@ func CutWithEnzyme(seq Part, directional bool, enzyme Enzyme) []Fragment {
 	// Check for palindromes
 	palindromic := checks.IsPalindromic(enzyme.RecognitionSite)
 
+	// Enzymes without cut positions are only described by Skip and OverhangLen, which make 5' overhangs.
+	overhangType := enzyme.OverhangType()
+	if overhangType == Blunt && enzyme.OverhangLen > 0 {
+		overhangType = FivePrimeOverhang
+	}
+
 	// Find and define overhangs
 	var overhangs []Overhang
 	var forwardOverhangs []Overhang
@@ -173,8 +224,8 @@ func CutWithEnzyme(seq Part, directional bool, enzyme Enzyme) []Fragment {
 		fragmentSeq1 := sequence[overhangs[0].Position+overhangs[0].Length:]
 		fragmentSeq2 := sequence[:overhangs[0].Position]
 		overhangSeq := sequence[overhangs[0].Position : overhangs[0].Position+overhangs[0].Length]
-		fragments = append(fragments, Fragment{fragmentSeq1, overhangSeq, ""})
-		fragments = append(fragments, Fragment{fragmentSeq2, "", overhangSeq})
+		fragments = append(fragments, Fragment{fragmentSeq1, overhangSeq, "", overhangType, Blunt})
+		fragments = append(fragments, Fragment{fragmentSeq2, "", overhangSeq, Blunt, overhangType})
 		return fragments
 	}
 
@@ -187,7 +238,7 @@ func CutWithEnzyme(seq Part, directional bool, enzyme Enzyme) []Fragment {
 		fragmentSeq2 := sequence[:overhangs[0].Position]
 		fragmentSeq := fragmentSeq1 + fragmentSeq2
 		overhangSeq := sequence[overhangs[0].Position : overhangs[0].Position+overhangs[0].Length]
-		fragments = append(fragments, Fragment{fragmentSeq, overhangSeq, overhangSeq})
+		fragments = append(fragments, Fragment{fragmentSeq, overhangSeq, overhangSeq, overhangType, overhangType})
 		return fragments
 	}
 
@@ -227,7 +278,7 @@ func CutWithEnzyme(seq Part, directional bool, enzyme Enzyme) []Fragment {
 				fragmentSequence := fragment[enzyme.OverhangLen : len(fragment)-enzyme.OverhangLen]
 				forwardOverhang := fragment[:enzyme.OverhangLen]
 				reverseOverhang := fragment[len(fragment)-enzyme.OverhangLen:]
-				fragments = append(fragments, Fragment{Sequence: fragmentSequence, ForwardOverhang: forwardOverhang, ReverseOverhang: reverseOverhang})
+				fragments = append(fragments, Fragment{Sequence: fragmentSequence, ForwardOverhang: forwardOverhang, ReverseOverhang: reverseOverhang, ForwardOverhangType: overhangType, ReverseOverhangType: overhangType})
 			}
 		}
 	}
@@ -239,22 +290,22 @@ func recurseLigate(wg *sync.WaitGroup, constructs chan string, infiniteLoopingCo
 	// Recurse ligate simulates all possible ligations of a series of fragments. Each possible combination begins with a "seed" that fragments from the pool can be added to.
 	defer wg.Done()
 	// If the seed ligates to itself, we can call it done with a successful circularization!
-	if seedFragment.ForwardOverhang == seedFragment.ReverseOverhang {
+	if CanLigate(seedFragment, seedFragment) {
 		constructs <- seedFragment.ForwardOverhang + seedFragment.Sequence
 	} else {
 		for _, newFragment := range fragmentList {
 			// If the seedFragment's reverse overhang is ligates to a fragment's forward overhang, we can ligate those together and seed another ligation reaction
 			var newSeed Fragment
 			var fragmentAttached bool
-			if seedFragment.ReverseOverhang == newFragment.ForwardOverhang {
+			if CanLigate(seedFragment, newFragment) {
 				fragmentAttached = true
-				newSeed = Fragment{seedFragment.Sequence + seedFragment.ReverseOverhang + newFragment.Sequence, seedFragment.ForwardOverhang, newFragment.ReverseOverhang}
+				newSeed = joinFragments(seedFragment, newFragment)
 			}
 			// This checks if we can ligate the next fragment in its reverse direction. We have to be careful though - if our seed has a palindrome, it will ligate to itself
 			// like [-> <- -> <- -> ...] infinitely. We check for that case here as well.
-			if (seedFragment.ReverseOverhang == transform.ReverseComplement(newFragment.ReverseOverhang)) && (seedFragment.ReverseOverhang != transform.ReverseComplement(seedFragment.ReverseOverhang)) { // If the second statement isn't there, program will crash on palindromes
+			if CanLigate(seedFragment, reverseFragment(newFragment)) && (seedFragment.ReverseOverhang != transform.ReverseComplement(seedFragment.ReverseOverhang)) { // If the second statement isn't there, program will crash on palindromes
 				fragmentAttached = true
-				newSeed = Fragment{seedFragment.Sequence + seedFragment.ReverseOverhang + transform.ReverseComplement(newFragment.Sequence), seedFragment.ForwardOverhang, transform.ReverseComplement(newFragment.ForwardOverhang)}
+				newSeed = joinFragments(seedFragment, reverseFragment(newFragment))
 			}
 
 			// If fragment is actually attached, move to some checks
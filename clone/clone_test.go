@@ -2,6 +2,7 @@ package clone_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/TimothyStiles/poly/clone"
@@ -101,8 +102,8 @@ func TestCutWithEnzyme(t *testing.T) {
 func TestCircularLigate(t *testing.T) {
 	// The following tests for complementing overhangs. Specific, this line:
 	// newSeed := Fragment{seedFragment.Sequence + seedFragment.ReverseOverhang + ReverseComplement(newFragment.Sequence), seedFragment.ForwardOverhang, ReverseComplement(newFragment.ForwardOverhang)}
-	fragment1 := clone.Fragment{"AAAAAA", "GTTG", "CTAT"}
-	fragment2 := clone.Fragment{"AAAAAA", "CAAC", "ATAG"}
+	fragment1 := clone.Fragment{"AAAAAA", "GTTG", "CTAT", clone.FivePrimeOverhang, clone.FivePrimeOverhang}
+	fragment2 := clone.Fragment{"AAAAAA", "CAAC", "ATAG", clone.FivePrimeOverhang, clone.FivePrimeOverhang}
 	outputConstructs, infiniteLoops, err := clone.CircularLigate([]clone.Fragment{fragment1, fragment2})
 	if err != nil {
 		t.Errorf("Failed circular ligation with error: %s", err)
@@ -186,3 +187,65 @@ func TestPanicGoldenGate(t *testing.T) {
 		t.Errorf("GoldenGate should not fail with these fragments. Got error: %s", err)
 	}
 }
+
+func TestLigate(t *testing.T) {
+	// a vector and an insert cut with EcoRI (AATT) and HindIII (AGCT).
+	vector := clone.Fragment{Sequence: "CTTTTTTA", ForwardOverhang: "AGCT", ReverseOverhang: "AATT", ForwardOverhangType: clone.FivePrimeOverhang, ReverseOverhangType: clone.FivePrimeOverhang}
+	insert := clone.Fragment{Sequence: "CGGGGGGA", ForwardOverhang: "AATT", ReverseOverhang: "AGCT", ForwardOverhangType: clone.FivePrimeOverhang, ReverseOverhangType: clone.FivePrimeOverhang}
+	ligations := clone.Ligate([]clone.Fragment{vector, insert}, 2)
+
+	// EcoRI and HindIII overhangs are palindromes, so fragments can also join head to head as multimers.
+	var plasmids, multimers []clone.Ligation
+	for _, ligation := range ligations {
+		switch {
+		case ligation.Multimer:
+			multimers = append(multimers, ligation)
+		case ligation.Circular:
+			plasmids = append(plasmids, ligation)
+		}
+	}
+	if len(plasmids) != 1 || plasmids[0].Sequence != "AGCTCTTTTTTAAATTCGGGGGGA" || plasmids[0].SelfLigation {
+		t.Errorf("expected the vector and insert to ligate into one plasmid, got %v", plasmids)
+	}
+	for _, multimer := range multimers {
+		if multimer.Reversed[0] == multimer.Reversed[1] {
+			t.Errorf("directional overhangs should only let copies of a fragment join head to head, got %v", multimer)
+		}
+	}
+
+	// a vector cut with a single enzyme can close on itself and take the insert in either orientation.
+	vector.ForwardOverhang = "AATT"
+	insert.ReverseOverhang = "AATT"
+	ligations = clone.Ligate([]clone.Fragment{vector, insert}, 2)
+	var selfLigations, reversedInserts, multimerCount int
+	for _, ligation := range ligations {
+		if ligation.SelfLigation {
+			selfLigations++
+		}
+		if ligation.Circular && !ligation.Multimer && len(ligation.Fragments) == 2 && ligation.Reversed[0] != ligation.Reversed[1] {
+			reversedInserts++
+		}
+		if ligation.Multimer {
+			multimerCount++
+		}
+	}
+	if selfLigations != 2 || reversedInserts != 1 || multimerCount == 0 {
+		t.Errorf("expected both fragments to self ligate, the insert to ligate backwards and multimers, got %v", ligations)
+	}
+
+	if !clone.CanLigate(clone.Fragment{Sequence: "AAA"}, clone.Fragment{Sequence: "TTT"}) {
+		t.Errorf("blunt ends should ligate")
+	}
+
+	// a 5' AATT overhang, like EcoRI leaves, can't ligate to a 3' AATT overhang even though the bases match.
+	fivePrime := clone.Fragment{Sequence: "CCC", ReverseOverhang: "AATT", ReverseOverhangType: clone.FivePrimeOverhang}
+	threePrime := clone.Fragment{Sequence: "GGG", ForwardOverhang: "AATT", ForwardOverhangType: clone.ThreePrimeOverhang}
+	if clone.CanLigate(fivePrime, threePrime) {
+		t.Errorf("a 5' overhang shouldn't ligate to a 3' overhang with the same bases")
+	}
+	for _, ligation := range clone.Ligate([]clone.Fragment{fivePrime, threePrime}, 2) {
+		if ligation.Fragments[0] != ligation.Fragments[len(ligation.Fragments)-1] && strings.Contains(ligation.Sequence, "AATT") {
+			t.Errorf("fragments with mismatched overhang types shouldn't ligate, got %v", ligation)
+		}
+	}
+}
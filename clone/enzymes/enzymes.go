@@ -0,0 +1,322 @@
+/*
+Package enzymes finds restriction enzyme sites and simulates digests.
+
+The clone package simulates whole cloning reactions, which is great when you
+already know what you're building. Most of the time though you're still at the
+bench asking simpler questions: where does EcoRI cut my plasmid, how big are
+the bands going to be on my gel, and what sticky ends am I left with? This
+package answers those.
+
+Enzymes are described by their recognition site and where they cut the top and
+bottom strands relative to the start of that site, which is the same way REBASE
+describes them (https://rebase.neb.com/rebase/rebase.html). For example EcoRI
+recognizes GAATTC and cuts G^AATT_C, so its top strand cut is 1 and its bottom
+strand cut is 5, leaving a 4 base 5' overhang of AATT.
+
+Enzymes are clone.Enzymes with their TopCut and BottomCut set. GetEnzyme and
+Enzymes give a built-in catalog of common enzymes parsed from embedded REBASE
+data with the rebase package, and FromRebase converts any other enzyme read
+from a REBASE file.
+FindCuts finds where an enzyme cuts a linear or circular sequence, while
+Digest and MultiDigest cut it into fragments with their sticky or blunt ends.
+*/
+package enzymes
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/TimothyStiles/poly/clone"
+	"github.com/TimothyStiles/poly/io/rebase"
+	"github.com/TimothyStiles/poly/transform"
+)
+
+//go:embed data/rebase.txt
+var rebaseCatalog []byte
+
+// defaultEnzymes are common commercially available restriction enzymes, parsed
+// from a REBASE excerpt embedded in the package. Enzymes with degenerate
+// recognition sites, like HincII (GTYRAC), and enzymes that cut on both sides
+// of their site, like BcgI, aren't included.
+var defaultEnzymes = func() map[string]clone.Enzyme {
+	enzymeMap := make(map[string]clone.Enzyme)
+	for name, rebaseEnzyme := range rebase.Parse(rebaseCatalog) {
+		enzyme, err := FromRebase(rebaseEnzyme)
+		if err != nil {
+			panic(err)
+		}
+		enzymeMap[name] = enzyme
+	}
+	return enzymeMap
+}()
+
+// FromRebase converts an enzyme parsed by the rebase package into a
+// clone.Enzyme, reading its cut positions from its recognition sequence. Both
+// of REBASE's notations are understood: a ^ inside the site of an enzyme that
+// cuts its palindrome symmetrically, like G^AATTC, and a (top/bottom) suffix
+// for an enzyme that cuts after its site, like GGTCTC(1/5).
+//
+// Enzymes whose cut positions aren't known, whose sites are degenerate or
+// which cut on both sides of their site return an error.
+func FromRebase(rebaseEnzyme rebase.Enzyme) (clone.Enzyme, error) {
+	recognitionSequence := strings.ToUpper(rebaseEnzyme.RecognitionSequence)
+	var recognitionSite string
+	var topCut, bottomCut int
+	switch {
+	case strings.Count(recognitionSequence, "^") == 1 && !strings.ContainsAny(recognitionSequence, "()"):
+		topCut = strings.Index(recognitionSequence, "^")
+		recognitionSite = strings.Replace(recognitionSequence, "^", "", 1)
+		bottomCut = len(recognitionSite) - topCut
+	case strings.HasSuffix(recognitionSequence, ")") && strings.Count(recognitionSequence, "(") == 1 && !strings.HasPrefix(recognitionSequence, "("):
+		var topOffset, bottomOffset int
+		siteEnd := strings.Index(recognitionSequence, "(")
+		if _, err := fmt.Sscanf(recognitionSequence[siteEnd:], "(%d/%d)", &topOffset, &bottomOffset); err != nil {
+			return clone.Enzyme{}, fmt.Errorf("enzyme %s has an unreadable recognition sequence %s: %w", rebaseEnzyme.Name, rebaseEnzyme.RecognitionSequence, err)
+		}
+		recognitionSite = recognitionSequence[:siteEnd]
+		topCut, bottomCut = len(recognitionSite)+topOffset, len(recognitionSite)+bottomOffset
+	default:
+		return clone.Enzyme{}, fmt.Errorf("enzyme %s has no single cut position in its recognition sequence %s", rebaseEnzyme.Name, rebaseEnzyme.RecognitionSequence)
+	}
+	if len(recognitionSite) == 0 || strings.Trim(recognitionSite, "ACGT") != "" {
+		return clone.Enzyme{}, fmt.Errorf("enzyme %s has a degenerate or empty recognition site %s", rebaseEnzyme.Name, recognitionSite)
+	}
+
+	enzyme := clone.Enzyme{
+		Name:            rebaseEnzyme.Name,
+		RegexpFor:       regexp.MustCompile(recognitionSite),
+		RegexpRev:       regexp.MustCompile(transform.ReverseComplement(recognitionSite)),
+		RecognitionSite: recognitionSite,
+		TopCut:          topCut,
+		BottomCut:       bottomCut,
+	}
+	// Skip and OverhangLen are how clone.CutWithEnzyme describes the cut: the
+	// overhang starts Skip bases after the recognition site, whichever strand is cut first.
+	enzyme.Skip = topCut - len(recognitionSite)
+	if bottomCut < topCut {
+		enzyme.Skip = bottomCut - len(recognitionSite)
+	}
+	enzyme.OverhangLen = enzyme.OverhangLength()
+	return enzyme, nil
+}
+
+// GetEnzyme returns a built-in enzyme by name.
+func GetEnzyme(name string) (clone.Enzyme, error) {
+	enzyme, ok := defaultEnzymes[name]
+	if !ok {
+		return clone.Enzyme{}, fmt.Errorf("enzyme %s not found", name)
+	}
+	return enzyme, nil
+}
+
+// Enzymes returns every built-in enzyme sorted by name.
+func Enzymes() []clone.Enzyme {
+	enzymes := make([]clone.Enzyme, 0, len(defaultEnzymes))
+	for _, enzyme := range defaultEnzymes {
+		enzymes = append(enzymes, enzyme)
+	}
+	sort.Slice(enzymes, func(i, j int) bool {
+		return enzymes[i].Name < enzymes[j].Name
+	})
+	return enzymes
+}
+
+// FindSites returns the 0-based start positions of every recognition site of
+// the enzyme in a linear sequence. Sites on the bottom strand are reported by
+// the position of their reverse complement on the top strand.
+func FindSites(sequence string, enzyme clone.Enzyme) []int {
+	var positions []int
+	for _, site := range findSites(strings.ToUpper(sequence), enzyme, false) {
+		positions = append(positions, site.position)
+	}
+	return positions
+}
+
+// site is a recognition site found on either the top (forward) or bottom strand.
+type site struct {
+	position int
+	forward  bool
+}
+
+// findSites finds recognition sites on both strands sorted by position. On
+// circular sequences it also finds sites spanning the origin.
+func findSites(sequence string, enzyme clone.Enzyme, circular bool) []site {
+	recognitionSite := strings.ToUpper(enzyme.RecognitionSite)
+	if len(recognitionSite) == 0 || len(recognitionSite) > len(sequence) {
+		return nil
+	}
+	searchSequence := sequence
+	if circular {
+		searchSequence = sequence + sequence[:len(recognitionSite)-1]
+	}
+
+	var sites []site
+	queries := []string{recognitionSite}
+	// palindromic sites are the same on both strands so only need to be searched for once.
+	if reverseSite := transform.ReverseComplement(recognitionSite); reverseSite != recognitionSite {
+		queries = append(queries, reverseSite)
+	}
+	for queryIndex, query := range queries {
+		for offset := 0; ; {
+			index := strings.Index(searchSequence[offset:], query)
+			if index == -1 {
+				break
+			}
+			sites = append(sites, site{position: offset + index, forward: queryIndex == 0})
+			offset += index + 1
+		}
+	}
+	sort.SliceStable(sites, func(i, j int) bool {
+		return sites[i].position < sites[j].position
+	})
+	return sites
+}
+
+// Cut is a double stranded break made by an enzyme. TopStrand and
+// BottomStrand are the positions of the cuts on each strand, counted as the
+// number of top strand bases before them, so the overhang left by the enzyme
+// spans from the lower of the two to the higher. On circular sequences the
+// lower of the two is always inside the sequence but the higher may be past
+// its end if the overhang spans the origin.
+type Cut struct {
+	Enzyme       string
+	TopStrand    int
+	BottomStrand int
+}
+
+// start returns the position of the first base of the cut's overhang.
+func (cut Cut) start() int {
+	if cut.TopStrand < cut.BottomStrand {
+		return cut.TopStrand
+	}
+	return cut.BottomStrand
+}
+
+// end returns the position after the last base of the cut's overhang.
+func (cut Cut) end() int {
+	if cut.TopStrand > cut.BottomStrand {
+		return cut.TopStrand
+	}
+	return cut.BottomStrand
+}
+
+// overhangType returns the kind of end the cut leaves.
+func (cut Cut) overhangType() clone.OverhangType {
+	switch {
+	case cut.TopStrand < cut.BottomStrand:
+		return clone.FivePrimeOverhang
+	case cut.TopStrand > cut.BottomStrand:
+		return clone.ThreePrimeOverhang
+	}
+	return clone.Blunt
+}
+
+// FindCuts returns the cuts an enzyme makes in a sequence sorted by position.
+// Sites so close to the end of a linear sequence that the enzyme would cut off
+// of it aren't cut.
+func FindCuts(sequence string, enzyme clone.Enzyme, circular bool) []Cut {
+	sequence = strings.ToUpper(sequence)
+	var cuts []Cut
+	for _, site := range findSites(sequence, enzyme, circular) {
+		topCut, bottomCut := site.position+enzyme.TopCut, site.position+enzyme.BottomCut
+		// sites on the bottom strand cut mirrored across the recognition site,
+		// with the enzyme's top strand cut landing on the bottom strand.
+		if !site.forward {
+			siteLength := len(enzyme.RecognitionSite)
+			topCut, bottomCut = site.position+siteLength-enzyme.BottomCut, site.position+siteLength-enzyme.TopCut
+		}
+		cut := Cut{Enzyme: enzyme.Name, TopStrand: topCut, BottomStrand: bottomCut}
+
+		if circular {
+			// normalize the cut so that it begins inside the sequence.
+			shift := ((cut.start()%len(sequence))+len(sequence))%len(sequence) - cut.start()
+			cut.TopStrand += shift
+			cut.BottomStrand += shift
+		} else if cut.start() < 0 || cut.end() > len(sequence) {
+			// the enzyme would cut off the end of a linear sequence.
+			continue
+		}
+		cuts = append(cuts, cut)
+	}
+	sortCuts(cuts)
+	return cuts
+}
+
+// sortCuts sorts cuts by the start of their overhangs.
+func sortCuts(cuts []Cut) {
+	sort.SliceStable(cuts, func(i, j int) bool {
+		return cuts[i].start() < cuts[j].start()
+	})
+}
+
+// Digest cuts a sequence with an enzyme and returns the resulting fragments in
+// order. Each fragment's Sequence is its double stranded portion and its
+// ForwardOverhang and ReverseOverhang are the single stranded ends left by the
+// enzyme, written as top strand sequence, with their types in
+// ForwardOverhangType and ReverseOverhangType. Blunt ends and the ends of a
+// linear sequence have empty overhangs.
+//
+// Circular sequences are cut across the origin, so a circular sequence with a
+// single site gives a single linear fragment. A circular sequence without any
+// sites can't be represented as a Fragment and returns no fragments.
+func Digest(sequence string, enzyme clone.Enzyme, circular bool) []clone.Fragment {
+	return MultiDigest(sequence, []clone.Enzyme{enzyme}, circular)
+}
+
+// MultiDigest cuts a sequence with several enzymes at once, like a double
+// digest, and returns the resulting fragments in order the same way Digest
+// does. Cuts by different enzymes whose overhangs overlap leave nothing double
+// stranded between them, so no fragment is returned for that stretch.
+func MultiDigest(sequence string, enzymeList []clone.Enzyme, circular bool) []clone.Fragment {
+	sequence = strings.ToUpper(sequence)
+	if len(sequence) == 0 {
+		return nil
+	}
+	var cuts []Cut
+	for _, enzyme := range enzymeList {
+		cuts = append(cuts, FindCuts(sequence, enzyme, circular)...)
+	}
+	sortCuts(cuts)
+
+	var fragments []clone.Fragment
+	if !circular {
+		// the ends of a linear sequence act as blunt cuts.
+		cuts = append([]Cut{{}}, append(cuts, Cut{TopStrand: len(sequence), BottomStrand: len(sequence)})...)
+		for index := 0; index < len(cuts)-1; index++ {
+			current, next := cuts[index], cuts[index+1]
+			if current.end() > next.start() {
+				continue // overlapping cuts leave nothing double stranded between them.
+			}
+			fragments = append(fragments, clone.Fragment{
+				Sequence:            sequence[current.end():next.start()],
+				ForwardOverhang:     sequence[current.start():current.end()],
+				ReverseOverhang:     sequence[next.start():next.end()],
+				ForwardOverhangType: current.overhangType(),
+				ReverseOverhangType: next.overhangType(),
+			})
+		}
+		return fragments
+	}
+
+	// three copies of a circular sequence are enough to read any fragment, even one that spans the origin.
+	circularSequence := strings.Repeat(sequence, 3)
+	for index, current := range cuts {
+		next := cuts[(index+1)%len(cuts)]
+		next.TopStrand += len(sequence) * ((index + 1) / len(cuts))
+		next.BottomStrand += len(sequence) * ((index + 1) / len(cuts))
+		if current.end() > next.start() {
+			continue
+		}
+		fragments = append(fragments, clone.Fragment{
+			Sequence:            circularSequence[current.end():next.start()],
+			ForwardOverhang:     circularSequence[current.start():current.end()],
+			ReverseOverhang:     circularSequence[next.start():next.end()],
+			ForwardOverhangType: current.overhangType(),
+			ReverseOverhangType: next.overhangType(),
+		})
+	}
+	return fragments
+}
@@ -0,0 +1,177 @@
+package enzymes_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TimothyStiles/poly/clone"
+	"github.com/TimothyStiles/poly/clone/enzymes"
+	"github.com/TimothyStiles/poly/io/rebase"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetEnzyme(t *testing.T) {
+	if _, err := enzymes.GetEnzyme("EcoFake"); err == nil {
+		t.Errorf("GetEnzyme should have failed when looking for fake restriction enzyme EcoFake")
+	}
+}
+
+func TestFindSites(t *testing.T) {
+	bsaI, _ := enzymes.GetEnzyme("BsaI")
+	// non-palindromic sites should be found on both strands.
+	sites := enzymes.FindSites("GGTCTCAAAAAAGAGACC", bsaI)
+	if diff := cmp.Diff([]int{0, 12}, sites); diff != "" {
+		t.Errorf("FindSites found the wrong sites. Got this diff:\n%s", diff)
+	}
+
+	// palindromic sites should only be reported once.
+	ecoRI, _ := enzymes.GetEnzyme("EcoRI")
+	if sites := enzymes.FindSites("aagaattcaa", ecoRI); len(sites) != 1 {
+		t.Errorf("expected a single EcoRI site, got %v", sites)
+	}
+}
+
+func TestDigestCircular(t *testing.T) {
+	ecoRI, _ := enzymes.GetEnzyme("EcoRI")
+
+	// this EcoRI site spans the origin.
+	fragments := enzymes.Digest("AATTCTTTTTTG", ecoRI, true)
+	expected := []clone.Fragment{{Sequence: "CTTTTTTG", ForwardOverhang: "AATT", ReverseOverhang: "AATT", ForwardOverhangType: clone.FivePrimeOverhang, ReverseOverhangType: clone.FivePrimeOverhang}}
+	if diff := cmp.Diff(expected, fragments); diff != "" {
+		t.Errorf("Digest did not cut across the origin. Got this diff:\n%s", diff)
+	}
+
+	// a circular sequence with no sites can't be linearized.
+	if fragments := enzymes.Digest("TTTTTTTTTTTT", ecoRI, true); len(fragments) != 0 {
+		t.Errorf("expected no fragments, got %v", fragments)
+	}
+
+	// a linear sequence with no sites is left whole.
+	if fragments := enzymes.Digest("TTTTTTTTTTTT", ecoRI, false); len(fragments) != 1 || fragments[0].Sequence != "TTTTTTTTTTTT" {
+		t.Errorf("expected the whole sequence, got %v", fragments)
+	}
+}
+
+func TestDigestOverhangs(t *testing.T) {
+	// PstI leaves a 3' overhang.
+	pstI, _ := enzymes.GetEnzyme("PstI")
+	fragments := enzymes.Digest("AAACTGCAGAAA", pstI, false)
+	expected := []clone.Fragment{
+		{Sequence: "AAAC", ForwardOverhang: "", ReverseOverhang: "TGCA", ReverseOverhangType: clone.ThreePrimeOverhang},
+		{Sequence: "GAAA", ForwardOverhang: "TGCA", ReverseOverhang: "", ForwardOverhangType: clone.ThreePrimeOverhang},
+	}
+	if diff := cmp.Diff(expected, fragments); diff != "" {
+		t.Errorf("Digest gave the wrong PstI fragments. Got this diff:\n%s", diff)
+	}
+
+	// EcoRV is blunt.
+	ecoRV, _ := enzymes.GetEnzyme("EcoRV")
+	fragments = enzymes.Digest("AAAGATATCAAA", ecoRV, false)
+	expected = []clone.Fragment{{Sequence: "AAAGAT"}, {Sequence: "ATCAAA"}}
+	if diff := cmp.Diff(expected, fragments); diff != "" {
+		t.Errorf("Digest gave the wrong EcoRV fragments. Got this diff:\n%s", diff)
+	}
+
+	// BsaI cuts outside of its site, and upstream of it on the bottom strand.
+	bsaI, _ := enzymes.GetEnzyme("BsaI")
+	fragments = enzymes.Digest("TTTTTATGCAGAGACCTTTTT", bsaI, false)
+	expected = []clone.Fragment{
+		{Sequence: "TTTTT", ForwardOverhang: "", ReverseOverhang: "ATGC", ReverseOverhangType: clone.FivePrimeOverhang},
+		{Sequence: "AGAGACCTTTTT", ForwardOverhang: "ATGC", ReverseOverhang: "", ForwardOverhangType: clone.FivePrimeOverhang},
+	}
+	if diff := cmp.Diff(expected, fragments); diff != "" {
+		t.Errorf("Digest gave the wrong BsaI fragments. Got this diff:\n%s", diff)
+	}
+
+	// sites too close to the end of a linear sequence can't be cut.
+	if fragments := enzymes.Digest("TGCAGAGACC", bsaI, false); len(fragments) != 1 {
+		t.Errorf("expected the uncut sequence, got %v", fragments)
+	}
+}
+
+func TestFromRebase(t *testing.T) {
+	rebaseEnzymes, err := rebase.Read("../../io/rebase/data/rebase_test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string][3]interface{}{"AatII": {"GACGTC", 5, 1}, "AarI": {"CACCTGC", 11, 15}, "AclWI": {"GGATC", 9, 10}} {
+		enzyme, err := enzymes.FromRebase(rebaseEnzymes[name])
+		if err != nil {
+			t.Errorf("failed to convert %s: %s", name, err)
+			continue
+		}
+		if got := [3]interface{}{enzyme.RecognitionSite, enzyme.TopCut, enzyme.BottomCut}; got != expected {
+			t.Errorf("expected %s to be %v, got %v", name, expected, got)
+		}
+	}
+	// AccI recognizes the degenerate site GT^MKAC.
+	if _, err := enzymes.FromRebase(rebaseEnzymes["AccI"]); err == nil {
+		t.Errorf("expected an error converting an enzyme with a degenerate site")
+	}
+
+	// enzymes from the catalog cut the same as clone's own enzymes.
+	bsaI, _ := enzymes.GetEnzyme("BsaI")
+	part := clone.Part{Sequence: "GGTCTCAATGCAAAAAAAAATTTTGGAGACC", Circular: false}
+	byName, _ := clone.CutWithEnzymeByName(part, true, "BsaI")
+	if diff := cmp.Diff(byName, clone.CutWithEnzyme(part, true, bsaI)); diff != "" {
+		t.Errorf("catalog BsaI cut differently from clone's BsaI. Got this diff:\n%s", diff)
+	}
+	// PstI leaves a 3' overhang, which starts at its bottom strand cut.
+	pstI, _ := enzymes.GetEnzyme("PstI")
+	fragments := clone.CutWithEnzyme(clone.Part{Sequence: "AAAAAAAAAACTGCAGAAAAAAAAAA"}, false, pstI)
+	if len(fragments) != 2 || fragments[0].ForwardOverhang != "TGCA" || fragments[1].ReverseOverhang != "TGCA" {
+		t.Errorf("expected PstI to leave TGCA overhangs, got %v", fragments)
+	}
+}
+
+func TestEnzymes(t *testing.T) {
+	for _, enzyme := range enzymes.Enzymes() {
+		if byName, err := enzymes.GetEnzyme(enzyme.Name); err != nil || byName != enzyme {
+			t.Errorf("built-in enzyme %s isn't listed under its own name", enzyme.Name)
+		}
+		if strings.Trim(enzyme.RecognitionSite, "ACGT") != "" {
+			t.Errorf("built-in enzyme %s has a degenerate recognition site %s", enzyme.Name, enzyme.RecognitionSite)
+		}
+	}
+
+	for name, expected := range map[string]clone.OverhangType{"EcoRI": clone.FivePrimeOverhang, "PstI": clone.ThreePrimeOverhang, "EcoRV": clone.Blunt} {
+		enzyme, _ := enzymes.GetEnzyme(name)
+		if enzyme.OverhangType() != expected {
+			t.Errorf("expected %s to leave a %s, got a %s", name, expected, enzyme.OverhangType())
+		}
+	}
+}
+
+func TestFindCuts(t *testing.T) {
+	bsaI, _ := enzymes.GetEnzyme("BsaI")
+	// the site on the bottom strand has its strands' cuts swapped as well as mirrored.
+	cuts := enzymes.FindCuts("GGTCTCAAAAAAAAAAAAAAAAGAGACC", bsaI, false)
+	expected := []enzymes.Cut{
+		{Enzyme: "BsaI", TopStrand: 7, BottomStrand: 11},
+		{Enzyme: "BsaI", TopStrand: 17, BottomStrand: 21},
+	}
+	if diff := cmp.Diff(expected, cuts); diff != "" {
+		t.Errorf("FindCuts found the wrong cuts. Got this diff:\n%s", diff)
+	}
+
+	// this PstI site spans the origin, so its overhang does too.
+	pstI, _ := enzymes.GetEnzyme("PstI")
+	cuts = enzymes.FindCuts("GCAGTTTTTTCT", pstI, true)
+	expected = []enzymes.Cut{{Enzyme: "PstI", TopStrand: 15, BottomStrand: 11}}
+	if diff := cmp.Diff(expected, cuts); diff != "" {
+		t.Errorf("FindCuts found the wrong cuts across the origin. Got this diff:\n%s", diff)
+	}
+}
+
+func TestMultiDigest(t *testing.T) {
+	ecoRI, _ := enzymes.GetEnzyme("EcoRI")
+	ecoRV, _ := enzymes.GetEnzyme("EcoRV")
+	fragments := enzymes.MultiDigest("TTGATATCTTTTGAATTCTT", []clone.Enzyme{ecoRI, ecoRV}, true)
+	expected := []clone.Fragment{
+		{Sequence: "ATCTTTTG", ForwardOverhang: "", ReverseOverhang: "AATT", ReverseOverhangType: clone.FivePrimeOverhang},
+		{Sequence: "CTTTTGAT", ForwardOverhang: "AATT", ReverseOverhang: "", ForwardOverhangType: clone.FivePrimeOverhang},
+	}
+	if diff := cmp.Diff(expected, fragments); diff != "" {
+		t.Errorf("MultiDigest gave the wrong fragments. Got this diff:\n%s", diff)
+	}
+}
@@ -0,0 +1,61 @@
+package enzymes_test
+
+import (
+	"fmt"
+
+	"github.com/TimothyStiles/poly/clone"
+	"github.com/TimothyStiles/poly/clone/enzymes"
+	"github.com/TimothyStiles/poly/io/genbank"
+)
+
+// This example shows how to predict the bands of a diagnostic digest.
+func Example_basic() {
+	puc19, _ := genbank.Read("../../data/puc19.gbk")
+	pvuII := clone.Enzyme{Name: "PvuII", RecognitionSite: "CAGCTG", TopCut: 3, BottomCut: 3}
+
+	for _, fragment := range enzymes.Digest(puc19.Sequence, pvuII, true) {
+		fmt.Println(len(fragment.Sequence))
+	}
+	// Output:
+	// 322
+	// 2364
+}
+
+func ExampleFindSites() {
+	ecoRI, _ := enzymes.GetEnzyme("EcoRI")
+
+	fmt.Println(enzymes.FindSites("GAATTCAAAAGAATTC", ecoRI))
+	// Output: [0 10]
+}
+
+func ExampleDigest() {
+	ecoRI, _ := enzymes.GetEnzyme("EcoRI")
+	fragments := enzymes.Digest("ATGCGAATTCATGC", ecoRI, false)
+
+	for _, fragment := range fragments {
+		fmt.Printf("%q %q %q\n", fragment.ForwardOverhang, fragment.Sequence, fragment.ReverseOverhang)
+	}
+	// Output:
+	// "" "ATGCG" "AATT"
+	// "AATT" "CATGC" ""
+}
+
+func ExampleGetEnzyme() {
+	pstI, _ := enzymes.GetEnzyme("PstI")
+
+	fmt.Println(pstI.RecognitionSite, pstI.OverhangLength())
+	// Output: CTGCAG 4
+}
+
+func ExampleMultiDigest() {
+	puc19, _ := genbank.Read("../../data/puc19.gbk")
+	ecoRI, _ := enzymes.GetEnzyme("EcoRI")
+	hindIII, _ := enzymes.GetEnzyme("HindIII")
+
+	for _, fragment := range enzymes.MultiDigest(puc19.Sequence, []clone.Enzyme{ecoRI, hindIII}, true) {
+		fmt.Println(len(fragment.Sequence), fragment.ForwardOverhang, fragment.ReverseOverhang)
+	}
+	// Output:
+	// 47 AGCT AATT
+	// 2631 AATT AGCT
+}
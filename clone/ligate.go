@@ -0,0 +1,202 @@
+package clone
+
+import (
+	"fmt"
+
+	"github.com/TimothyStiles/poly/transform"
+)
+
+/******************************************************************************
+
+Ligation begins here.
+
+CircularLigate answers "what plasmids does my GoldenGate reaction make?", which
+is all you need when every fragment was designed to fit exactly one place.
+Ligate answers the question you ask before running a traditional restriction
+cloning at the bench: what else could ligase make out of these fragments? Cut
+vector closing on itself, inserts going in backwards, two copies of an insert
+joining up and linear pieces that never circularize all show up on a colony
+PCR or a gel, so Ligate enumerates every one of them.
+
+Two ends can be ligated when the overhang at the end of one fragment is the
+same as the overhang at the start of the next, written as top strand sequence,
+which is how Fragments from CutWithEnzyme and the enzymes package store them,
+and both are the same type of overhang. Blunt ends have empty overhangs and
+ligate to any other blunt end. Fragments can be ligated in either orientation.
+
+******************************************************************************/
+
+// Ligation is a product of a ligation reaction.
+type Ligation struct {
+	// Sequence is the sequence of a circular product, starting with the
+	// forward overhang of its first fragment, or the double stranded part of
+	// a linear product.
+	Sequence string
+	// ForwardOverhang and ReverseOverhang are the free ends of a linear
+	// product. They are empty for circular products.
+	ForwardOverhang string
+	ReverseOverhang string
+	Circular        bool
+	Fragments       []int  // indices of the ligated fragments in the input, in order.
+	Reversed        []bool // whether each fragment was ligated as its reverse complement.
+	SelfLigation    bool   // a circular product of a single fragment ligated to itself.
+	Multimer        bool   // a product that uses at least one fragment more than once.
+}
+
+// CanLigate returns whether the start of next can be ligated to the end of
+// previous, which is when the overhangs left on the two ends are the same. A
+// 5' overhang never ligates to a 3' overhang, even when their bases match.
+func CanLigate(previous, next Fragment) bool {
+	return previous.ReverseOverhang == next.ForwardOverhang && previous.ReverseOverhangType == next.ForwardOverhangType
+}
+
+// joinFragments ligates the start of next to the end of previous, which CanLigate must allow.
+func joinFragments(previous, next Fragment) Fragment {
+	return Fragment{
+		Sequence:            previous.Sequence + previous.ReverseOverhang + next.Sequence,
+		ForwardOverhang:     previous.ForwardOverhang,
+		ReverseOverhang:     next.ReverseOverhang,
+		ForwardOverhangType: previous.ForwardOverhangType,
+		ReverseOverhangType: next.ReverseOverhangType,
+	}
+}
+
+// reverseFragment returns the reverse complement of a fragment, which swaps and reverse complements its overhangs as well.
+func reverseFragment(fragment Fragment) Fragment {
+	return Fragment{
+		Sequence:            transform.ReverseComplement(fragment.Sequence),
+		ForwardOverhang:     transform.ReverseComplement(fragment.ReverseOverhang),
+		ReverseOverhang:     transform.ReverseComplement(fragment.ForwardOverhang),
+		ForwardOverhangType: fragment.ReverseOverhangType,
+		ReverseOverhangType: fragment.ForwardOverhangType,
+	}
+}
+
+// piece is a fragment in a ligation product, encoded as twice its index plus 1 if it is reversed.
+type piece int
+
+// flip returns the same fragment in the opposite orientation.
+func (p piece) flip() piece {
+	return p ^ 1
+}
+
+// Ligate enumerates every circular and linear product of ligating fragments
+// together, made of up to maxFragments fragments. Linear products are made of
+// at least two fragments. Each product is returned once however it's rotated
+// or flipped, in the order they're first found.
+//
+// Ligations can make multimers of any length, so maxFragments must bound the
+// search. The number of products grows exponentially with it, so it should be
+// kept to the few more than the number of fragments expected in the product
+// needed to spot unwanted multimers.
+func Ligate(fragments []Fragment, maxFragments int) []Ligation {
+	// oriented[p] is the fragment for piece p in its orientation.
+	oriented := make([]Fragment, 2*len(fragments))
+	for index, fragment := range fragments {
+		oriented[2*index] = fragment
+		oriented[2*index+1] = reverseFragment(fragment)
+	}
+
+	var ligations []Ligation
+	seen := make(map[string]bool)
+	record := func(pieces []piece, circular bool) {
+		key := canonicalKey(pieces, circular)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		ligations = append(ligations, buildLigation(pieces, oriented, circular))
+	}
+
+	var extend func(pieces []piece)
+	extend = func(pieces []piece) {
+		first, last := oriented[pieces[0]], oriented[pieces[len(pieces)-1]]
+		if CanLigate(last, first) {
+			record(pieces, true)
+		}
+		if len(pieces) > 1 {
+			record(pieces, false)
+		}
+		if len(pieces) == maxFragments {
+			return
+		}
+		for next := range oriented {
+			if CanLigate(last, oriented[next]) {
+				extend(append(pieces, piece(next)))
+			}
+		}
+	}
+	for start := range oriented {
+		extend([]piece{piece(start)})
+	}
+	return ligations
+}
+
+// buildLigation joins the pieces of a product together.
+func buildLigation(pieces []piece, oriented []Fragment, circular bool) Ligation {
+	ligation := Ligation{Circular: circular}
+	used := make(map[int]bool)
+	joined := oriented[pieces[0]]
+	for index, p := range pieces {
+		if index > 0 {
+			joined = joinFragments(joined, oriented[p])
+		}
+		fragmentIndex := int(p) / 2
+		if used[fragmentIndex] {
+			ligation.Multimer = true
+		}
+		used[fragmentIndex] = true
+		ligation.Fragments = append(ligation.Fragments, fragmentIndex)
+		ligation.Reversed = append(ligation.Reversed, p%2 == 1)
+	}
+	if circular {
+		ligation.Sequence = joined.ForwardOverhang + joined.Sequence
+		ligation.SelfLigation = len(pieces) == 1
+	} else {
+		ligation.Sequence = joined.Sequence
+		ligation.ForwardOverhang, ligation.ReverseOverhang = joined.ForwardOverhang, joined.ReverseOverhang
+	}
+	return ligation
+}
+
+// canonicalKey returns the same key for every way of writing a product: both
+// orientations of linear products and every rotation of both orientations of
+// circular ones.
+func canonicalKey(pieces []piece, circular bool) string {
+	reversed := make([]piece, len(pieces))
+	for index, p := range pieces {
+		reversed[len(pieces)-1-index] = p.flip()
+	}
+	candidates := [][]piece{pieces, reversed}
+	if circular {
+		candidates = nil
+		for rotation := range pieces {
+			candidates = append(candidates,
+				append(append([]piece{}, pieces[rotation:]...), pieces[:rotation]...),
+				append(append([]piece{}, reversed[rotation:]...), reversed[:rotation]...))
+		}
+	}
+	best := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if lessPieces(candidate, best) {
+			best = candidate
+		}
+	}
+	return fmt.Sprint(circular, best)
+}
+
+// lessPieces compares two equally long lists of pieces.
+func lessPieces(a, b []piece) bool {
+	for index := range a {
+		if a[index] != b[index] {
+			return a[index] < b[index]
+		}
+	}
+	return false
+}
+
+/******************************************************************************
+
+Ligation ends here.
+
+******************************************************************************/
@@ -0,0 +1,272 @@
+/*
+Package crispr designs guide RNAs for CRISPR nucleases.
+
+Cas nucleases are sent to a site by a guide RNA whose spacer pairs with the
+target, but they only cut if the target also has a short protospacer adjacent
+motif (PAM) next to the spacer, NGG right after it for SpCas9. FindGuides lists
+every spacer next to a PAM on either strand of a target sequence.
+
+Not every guide cuts equally well. Each guide found is scored with Rule Set 1
+of Doench et al. [Doench JG et al. (2014) Nat Biotechnol, doi:10.1038/nbt.3026],
+a logistic regression on the 30 bases around a SpCas9 site that gives the
+chance the guide is among the most active, from 0 to 1.
+
+A guide can also cut anywhere else in the genome that looks enough like its
+target. FindOffTargets looks for those sites in a search.Index of the genome,
+allowing a few mismatches in the spacer but none in the PAM.
+*/
+package crispr
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/TimothyStiles/poly/io/genbank"
+	"github.com/TimothyStiles/poly/search"
+	"github.com/TimothyStiles/poly/transform"
+)
+
+// Nuclease describes where a Cas nuclease binds relative to its PAM.
+type Nuclease struct {
+	Name         string
+	PAM          string // IUPAC pattern of the PAM, on the same strand as the spacer.
+	SpacerLength int
+	PAMFivePrime bool // whether the PAM is 5' of the spacer, like Cas12a, rather than 3' like Cas9.
+}
+
+// Common nucleases. Any other can be described with its own Nuclease.
+var (
+	SpCas9   = Nuclease{Name: "SpCas9", PAM: "NGG", SpacerLength: 20}
+	SaCas9   = Nuclease{Name: "SaCas9", PAM: "NNGRRT", SpacerLength: 21}
+	AsCas12a = Nuclease{Name: "AsCas12a", PAM: "TTTV", SpacerLength: 23, PAMFivePrime: true}
+)
+
+// Options changes how FindGuides looks for guides.
+type Options struct {
+	Nuclease Nuclease // the nuclease to find guides for. Defaults to SpCas9.
+	Circular bool     // find guides across the origin of a circular target.
+}
+
+// Guide is a guide RNA found by FindGuides.
+type Guide struct {
+	Spacer   string           // the spacer, 5' to 3' on the strand it matches.
+	PAM      string           // the PAM next to the spacer in the target.
+	Location genbank.Location // where the spacer is in the target, with Complement set for guides matching the reverse strand.
+	Score    float64          // Rule Set 1 on-target score, or 0 if it can't be calculated.
+}
+
+// FindGuides returns every guide for a target sequence, sorted by where their
+// spacers start. Spacers with anything other than A, C, G or T in them are
+// skipped. Guides are scored with RuleSet1Score if the nuclease has a 3 base
+// PAM 3' of a 20 base spacer, like SpCas9, and the target has the 4 bases
+// before the spacer and 3 bases after the PAM that the score needs. Other
+// guides have a Score of 0.
+func FindGuides(target string, options Options) ([]Guide, error) {
+	nuclease := options.Nuclease
+	if nuclease.PAM == "" {
+		nuclease = SpCas9
+	}
+	if nuclease.SpacerLength <= 0 {
+		return nil, fmt.Errorf("spacer length must be positive, got %d", nuclease.SpacerLength)
+	}
+
+	// each strand is searched separately since search.Find only reports PAMs
+	// that are their own reverse complement once.
+	target = strings.ToUpper(target)
+	pams, err := search.Find(target, nuclease.PAM, search.Options{Circular: options.Circular, ForwardOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	reversePAMs, _ := search.Find(target, transform.ReverseComplement(nuclease.PAM), search.Options{Circular: options.Circular, ForwardOnly: true})
+	for _, pam := range reversePAMs {
+		pam.Complement = true
+		pams = append(pams, pam)
+	}
+
+	var guides []Guide
+	pamLength := len(nuclease.PAM)
+	scorable := nuclease.SpacerLength == 20 && pamLength == 3 && !nuclease.PAMFivePrime
+	for _, pam := range pams {
+		// spacers are upstream of 3' PAMs on their own strand, so downstream of
+		// them on the forward strand for guides matching the reverse strand.
+		spacerStart := pam.Start - nuclease.SpacerLength
+		if nuclease.PAMFivePrime != pam.Complement {
+			spacerStart = pam.Start + pamLength
+		}
+		spacer, ok := slice(target, spacerStart, spacerStart+nuclease.SpacerLength, options.Circular)
+		if !ok || strings.Trim(spacer, "ACGT") != "" {
+			continue
+		}
+		pamSequence, _ := slice(target, pam.Start, pam.End, options.Circular)
+
+		// the 30 bases scored by Rule Set 1, from 4 bases before the spacer to 3 after the PAM.
+		contextStart, contextEnd := spacerStart-4, pam.End+3
+		if pam.Complement {
+			contextStart, contextEnd = pam.Start-3, spacerStart+nuclease.SpacerLength+4
+		}
+		context, hasContext := slice(target, contextStart, contextEnd, options.Circular)
+
+		if pam.Complement {
+			spacer = transform.ReverseComplement(spacer)
+			pamSequence = transform.ReverseComplement(pamSequence)
+			context = transform.ReverseComplement(context)
+		}
+		guide := Guide{Spacer: spacer, PAM: pamSequence, Location: wrap(spacerStart, nuclease.SpacerLength, len(target), pam.Complement)}
+		if scorable && hasContext {
+			guide.Score, _ = RuleSet1Score(context)
+		}
+		guides = append(guides, guide)
+	}
+	sort.SliceStable(guides, func(i, j int) bool {
+		return guides[i].Location.Start < guides[j].Location.Start
+	})
+	return guides, nil
+}
+
+// slice returns sequence[start:end], wrapping around the origin of circular
+// sequences, and whether the region is inside the sequence.
+func slice(sequence string, start, end int, circular bool) (string, bool) {
+	if start >= 0 && end <= len(sequence) {
+		return sequence[start:end], true
+	}
+	if !circular || end-start > len(sequence) {
+		return "", false
+	}
+	var builder strings.Builder
+	for position := start; position < end; position++ {
+		builder.WriteByte(sequence[(position%len(sequence)+len(sequence))%len(sequence)])
+	}
+	return builder.String(), true
+}
+
+// wrap returns the location of a region of a circular sequence that may start
+// before its origin, moving the start back onto the sequence and letting the end
+// run past the end of it like genbank.Location does for regions spanning the origin.
+func wrap(start, length, sequenceLength int, complement bool) genbank.Location {
+	start = (start%sequenceLength + sequenceLength) % sequenceLength
+	return genbank.Location{Start: start, End: start + length, Complement: complement}
+}
+
+/******************************************************************************
+
+Rule Set 1 begins here.
+
+Doench et al. measured how well 1,841 guides knocked out their genes and fit
+a logistic regression to the 30 bases around each SpCas9 site: 4 bases before
+the spacer, the 20 base spacer, the NGG PAM and 3 bases after it. Each feature
+is a base or pair of bases at a position of those 30 bases, plus a penalty
+for spacers whose GC content is far from 10 of 20.
+
+******************************************************************************/
+
+// ruleSet1Feature is a base or pair of bases at a 0-based position of the 30
+// bases and how much having it adds to the score.
+type ruleSet1Feature struct {
+	position int
+	bases    string
+	weight   float64
+}
+
+// ruleSet1Features are the weights of Doench et al.'s supplementary data.
+var ruleSet1Features = []ruleSet1Feature{
+	{1, "G", -0.2753771}, {2, "A", -0.3238875}, {2, "C", 0.17212887}, {3, "C", -0.1006662},
+	{4, "C", -0.2018029}, {4, "G", 0.24595663}, {5, "A", 0.03644004}, {5, "C", 0.09837684},
+	{6, "C", -0.7411813}, {6, "G", -0.3932644}, {11, "A", -0.466099}, {14, "A", 0.08537695},
+	{14, "C", -0.013814}, {15, "A", 0.27262051}, {15, "C", -0.1190226}, {15, "T", -0.2859442},
+	{16, "A", 0.09745459}, {16, "G", -0.1755462}, {17, "C", -0.3457955}, {17, "G", -0.6780964},
+	{18, "A", 0.22508903}, {18, "C", -0.5077941}, {19, "G", -0.4173736}, {19, "T", -0.054307},
+	{20, "G", 0.37989937}, {20, "T", -0.0907126}, {21, "C", 0.05782332}, {21, "T", -0.5305673},
+	{22, "T", -0.8770074}, {23, "C", -0.8762358}, {23, "G", 0.27891626}, {23, "T", -0.4031022},
+	{24, "A", -0.0773007}, {24, "C", 0.28793562}, {24, "T", -0.2216372}, {27, "G", -0.6890167},
+	{27, "T", 0.11787758}, {28, "C", -0.1604453}, {29, "G", 0.38634258}, {1, "GT", -0.6257787},
+	{4, "GC", 0.30004332}, {5, "AA", -0.8348362}, {5, "TA", 0.76062777}, {6, "GG", -0.4908167},
+	{11, "GG", -1.5169074}, {11, "TA", 0.7092612}, {11, "TC", 0.49629861}, {11, "TT", -0.5868739},
+	{12, "GG", -0.3345637}, {13, "GA", 0.76384993}, {13, "GC", -0.5370252}, {16, "TG", -0.7981461},
+	{18, "GG", -0.6668087}, {18, "TC", 0.35318325}, {19, "CC", 0.74807209}, {19, "TG", -0.3672668},
+	{20, "AC", 0.56820913}, {20, "CG", 0.32907207}, {20, "GA", -0.8364568}, {20, "GG", -0.7822076},
+	{21, "TC", -1.029693}, {22, "CG", 0.85619782}, {22, "CT", -0.4632077}, {23, "AA", -0.5794924},
+	{23, "AG", 0.64907554}, {24, "AG", -0.0773007}, {24, "CG", 0.28793562}, {24, "TG", -0.2216372},
+	{26, "GT", 0.11787758}, {28, "GG", -0.69774},
+}
+
+const (
+	ruleSet1Intercept = 0.59763615
+	ruleSet1GCLow     = -0.2026259 // weight per base the spacer's GC count is below 10, or 10 exactly.
+	ruleSet1GCHigh    = -0.1665878 // weight per base the spacer's GC count is above 10.
+)
+
+// RuleSet1Score returns the Doench et al. Rule Set 1 on-target score of a
+// SpCas9 guide from the 30 bases around its site: the 4 bases before its 20
+// base spacer, the spacer, the PAM and the 3 bases after the PAM. Higher
+// scores are better.
+func RuleSet1Score(context string) (float64, error) {
+	context = strings.ToUpper(context)
+	if len(context) != 30 {
+		return 0, fmt.Errorf("expected 30 bases of context, got %d", len(context))
+	}
+	if strings.Trim(context, "ACGT") != "" {
+		return 0, fmt.Errorf("context %s has bases other than A, C, G and T", context)
+	}
+
+	spacer := context[4:24]
+	gcCount := strings.Count(spacer, "G") + strings.Count(spacer, "C")
+	gcWeight := ruleSet1GCLow
+	if gcCount > 10 {
+		gcWeight = ruleSet1GCHigh
+	}
+	score := ruleSet1Intercept + math.Abs(float64(10-gcCount))*gcWeight
+	for _, feature := range ruleSet1Features {
+		if strings.HasPrefix(context[feature.position:], feature.bases) {
+			score += feature.weight
+		}
+	}
+	return 1 / (1 + math.Exp(-score)), nil
+}
+
+/******************************************************************************
+
+Rule Set 1 ends here.
+
+******************************************************************************/
+
+// OffTarget is a site a guide could bind to found by FindOffTargets.
+type OffTarget struct {
+	Location   genbank.Location // where the spacer and PAM are in the reference.
+	Mismatches int              // mismatches between the site and the spacer.
+}
+
+// FindOffTargets returns every site in an indexed reference that a guide for
+// a nuclease could bind to with at most maxMismatches mismatches in its spacer,
+// sorted by where they start. Sites must have an exact match to the nuclease's
+// PAM. The guide's own target is included, with 0 mismatches, if it's in the
+// reference.
+func FindOffTargets(guide Guide, nuclease Nuclease, reference *search.Index, maxMismatches int) ([]OffTarget, error) {
+	if nuclease.PAM == "" {
+		nuclease = SpCas9
+	}
+	site, pamStart := guide.Spacer+nuclease.PAM, len(guide.Spacer)
+	if nuclease.PAMFivePrime {
+		site, pamStart = nuclease.PAM+guide.Spacer, 0
+	}
+	matches, err := reference.FindApproximate(site, maxMismatches, search.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	sequence := reference.Sequence()
+	var offTargets []OffTarget
+	for _, match := range matches {
+		matched, _ := slice(sequence, match.Location.Start, match.Location.End, true)
+		if match.Location.Complement {
+			matched = transform.ReverseComplement(matched)
+		}
+		pam := matched[pamStart : pamStart+len(nuclease.PAM)]
+		if pams, _ := search.Find(pam, nuclease.PAM, search.Options{ForwardOnly: true}); len(pams) == 0 {
+			continue
+		}
+		offTargets = append(offTargets, OffTarget{match.Location, match.Distance})
+	}
+	return offTargets, nil
+}
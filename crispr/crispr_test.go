@@ -0,0 +1,112 @@
+package crispr_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/TimothyStiles/poly/crispr"
+	"github.com/TimothyStiles/poly/search"
+	"github.com/TimothyStiles/poly/transform"
+)
+
+func TestRuleSet1Score(t *testing.T) {
+	// scores from the reference implementation of Doench et al.
+	for _, test := range []struct {
+		context string
+		score   float64
+	}{
+		{"TATAGCTGCGATCTGAGGTAGGGAGGGACC", 0.713089},
+		{"TCCGCACCTGTCACGGTCGGGGCTTGGCGC", 0.018984},
+	} {
+		score, err := crispr.RuleSet1Score(test.context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(score-test.score) > 1e-6 {
+			t.Errorf("expected %s to score %f, got %f", test.context, test.score, score)
+		}
+	}
+
+	for _, context := range []string{"TATAGCTGCGATCTGAGGTAGGGAGGGAC", "TATAGCTGCGATCTGAGGTAGGGAGGGANC"} {
+		if _, err := crispr.RuleSet1Score(context); err == nil {
+			t.Errorf("expected an error for context %s", context)
+		}
+	}
+}
+
+func TestFindGuides(t *testing.T) {
+	target := "TATAGCTGCGATCTGAGGTAGGGAGGGACCttt"
+	guides, err := crispr.FindGuides(target, crispr.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(guides) != 3 {
+		t.Fatalf("expected 3 guides, got %v", guides)
+	}
+	// only the last guide has the bases around it needed to score it.
+	if guides[0].Spacer != "TATAGCTGCGATCTGAGGTA" || guides[0].PAM != "GGG" || guides[0].Score != 0 {
+		t.Errorf("expected an unscored guide TATAGCTGCGATCTGAGGTA with PAM GGG, got %+v", guides[0])
+	}
+	if guides[2].Location.Start != 4 || guides[2].Location.End != 24 || math.Abs(guides[2].Score-0.713089) > 1e-6 {
+		t.Errorf("expected a guide at 4..24 scoring 0.713089, got %+v", guides[2])
+	}
+
+	// guides on the reverse strand are found at the same places.
+	reverse, _ := crispr.FindGuides(transform.ReverseComplement(target), crispr.Options{})
+	if len(reverse) != 3 {
+		t.Fatalf("expected 3 guides, got %v", reverse)
+	}
+	for index, guide := range reverse {
+		forward := guides[len(guides)-1-index]
+		if !guide.Location.Complement || guide.Spacer != forward.Spacer || guide.Score != forward.Score || guide.Location.Start != len(target)-forward.Location.End {
+			t.Errorf("expected the reverse strand guide %+v to match %+v", guide, forward)
+		}
+	}
+
+	// across the origin of a circular target, the last guide's spacer starts
+	// near the end and carries on from the start.
+	circular, _ := crispr.FindGuides(target, crispr.Options{Circular: true})
+	last := circular[len(circular)-1]
+	if last.Spacer != "TTATAGCTGCGATCTGAGGT" || last.Location.Start != 32 || last.Location.End != 52 {
+		t.Errorf("expected a guide TTATAGCTGCGATCTGAGGT at 32..52, got %+v", last)
+	}
+}
+
+func TestFindGuidesCas12a(t *testing.T) {
+	guides, err := crispr.FindGuides("ggTTTAGATCGATCGATCGATCGATCGATCtgt", crispr.Options{Nuclease: crispr.AsCas12a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(guides) != 1 || guides[0].Spacer != "GATCGATCGATCGATCGATCGAT" || guides[0].PAM != "TTTA" || guides[0].Location.Start != 6 {
+		t.Errorf("expected a guide GATCGATCGATCGATCGATCGAT after TTTA at 6, got %+v", guides)
+	}
+	if guides[0].Score != 0 {
+		t.Errorf("expected Cas12a guides to be unscored, got %f", guides[0].Score)
+	}
+
+	if _, err := crispr.FindGuides("ACGT", crispr.Options{Nuclease: crispr.Nuclease{PAM: "NGG"}}); err == nil {
+		t.Errorf("expected an error for a nuclease without a spacer length")
+	}
+}
+
+func TestFindOffTargets(t *testing.T) {
+	guide := crispr.Guide{Spacer: "TATAGCTGCGATCTGAGGTA"}
+	// the first site has 2 mismatches, the second 3 and the third has a bad
+	// PAM. The fourth has 1 mismatch on the reverse strand.
+	reference := "aaaaGCTGCGATCTGAGGTAGGGaaaaGCTGCGATCTtAGGTAGGGaaTATAGCTGCGATCTGAGGTAGCTaa" + transform.ReverseComplement("TATAGCTGCGATCTGAGcTATGG")
+	index := search.NewIndex(reference, false)
+
+	offTargets, err := crispr.FindOffTargets(guide, crispr.SpCas9, index, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offTargets) != 2 {
+		t.Fatalf("expected 2 off targets, got %v", offTargets)
+	}
+	if offTargets[0].Location.Start != 0 || offTargets[0].Mismatches != 2 || offTargets[0].Location.Complement {
+		t.Errorf("expected an off target at 0 with 2 mismatches, got %+v", offTargets[0])
+	}
+	if offTargets[1].Location.Start != 73 || offTargets[1].Mismatches != 1 || !offTargets[1].Location.Complement {
+		t.Errorf("expected a reverse strand off target at 73 with 1 mismatch, got %+v", offTargets[1])
+	}
+}
@@ -0,0 +1,44 @@
+package crispr_test
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/TimothyStiles/poly/crispr"
+	"github.com/TimothyStiles/poly/io/genbank"
+	"github.com/TimothyStiles/poly/search"
+)
+
+// This example shows how to pick the best guides for knocking out the lacZ
+// alpha fragment in pUC19 that don't have close matches elsewhere in it.
+func Example_basic() {
+	puc19, _ := genbank.Read("../data/puc19.gbk")
+	index := search.NewIndex(puc19.Sequence, true)
+
+	var lacZ string
+	for _, feature := range puc19.Features {
+		if feature.Attributes["label"] == "lacZ-alpha" {
+			lacZ, _ = feature.GetSequence()
+		}
+	}
+
+	guides, _ := crispr.FindGuides(lacZ, crispr.Options{})
+	sort.SliceStable(guides, func(i, j int) bool {
+		return guides[i].Score > guides[j].Score
+	})
+	for _, guide := range guides[:3] {
+		offTargets, _ := crispr.FindOffTargets(guide, crispr.SpCas9, index, 3)
+		fmt.Printf("%s %s %.2f %d\n", guide.Spacer, guide.PAM, guide.Score, len(offTargets))
+	}
+	// Output:
+	// GTCACGACGTTGTAAAACGA CGG 0.79 1
+	// GATTAAGTTGGGTAACGCCA GGG 0.78 1
+	// TTACGCCAGCTGGCGAAAGG GGG 0.67 1
+}
+
+func ExampleRuleSet1Score() {
+	score, _ := crispr.RuleSet1Score("TATAGCTGCGATCTGAGGTAGGGAGGGACC")
+
+	fmt.Printf("%.3f\n", score)
+	// Output: 0.713
+}
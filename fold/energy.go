@@ -0,0 +1,364 @@
+package fold
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+/******************************************************************************
+
+Energy model begins here.
+
+Secondary structures are scored by breaking them into loops, each closed by one
+or more base pairs, and adding up the free energy of every loop. This is the
+nearest neighbor model of Turner for RNA and SantaLucia for DNA, simplified to
+the loop types that matter most:
+
+- stacks: two pairs next to each other, scored by which pairs they are.
+- hairpins: a single pair closing a loop of unpaired bases, scored by length.
+- bulges and interior loops: two pairs with unpaired bases between them on one
+  or both sides, scored by length and asymmetry. Bulges of a single base also
+  keep the stacking energy of the pairs either side of them.
+- multiloops: three or more pairs around a loop, scored linearly as
+  a + b * unpaired bases + c * branches.
+
+Sequence dependent loop bonuses (tetraloops, terminal mismatches, dangling ends
+and the special tables for small interior loops) aren't included, so energies
+are usually within a kcal/mol or two of ViennaRNA or mfold for the same
+structure rather than identical to them. Energies are for 37°C in 1 M salt.
+
+GU wobble pairs are allowed in RNA and are stacked as if they were AU pairs,
+which is a rough approximation of their real stacking energies.
+
+******************************************************************************/
+
+// NucleicAcid is the kind of sequence being folded, which decides which pairs
+// are allowed and which energy parameters are used.
+type NucleicAcid int
+
+const (
+	RNA NucleicAcid = iota
+	DNA
+)
+
+// ErrInvalidSequence is returned when a sequence has bases other than A, C, G, T and U.
+var ErrInvalidSequence = errors.New("invalid sequence")
+
+// ErrInvalidStructure is returned by Energy when a dot-bracket structure is
+// unbalanced, doesn't match its sequence or pairs bases that can't pair.
+var ErrInvalidStructure = errors.New("invalid structure")
+
+// infinity marks impossible structures. It is low enough that adding a few energies to it doesn't overflow.
+const infinity = math.MaxInt32 / 4
+
+// maxLoop is the longest interior loop or bulge considered, the same limit mfold and ViennaRNA use.
+const maxLoop = 30
+
+// minHairpin is the fewest unpaired bases a hairpin loop can have.
+const minHairpin = 3
+
+// model holds the energy parameters of a nucleic acid. Energies are in
+// hundredths of a kcal/mol so they can be added up exactly.
+type model struct {
+	// stacks is keyed by the top strand bases of two stacked pairs, 5' to 3'.
+	stacks map[string]int
+	// hairpins, bulges and interiors are loop initiation energies by loop length.
+	hairpins  []int
+	bulges    []int
+	interiors []int
+	// terminal is the penalty for closing a helix with an AU, GU or AT pair.
+	terminal int
+	// ninio is the penalty per unpaired base of interior loop asymmetry, up to maxNinio.
+	ninio    int
+	maxNinio int
+	// multiA, multiB and multiC are a, b and c of the multiloop energy.
+	multiA int
+	multiB int
+	multiC int
+	// pairs lists the base pairs that can form.
+	pairs map[[2]byte]bool
+}
+
+// rnaModel holds Turner 2004 parameters [Mathews DH et al. (2004) PNAS, doi:10.1073/pnas.0401799101]
+// with Turner 1999 multiloop parameters.
+var rnaModel = &model{
+	stacks: map[string]int{
+		"AA": -93, "UU": -93,
+		"AU": -110,
+		"UA": -133,
+		"CU": -208, "AG": -208,
+		"CA": -211, "UG": -211,
+		"GU": -224, "AC": -224,
+		"GA": -235, "UC": -235,
+		"CG": -236,
+		"GG": -326, "CC": -326,
+		"GC": -342,
+	},
+	hairpins:  []int{3: 540, 4: 560, 5: 570, 6: 540, 7: 600, 8: 550, 9: 640},
+	bulges:    []int{1: 380, 2: 280, 3: 320, 4: 360, 5: 400, 6: 440, 7: 459, 8: 470, 9: 480, 10: 490},
+	interiors: []int{2: 50, 3: 160, 4: 110, 5: 200, 6: 200, 7: 210, 8: 230, 9: 240, 10: 250},
+	terminal:  50,
+	ninio:     60,
+	maxNinio:  300,
+	multiA:    340,
+	multiB:    0,
+	multiC:    40,
+	pairs: map[[2]byte]bool{
+		{'A', 'U'}: true, {'U', 'A'}: true,
+		{'C', 'G'}: true, {'G', 'C'}: true,
+		{'G', 'U'}: true, {'U', 'G'}: true,
+	},
+}
+
+// dnaModel holds SantaLucia's parameters [SantaLucia J & Hicks D (2004) Annu Rev Biophys Biomol Struct, doi:10.1146/annurev.biophys.32.110601.141800]
+// with the same multiloop parameters as RNA.
+var dnaModel = &model{
+	stacks: map[string]int{
+		"AA": -100, "TT": -100,
+		"AT": -88,
+		"TA": -58,
+		"CA": -145, "TG": -145,
+		"GT": -144, "AC": -144,
+		"CT": -128, "AG": -128,
+		"GA": -130, "TC": -130,
+		"CG": -217,
+		"GC": -224,
+		"GG": -184, "CC": -184,
+	},
+	hairpins:  []int{3: 350, 4: 350, 5: 330, 6: 400, 7: 420, 8: 430, 9: 450, 10: 460},
+	bulges:    []int{1: 400, 2: 290, 3: 310, 4: 320, 5: 330, 6: 350, 7: 370, 8: 390, 9: 410, 10: 430},
+	interiors: []int{2: 320, 3: 320, 4: 360, 5: 400, 6: 440, 7: 460, 8: 480, 9: 490, 10: 490},
+	terminal:  5,
+	ninio:     60,
+	maxNinio:  300,
+	multiA:    340,
+	multiB:    0,
+	multiC:    40,
+	pairs: map[[2]byte]bool{
+		{'A', 'T'}: true, {'T', 'A'}: true,
+		{'C', 'G'}: true, {'G', 'C'}: true,
+	},
+}
+
+// getModel returns the energy model of a nucleic acid.
+func getModel(nucleicAcid NucleicAcid) (*model, error) {
+	switch nucleicAcid {
+	case RNA:
+		return rnaModel, nil
+	case DNA:
+		return dnaModel, nil
+	}
+	return nil, fmt.Errorf("unknown nucleic acid %d", nucleicAcid)
+}
+
+// normalize uppercases a sequence, writes it in the alphabet of the nucleic
+// acid and checks it only has valid bases.
+func normalize(sequence string, nucleicAcid NucleicAcid) ([]byte, error) {
+	sequence = strings.ToUpper(sequence)
+	if nucleicAcid == RNA {
+		sequence = strings.ReplaceAll(sequence, "T", "U")
+	} else {
+		sequence = strings.ReplaceAll(sequence, "U", "T")
+	}
+	for index := range sequence {
+		if !strings.ContainsRune("ACGTU", rune(sequence[index])) {
+			return nil, fmt.Errorf("%w: base %q at position %d", ErrInvalidSequence, sequence[index], index)
+		}
+	}
+	return []byte(sequence), nil
+}
+
+// energies scores the loops of a particular sequence.
+type energies struct {
+	sequence []byte
+	model    *model
+	// pairs[a][b] is whether base a can pair with base b, looked up far more
+	// often than anything else so kept as an array rather than a map.
+	pairs *[256][256]bool
+}
+
+// newEnergies returns the energies of a normalized sequence.
+func newEnergies(sequence []byte, model *model) energies {
+	pairs := new([256][256]bool)
+	for pair := range model.pairs {
+		pairs[pair[0]][pair[1]] = true
+	}
+	return energies{sequence: sequence, model: model, pairs: pairs}
+}
+
+// canPair returns whether sequence[i] and sequence[j] can pair with each other.
+func (e energies) canPair(i, j int) bool {
+	return e.pairs[e.sequence[i]][e.sequence[j]]
+}
+
+// terminal returns the penalty for a helix ending with the pair i, j.
+func (e energies) terminal(i, j int) int {
+	if e.sequence[i] == 'G' && e.sequence[j] == 'C' || e.sequence[i] == 'C' && e.sequence[j] == 'G' {
+		return 0
+	}
+	return e.model.terminal
+}
+
+// stackBase returns the base at i as it's written in the stacking table,
+// where GU pairs are treated like AU pairs.
+func (e energies) stackBase(i, j int) byte {
+	if e.sequence[i] == 'G' && e.sequence[j] == 'U' {
+		return 'A'
+	}
+	return e.sequence[i]
+}
+
+// stack returns the energy of the pair i, j stacked on the pair k, l.
+func (e energies) stack(i, j, k, l int) int {
+	return e.model.stacks[string([]byte{e.stackBase(i, j), e.stackBase(k, l)})]
+}
+
+// loopEnergy looks up the initiation energy of a loop, extrapolating
+// logarithmically past the end of the table like Jacobson and Stockmayer.
+func loopEnergy(table []int, length int) int {
+	if length < len(table) {
+		return table[length]
+	}
+	const extrapolation = 107.856 // 1.75 RT at 37°C, in hundredths of a kcal/mol.
+	longest := len(table) - 1
+	return table[longest] + int(math.Round(extrapolation*math.Log(float64(length)/float64(longest))))
+}
+
+// hairpin returns the energy of a hairpin loop closed by the pair i, j.
+func (e energies) hairpin(i, j int) int {
+	length := j - i - 1
+	if length < minHairpin {
+		return infinity
+	}
+	energy := loopEnergy(e.model.hairpins, length)
+	if length == minHairpin {
+		energy += e.terminal(i, j)
+	}
+	return energy
+}
+
+// interior returns the energy of the loop between the pair i, j and the pair
+// k, l inside it, whether that's a stack, a bulge or an interior loop.
+func (e energies) interior(i, j, k, l int) int {
+	left, right := k-i-1, j-l-1
+	switch {
+	case left == 0 && right == 0:
+		return e.stack(i, j, k, l)
+	case left == 0 || right == 0:
+		length := left + right
+		energy := loopEnergy(e.model.bulges, length)
+		if length == 1 {
+			return energy + e.stack(i, j, k, l)
+		}
+		return energy + e.terminal(i, j) + e.terminal(k, l)
+	}
+	asymmetry := left - right
+	if asymmetry < 0 {
+		asymmetry = -asymmetry
+	}
+	ninio := asymmetry * e.model.ninio
+	if ninio > e.model.maxNinio {
+		ninio = e.model.maxNinio
+	}
+	return loopEnergy(e.model.interiors, left+right) + ninio
+}
+
+// Energy returns the free energy in kcal/mol of a sequence folded into a
+// structure written in dot-bracket notation, where matching brackets are
+// paired bases and dots are unpaired.
+func Energy(sequence, structure string, nucleicAcid NucleicAcid) (float64, error) {
+	model, err := getModel(nucleicAcid)
+	if err != nil {
+		return 0, err
+	}
+	normalized, err := normalize(sequence, nucleicAcid)
+	if err != nil {
+		return 0, err
+	}
+	if len(structure) != len(normalized) {
+		return 0, fmt.Errorf("%w: structure has %d characters for a sequence of %d bases", ErrInvalidStructure, len(structure), len(normalized))
+	}
+	pairs, err := parseStructure(structure)
+	if err != nil {
+		return 0, err
+	}
+	e := newEnergies(normalized, model)
+	for i, j := range pairs {
+		if j > i && !e.canPair(i, j) {
+			return 0, fmt.Errorf("%w: %c at %d can't pair with %c at %d", ErrInvalidStructure, normalized[i], i, normalized[j], j)
+		}
+		if j > i && j-i-1 < minHairpin {
+			return 0, fmt.Errorf("%w: the pair of %d and %d leaves fewer than %d bases for a hairpin loop", ErrInvalidStructure, i, j, minHairpin)
+		}
+	}
+
+	total := 0
+	for i := 0; i < len(pairs); i++ {
+		if pairs[i] > i {
+			total += e.terminal(i, pairs[i]) + e.loop(pairs, i, pairs[i])
+			i = pairs[i]
+		}
+	}
+	return float64(total) / 100, nil
+}
+
+// loop returns the energy of the loop closed by the pair i, j plus the energy of everything inside it.
+func (e energies) loop(pairs []int, i, j int) int {
+	var inner [][2]int
+	for k := i + 1; k < j; k++ {
+		if pairs[k] > k {
+			inner = append(inner, [2]int{k, pairs[k]})
+			k = pairs[k]
+		}
+	}
+	switch len(inner) {
+	case 0:
+		return e.hairpin(i, j)
+	case 1:
+		k, l := inner[0][0], inner[0][1]
+		return e.interior(i, j, k, l) + e.loop(pairs, k, l)
+	}
+	unpaired := j - i - 1
+	energy := e.model.multiA + e.model.multiC + e.terminal(i, j)
+	for _, pair := range inner {
+		unpaired -= pair[1] - pair[0] + 1
+		energy += e.model.multiC + e.terminal(pair[0], pair[1]) + e.loop(pairs, pair[0], pair[1])
+	}
+	return energy + unpaired*e.model.multiB
+}
+
+// parseStructure returns the index of the base each base of a dot-bracket
+// structure is paired with, or -1 for unpaired bases.
+func parseStructure(structure string) ([]int, error) {
+	pairs := make([]int, len(structure))
+	var open []int
+	for index, character := range structure {
+		switch character {
+		case '.':
+			pairs[index] = -1
+		case '(':
+			open = append(open, index)
+		case ')':
+			if len(open) == 0 {
+				return nil, fmt.Errorf("%w: unmatched ) at %d", ErrInvalidStructure, index)
+			}
+			partner := open[len(open)-1]
+			open = open[:len(open)-1]
+			pairs[index], pairs[partner] = partner, index
+		default:
+			return nil, fmt.Errorf("%w: unexpected character %q at %d", ErrInvalidStructure, character, index)
+		}
+	}
+	if len(open) > 0 {
+		return nil, fmt.Errorf("%w: unmatched ( at %d", ErrInvalidStructure, open[len(open)-1])
+	}
+	return pairs, nil
+}
+
+/******************************************************************************
+
+Energy model ends here.
+
+******************************************************************************/
@@ -0,0 +1,22 @@
+package fold_test
+
+import (
+	"fmt"
+
+	"github.com/TimothyStiles/poly/fold"
+)
+
+func ExampleZuker() {
+	result, _ := fold.Zuker("GGGGAAAACCCC", fold.RNA)
+
+	fmt.Println(result.Structure, result.DeltaG)
+	// Output: ((((....)))) -4.18
+}
+
+func ExampleEnergy() {
+	// the same stem with an unpaired base in the loop closing pair.
+	deltaG, _ := fold.Energy("GGGGAAAACCCC", ".(((....))).", fold.RNA)
+
+	fmt.Printf("%.2f\n", deltaG)
+	// Output: -0.92
+}
@@ -0,0 +1,272 @@
+/*
+Package fold predicts the secondary structure of DNA and RNA.
+
+Single stranded DNA and RNA fold back on themselves, pairing up bases into
+helices separated by loops. Those structures decide a lot of what happens to
+a sequence: a hairpin at the start of an mRNA hides its ribosome binding site,
+a stable structure in an oligo stops it from being synthesized or assembled,
+and a primer folded up on itself won't bind its template.
+
+Zuker finds the minimum free energy (MFE) structure, the most stable one, with
+Zuker's dynamic programming algorithm [Zuker M & Stiegler P (1981) Nucleic
+Acids Res, doi:10.1093/nar/9.1.133]. Structures are written in dot-bracket
+notation, where matching brackets are paired bases and dots are unpaired, and
+come with their free energy (dG) in kcal/mol. The more negative dG is, the
+more stable the structure.
+
+Zuker takes O(n^3) time, so long sequences like whole mRNAs are better folded
+with LinearFold, which uses the same energy model but only keeps the most
+promising partial structures as it scans the sequence, and runs in linear time.
+
+Energy scores any structure with the same energy model.
+*/
+package fold
+
+import (
+	"strings"
+)
+
+// Result is a folded structure.
+type Result struct {
+	Structure string  // dot-bracket structure.
+	DeltaG    float64 // free energy of the structure in kcal/mol at 37°C.
+}
+
+/******************************************************************************
+
+Zuker begins here.
+
+Three tables are filled in for every stretch of the sequence i to j:
+
+- paired[i][j] is the lowest energy of i to j given that i pairs with j.
+- multi[i][j] is the lowest energy of i to j as part of a multiloop, with at
+  least one pair inside it.
+- exterior[j] is the lowest energy of the first j bases, which aren't inside
+  any loop.
+
+Interior loops are limited to maxLoop unpaired bases, which is what keeps the
+algorithm O(n^3) rather than O(n^4), the same trick Lyngso et al. refined.
+
+******************************************************************************/
+
+// Zuker returns the minimum free energy structure of a sequence. RNA and DNA
+// are folded with their own energy parameters, and T and U are treated as the
+// same base. Sequences with no stable structure give an unpaired structure
+// with a dG of 0.
+func Zuker(sequence string, nucleicAcid NucleicAcid) (Result, error) {
+	model, err := getModel(nucleicAcid)
+	if err != nil {
+		return Result{}, err
+	}
+	normalized, err := normalize(sequence, nucleicAcid)
+	if err != nil {
+		return Result{}, err
+	}
+	z := newZuker(newEnergies(normalized, model))
+	z.fill()
+	return Result{Structure: z.traceBack(), DeltaG: float64(z.exterior[len(normalized)]) / 100}, nil
+}
+
+// zuker holds the dynamic programming tables of a sequence.
+type zuker struct {
+	energies
+	length   int
+	paired   [][]int
+	multi    [][]int
+	exterior []int
+}
+
+// newZuker allocates the tables for a sequence with every entry set to infinity.
+func newZuker(e energies) *zuker {
+	length := len(e.sequence)
+	z := &zuker{energies: e, length: length, exterior: make([]int, length+1)}
+	z.paired, z.multi = make([][]int, length), make([][]int, length)
+	for i := 0; i < length; i++ {
+		z.paired[i], z.multi[i] = make([]int, length), make([]int, length)
+		for j := range z.paired[i] {
+			z.paired[i][j], z.multi[i][j] = infinity, infinity
+		}
+	}
+	return z
+}
+
+// fill fills in the tables from the shortest stretches of the sequence to the longest.
+func (z *zuker) fill() {
+	for span := minHairpin + 1; span < z.length; span++ {
+		for i := 0; i+span < z.length; i++ {
+			j := i + span
+			z.paired[i][j] = z.bestPaired(i, j)
+			z.multi[i][j] = z.bestMulti(i, j)
+		}
+	}
+	for j := 1; j <= z.length; j++ {
+		z.exterior[j] = z.bestExterior(j)
+	}
+}
+
+// bestPaired returns the lowest energy of i to j closed by the pair i, j.
+func (z *zuker) bestPaired(i, j int) int {
+	if !z.canPair(i, j) {
+		return infinity
+	}
+	best := z.hairpin(i, j)
+	z.eachInterior(i, j, func(k, l, energy int) {
+		if energy < best {
+			best = energy
+		}
+	})
+	if energy := z.closedMultiloop(i, j); energy < best {
+		best = energy
+	}
+	return best
+}
+
+// eachInterior calls found with every pair k, l that can close an interior
+// loop, bulge or stack inside the pair i, j and the energy of i to j if it does.
+func (z *zuker) eachInterior(i, j int, found func(k, l, energy int)) {
+	for k := i + 1; k <= i+maxLoop+1 && k < j; k++ {
+		for l := j - 1; l > k+minHairpin && (k-i-1)+(j-l-1) <= maxLoop; l-- {
+			if z.paired[k][l] >= infinity {
+				continue
+			}
+			found(k, l, z.interior(i, j, k, l)+z.paired[k][l])
+		}
+	}
+}
+
+// closedMultiloop returns the lowest energy of a multiloop closed by the pair i, j.
+func (z *zuker) closedMultiloop(i, j int) int {
+	best := infinity
+	for u := i + 2; u < j-1; u++ {
+		if z.multi[i+1][u] >= infinity || z.multi[u+1][j-1] >= infinity {
+			continue
+		}
+		if energy := z.multi[i+1][u] + z.multi[u+1][j-1]; energy < best {
+			best = energy
+		}
+	}
+	if best >= infinity {
+		return infinity
+	}
+	return best + z.model.multiA + z.model.multiC + z.terminal(i, j)
+}
+
+// branch returns the energy of the pair i, j as a branch of a multiloop.
+func (z *zuker) branch(i, j int) int {
+	if z.paired[i][j] >= infinity {
+		return infinity
+	}
+	return z.paired[i][j] + z.model.multiC + z.terminal(i, j)
+}
+
+// bestMulti returns the lowest energy of i to j inside a multiloop.
+func (z *zuker) bestMulti(i, j int) int {
+	best := z.branch(i, j)
+	if energy := z.multi[i+1][j] + z.model.multiB; energy < best {
+		best = energy
+	}
+	if energy := z.multi[i][j-1] + z.model.multiB; energy < best {
+		best = energy
+	}
+	for u := i + 1; u < j; u++ {
+		if z.multi[i][u] >= infinity || z.multi[u+1][j] >= infinity {
+			continue
+		}
+		if energy := z.multi[i][u] + z.multi[u+1][j]; energy < best {
+			best = energy
+		}
+	}
+	if best > infinity {
+		return infinity
+	}
+	return best
+}
+
+// bestExterior returns the lowest energy of the first j bases.
+func (z *zuker) bestExterior(j int) int {
+	best := z.exterior[j-1]
+	for i := 0; i+minHairpin+1 < j; i++ {
+		if z.paired[i][j-1] >= infinity {
+			continue
+		}
+		if energy := z.exterior[i] + z.paired[i][j-1] + z.terminal(i, j-1); energy < best {
+			best = energy
+		}
+	}
+	return best
+}
+
+// traceBack rebuilds the structure the tables' energies came from.
+func (z *zuker) traceBack() string {
+	structure := []byte(strings.Repeat(".", z.length))
+	var pair func(i, j int)
+	var inMulti func(i, j int)
+
+	pair = func(i, j int) {
+		structure[i], structure[j] = '(', ')'
+		target := z.paired[i][j]
+		if z.hairpin(i, j) == target {
+			return
+		}
+		found := false
+		z.eachInterior(i, j, func(k, l, energy int) {
+			if !found && energy == target {
+				found = true
+				pair(k, l)
+			}
+		})
+		if found {
+			return
+		}
+		for u := i + 2; u < j-1; u++ {
+			if z.multi[i+1][u]+z.multi[u+1][j-1]+z.model.multiA+z.model.multiC+z.terminal(i, j) == target {
+				inMulti(i+1, u)
+				inMulti(u+1, j-1)
+				return
+			}
+		}
+	}
+
+	inMulti = func(i, j int) {
+		target := z.multi[i][j]
+		switch {
+		case z.branch(i, j) == target:
+			pair(i, j)
+			return
+		case z.multi[i+1][j]+z.model.multiB == target:
+			inMulti(i+1, j)
+			return
+		case z.multi[i][j-1]+z.model.multiB == target:
+			inMulti(i, j-1)
+			return
+		}
+		for u := i + 1; u < j; u++ {
+			if z.multi[i][u]+z.multi[u+1][j] == target {
+				inMulti(i, u)
+				inMulti(u+1, j)
+				return
+			}
+		}
+	}
+
+	for j := z.length; j > 0; {
+		if z.exterior[j] == z.exterior[j-1] {
+			j--
+			continue
+		}
+		for i := 0; i+minHairpin+1 < j; i++ {
+			if z.paired[i][j-1] < infinity && z.exterior[i]+z.paired[i][j-1]+z.terminal(i, j-1) == z.exterior[j] {
+				pair(i, j-1)
+				j = i
+				break
+			}
+		}
+	}
+	return string(structure)
+}
+
+/******************************************************************************
+
+Zuker ends here.
+
+******************************************************************************/
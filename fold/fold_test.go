@@ -0,0 +1,143 @@
+package fold_test
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/TimothyStiles/poly/fold"
+)
+
+func TestZuker(t *testing.T) {
+	for _, test := range []struct {
+		sequence    string
+		nucleicAcid fold.NucleicAcid
+		structure   string
+	}{
+		// a GC rich stem around a 4 base loop.
+		{"GGGGAAAACCCC", fold.RNA, "((((....))))"},
+		{"GGGGAAAACCCC", fold.DNA, "((((....))))"},
+		// nothing can pair.
+		{"AAAAAAAAAA", fold.RNA, ".........."},
+		// a multiloop of three hairpins.
+		{"GGGGAAAGGCAGAAAACUGCCAAGUCCGAAAACGGACAAACCCC", fold.RNA, "((((...(((((....)))))..(((((....)))))...))))"},
+	} {
+		result, err := fold.Zuker(test.sequence, test.nucleicAcid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Structure != test.structure {
+			t.Errorf("expected %s to fold into %s, got %s", test.sequence, test.structure, result.Structure)
+		}
+	}
+}
+
+func TestZukerEnergy(t *testing.T) {
+	// the dG of the MFE structure should be what Energy gives for it, and no
+	// worse than a few structures picked by hand.
+	random := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 20; trial++ {
+		var sequence strings.Builder
+		for index := 0; index < 20+random.Intn(60); index++ {
+			sequence.WriteByte("ACGU"[random.Intn(4)])
+		}
+		for _, nucleicAcid := range []fold.NucleicAcid{fold.RNA, fold.DNA} {
+			result, err := fold.Zuker(sequence.String(), nucleicAcid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			energy, err := fold.Energy(sequence.String(), result.Structure, nucleicAcid)
+			if err != nil {
+				t.Fatalf("the MFE structure %s of %s isn't valid: %v", result.Structure, sequence.String(), err)
+			}
+			if math.Abs(energy-result.DeltaG) > 1e-9 {
+				t.Errorf("Zuker gave %s a dG of %f but Energy gives %f", result.Structure, result.DeltaG, energy)
+			}
+			// the MFE structure is at least as stable as the structure of either half.
+			for _, half := range []string{sequence.String()[:10], sequence.String()[10:]} {
+				halfResult, _ := fold.Zuker(half, nucleicAcid)
+				if halfResult.DeltaG < result.DeltaG-1e-9 {
+					t.Errorf("%s folds into %s with a dG of %f, below the MFE of the whole sequence %f", half, halfResult.Structure, halfResult.DeltaG, result.DeltaG)
+				}
+			}
+			if result.DeltaG > 0 {
+				t.Errorf("an MFE structure can't be less stable than the unfolded sequence, got %f", result.DeltaG)
+			}
+		}
+	}
+}
+
+func TestEnergy(t *testing.T) {
+	// a stack of GC pairs on a hairpin of 4: 3 stacks plus the loop.
+	energy, err := fold.Energy("GGGGAAAACCCC", "((((....))))", fold.RNA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 3*-3.26 + 5.6; math.Abs(energy-expected) > 1e-9 {
+		t.Errorf("expected a dG of %f, got %f", expected, energy)
+	}
+
+	for _, invalid := range []struct{ sequence, structure string }{
+		{"GGGGAAAACCCC", "((((....)))"},
+		{"GGGGAAAACCCC", "((((....))))("},
+		{"GGGGAAAACCCC", "(((((..)))))"},
+		{"GGGGAAAACCCC", "((((....)))]"},
+		{"AAAAAAAAAAAA", "((((....))))"},
+	} {
+		if _, err := fold.Energy(invalid.sequence, invalid.structure, fold.RNA); !errors.Is(err, fold.ErrInvalidStructure) {
+			t.Errorf("expected an invalid structure error for %s, got %v", invalid.structure, err)
+		}
+	}
+	if _, err := fold.Zuker("GGGGNAAACCCC", fold.RNA); !errors.Is(err, fold.ErrInvalidSequence) {
+		t.Errorf("expected an invalid sequence error, got %v", err)
+	}
+}
+
+func TestLinearFold(t *testing.T) {
+	random := rand.New(rand.NewSource(2))
+	for trial := 0; trial < 50; trial++ {
+		var sequence strings.Builder
+		for index := 0; index < 10+random.Intn(23); index++ {
+			sequence.WriteByte("ACGU"[random.Intn(4)])
+		}
+		for _, nucleicAcid := range []fold.NucleicAcid{fold.RNA, fold.DNA} {
+			// with a beam wide enough to keep everything, LinearFold should find the MFE.
+			linear, err := fold.LinearFold(sequence.String(), nucleicAcid, 1000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			zuker, _ := fold.Zuker(sequence.String(), nucleicAcid)
+			if math.Abs(linear.DeltaG-zuker.DeltaG) > 1e-9 {
+				t.Errorf("LinearFold gave %s a dG of %f (%s) but Zuker found %f (%s)", sequence.String(), linear.DeltaG, linear.Structure, zuker.DeltaG, zuker.Structure)
+			}
+			energy, err := fold.Energy(sequence.String(), linear.Structure, nucleicAcid)
+			if err != nil || math.Abs(energy-linear.DeltaG) > 1e-9 {
+				t.Errorf("LinearFold gave %s a dG of %f but Energy gives %f, %v", linear.Structure, linear.DeltaG, energy, err)
+			}
+		}
+	}
+}
+
+func TestLinearFoldLong(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping a 3kb fold in short mode")
+	}
+	random := rand.New(rand.NewSource(3))
+	sequence := make([]byte, 3000)
+	for index := range sequence {
+		sequence[index] = "ACGU"[random.Intn(4)]
+	}
+	result, err := fold.LinearFold(string(sequence), fold.RNA, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	energy, err := fold.Energy(string(sequence), result.Structure, fold.RNA)
+	if err != nil || math.Abs(energy-result.DeltaG) > 1e-9 {
+		t.Errorf("LinearFold gave a dG of %f but Energy gives %f, %v", result.DeltaG, energy, err)
+	}
+	if result.DeltaG >= 0 {
+		t.Errorf("expected a random 3kb RNA to have a stable structure, got %f", result.DeltaG)
+	}
+}
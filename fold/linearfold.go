@@ -0,0 +1,303 @@
+package fold
+
+import (
+	"sort"
+	"strings"
+)
+
+/******************************************************************************
+
+LinearFold begins here.
+
+Zuker takes O(n^3) time and O(n^2) memory, which is fine for oligos and short
+RNAs but takes minutes on a 3kb mRNA. LinearFold [Huang L et al. (2019)
+Bioinformatics, doi:10.1093/bioinformatics/btz375] gets the same kind of
+answer in linear time by scanning the sequence left to right and, at each
+position, only keeping the beamSize most promising partial structures of each
+kind ending there. Good structures are almost never pruned, so it usually
+finds the MFE structure or one very close to it.
+
+The partial structures kept at each position j, each keyed by where they start, are:
+
+- hairpin: i pairs with j around a hairpin loop.
+- paired: i pairs with j around anything.
+- multi: i pairs with j around a multiloop.
+- branches and twoBranches: i to j is part of a multiloop with at least one or
+  two pairs in it.
+- exterior: the lowest energy of everything up to j.
+
+Unlike Zuker, the unpaired bases between a multiloop's closing pair and its
+first branch are limited to maxLoop.
+
+******************************************************************************/
+
+// defaultBeamSize is the beam size LinearFold uses when it isn't given one, the same default as the original.
+const defaultBeamSize = 100
+
+// LinearFold returns a low free energy structure of a sequence in time linear
+// in its length, using beam search over the same energy model as Zuker. A
+// beamSize of 0 or less uses the default of 100; larger beams are slower but
+// less likely to miss the MFE structure.
+func LinearFold(sequence string, nucleicAcid NucleicAcid, beamSize int) (Result, error) {
+	model, err := getModel(nucleicAcid)
+	if err != nil {
+		return Result{}, err
+	}
+	normalized, err := normalize(sequence, nucleicAcid)
+	if err != nil {
+		return Result{}, err
+	}
+	if beamSize <= 0 {
+		beamSize = defaultBeamSize
+	}
+	if len(normalized) == 0 {
+		return Result{}, nil
+	}
+	l := newLinearFolder(newEnergies(normalized, model), beamSize)
+	l.fill()
+	return Result{Structure: l.traceBack(), DeltaG: float64(l.exterior[len(normalized)-1].energy) / 100}, nil
+}
+
+// backPointer kinds record how a partial structure was made.
+const (
+	fromHairpin     = iota // a paired state around a hairpin loop.
+	fromInterior           // a paired state around the pair k, l.
+	fromMulti              // a paired state closing the multi state of the same span.
+	fromTwoBranches        // a multi state or a branches state from the twoBranches state k to l.
+	fromPaired             // a branches state from the paired state of the same span, or an exterior state from the paired state k, j.
+	fromSplit              // a twoBranches state from the branches state i to k-1 and the paired state k, j.
+	fromUnpaired           // any state extended by an unpaired base at its end.
+)
+
+// beamState is the best energy found for a partial structure and how it was made.
+type beamState struct {
+	energy int
+	kind   int
+	k, l   int
+}
+
+// linearFolder holds the beams of a sequence, one map per position for each kind of state.
+type linearFolder struct {
+	energies
+	beamSize    int
+	length      int
+	hairpin     []map[int]beamState
+	paired      []map[int]beamState
+	multi       []map[int]beamState
+	branches    []map[int]beamState
+	twoBranches []map[int]beamState
+	exterior    []beamState
+	// nextBase[i][index] is the first position from i onwards holding bases[index].
+	nextBase [][4]int
+	bases    string
+}
+
+// newLinearFolder allocates the beams of a sequence.
+func newLinearFolder(e energies, beamSize int) *linearFolder {
+	length := len(e.sequence)
+	l := &linearFolder{energies: e, beamSize: beamSize, length: length, exterior: make([]beamState, length)}
+	for _, beams := range []*[]map[int]beamState{&l.hairpin, &l.paired, &l.multi, &l.branches, &l.twoBranches} {
+		*beams = make([]map[int]beamState, length+1)
+		for index := range *beams {
+			(*beams)[index] = make(map[int]beamState)
+		}
+	}
+	l.bases = "ACGU"
+	if e.model == dnaModel {
+		l.bases = "ACGT"
+	}
+	l.nextBase = make([][4]int, length+1)
+	l.nextBase[length] = [4]int{length, length, length, length}
+	for i := length - 1; i >= 0; i-- {
+		l.nextBase[i] = l.nextBase[i+1]
+		l.nextBase[i][strings.IndexByte(l.bases, e.sequence[i])] = i
+	}
+	return l
+}
+
+// nextPairable returns the first position from start onwards that can pair
+// with i, or the length of the sequence if there isn't one.
+func (l *linearFolder) nextPairable(i, start int) int {
+	if start >= l.length {
+		return l.length
+	}
+	next := l.length
+	for index := range l.bases {
+		if position := l.nextBase[start][index]; position < next && l.canPair(i, position) {
+			next = position
+		}
+	}
+	return next
+}
+
+// update keeps a state if it's better than the one already found for its span.
+func update(beam map[int]beamState, start int, state beamState) {
+	if existing, ok := beam[start]; !ok || state.energy < existing.energy {
+		beam[start] = state
+	}
+}
+
+// prune drops all but the beamSize best states of a beam, ranking them by
+// their energy plus the exterior energy before them. It returns the kept
+// starts in increasing order.
+func (l *linearFolder) prune(beam map[int]beamState) []int {
+	starts := make([]int, 0, len(beam))
+	for start := range beam {
+		starts = append(starts, start)
+	}
+	if len(starts) > l.beamSize {
+		scores := make(map[int]int, len(starts))
+		for _, start := range starts {
+			scores[start] = beam[start].energy
+			if start > 0 {
+				scores[start] += l.exterior[start-1].energy
+			}
+		}
+		sort.Slice(starts, func(a, b int) bool {
+			if scores[starts[a]] != scores[starts[b]] {
+				return scores[starts[a]] < scores[starts[b]]
+			}
+			return starts[a] < starts[b]
+		})
+		for _, start := range starts[l.beamSize:] {
+			delete(beam, start)
+		}
+		starts = starts[:l.beamSize]
+	}
+	sort.Ints(starts)
+	return starts
+}
+
+// fill scans the sequence left to right, pushing each kept state forward to the states it can become.
+func (l *linearFolder) fill() {
+	multiB := l.model.multiB
+	for i := 0; i < l.length; i++ {
+		if j := l.nextPairable(i, i+minHairpin+1); j < l.length {
+			update(l.hairpin[j], i, beamState{energy: l.energies.hairpin(i, j), kind: fromHairpin})
+		}
+	}
+
+	for j := 0; j < l.length; j++ {
+		for _, i := range l.prune(l.hairpin[j]) {
+			update(l.paired[j], i, l.hairpin[j][i])
+			if next := l.nextPairable(i, j+1); next < l.length {
+				update(l.hairpin[next], i, beamState{energy: l.energies.hairpin(i, next), kind: fromHairpin})
+			}
+		}
+
+		for _, i := range l.prune(l.multi[j]) {
+			state := l.multi[j][i]
+			update(l.paired[j], i, beamState{energy: state.energy + l.model.multiA + l.model.multiC + l.terminal(i, j), kind: fromMulti})
+			if next := l.nextPairable(i, j+1); next < l.length {
+				update(l.multi[next], i, beamState{energy: state.energy + (next-j)*multiB, kind: state.kind, k: state.k, l: state.l})
+			}
+		}
+
+		l.exterior[j] = beamState{kind: fromUnpaired}
+		if j > 0 {
+			l.exterior[j].energy = l.exterior[j-1].energy
+		}
+		for _, i := range l.prune(l.paired[j]) {
+			energy := l.paired[j][i].energy
+			before := 0
+			if i > 0 {
+				before = l.exterior[i-1].energy
+			}
+			if exterior := before + energy + l.terminal(i, j); exterior < l.exterior[j].energy {
+				l.exterior[j] = beamState{energy: exterior, kind: fromPaired, k: i}
+			}
+
+			// i, j as the inner pair of a stack, bulge or interior loop.
+			for p := i - 1; p >= 0 && i-p-1 <= maxLoop; p-- {
+				for q := l.nextPairable(p, j+1); q < l.length && (i-p-1)+(q-j-1) <= maxLoop; q = l.nextPairable(p, q+1) {
+					update(l.paired[q], p, beamState{energy: energy + l.interior(p, q, i, j), kind: fromInterior, k: i, l: j})
+				}
+			}
+
+			// i, j as a branch of a multiloop, on its own or after other branches.
+			branch := energy + l.model.multiC + l.terminal(i, j)
+			update(l.branches[j], i, beamState{energy: branch, kind: fromPaired})
+			if i > 0 {
+				for start, previous := range l.branches[i-1] {
+					update(l.twoBranches[j], start, beamState{energy: previous.energy + branch, kind: fromSplit, k: i})
+				}
+			}
+		}
+
+		for _, k := range l.prune(l.twoBranches[j]) {
+			state := l.twoBranches[j][k]
+			update(l.branches[j], k, beamState{energy: state.energy, kind: fromTwoBranches, k: k, l: j})
+			if j+1 < l.length {
+				update(l.twoBranches[j+1], k, beamState{energy: state.energy + multiB, kind: fromUnpaired})
+			}
+			// close the multiloop with a pair p, q around it.
+			for p := k - 1; p >= 0 && k-p-1 <= maxLoop; p-- {
+				if q := l.nextPairable(p, j+1); q < l.length {
+					update(l.multi[q], p, beamState{energy: state.energy + (k-p-1+q-j-1)*multiB, kind: fromTwoBranches, k: k, l: j})
+				}
+			}
+		}
+
+		for _, i := range l.prune(l.branches[j]) {
+			if j+1 < l.length {
+				update(l.branches[j+1], i, beamState{energy: l.branches[j][i].energy + multiB, kind: fromUnpaired})
+			}
+		}
+	}
+}
+
+// traceBack rebuilds the structure from the back pointers of the exterior states.
+func (l *linearFolder) traceBack() string {
+	structure := []byte(strings.Repeat(".", l.length))
+	var paired, branches, twoBranches func(i, j int)
+
+	paired = func(i, j int) {
+		structure[i], structure[j] = '(', ')'
+		state := l.paired[j][i]
+		switch state.kind {
+		case fromInterior:
+			paired(state.k, state.l)
+		case fromMulti:
+			multi := l.multi[j][i]
+			twoBranches(multi.k, multi.l)
+		}
+	}
+	branches = func(i, j int) {
+		state := l.branches[j][i]
+		switch state.kind {
+		case fromPaired:
+			paired(i, j)
+		case fromTwoBranches:
+			twoBranches(i, j)
+		case fromUnpaired:
+			branches(i, j-1)
+		}
+	}
+	twoBranches = func(i, j int) {
+		state := l.twoBranches[j][i]
+		switch state.kind {
+		case fromSplit:
+			branches(i, state.k-1)
+			paired(state.k, j)
+		case fromUnpaired:
+			twoBranches(i, j-1)
+		}
+	}
+
+	for j := l.length - 1; j >= 0; {
+		state := l.exterior[j]
+		if state.kind == fromPaired {
+			paired(state.k, j)
+			j = state.k - 1
+		} else {
+			j--
+		}
+	}
+	return string(structure)
+}
+
+/******************************************************************************
+
+LinearFold ends here.
+
+******************************************************************************/
@@ -0,0 +1,369 @@
+/*
+Package bed provides BED parsers and writers.
+
+BED (browser extensible data) is a tab separated, line based format used by
+genome browsers like UCSC and IGV to display annotations on top of a reference
+sequence. The first three columns (chrom, start, end) are required while the
+remaining nine are optional, but must be filled in order.
+
+Unlike GFF and GenBank files, BED coordinates are 0-based and half-open. This
+happens to be exactly how poly stores locations internally, so converting
+between a BED record and a gff.Feature does not need any shifting of
+coordinates. The shifting only ever happens when a gff file is parsed or built.
+
+This package provides a parser and writer for BED files as well as helpers to
+convert between BED records and gff features.
+*/
+package bed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/TimothyStiles/poly/io/gff"
+	"github.com/TimothyStiles/poly/io/internal/compress"
+)
+
+// BedRecord is a struct that represents a single line of a BED file.
+type BedRecord struct {
+	Chrom       string `json:"chrom"`
+	Start       int    `json:"start"` // 0-based, inclusive.
+	End         int    `json:"end"`   // 0-based, exclusive.
+	Name        string `json:"name"`
+	Score       int    `json:"score"`
+	Strand      string `json:"strand"`
+	ThickStart  int    `json:"thick_start"`
+	ThickEnd    int    `json:"thick_end"`
+	ItemRgb     string `json:"item_rgb"`
+	BlockCount  int    `json:"block_count"`
+	BlockSizes  []int  `json:"block_sizes"`
+	BlockStarts []int  `json:"block_starts"` // relative to Start.
+}
+
+// ErrInvalidRecord is wrapped by errors about BED lines that can't be parsed and records that can't be built.
+var ErrInvalidRecord = errors.New("invalid record")
+
+// ParseError is returned by Parse when a line can't be parsed. Use errors.As to
+// get the line number and errors.Is with ErrInvalidRecord to check its cause.
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("bed: line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Parse takes in a byte array representing a BED file and parses it into a slice of BedRecords.
+func Parse(file []byte) ([]BedRecord, error) {
+	var records []BedRecord
+	lines := strings.Split(string(file), "\n")
+	for lineIndex, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		// skip blank lines, comments and browser/track configuration lines.
+		if len(strings.TrimSpace(line)) == 0 || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "track") || strings.HasPrefix(line, "browser") {
+			continue
+		}
+
+		record, err := parseRecord(line)
+		if err != nil {
+			return records, &ParseError{lineIndex + 1, err}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// parseRecord parses a single BED line into a BedRecord.
+func parseRecord(line string) (BedRecord, error) {
+	var record BedRecord
+	var err error
+
+	fields := strings.Split(line, "\t")
+	if len(fields) < 3 {
+		return record, fmt.Errorf("%w: expected at least 3 fields, got %d", ErrInvalidRecord, len(fields))
+	}
+
+	record.Chrom = fields[0]
+	if record.Start, err = strconv.Atoi(fields[1]); err != nil {
+		return record, fmt.Errorf("%w: start %q is not a number", ErrInvalidRecord, fields[1])
+	}
+	if record.End, err = strconv.Atoi(fields[2]); err != nil {
+		return record, fmt.Errorf("%w: end %q is not a number", ErrInvalidRecord, fields[2])
+	}
+	if record.End < record.Start {
+		return record, fmt.Errorf("%w: end %d is before start %d", ErrInvalidRecord, record.End, record.Start)
+	}
+
+	if len(fields) > 3 {
+		record.Name = fields[3]
+	}
+	if len(fields) > 4 {
+		// some tools write "." for a missing score even though the spec asks for 0.
+		if fields[4] != "." {
+			score, err := strconv.ParseFloat(fields[4], 64)
+			if err != nil {
+				return record, fmt.Errorf("%w: score %q is not a number", ErrInvalidRecord, fields[4])
+			}
+			record.Score = int(math.Round(score))
+		}
+	}
+	if len(fields) > 5 {
+		record.Strand = fields[5]
+	}
+	if len(fields) > 6 {
+		if record.ThickStart, err = strconv.Atoi(fields[6]); err != nil {
+			return record, fmt.Errorf("%w: thickStart %q is not a number", ErrInvalidRecord, fields[6])
+		}
+	}
+	if len(fields) > 7 {
+		if record.ThickEnd, err = strconv.Atoi(fields[7]); err != nil {
+			return record, fmt.Errorf("%w: thickEnd %q is not a number", ErrInvalidRecord, fields[7])
+		}
+	}
+	if len(fields) > 8 {
+		record.ItemRgb = fields[8]
+	}
+	if len(fields) > 9 {
+		if record.BlockCount, err = strconv.Atoi(fields[9]); err != nil {
+			return record, fmt.Errorf("%w: blockCount %q is not a number", ErrInvalidRecord, fields[9])
+		}
+	}
+	if len(fields) > 10 {
+		if record.BlockSizes, err = parseIntList(fields[10]); err != nil {
+			return record, fmt.Errorf("%w: blockSizes %q is not a list of numbers", ErrInvalidRecord, fields[10])
+		}
+	}
+	if len(fields) > 11 {
+		if record.BlockStarts, err = parseIntList(fields[11]); err != nil {
+			return record, fmt.Errorf("%w: blockStarts %q is not a list of numbers", ErrInvalidRecord, fields[11])
+		}
+	}
+	if len(record.BlockSizes) != record.BlockCount || len(record.BlockStarts) != record.BlockCount {
+		return record, fmt.Errorf("%w: blockCount %d does not match the number of block sizes (%d) and starts (%d)", ErrInvalidRecord, record.BlockCount, len(record.BlockSizes), len(record.BlockStarts))
+	}
+
+	return record, nil
+}
+
+// parseIntList parses BED's comma separated integer lists. A trailing comma is allowed.
+func parseIntList(list string) ([]int, error) {
+	var integers []int
+	for _, item := range strings.Split(strings.TrimSuffix(list, ","), ",") {
+		if item == "" {
+			continue
+		}
+		integer, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, err
+		}
+		integers = append(integers, integer)
+	}
+	return integers, nil
+}
+
+// Build takes a slice of BedRecords and returns a byte array representing a BED file to be written out.
+func Build(records []BedRecord) ([]byte, error) {
+	var bedBuffer bytes.Buffer
+	for _, record := range records {
+		if record.End < record.Start {
+			return nil, fmt.Errorf("bed: record %q: %w: end %d is before start %d", record.Name, ErrInvalidRecord, record.End, record.Start)
+		}
+		bedBuffer.WriteString(strings.Join(buildFields(record), "\t"))
+		bedBuffer.WriteString("\n")
+	}
+	return bedBuffer.Bytes(), nil
+}
+
+// buildFields returns the columns of a BedRecord. Optional columns are only
+// written if they, or a column after them, are set since BED columns must be
+// filled in order.
+func buildFields(record BedRecord) []string {
+	columns := 3
+	switch {
+	case record.BlockCount > 0:
+		columns = 12
+	case record.ItemRgb != "":
+		columns = 9
+	case record.ThickStart != 0 || record.ThickEnd != 0:
+		columns = 8
+	case record.Strand != "":
+		columns = 6
+	case record.Score != 0:
+		columns = 5
+	case record.Name != "":
+		columns = 4
+	}
+
+	name := record.Name
+	if name == "" {
+		name = "."
+	}
+	strand := record.Strand
+	if strand == "" {
+		strand = "."
+	}
+	itemRgb := record.ItemRgb
+	if itemRgb == "" {
+		itemRgb = "0"
+	}
+
+	fields := []string{
+		record.Chrom,
+		strconv.Itoa(record.Start),
+		strconv.Itoa(record.End),
+		name,
+		strconv.Itoa(record.Score),
+		strand,
+		strconv.Itoa(record.ThickStart),
+		strconv.Itoa(record.ThickEnd),
+		itemRgb,
+		strconv.Itoa(record.BlockCount),
+		buildIntList(record.BlockSizes),
+		buildIntList(record.BlockStarts),
+	}
+	return fields[:columns]
+}
+
+// buildIntList writes out a comma separated integer list with the trailing comma UCSC uses.
+func buildIntList(integers []int) string {
+	var list strings.Builder
+	for _, integer := range integers {
+		list.WriteString(strconv.Itoa(integer))
+		list.WriteString(",")
+	}
+	return list.String()
+}
+
+// Read takes in a filepath for a BED file and parses it into a slice of BedRecords. Gzip and bzip2 compressed files are detected and decompressed automatically.
+func Read(path string) ([]BedRecord, error) {
+	file, err := compress.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(file)
+}
+
+// Write takes a slice of BedRecords and a path string and writes out a BED file to that path.
+func Write(records []BedRecord, path string) error {
+	bed, err := Build(records)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, bed, 0644)
+}
+
+/******************************************************************************
+
+GFF conversion functions begin here.
+
+******************************************************************************/
+
+// FromGff converts a gff.Feature into a BedRecord. Features with sub locations
+// are converted into a BED12 record with one block per sub location.
+func FromGff(feature gff.Feature) BedRecord {
+	record := BedRecord{
+		Chrom: feature.Name,
+		Start: feature.Location.Start,
+		End:   feature.Location.End,
+	}
+
+	// prefer the human readable name, fall back to the feature's ID.
+	if name, ok := feature.Attributes["Name"]; ok {
+		record.Name = name
+	} else if id, ok := feature.Attributes["ID"]; ok {
+		record.Name = id
+	}
+
+	if score, err := strconv.ParseFloat(feature.Score, 64); err == nil {
+		record.Score = int(math.Round(score))
+	}
+
+	switch feature.Strand {
+	case "+", "-":
+		record.Strand = feature.Strand
+	default:
+		if feature.Location.Complement {
+			record.Strand = "-"
+		} else if record.Name != "" || record.Score != 0 {
+			record.Strand = "."
+		}
+	}
+
+	if len(feature.Location.SubLocations) > 0 {
+		// a feature's outer location isn't always set when it's built from sub locations.
+		start, end := feature.Location.SubLocations[0].Start, feature.Location.SubLocations[0].End
+		for _, subLocation := range feature.Location.SubLocations {
+			if subLocation.Start < start {
+				start = subLocation.Start
+			}
+			if subLocation.End > end {
+				end = subLocation.End
+			}
+		}
+		record.Start, record.End = start, end
+		record.ThickStart, record.ThickEnd = start, end
+
+		for _, subLocation := range feature.Location.SubLocations {
+			record.BlockSizes = append(record.BlockSizes, subLocation.End-subLocation.Start)
+			record.BlockStarts = append(record.BlockStarts, subLocation.Start-start)
+		}
+		record.BlockCount = len(feature.Location.SubLocations)
+		record.ItemRgb = "0"
+		if record.Strand == "" {
+			record.Strand = "."
+		}
+		if record.Name == "" {
+			record.Name = "."
+		}
+	}
+
+	return record
+}
+
+// ToGff converts a BedRecord into a gff.Feature. BED12 blocks are converted
+// into joined sub locations.
+func (record BedRecord) ToGff() gff.Feature {
+	feature := gff.Feature{
+		Name:       record.Chrom,
+		Score:      ".",
+		Strand:     record.Strand,
+		Phase:      ".",
+		Attributes: make(map[string]string),
+		Location: gff.Location{
+			Start:      record.Start,
+			End:        record.End,
+			Complement: record.Strand == "-",
+		},
+	}
+
+	if record.Score != 0 {
+		feature.Score = strconv.Itoa(record.Score)
+	}
+	if feature.Strand == "" {
+		feature.Strand = "."
+	}
+	if record.Name != "" && record.Name != "." {
+		feature.Attributes["Name"] = record.Name
+	}
+
+	for blockIndex := 0; blockIndex < record.BlockCount && blockIndex < len(record.BlockSizes) && blockIndex < len(record.BlockStarts); blockIndex++ {
+		blockStart := record.Start + record.BlockStarts[blockIndex]
+		feature.Location.SubLocations = append(feature.Location.SubLocations, gff.Location{
+			Start: blockStart,
+			End:   blockStart + record.BlockSizes[blockIndex],
+		})
+	}
+	feature.Location.Join = len(feature.Location.SubLocations) > 1
+
+	return feature
+}
@@ -0,0 +1,139 @@
+package bed_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TimothyStiles/poly/io/bed"
+	"github.com/TimothyStiles/poly/io/gff"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBedIO(t *testing.T) {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	testInputPath := "../../data/example.bed"
+	tmpBedFilePath := filepath.Join(tmpDataDir, "example.bed")
+
+	records, err := bed.Read(testInputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bed.Write(records, tmpBedFilePath); err != nil {
+		t.Fatal(err)
+	}
+	writtenRecords, _ := bed.Read(tmpBedFilePath)
+
+	if diff := cmp.Diff(records, writtenRecords); diff != "" {
+		t.Errorf("Parsing the output of Build() does not produce the same output as parsing the original file read with Read(). Got this diff:\n%s", diff)
+	}
+
+	// the data lines of the original file should be reproduced exactly.
+	original, _ := ioutil.ReadFile(testInputPath)
+	builtOutput, _ := ioutil.ReadFile(tmpBedFilePath)
+	originalLines := strings.SplitN(string(original), "\n", 3)
+	if originalLines[2] != string(builtOutput) {
+		t.Errorf("Build() does not output the same records as were input through Read(). Got:\n%s", builtOutput)
+	}
+}
+
+func TestReadCompressed(t *testing.T) {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	// gzip the test file so we can check Read decompresses it transparently.
+	file, _ := ioutil.ReadFile("../../data/example.bed")
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, _ = gzipWriter.Write(file)
+	_ = gzipWriter.Close()
+	tmpGzipFilePath := filepath.Join(tmpDataDir, "example.bed.gz")
+	_ = ioutil.WriteFile(tmpGzipFilePath, gzipped.Bytes(), 0644)
+
+	expected, _ := bed.Read("../../data/example.bed")
+	for _, path := range []string{tmpGzipFilePath, "../../data/example.bed.bz2"} {
+		got, err := bed.Read(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, got); diff != "" {
+			t.Errorf("Reading %s does not give the same result as the plain file. Got this diff:\n%s", path, diff)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	badFiles := map[string]string{
+		"too few fields":       "chr1\t100\n",
+		"non-numeric start":    "chr1\tone\t100\n",
+		"end before start":     "chr1\t100\t50\n",
+		"block count mismatch": "chr1\t0\t100\tx\t0\t+\t0\t100\t0\t2\t10,\t0,\n",
+	}
+	for name, file := range badFiles {
+		if _, err := bed.Parse([]byte(file)); !errors.Is(err, bed.ErrInvalidRecord) {
+			t.Errorf("expected an invalid record error parsing a file with %s, got %v", name, err)
+		}
+	}
+
+	var parseError *bed.ParseError
+	if _, err := bed.Parse([]byte("# comment\nchr1\t0\t100\nchr1\tone\t100\n")); !errors.As(err, &parseError) || parseError.Line != 3 {
+		t.Errorf("expected a ParseError on line 3, got %v", err)
+	}
+	if _, err := bed.Read("../../data/does_not_exist.bed"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a file not found error, got %v", err)
+	}
+	if _, err := bed.Build([]bed.BedRecord{{Chrom: "chr1", Start: 100, End: 50}}); !errors.Is(err, bed.ErrInvalidRecord) {
+		t.Errorf("expected an invalid record error building a record that ends before it starts, got %v", err)
+	}
+}
+
+func TestMinimalColumns(t *testing.T) {
+	records := []bed.BedRecord{{Chrom: "chr1", Start: 10, End: 20}, {Chrom: "chr1", Start: 10, End: 20, Score: 5}}
+	output, _ := bed.Build(records)
+	expected := "chr1\t10\t20\nchr1\t10\t20\t.\t5\n"
+	if string(output) != expected {
+		t.Errorf("expected %q, got %q", expected, string(output))
+	}
+}
+
+func TestGffRoundTrip(t *testing.T) {
+	sequence, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+	for _, feature := range sequence.Features {
+		record := bed.FromGff(feature)
+		// BED intervals are 0-based half-open so their length is end - start.
+		if record.End-record.Start != feature.Location.End-feature.Location.Start {
+			t.Errorf("feature length changed during conversion")
+		}
+		converted := record.ToGff()
+		if converted.Location.Start != feature.Location.Start || converted.Location.End != feature.Location.End {
+			t.Errorf("expected location %d..%d, got %d..%d", feature.Location.Start, feature.Location.End, converted.Location.Start, converted.Location.End)
+		}
+		if converted.Strand != feature.Strand {
+			t.Errorf("expected strand %s, got %s", feature.Strand, converted.Strand)
+		}
+	}
+
+	// blocks should map to sub locations and back again.
+	blocked := bed.BedRecord{Chrom: "chr22", Start: 1000, End: 5000, Name: "cloneA", Score: 960, Strand: "+", ThickStart: 1000, ThickEnd: 5000, ItemRgb: "0", BlockCount: 2, BlockSizes: []int{567, 488}, BlockStarts: []int{0, 3512}}
+	feature := blocked.ToGff()
+	if len(feature.Location.SubLocations) != 2 || feature.Location.SubLocations[1].Start != 4512 || feature.Location.SubLocations[1].End != 5000 {
+		t.Errorf("blocks were not converted into sub locations: %+v", feature.Location.SubLocations)
+	}
+	if diff := cmp.Diff(blocked, bed.FromGff(feature)); diff != "" {
+		t.Errorf("BED12 record did not survive a round trip through gff. Got this diff:\n%s", diff)
+	}
+}
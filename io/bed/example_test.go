@@ -0,0 +1,71 @@
+package bed_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/TimothyStiles/poly/io/bed"
+	"github.com/TimothyStiles/poly/io/gff"
+)
+
+func ExampleRead() {
+	records, _ := bed.Read("../../data/example.bed")
+	fmt.Println(records[0].Name)
+	// Output: Pos1
+}
+
+func ExampleParse() {
+	file, _ := ioutil.ReadFile("../../data/example.bed")
+	records, _ := bed.Parse(file)
+
+	fmt.Println(records[3].BlockSizes)
+	// Output: [567 488]
+}
+
+func ExampleBuild() {
+	records, _ := bed.Read("../../data/example.bed")
+	bedBytes, _ := bed.Build(records)
+	reparsedRecords, _ := bed.Parse(bedBytes)
+
+	fmt.Println(reparsedRecords[4].Name)
+	// Output: cloneB
+}
+
+func ExampleWrite() {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	records, _ := bed.Read("../../data/example.bed")
+
+	tmpBedFilePath := filepath.Join(tmpDataDir, "example.bed")
+	_ = bed.Write(records, tmpBedFilePath)
+
+	testRecords, _ := bed.Read(tmpBedFilePath)
+
+	fmt.Println(testRecords[2].Strand)
+	// Output: -
+}
+
+// This example shows how to export the genes of a gff file as BED records
+// for viewing in a genome browser.
+func ExampleFromGff() {
+	sequence, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+
+	// thrL spans 190..255 in the 1-based gff file.
+	record := bed.FromGff(sequence.Features[0])
+	fmt.Println(record.Chrom, record.Start, record.End, record.Strand)
+	// Output: U00096.3 189 255 +
+}
+
+func ExampleBedRecord_ToGff() {
+	record := bed.BedRecord{Chrom: "chr1", Start: 0, End: 100, Name: "promoter", Strand: "+"}
+	feature := record.ToGff()
+
+	fmt.Println(feature.Location.Start, feature.Location.End, feature.Attributes["Name"])
+	// Output: 0 100 promoter
+}
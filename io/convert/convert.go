@@ -0,0 +1,393 @@
+/*
+Package convert converts sequences between the formats of poly's io packages.
+
+Every io package parses into its own struct, shaped like the file format it
+reads. Code that only reads sequences can take any of them as a
+poly.AnnotatedSequence, but writing a sequence out in another format means
+moving it into that format's struct, which is what this package is for.
+
+ToFasta turns any poly.AnnotatedSequence into a fasta.Fasta.
+(fasta has no features or topology, so those are dropped.)
+
+GenbankToGFF and GFFToGenbank move a sequence and its features between
+genbank.Genbank and gff.Gff.
+(converting a sequence to the other format and back gives the same features.)
+
+GenBank and GFF3 describe features in different ways, so the conversion maps
+between them like NCBI's own GFF3 files do:
+
+  - GenBank source features are GFF3 region features, which carry
+    Is_circular=true on circular sequences.
+  - Locations joining several parts, like the exons of a CDS, become one GFF3
+    line per part, all with the same ID. Features that don't have an /ID
+    qualifier get one starting with "genbank-join-", which is dropped again on
+    the way back.
+  - Complemented locations are on the - strand.
+  - Partial locations ("<" and ">") get start_range=.,N and end_range=N,.
+    attributes.
+  - /codon_start is the phase of the first part of a CDS, and the phases of
+    the other parts follow on from it.
+  - Qualifiers and attributes keep their names and every one of their values.
+  - The source and score columns of GFF3 features are kept in /gff_source and
+    /gff_score qualifiers.
+
+Some things can't go both ways, either because the other format has no place
+for them or because the same thing can be written in more than one way:
+
+  - The GenBank header other than the name, definition and topology, like the
+    accession, keywords and references, has nowhere to go in GFF3. The
+    organism is usually also a qualifier of the source feature, which is kept.
+  - GFF3 directives, the order of attributes and how their values were
+    percent-encoded aren't kept. Neither are sequences other than the first of
+    files that annotate several, along with the features on them.
+  - GFF3 features with an undefined start or end (".") are skipped.
+  - Sites between two bases, like 10^11, are written as empty ranges.
+  - GFF3 doesn't order the parts of a feature, so those on the - strand are
+    taken to be transcribed from the last to the first. Joins of complemented
+    parts like join(complement(4..6),complement(1..3)) come back as the
+    equivalent complement(join(1..3,4..6)), but ones that go forwards, like
+    join(complement(1..3),complement(4..6)), come back reversed.
+  - Circular sequences without a source feature lose their topology when the
+    GFF3 file is written, since GFF3 only records it on region features.
+*/
+package convert
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TimothyStiles/poly"
+	"github.com/TimothyStiles/poly/io/fasta"
+	"github.com/TimothyStiles/poly/io/genbank"
+	"github.com/TimothyStiles/poly/io/gff"
+)
+
+const (
+	sourceQualifier   = "gff_source"
+	scoreQualifier    = "gff_score"
+	generatedIDPrefix = "genbank-join-"
+	// genbankSource is the source column of features converted from GenBank.
+	genbankSource = "GenBank"
+)
+
+// ToFasta returns a sequence as a fasta record. The description of the
+// sequence, if it has one, follows its name on the header line.
+func ToFasta(sequence poly.AnnotatedSequence) fasta.Fasta {
+	meta := sequence.GetMeta()
+	name := meta.Name
+	if meta.Description != "" {
+		name += " " + meta.Description
+	}
+	return fasta.Fasta{Name: name, Sequence: sequence.GetSequence()}
+}
+
+/******************************************************************************
+
+GenBank to GFF3 conversion begins here.
+
+******************************************************************************/
+
+// GenbankToGFF converts a Genbank into a Gff, mapping features between the
+// formats as described in the package documentation.
+func GenbankToGFF(sequence genbank.Genbank) gff.Gff {
+	meta := sequence.GetMeta()
+	converted := gff.Gff{
+		Meta: gff.Meta{
+			Name:        meta.Name,
+			Description: meta.Description,
+			Version:     "3",
+			RegionStart: 1,
+			RegionEnd:   len(sequence.Sequence),
+			Size:        len(sequence.Sequence),
+			Circular:    meta.Circular,
+		},
+		Sequence: sequence.Sequence,
+	}
+
+	for index, feature := range sequence.Features {
+		template := gff.Feature{Name: meta.Name, Source: genbankSource, Type: feature.Type, Score: "."}
+		if source, ok := feature.Attributes[sourceQualifier]; ok {
+			template.Source = source
+		}
+		if score, ok := feature.Attributes[scoreQualifier]; ok {
+			template.Score = score
+		}
+
+		qualifiers := make([]string, 0, len(feature.Attributes))
+		for qualifier := range feature.Attributes {
+			if qualifier != sourceQualifier && qualifier != scoreQualifier {
+				qualifiers = append(qualifiers, qualifier)
+			}
+		}
+		sort.Strings(qualifiers)
+		for _, qualifier := range qualifiers {
+			values, repeated := feature.RepeatedAttributes[qualifier]
+			if !repeated {
+				values = []string{feature.Attributes[qualifier]}
+			}
+			template.SetAttribute(qualifier, values...)
+		}
+
+		if feature.Type == "source" {
+			template.Type = "region"
+			if meta.Circular {
+				template.SetAttribute("Is_circular", "true")
+			}
+		}
+
+		parts := flatten(feature.Location, false)
+		if _, ok := template.Attributes["ID"]; !ok && len(parts) > 1 {
+			template.SetAttribute("ID", generatedIDPrefix+strconv.Itoa(index+1))
+		}
+
+		phase := 0
+		if codonStart, err := strconv.Atoi(feature.Attributes["codon_start"]); err == nil && codonStart >= 1 && codonStart <= 3 {
+			phase = codonStart - 1
+		}
+		for _, part := range parts {
+			gffFeature := copyFeature(template)
+			start, end := part.Start, part.End
+			// GFF3 features that run across the origin of a circular sequence end past its end.
+			if end < start {
+				end += len(sequence.Sequence)
+			}
+			gffFeature.Location = gff.Location{
+				Start:             start,
+				End:               end,
+				Complement:        part.Complement,
+				FivePrimePartial:  part.FivePrimePartial,
+				ThreePrimePartial: part.ThreePrimePartial,
+			}
+			gffFeature.Strand = "+"
+			if part.Complement {
+				gffFeature.Strand = "-"
+			}
+			if part.FivePrimePartial {
+				gffFeature.SetAttribute("start_range", ".", strconv.Itoa(start+1))
+			}
+			if part.ThreePrimePartial {
+				gffFeature.SetAttribute("end_range", strconv.Itoa(end), ".")
+			}
+
+			// the phase of each part is the number of bases left over from the last codon of the part before it.
+			gffFeature.Phase = "."
+			if feature.Type == "CDS" {
+				gffFeature.Phase = strconv.Itoa(phase)
+				phase = ((phase-(end-start))%3 + 3) % 3
+			}
+			_ = converted.AddFeature(&gffFeature)
+		}
+	}
+	return converted
+}
+
+// flatten returns the parts of a GenBank location in the order they are
+// transcribed, with each part's Complement saying which strand it is on.
+// Complementing a join reverses the order of its parts.
+func flatten(location genbank.Location, complement bool) []genbank.Location {
+	complement = complement != location.Complement
+	if len(location.SubLocations) == 0 {
+		location.Complement = complement
+		return []genbank.Location{location}
+	}
+	var parts []genbank.Location
+	for _, subLocation := range location.SubLocations {
+		parts = append(parts, flatten(subLocation, complement)...)
+	}
+	if location.Complement {
+		reverseLocations(parts)
+	}
+	return parts
+}
+
+// reverseLocations reverses a slice of locations in place.
+func reverseLocations(locations []genbank.Location) {
+	for left, right := 0, len(locations)-1; left < right; left, right = left+1, right-1 {
+		locations[left], locations[right] = locations[right], locations[left]
+	}
+}
+
+// copyFeature returns a copy of a gff feature that doesn't share its attributes.
+func copyFeature(feature gff.Feature) gff.Feature {
+	attributes := make(map[string]string, len(feature.Attributes))
+	for key, value := range feature.Attributes {
+		attributes[key] = value
+	}
+	feature.Attributes = attributes
+	feature.AttributeOrder = append([]string(nil), feature.AttributeOrder...)
+	return feature
+}
+
+/******************************************************************************
+
+GenBank to GFF3 conversion ends here.
+
+******************************************************************************/
+
+/******************************************************************************
+
+GFF3 to GenBank conversion begins here.
+
+******************************************************************************/
+
+// GFFToGenbank converts a Gff into a linear or circular DNA Genbank, mapping
+// features between the formats as described in the package documentation.
+// It returns an error if an attribute value isn't properly percent-encoded.
+func GFFToGenbank(sequence gff.Gff) (genbank.Genbank, error) {
+	meta := sequence.GetMeta()
+	converted := genbank.Genbank{
+		Meta: genbank.Meta{
+			Name:       meta.Name,
+			Definition: meta.Description,
+			Locus: genbank.Locus{
+				Name:           meta.Name,
+				SequenceLength: strconv.Itoa(len(sequence.Sequence)),
+				MoleculeType:   "DNA",
+				Circular:       meta.Circular,
+				Linear:         !meta.Circular,
+			},
+		},
+		Sequence: sequence.Sequence,
+	}
+
+	// the parts of a feature are the lines with its type and ID.
+	var groups [][]gff.Feature
+	groupIndices := make(map[string]int)
+	for _, feature := range sequence.Features {
+		if len(sequence.Sequences) > 1 && feature.Name != sequence.Sequences[0].Name {
+			continue
+		}
+		if feature.Location.UndefinedStart || feature.Location.UndefinedEnd {
+			continue
+		}
+		if id := feature.Attributes["ID"]; id != "" {
+			key := feature.Type + "\t" + id
+			if groupIndex, ok := groupIndices[key]; ok {
+				groups[groupIndex] = append(groups[groupIndex], feature)
+				continue
+			}
+			groupIndices[key] = len(groups)
+		}
+		groups = append(groups, []gff.Feature{feature})
+	}
+
+	for _, parts := range groups {
+		genbankFeature, err := joinParts(parts, len(sequence.Sequence), meta.Circular)
+		if err != nil {
+			return genbank.Genbank{}, err
+		}
+		_ = converted.AddFeature(&genbankFeature)
+	}
+	return converted, nil
+}
+
+// joinParts turns the lines of a GFF3 feature into a GenBank feature,
+// joining their locations if there is more than one.
+func joinParts(parts []gff.Feature, length int, circular bool) (genbank.Feature, error) {
+	first := parts[0]
+	feature := genbank.Feature{Type: first.Type, Attributes: make(map[string]string)}
+	if feature.Type == "region" {
+		feature.Type = "source"
+	}
+
+	for key := range first.Attributes {
+		if skipAttribute(first, key) {
+			continue
+		}
+		values, err := first.AttributeValues(key)
+		if err != nil {
+			return genbank.Feature{}, err
+		}
+		feature.Attributes[key] = values[len(values)-1]
+		if len(values) > 1 {
+			if feature.RepeatedAttributes == nil {
+				feature.RepeatedAttributes = make(map[string][]string)
+			}
+			feature.RepeatedAttributes[key] = values
+		}
+	}
+	if first.Source != "" && first.Source != genbankSource {
+		feature.Attributes[sourceQualifier] = first.Source
+	}
+	if first.Score != "" && first.Score != "." {
+		feature.Attributes[scoreQualifier] = first.Score
+	}
+
+	// GFF3 doesn't order the parts of a feature. Those on the - strand are
+	// transcribed from the last to the first, which GenBank writes as the
+	// complement of a join of them in order.
+	complement := true
+	for _, part := range parts {
+		complement = complement && (part.Strand == "-" || part.Location.Complement)
+	}
+	firstPart := parts[0]
+	if complement && len(parts) > 1 {
+		parts = append([]gff.Feature(nil), parts...)
+		sort.SliceStable(parts, func(i, j int) bool {
+			return parts[i].Location.Start < parts[j].Location.Start
+		})
+		firstPart = parts[len(parts)-1]
+	}
+
+	locations := make([]genbank.Location, len(parts))
+	for index, part := range parts {
+		locations[index] = genbank.Location{
+			Start:             part.Location.Start,
+			End:               part.Location.End,
+			Complement:        part.Strand == "-" || part.Location.Complement,
+			FivePrimePartial:  part.Location.FivePrimePartial,
+			ThreePrimePartial: part.Location.ThreePrimePartial,
+		}
+		// GenBank writes locations across the origin as starting after they end.
+		if circular && length > 0 && locations[index].End > length {
+			locations[index].End -= length
+		}
+	}
+
+	switch {
+	case len(locations) == 1:
+		feature.Location = locations[0]
+	case complement:
+		for index := range locations {
+			locations[index].Complement = false
+		}
+		feature.Location = genbank.Location{Join: true, Complement: true, SubLocations: locations}
+	default:
+		feature.Location = genbank.Location{Join: true, SubLocations: locations}
+	}
+	for _, location := range locations {
+		feature.Location.FivePrimePartial = feature.Location.FivePrimePartial || location.FivePrimePartial
+		feature.Location.ThreePrimePartial = feature.Location.ThreePrimePartial || location.ThreePrimePartial
+	}
+
+	if phase, err := strconv.Atoi(firstPart.Phase); err == nil && feature.Type == "CDS" && phase > 0 {
+		if _, ok := feature.Attributes["codon_start"]; !ok {
+			feature.Attributes["codon_start"] = strconv.Itoa(phase + 1)
+		}
+	}
+	return feature, nil
+}
+
+// skipAttribute reports whether an attribute of a GFF3 feature is part of how
+// GenbankToGFF writes locations and topology rather than a qualifier.
+func skipAttribute(feature gff.Feature, key string) bool {
+	value := feature.Attributes[key]
+	switch key {
+	case "start_range":
+		return strings.HasPrefix(value, ".,")
+	case "end_range":
+		return strings.HasSuffix(value, ",.")
+	case "Is_circular":
+		return feature.Type == "region"
+	case "ID":
+		return strings.HasPrefix(value, generatedIDPrefix)
+	}
+	return false
+}
+
+/******************************************************************************
+
+GFF3 to GenBank conversion ends here.
+
+******************************************************************************/
@@ -0,0 +1,259 @@
+package convert_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TimothyStiles/poly/io/convert"
+	"github.com/TimothyStiles/poly/io/fasta"
+	"github.com/TimothyStiles/poly/io/genbank"
+	"github.com/TimothyStiles/poly/io/gff"
+)
+
+func TestToFasta(t *testing.T) {
+	puc19, _ := genbank.Read("../../data/puc19.gbk")
+	record := convert.ToFasta(puc19)
+	if record.Name != "puc19.gbk "+puc19.Meta.Definition || record.Sequence != puc19.Sequence {
+		t.Errorf("unexpected record %q with %d bases", record.Name, len(record.Sequence))
+	}
+
+	// records without a description keep their name as it is.
+	record = convert.ToFasta(fasta.Fasta{Name: "oligo", Sequence: "GATTACA"})
+	if record.Name != "oligo" || record.Sequence != "GATTACA" {
+		t.Errorf("unexpected record %+v", record)
+	}
+}
+
+func TestGenbankToGFF(t *testing.T) {
+	puc19, _ := genbank.Read("../../data/puc19.gbk")
+	converted := convert.GenbankToGFF(puc19)
+	if converted.Meta.Name != puc19.GetName() || !converted.Meta.Circular || converted.Sequence != puc19.Sequence {
+		t.Errorf("unexpected meta %+v", converted.Meta)
+	}
+	if len(converted.Features) != len(puc19.Features) {
+		t.Fatalf("expected %d features, got %d", len(puc19.Features), len(converted.Features))
+	}
+	for index, feature := range puc19.Features {
+		expected, _ := feature.GetSequence()
+		got, err := converted.Features[index].GetSequence()
+		if err != nil || got != expected {
+			t.Errorf("feature %d (%s) has sequence %s, expected %s: %v", index, feature.Type, got, expected, err)
+		}
+	}
+
+	// the converted file should write out and read back in as gff.
+	built, err := gff.Build(converted)
+	if err != nil {
+		t.Fatalf("failed to build converted gff: %s", err)
+	}
+	parsed, err := gff.Parse(built)
+	if err != nil {
+		t.Fatalf("failed to parse converted gff: %s", err)
+	}
+	if len(parsed.Features) != len(puc19.Features) || parsed.Sequence != puc19.Sequence {
+		t.Errorf("expected %d features, got %d", len(puc19.Features), len(parsed.Features))
+	}
+}
+
+func TestGFFToGenbank(t *testing.T) {
+	ecoli, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+	converted, err := convert.GFFToGenbank(ecoli)
+	if err != nil {
+		t.Fatalf("failed to convert: %s", err)
+	}
+	if converted.GetName() != ecoli.Meta.Name || converted.Sequence != ecoli.Sequence {
+		t.Errorf("unexpected meta %+v", converted.Meta)
+	}
+	if len(converted.Features) != len(ecoli.Features) {
+		t.Fatalf("expected %d features, got %d", len(ecoli.Features), len(converted.Features))
+	}
+	for index, feature := range ecoli.Features {
+		expected, _ := feature.GetSequence()
+		if feature.Strand == "-" && !feature.Location.Complement {
+			continue
+		}
+		got, err := converted.Features[index].GetSequence()
+		if err != nil || got != expected {
+			t.Errorf("feature %d (%s) has sequence %s, expected %s: %v", index, feature.Type, got, expected, err)
+		}
+	}
+	if gene, _ := converted.Features[1].Qualifier("gene"); gene != "thrL" {
+		t.Errorf("expected thrL, got %q", gene)
+	}
+
+	// attributes with several values become repeated qualifiers.
+	feature := gff.Feature{Type: "gene", Strand: "-", Location: gff.Location{Start: 0, End: 3}}
+	feature.SetAttribute("Dbxref", "ASAP:ABE-0000006", "UniProtKB/Swiss-Prot:P0AD86")
+	feature.SetAttribute("Note", "a; b")
+	sequence := gff.Gff{Meta: gff.Meta{Name: "test"}, Sequence: "ATGC"}
+	_ = sequence.AddFeature(&feature)
+	converted, err = convert.GFFToGenbank(sequence)
+	if err != nil {
+		t.Fatalf("failed to convert: %s", err)
+	}
+	dbxrefs := converted.Features[0].Qualifiers("Dbxref")
+	if len(dbxrefs) != 2 || dbxrefs[1] != "UniProtKB/Swiss-Prot:P0AD86" {
+		t.Errorf("unexpected Dbxref qualifiers %v", dbxrefs)
+	}
+	if note, _ := converted.Features[0].Qualifier("Note"); note != "a; b" {
+		t.Errorf("expected the note to be decoded, got %q", note)
+	}
+	if featureSequence, _ := converted.Features[0].GetSequence(); featureSequence != "CAT" {
+		t.Errorf("expected CAT, got %s", featureSequence)
+	}
+
+	// badly encoded attributes can't be decoded.
+	sequence.Features[0].Attributes["Note"] = "100%"
+	if _, err := convert.GFFToGenbank(sequence); err == nil {
+		t.Errorf("expected an error for a badly encoded attribute")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, path := range []string{"puc19.gbk", "bsub.gbk", "phix174.gb", "t4_intron.gb", "pichia_chr1_head.gb", "sample.gbk"} {
+		original, err := genbank.Read("../../data/" + path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", path, err)
+		}
+
+		// go through a written gff file so that nothing only survives in memory.
+		built, err := gff.Build(convert.GenbankToGFF(original))
+		if err != nil {
+			t.Fatalf("%s: failed to build gff: %s", path, err)
+		}
+		parsed, err := gff.Parse(built)
+		if err != nil {
+			t.Fatalf("%s: failed to parse gff: %s", path, err)
+		}
+		roundTripped, err := convert.GFFToGenbank(parsed)
+		if err != nil {
+			t.Fatalf("%s: failed to convert back: %s", path, err)
+		}
+
+		if roundTripped.GetName() != original.GetName() || roundTripped.Meta.Locus.Circular != original.Meta.Locus.Circular || roundTripped.Sequence != original.Sequence {
+			t.Errorf("%s: meta changed to %+v", path, roundTripped.Meta.Locus)
+		}
+		if len(roundTripped.Features) != len(original.Features) {
+			t.Fatalf("%s: expected %d features, got %d", path, len(original.Features), len(roundTripped.Features))
+		}
+		for index, feature := range original.Features {
+			got := roundTripped.Features[index]
+			if forwardComplementJoin(feature.Location) {
+				continue
+			}
+			if got.Type != feature.Type {
+				t.Errorf("%s: feature %d changed type from %s to %s", path, index, feature.Type, got.Type)
+			}
+			// joins of complemented parts come back as the complement of a join, so only their sequences are compared.
+			if location, expected := genbank.BuildLocationString(got.Location), genbank.BuildLocationString(feature.Location); location != expected && !strings.HasPrefix(expected, "join(complement(") {
+				t.Errorf("%s: %s feature %d changed location from %s to %s", path, feature.Type, index, expected, location)
+			}
+			if !reflect.DeepEqual(got.Attributes, feature.Attributes) || !reflect.DeepEqual(got.RepeatedAttributes, feature.RepeatedAttributes) {
+				t.Errorf("%s: %s feature %d changed qualifiers from %v to %v", path, feature.Type, index, feature.Attributes, got.Attributes)
+			}
+			expected, expectedErr := feature.GetSequence()
+			sequence, err := got.GetSequence()
+			if sequence != expected || (err == nil) != (expectedErr == nil) {
+				t.Errorf("%s: %s feature %d changed sequence", path, feature.Type, index)
+			}
+		}
+	}
+
+	// gff files should round trip too, other than the order and encoding of their attributes.
+	ecoli, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+	converted, _ := convert.GFFToGenbank(ecoli)
+	roundTripped := convert.GenbankToGFF(converted)
+	if len(roundTripped.Features) != len(ecoli.Features) {
+		t.Fatalf("expected %d features, got %d", len(ecoli.Features), len(roundTripped.Features))
+	}
+	for index, feature := range ecoli.Features {
+		got := roundTripped.Features[index]
+		if len(got.Attributes) != len(feature.Attributes) {
+			t.Errorf("feature %d has %d attributes, expected %d", index, len(got.Attributes), len(feature.Attributes))
+		}
+		for key := range feature.Attributes {
+			expected, _ := feature.AttributeValues(key)
+			values, _ := got.AttributeValues(key)
+			if !reflect.DeepEqual(values, expected) {
+				t.Errorf("feature %d changed %s from %v to %v", index, key, expected, values)
+			}
+		}
+		got.Attributes, got.AttributeOrder, got.ParentSequence = nil, nil, nil
+		feature.Attributes, feature.AttributeOrder, feature.ParentSequence = nil, nil, nil
+		if !reflect.DeepEqual(got, feature) {
+			t.Errorf("feature %d changed from %+v to %+v", index, feature, got)
+		}
+	}
+}
+
+// forwardComplementJoin reports whether a location joins complemented parts
+// going forwards, which GFF3 can't tell apart from going backwards.
+func forwardComplementJoin(location genbank.Location) bool {
+	if !location.Join || location.Complement || len(location.SubLocations) < 2 {
+		return false
+	}
+	for index, subLocation := range location.SubLocations {
+		if !subLocation.Complement || (index > 0 && subLocation.Start < location.SubLocations[index-1].Start) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestJoins(t *testing.T) {
+	// a spliced CDS on the - strand with a partial 5' end, which is its end coordinate.
+	sequence := genbank.Genbank{Meta: genbank.Meta{Locus: genbank.Locus{Name: "spliced"}}, Sequence: "ATGAAACCCGGGTTTTAG"}
+	for _, location := range []genbank.Location{
+		{Join: true, Complement: true, SubLocations: []genbank.Location{{Start: 0, End: 4}, {Start: 8, End: 12, ThreePrimePartial: true}}},
+		{Join: true, SubLocations: []genbank.Location{{Start: 0, End: 4}, {Start: 8, End: 12}}},
+		{Join: true, SubLocations: []genbank.Location{{Start: 0, End: 4}, {Start: 8, End: 12, Complement: true}}},
+	} {
+		_ = sequence.AddFeature(&genbank.Feature{Type: "CDS", Attributes: map[string]string{"codon_start": "2"}, Location: location})
+	}
+	converted := convert.GenbankToGFF(sequence)
+
+	type line struct {
+		start, end    int
+		strand, phase string
+		partial       string
+	}
+	expected := []line{
+		{8, 12, "-", "1", "12,."},
+		{0, 4, "-", "0", ""},
+		{0, 4, "+", "1", ""},
+		{8, 12, "+", "0", ""},
+		{0, 4, "+", "1", ""},
+		{8, 12, "-", "0", ""},
+	}
+	if len(converted.Features) != len(expected) {
+		t.Fatalf("expected %d lines, got %d", len(expected), len(converted.Features))
+	}
+	for index, feature := range converted.Features {
+		got := line{feature.Location.Start, feature.Location.End, feature.Strand, feature.Phase, feature.Attributes["end_range"]}
+		if got != expected[index] {
+			t.Errorf("line %d is %+v, expected %+v", index, got, expected[index])
+		}
+		if id := feature.Attributes["ID"]; id != converted.Features[index/2*2].Attributes["ID"] || id == "" {
+			t.Errorf("line %d has ID %q", index, id)
+		}
+	}
+
+	// NCBI lists the parts of - strand features forwards, which should join the same way.
+	ncbi := gff.Gff{Meta: gff.Meta{Name: "spliced"}, Sequence: sequence.Sequence}
+	for _, index := range []int{1, 0} {
+		feature := converted.Features[index]
+		feature.SetAttribute("ID", "cds-1")
+		_ = ncbi.AddFeature(&feature)
+	}
+	joined, err := convert.GFFToGenbank(ncbi)
+	if err != nil {
+		t.Fatalf("failed to convert: %s", err)
+	}
+	if location := genbank.BuildLocationString(joined.Features[0].Location); location != "complement(join(1..4,9..>12))" {
+		t.Errorf("expected complement(join(1..4,9..>12)), got %s", location)
+	}
+	if joined.Features[0].Attributes["ID"] != "cds-1" || joined.Features[0].Attributes["codon_start"] != "2" {
+		t.Errorf("unexpected qualifiers %v", joined.Features[0].Attributes)
+	}
+}
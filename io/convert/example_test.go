@@ -0,0 +1,36 @@
+package convert_test
+
+import (
+	"fmt"
+
+	"github.com/TimothyStiles/poly/io/convert"
+	"github.com/TimothyStiles/poly/io/genbank"
+	"github.com/TimothyStiles/poly/io/gff"
+)
+
+func ExampleGenbankToGFF() {
+	puc19, _ := genbank.Read("../../data/puc19.gbk")
+	converted := convert.GenbankToGFF(puc19)
+
+	// puc19's origin of replication runs across the end of the plasmid, which GFF3 writes as ending past it.
+	feature := converted.Features[len(converted.Features)-1]
+	fmt.Println(feature.Type, feature.Strand, feature.Location.Start, feature.Location.End)
+	// Output: rep_origin + 2314 2903
+}
+
+func ExampleGFFToGenbank() {
+	ecoli, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+	converted, _ := convert.GFFToGenbank(ecoli)
+
+	gene, _ := converted.Features[1].Qualifier("gene")
+	fmt.Println(converted.Features[1].Type, gene)
+	// Output: CDS thrL
+}
+
+func ExampleToFasta() {
+	ecoli, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+	record := convert.ToFasta(ecoli)
+
+	fmt.Println(record.Name, len(record.Sequence))
+	// Output: U00096.3 6370
+}
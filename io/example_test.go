@@ -28,6 +28,6 @@ func Example() {
 	// Extra tips:
 
 	// 1. All of these file formats can be read and written in JSON format using their native schemas.
-	// 2. If you want to convert from one format to another (e.g. genbank to polyjson), you can easily do so with a for-loop and some field mapping.
+	// 2. If you want to convert from one format to another (e.g. genbank to gff), the convert package does the field mapping for you.
 	// 3. Every file format is unique but they all share a common interface so you can use them with almost every native function in Poly.
 }
@@ -7,7 +7,8 @@ languages. However, this simplicity means that annotation of genetic objects
 is not supported.
 
 This package provides a parser and writer for working with Fasta formatted
-genetic sequences.
+genetic sequences. Files too large to load into memory can be read a record at
+a time with a Parser.
 */
 package fasta
 
@@ -15,10 +16,19 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"strings"
+	"unicode"
+
+	"github.com/TimothyStiles/poly"
+	"github.com/TimothyStiles/poly/io/internal/compress"
+	"github.com/TimothyStiles/poly/seqhash"
+	"lukechampine.com/blake3"
 )
 
 /******************************************************************************
@@ -59,63 +69,175 @@ type Fasta struct {
 	Sequence string `json:"sequence"`
 }
 
+// GetName returns the name of the Fasta record.
+func (fasta Fasta) GetName() string {
+	return fasta.Name
+}
+
+// GetSequence returns the sequence of the Fasta record.
+func (fasta Fasta) GetSequence() string {
+	return fasta.Sequence
+}
+
+// GetFeatures always returns nil since fasta files have no annotations.
+func (fasta Fasta) GetFeatures() []poly.Feature {
+	return nil
+}
+
+// GetMeta returns the name of the Fasta record. Fasta headers don't say whether a
+// sequence is circular, so it is always linear.
+func (fasta Fasta) GetMeta() poly.Meta {
+	return poly.Meta{Name: fasta.Name}
+}
+
+// Seqhash returns the Seqhash of the Fasta's sequence. Fasta files don't say
+// what kind of sequence they hold, so sequenceType is one of seqhash.DNA,
+// seqhash.RNA or seqhash.PROTEIN. Sequences are taken to be linear, with DNA
+// double stranded and RNA and protein single stranded like seqhash.FromSequence
+// does for every format. Use seqhash.Hash for any other kind of sequence.
+func (fasta Fasta) Seqhash(sequenceType string) (string, error) {
+	return seqhash.FromSequence(fasta.Sequence, sequenceType, false)
+}
+
+// HashFunction is the hash used by Fasta.Hash. It defaults to blake3 to match the
+// checksums of the gff and genbank packages but can be swapped out for any other
+// function that returns a 32 byte digest.
+var HashFunction func([]byte) [32]byte = blake3.Sum256
+
+// Hash returns the HashFunction digest of the Fasta's sequence. The sequence is
+// uppercased and stripped of whitespace before hashing so that the same sequence
+// always hashes the same regardless of how it was formatted or soft-masked.
+func (fasta Fasta) Hash() [32]byte {
+	normalizedSequence := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, fasta.Sequence)
+	return HashFunction([]byte(normalizedSequence))
+}
+
+// ErrEmptyFile is returned when a Fasta file has no records in it.
+var ErrEmptyFile = errors.New("fasta file is empty")
+
+// ErrMissingHeader is returned when a Fasta file has sequence before its first ">" header line.
+var ErrMissingHeader = errors.New("fasta sequence found before a > header line")
+
 // Parse parses a given Fasta file into an array of Fasta structs. Internally, it uses ParseFastaConcurrent.
+//
+// Parse returns ErrEmptyFile if there are no records to parse and wraps
+// ErrMissingHeader if sequence appears before the first header, so both can be
+// checked for with errors.Is.
 func Parse(r io.Reader) ([]Fasta, error) {
 	fastas := make(chan Fasta, 1000) // A buffer is used so that the functions runs as it is appending to outputFastas
-	go ParseConcurrent(r, fastas)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- parse(r, fastas)
+	}()
 
 	var outputFastas []Fasta
 	for fasta := range fastas {
 		outputFastas = append(outputFastas, fasta)
 	}
+	if err := <-errs; err != nil {
+		return nil, err
+	}
 	return outputFastas, nil
 }
 
 // ParseConcurrent concurrently parses a given Fasta file in an io.Reader into a channel of Fasta structs.
+// Parsing stops at the first malformed line. Use Parse if you need to know why.
 func ParseConcurrent(r io.Reader, sequences chan<- Fasta) {
-	// Initialize necessary variables
-	var sequenceLines []string
-	var name string
-	start := true
-
-	// Start the scanner
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Text()
+	_ = parse(r, sequences)
+}
+
+// parse does the work for Parse and ParseConcurrent. It closes the sequences channel once it's done.
+func parse(r io.Reader, sequences chan<- Fasta) error {
+	defer close(sequences)
+
+	parser := NewParser(r)
+	for {
+		fasta, err := parser.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		sequences <- fasta
+	}
+}
+
+// Parser reads Fasta records from an io.Reader one at a time, so that files
+// too large to fit into memory, like whole genomes or the Uniprot dumps, can
+// be worked through a record at a time. Only the record being parsed is held
+// in memory.
+type Parser struct {
+	reader     *bufio.Reader
+	lineNumber int
+	name       string // header of the record currently being read.
+	started    bool   // whether the first header line has been read yet.
+	done       bool
+}
+
+// NewParser returns a Parser that reads Fasta records from r.
+func NewParser(r io.Reader) *Parser {
+	return &Parser{reader: bufio.NewReader(r)}
+}
+
+// Next returns the next Fasta record. It returns io.EOF once every record has
+// been read. Like Parse, an input without any records returns ErrEmptyFile and
+// sequence before the first header wraps ErrMissingHeader.
+func (parser *Parser) Next() (Fasta, error) {
+	if parser.done {
+		return Fasta{}, io.EOF
+	}
+
+	var sequence strings.Builder
+	for {
+		// ReadString is used rather than a bufio.Scanner so that very long
+		// unwrapped sequence lines don't hit the scanner's token size limit.
+		line, err := parser.reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return Fasta{}, err
+		}
+		if err == io.EOF && len(line) == 0 {
+			parser.done = true
+			if !parser.started {
+				return Fasta{}, ErrEmptyFile
+			}
+			// Return final sequence in file
+			return Fasta{Name: parser.name, Sequence: sequence.String()}, nil
+		}
+		parser.lineNumber++
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
 		switch {
 		// if there's nothing on this line skip this iteration of the loop
-		case len(line) == 0:
+		case len(strings.TrimSpace(line)) == 0:
 			continue
 		// if it's a comment skip this line
-		case line[0:1] == ";":
+		case line[0] == ';':
 			continue
+		// sequence without a header to name it
+		case line[0] != '>' && !parser.started:
+			parser.done = true
+			return Fasta{}, fmt.Errorf("line %d: %w", parser.lineNumber, ErrMissingHeader)
 		// start of a fasta line
-		case line[0:1] != ">":
-			sequenceLines = append(sequenceLines, line)
+		case line[0] != '>':
+			sequence.WriteString(line)
+		// Process first line of file
+		case !parser.started:
+			parser.name = line[1:]
+			parser.started = true
 		// Process normal new lines
-		case line[0:1] == ">" && !start:
-			sequence := strings.Join(sequenceLines, "")
-			newFasta := Fasta{
-				Name:     name,
-				Sequence: sequence}
-			// Reset sequence lines
-			sequenceLines = []string{}
+		default:
+			fasta := Fasta{Name: parser.name, Sequence: sequence.String()}
 			// New name
-			name = line[1:]
-			sequences <- newFasta
-		// Process first line of file
-		case line[0:1] == ">" && start:
-			name = line[1:]
-			start = false
+			parser.name = line[1:]
+			return fasta, nil
 		}
 	}
-	// Add final sequence in file to channel
-	sequence := strings.Join(sequenceLines, "")
-	newFasta := Fasta{
-		Name:     name,
-		Sequence: sequence}
-	sequences <- newFasta
-	close(sequences)
 }
 
 /******************************************************************************
@@ -125,18 +247,40 @@ Start of  Read functions
 ******************************************************************************/
 
 // ReadGzConcurrent concurrently reads a gzipped Fasta file into a Fasta channel.
-func ReadGzConcurrent(path string, sequences chan<- Fasta) {
-	file, _ := os.Open(path) // these errors need to be handled/logged
-
-	reader, _ := gzip.NewReader(file)
-	defer reader.Close()
-	go ParseConcurrent(reader, sequences)
+// If the file can't be opened the channel is closed and the error is returned.
+func ReadGzConcurrent(path string, sequences chan<- Fasta) error {
+	file, err := os.Open(path)
+	if err != nil {
+		close(sequences)
+		return err
+	}
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		close(sequences)
+		return err
+	}
+	go func() {
+		defer file.Close()
+		defer reader.Close()
+		ParseConcurrent(reader, sequences)
+	}()
+	return nil
 }
 
-// ReadConcurrent concurrently reads a flat Fasta file into a Fasta channel.
-func ReadConcurrent(path string, sequences chan<- Fasta) {
-	file, _ := os.Open(path) // these errors need to be handled/logged
-	go ParseConcurrent(file, sequences)
+// ReadConcurrent concurrently reads a flat Fasta file into a Fasta channel. Gzip and bzip2 compressed files are decompressed automatically.
+// If the file can't be opened the channel is closed and the error is returned.
+func ReadConcurrent(path string, sequences chan<- Fasta) error {
+	reader, err := compress.Open(path)
+	if err != nil {
+		close(sequences)
+		return err
+	}
+	go func() {
+		defer reader.Close()
+		ParseConcurrent(reader, sequences)
+	}()
+	return nil
 }
 
 // ReadGz reads a gzipped  file into an array of Fasta structs.
@@ -145,10 +289,14 @@ func ReadGz(path string) ([]Fasta, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer file.Close()
+
 	reader, err := gzip.NewReader(file)
 	if err != nil {
 		return nil, err
 	}
+	defer reader.Close()
+
 	fastas, err := Parse(reader)
 	if err != nil {
 		return nil, err
@@ -156,14 +304,16 @@ func ReadGz(path string) ([]Fasta, error) {
 	return fastas, nil
 }
 
-// Read reads a  file into an array of Fasta structs
+// Read reads a  file into an array of Fasta structs. Gzip and bzip2 compressed files are detected and decompressed automatically.
+// Errors from opening the file are returned as is, so a missing file can be checked for with errors.Is(err, fs.ErrNotExist).
 func Read(path string) ([]Fasta, error) {
-	file, err := os.Open(path)
+	reader, err := compress.Open(path)
 	if err != nil {
 		return nil, err
 	}
+	defer reader.Close()
 
-	fastas, err := Parse(file)
+	fastas, err := Parse(reader)
 	if err != nil {
 		return nil, err
 	}
@@ -198,3 +348,123 @@ func Write(fastas []Fasta, path string) error {
 	err = ioutil.WriteFile(path, fastaBytes, 0644)
 	return err
 }
+
+/******************************************************************************
+
+Consensus functions begin here
+
+A multiple sequence alignment is usually stored as a fasta file where every
+record has been padded with "-" gaps to the same length. Reading down a column
+of that alignment tells you how conserved a position is, and the consensus is
+the sequence you get by writing down what each column agrees on.
+
+******************************************************************************/
+
+// consensusBits maps nucleotides and IUPAC ambiguity codes onto the set of
+// bases they stand for so that they can be combined with a bitwise or.
+var consensusBits = map[rune]uint8{
+	'A': 1, 'C': 2, 'G': 4, 'T': 8, 'U': 8,
+	'M': 3, 'R': 5, 'S': 6, 'V': 7, 'W': 9, 'Y': 10,
+	'H': 11, 'K': 12, 'D': 13, 'B': 14, 'N': 15,
+}
+
+// consensusCodes maps a set of bases back onto its IUPAC ambiguity code.
+var consensusCodes = [16]rune{'-', 'A', 'C', 'M', 'G', 'R', 'S', 'V', 'T', 'W', 'Y', 'H', 'K', 'D', 'B', 'N'}
+
+// Consensus takes a multiple sequence alignment of equal length fasta records
+// and returns its consensus sequence. For each column the most frequent residue
+// is used if its frequency across all records is above threshold, which must be
+// between 0 and 1. Gaps ("-") are counted like any other residue, so a column
+// that is mostly gaps has a gap as its consensus.
+//
+// Columns without a residue above threshold are written as the IUPAC ambiguity
+// code covering every nucleotide in that column, ignoring gaps. Protein
+// alignments have no ambiguity codes so those columns are written as X. An
+// alignment is treated as protein if any residue in it isn't a nucleotide or
+// an IUPAC ambiguity code.
+func Consensus(fastas []Fasta, threshold float64) (string, error) {
+	if len(fastas) == 0 {
+		return "", fmt.Errorf("no sequences to build a consensus from")
+	}
+	if threshold < 0 || threshold > 1 {
+		return "", fmt.Errorf("threshold %v must be between 0 and 1", threshold)
+	}
+	alignment := make([]string, len(fastas))
+	for index, fasta := range fastas {
+		alignment[index] = strings.ToUpper(fasta.Sequence)
+		if len(alignment[index]) != len(alignment[0]) {
+			return "", fmt.Errorf("sequence %s has length %d but %s has length %d, aligned sequences must all be the same length", fasta.Name, len(alignment[index]), fastas[0].Name, len(alignment[0]))
+		}
+	}
+
+	// an alignment is only treated as nucleotides if every residue in it is a nucleotide or ambiguity code.
+	nucleotides := true
+	for _, sequence := range alignment {
+		for _, residue := range sequence {
+			if _, ok := consensusBits[residue]; !ok && residue != '-' {
+				nucleotides = false
+			}
+		}
+	}
+
+	var consensus strings.Builder
+	for column := 0; column < len(alignment[0]); column++ {
+		counts := make(map[rune]int)
+		var mostFrequent rune
+		for _, sequence := range alignment {
+			residue := rune(sequence[column])
+			counts[residue]++
+			// ties go to the residue that appears first so that the consensus is deterministic.
+			if counts[residue] > counts[mostFrequent] {
+				mostFrequent = residue
+			}
+		}
+		if float64(counts[mostFrequent])/float64(len(alignment)) > threshold {
+			consensus.WriteRune(mostFrequent)
+			continue
+		}
+		if !nucleotides {
+			consensus.WriteRune('X')
+			continue
+		}
+		var bases uint8
+		for residue := range counts {
+			bases |= consensusBits[residue]
+		}
+		consensus.WriteRune(consensusCodes[bases])
+	}
+	return consensus.String(), nil
+}
+
+/******************************************************************************
+
+Start of JSON functions
+
+******************************************************************************/
+
+// ParseJSON parses an array of Fasta structs from JSON, like the output of WriteJSON.
+func ParseJSON(file []byte) ([]Fasta, error) {
+	var fastas []Fasta
+	if err := json.Unmarshal(file, &fastas); err != nil {
+		return nil, err
+	}
+	return fastas, nil
+}
+
+// ReadJSON reads a JSON file of Fasta structs.
+func ReadJSON(path string) ([]Fasta, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJSON(file)
+}
+
+// WriteJSON writes an array of Fasta structs out to JSON.
+func WriteJSON(fastas []Fasta, path string) error {
+	file, err := json.MarshalIndent(fastas, "", " ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, file, 0644)
+}
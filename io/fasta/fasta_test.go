@@ -2,8 +2,18 @@ package fasta
 
 import (
 	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TimothyStiles/poly/seqhash"
 )
 
 // ExampleRead shows basic usage for Read.
@@ -81,3 +91,335 @@ func ExampleReadConcurrent() {
 	fmt.Println(name)
 	// Output: MCHU - Calmodulin - Human, rabbit, bovine, rat, and chicken
 }
+
+// ExampleParser shows how to read a Fasta file one record at a time.
+func ExampleParser() {
+	file, _ := os.Open("data/base.fasta")
+	defer file.Close()
+
+	parser := NewParser(file)
+	for {
+		fasta, err := parser.Next()
+		if err != nil {
+			break
+		}
+		fmt.Println(len(fasta.Sequence))
+	}
+	// Output:
+	// 284
+	// 149
+}
+
+// ExampleFasta_Hash shows how to find duplicate sequences regardless of formatting.
+func ExampleFasta_Hash() {
+	fastas, _ := Parse(strings.NewReader(">a\nATGC\n>b\natgc\n>c\nATGG\n"))
+
+	seen := make(map[[32]byte]string)
+	for _, fasta := range fastas {
+		if name, ok := seen[fasta.Hash()]; ok {
+			fmt.Printf("%s is a duplicate of %s\n", fasta.Name, name)
+			continue
+		}
+		seen[fasta.Hash()] = fasta.Name
+	}
+	// Output: b is a duplicate of a
+}
+
+// ExampleConsensus shows how to summarize an alignment as a single sequence.
+func ExampleConsensus() {
+	alignment, _ := Parse(strings.NewReader(">a\nATGCA-TT\n>b\nATGCAGTT\n>c\nATGTA-TC\n>d\nATGTACTA\n"))
+
+	consensus, _ := Consensus(alignment, 0.5)
+	fmt.Println(consensus)
+	// Output: ATGYASTH
+}
+
+func TestSoftMaskedRoundTrip(t *testing.T) {
+	// lowercase soft-masking marks repeats and must survive a round trip.
+	softMasked := ">chr1 soft-masked\nACGTacgtacgtACGT\nNNNNacgtACGT\n"
+	fastas, _ := Parse(strings.NewReader(softMasked))
+	if fastas[0].Sequence != "ACGTacgtacgtACGTNNNNacgtACGT" {
+		t.Errorf("Parse did not preserve soft-masking. Got %q", fastas[0].Sequence)
+	}
+
+	built, _ := Build(fastas)
+	reparsed, _ := Parse(bytes.NewReader(built))
+	if reparsed[0].Sequence != fastas[0].Sequence {
+		t.Errorf("Build did not preserve soft-masking. Got %q", reparsed[0].Sequence)
+	}
+}
+
+func TestHash(t *testing.T) {
+	reference := Fasta{Name: "gfp", Sequence: "ATGGCTAGCAAAGGAGAAGAACTTTTC"}
+	reformatted := Fasta{Name: "gfp soft-masked", Sequence: "ATGGCTAGCA\naaggagaaga ACTTTTC\r\n"}
+	if reference.Hash() != reformatted.Hash() {
+		t.Errorf("the same sequence formatted differently should hash identically")
+	}
+
+	mutated := Fasta{Name: "gfp", Sequence: "ATGGCTAGCAAAGGAGAAGAACTTTTT"}
+	if reference.Hash() == mutated.Hash() {
+		t.Errorf("different sequences should not hash identically")
+	}
+
+	// swapping the hash function should change the digest.
+	defer func(hashFunction func([]byte) [32]byte) { HashFunction = hashFunction }(HashFunction)
+	HashFunction = func(sequence []byte) [32]byte { return [32]byte{byte(len(sequence))} }
+	if reference.Hash() != [32]byte{27} {
+		t.Errorf("Hash did not use HashFunction")
+	}
+}
+
+func TestReadGzipped(t *testing.T) {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	// gzip the test file so we can check Read decompresses it transparently.
+	file, _ := ioutil.ReadFile("data/base.fasta")
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, _ = gzipWriter.Write(file)
+	_ = gzipWriter.Close()
+	tmpGzipFilePath := filepath.Join(tmpDataDir, "base.fasta.gz")
+	_ = ioutil.WriteFile(tmpGzipFilePath, gzipped.Bytes(), 0644)
+
+	expected, _ := Read("data/base.fasta")
+	got, err := Read(tmpGzipFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(expected) || got[1].Sequence != expected[1].Sequence {
+		t.Errorf("Reading a gzipped file does not give the same result as the plain file")
+	}
+}
+
+func TestReadBzip2(t *testing.T) {
+	expected, _ := Read("data/base.fasta")
+	got, err := Read("data/base.fasta.bz2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(expected) || got[1].Sequence != expected[1].Sequence {
+		t.Errorf("Reading a bzip2 compressed file does not give the same result as the plain file")
+	}
+
+	// plain text that happens to start with the bzip2 magic bytes isn't decompressed.
+	fastas, err := Parse(strings.NewReader(">BZh9\nATGC\n"))
+	if err != nil || fastas[0].Name != "BZh9" {
+		t.Errorf("expected plain text to be parsed as is, got %v and %v", fastas, err)
+	}
+}
+
+func TestConsensus(t *testing.T) {
+	alignment := []Fasta{
+		{Name: "a", Sequence: "ACGT-a"},
+		{Name: "b", Sequence: "ACGT-A"},
+		{Name: "c", Sequence: "AGTTCC"},
+		{Name: "d", Sequence: "AGCT-G"},
+	}
+	tests := []struct {
+		threshold float64
+		consensus string
+	}{
+		{0, "ACGT-A"},
+		{0.5, "ASBT-V"},
+		{0.9, "ASBTCV"},
+	}
+	for _, test := range tests {
+		consensus, err := Consensus(alignment, test.threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if consensus != test.consensus {
+			t.Errorf("threshold %v: expected %s, got %s", test.threshold, test.consensus, consensus)
+		}
+	}
+
+	// protein columns without a clear consensus become X.
+	proteins := []Fasta{{Name: "a", Sequence: "MEL"}, {Name: "b", Sequence: "MQF"}}
+	if consensus, _ := Consensus(proteins, 0.5); consensus != "MXX" {
+		t.Errorf("expected MXX, got %s", consensus)
+	}
+
+	if _, err := Consensus(append(alignment, Fasta{Name: "short", Sequence: "ACG"}), 0.5); err == nil {
+		t.Errorf("expected an error for sequences of different lengths")
+	}
+	if _, err := Consensus(alignment, 1.5); err == nil {
+		t.Errorf("expected an error for a threshold above 1")
+	}
+	if _, err := Consensus(nil, 0.5); err == nil {
+		t.Errorf("expected an error for an empty alignment")
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	_, err := Read("data/does_not_exist.fasta")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a file not found error, got %v", err)
+	}
+
+	tmpDir, err := ioutil.TempDir("", "fasta_errors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tests := []struct {
+		name     string
+		contents string
+		err      error
+	}{
+		{"empty.fasta", "", ErrEmptyFile},
+		{"blank.fasta", "\n  \n; just a comment\n", ErrEmptyFile},
+		{"headerless.fasta", "ATGC\n>gene\nATGC\n", ErrMissingHeader},
+	}
+	for _, test := range tests {
+		path := filepath.Join(tmpDir, test.name)
+		if err := ioutil.WriteFile(path, []byte(test.contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		fastas, err := Read(path)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+		if fastas != nil {
+			t.Errorf("%s: expected no records, got %v", test.name, fastas)
+		}
+	}
+
+	// the concurrent readers close their channel so callers ranging over it don't hang.
+	for _, read := range []func(string, chan<- Fasta) error{ReadConcurrent, ReadGzConcurrent} {
+		fastas := make(chan Fasta)
+		if err := read("data/does_not_exist.fasta", fastas); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected a file not found error, got %v", err)
+		}
+		if _, open := <-fastas; open {
+			t.Error("expected the channel to be closed")
+		}
+	}
+
+	// a record with a header but no sequence is still a valid record.
+	fastas, err := Parse(strings.NewReader(">empty\n"))
+	if err != nil || len(fastas) != 1 || fastas[0].Name != "empty" {
+		t.Errorf("expected a single empty record, got %v and %v", fastas, err)
+	}
+}
+
+func TestParser(t *testing.T) {
+	// lines longer than bufio.Scanner's default 64kb token limit are read whole.
+	longSequence := strings.Repeat("ATGC", 50000)
+	input := "; comment\r\n>first record\r\nATG\r\nCCC\r\n\n>second\n" + longSequence + "\n>empty\n>last\nTAA"
+	expected := []Fasta{
+		{Name: "first record", Sequence: "ATGCCC"},
+		{Name: "second", Sequence: longSequence},
+		{Name: "empty", Sequence: ""},
+		{Name: "last", Sequence: "TAA"},
+	}
+
+	parser := NewParser(strings.NewReader(input))
+	for _, want := range expected {
+		got, err := parser.Next()
+		if err != nil {
+			t.Fatalf("unexpected error reading %s: %v", want.Name, err)
+		}
+		if got != want {
+			t.Errorf("expected %s with a %d long sequence, got %s with a %d long sequence", want.Name, len(want.Sequence), got.Name, len(got.Sequence))
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := parser.Next(); err != io.EOF {
+			t.Errorf("expected io.EOF once all records are read, got %v", err)
+		}
+	}
+
+	if _, err := NewParser(strings.NewReader("")).Next(); !errors.Is(err, ErrEmptyFile) {
+		t.Errorf("expected %v, got %v", ErrEmptyFile, err)
+	}
+	if _, err := NewParser(strings.NewReader("\nATGC\n>gene\n")).Next(); !errors.Is(err, ErrMissingHeader) || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected %v on line 2, got %v", ErrMissingHeader, err)
+	}
+
+	// the parser gives the same records as Parse.
+	file, err := os.Open("data/uniprot_1mb_test.fasta.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fastas, err := ReadGz("data/uniprot_1mb_test.fasta.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser = NewParser(reader)
+	for index := 0; ; index++ {
+		fasta, err := parser.Next()
+		if err == io.EOF {
+			if index != len(fastas) {
+				t.Errorf("expected %d records, got %d", len(fastas), index)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if index >= len(fastas) || fasta != fastas[index] {
+			t.Fatalf("record %d differs from Parse", index)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "fasta_json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fastas, _ := Read("data/base.fasta")
+	path := filepath.Join(tmpDir, "base.json")
+	if err := WriteJSON(fastas, path); err != nil {
+		t.Fatal(err)
+	}
+	jsonFastas, err := ReadJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jsonFastas) != len(fastas) {
+		t.Fatalf("expected %d records, got %d", len(fastas), len(jsonFastas))
+	}
+	for index := range fastas {
+		if jsonFastas[index] != fastas[index] {
+			t.Errorf("record %d changed on a JSON round trip: got %v, expected %v", index, jsonFastas[index], fastas[index])
+		}
+	}
+
+	if _, err := ParseJSON([]byte("[")); err == nil {
+		t.Errorf("expected an error parsing invalid JSON")
+	}
+}
+
+func TestFastaSeqhash(t *testing.T) {
+	dna, err := Fasta{Sequence: "atgAAACCC"}.Seqhash(seqhash.DNA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := seqhash.Hash("GGGTTTCAT", seqhash.DNA, false, true)
+	if dna != expected {
+		t.Errorf("expected %s, got %s", expected, dna)
+	}
+
+	if protein, err := (Fasta{Sequence: "MKK*"}).Seqhash(seqhash.PROTEIN); err != nil || !strings.HasPrefix(protein, "v1_PLS_") {
+		t.Errorf("expected a v1_PLS_ Seqhash, got %s and %v", protein, err)
+	}
+	if rna, err := (Fasta{Sequence: "AUGAAA"}).Seqhash(seqhash.RNA); err != nil || !strings.HasPrefix(rna, "v1_RLS_") {
+		t.Errorf("expected a v1_RLS_ Seqhash, got %s and %v", rna, err)
+	}
+	if _, err := (Fasta{Sequence: "ATG"}).Seqhash("dna"); err == nil {
+		t.Errorf("expected an error for an unknown sequence type")
+	}
+}
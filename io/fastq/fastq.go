@@ -0,0 +1,379 @@
+/*
+Package fastq contains fastq parsers and writers.
+
+Fastq is a flat text file format for storing sequencing reads alongside a
+quality score for every base. It is the output of almost every sequencer,
+from Illumina to Nanopore, so it is usually the first file you touch after a
+sequencing run.
+
+Every fastq record is made of four parts:
+
+	@read identifier and optional description
+	GATTTGGGGTTCAAAGCAGTATCGATCAAATAGTAAATCCATTTGTTCAACTCACAGTTT
+	+
+	!''*((((***+))%%%++)(%%%%).1***-+*''))**55CCF>>>>>>CCCCCCC65
+
+The quality line has exactly one character per base. Each character encodes a
+Phred score, which is -10*log10 of the probability that the base was called
+wrong, as an ASCII character offset by either 33 (Phred33, used by Sanger,
+Nanopore and Illumina 1.8+) or 64 (Phred64, used by Illumina 1.3 to 1.7).
+
+This package provides a parser and writer for working with Fastq formatted
+sequencing reads, along with helpers for decoding their quality scores.
+*/
+package fastq
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/TimothyStiles/poly"
+	"github.com/TimothyStiles/poly/io/internal/compress"
+)
+
+// Fastq is a struct representing a single Fastq read with a Name, its Sequence
+// and the Quality string for that sequence.
+type Fastq struct {
+	Name     string `json:"name"`
+	Sequence string `json:"sequence"`
+	Quality  string `json:"quality"`
+}
+
+// GetName returns the name of the Fastq read.
+func (fastq Fastq) GetName() string {
+	return fastq.Name
+}
+
+// GetSequence returns the sequence of the Fastq read.
+func (fastq Fastq) GetSequence() string {
+	return fastq.Sequence
+}
+
+// GetFeatures always returns nil since fastq files have no annotations.
+func (fastq Fastq) GetFeatures() []poly.Feature {
+	return nil
+}
+
+// GetMeta returns the name of the Fastq read.
+func (fastq Fastq) GetMeta() poly.Meta {
+	return poly.Meta{Name: fastq.Name}
+}
+
+// ErrEmptyFile is returned when a Fastq file has no reads in it.
+var ErrEmptyFile = errors.New("fastq file is empty")
+
+// ErrInvalidRecord is wrapped by errors for reads that don't follow the
+// four part @name, sequence, +, quality layout.
+var ErrInvalidRecord = errors.New("invalid fastq record")
+
+/******************************************************************************
+
+Fastq Parser begins here
+
+Reads are nearly always written as exactly four lines, but the format allows
+the sequence and quality to be wrapped over several lines like a fasta file.
+Since "@" is also a valid quality character, the parser relies on the quality
+being exactly as long as the sequence to know where a wrapped read ends.
+
+******************************************************************************/
+
+// Parse parses a given Fastq file into an array of Fastq structs.
+//
+// Parse returns ErrEmptyFile if there are no reads to parse and wraps
+// ErrInvalidRecord for malformed reads, so both can be checked for with errors.Is.
+func Parse(r io.Reader) ([]Fastq, error) {
+	parser := NewParser(r)
+	var fastqs []Fastq
+	for {
+		fastq, err := parser.Next()
+		if err == io.EOF {
+			return fastqs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		fastqs = append(fastqs, fastq)
+	}
+}
+
+// ParseConcurrent concurrently parses a given Fastq file in an io.Reader into a channel of Fastq structs.
+// Parsing stops at the first malformed read. Use Parse or a Parser if you need to know why.
+func ParseConcurrent(r io.Reader, sequences chan<- Fastq) {
+	defer close(sequences)
+	parser := NewParser(r)
+	for {
+		fastq, err := parser.Next()
+		if err != nil {
+			return
+		}
+		sequences <- fastq
+	}
+}
+
+// Parser reads Fastq records from an io.Reader one at a time, so that whole
+// sequencing runs can be worked through without loading them into memory.
+type Parser struct {
+	reader     *bufio.Reader
+	lineNumber int
+	started    bool // whether any read has been found yet.
+	done       bool
+}
+
+// NewParser returns a Parser that reads Fastq records from r.
+func NewParser(r io.Reader) *Parser {
+	return &Parser{reader: bufio.NewReader(r)}
+}
+
+// readLine returns the next line without its line ending. ok is false once
+// there are no lines left.
+func (parser *Parser) readLine() (line string, ok bool, err error) {
+	line, err = parser.reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", false, err
+	}
+	if err == io.EOF && len(line) == 0 {
+		return "", false, nil
+	}
+	parser.lineNumber++
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), true, nil
+}
+
+// Next returns the next Fastq read. It returns io.EOF once every read has
+// been parsed. Like Parse, an input without any reads returns ErrEmptyFile and
+// malformed reads wrap ErrInvalidRecord.
+func (parser *Parser) Next() (Fastq, error) {
+	if parser.done {
+		return Fastq{}, io.EOF
+	}
+
+	// find the header, skipping any blank lines between reads.
+	var header string
+	for {
+		line, ok, err := parser.readLine()
+		if err != nil {
+			return Fastq{}, err
+		}
+		if !ok {
+			parser.done = true
+			if !parser.started {
+				return Fastq{}, ErrEmptyFile
+			}
+			return Fastq{}, io.EOF
+		}
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		if line[0] != '@' {
+			return Fastq{}, parser.invalidRecord("expected a header starting with @, got %q", line)
+		}
+		header = line[1:]
+		parser.started = true
+		break
+	}
+
+	// sequence lines continue until the "+" separator.
+	var sequence strings.Builder
+	for {
+		line, ok, err := parser.readLine()
+		if err != nil {
+			return Fastq{}, err
+		}
+		if !ok {
+			return Fastq{}, parser.invalidRecord("read %q ends before its + separator", header)
+		}
+		if strings.HasPrefix(line, "+") {
+			break
+		}
+		sequence.WriteString(strings.TrimSpace(line))
+	}
+
+	// quality lines continue until there is a score for every base.
+	var quality strings.Builder
+	for quality.Len() < sequence.Len() {
+		line, ok, err := parser.readLine()
+		if err != nil {
+			return Fastq{}, err
+		}
+		if !ok {
+			break
+		}
+		quality.WriteString(strings.TrimSpace(line))
+	}
+	if quality.Len() != sequence.Len() {
+		return Fastq{}, parser.invalidRecord("read %q has %d bases but %d quality scores", header, sequence.Len(), quality.Len())
+	}
+
+	return Fastq{Name: header, Sequence: sequence.String(), Quality: quality.String()}, nil
+}
+
+// invalidRecord stops the parser and returns an ErrInvalidRecord for the current line.
+func (parser *Parser) invalidRecord(format string, args ...interface{}) error {
+	parser.done = true
+	return fmt.Errorf("line %d: %w: %s", parser.lineNumber, ErrInvalidRecord, fmt.Sprintf(format, args...))
+}
+
+/******************************************************************************
+
+Start of Read functions
+
+******************************************************************************/
+
+// ReadConcurrent concurrently reads a Fastq file into a Fastq channel. Gzip and bzip2 compressed files are decompressed automatically.
+// If the file can't be opened the channel is closed and the error is returned.
+func ReadConcurrent(path string, sequences chan<- Fastq) error {
+	reader, err := compress.Open(path)
+	if err != nil {
+		close(sequences)
+		return err
+	}
+	go func() {
+		defer reader.Close()
+		ParseConcurrent(reader, sequences)
+	}()
+	return nil
+}
+
+// Read reads a Fastq file into an array of Fastq structs. Gzipped files, which
+// most sequencers write by default, and bzip2 compressed files are detected and
+// decompressed automatically.
+func Read(path string) ([]Fastq, error) {
+	reader, err := compress.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return Parse(reader)
+}
+
+/******************************************************************************
+
+Start of Write functions
+
+******************************************************************************/
+
+// Build writes an array of Fastq structs to a Fastq formatted byte array. Each
+// read is written on four lines. Reads whose quality string isn't the same
+// length as their sequence can't be read back in, so they return an error.
+func Build(fastqs []Fastq) ([]byte, error) {
+	var fastqString bytes.Buffer
+	for _, fastq := range fastqs {
+		if len(fastq.Quality) != len(fastq.Sequence) {
+			return nil, fmt.Errorf("read %q has %d bases but %d quality scores", fastq.Name, len(fastq.Sequence), len(fastq.Quality))
+		}
+		fastqString.WriteString("@")
+		fastqString.WriteString(fastq.Name)
+		fastqString.WriteString("\n")
+		fastqString.WriteString(fastq.Sequence)
+		fastqString.WriteString("\n+\n")
+		fastqString.WriteString(fastq.Quality)
+		fastqString.WriteString("\n")
+	}
+	return fastqString.Bytes(), nil
+}
+
+// Write writes a fastq array to a file.
+func Write(fastqs []Fastq, path string) error {
+	fastqBytes, err := Build(fastqs)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, fastqBytes, 0644)
+}
+
+/******************************************************************************
+
+Quality score functions begin here
+
+******************************************************************************/
+
+// Encoding is the ASCII offset used to write Phred quality scores.
+type Encoding int
+
+const (
+	// Phred33 quality strings start at "!" for a score of 0. It is used by
+	// Sanger, Nanopore and Illumina 1.8+ reads.
+	Phred33 Encoding = 33
+	// Phred64 quality strings start at "@" for a score of 0. It is used by
+	// Illumina 1.3 to 1.7 reads.
+	Phred64 Encoding = 64
+)
+
+// DetectEncoding guesses whether reads were written with Phred33 or Phred64
+// quality scores by looking at the range of quality characters they use.
+//
+// Phred64 reads never use characters below "@", a score of 0, and the Illumina
+// 1.3 to 1.7 machines that wrote them never called scores above 41, written as
+// "i". Reads are only taken to be Phred64 when all of their characters are in
+// that range and at least one is above "K", which is higher than any
+// Illumina Phred33 score. Everything else, including reads with very high
+// Phred33 scores from PacBio HiFi or basecalled Nanopore data, is Phred33,
+// by far the most common encoding today.
+//
+// Solexa scores, written by the Illumina pipeline before 1.3 with the same
+// offset as Phred64 but going down to -5, aren't supported.
+func DetectEncoding(fastqs []Fastq) (Encoding, error) {
+	minimum, maximum := byte(255), byte(0)
+	for _, fastq := range fastqs {
+		for index := 0; index < len(fastq.Quality); index++ {
+			character := fastq.Quality[index]
+			if character < '!' || character > '~' {
+				return 0, fmt.Errorf("read %q has invalid quality character %q", fastq.Name, character)
+			}
+			if character < minimum {
+				minimum = character
+			}
+			if character > maximum {
+				maximum = character
+			}
+		}
+	}
+	if minimum >= '@' && maximum > 'K' && maximum <= 'i' {
+		return Phred64, nil
+	}
+	return Phred33, nil
+}
+
+// Scores decodes the Fastq read's quality string into a Phred score for each
+// base. Solexa scores below 0 aren't Phred scores, so decoding them as Phred64
+// returns an error.
+func (fastq Fastq) Scores(encoding Encoding) ([]int, error) {
+	scores := make([]int, len(fastq.Quality))
+	for index := 0; index < len(fastq.Quality); index++ {
+		score := int(fastq.Quality[index]) - int(encoding)
+		if score < 0 || fastq.Quality[index] > '~' {
+			return nil, fmt.Errorf("read %q has invalid quality character %q at position %d for Phred%d", fastq.Name, fastq.Quality[index], index, encoding)
+		}
+		scores[index] = score
+	}
+	return scores, nil
+}
+
+// ConvertEncoding returns a copy of the reads with their quality strings
+// rewritten from one encoding to another, for example to bring old Phred64
+// Illumina reads in line with modern Phred33 tools.
+func ConvertEncoding(fastqs []Fastq, from Encoding, to Encoding) ([]Fastq, error) {
+	converted := make([]Fastq, len(fastqs))
+	for index, fastq := range fastqs {
+		scores, err := fastq.Scores(from)
+		if err != nil {
+			return nil, err
+		}
+		quality := make([]byte, len(scores))
+		for position, score := range scores {
+			character := score + int(to)
+			if character > '~' {
+				return nil, fmt.Errorf("read %q has a quality score of %d which can't be written as Phred%d", fastq.Name, score, to)
+			}
+			quality[position] = byte(character)
+		}
+		fastq.Quality = string(quality)
+		converted[index] = fastq
+	}
+	return converted, nil
+}
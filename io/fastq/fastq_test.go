@@ -0,0 +1,279 @@
+package fastq
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ExampleRead shows basic usage for Read.
+func ExampleRead() {
+	fastqs, _ := Read("data/example.fastq")
+	fmt.Println(fastqs[0].Name)
+	// Output: SRR001666.1 071112_SLXA-EAS1_s_7:5:1:817:345 length=72
+}
+
+// ExampleParse shows basic usage for Parse.
+func ExampleParse() {
+	file, _ := os.Open("data/example.fastq")
+	defer file.Close()
+	fastqs, _ := Parse(file)
+
+	fmt.Println(fastqs[1].Sequence)
+	// Output: GTTCAGGGATACGACGTTTGTATTTTAAGAATCTGAAGCAGAAGTCGATGATAATACGCGTCGTTTTATCAT
+}
+
+// ExampleBuild shows basic usage for Build.
+func ExampleBuild() {
+	fastqs, _ := Read("data/example.fastq")
+	fastqBytes, _ := Build(fastqs[:1])
+
+	fmt.Println(strings.Count(string(fastqBytes), "\n"))
+	// Output: 4
+}
+
+// ExampleWrite shows basic usage of the writer.
+func ExampleWrite() {
+	fastqs, _ := Read("data/example.fastq")
+	tmpDir, _ := ioutil.TempDir("", "fastq_example")
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "test.fastq")
+
+	_ = Write(fastqs, path)
+	fastqsReread, _ := Read(path)
+
+	fmt.Println(fastqsReread[2].Quality == fastqs[2].Quality)
+	// Output: true
+}
+
+// ExampleParser shows how to read a Fastq file one read at a time.
+func ExampleParser() {
+	file, _ := os.Open("data/example.fastq")
+	defer file.Close()
+
+	parser := NewParser(file)
+	for {
+		fastq, err := parser.Next()
+		if err != nil {
+			break
+		}
+		fmt.Println(len(fastq.Sequence))
+	}
+	// Output:
+	// 72
+	// 72
+	// 72
+}
+
+// ExampleFastq_Scores shows how to decode a read's quality scores.
+func ExampleFastq_Scores() {
+	fastqs, _ := Read("data/example.fastq")
+	encoding, _ := DetectEncoding(fastqs)
+	scores, _ := fastqs[2].Scores(encoding)
+
+	fmt.Println(encoding == Phred33, scores[:5])
+	// Output: true [3 4 4 7 5]
+}
+
+func TestParse(t *testing.T) {
+	// wrapped reads, windows line endings and "@" as a quality character are all allowed.
+	input := "@first\r\nATGC\r\nAT\r\n+\r\n@@II\r\nI#\r\n\n@second read\nGGG\n+second read\n@!I\n"
+	expected := []Fastq{
+		{Name: "first", Sequence: "ATGCAT", Quality: "@@III#"},
+		{Name: "second read", Sequence: "GGG", Quality: "@!I"},
+	}
+	fastqs, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fastqs) != len(expected) {
+		t.Fatalf("expected %d reads, got %d", len(expected), len(fastqs))
+	}
+	for index := range expected {
+		if fastqs[index] != expected[index] {
+			t.Errorf("expected %v, got %v", expected[index], fastqs[index])
+		}
+	}
+
+	parser := NewParser(strings.NewReader(input))
+	for range expected {
+		if _, err := parser.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := parser.Next(); err != io.EOF {
+			t.Errorf("expected io.EOF once all reads are parsed, got %v", err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		err      error
+		line     string
+	}{
+		{"empty", "", ErrEmptyFile, ""},
+		{"blank", "\n \n", ErrEmptyFile, ""},
+		{"fasta", ">read\nATGC\n", ErrInvalidRecord, "line 1"},
+		{"no separator", "@read\nATGC\n", ErrInvalidRecord, "line 2"},
+		{"short quality", "@read\nATGC\n+\nII\n", ErrInvalidRecord, "line 4"},
+		{"long quality", "@read\nATGC\n+\nIIIII\n", ErrInvalidRecord, "line 4"},
+		{"second read", "@read\nATGC\n+\nIIII\nread\n", ErrInvalidRecord, "line 5"},
+	}
+	for _, test := range tests {
+		fastqs, err := Parse(strings.NewReader(test.contents))
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), test.line) {
+			t.Errorf("%s: expected error on %s, got %v", test.name, test.line, err)
+		}
+		if fastqs != nil {
+			t.Errorf("%s: expected no reads, got %v", test.name, fastqs)
+		}
+	}
+
+	if _, err := Read("data/does_not_exist.fastq"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a file not found error, got %v", err)
+	}
+	fastqs := make(chan Fastq)
+	if err := ReadConcurrent("data/does_not_exist.fastq", fastqs); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a file not found error, got %v", err)
+	}
+	if _, open := <-fastqs; open {
+		t.Error("expected the channel to be closed")
+	}
+
+	if _, err := Build([]Fastq{{Name: "read", Sequence: "ATGC", Quality: "II"}}); err == nil {
+		t.Error("expected an error building a read with too few quality scores")
+	}
+}
+
+func TestReadGzipped(t *testing.T) {
+	plain, err := ioutil.ReadFile("data/example.fastq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+	writer.Close()
+
+	tmpDir, err := ioutil.TempDir("", "fastq_gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "example.fastq.gz")
+	if err := ioutil.WriteFile(path, compressed.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := Read("data/example.fastq")
+	fastqs, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fastqs) != len(expected) {
+		t.Fatalf("expected %d reads, got %d", len(expected), len(fastqs))
+	}
+
+	sequences := make(chan Fastq, 10)
+	if err := ReadConcurrent(path, sequences); err != nil {
+		t.Fatal(err)
+	}
+	index := 0
+	for fastq := range sequences {
+		if fastq != expected[index] {
+			t.Errorf("read %d differs between Read and ReadConcurrent", index)
+		}
+		index++
+	}
+	if index != len(expected) {
+		t.Errorf("expected %d reads from ReadConcurrent, got %d", len(expected), index)
+	}
+}
+
+func TestReadBzip2(t *testing.T) {
+	expected, _ := Read("data/example.fastq")
+	got, err := Read("data/example.fastq.bz2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Reading a bzip2 compressed file does not give the same result as the plain file")
+	}
+}
+
+func TestQualityEncoding(t *testing.T) {
+	tests := []struct {
+		quality  string
+		encoding Encoding
+		scores   []int
+	}{
+		{"!+5?I", Phred33, []int{0, 10, 20, 30, 40}},
+		{"@JT^h", Phred64, []int{0, 10, 20, 30, 40}},
+		// ambiguous reads are assumed to be Phred33.
+		{"BBBB", Phred33, []int{33, 33, 33, 33}},
+		// high quality Phred33 reads, like PacBio HiFi's, go well past Phred64's lowest characters.
+		{"S^hs~", Phred33, []int{50, 61, 71, 82, 93}},
+		{"5S~", Phred33, []int{20, 50, 93}},
+	}
+	for _, test := range tests {
+		fastq := Fastq{Name: test.quality, Sequence: strings.Repeat("A", len(test.quality)), Quality: test.quality}
+		encoding, err := DetectEncoding([]Fastq{fastq})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoding != test.encoding {
+			t.Errorf("%s: expected Phred%d, got Phred%d", test.quality, test.encoding, encoding)
+		}
+		scores, err := fastq.Scores(encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(scores) != fmt.Sprint(test.scores) {
+			t.Errorf("%s: expected scores %v, got %v", test.quality, test.scores, scores)
+		}
+	}
+
+	if _, err := DetectEncoding([]Fastq{{Name: "invalid", Sequence: "AA", Quality: "! "}}); err == nil {
+		t.Error("expected an error for a quality character that isn't printable")
+	}
+	if _, err := (Fastq{Name: "low", Sequence: "A", Quality: "!"}).Scores(Phred64); err == nil {
+		t.Error("expected an error decoding Phred33 characters as Phred64")
+	}
+
+	// Solexa scores below 0 aren't supported.
+	if _, err := ConvertEncoding([]Fastq{{Name: "solexa", Sequence: "AAAA", Quality: ";@JT"}}, Phred64, Phred33); err == nil {
+		t.Error("expected an error converting negative Solexa scores")
+	}
+
+	converted, err := ConvertEncoding([]Fastq{{Name: "phred64", Sequence: "AAAA", Quality: "@@JT"}}, Phred64, Phred33)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted[0].Quality != "!!+5" {
+		t.Errorf("expected converted quality !!+5, got %s", converted[0].Quality)
+	}
+	roundTrip, err := ConvertEncoding(converted, Phred33, Phred64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roundTrip[0].Quality != "@@JT" {
+		t.Errorf("expected converted quality @@JT, got %s", roundTrip[0].Quality)
+	}
+}
@@ -50,6 +50,19 @@ func ExampleGenbank_AddFeature() {
 	// Output: true
 }
 
+func ExampleFeature_Qualifiers() {
+	sequence, _ := genbank.Read("../../data/bsub.gbk")
+	for _, feature := range sequence.Features {
+		if gene, _ := feature.Qualifier("gene"); gene == "dnaA" && feature.Type == "CDS" {
+			fmt.Println(feature.Product())
+			fmt.Println(feature.Qualifiers("db_xref")[0:3])
+		}
+	}
+	// Output:
+	// chromosomal replication initiator informational ATPase
+	// [EnsemblGenomes-Gn:BSU00010 EnsemblGenomes-Tr:CAB11777 GOA:P05648]
+}
+
 func ExampleFeature_GetSequence() {
 
 	// Sequence for greenflourescent protein (GFP) that we're using as test data for this example.
@@ -130,3 +143,11 @@ func TestFeature_GetSequence(t *testing.T) {
 	}
 
 }
+
+func ExampleGenbank_Seqhash() {
+	sequence, _ := genbank.Read("../../data/puc19.gbk")
+
+	hash, _ := sequence.Seqhash()
+	fmt.Println(hash)
+	// Output: v1_DCD_4b0616d1b3fc632e42d78521deb38b44fba95cca9fde159e01cd567fa996ceb9
+}
@@ -13,7 +13,8 @@ package genbank
 import (
 	"bufio"
 	"bytes"
-	"compress/gzip"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
@@ -21,6 +22,10 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/TimothyStiles/poly"
+	"github.com/TimothyStiles/poly/io/internal/compress"
+	"github.com/TimothyStiles/poly/io/internal/slice"
+	"github.com/TimothyStiles/poly/seqhash"
 	"github.com/TimothyStiles/poly/transform"
 	"github.com/mitchellh/go-wordwrap"
 	"lukechampine.com/blake3"
@@ -55,19 +60,23 @@ type Meta struct {
 	Name                 string            `json:"name"`
 	SequenceHash         string            `json:"sequence_hash"`
 	SequenceHashFunction string            `json:"hash_function"`
-	CheckSum             [32]byte          `json:"checkSum"` // blake3 checksum of the parsed file itself. Useful for if you want to check if incoming genbank/gff files are different.
+	CheckSum             [32]byte          `json:"checkSum"`         // blake3 checksum of the parsed file itself. Useful for if you want to check if incoming genbank/gff files are different.
+	Contig               []ContigLocation  `json:"contig,omitempty"` // parsed from the CONTIG line of records that are assembled from other records instead of carrying their own sequence. The raw line stays in Other.
 }
 
 // Feature holds the information for a feature in a Genbank file and other annotated sequence files.
 type Feature struct {
-	Type                 string            `json:"type"`
-	Description          string            `json:"description"`
-	Attributes           map[string]string `json:"attributes"`
-	SequenceHash         string            `json:"sequence_hash"`
-	SequenceHashFunction string            `json:"hash_function"`
-	Sequence             string            `json:"sequence"`
-	Location             Location          `json:"location"`
-	ParentSequence       *Genbank          `json:"-"`
+	Type        string            `json:"type"`
+	Description string            `json:"description"`
+	Attributes  map[string]string `json:"attributes"`
+	// RepeatedAttributes holds every value of qualifiers that occur more than once, such as /db_xref.
+	// Attributes only holds the last value of those qualifiers.
+	RepeatedAttributes   map[string][]string `json:"repeated_attributes,omitempty"`
+	SequenceHash         string              `json:"sequence_hash"`
+	SequenceHashFunction string              `json:"hash_function"`
+	Sequence             string              `json:"sequence"`
+	Location             Location            `json:"location"`
+	ParentSequence       *Genbank            `json:"-"`
 }
 
 // Reference holds information for one reference in a Meta struct.
@@ -105,6 +114,15 @@ type Location struct {
 	SubLocations      []Location `json:"sub_locations"`
 }
 
+// ContigLocation is one piece of a CONTIG join, either a region of another
+// record or a gap between two of them.
+type ContigLocation struct {
+	Accession string   `json:"accession"`            // the record the region comes from. Empty for gaps.
+	Location  Location `json:"location"`             // the region of that record, in its own coordinates.
+	Gap       bool     `json:"gap,omitempty"`        // set for gap() pieces.
+	GapLength int      `json:"gap_length,omitempty"` // estimated length of the gap, or 0 if it is unknown.
+}
+
 // AddFeature adds a feature to a Genbank struct.
 func (sequence *Genbank) AddFeature(feature *Feature) error {
 	feature.ParentSequence = sequence
@@ -112,8 +130,99 @@ func (sequence *Genbank) AddFeature(feature *Feature) error {
 	return nil
 }
 
-// GetSequence returns the sequence of a feature.
+// GetName returns the locus name of the Genbank, falling back on Meta.Name.
+func (sequence Genbank) GetName() string {
+	if sequence.Meta.Locus.Name != "" {
+		return sequence.Meta.Locus.Name
+	}
+	return sequence.Meta.Name
+}
+
+// GetSequence returns the sequence of the Genbank.
+func (sequence Genbank) GetSequence() string {
+	return sequence.Sequence
+}
+
+// GetFeatures returns the features of the Genbank as poly.Features.
+func (sequence Genbank) GetFeatures() []poly.Feature {
+	features := make([]poly.Feature, len(sequence.Features))
+	for index, feature := range sequence.Features {
+		features[index] = feature
+	}
+	return features
+}
+
+// GetMeta returns the name, definition and topology of the Genbank.
+func (sequence Genbank) GetMeta() poly.Meta {
+	return poly.Meta{Name: sequence.GetName(), Description: sequence.Meta.Definition, Circular: sequence.Meta.Locus.Circular}
+}
+
+// Seqhash returns the Seqhash of the sequence. Its LOCUS line says whether it's
+// DNA, RNA or protein and whether it's circular, which seqhash.FromSequence
+// turns into a Seqhash.
+func (sequence Genbank) Seqhash() (string, error) {
+	locus := sequence.Meta.Locus
+	moleculeType := locus.MoleculeType
+	if locus.SequenceCoding == "aa" {
+		moleculeType = seqhash.PROTEIN
+	}
+	return seqhash.FromSequence(sequence.Sequence, moleculeType, locus.Circular)
+}
+
+// GetType returns the type of a feature.
+func (feature Feature) GetType() string {
+	return feature.Type
+}
+
+// GetAttributes returns the attributes of a feature.
+func (feature Feature) GetAttributes() map[string]string {
+	return feature.Attributes
+}
+
+// Qualifier returns the first value of a qualifier such as "gene" or "locus_tag".
+// Keys are matched case-insensitively and without their leading "/".
+func (feature Feature) Qualifier(key string) (string, bool) {
+	values := feature.Qualifiers(key)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// Qualifiers returns every value of a qualifier in the order they appear in
+// the feature. Most qualifiers only have one value but some, like /db_xref,
+// are often repeated. Keys are matched case-insensitively.
+func (feature Feature) Qualifiers(key string) []string {
+	key = strings.TrimPrefix(key, "/")
+	for attributeKey, values := range feature.RepeatedAttributes {
+		if strings.EqualFold(attributeKey, key) {
+			return values
+		}
+	}
+	for attributeKey, value := range feature.Attributes {
+		if strings.EqualFold(attributeKey, key) {
+			return []string{value}
+		}
+	}
+	return nil
+}
+
+// Product returns the /product qualifier of a feature, or an empty string if it doesn't have one.
+func (feature Feature) Product() string {
+	product, _ := feature.Qualifier("product")
+	return product
+}
+
+// GetSequence returns the sequence of a feature. Records that are built from a
+// CONTIG join don't have a sequence of their own, so getting the sequence of
+// their features returns an error.
 func (feature Feature) GetSequence() (string, error) {
+	if feature.ParentSequence == nil {
+		return "", fmt.Errorf("%s feature has no parent sequence", feature.Type)
+	}
+	if feature.ParentSequence.Sequence == "" && len(feature.ParentSequence.Meta.Contig) > 0 {
+		return "", fmt.Errorf("record %s is assembled from a CONTIG join and has no sequence of its own, fetch the sequences of its contigs separately", feature.ParentSequence.Meta.Locus.Name)
+	}
 	return getFeatureSequence(feature, feature.Location)
 }
 
@@ -124,7 +233,11 @@ func getFeatureSequence(feature Feature, location Location) (string, error) {
 	parentSequence := feature.ParentSequence.Sequence
 
 	if len(location.SubLocations) == 0 {
-		sequenceBuffer.WriteString(parentSequence[location.Start:location.End])
+		sequence, err := slice.Sequence(parentSequence, location.Start, location.End, feature.ParentSequence.Meta.Locus.Circular)
+		if err != nil {
+			return "", err
+		}
+		sequenceBuffer.WriteString(sequence)
 	} else {
 
 		for _, subLocation := range location.SubLocations {
@@ -146,11 +259,39 @@ func getFeatureSequence(feature Feature, location Location) (string, error) {
 	return sequenceString, nil
 }
 
+// ErrInvalidLocation is wrapped by errors about feature and CONTIG locations that can't be parsed.
+var ErrInvalidLocation = errors.New("invalid location")
+
+// ParseError is returned when a Genbank file can't be parsed. Use errors.As to
+// get the line number and errors.Is with ErrInvalidLocation or
+// io.ErrUnexpectedEOF (for files that end part of the way through a feature)
+// to check its cause.
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("genbank: line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
 // Parse takes in a string representing a gbk/gb/genbank file and parses it into an Sequence object.
+// Files holding several records only have their first record parsed, so use
+// ParseMulti to get all of them.
 func Parse(file []byte) (Genbank, error) {
 
 	gbk := string(file)
 	lines := strings.Split(gbk, "\n")
+	for index, line := range lines {
+		if strings.TrimSpace(line) == "//" {
+			lines = lines[:index]
+			break
+		}
+	}
 
 	// Create meta struct
 	meta := Meta{}
@@ -198,7 +339,17 @@ func Parse(file []byte) (Genbank, error) {
 			meta.References = append(meta.References, getReference(splitLine, subLines))
 			continue
 		case "FEATURES":
-			features = getFeatures(subLines)
+			var err error
+			if features, err = getFeatures(subLines, numLine+2); err != nil {
+				return Genbank{}, err
+			}
+		case "CONTIG":
+			meta.Other["CONTIG"] = joinSubLines(splitLine, subLines)
+			contig, err := parseContig(meta.Other["CONTIG"])
+			if err != nil {
+				return Genbank{}, &ParseError{numLine + 1, err}
+			}
+			meta.Contig = contig
 		case "ORIGIN":
 			sequence.Sequence = getSequence(subLines)
 			sequenceBreakFlag = true
@@ -219,7 +370,6 @@ func Parse(file []byte) (Genbank, error) {
 		_ = sequence.AddFeature(&feature)
 
 	}
-
 	return sequence, nil
 }
 
@@ -227,20 +377,9 @@ func Parse(file []byte) (Genbank, error) {
 func Build(sequence Genbank) ([]byte, error) {
 	var gbkString bytes.Buffer
 	locus := sequence.Meta.Locus
-	var shape string
-
-	if locus.Circular {
-		shape = "circular"
-	} else if locus.Linear {
-		shape = "linear"
-	}
-
-	fivespace := generateWhiteSpace(subMetaIndex)
 
 	// building locus
-	locusData := locus.Name + fivespace + locus.SequenceLength + " bp" + fivespace + locus.MoleculeType + fivespace + shape + fivespace + locus.GenbankDivision + fivespace + locus.ModificationDate
-	locusString := "LOCUS       " + locusData + "\n"
-	gbkString.WriteString(locusString)
+	gbkString.WriteString(buildLocus(locus, sequence.Sequence))
 
 	// building other standard meta features
 	definitionString := buildMetaString("DEFINITION", sequence.Meta.Definition)
@@ -304,6 +443,9 @@ func Build(sequence Genbank) ([]byte, error) {
 	// start writing features section.
 	gbkString.WriteString("FEATURES             Location/Qualifiers\n")
 	for _, feature := range sequence.Features {
+		if locus.Circular && feature.Location.GbkLocationString == "" {
+			feature.Location = wrapOriginSpanning(feature.Location, len(sequence.Sequence))
+		}
 		gbkString.WriteString(BuildFeatureString(feature))
 	}
 
@@ -339,19 +481,83 @@ func Build(sequence Genbank) ([]byte, error) {
 	return gbkString.Bytes(), nil
 }
 
-// Read reads a Gbk from path and parses into an Annotated sequence struct.
-func Read(path string) (Genbank, error) {
-	file, err := ioutil.ReadFile(path)
-	if err != nil {
-		return Genbank{}, err
+// buildLocus builds the LOCUS line of a record, laying its fields out in the
+// columns NCBI uses so that tools that read the line by column can parse it:
+//
+//	LOCUS       CP004084                5386 bp    DNA     circular PHG 04-MAR-2015
+//
+// Names too long for their column push the rest of the line to the right.
+// The sequence length is taken from the sequence itself when there is one so
+// that it stays correct after the sequence has been edited.
+func buildLocus(locus Locus, sequence string) string {
+	sequenceLength := locus.SequenceLength
+	if sequence != "" || sequenceLength == "" {
+		sequenceLength = strconv.Itoa(len(sequence))
+	}
+	sequenceCoding := locus.SequenceCoding
+	if sequenceCoding == "" {
+		sequenceCoding = "bp"
+	}
+
+	// the name and length share 28 columns with at least one space between them.
+	padding := 28 - len(locus.Name) - len(sequenceLength)
+	if padding < 1 {
+		padding = 1
+	}
+	nameAndLength := locus.Name + generateWhiteSpace(padding) + sequenceLength
+
+	// strandedness prefixes like "ds-" go in the three columns before the molecule type.
+	moleculeType := locus.MoleculeType
+	if len(moleculeType) < 3 || moleculeType[2] != '-' {
+		moleculeType = "   " + moleculeType
+	}
+
+	var topology string
+	if locus.Circular {
+		topology = "circular"
+	} else if locus.Linear {
+		topology = "linear"
 	}
 
-	sequence, err := Parse(file)
+	locusString := "LOCUS       " + nameAndLength + " " + sequenceCoding + " " + padRight(moleculeType, 9) + "  " + padRight(topology, 8) + " " + padRight(locus.GenbankDivision, 3) + " " + locus.ModificationDate
+	return strings.TrimRight(locusString, " ") + "\n"
+}
+
+// padRight pads a string with spaces up to length. Longer strings are left as they are.
+func padRight(text string, length int) string {
+	if len(text) >= length {
+		return text
+	}
+	return text + generateWhiteSpace(length-len(text))
+}
+
+// wrapOriginSpanning rewrites locations that run past the end of a circular
+// sequence of the given length, like 2601..2786 on a 2686 bp plasmid, as the
+// 2601..100 GenBank files use for features that span the origin.
+func wrapOriginSpanning(location Location, length int) Location {
+	if len(location.SubLocations) > 0 {
+		subLocations := make([]Location, len(location.SubLocations))
+		for index, subLocation := range location.SubLocations {
+			subLocations[index] = wrapOriginSpanning(subLocation, length)
+		}
+		location.SubLocations = subLocations
+		return location
+	}
+	if length > 0 && location.End > length && location.End-length <= location.Start {
+		location.End -= length
+	}
+	return location
+}
+
+// Read reads a Gbk from path and parses into an Annotated sequence struct. Gzip and bzip2 compressed files are detected and decompressed automatically.
+// Like Parse, only the first record of files holding several records is returned. Use ReadMulti to get all of them.
+func Read(path string) (Genbank, error) {
+	file, err := compress.ReadFile(path)
 	if err != nil {
 		return Genbank{}, err
 	}
 
-	return sequence, nil
+	return Parse(file)
 }
 
 // Write takes an Sequence struct and a path string and writes out a gff to that path.
@@ -384,21 +590,8 @@ var genbankDivisions = []string{
 	"HTG", //HTG sequences (high-throughput genomic sequences)
 	"HTC", //unfinished high-throughput cDNA sequencing
 	"ENV", //environmental sampling sequences
-}
-
-var genBankMoleculeTypes = []string{
-	"DNA",
-	"genomic DNA",
-	"genomic RNA",
-	"mRNA",
-	"tRNA",
-	"rRNA",
-	"other RNA",
-	"other DNA",
-	"transcribed RNA",
-	"viral cRNA",
-	"unassigned DNA",
-	"unassigned RNA",
+	"CON", //constructed sequences, assembled from other records with a CONTIG join
+	"TSA", //transcriptome shotgun assembly sequences
 }
 
 // used in feature check functions.
@@ -489,70 +682,66 @@ func topLevelFeatureCheck(featureString string) bool {
 	return flag
 }
 
-// parses locus from provided string.
+// parses locus from provided string. Fields are found by what they look like
+// rather than by column since many tools don't line the LOCUS line up the way
+// NCBI does, and older files leave out the topology and division.
 func parseLocus(locusString string) Locus {
 	locus := Locus{}
 
-	basePairRegex, _ := regexp.Compile(` \d* \w{2} `)
-	circularRegex, _ := regexp.Compile(` circular `)
-	linearRegex, _ := regexp.Compile(` linear `)
-
-	ModificationDateRegex, _ := regexp.Compile(`\d{2}-[A-Z]{3}-\d{4}`)
+	modificationDateRegex, _ := regexp.Compile(`^\d{2}-[A-Z]{3}-\d{4}$`)
 
-	locusSplit := strings.Split(strings.TrimSpace(locusString), " ")
-
-	var filteredLocusSplit []string
-	for i := range locusSplit {
-		if locusSplit[i] != "" {
-			filteredLocusSplit = append(filteredLocusSplit, locusSplit[i])
-		}
+	fields := strings.Fields(locusString)
+	if len(fields) < 2 {
+		return locus
 	}
-
-	locus.Name = filteredLocusSplit[1]
-
-	// sequence length and coding
-	baseSequenceLength := string(basePairRegex.FindString(locusString))
-	if baseSequenceLength != "" {
-		splitBaseSequenceLength := strings.Split(strings.TrimSpace(baseSequenceLength), " ")
-		if len(splitBaseSequenceLength) == 2 {
-			locus.SequenceLength = splitBaseSequenceLength[0]
-			locus.SequenceCoding = splitBaseSequenceLength[1]
-		}
-	}
-
-	// molecule type
-	for _, moleculeType := range genBankMoleculeTypes {
-		moleculeRegex, _ := regexp.Compile(moleculeType)
-		match := string(moleculeRegex.Find([]byte(locusString)))
-		if match != "" {
-			locus.MoleculeType = match
-			break
+	locus.Name = fields[1]
+
+	var moleculeType []string
+	for index := 2; index < len(fields); index++ {
+		field := fields[index]
+		switch {
+		// sequence length and coding
+		case locus.SequenceLength == "" && index+1 < len(fields) && (fields[index+1] == "bp" || fields[index+1] == "aa") && isNumber(field):
+			locus.SequenceLength = field
+			locus.SequenceCoding = fields[index+1]
+			index++
+		// circularity flag
+		case field == "circular":
+			locus.Circular = true
+		case field == "linear":
+			locus.Linear = true
+		case locus.GenbankDivision == "" && isGenbankDivision(field):
+			locus.GenbankDivision = field
+		case modificationDateRegex.MatchString(field):
+			locus.ModificationDate = field
+		// whatever is left is the molecule type, like "DNA", "ds-DNA" or "mRNA".
+		default:
+			moleculeType = append(moleculeType, field)
 		}
 	}
+	locus.MoleculeType = strings.Join(moleculeType, " ")
 
-	// circularity flag
-	if circularRegex.Match([]byte(locusString)) {
-		locus.Circular = true
-	}
+	return locus
+}
 
-	if linearRegex.Match([]byte(locusString)) {
-		locus.Linear = true
+// isNumber checks if a string is made up of only digits.
+func isNumber(text string) bool {
+	for _, character := range text {
+		if character < '0' || character > '9' {
+			return false
+		}
 	}
+	return text != ""
+}
 
-	// genbank division
+// isGenbankDivision checks if a string is one of the three letter genbank division codes.
+func isGenbankDivision(text string) bool {
 	for _, genbankDivision := range genbankDivisions {
-		genbankDivisionRegex, _ := regexp.Compile(genbankDivision)
-		match := string(genbankDivisionRegex.Find([]byte(locusString)))
-		if match != "" {
-			locus.GenbankDivision = match
-			break
+		if text == genbankDivision {
+			return true
 		}
 	}
-
-	// ModificationDate
-	locus.ModificationDate = ModificationDateRegex.FindString(locusString)
-
-	return locus
+	return false
 }
 
 // really important helper function. It finds sublines of a feature and joins them.
@@ -622,7 +811,9 @@ func getReference(splitLine, subLines []string) Reference {
 	return reference
 }
 
-func getFeatures(lines []string) []Feature {
+// getFeatures parses the features of a FEATURES section. firstLineNumber is the
+// line number of lines[0] in the file, used to report where errors happened.
+func getFeatures(lines []string, firstLineNumber int) ([]Feature, error) {
 	lineIndex := 0
 	features := []Feature{}
 
@@ -653,23 +844,29 @@ func getFeatures(lines []string) []Feature {
 		nextLineNum := 0
 		for {
 			nextLineNum++
+			if lineIndex+nextLineNum >= len(lines) {
+				return features, &ParseError{firstLineNumber + lineIndex, io.ErrUnexpectedEOF}
+			}
 			nextLine := lines[lineIndex+nextLineNum]
-			// Check if the next line is not a qualifier, it is part of the
-			// GbkLocation String
-			if !strings.Contains(nextLine, "/") {
-				feature.Location.GbkLocationString = feature.Location.GbkLocationString + strings.TrimSpace(nextLine)
-			} else {
+			// The location string carries on until the first qualifier or, for
+			// features without qualifiers, the next feature or top level keyword.
+			if strings.Contains(nextLine, "/") || quickMetaCheck(nextLine) || (len(nextLine) > subMetaIndex && quickFeatureCheck(nextLine)) {
 				break
 			}
+			feature.Location.GbkLocationString = feature.Location.GbkLocationString + strings.TrimSpace(nextLine)
 		}
-		feature.Location = parseLocation(feature.Location.GbkLocationString)
+		location, err := parseLocation(feature.Location.GbkLocationString)
+		if err != nil {
+			return features, &ParseError{firstLineNumber + lineIndex, err}
+		}
+		feature.Location = location
 
 		// initialize attributes.
 		feature.Attributes = make(map[string]string)
 
-		// end of feature declaration line. Bump to next line and begin looking for qualifiers.
-		lineIndex++
-		line = lines[lineIndex+nextLineNum-1]
+		// end of feature declaration lines. Bump to next line and begin looking for qualifiers.
+		lineIndex += nextLineNum
+		line = lines[lineIndex]
 
 		// loop through potential qualifiers. Break if not a qualifier or sub line.
 		// Definition of qualifiers here: http://www.insdc.org/files/feature_table.html#3.3
@@ -685,6 +882,9 @@ func getFeatures(lines []string) []Feature {
 
 			// end of qualifier declaration line. Bump to next line and begin looking for qualifier sublines.
 			lineIndex++
+			if lineIndex >= len(lines) {
+				return features, &ParseError{firstLineNumber + lineIndex, io.ErrUnexpectedEOF}
+			}
 			line = lines[lineIndex]
 
 			// loop through any potential continuing lines of qualifiers. Break if not.
@@ -703,6 +903,9 @@ func getFeatures(lines []string) []Feature {
 
 				// nextline
 				lineIndex++
+				if lineIndex >= len(lines) {
+					return features, &ParseError{firstLineNumber + lineIndex, io.ErrUnexpectedEOF}
+				}
 				line = lines[lineIndex]
 			}
 			//add qualifier to feature.
@@ -716,6 +919,15 @@ func getFeatures(lines []string) []Feature {
 			} else {
 				attributeValue = strings.TrimSpace(attributeSplit[1])
 			}
+			if previousValue, ok := feature.Attributes[attributeLabel]; ok {
+				if feature.RepeatedAttributes == nil {
+					feature.RepeatedAttributes = make(map[string][]string)
+				}
+				if _, ok := feature.RepeatedAttributes[attributeLabel]; !ok {
+					feature.RepeatedAttributes[attributeLabel] = []string{previousValue}
+				}
+				feature.RepeatedAttributes[attributeLabel] = append(feature.RepeatedAttributes[attributeLabel], attributeValue)
+			}
 			feature.Attributes[attributeLabel] = attributeValue
 		}
 
@@ -723,7 +935,7 @@ func getFeatures(lines []string) []Feature {
 		features = append(features, feature)
 
 	}
-	return features
+	return features, nil
 }
 
 // takes every line after origin feature and removes anything that isn't in the alphabet. Returns sequence string.
@@ -740,51 +952,63 @@ func getSequence(subLines []string) string {
 	return sequence
 }
 
-func parseLocation(locationString string) Location {
+func parseLocation(locationString string) (Location, error) {
 	var location Location
 	location.GbkLocationString = locationString
 	if !(strings.ContainsAny(locationString, "(")) { // Case checks for simple expression of x..x
-		if !(strings.ContainsAny(locationString, ".")) { //Case checks for simple expression x
-			position, _ := strconv.Atoi(locationString)
+		if before, after, ok := strings.Cut(locationString, "^"); ok { // Case checks for a site between two bases x^x
+			position, beforeErr := strconv.Atoi(before)
+			_, afterErr := strconv.Atoi(after)
+			if beforeErr != nil || afterErr != nil {
+				return Location{}, fmt.Errorf("%w %q", ErrInvalidLocation, locationString)
+			}
 			location = Location{Start: position, End: position}
+		} else if !(strings.ContainsAny(locationString, ".")) { //Case checks for simple expression x
+			position, err := strconv.Atoi(strings.Trim(locationString, "<>"))
+			if err != nil {
+				return Location{}, fmt.Errorf("%w %q", ErrInvalidLocation, locationString)
+			}
+			location = Location{Start: position - 1, End: position}
 		} else {
 			// to remove FivePrimePartial and ThreePrimePartial indicators from start and end before converting to int.
 			partialRegex, _ := regexp.Compile("<|>")
 			startEndSplit := strings.Split(locationString, "..")
-			start, _ := strconv.Atoi(partialRegex.ReplaceAllString(startEndSplit[0], ""))
-			end, _ := strconv.Atoi(partialRegex.ReplaceAllString(startEndSplit[1], ""))
+			if len(startEndSplit) != 2 {
+				return Location{}, fmt.Errorf("%w %q", ErrInvalidLocation, locationString)
+			}
+			start, startErr := strconv.Atoi(partialRegex.ReplaceAllString(startEndSplit[0], ""))
+			end, endErr := strconv.Atoi(partialRegex.ReplaceAllString(startEndSplit[1], ""))
+			if startErr != nil || endErr != nil {
+				return Location{}, fmt.Errorf("%w %q", ErrInvalidLocation, locationString)
+			}
 			location = Location{Start: start - 1, End: end}
 		}
 
 	} else {
 		firstOuterParentheses := strings.Index(locationString, "(")
-		expression := locationString[firstOuterParentheses+1 : strings.LastIndex(locationString, ")")]
+		lastOuterParentheses := strings.LastIndex(locationString, ")")
+		if lastOuterParentheses < firstOuterParentheses {
+			return Location{}, fmt.Errorf("%w %q", ErrInvalidLocation, locationString)
+		}
+		expression := locationString[firstOuterParentheses+1 : lastOuterParentheses]
 		switch command := locationString[0:firstOuterParentheses]; command {
 		case "join":
 			location.Join = true
-			// This case checks for join(complement(x..x),complement(x..x)), or any more complicated derivatives
-			if strings.ContainsAny(expression, "(") {
-				firstInnerParentheses := strings.Index(expression, "(")
-				ParenthesesCount := 1
-				comma := 0
-				for i := 1; ParenthesesCount > 0; i++ { // "(" is at 0, so we start at 1
-					comma = i
-					switch expression[firstInnerParentheses+i] {
-					case []byte("(")[0]:
-						ParenthesesCount++
-					case []byte(")")[0]:
-						ParenthesesCount--
-					}
-				}
-				location.SubLocations = append(location.SubLocations, parseLocation(expression[:firstInnerParentheses+comma+1]), parseLocation(expression[2+firstInnerParentheses+comma:]))
-			} else { // This is the default join(x..x,x..x)
-				for _, numberRange := range strings.Split(expression, ",") {
-					location.SubLocations = append(location.SubLocations, parseLocation(numberRange))
+			// Sub locations can themselves be nested expressions like complement(join(x..x,x..x)) so we
+			// only split on commas that aren't inside of parentheses.
+			for _, subLocationString := range splitLocationExpression(expression) {
+				subLocation, err := parseLocation(subLocationString)
+				if err != nil {
+					return Location{}, err
 				}
+				location.SubLocations = append(location.SubLocations, subLocation)
 			}
 
 		case "complement":
-			subLocation := parseLocation(expression)
+			subLocation, err := parseLocation(expression)
+			if err != nil {
+				return Location{}, err
+			}
 			subLocation.Complement = true
 			location.SubLocations = append(location.SubLocations, subLocation)
 		}
@@ -799,11 +1023,67 @@ func parseLocation(locationString string) Location {
 	}
 
 	// if excess root node then trim node. Maybe should just be handled with second arg?
-	if location.Start == 0 && location.End == 0 && !location.Join && !location.Complement {
+	if location.Start == 0 && location.End == 0 && !location.Join && !location.Complement && len(location.SubLocations) > 0 {
 		location = location.SubLocations[0]
 	}
 
-	return location
+	return location, nil
+}
+
+// parseContig parses the join expression of a CONTIG line, such as
+// join(NC_000913.3:1..1000,gap(100),complement(NC_000913.3:2001..3000)).
+func parseContig(contigString string) ([]ContigLocation, error) {
+	expression := strings.Join(strings.Fields(contigString), "")
+	if strings.HasPrefix(expression, "join(") && strings.HasSuffix(expression, ")") {
+		expression = expression[len("join(") : len(expression)-1]
+	}
+
+	var contig []ContigLocation
+	for _, piece := range splitLocationExpression(expression) {
+		if strings.HasPrefix(piece, "gap(") {
+			gapLength, _ := strconv.Atoi(strings.TrimPrefix(strings.TrimSuffix(strings.TrimPrefix(piece, "gap("), ")"), "unk"))
+			contig = append(contig, ContigLocation{Gap: true, GapLength: gapLength})
+			continue
+		}
+		complement := strings.HasPrefix(piece, "complement(")
+		if complement {
+			piece = strings.TrimSuffix(strings.TrimPrefix(piece, "complement("), ")")
+		}
+		var contigLocation ContigLocation
+		if colon := strings.Index(piece, ":"); colon != -1 {
+			contigLocation.Accession, piece = piece[:colon], piece[colon+1:]
+		}
+		location, err := parseLocation(piece)
+		if err != nil {
+			return nil, err
+		}
+		contigLocation.Location = location
+		contigLocation.Location.Complement = complement
+		contig = append(contig, contigLocation)
+	}
+	return contig, nil
+}
+
+// splitLocationExpression splits the inner expression of a join into its top level
+// sub location strings, leaving anything nested inside of parentheses intact.
+func splitLocationExpression(expression string) []string {
+	var subLocationStrings []string
+	parenthesesCount := 0
+	start := 0
+	for index, character := range expression {
+		switch character {
+		case '(':
+			parenthesesCount++
+		case ')':
+			parenthesesCount--
+		case ',':
+			if parenthesesCount == 0 {
+				subLocationStrings = append(subLocationStrings, expression[start:index])
+				start = index + 1
+			}
+		}
+	}
+	return append(subLocationStrings, expression[start:])
 }
 
 // buildMetaString is a helper function to build the meta section of genbank files.
@@ -845,14 +1125,15 @@ func BuildLocationString(location Location) string {
 		locationString = strings.TrimSuffix(locationString, ",") + ")"
 	} else {
 
-		locationString = strconv.Itoa(location.Start+1) + ".." + strconv.Itoa(location.End)
+		startString, endString := strconv.Itoa(location.Start+1), strconv.Itoa(location.End)
 		if location.FivePrimePartial {
-			locationString = "<" + locationString
+			startString = "<" + startString
 		}
 
 		if location.ThreePrimePartial {
-			locationString += ">"
+			endString = ">" + endString
 		}
+		locationString = startString + ".." + endString
 	}
 	return locationString
 }
@@ -877,8 +1158,13 @@ func BuildFeatureString(feature Feature) string {
 	}
 
 	for _, qualifier := range qualifierKeys {
-		returnString += generateWhiteSpace(qualifierIndex) + "/" + qualifier + "=\"" + feature.Attributes[qualifier] + "\"\n"
-
+		values, repeated := feature.RepeatedAttributes[qualifier]
+		if !repeated {
+			values = []string{feature.Attributes[qualifier]}
+		}
+		for _, value := range values {
+			returnString += generateWhiteSpace(qualifierIndex) + "/" + qualifier + "=\"" + value + "\"\n"
+		}
 	}
 	return returnString
 }
@@ -905,55 +1191,75 @@ Genbank Flat specific IO related things begin here.
 
 ******************************************************************************/
 
-// ParseMulti parses multiple Genbank files in a byte array to multiple sequences
-func ParseMulti(file []byte) []Genbank {
-	r := bytes.NewReader(file)
-	sequences := make(chan Genbank)
-	go ParseConcurrent(r, sequences)
-
+// ParseMulti parses multiple Genbank files in a byte array to multiple sequences.
+// The first record that can't be parsed stops parsing and its error is returned
+// along with the records parsed before it.
+func ParseMulti(file []byte) ([]Genbank, error) {
 	var outputGenbanks []Genbank
-	for sequence := range sequences {
+	err := parseRecords(bytes.NewReader(file), func(sequence Genbank) {
 		outputGenbanks = append(outputGenbanks, sequence)
-	}
-	return outputGenbanks
+	})
+	return outputGenbanks, err
 }
 
 // ParseFlat specifically takes the output of a Genbank Flat file that from
 // the genbank ftp dumps. These files have 10 line headers, which are entirely
 // removed
-func ParseFlat(file []byte) []Genbank {
-	r := bytes.NewReader(file)
-	sequences := make(chan Genbank)
-	go ParseFlatConcurrent(r, sequences)
+func ParseFlat(file []byte) ([]Genbank, error) {
+	parser, err := NewFlatParser(bytes.NewReader(file))
+	if err != nil {
+		return nil, err
+	}
 	var outputGenbanks []Genbank
-	for sequence := range sequences {
-		outputGenbanks = append(outputGenbanks, sequence)
+	for {
+		gbk, err := parser.Next()
+		if err == io.EOF {
+			return outputGenbanks, nil
+		}
+		if err != nil {
+			return outputGenbanks, err
+		}
+		outputGenbanks = append(outputGenbanks, gbk)
+	}
+}
+
+// ReadMulti reads multiple genbank files from a single file. Gzip and bzip2 compressed files are detected and decompressed automatically.
+func ReadMulti(path string) ([]Genbank, error) {
+	file, err := compress.ReadFile(path)
+	if err != nil {
+		return nil, err
 	}
-	return outputGenbanks
+	return ParseMulti(file)
 }
 
-// ReadMulti reads multiple genbank files from a single file
-func ReadMulti(path string) []Genbank {
-	file, _ := ioutil.ReadFile(path)
-	sequences := ParseMulti(file)
-	return sequences
+// ReadFlat reads flat genbank files, like the ones provided by the NCBI FTP server. Compressed files are decompressed automatically.
+func ReadFlat(path string) ([]Genbank, error) {
+	file, err := compress.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFlat(file)
 }
 
-// ReadFlat reads flat genbank files, like the ones provided by the NCBI FTP server (after decompression)
-func ReadFlat(path string) []Genbank {
-	file, _ := ioutil.ReadFile(path)
-	sequences := ParseFlat(file)
-	return sequences
+// ReadFlatGz reads flat gzip'd genbank files, like the ones provided by the NCBI FTP server.
+//
+// Deprecated: ReadFlat detects and decompresses gzip'd files itself, so use ReadFlat instead.
+func ReadFlatGz(path string) ([]Genbank, error) {
+	return ReadFlat(path)
 }
 
-// ReadFlatGz reads flat gzip'd genbank files, like the ones provided by the NCBI FTP server
-func ReadFlatGz(path string) []Genbank {
-	file, _ := ioutil.ReadFile(path)
-	rdata := bytes.NewReader(file)
-	r, _ := gzip.NewReader(rdata)
-	s, _ := ioutil.ReadAll(r)
-	sequences := ParseFlat(s)
-	return sequences
+// skipFlatHeader reads past the 10 line header of a flat file.
+// Header data is not needed to parse the Genbank files, though it may contain useful information.
+func skipFlatHeader(reader *bufio.Reader) error {
+	for i := 0; i < 10; i++ {
+		if _, err := reader.ReadString('\n'); err != nil {
+			if err == io.EOF {
+				return &ParseError{i + 1, io.ErrUnexpectedEOF}
+			}
+			return err
+		}
+	}
+	return nil
 }
 
 /******************************************************************************
@@ -969,26 +1275,11 @@ Genbank Concurrent specific IO related things begin here.
 ******************************************************************************/
 
 // ParseConcurrent concurrently parses a given multi-Genbank file in an io.Reader into a channel of Genbank.
+// Parsing stops at the first record that can't be parsed. Use ParseMulti to find out why.
 func ParseConcurrent(r io.Reader, sequences chan<- Genbank) {
-	var gbkStr string
-	var gbk Genbank
-
-	// Start a new scanner
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "//" {
-			gbkStr = gbkStr + "//"
-			// Parse the genbank string and send it to the channel
-			gbk, _ = Parse([]byte(gbkStr)) // TODO: Ask Keoni how to handle this error
-			sequences <- gbk
-			// Reset the genbank string
-			gbkStr = ""
-		} else {
-			// Append new lines of the Genbank file to a growing string
-			gbkStr = gbkStr + line + "\n"
-		}
-	}
+	_ = parseRecords(r, func(sequence Genbank) {
+		sequences <- sequence
+	})
 	close(sequences)
 }
 
@@ -997,13 +1288,87 @@ func ParseFlatConcurrent(r io.Reader, sequences chan<- Genbank) {
 	// Start a new reader
 	reader := bufio.NewReader(r)
 	// Read 10 lines, or the header of a flat file
-	// Header data is not needed to parse the Genbank files, though it may contain useful information.
-	for i := 0; i < 10; i++ {
-		_, _, _ = reader.ReadLine()
+	if err := skipFlatHeader(reader); err != nil {
+		close(sequences)
+		return
 	}
 	go ParseConcurrent(reader, sequences)
 }
 
+// parseRecords passes each record parsed from a multi-Genbank file to handle.
+func parseRecords(r io.Reader, handle func(Genbank)) error {
+	parser := NewParser(r)
+	for {
+		gbk, err := parser.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		handle(gbk)
+	}
+}
+
+// Parser reads Genbank records from a multi-Genbank io.Reader one at a time,
+// so that large files like the RefSeq division files can be worked through
+// without holding every record in memory.
+type Parser struct {
+	scanner    *bufio.Scanner
+	lineNumber int
+}
+
+// NewParser returns a Parser that reads Genbank records from r.
+func NewParser(r io.Reader) *Parser {
+	return &Parser{scanner: bufio.NewScanner(r)}
+}
+
+// NewFlatParser returns a Parser that reads Genbank records from a flat file,
+// like the ones provided by the NCBI FTP server, skipping the flat file header.
+func NewFlatParser(r io.Reader) (*Parser, error) {
+	reader := bufio.NewReader(r)
+	if err := skipFlatHeader(reader); err != nil {
+		return nil, err
+	}
+	parser := NewParser(reader)
+	parser.lineNumber = 10
+	return parser, nil
+}
+
+// Next returns the next Genbank record, splitting records on their "//" lines.
+// It returns io.EOF once every record has been read. ParseErrors give the line
+// number in the whole file rather than in the record.
+func (parser *Parser) Next() (Genbank, error) {
+	var gbkStr strings.Builder
+	recordStartLine := parser.lineNumber + 1
+
+	for parser.scanner.Scan() {
+		parser.lineNumber++
+		line := parser.scanner.Text()
+		if line != "//" {
+			// Append new lines of the Genbank file to a growing string
+			gbkStr.WriteString(line + "\n")
+			continue
+		}
+
+		gbkStr.WriteString("//")
+		// Parse the genbank string and hand it over
+		gbk, err := Parse([]byte(gbkStr.String()))
+		if err != nil {
+			var parseError *ParseError
+			if errors.As(err, &parseError) {
+				return Genbank{}, &ParseError{parseError.Line + recordStartLine - 1, parseError.Err}
+			}
+			return Genbank{}, err
+		}
+		return gbk, nil
+	}
+	if err := parser.scanner.Err(); err != nil {
+		return Genbank{}, err
+	}
+	return Genbank{}, io.EOF
+}
+
 /******************************************************************************
 
 Genbank Concurrent specific IO related things end here.
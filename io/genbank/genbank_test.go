@@ -1,7 +1,12 @@
 package genbank_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -9,6 +14,8 @@ import (
 	"testing"
 
 	"github.com/TimothyStiles/poly/io/genbank"
+	"github.com/TimothyStiles/poly/synthesis/codon"
+	"github.com/TimothyStiles/poly/transform"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 )
@@ -88,6 +95,180 @@ func TestGbkIO(t *testing.T) {
 	}
 }
 
+func TestReadGzipped(t *testing.T) {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	// gzip the test file so we can check Read decompresses it transparently.
+	file, _ := ioutil.ReadFile("../../data/puc19.gbk")
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, _ = gzipWriter.Write(file)
+	_ = gzipWriter.Close()
+	tmpGzipFilePath := filepath.Join(tmpDataDir, "puc19.gbk.gz")
+	_ = ioutil.WriteFile(tmpGzipFilePath, gzipped.Bytes(), 0644)
+
+	expected, _ := genbank.Read("../../data/puc19.gbk")
+	got, err := genbank.Read(tmpGzipFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, got, []cmp.Option{cmpopts.IgnoreFields(genbank.Feature{}, "ParentSequence")}...); diff != "" {
+		t.Errorf("Reading a gzipped file does not give the same result as the plain file. Got this diff:\n%s", diff)
+	}
+}
+
+func TestReadBzip2(t *testing.T) {
+	expected, _ := genbank.Read("../../data/puc19.gbk")
+	got, err := genbank.Read("../../data/puc19.gbk.bz2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, got, []cmp.Option{cmpopts.IgnoreFields(genbank.Feature{}, "ParentSequence")}...); diff != "" {
+		t.Errorf("Reading a bzip2 compressed file does not give the same result as the plain file. Got this diff:\n%s", diff)
+	}
+
+	// zstd can't be decompressed with the standard library so it should error rather than be parsed as garbage.
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+	zstdPath := filepath.Join(tmpDataDir, "puc19.gbk.zst")
+	_ = ioutil.WriteFile(zstdPath, []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}, 0644)
+	if _, err := genbank.Read(zstdPath); err == nil {
+		t.Error("expected an error reading a zstd compressed file")
+	}
+}
+
+func TestOriginSpanningFeature(t *testing.T) {
+	puc19, _ := genbank.Read("../../data/puc19.gbk")
+	if !puc19.Meta.Locus.Circular {
+		t.Fatalf("puc19 should be parsed as circular")
+	}
+
+	// a feature written as 2601..100 wraps around the origin.
+	feature := genbank.Feature{Type: "misc_feature", Location: genbank.Location{Start: 2600, End: 100}}
+	_ = puc19.AddFeature(&feature)
+	featureSequence, err := feature.GetSequence()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := puc19.Sequence[2600:] + puc19.Sequence[:100]; featureSequence != expected {
+		t.Errorf("expected %q, got %q", expected, featureSequence)
+	}
+
+	// the location should survive a round trip through the location parser.
+	if location := genbank.BuildLocationString(feature.Location); location != "2601..100" {
+		t.Errorf("expected location string 2601..100, got %q", location)
+	}
+
+	// NCBI writes features that wrap around the origin as a join of the pieces either side of it.
+	// Neither it nor the feature added above have qualifiers, which shouldn't stop them being parsed.
+	puc19Bytes, _ := genbank.Build(puc19)
+	joined := strings.Replace(string(puc19Bytes), "FEATURES             Location/Qualifiers\n", "FEATURES             Location/Qualifiers\n     CDS             complement(join(2601..2686,1..100))\n", 1)
+	joinedSequence, err := genbank.Parse([]byte(joined))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(joinedSequence.Features) != len(puc19.Features)+1 {
+		t.Fatalf("expected %d features, got %d", len(puc19.Features)+1, len(joinedSequence.Features))
+	}
+	featureSequence, err = joinedSequence.Features[0].GetSequence()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := transform.ReverseComplement(puc19.Sequence[2600:] + puc19.Sequence[:100]); featureSequence != expected {
+		t.Errorf("expected %q, got %q", expected, featureSequence)
+	}
+
+	if _, err := (genbank.Feature{Type: "CDS"}).GetSequence(); err == nil {
+		t.Errorf("expected an error getting the sequence of a feature without a parent sequence")
+	}
+
+	// linear sequences can't have features wrap around.
+	puc19.Meta.Locus.Circular = false
+	if _, err := puc19.Features[len(puc19.Features)-1].GetSequence(); err == nil {
+		t.Errorf("expected an error for a wrapping feature on a linear sequence")
+	}
+}
+
+func TestLocus(t *testing.T) {
+	tests := []struct {
+		line  string
+		locus genbank.Locus
+	}{
+		{"LOCUS       CP004084                5386 bp    DNA     circular PHG 04-MAR-2015", genbank.Locus{Name: "CP004084", SequenceLength: "5386", SequenceCoding: "bp", MoleculeType: "DNA", Circular: true, GenbankDivision: "PHG", ModificationDate: "04-MAR-2015"}},
+		{"LOCUS       Exported                2686 bp ds-DNA     circular SYN 22-OCT-2019", genbank.Locus{Name: "Exported", SequenceLength: "2686", SequenceCoding: "bp", MoleculeType: "ds-DNA", Circular: true, GenbankDivision: "SYN", ModificationDate: "22-OCT-2019"}},
+		{"LOCUS       NM_000546               2512 bp    mRNA    linear   PRI 27-MAR-2022", genbank.Locus{Name: "NM_000546", SequenceLength: "2512", SequenceCoding: "bp", MoleculeType: "mRNA", Linear: true, GenbankDivision: "PRI", ModificationDate: "27-MAR-2022"}},
+		{"LOCUS       NC_000913            4641652 bp    DNA     circular CON 09-MAR-2022", genbank.Locus{Name: "NC_000913", SequenceLength: "4641652", SequenceCoding: "bp", MoleculeType: "DNA", Circular: true, GenbankDivision: "CON", ModificationDate: "09-MAR-2022"}},
+		{"LOCUS       P53_HUMAN                393 aa            linear   PRI 01-JUN-2022", genbank.Locus{Name: "P53_HUMAN", SequenceLength: "393", SequenceCoding: "aa", Linear: true, GenbankDivision: "PRI", ModificationDate: "01-JUN-2022"}},
+		// old files and some plasmid editors leave out the topology and division.
+		{"LOCUS       SCU49845     5028 bp    DNA             PLN       21-JUN-1999", genbank.Locus{Name: "SCU49845", SequenceLength: "5028", SequenceCoding: "bp", MoleculeType: "DNA", GenbankDivision: "PLN", ModificationDate: "21-JUN-1999"}},
+		{"LOCUS       Benchling-example-export               3411 bp ds-DNA     linear       28-JUL-2021", genbank.Locus{Name: "Benchling-example-export", SequenceLength: "3411", SequenceCoding: "bp", MoleculeType: "ds-DNA", Linear: true, ModificationDate: "28-JUL-2021"}},
+	}
+	for _, test := range tests {
+		sequence, err := genbank.Parse([]byte(test.line + "\nORIGIN\n//\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.locus, sequence.Meta.Locus); diff != "" {
+			t.Errorf("unexpected locus parsing %q (-want +got):\n%s", test.line, diff)
+		}
+	}
+
+	// the LOCUS line should be laid out in NCBI's columns with the length of the sequence being written.
+	locus := genbank.Locus{Name: "pTest", SequenceLength: "10", MoleculeType: "ds-DNA", Circular: true, GenbankDivision: "SYN", ModificationDate: "01-JAN-2022"}
+	built, _ := genbank.Build(genbank.Genbank{Meta: genbank.Meta{Locus: locus}, Sequence: "atgc"})
+	expected := "LOCUS       pTest                      4 bp ds-DNA     circular SYN 01-JAN-2022\n"
+	if !strings.HasPrefix(string(built), expected) {
+		t.Errorf("expected LOCUS line %q, got %q", expected, strings.SplitAfter(string(built), "\n")[0])
+	}
+	reparsed, _ := genbank.Parse(built)
+	locus.SequenceLength, locus.SequenceCoding = "4", "bp"
+	if diff := cmp.Diff(locus, reparsed.Meta.Locus); diff != "" {
+		t.Errorf("LOCUS line did not survive a round trip (-want +got):\n%s", diff)
+	}
+
+	// features that run past the end of a circular sequence are written wrapped around the origin.
+	feature := genbank.Feature{Type: "misc_feature", Location: genbank.Location{Start: 2, End: 6}}
+	built, _ = genbank.Build(genbank.Genbank{Meta: genbank.Meta{Locus: locus}, Features: []genbank.Feature{feature}, Sequence: "atgc"})
+	if !strings.Contains(string(built), "misc_feature    3..2\n") {
+		t.Errorf("expected an origin spanning location of 3..2, got:\n%s", built)
+	}
+}
+
+func TestRepeatedQualifiers(t *testing.T) {
+	sequence, _ := genbank.Read("../../data/bsub.gbk")
+	var dnaA genbank.Feature
+	for _, feature := range sequence.Features {
+		if feature.Type == "CDS" && feature.Attributes["gene"] == "dnaA" {
+			dnaA = feature
+		}
+	}
+
+	dbXrefs := dnaA.Qualifiers("DB_XREF")
+	if len(dbXrefs) < 2 {
+		t.Fatalf("expected repeated db_xref qualifiers, got %v", dbXrefs)
+	}
+	if first, ok := dnaA.Qualifier("/db_xref"); !ok || first != dbXrefs[0] {
+		t.Errorf("Qualifier should return the first value, got %q", first)
+	}
+	if _, ok := dnaA.Qualifier("not_a_qualifier"); ok {
+		t.Errorf("Qualifier should not find a missing qualifier")
+	}
+
+	// repeated qualifiers should survive a round trip.
+	built, _ := genbank.Build(genbank.Genbank{Meta: sequence.Meta, Features: []genbank.Feature{dnaA}, Sequence: sequence.Sequence[:2000]})
+	reparsed, _ := genbank.Parse(built)
+	if diff := cmp.Diff(dbXrefs, reparsed.Features[0].Qualifiers("db_xref")); diff != "" {
+		t.Errorf("repeated qualifiers did not survive a round trip. Got this diff:\n%s", diff)
+	}
+}
+
 func TestGbkLocationStringBuilder(t *testing.T) {
 	tmpDataDir, err := ioutil.TempDir("", "data-*")
 	if err != nil {
@@ -214,6 +395,147 @@ func TestLocationParser(t *testing.T) {
 	}
 }
 
+func TestNestedLocationGetSequence(t *testing.T) {
+	// GFP split into three exons, with the second exon sitting on the opposite strand. This mirrors the layout of
+	// trans-spliced genes like the chloroplast rps12 where a single join spans both strands of the genome.
+	gfpSequence := "ATGGCTAGCAAAGGAGAAGAACTTTTCACTGGAGTTGTCCCAATTCTTGTTGAATTAGATGGTGATGTTAATGGGCACAAATTTTCTGTCAGTGGAGAGGGTGAAGGTGATGCTACATACGGAAAGCTTACCCTTAAATTTATTTGCACTACTGGAAAACTACCTGTTCCATGGCCAACACTTGTCACTACTTTCTCTTATGGTGTTCAATGCTTTTCCCGTTATCCGGATCATATGAAACGGCATGACTTTTTCAAGAGTGCCATGCCCGAAGGTTATGTACAGGAACGCACTATATCTTTCAAAGATGACGGGAACTACAAGACGCGTGCTGAAGTCAAGTTTGAAGGTGATACCCTTGTTAATCGTATCGAGTTAAAAGGTATTGATTTTAAAGAAGATGGAAACATTCTCGGACACAAACTCGAGTACAACTATAACTCACACAATGTATACATCACGGCAGACAAACAAAAGAATGGAATCAAAGCTAACTTCAAAATTCGCCACAACATTGAAGATGGATCCGTTCAACTAGCAGACCATTATCAACAAAATACTCCAATTGGCGATGGCCCTGTCCTTTTACCAGACAACCATTACCTGTCGACACAATCTGCCCTTTCGAAAGATCCCAACGAAAAGCGTGACCACATGGTCCTTCTTGAGTTTGTAACTGCTGCTGGGATTACACATGGCATGGATGAGCTCTACAAATAA"
+	intron := strings.Repeat("GTAAGT", 5) + strings.Repeat("T", 14) + "CAG" + "AAA" // 50bp stand in for an intron.
+	exonOne, exonTwo, exonThree := gfpSequence[:240], gfpSequence[240:480], gfpSequence[480:]
+
+	// exon one: 1..240, intron: 241..290, exon two (reverse strand): 291..530, intron: 531..580, exon three: 581..820
+	genome := exonOne + intron + transform.ReverseComplement(exonTwo) + intron + exonThree
+
+	// the same spliced product expressed with the reverse strand exons nested at different depths.
+	locationStrings := []string{
+		"join(1..240,complement(291..530),581..820)",
+		"join(1..240,complement(join(291..410,411..530)),581..820)",
+		"join(join(1..120,121..240),complement(291..530),join(581..700,701..820))",
+		"join(1..240,join(complement(301..530),complement(join(291..300))),581..820)",
+	}
+
+	for _, locationString := range locationStrings {
+		sequence, _ := genbank.Read("../../data/puc19.gbk")
+		sequence.Sequence = genome
+		sequence.Features = nil
+		feature := genbank.Feature{Type: "CDS", Attributes: map[string]string{"gene": "gfp"}}
+		feature.Location.GbkLocationString = locationString
+		_ = sequence.AddFeature(&feature)
+
+		// round trip through Build and Parse so that the location string gets parsed.
+		gbkBytes, _ := genbank.Build(sequence)
+		parsedSequence, err := genbank.Parse(gbkBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		splicedSequence, err := parsedSequence.Features[0].GetSequence()
+		if err != nil {
+			t.Errorf("GetSequence returned an error for %s: %s", locationString, err)
+		}
+		if splicedSequence != gfpSequence {
+			t.Errorf("Nested location %s was not spliced correctly. Got this:\n%s instead of \n%s", locationString, splicedSequence, gfpSequence)
+		}
+	}
+
+	// a complement wrapping a whole join reverse complements the concatenated product.
+	sequence := genbank.Genbank{Sequence: "AAACCCGGGTTT"}
+	feature := genbank.Feature{Location: genbank.Location{Complement: true, Join: true, SubLocations: []genbank.Location{{Start: 0, End: 3}, {Start: 6, End: 9}}}}
+	_ = sequence.AddFeature(&feature)
+	complementedJoin, _ := feature.GetSequence()
+	if complementedJoin != "CCCTTT" {
+		t.Errorf("Expected CCCTTT for complement(join(1..3,7..9)), got %s", complementedJoin)
+	}
+
+	// locations that fall off of the end of the sequence should error instead of panicking.
+	outOfBounds := genbank.Feature{Location: genbank.Location{Start: 6, End: 20}}
+	_ = sequence.AddFeature(&outOfBounds)
+	if _, err := outOfBounds.GetSequence(); err == nil {
+		t.Errorf("Expected an error for an out of bounds location")
+	}
+}
+
+func TestSplicedCDSTranslations(t *testing.T) {
+	// real spliced CDSs, T4's intron interrupted td and the complemented joins of B. subtilis, should translate to
+	// the protein their records publish. This checks the splicing against sequences nobody made up for the test.
+	var spliced int
+	for _, path := range []string{"../../data/t4_intron.gb", "../../data/bsub.gbk"} {
+		sequence, err := genbank.Read(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, feature := range sequence.Features {
+			if feature.Type != "CDS" || len(feature.Location.SubLocations) == 0 {
+				continue
+			}
+			spliced++
+			location := genbank.BuildLocationString(feature.Location)
+			cds, err := feature.GetSequence()
+			if err != nil {
+				t.Errorf("%s: GetSequence returned an error for %s: %s", path, location, err)
+				continue
+			}
+			protein, err := codon.TranslateWithOptions(cds, codon.GetCodonTable(11), codon.TranslateOptions{StartCodon: true})
+			if err != nil {
+				t.Errorf("%s: failed to translate %s: %s", path, location, err)
+			}
+			// t4_intron.gb has windows line endings, which are kept along with the spaces of wrapped translations.
+			if expected := strings.Join(strings.Fields(feature.Attributes["translation"]), ""); strings.TrimSuffix(protein, "*") != expected {
+				t.Errorf("%s: %s was not spliced correctly. It translates to\n%s instead of \n%s", path, location, protein, expected)
+			}
+		}
+	}
+	if spliced != 4 {
+		t.Errorf("expected 4 spliced CDSs, got %d", spliced)
+	}
+}
+
+func TestEukaryoticSplicing(t *testing.T) {
+	pichia, err := genbank.Read("../../data/pichia_chr1_head.gb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mRNA, cds genbank.Feature
+	for _, feature := range pichia.Features {
+		switch {
+		case feature.Type == "mRNA" && feature.Location.Join:
+			mRNA = feature
+		case feature.Type == "CDS":
+			cds = feature
+		}
+	}
+
+	// PP7435_CHR1-0252's mRNA is spliced from four exons, partial at both ends.
+	expected := []genbank.Location{
+		{Start: 459259, End: 459456, FivePrimePartial: true},
+		{Start: 459555, End: 459637},
+		{Start: 459684, End: 459739},
+		{Start: 459809, End: 460126, ThreePrimePartial: true},
+	}
+	if diff := cmp.Diff(expected, mRNA.Location.SubLocations, cmpopts.IgnoreFields(genbank.Location{}, "GbkLocationString")); diff != "" {
+		t.Errorf("the exons of PP7435_CHR1-0252 were parsed wrong. Got this diff:\n%s", diff)
+	}
+	if location := genbank.BuildLocationString(mRNA.Location); location != "join(<459260..459456,459556..459637,459685..459739,459810..>460126)" {
+		t.Errorf("PP7435_CHR1-0252's location was built as %s", location)
+	}
+	// the fixture only keeps the first 7320 bases of the chromosome, so the exons can't be read from it.
+	if _, err := mRNA.GetSequence(); err == nil {
+		t.Errorf("expected an error getting exons past the end of the sequence")
+	}
+
+	// PP7435_CHR1-0001 is read on the standard nuclear code.
+	sequence, err := cds.GetSequence()
+	if err != nil {
+		t.Fatal(err)
+	}
+	protein, err := codon.TranslateWithOptions(sequence, codon.GetCodonTable(1), codon.TranslateOptions{StartCodon: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := strings.Join(strings.Fields(cds.Attributes["translation"]), ""); strings.TrimSuffix(protein, "*") != expected {
+		t.Errorf("PP7435_CHR1-0001 translates to\n%s instead of \n%s", protein, expected)
+	}
+}
+
 func TestGenbankNewlineParsingRegression(t *testing.T) {
 	gbk, _ := genbank.Read("../../data/puc19.gbk")
 
@@ -261,7 +583,7 @@ GbkMulti/GbkFlat related tests begin here.
 ******************************************************************************/
 
 func ExampleReadMulti() {
-	sequences := genbank.ReadMulti("../../data/multiGbk_test.seq")
+	sequences, _ := genbank.ReadMulti("../../data/multiGbk_test.seq")
 	var locus []string
 	for _, sequence := range sequences {
 		locus = append(locus, sequence.Meta.Locus.Name)
@@ -271,8 +593,60 @@ func ExampleReadMulti() {
 	// Output: AB000100, AB000106
 }
 
+func TestMultiRecordAndContig(t *testing.T) {
+	// Read parses the first record instead of mixing every record together.
+	sequence, err := genbank.Read("../../data/multiGbk_test.seq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sequences, _ := genbank.ReadMulti("../../data/multiGbk_test.seq")
+	if sequence.Meta.Locus.Name != "AB000100" || sequence.Sequence != sequences[0].Sequence {
+		t.Errorf("Read did not return just the first record. Got %s with a %d bp sequence", sequence.Meta.Locus.Name, len(sequence.Sequence))
+	}
+	if len(sequence.Features) != len(sequences[0].Features) {
+		t.Errorf("Read returned %d features, expected %d", len(sequence.Features), len(sequences[0].Features))
+	}
+
+	contigRecord := "LOCUS       NC_000913               4641652 bp    DNA     circular CON 09-MAR-2022\n" +
+		"DEFINITION  Escherichia coli str. K-12 substr. MG1655, complete genome.\n" +
+		"FEATURES             Location/Qualifiers\n" +
+		"     gene            190..255\n" +
+		"                     /gene=\"thrL\"\n" +
+		"CONTIG      join(U00096.3:1..2000000,gap(100),gap(unk50),gap(),\n" +
+		"            complement(U00096.3:2000001..4641652))\n" +
+		"//\n"
+	contig, err := genbank.Parse([]byte(contigRecord))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []genbank.ContigLocation{
+		{Accession: "U00096.3", Location: genbank.Location{Start: 0, End: 2000000}},
+		{Gap: true, GapLength: 100},
+		{Gap: true, GapLength: 50},
+		{Gap: true},
+		{Accession: "U00096.3", Location: genbank.Location{Start: 2000000, End: 4641652, Complement: true}},
+	}
+	if diff := cmp.Diff(expected, contig.Meta.Contig); diff != "" {
+		t.Errorf("unexpected CONTIG (-want +got):\n%s", diff)
+	}
+	if len(contig.Features) != 1 {
+		t.Fatalf("expected 1 feature, got %d", len(contig.Features))
+	}
+	if _, err := contig.Features[0].GetSequence(); err == nil || !strings.Contains(err.Error(), "CONTIG") {
+		t.Errorf("expected an error explaining the record has no sequence, got %v", err)
+	}
+
+	built, err := genbank.Build(contig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(built), "CONTIG") {
+		t.Errorf("Build dropped the CONTIG line. Got:\n%s", built)
+	}
+}
+
 func ExampleReadFlat() {
-	sequences := genbank.ReadFlat("../../data/long_comment.seq")
+	sequences, _ := genbank.ReadFlat("../../data/long_comment.seq")
 	var locus []string
 	for _, sequence := range sequences {
 		locus = append(locus, sequence.Meta.Locus.Name)
@@ -283,7 +657,7 @@ func ExampleReadFlat() {
 }
 
 func ExampleReadFlatGz() {
-	sequences := genbank.ReadFlatGz("../../data/flatGbk_test.seq.gz")
+	sequences, _ := genbank.ReadFlatGz("../../data/flatGbk_test.seq.gz")
 	//sequences := ReadFlatGz("../../data/gbbct358.seq.gz")
 	var locus []string
 	for _, sequence := range sequences {
@@ -295,7 +669,7 @@ func ExampleReadFlatGz() {
 
 func ExampleParseMulti() {
 	file, _ := ioutil.ReadFile("../../data/multiGbk_test.seq")
-	sequences := genbank.ParseMulti(file)
+	sequences, _ := genbank.ParseMulti(file)
 	var locus []string
 	for _, sequence := range sequences {
 		locus = append(locus, sequence.Meta.Locus.Name)
@@ -305,9 +679,75 @@ func ExampleParseMulti() {
 	// Output: AB000100, AB000106
 }
 
+// ExampleParser shows how to read a multi-Genbank file one record at a time.
+func ExampleParser() {
+	file, _ := os.Open("../../data/multiGbk_test.seq")
+	defer file.Close()
+
+	parser := genbank.NewParser(file)
+	for {
+		sequence, err := parser.Next()
+		if err != nil {
+			break
+		}
+		fmt.Println(sequence.Meta.Locus.Name)
+	}
+	// Output:
+	// AB000100
+	// AB000106
+}
+
+func TestParser(t *testing.T) {
+	expected, _ := genbank.ReadFlat("../../data/flatGbk_test.seq")
+	file, err := os.Open("../../data/flatGbk_test.seq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	parser, err := genbank.NewFlatParser(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index := 0; ; index++ {
+		sequence, err := parser.Next()
+		if err == io.EOF {
+			if index != len(expected) {
+				t.Errorf("expected %d records, got %d", len(expected), index)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected[index], sequence, []cmp.Option{cmpopts.IgnoreFields(genbank.Feature{}, "ParentSequence")}...); diff != "" {
+			t.Errorf("record %d differs from ReadFlat. Got this diff:\n%s", index, diff)
+		}
+	}
+	if _, err := parser.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF after the last record, got %v", err)
+	}
+
+	if _, err := genbank.NewFlatParser(strings.NewReader("too\nshort\n")); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected an unexpected EOF error for a flat file shorter than its header, got %v", err)
+	}
+
+	// errors in flat files count the header lines too.
+	flatFile := strings.Repeat("header\n", 10) +
+		"LOCUS       test                      12 bp    DNA     linear   UNA 01-JAN-2022\n" +
+		"FEATURES             Location/Qualifiers\n" +
+		"     gene            1..x\n" +
+		"//\n"
+	parser, _ = genbank.NewFlatParser(strings.NewReader(flatFile))
+	var parseError *genbank.ParseError
+	if _, err := parser.Next(); !errors.As(err, &parseError) || parseError.Line != 13 {
+		t.Errorf("expected a ParseError on line 13, got %v", err)
+	}
+}
+
 func ExampleParseFlat() {
 	file, _ := ioutil.ReadFile("../../data/flatGbk_test.seq")
-	sequences := genbank.ParseFlat(file)
+	sequences, _ := genbank.ParseFlat(file)
 	var locus []string
 	for _, sequence := range sequences {
 		locus = append(locus, sequence.Meta.Locus.Name)
@@ -330,3 +770,95 @@ func TestBenchlingGenbank(t *testing.T) {
 		t.Errorf("Parsing benchling genbank file not returned the correct quantity of features")
 	}
 }
+
+func TestParseErrors(t *testing.T) {
+	record := func(location string) string {
+		return "LOCUS       test                      12 bp    DNA     linear   UNA 01-JAN-2022\n" +
+			"FEATURES             Location/Qualifiers\n" +
+			"     gene            " + location + "\n" +
+			"                     /gene=\"test\"\n" +
+			"ORIGIN\n" +
+			"        1 atgaaataat ga\n" +
+			"//\n"
+	}
+
+	for _, location := range []string{"1..", "one..12", "join(1..3,x..12)", "complement(1..3"} {
+		_, err := genbank.Parse([]byte(record(location)))
+		if !errors.Is(err, genbank.ErrInvalidLocation) {
+			t.Errorf("%s: expected an invalid location error, got %v", location, err)
+		}
+		var parseError *genbank.ParseError
+		if !errors.As(err, &parseError) || parseError.Line != 3 {
+			t.Errorf("%s: expected a ParseError on line 3, got %v", location, err)
+		}
+	}
+
+	// locations that used to be silently misread.
+	for location, expected := range map[string]genbank.Location{
+		"<5":  {Start: 4, End: 5, FivePrimePartial: true},
+		"3^4": {Start: 3, End: 3},
+	} {
+		sequence, err := genbank.Parse([]byte(record(location)))
+		if err != nil {
+			t.Fatalf("%s: %v", location, err)
+		}
+		if diff := cmp.Diff(expected, sequence.Features[0].Location); diff != "" {
+			t.Errorf("%s: unexpected location (-want +got):\n%s", location, diff)
+		}
+	}
+
+	truncated := strings.SplitAfter(record("1..12"), "\n")
+	if _, err := genbank.Parse([]byte(strings.Join(truncated[:3], ""))); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected an unexpected EOF error for a file ending part of the way through a feature, got %v", err)
+	}
+
+	// line numbers count from the start of the whole file, not the failing record.
+	var parseError *genbank.ParseError
+	sequences, err := genbank.ParseMulti([]byte(record("1..12") + record("1..x")))
+	if !errors.As(err, &parseError) || parseError.Line != 10 {
+		t.Errorf("expected a ParseError on line 10, got %v", err)
+	}
+	if len(sequences) != 1 {
+		t.Errorf("expected the first record to be returned, got %d records", len(sequences))
+	}
+
+	if _, err := genbank.ReadMulti("../../data/does_not_exist.gbk"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a file not found error, got %v", err)
+	}
+	if _, err := genbank.Read("../../data/does_not_exist.gbk"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a file not found error, got %v", err)
+	}
+}
+
+func TestGenbankSeqhash(t *testing.T) {
+	puc19, err := genbank.Read("../../data/puc19.gbk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := puc19.Seqhash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pUC19 is circular double stranded DNA, so any rotation of it on either
+	// strand has the same Seqhash.
+	rotated := puc19
+	rotated.Sequence = transform.ReverseComplement(puc19.Sequence[100:] + puc19.Sequence[:100])
+	if rotatedHash, _ := rotated.Seqhash(); rotatedHash != hash || !strings.HasPrefix(hash, "v1_DCD_") {
+		t.Errorf("expected a v1_DCD_ Seqhash that doesn't change with rotation, got %s and %s", hash, rotatedHash)
+	}
+
+	for _, test := range []struct {
+		locus  genbank.Locus
+		prefix string
+	}{
+		{genbank.Locus{MoleculeType: "mRNA", Linear: true}, "v1_RLS_"},
+		{genbank.Locus{MoleculeType: "ds-RNA", Linear: true}, "v1_RLD_"},
+		{genbank.Locus{MoleculeType: "ss-DNA", Circular: true}, "v1_DCS_"},
+		{genbank.Locus{SequenceCoding: "aa", Linear: true}, "v1_PLS_"},
+	} {
+		sequence := genbank.Genbank{Meta: genbank.Meta{Locus: test.locus}, Sequence: "atgaaa"}
+		if hash, err := sequence.Seqhash(); err != nil || !strings.HasPrefix(hash, test.prefix) {
+			t.Errorf("expected a %s Seqhash for %+v, got %s and %v", test.prefix, test.locus, hash, err)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/TimothyStiles/poly/io/gff"
+	"github.com/TimothyStiles/poly/synthesis/codon"
 	"github.com/TimothyStiles/poly/transform"
 )
 
@@ -49,6 +50,61 @@ func ExampleGff_AddFeature() {
 	// Output: true
 }
 
+func ExampleGff_FindFeatures() {
+	sequence, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+
+	// thrB has both a gene and a CDS feature.
+	fmt.Println(sequence.FindFeatures("thrB"))
+	// Output: [4 5]
+}
+
+func ExampleGff_TranslateCDS() {
+	sequence, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+
+	// CDS features in this file are named by their locus tag.
+	proteins, _ := sequence.TranslateCDS(codon.GetCodonTable(11))
+	fmt.Println(proteins["b0001"])
+	// Output: MKRISTTITTTITITTGNGAG*
+}
+
+func ExampleGff_FeaturesInRange() {
+	sequence, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+
+	// find everything annotated over the first 500 bases.
+	for _, feature := range sequence.FeaturesInRange(0, 500) {
+		fmt.Println(feature.Type, feature.Attributes["gene"])
+	}
+	// Output:
+	// gene thrL
+	// CDS thrL
+	// gene thrA
+	// CDS thrA
+}
+
+func ExampleGff_RemoveFeature() {
+	sequence, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+
+	// remove every feature annotating thrL, starting from the back so indices stay valid.
+	indices := sequence.FindFeatures("thrL")
+	for i := len(indices) - 1; i >= 0; i-- {
+		_ = sequence.RemoveFeature(indices[i])
+	}
+
+	fmt.Println(sequence.Features[0].Attributes["gene"])
+	// Output: thrA
+}
+
+func ExampleGff_ReplaceFeature() {
+	sequence, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+
+	feature := sequence.Features[0]
+	feature.Type = "ncRNA"
+	_ = sequence.ReplaceFeature(0, feature)
+
+	fmt.Println(sequence.Features[0].Type)
+	// Output: ncRNA
+}
+
 func ExampleFeature_GetSequence() {
 
 	// Sequence for greenflourescent protein (GFP) that we're using as test data for this example.
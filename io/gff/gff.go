@@ -12,48 +12,79 @@ format and the more general poly.Sequence struct.
 package gff
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
+	"unicode"
 
+	"github.com/TimothyStiles/poly"
+	"github.com/TimothyStiles/poly/io/internal/compress"
+	"github.com/TimothyStiles/poly/io/internal/slice"
+	"github.com/TimothyStiles/poly/seqhash"
 	"lukechampine.com/blake3"
 
+	"github.com/TimothyStiles/poly/io/fasta"
+	"github.com/TimothyStiles/poly/synthesis/codon"
 	"github.com/TimothyStiles/poly/transform"
 )
 
 // Gff is a struct that represents a gff file.
 type Gff struct {
-	Meta     Meta
-	Features []Feature // will need a GetFeatures interface to standardize
-	Sequence string
+	Meta      Meta          `json:"meta"`
+	Features  []Feature     `json:"features"` // will need a GetFeatures interface to standardize
+	Sequence  string        `json:"sequence"`
+	Sequences []fasta.Fasta `json:"sequences,omitempty"` // every record of a ##FASTA section with more than one sequence, like a genome with several chromosomes. Sequence is the first of them.
 }
 
 // Meta holds meta information about a gff file.
 type Meta struct {
-	Name                 string   `json:"name"`
-	Description          string   `json:"description"`
-	Version              string   `json:"gff_version"`
-	RegionStart          int      `json:"region_start"`
-	RegionEnd            int      `json:"region_end"`
-	Size                 int      `json:"size"`
-	SequenceHash         string   `json:"sequence_hash"`
-	SequenceHashFunction string   `json:"hash_function"`
-	CheckSum             [32]byte `json:"checkSum"` // blake3 checksum of the parsed file itself. Useful for if you want to check if incoming genbank/gff files are different.
+	Name                 string           `json:"name"`
+	Description          string           `json:"description"`
+	Version              string           `json:"gff_version"`
+	RegionStart          int              `json:"region_start"`
+	RegionEnd            int              `json:"region_end"`
+	Size                 int              `json:"size"`
+	SequenceHash         string           `json:"sequence_hash"`
+	SequenceHashFunction string           `json:"hash_function"`
+	CheckSum             [32]byte         `json:"checkSum"`                   // blake3 checksum of the parsed file itself. Useful for if you want to check if incoming genbank/gff files are different.
+	LineWidth            int              `json:"line_width"`                 // number of bases per line of the ##FASTA section. Build uses DefaultLineWidth if this is 0.
+	Circular             bool             `json:"circular"`                   // set when a feature has the Is_circular=true attribute, which GFF3 puts on the region feature of circular sequences.
+	Directives           []string         `json:"directives"`                 // ## directives and # comments other than ##gff-version and ##sequence-region, in the order they were found. Build writes them after the header.
+	SequenceRegions      []SequenceRegion `json:"sequence_regions,omitempty"` // every ##sequence-region directive in the file. Name, RegionStart and RegionEnd are taken from the first one.
 }
 
+// SequenceRegion is a ##sequence-region directive, giving the bounds of one
+// of the sequences (seqids) a gff file annotates.
+type SequenceRegion struct {
+	Name  string `json:"name"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// DefaultLineWidth is the number of bases per line Build writes in the ##FASTA section when Meta.LineWidth isn't set.
+const DefaultLineWidth = 70
+
 // Feature is a struct that represents a feature in a gff file.
 type Feature struct {
-	Name           string            `json:"name"`
-	Source         string            `json:"source"`
-	Type           string            `json:"type"`
-	Score          string            `json:"score"`
-	Strand         string            `json:"strand"`
-	Phase          string            `json:"phase"`
-	Attributes     map[string]string `json:"attributes"`
-	Location       Location          `json:"location"`
-	ParentSequence *Gff              `json:"-"`
+	Name             string            `json:"name"`
+	Source           string            `json:"source"`
+	Type             string            `json:"type"`
+	Score            string            `json:"score"`
+	Strand           string            `json:"strand"`
+	Phase            string            `json:"phase"`
+	Attributes       map[string]string `json:"attributes"`
+	AttributeOrder   []string          `json:"attribute_order,omitempty"`   // order of the keys in Attributes when the feature was parsed. Build writes attributes in this order.
+	QuotedAttributes map[string]bool   `json:"quoted_attributes,omitempty"` // whether each attribute of a feature parsed by the gtf package had its value quoted, so that gtf.Build writes it back the same way.
+	Location         Location          `json:"location"`
+	ParentSequence   *Gff              `json:"-"`
 }
 
 // Location is a struct that represents a location in a gff file.
@@ -62,8 +93,10 @@ type Location struct {
 	End               int        `json:"end"`
 	Complement        bool       `json:"complement"`
 	Join              bool       `json:"join"`
-	FivePrimePartial  bool       `json:"five_prime_partial"`
-	ThreePrimePartial bool       `json:"three_prime_partial"`
+	FivePrimePartial  bool       `json:"five_prime_partial"`        // the feature continues past its start, set from NCBI's start_range=.,N attribute.
+	ThreePrimePartial bool       `json:"three_prime_partial"`       // the feature continues past its end, set from NCBI's end_range=N,. attribute.
+	UndefinedStart    bool       `json:"undefined_start,omitempty"` // the start was written as "." so Start is meaningless.
+	UndefinedEnd      bool       `json:"undefined_end,omitempty"`   // the end was written as "." so End is meaningless.
 	SubLocations      []Location `json:"sub_locations"`
 }
 
@@ -75,8 +108,406 @@ func (sequence *Gff) AddFeature(feature *Feature) error {
 	return nil
 }
 
+// GetName returns the name of the sequence the gff file annotates.
+func (sequence Gff) GetName() string {
+	return sequence.Meta.Name
+}
+
+// GetSequence returns the sequence the gff file annotates.
+func (sequence Gff) GetSequence() string {
+	return sequence.Sequence
+}
+
+// GetFeatures returns the features of the gff file as poly.Features.
+func (sequence Gff) GetFeatures() []poly.Feature {
+	features := make([]poly.Feature, len(sequence.Features))
+	for index, feature := range sequence.Features {
+		features[index] = feature
+	}
+	return features
+}
+
+// GetMeta returns the name, description and topology of the gff file.
+func (sequence Gff) GetMeta() poly.Meta {
+	return poly.Meta{Name: sequence.Meta.Name, Description: sequence.Meta.Description, Circular: sequence.Meta.Circular}
+}
+
+// Seqhash returns the Seqhash of the sequence of the ##FASTA section, as double
+// stranded DNA that is circular if Meta.Circular is set.
+func (sequence Gff) Seqhash() (string, error) {
+	return seqhash.FromSequence(sequence.Sequence, seqhash.DNA, sequence.Meta.Circular)
+}
+
+// GetType returns the type of the feature.
+func (feature Feature) GetType() string {
+	return feature.Type
+}
+
+// GetAttributes returns the attributes of the feature.
+func (feature Feature) GetAttributes() map[string]string {
+	return feature.Attributes
+}
+
+// RemoveFeature removes the feature at the given index from the Gff struct.
+func (sequence *Gff) RemoveFeature(index int) error {
+	if index < 0 || index >= len(sequence.Features) {
+		return fmt.Errorf("feature index %d out of range for %d features", index, len(sequence.Features))
+	}
+	sequence.Features = append(sequence.Features[:index], sequence.Features[index+1:]...)
+	sequence.linkFeatures()
+	return nil
+}
+
+// ReplaceFeature replaces the feature at the given index with a new feature.
+func (sequence *Gff) ReplaceFeature(index int, feature Feature) error {
+	if index < 0 || index >= len(sequence.Features) {
+		return fmt.Errorf("feature index %d out of range for %d features", index, len(sequence.Features))
+	}
+	sequence.Features[index] = feature
+	sequence.linkFeatures()
+	return nil
+}
+
+// FindFeatures returns the indices of every feature whose Name, ID or gene attribute matches name.
+func (sequence *Gff) FindFeatures(name string) []int {
+	var indices []int
+	for index, feature := range sequence.Features {
+		for _, key := range []string{"Name", "ID", "gene"} {
+			if value, ok := feature.Attributes[key]; ok && value == name {
+				indices = append(indices, index)
+				break
+			}
+		}
+	}
+	return indices
+}
+
+// FeaturesInRange returns every feature that overlaps the 0-based half-open
+// range [start, end), in the order they appear in the Gff. Empty ranges don't
+// overlap anything. Features with
+// sub-locations only overlap the range if one of their sub-locations does, so
+// a range falling inside an intron doesn't return the spliced feature. On
+// circular sequences features that wrap around the origin are handled too.
+//
+// FeaturesInRange scans every feature. If you're making many queries against
+// the same Gff build a FeatureIndex with NewFeatureIndex instead.
+func (sequence Gff) FeaturesInRange(start, end int) []Feature {
+	if start >= end {
+		return nil
+	}
+	var features []Feature
+	for _, feature := range sequence.Features {
+		for _, interval := range sequence.locationIntervals(feature.Location) {
+			if interval[0] < end && interval[1] > start {
+				features = append(features, feature)
+				break
+			}
+		}
+	}
+	return features
+}
+
+// locationIntervals splits a location into the 0-based half-open intervals it covers.
+func (sequence Gff) locationIntervals(location Location) [][2]int {
+	if len(location.SubLocations) > 0 {
+		var intervals [][2]int
+		for _, subLocation := range location.SubLocations {
+			intervals = append(intervals, sequence.locationIntervals(subLocation)...)
+		}
+		return intervals
+	}
+	if location.UndefinedStart || location.UndefinedEnd {
+		return nil
+	}
+	sequenceLength := len(sequence.Sequence)
+	if sequence.Meta.Circular && sequenceLength > 0 {
+		if location.Start > location.End {
+			return [][2]int{{location.Start, sequenceLength}, {0, location.End}}
+		}
+		if location.End > sequenceLength {
+			return [][2]int{{location.Start, sequenceLength}, {0, location.End - sequenceLength}}
+		}
+	}
+	return [][2]int{{location.Start, location.End}}
+}
+
+// FeatureIndex answers FeaturesInRange queries without scanning every feature.
+// It is a snapshot of the Gff it was built from, so build a new one after
+// adding, removing or replacing features.
+type FeatureIndex struct {
+	features  []Feature
+	intervals []featureInterval // sorted by start.
+	maxEnds   []int             // maxEnds[i] is the largest end of intervals[:i+1].
+}
+
+// featureInterval is a single interval covered by the feature at featureIndex.
+type featureInterval struct {
+	start, end   int
+	featureIndex int
+}
+
+// NewFeatureIndex indexes the locations of every feature in a Gff.
+func NewFeatureIndex(sequence Gff) FeatureIndex {
+	index := FeatureIndex{features: sequence.Features}
+	for featureIndex, feature := range sequence.Features {
+		for _, interval := range sequence.locationIntervals(feature.Location) {
+			index.intervals = append(index.intervals, featureInterval{interval[0], interval[1], featureIndex})
+		}
+	}
+	sort.SliceStable(index.intervals, func(i, j int) bool {
+		return index.intervals[i].start < index.intervals[j].start
+	})
+
+	index.maxEnds = make([]int, len(index.intervals))
+	for position, interval := range index.intervals {
+		index.maxEnds[position] = interval.end
+		if position > 0 && index.maxEnds[position-1] > interval.end {
+			index.maxEnds[position] = index.maxEnds[position-1]
+		}
+	}
+	return index
+}
+
+// FeaturesInRange returns the same features as Gff.FeaturesInRange.
+func (index FeatureIndex) FeaturesInRange(start, end int) []Feature {
+	if start >= end {
+		return nil
+	}
+	// intervals starting at or after end can't overlap, and neither can any
+	// interval before the first point where the running maximum end passes start.
+	last := sort.Search(len(index.intervals), func(i int) bool { return index.intervals[i].start >= end })
+	first := sort.Search(last, func(i int) bool { return index.maxEnds[i] > start })
+
+	var featureIndices []int
+	found := make(map[int]bool)
+	for _, interval := range index.intervals[first:last] {
+		if interval.end > start && !found[interval.featureIndex] {
+			found[interval.featureIndex] = true
+			featureIndices = append(featureIndices, interval.featureIndex)
+		}
+	}
+	sort.Ints(featureIndices)
+
+	var features []Feature
+	for _, featureIndex := range featureIndices {
+		features = append(features, index.features[featureIndex])
+	}
+	return features
+}
+
+// GeometryOptions changes how Overlaps, Contains and DistanceTo compare features.
+type GeometryOptions struct {
+	// StrandAware only compares features on the same strand. Features on
+	// different strands never overlap or contain each other.
+	StrandAware bool
+	// BySubLocation compares joined features sub-location by sub-location, so
+	// introns don't count as part of the feature. By default a joined feature
+	// covers everything from its smallest start to its largest end.
+	BySubLocation bool
+}
+
+// Overlaps reports whether two features share at least one base.
+func (feature Feature) Overlaps(other Feature, options ...GeometryOptions) bool {
+	geometryOptions := mergeGeometryOptions(options)
+	if !geometryOptions.sameStrand(feature, other) {
+		return false
+	}
+	for _, interval := range featureIntervals(feature.Location, geometryOptions.BySubLocation) {
+		for _, otherInterval := range featureIntervals(other.Location, geometryOptions.BySubLocation) {
+			if interval[0] < otherInterval[1] && otherInterval[0] < interval[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Contains reports whether every base of other is also covered by feature.
+func (feature Feature) Contains(other Feature, options ...GeometryOptions) bool {
+	geometryOptions := mergeGeometryOptions(options)
+	if !geometryOptions.sameStrand(feature, other) {
+		return false
+	}
+	intervals := featureIntervals(feature.Location, geometryOptions.BySubLocation)
+	otherIntervals := featureIntervals(other.Location, geometryOptions.BySubLocation)
+	if len(otherIntervals) == 0 {
+		return false
+	}
+	for _, otherInterval := range otherIntervals {
+		contained := false
+		for _, interval := range intervals {
+			if interval[0] <= otherInterval[0] && otherInterval[1] <= interval[1] {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			return false
+		}
+	}
+	return true
+}
+
+// DistanceTo returns the number of bases between two features. It is 0 when
+// the features overlap or sit right next to each other, and -1 when they can't
+// be compared because one has undefined coordinates or, with StrandAware set,
+// they're on different strands.
+func (feature Feature) DistanceTo(other Feature, options ...GeometryOptions) int {
+	geometryOptions := mergeGeometryOptions(options)
+	if !geometryOptions.sameStrand(feature, other) {
+		return -1
+	}
+	distance := -1
+	for _, interval := range featureIntervals(feature.Location, geometryOptions.BySubLocation) {
+		for _, otherInterval := range featureIntervals(other.Location, geometryOptions.BySubLocation) {
+			gap := 0
+			if otherInterval[0] >= interval[1] {
+				gap = otherInterval[0] - interval[1]
+			} else if interval[0] >= otherInterval[1] {
+				gap = interval[0] - otherInterval[1]
+			}
+			if distance == -1 || gap < distance {
+				distance = gap
+			}
+		}
+	}
+	return distance
+}
+
+// mergeGeometryOptions combines the optional GeometryOptions passed to the geometry methods.
+func mergeGeometryOptions(options []GeometryOptions) GeometryOptions {
+	var merged GeometryOptions
+	for _, option := range options {
+		merged.StrandAware = merged.StrandAware || option.StrandAware
+		merged.BySubLocation = merged.BySubLocation || option.BySubLocation
+	}
+	return merged
+}
+
+// sameStrand reports whether two features should be compared at all.
+func (options GeometryOptions) sameStrand(feature, other Feature) bool {
+	return !options.StrandAware || feature.Strand == other.Strand
+}
+
+// featureIntervals returns the 0-based half-open intervals a location covers,
+// either one per sub-location or a single interval spanning all of them.
+func featureIntervals(location Location, bySubLocation bool) [][2]int {
+	if len(location.SubLocations) == 0 {
+		if location.UndefinedStart || location.UndefinedEnd {
+			return nil
+		}
+		return [][2]int{{location.Start, location.End}}
+	}
+
+	var intervals [][2]int
+	for _, subLocation := range location.SubLocations {
+		intervals = append(intervals, featureIntervals(subLocation, bySubLocation)...)
+	}
+	if bySubLocation || len(intervals) == 0 {
+		return intervals
+	}
+	span := intervals[0]
+	for _, interval := range intervals[1:] {
+		if interval[0] < span[0] {
+			span[0] = interval[0]
+		}
+		if interval[1] > span[1] {
+			span[1] = interval[1]
+		}
+	}
+	return [][2]int{span}
+}
+
+// sequenceOntologyTypes maps feature types, squashed by squashFeatureType, onto
+// the Sequence Ontology (http://www.sequenceontology.org) terms GFF3 expects.
+var sequenceOntologyTypes = map[string]string{
+	"gene":                   "gene",
+	"pseudogene":             "pseudogene",
+	"mrna":                   "mRNA",
+	"messengerrna":           "mRNA",
+	"transcript":             "transcript",
+	"cds":                    "CDS",
+	"codingsequence":         "CDS",
+	"exon":                   "exon",
+	"intron":                 "intron",
+	"fiveprimeutr":           "five_prime_UTR",
+	"5utr":                   "five_prime_UTR",
+	"utr5":                   "five_prime_UTR",
+	"threeprimeutr":          "three_prime_UTR",
+	"3utr":                   "three_prime_UTR",
+	"utr3":                   "three_prime_UTR",
+	"startcodon":             "start_codon",
+	"stopcodon":              "stop_codon",
+	"trna":                   "tRNA",
+	"rrna":                   "rRNA",
+	"ncrna":                  "ncRNA",
+	"lncrna":                 "lnc_RNA",
+	"lncrnagene":             "lncRNA_gene",
+	"mirna":                  "miRNA",
+	"snrna":                  "snRNA",
+	"snorna":                 "snoRNA",
+	"region":                 "region",
+	"chromosome":             "chromosome",
+	"promoter":               "promoter",
+	"terminator":             "terminator",
+	"operon":                 "operon",
+	"polyasignal":            "polyA_signal_sequence",
+	"polyasignalsequence":    "polyA_signal_sequence",
+	"repeatregion":           "repeat_region",
+	"originofreplication":    "origin_of_replication",
+	"rbs":                    "ribosome_entry_site",
+	"ribosomeentrysite":      "ribosome_entry_site",
+	"ribosomebindingsite":    "ribosome_entry_site",
+	"signalpeptide":          "signal_peptide",
+	"transcriptionstartsite": "TSS",
+	"tss":                    "TSS",
+}
+
+// squashFeatureType lowercases a feature type and strips the punctuation that
+// differs between tools, so that "5'UTR", "five_prime_UTR" and "Five-Prime-UTR"
+// all look alike.
+func squashFeatureType(featureType string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '_', '-', ' ', '\'', '.':
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, featureType)
+}
+
+// NormalizeFeatureTypes rewrites the type of every feature to its Sequence
+// Ontology term, fixing differences in case and punctuation along with common
+// synonyms like 5'UTR for five_prime_UTR. Types it doesn't recognize are left
+// as they are and returned, once each, in the order they were found.
+func (sequence *Gff) NormalizeFeatureTypes() []string {
+	var unknownTypes []string
+	seen := make(map[string]bool)
+	for index, feature := range sequence.Features {
+		if term, ok := sequenceOntologyTypes[squashFeatureType(feature.Type)]; ok {
+			sequence.Features[index].Type = term
+			continue
+		}
+		if !seen[feature.Type] {
+			seen[feature.Type] = true
+			unknownTypes = append(unknownTypes, feature.Type)
+		}
+	}
+	return unknownTypes
+}
+
+// linkFeatures points every feature's ParentSequence back at the Gff struct.
+func (sequence *Gff) linkFeatures() {
+	for index := range sequence.Features {
+		sequence.Features[index].ParentSequence = sequence
+	}
+}
+
 // GetSequence takes a feature and returns a sequence string for that feature.
 func (feature Feature) GetSequence() (string, error) {
+	if feature.ParentSequence == nil {
+		return "", fmt.Errorf("%s feature has no parent sequence", feature.Type)
+	}
 	return getFeatureSequence(feature, feature.Location)
 }
 
@@ -84,10 +515,17 @@ func (feature Feature) GetSequence() (string, error) {
 func getFeatureSequence(feature Feature, location Location) (string, error) {
 	var sequenceBuffer bytes.Buffer
 	var sequenceString string
-	parentSequence := feature.ParentSequence.Sequence
+	parentSequence := feature.ParentSequence.sequenceFor(feature.Name)
 
 	if len(location.SubLocations) == 0 {
-		sequenceBuffer.WriteString(parentSequence[location.Start:location.End])
+		if location.UndefinedStart || location.UndefinedEnd {
+			return "", fmt.Errorf("can't get the sequence of a %s feature with an undefined start or end", feature.Type)
+		}
+		sequence, err := slice.Sequence(parentSequence, location.Start, location.End, feature.ParentSequence.Meta.Circular)
+		if err != nil {
+			return "", err
+		}
+		sequenceBuffer.WriteString(sequence)
 	} else {
 
 		for _, subLocation := range location.SubLocations {
@@ -109,77 +547,772 @@ func getFeatureSequence(feature Feature, location Location) (string, error) {
 	return sequenceString, nil
 }
 
+// sequenceFor returns the sequence a feature on seqid annotates. Files with
+// several sequences look it up by name, everything else uses Sequence.
+func (sequence Gff) sequenceFor(seqid string) string {
+	for _, record := range sequence.Sequences {
+		if record.Name == seqid {
+			return record.Sequence
+		}
+	}
+	return sequence.Sequence
+}
+
+// cdsKeys are the attributes TranslateCDS names proteins by, in order of preference.
+var cdsKeys = []string{"ID", "Name", "locus_tag", "protein_id", "gene"}
+
+// TranslateCDS translates every CDS feature into a protein, keyed by the
+// feature's ID, falling back to its Name, locus_tag, protein_id or gene
+// attributes and finally its location. Following GFF3, CDS features that share a
+// key are parts of the same protein and are joined in order along their strand
+// before translating. Features on the - strand are reverse complemented and the
+// phase of the first part is skipped. Proteins that begin with any of the table's
+// start codons begin with M, unless their CDS is missing its 5' end. The first
+// error met is returned along with the name of the CDS it came from.
+func (sequence Gff) TranslateCDS(codonTable codon.Table) (map[string]string, error) {
+	var keys []string
+	parts := make(map[string][]Feature)
+	for _, feature := range sequence.Features {
+		if feature.Type != "CDS" {
+			continue
+		}
+		key := fmt.Sprintf("%s:%d..%d", feature.Name, feature.Location.Start+1, feature.Location.End)
+		for _, cdsKey := range cdsKeys {
+			if value := feature.Attributes[cdsKey]; value != "" {
+				key = value
+				break
+			}
+		}
+		if _, ok := parts[key]; !ok {
+			keys = append(keys, key)
+		}
+		parts[key] = append(parts[key], feature)
+	}
+
+	proteins := make(map[string]string)
+	for _, key := range keys {
+		features := parts[key]
+		codingSequence, err := joinCodingSequence(features)
+		if err != nil {
+			return nil, fmt.Errorf("CDS %s: %w", key, err)
+		}
+		protein, err := translateCodingSequence(codingSequence, features[0], codonTable)
+		if err != nil {
+			return nil, fmt.Errorf("CDS %s: %w", key, err)
+		}
+		proteins[key] = protein
+	}
+	return proteins, nil
+}
+
+// joinCodingSequence sorts the parts of a CDS in order along their strand and
+// joins their sequences, reverse complementing parts on the - strand. The parts
+// must be in the order they're written in. After sorting, features[0] is the 5'
+// most part.
+func joinCodingSequence(features []Feature) (string, error) {
+	writtenFirst := features[0].Location
+	sort.SliceStable(features, func(i, j int) bool {
+		return features[i].Location.Start < features[j].Location.Start
+	})
+	if parent := features[0].ParentSequence; parent != nil && parent.Meta.Circular {
+		features = rotateAroundOrigin(features, writtenFirst, len(parent.sequenceFor(features[0].Name)))
+	}
+	if features[0].Strand == "-" {
+		for i, j := 0, len(features)-1; i < j; i, j = i+1, j-1 {
+			features[i], features[j] = features[j], features[i]
+		}
+	}
+
+	var codingSequence strings.Builder
+	for _, feature := range features {
+		featureSequence, err := feature.GetSequence()
+		if err != nil {
+			return "", err
+		}
+		if feature.Strand == "-" && !feature.Location.Complement {
+			featureSequence = transform.ReverseComplement(featureSequence)
+		}
+		codingSequence.WriteString(featureSequence)
+	}
+	return codingSequence.String(), nil
+}
+
+// rotateAroundOrigin takes the pieces of a feature on a circular sequence of
+// the given length, sorted by start, and rotates them so the feature runs
+// through the origin if it was split there. GFF3 writes features crossing the
+// origin with an end past the length of the sequence, which sort correctly as
+// they are, but some files split them into a piece ending at the end of the
+// sequence and one starting at 1. Coordinates alone can't tell where such a
+// feature begins, since any gap between its pieces could be an intron, so it
+// begins with the piece written first, like the parts of a GenBank join. If
+// that's the piece starting at 1 the file was sorted by start and the feature
+// begins with the piece ending at the origin instead. A CDS written as
+// 4500..4642 and 1..200 on a 4642 bp plasmid then begins at 4500 either way,
+// while pieces that don't meet at the origin are never rotated.
+func rotateAroundOrigin(features []Feature, writtenFirst Location, length int) []Feature {
+	last := len(features) - 1
+	if length == 0 || last == 0 || features[0].Location.Start != 0 || features[last].Location.End != length {
+		return features
+	}
+	first := last
+	for index, feature := range features {
+		if index > 0 && feature.Location.Start == writtenFirst.Start && feature.Location.End == writtenFirst.End {
+			first = index
+			break
+		}
+	}
+	return append(append([]Feature{}, features[first:]...), features[:first]...)
+}
+
+// Translate translates a single CDS feature. Features on the - strand are
+// reverse complemented and the number of bases given by the feature's phase is
+// skipped before translating. If the feature starts with one of the table's
+// start codons the protein begins with M, unless the feature is missing its 5'
+// end (see Location.FivePrimePartial) in which case its first codon is just an
+// ordinary codon in the middle of a protein. Use Gff.TranslateCDS to translate
+// CDS features that are split over several lines.
+func (feature Feature) Translate(codonTable codon.Table) (string, error) {
+	featureSequence, err := feature.GetSequence()
+	if err != nil {
+		return "", err
+	}
+	if feature.Strand == "-" && !feature.Location.Complement {
+		featureSequence = transform.ReverseComplement(featureSequence)
+	}
+	return translateCodingSequence(featureSequence, feature, codonTable)
+}
+
+// translateCodingSequence translates a coding sequence that begins with first,
+// the 5' most part of its CDS.
+func translateCodingSequence(codingString string, first Feature, codonTable codon.Table) (string, error) {
+	if phase, err := strconv.Atoi(first.Phase); err == nil && phase > 0 && phase <= len(codingString) {
+		codingString = codingString[phase:]
+	}
+	// 3' partial CDSs can end with an incomplete codon, which is left out like Translate does.
+	codingString = codingString[:len(codingString)-len(codingString)%3]
+	// alternative start codons like GTG still code for methionine when they start a protein,
+	// but a 5' partial CDS doesn't start with its start codon.
+	return codon.TranslateWithOptions(codingString, codonTable, codon.TranslateOptions{StartCodon: !first.fivePrimePartial()})
+}
+
+// fivePrimePartial reports whether a feature is missing the 5' end of its
+// strand. Like GenBank's "<" and ">", FivePrimePartial and ThreePrimePartial
+// mark the start and end coordinates, so on the - strand the 5' end is the end.
+func (feature Feature) fivePrimePartial() bool {
+	if feature.Strand == "-" {
+		return feature.Location.ThreePrimePartial
+	}
+	return feature.Location.FivePrimePartial
+}
+
+// AttributeValues returns the values of a feature's attribute. GFF3 separates
+// multiple values of an attribute with commas and percent-encodes characters
+// with special meaning (like "%3B" for ";"), so the raw value is split on
+// commas and each value is decoded. Attributes keeps the raw values so that
+// Build writes them back exactly as they were parsed. It returns nil if the
+// feature doesn't have the attribute.
+func (feature Feature) AttributeValues(key string) ([]string, error) {
+	rawValue, ok := feature.Attributes[key]
+	if !ok {
+		return nil, nil
+	}
+	rawValues := strings.Split(rawValue, ",")
+	values := make([]string, len(rawValues))
+	for index, value := range rawValues {
+		decodedValue, err := url.PathUnescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q: %w", key, value, err)
+		}
+		values[index] = decodedValue
+	}
+	return values, nil
+}
+
+// SetAttribute sets a feature's attribute to values, percent-encoding any
+// characters that would otherwise break the attribute column when written with
+// Build. Multiple values are joined with commas. New attributes are written
+// after the feature's existing ones.
+func (feature *Feature) SetAttribute(key string, values ...string) {
+	if feature.Attributes == nil {
+		feature.Attributes = make(map[string]string)
+	}
+	if _, ok := feature.Attributes[key]; !ok && feature.AttributeOrder != nil {
+		feature.AttributeOrder = append(feature.AttributeOrder, key)
+	}
+	encodedValues := make([]string, len(values))
+	for index, value := range values {
+		encodedValues[index] = encodeAttributeValue(value)
+	}
+	feature.Attributes[key] = strings.Join(encodedValues, ",")
+}
+
+// encodeAttributeValue percent-encodes the characters GFF3 reserves in the
+// attribute column: tabs, newlines and other control characters, "%", and the
+// ";", "=", "&" and "," separators.
+func encodeAttributeValue(value string) string {
+	var encoded strings.Builder
+	for index := 0; index < len(value); index++ {
+		character := value[index]
+		if character < 0x20 || character == 0x7f || strings.IndexByte("%;=&,", character) != -1 {
+			fmt.Fprintf(&encoded, "%%%02X", character)
+			continue
+		}
+		encoded.WriteByte(character)
+	}
+	return encoded.String()
+}
+
+// Alignment is the alignment of a feature against another sequence, as
+// described by the GFF3 Target and Gap attributes. Aligners like exonerate and
+// BLAT write one of these for every matched region (or exon) of a query.
+type Alignment struct {
+	TargetID     string         `json:"target_id"`
+	TargetStart  int            `json:"target_start"`  // 0-based start on the target.
+	TargetEnd    int            `json:"target_end"`    // exclusive end on the target.
+	TargetStrand string         `json:"target_strand"` // "+", "-" or "" if the Target attribute has no strand.
+	Gap          []GapOperation `json:"gap"`           // empty if the feature aligns without gaps.
+}
+
+// GapOperation is a single operation of a GFF3 Gap attribute. Operations are
+// M (match), I (insert a gap into the feature's sequence), D (insert a gap into
+// the target), F (forward frameshift) and R (reverse frameshift).
+type GapOperation struct {
+	Operation rune `json:"operation"`
+	Length    int  `json:"length"`
+}
+
+// Alignment parses a feature's Target and Gap attributes. It returns nil
+// without an error if the feature has no Target attribute. The raw attributes
+// are left untouched so Build writes them exactly as they were parsed.
+func (feature Feature) Alignment() (*Alignment, error) {
+	target, ok := feature.Attributes["Target"]
+	if !ok {
+		if _, ok := feature.Attributes["Gap"]; ok {
+			return nil, fmt.Errorf("feature has a Gap attribute but no Target")
+		}
+		return nil, nil
+	}
+
+	fields := strings.Fields(target)
+	if len(fields) != 3 && len(fields) != 4 {
+		return nil, fmt.Errorf("invalid Target %q: expected \"target_id start end [strand]\"", target)
+	}
+	targetID, err := url.PathUnescape(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid Target id %q: %w", fields[0], err)
+	}
+	start, err := strconv.Atoi(fields[1])
+	if err != nil || start < 1 {
+		return nil, fmt.Errorf("invalid Target start %q", fields[1])
+	}
+	end, err := strconv.Atoi(fields[2])
+	if err != nil || end < start {
+		return nil, fmt.Errorf("invalid Target end %q", fields[2])
+	}
+	alignment := Alignment{TargetID: targetID, TargetStart: start - 1, TargetEnd: end}
+	if len(fields) == 4 {
+		if fields[3] != "+" && fields[3] != "-" {
+			return nil, fmt.Errorf("invalid Target strand %q", fields[3])
+		}
+		alignment.TargetStrand = fields[3]
+	}
+
+	for _, operation := range strings.Fields(feature.Attributes["Gap"]) {
+		length, err := strconv.Atoi(operation[1:])
+		if err != nil || length < 1 || !strings.ContainsRune("MIDFR", rune(operation[0])) {
+			return nil, fmt.Errorf("invalid Gap operation %q", operation)
+		}
+		alignment.Gap = append(alignment.Gap, GapOperation{Operation: rune(operation[0]), Length: length})
+	}
+	return &alignment, nil
+}
+
+/******************************************************************************
+
+Feature hierarchy functions begin here.
+
+GFF3 describes how features relate to each other with the ID and Parent
+attributes. A gene is the parent of its mRNAs, and each mRNA is the parent of
+its exons and CDS parts, so a single gene can be spread over dozens of lines.
+Features keep the flat order of the file so Build can write them back as they
+were read, and the hierarchy is built on demand with FeatureTree.
+
+******************************************************************************/
+
+// FeatureNode is a feature in the hierarchy described by the GFF3 ID and Parent attributes.
+type FeatureNode struct {
+	Feature  Feature        `json:"feature"`
+	Children []*FeatureNode `json:"children"`
+}
+
+// FeatureTree builds the hierarchy of the Gff's features from their ID and
+// Parent attributes and returns its top level features, like genes, in the
+// order they appear in the file. Children are also kept in file order.
+//
+// A feature with several parents (Parent=mRNA1,mRNA2) is a child of each of
+// them. Features whose parent isn't in the file are treated as top level
+// features. A feature that is its own ancestor returns an error.
+func (sequence Gff) FeatureTree() ([]*FeatureNode, error) {
+	nodes := make([]*FeatureNode, len(sequence.Features))
+	nodesByID := make(map[string]*FeatureNode)
+	for index, feature := range sequence.Features {
+		nodes[index] = &FeatureNode{Feature: feature}
+		ids, err := feature.AttributeValues("ID")
+		if err != nil {
+			return nil, err
+		}
+		// features that share an ID are parts of one feature, like a CDS split over several lines, so children go under the first part.
+		if len(ids) > 0 {
+			if _, ok := nodesByID[ids[0]]; !ok {
+				nodesByID[ids[0]] = nodes[index]
+			}
+		}
+	}
+
+	var roots []*FeatureNode
+	for index, feature := range sequence.Features {
+		parents, err := feature.AttributeValues("Parent")
+		if err != nil {
+			return nil, err
+		}
+		hasParent := false
+		for _, parent := range parents {
+			if parentNode, ok := nodesByID[parent]; ok {
+				parentNode.Children = append(parentNode.Children, nodes[index])
+				hasParent = true
+			}
+		}
+		if !hasParent {
+			roots = append(roots, nodes[index])
+		}
+	}
+
+	// every feature should be reachable from the top level, unless it's part of a cycle.
+	reached := make(map[*FeatureNode]bool)
+	var visit func(node *FeatureNode, ancestors map[*FeatureNode]bool) error
+	visit = func(node *FeatureNode, ancestors map[*FeatureNode]bool) error {
+		if ancestors[node] {
+			return fmt.Errorf("%w: feature %q is its own ancestor", ErrInvalidFeature, node.Feature.Attributes["ID"])
+		}
+		reached[node] = true
+		ancestors[node] = true
+		for _, child := range node.Children {
+			if err := visit(child, ancestors); err != nil {
+				return err
+			}
+		}
+		delete(ancestors, node)
+		return nil
+	}
+	for _, root := range roots {
+		if err := visit(root, make(map[*FeatureNode]bool)); err != nil {
+			return nil, err
+		}
+	}
+	for _, node := range nodes {
+		if !reached[node] {
+			return nil, fmt.Errorf("%w: feature %q is its own ancestor", ErrInvalidFeature, node.Feature.Attributes["ID"])
+		}
+	}
+	return roots, nil
+}
+
+// GetCDSForTranscript returns the spliced coding sequence of a transcript,
+// like an mRNA, by joining the CDS features whose Parent is transcriptID in
+// order along their strand. The CDS phase isn't applied, so the sequence starts
+// at the first base of the first CDS part.
+func (sequence Gff) GetCDSForTranscript(transcriptID string) (string, error) {
+	var features []Feature
+	for _, feature := range sequence.Features {
+		if feature.Type != "CDS" {
+			continue
+		}
+		parents, err := feature.AttributeValues("Parent")
+		if err != nil {
+			return "", err
+		}
+		for _, parent := range parents {
+			if parent == transcriptID {
+				features = append(features, feature)
+				break
+			}
+		}
+	}
+	if len(features) == 0 {
+		return "", fmt.Errorf("no CDS features found for transcript %q", transcriptID)
+	}
+	codingSequence, err := joinCodingSequence(features)
+	if err != nil {
+		return "", fmt.Errorf("transcript %s: %w", transcriptID, err)
+	}
+	return codingSequence, nil
+}
+
+/******************************************************************************
+
+Feature hierarchy functions end here.
+
+******************************************************************************/
+
 // Parse Takes in a string representing a gffv3 file and parses it into an Sequence object.
 func Parse(file []byte) (Gff, error) {
-
-	gffString := string(file)
 	gff := Gff{}
 
+	parser := NewParser(bytes.NewReader(file))
+	for {
+		feature, err := parser.NextFeature()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Gff{}, err
+		}
+		if feature.Attributes["Is_circular"] == "true" {
+			gff.Meta.Circular = true
+		}
+		_ = gff.AddFeature(&feature)
+	}
+
+	lineWidth := 0
+	for {
+		record, err := parser.NextSequence()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Gff{}, err
+		}
+		if lineWidth == 0 {
+			lineWidth = parser.lineWidth
+		}
+		gff.Sequences = append(gff.Sequences, record)
+	}
+	if len(gff.Sequences) > 0 {
+		gff.Sequence = gff.Sequences[0].Sequence
+	}
+	if len(gff.Sequences) < 2 {
+		gff.Sequences = nil
+	}
+	circular := gff.Meta.Circular
+	gff.Meta = parser.Meta()
+	gff.Meta.LineWidth = lineWidth
+	gff.Meta.Circular = circular
+
 	// Add the CheckSum to sequence (blake3)
 	gff.Meta.CheckSum = blake3.Sum256(file)
 
-	lines := strings.Split(gffString, "\n")
-	metaString := lines[0:2]
-	versionString := metaString[0]
-	regionStringArray := strings.Split(metaString[1], " ")
+	return gff, nil
+}
+
+/******************************************************************************
 
-	// get name for general meta
-	meta := Meta{}
-	meta.Name = regionStringArray[1] // Formally region name, but changed to name here for generality/interoperability.
+Streaming parser begins here.
 
-	// get meta info only specific to GFF files
-	meta.Version = strings.Split(versionString, " ")[1]
-	meta.RegionStart, _ = strconv.Atoi(regionStringArray[2])
-	meta.RegionEnd, _ = strconv.Atoi(regionStringArray[3])
-	meta.Size = meta.RegionEnd - meta.RegionStart
+Ensembl and RefSeq gff files for large genomes run into the hundreds of
+megabytes so reading them into a single string before splitting on newlines
+isn't always an option. Parser reads one line at a time from an io.Reader so
+that features can be scanned without ever holding the whole file in memory.
 
-	var sequenceBuffer bytes.Buffer
-	fastaFlag := false
-	for _, line := range lines {
-		if line == "##FASTA" {
-			fastaFlag = true
-		} else if len(line) == 0 {
+******************************************************************************/
+
+// ErrInvalidFeature is wrapped by errors about feature lines that can't be parsed and features that can't be built.
+var ErrInvalidFeature = errors.New("invalid feature")
+
+// ErrInvalidDirective is wrapped by errors about ## directive lines that can't be parsed.
+var ErrInvalidDirective = errors.New("invalid directive")
+
+// ParseError is returned by Parse and Parser.NextFeature when a line can't be
+// read. Use errors.As to get the line number and errors.Is with ErrInvalidFeature
+// or ErrInvalidDirective to find out what was wrong with it.
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("gff: line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// maxLineSize is the longest line Parser will read. Some gff attributes carry
+// whole protein translations so this is much larger than bufio's default.
+const maxLineSize = 16 * 1024 * 1024
+
+// Parser is a streaming gff parser that reads features one at a time from an io.Reader.
+type Parser struct {
+	scanner    *bufio.Scanner
+	meta       Meta
+	lineNumber int
+	fasta      bool
+	// the name of the next sequence in the ##FASTA section, once its header line has been read.
+	nextName      string
+	pendingHeader bool
+	lineWidth     int // the length of the first line of the last sequence NextSequence read.
+}
+
+// NewParser returns a Parser that reads gff formatted data from reader.
+func NewParser(reader io.Reader) *Parser {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	// files without a ##gff-version directive are assumed to be GFF3.
+	return &Parser{scanner: scanner, meta: Meta{Version: "3"}}
+}
+
+// Meta returns the meta information gathered from the directives the Parser has read so far.
+func (parser *Parser) Meta() Meta {
+	return parser.meta
+}
+
+// NextFeature returns the next feature in the gff file. Directives found along
+// the way are used to fill in the Parser's Meta. Once the end of the file or a
+// ##FASTA directive is reached NextFeature returns io.EOF, and any sequences
+// can then be read with NextSequence.
+func (parser *Parser) NextFeature() (Feature, error) {
+	if parser.fasta {
+		return Feature{}, io.EOF
+	}
+	for parser.scanner.Scan() {
+		parser.lineNumber++
+		line := strings.TrimRight(parser.scanner.Text(), "\r")
+		if parser.lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\uFEFF") // byte order mark
+		}
+		switch {
+		case len(strings.TrimSpace(line)) == 0:
 			continue
-		} else if line[0:2] == "##" {
+		case line == "##FASTA":
+			parser.fasta = true
+			return Feature{}, io.EOF
+		case strings.HasPrefix(strings.TrimSpace(line), "##"):
+			if err := parser.parseDirective(strings.TrimSpace(line)); err != nil {
+				return Feature{}, &ParseError{parser.lineNumber, err}
+			}
+		case strings.HasPrefix(line, "#"):
+			parser.meta.Directives = append(parser.meta.Directives, line)
+		default:
+			feature, err := parseFeature(line)
+			if err != nil {
+				return Feature{}, &ParseError{parser.lineNumber, err}
+			}
+			return feature, nil
+		}
+	}
+	if err := parser.scanner.Err(); err != nil {
+		return Feature{}, &ParseError{parser.lineNumber + 1, err}
+	}
+	return Feature{}, io.EOF
+}
+
+// parseDirective fills in the Parser's Meta from a ## directive line.
+func (parser *Parser) parseDirective(line string) error {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case "##gff-version":
+		if len(fields) > 1 {
+			parser.meta.Version = fields[1]
+		}
+	case "##sequence-region":
+		var region SequenceRegion
+		if len(fields) > 1 {
+			region.Name = fields[1]
+		}
+		if len(fields) > 3 {
+			regionStart, startErr := strconv.Atoi(fields[2])
+			regionEnd, endErr := strconv.Atoi(fields[3])
+			if startErr != nil || endErr != nil {
+				return fmt.Errorf("%w: sequence-region start %q and end %q must be integers", ErrInvalidDirective, fields[2], fields[3])
+			}
+			region.Start, region.End = regionStart, regionEnd
+		}
+		// files annotating several sequences have a sequence-region for each of them, the first is used as the Gff's own.
+		if len(parser.meta.SequenceRegions) == 0 {
+			parser.meta.Name = region.Name // Formally region name, but changed to name here for generality/interoperability.
+			parser.meta.RegionStart, parser.meta.RegionEnd = region.Start, region.End
+			parser.meta.Size = region.End - region.Start
+		}
+		parser.meta.SequenceRegions = append(parser.meta.SequenceRegions, region)
+	case "###":
+		// only marks that all forward references so far have been resolved so there's nothing to keep.
+	default:
+		parser.meta.Directives = append(parser.meta.Directives, line)
+	}
+	return nil
+}
+
+// NextSequence returns the next sequence from the ##FASTA section at the end of
+// the gff file, named by its > header line. It can only be called once
+// NextFeature has returned io.EOF, and it returns io.EOF itself once every
+// sequence has been read. Sequence without a header before it is named after
+// the Meta's Name.
+func (parser *Parser) NextSequence() (fasta.Fasta, error) {
+	if !parser.fasta {
+		return fasta.Fasta{}, io.EOF
+	}
+	record := fasta.Fasta{Name: parser.meta.Name}
+	found := parser.pendingHeader
+	if parser.pendingHeader {
+		record.Name = parser.nextName
+		parser.pendingHeader = false
+	}
+	parser.lineWidth = 0
+
+	var sequenceBuffer bytes.Buffer
+	for parser.scanner.Scan() {
+		parser.lineNumber++
+		line := strings.TrimRight(parser.scanner.Text(), "\r")
+		if len(line) == 0 || strings.HasPrefix(line, "##") {
 			continue
-		} else if fastaFlag && line[0:1] != ">" {
-			// sequence.Sequence = sequence.Sequence + line
-			sequenceBuffer.WriteString(line)
-		} else if fastaFlag && line[0:1] == ">" {
-			gff.Meta.Description = line
-		} else {
-			record := Feature{}
-			fields := strings.Split(line, "\t")
-			record.Name = fields[0]
-			record.Source = fields[1]
-			record.Type = fields[2]
-
-			// Indexing starts at 1 for gff so we need to shift down for Sequence 0 index.
-			record.Location.Start, _ = strconv.Atoi(fields[3])
-			record.Location.Start--
-			record.Location.End, _ = strconv.Atoi(fields[4])
-
-			record.Score = fields[5]
-			record.Strand = fields[6]
-			record.Phase = fields[7]
-			record.Attributes = make(map[string]string)
-			attributes := fields[8]
-			// var eqIndex int
-			attributeSlice := strings.Split(attributes, ";")
-
-			for _, attribute := range attributeSlice {
-				attributeSplit := strings.Split(attribute, "=")
-				key := attributeSplit[0]
-				value := attributeSplit[1]
-				record.Attributes[key] = value
+		}
+		if line[0] == '>' {
+			name := strings.TrimSpace(line[1:])
+			// a header after sequence starts the next record, which is kept for the next call.
+			if found {
+				parser.nextName, parser.pendingHeader = name, true
+				record.Sequence = sequenceBuffer.String()
+				return record, nil
 			}
-			_ = gff.AddFeature(&record)
+			record.Name = name
+			found = true
+			continue
+		}
+		found = true
+		if parser.lineWidth == 0 {
+			parser.lineWidth = len(line)
 		}
+		sequenceBuffer.WriteString(line)
 	}
-	gff.Sequence = sequenceBuffer.String()
-	gff.Meta = meta
+	if err := parser.scanner.Err(); err != nil {
+		return fasta.Fasta{}, &ParseError{parser.lineNumber + 1, err}
+	}
+	parser.fasta = false
+	if !found {
+		return fasta.Fasta{}, io.EOF
+	}
+	record.Sequence = sequenceBuffer.String()
+	return record, nil
+}
 
-	return gff, nil
+// parseFeature parses a single tab separated feature line.
+func parseFeature(line string) (Feature, error) {
+	record := Feature{}
+	fields := strings.Split(line, "\t")
+	if len(fields) < 9 {
+		return record, fmt.Errorf("%w: expected 9 tab separated fields, got %d", ErrInvalidFeature, len(fields))
+	}
+	record.Name = fields[0]
+	record.Source = fields[1]
+	record.Type = fields[2]
+
+	// Indexing starts at 1 for gff so we need to shift down for Sequence 0 index.
+	// Some feature types have no meaningful coordinates and use "." instead.
+	var err error
+	if fields[3] == "." {
+		record.Location.UndefinedStart = true
+	} else if record.Location.Start, err = strconv.Atoi(fields[3]); err != nil {
+		return record, fmt.Errorf("%w: start %q is not a number", ErrInvalidFeature, fields[3])
+	} else {
+		record.Location.Start--
+	}
+	if fields[4] == "." {
+		record.Location.UndefinedEnd = true
+	} else if record.Location.End, err = strconv.Atoi(fields[4]); err != nil {
+		return record, fmt.Errorf("%w: end %q is not a number", ErrInvalidFeature, fields[4])
+	}
+
+	record.Score = fields[5]
+	record.Strand = fields[6]
+	record.Phase = fields[7]
+	record.Attributes = make(map[string]string)
+	attributes := fields[8]
+	attributeSlice := strings.Split(attributes, ";")
+
+	for _, attribute := range attributeSlice {
+		// some tools write "; " between attributes, which isn't part of the key.
+		attribute = strings.TrimSpace(attribute)
+		if attribute == "" {
+			continue
+		}
+		attributeSplit := strings.SplitN(attribute, "=", 2)
+		key := attributeSplit[0]
+		var value string
+		if len(attributeSplit) > 1 {
+			value = attributeSplit[1]
+		}
+		if _, ok := record.Attributes[key]; !ok {
+			record.AttributeOrder = append(record.AttributeOrder, key)
+		}
+		record.Attributes[key] = value
+	}
+
+	// NCBI marks features that run off the edge of a sequence with a "." in
+	// their start_range or end_range attributes.
+	record.Location.FivePrimePartial = strings.HasPrefix(record.Attributes["start_range"], ".,")
+	record.Location.ThreePrimePartial = strings.HasSuffix(record.Attributes["end_range"], ",.")
+	return record, nil
+}
+
+// attributeKeys returns the keys of a feature's attributes in the order Build
+// should write them. Keys are written in AttributeOrder first, then any keys
+// missing from it follow with ID and Parent first (per GFF3 convention) and
+// the rest sorted alphabetically.
+func attributeKeys(feature Feature) []string {
+	keys := make([]string, 0, len(feature.Attributes))
+	written := make(map[string]bool)
+	for _, key := range feature.AttributeOrder {
+		if _, ok := feature.Attributes[key]; ok && !written[key] {
+			keys = append(keys, key)
+			written[key] = true
+		}
+	}
+
+	var unorderedKeys []string
+	for key := range feature.Attributes {
+		if !written[key] {
+			unorderedKeys = append(unorderedKeys, key)
+		}
+	}
+	priority := map[string]int{"ID": 0, "Parent": 1}
+	sort.Slice(unorderedKeys, func(i, j int) bool {
+		iPriority, iOk := priority[unorderedKeys[i]]
+		jPriority, jOk := priority[unorderedKeys[j]]
+		switch {
+		case iOk && jOk:
+			return iPriority < jPriority
+		case iOk != jOk:
+			return iOk
+		}
+		return unorderedKeys[i] < unorderedKeys[j]
+	})
+	return append(keys, unorderedKeys...)
+}
+
+// buildStrand checks that a strand is one of the values allowed by GFF3. An empty strand is written as "." (unstranded).
+func buildStrand(strand string) (string, error) {
+	switch strand {
+	case "":
+		return ".", nil
+	case "+", "-", ".", "?":
+		return strand, nil
+	}
+	return "", fmt.Errorf("%w: strand %q must be one of + - . ?", ErrInvalidFeature, strand)
+}
+
+// buildPhase checks that a phase is one of the values allowed by GFF3. CDS features must have a phase,
+// other features with an empty phase are written as "." (no phase).
+func buildPhase(featureType, phase string) (string, error) {
+	switch phase {
+	case "", ".":
+		if featureType == "CDS" {
+			return "", fmt.Errorf("%w: CDS features require a phase of 0, 1 or 2", ErrInvalidFeature)
+		}
+		return ".", nil
+	case "0", "1", "2":
+		return phase, nil
+	}
+	return "", fmt.Errorf("%w: phase %q must be one of 0 1 2 .", ErrInvalidFeature, phase)
 }
 
 // Build takes an Annotated sequence and returns a byte array representing a gff to be written out.
@@ -214,9 +1347,21 @@ func Build(sequence Gff) ([]byte, error) {
 	end = strconv.Itoa(sequence.Meta.RegionEnd)
 
 	regionString = "##sequence-region " + name + " " + start + " " + end + "\n"
+	// files annotating several sequences get a sequence-region for each of them.
+	if len(sequence.Meta.SequenceRegions) > 1 {
+		regionString = ""
+		for _, region := range sequence.Meta.SequenceRegions {
+			regionString += "##sequence-region " + region.Name + " " + strconv.Itoa(region.Start) + " " + strconv.Itoa(region.End) + "\n"
+		}
+	}
 	gffBuffer.WriteString(regionString)
 
-	for _, feature := range sequence.Features {
+	for _, directive := range sequence.Meta.Directives {
+		gffBuffer.WriteString(directive)
+		gffBuffer.WriteString("\n")
+	}
+
+	for featureIndex, feature := range sequence.Features {
 		var featureString string
 		var featureSource string
 		if feature.Source != "" {
@@ -234,20 +1379,26 @@ func Build(sequence Gff) ([]byte, error) {
 
 		// Indexing starts at 1 for gff so we need to shift up from Sequence 0 index.
 		featureStart := strconv.Itoa(feature.Location.Start + 1)
+		if feature.Location.UndefinedStart {
+			featureStart = "."
+		}
 		featureEnd := strconv.Itoa(feature.Location.End)
+		if feature.Location.UndefinedEnd {
+			featureEnd = "."
+		}
 
 		featureScore := feature.Score
-		featureStrand := string(feature.Strand)
-		featurePhase := feature.Phase
-		var featureAttributes string
-
-		keys := make([]string, 0, len(feature.Attributes))
-		for key := range feature.Attributes {
-			keys = append(keys, key)
+		featureStrand, err := buildStrand(feature.Strand)
+		if err != nil {
+			return nil, fmt.Errorf("gff: feature %d: %w", featureIndex, err)
+		}
+		featurePhase, err := buildPhase(featureType, feature.Phase)
+		if err != nil {
+			return nil, fmt.Errorf("gff: feature %d: %w", featureIndex, err)
 		}
-		sort.Strings(keys)
+		var featureAttributes string
 
-		for _, key := range keys {
+		for _, key := range attributeKeys(feature) {
 			attributeString := key + "=" + feature.Attributes[key] + ";"
 			featureAttributes += attributeString
 		}
@@ -262,24 +1413,35 @@ func Build(sequence Gff) ([]byte, error) {
 
 	gffBuffer.WriteString("###\n")
 	gffBuffer.WriteString("##FASTA\n")
-	gffBuffer.WriteString(">" + sequence.Meta.Name + "\n")
 
-	for letterIndex, letter := range sequence.Sequence {
-		letterIndex++
-		if letterIndex%70 == 0 && letterIndex != 0 && letterIndex != sequence.Meta.RegionEnd {
-			gffBuffer.WriteRune(letter)
+	records := sequence.Sequences
+	if len(records) == 0 {
+		records = []fasta.Fasta{{Name: sequence.Meta.Name, Sequence: sequence.Sequence}}
+	}
+	lineWidth := sequence.Meta.LineWidth
+	if lineWidth <= 0 {
+		lineWidth = DefaultLineWidth
+	}
+	for _, record := range records {
+		gffBuffer.WriteString(">" + record.Name + "\n")
+		for lineStart := 0; lineStart < len(record.Sequence); lineStart += lineWidth {
+			lineEnd := lineStart + lineWidth
+			if lineEnd > len(record.Sequence) {
+				lineEnd = len(record.Sequence)
+			}
+			gffBuffer.WriteString(record.Sequence[lineStart:lineEnd])
 			gffBuffer.WriteString("\n")
-		} else {
-			gffBuffer.WriteRune(letter)
 		}
 	}
-	gffBuffer.WriteString("\n")
 	return gffBuffer.Bytes(), nil
 }
 
-// Read takes in a filepath for a .gffv3 file and parses it into an Annotated poly.Sequence struct.
+// Read takes in a filepath for a .gffv3 file and parses it into an Annotated poly.Sequence struct. Gzip and bzip2 compressed files are detected and decompressed automatically.
 func Read(path string) (Gff, error) {
-	file, _ := ioutil.ReadFile(path)
+	file, err := compress.ReadFile(path)
+	if err != nil {
+		return Gff{}, err
+	}
 	sequence, err := Parse(file)
 	if err != nil {
 		return Gff{}, err
@@ -287,6 +1449,41 @@ func Read(path string) (Gff, error) {
 	return sequence, nil
 }
 
+// ParseJSON parses a Gff struct from JSON, like the output of WriteJSON, and
+// points every feature's ParentSequence back at it so GetSequence keeps working.
+func ParseJSON(file []byte) (Gff, error) {
+	var sequence Gff
+	if err := json.Unmarshal(file, &sequence); err != nil {
+		return Gff{}, err
+	}
+	legacyFeatures := sequence.Features
+	sequence.Features = []Feature{}
+
+	for _, feature := range legacyFeatures {
+		_ = sequence.AddFeature(&feature)
+	}
+	return sequence, nil
+}
+
+// ReadJSON reads a Gff JSON file.
+func ReadJSON(path string) (Gff, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Gff{}, err
+	}
+	return ParseJSON(file)
+}
+
+// WriteJSON writes a Gff struct out to JSON. This is much faster to read back
+// than the original gff file, so it's handy for caching large parsed files.
+func WriteJSON(sequence Gff, path string) error {
+	file, err := json.MarshalIndent(sequence, "", " ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, file, 0644)
+}
+
 // Write takes an poly.Sequence struct and a path string and writes out a gff to that path.
 func Write(sequence Gff, path string) error {
 	gff, err := Build(sequence)
@@ -1,13 +1,21 @@
 package gff_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/TimothyStiles/poly/io/gff"
+	"github.com/TimothyStiles/poly/synthesis/codon"
+	"github.com/TimothyStiles/poly/transform"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/pmezard/go-difflib/difflib"
@@ -99,6 +107,647 @@ func ExampleWrite() {
 	// Output: U00096.3
 }
 
+func ExampleParser_NextFeature() {
+	file, _ := os.Open("../../data/ecoli-mg1655-short.gff")
+	defer file.Close()
+	parser := gff.NewParser(file)
+
+	var geneCount int
+	for {
+		feature, err := parser.NextFeature()
+		if err != nil {
+			break // io.EOF once the features or file run out.
+		}
+		if feature.Type == "gene" {
+			geneCount++
+		}
+	}
+
+	fmt.Println(parser.Meta().Name, geneCount)
+	// Output: U00096.3 5
+}
+
+func TestParser(t *testing.T) {
+	testSequence, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+
+	file, err := os.Open("../../data/ecoli-mg1655-short.gff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	parser := gff.NewParser(file)
+	var features []gff.Feature
+	for {
+		feature, err := parser.NextFeature()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		features = append(features, feature)
+	}
+
+	if diff := cmp.Diff(testSequence.Features, features, cmpopts.IgnoreFields(gff.Feature{}, "ParentSequence")); diff != "" {
+		t.Errorf("Parser features differ from Parse. Got this diff:\n%s", diff)
+	}
+	// the line width is only known once the ##FASTA section has been read.
+	if diff := cmp.Diff(testSequence.Meta, parser.Meta(), cmpopts.IgnoreFields(gff.Meta{}, "CheckSum", "LineWidth")); diff != "" {
+		t.Errorf("Parser meta differs from Parse. Got this diff:\n%s", diff)
+	}
+
+	// once the ##FASTA directive is reached the parser should keep returning io.EOF.
+	if _, err := parser.NextFeature(); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last feature, got %v", err)
+	}
+}
+
+func TestMultipleSequences(t *testing.T) {
+	file := "##gff-version 3\n" +
+		"##sequence-region chr1 1 12\n" +
+		"##sequence-region chr2 1 8\n" +
+		"chr1\t.\tgene\t1\t6\t.\t+\t.\tID=gene1\n" +
+		"###\n" +
+		"chr2\t.\tgene\t3\t8\t.\t-\t.\tID=gene2\n" +
+		"###\n" +
+		"##FASTA\n" +
+		">chr1\nATGAAA\nCCCGGG\n" +
+		">chr2 second chromosome\nTTTTCATG\n"
+
+	parser := gff.NewParser(strings.NewReader(file))
+	for {
+		if _, err := parser.NextFeature(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var names []string
+	for {
+		record, err := parser.NextSequence()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, record.Name)
+	}
+	if diff := cmp.Diff([]string{"chr1", "chr2 second chromosome"}, names); diff != "" {
+		t.Errorf("unexpected sequence names (-want +got):\n%s", diff)
+	}
+	expectedRegions := []gff.SequenceRegion{{Name: "chr1", Start: 1, End: 12}, {Name: "chr2", Start: 1, End: 8}}
+	if diff := cmp.Diff(expectedRegions, parser.Meta().SequenceRegions); diff != "" {
+		t.Errorf("unexpected sequence regions (-want +got):\n%s", diff)
+	}
+
+	sequence, err := gff.Parse([]byte(strings.Replace(file, "chr2 second chromosome", "chr2", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sequence.Meta.Name != "chr1" || sequence.Meta.RegionEnd != 12 {
+		t.Errorf("expected the first sequence-region to name the Gff, got %s ending at %d", sequence.Meta.Name, sequence.Meta.RegionEnd)
+	}
+	if sequence.Sequence != "ATGAAACCCGGG" || len(sequence.Sequences) != 2 {
+		t.Fatalf("expected chr1 as the Gff's sequence and both chromosomes in Sequences, got %s and %d sequences", sequence.Sequence, len(sequence.Sequences))
+	}
+	// features get their sequence from the chromosome they annotate.
+	for index, expected := range []string{"ATGAAA", "CATGAA"} {
+		featureSequence, err := sequence.Features[index].GetSequence()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sequence.Features[index].Strand == "-" {
+			featureSequence = transform.ReverseComplement(featureSequence)
+		}
+		if featureSequence != expected {
+			t.Errorf("expected feature %d to have sequence %s, got %s", index, expected, featureSequence)
+		}
+	}
+
+	output, err := gff.Build(sequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := gff.Parse(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(sequence, reparsed, cmpopts.IgnoreFields(gff.Feature{}, "ParentSequence"), cmpopts.IgnoreFields(gff.Meta{}, "CheckSum")); diff != "" {
+		t.Errorf("multiple sequences did not round trip through Build. Got this diff:\n%s", diff)
+	}
+}
+
+func TestDirectivesRoundTrip(t *testing.T) {
+	file := "##gff-version 3\n" +
+		"##sequence-region ctg123 1 10000\n" +
+		"##feature-ontology http://song.cvs.sourceforge.net/*checkout*/song/ontology/sofa.obo?revision=1.217\n" +
+		"##species https://www.ncbi.nlm.nih.gov/Taxonomy/Browser/wwwtax.cgi?id=9606\n" +
+		"# generated by our annotation pipeline\n" +
+		"ctg123\t.\tgene\t1000\t9000\t.\t+\t.\tID=gene00001\n" +
+		"###\n" +
+		"#!processor NCBI annotwriter\n" +
+		"ctg123\t.\tgene\t9100\t9900\t.\t+\t.\tID=gene00002\n"
+	sequence, err := gff.Parse([]byte(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"##feature-ontology http://song.cvs.sourceforge.net/*checkout*/song/ontology/sofa.obo?revision=1.217",
+		"##species https://www.ncbi.nlm.nih.gov/Taxonomy/Browser/wwwtax.cgi?id=9606",
+		"# generated by our annotation pipeline",
+		"#!processor NCBI annotwriter",
+	}
+	if diff := cmp.Diff(expected, sequence.Meta.Directives); diff != "" {
+		t.Errorf("Parse did not keep directives and comments in order. Got this diff:\n%s", diff)
+	}
+
+	built, err := gff.Build(sequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(built), "##gff-version 3\n##sequence-region ctg123 1 10000\n"+strings.Join(expected, "\n")+"\n") {
+		t.Errorf("Build did not write directives after the header. Got:\n%s", built)
+	}
+	reparsed, _ := gff.Parse(built)
+	if diff := cmp.Diff(sequence.Meta.Directives, reparsed.Meta.Directives); diff != "" {
+		t.Errorf("directives changed on a round trip. Got this diff:\n%s", diff)
+	}
+}
+
+func TestUndefinedCoordinates(t *testing.T) {
+	file := "##gff-version 3\n" +
+		"ctg123\t.\tgene\t3\t8\t.\t+\t.\tID=gene1\n" +
+		"ctg123\t.\tsequence_variant\t.\t.\t.\t+\t.\tID=variant1\n" +
+		"ctg123\t.\tmRNA\t3\t.\t.\t+\t.\tID=mRNA1\n" +
+		"##FASTA\n>ctg123\nAAATGCATGAAA\n"
+	sequence, err := gff.Parse([]byte(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	variant := sequence.Features[1].Location
+	if !variant.UndefinedStart || !variant.UndefinedEnd {
+		t.Errorf("expected an undefined start and end, got %+v", variant)
+	}
+	mRNA := sequence.Features[2].Location
+	if mRNA.UndefinedStart || !mRNA.UndefinedEnd || mRNA.Start != 2 {
+		t.Errorf("expected a start of 2 and an undefined end, got %+v", mRNA)
+	}
+
+	if _, err := sequence.Features[0].GetSequence(); err != nil {
+		t.Errorf("unexpected error for a defined feature: %s", err)
+	}
+	for _, feature := range sequence.Features[1:] {
+		if _, err := feature.GetSequence(); err == nil {
+			t.Errorf("expected an error getting the sequence of %s", feature.Attributes["ID"])
+		}
+	}
+
+	built, _ := gff.Build(sequence)
+	for _, line := range []string{"ctg123\t.\tsequence_variant\t.\t.\t", "ctg123\t.\tmRNA\t3\t.\t"} {
+		if !strings.Contains(string(built), line) {
+			t.Errorf("Build did not write undefined coordinates as \".\". Got:\n%s", built)
+		}
+	}
+}
+
+func TestParseVersionDirective(t *testing.T) {
+	feature := "ctg123\t.\tgene\t1000\t9000\t.\t+\t.\tID=gene00001\n"
+	versions := map[string]string{
+		"first line":          "##gff-version 3\n" + feature,
+		"after other pragmas": "# AUGUSTUS output\n##sequence-region ctg123 1 10000\n##gff-version 3.1.26\n" + feature,
+		"extra whitespace":    "  ##gff-version\t  2 \n" + feature,
+		"byte order mark":     "\uFEFF##gff-version 3\n" + feature,
+		"missing":             feature,
+		"missing value":       "##gff-version\n" + feature,
+	}
+	expected := map[string]string{
+		"first line":          "3",
+		"after other pragmas": "3.1.26",
+		"extra whitespace":    "2",
+		"byte order mark":     "3",
+		"missing":             "3",
+		"missing value":       "3",
+	}
+	for name, file := range versions {
+		sequence, err := gff.Parse([]byte(file))
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		if sequence.Meta.Version != expected[name] {
+			t.Errorf("%s: expected version %q, got %q", name, expected[name], sequence.Meta.Version)
+		}
+		if len(sequence.Features) != 1 {
+			t.Errorf("%s: expected 1 feature, got %d", name, len(sequence.Features))
+		}
+	}
+}
+
+func TestParseMalformedFeature(t *testing.T) {
+	malformed := "##gff-version 3\n##sequence-region test 1 10\ntest\tfeature\tgene\tone\t10\t.\t+\t.\tID=gene1\n"
+	if _, err := gff.Parse([]byte(malformed)); err == nil {
+		t.Errorf("Expected an error for a feature with a non-numeric start")
+	}
+
+	truncated := "##gff-version 3\ntest\tfeature\tgene\n"
+	if _, err := gff.Parse([]byte(truncated)); err == nil {
+		t.Errorf("Expected an error for a feature with missing fields")
+	}
+}
+
+func TestSoftMaskedRoundTrip(t *testing.T) {
+	// lowercase soft-masking marks repeats and must survive a round trip.
+	softMasked := "##gff-version 3\n##sequence-region chr1 1 16\nchr1\trepeatmasker\trepeat_region\t5\t12\t.\t+\t.\tID=repeat1\n##FASTA\n>chr1\nACGTacgtacgtACGT\n"
+	sequence, _ := gff.Parse([]byte(softMasked))
+	if sequence.Sequence != "ACGTacgtacgtACGT" {
+		t.Errorf("Parse did not preserve soft-masking. Got %q", sequence.Sequence)
+	}
+
+	built, _ := gff.Build(sequence)
+	reparsed, _ := gff.Parse(built)
+	if reparsed.Sequence != sequence.Sequence {
+		t.Errorf("Build did not preserve soft-masking. Got %q", reparsed.Sequence)
+	}
+
+	repeat, _ := reparsed.Features[0].GetSequence()
+	if repeat != "acgtacgt" {
+		t.Errorf("Feature sequence lost its soft-masking. Got %q", repeat)
+	}
+}
+
+func TestReadGzipped(t *testing.T) {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	// gzip the test file so we can check Read decompresses it transparently.
+	file, _ := ioutil.ReadFile("../../data/ecoli-mg1655-short.gff")
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, _ = gzipWriter.Write(file)
+	_ = gzipWriter.Close()
+	tmpGzipFilePath := filepath.Join(tmpDataDir, "ecoli-mg1655-short.gff.gz")
+	_ = ioutil.WriteFile(tmpGzipFilePath, gzipped.Bytes(), 0644)
+
+	expected, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+	got, err := gff.Read(tmpGzipFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, got, cmpopts.IgnoreFields(gff.Feature{}, "ParentSequence")); diff != "" {
+		t.Errorf("Reading a gzipped file does not give the same result as the plain file. Got this diff:\n%s", diff)
+	}
+}
+
+func TestReadBzip2(t *testing.T) {
+	expected, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+	got, err := gff.Read("../../data/ecoli-mg1655-short.gff.bz2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, got, cmpopts.IgnoreFields(gff.Feature{}, "ParentSequence")); diff != "" {
+		t.Errorf("Reading a bzip2 compressed file does not give the same result as the plain file. Got this diff:\n%s", diff)
+	}
+}
+
+func TestAttributeOrder(t *testing.T) {
+	// attributes should be written back in the order they were read, even if it isn't alphabetical.
+	file := "##gff-version 3\n##sequence-region ctg123 1 1497228\nctg123\t.\tmRNA\t1050\t9000\t.\t+\t.\tID=mRNA00001;Parent=gene00001;Name=EDEN.1;Alias=eden\n"
+	sequence, _ := gff.Parse([]byte(file))
+	output, _ := gff.Build(sequence)
+	if !strings.Contains(string(output), "\tID=mRNA00001;Parent=gene00001;Name=EDEN.1;Alias=eden\n") {
+		t.Errorf("Build did not preserve attribute order. Got:\n%s", output)
+	}
+
+	// new attributes are written after the original ones with ID and Parent first.
+	feature := gff.Feature{Type: "exon", Attributes: map[string]string{"Name": "exon1", "Parent": "mRNA00001", "ID": "exon00001", "Alias": "e1"}}
+	output, _ = gff.Build(gff.Gff{Features: []gff.Feature{feature}})
+	if !strings.Contains(string(output), "\tID=exon00001;Parent=mRNA00001;Alias=e1;Name=exon1\n") {
+		t.Errorf("Build did not put ID and Parent first. Got:\n%s", output)
+	}
+
+	feature.AttributeOrder = []string{"Name", "Missing"}
+	output, _ = gff.Build(gff.Gff{Features: []gff.Feature{feature}})
+	if !strings.Contains(string(output), "\tName=exon1;ID=exon00001;Parent=mRNA00001;Alias=e1\n") {
+		t.Errorf("Build did not write ordered attributes first. Got:\n%s", output)
+	}
+}
+
+func TestAttributeValues(t *testing.T) {
+	attributes := "ID=gene00001; Note=foo%3Bbar,second note;Alias=a%2Cb,c;Dbxref=GenBank:AAA%3D1;Is_circular"
+	file := "##gff-version 3\nctg123\t.\tgene\t1000\t9000\t.\t+\t.\t" + attributes + "\n"
+	sequence, err := gff.Parse([]byte(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	feature := sequence.Features[0]
+
+	tests := map[string][]string{
+		"ID":          {"gene00001"},
+		"Note":        {"foo;bar", "second note"},
+		"Alias":       {"a,b", "c"},
+		"Dbxref":      {"GenBank:AAA=1"},
+		"Is_circular": {""},
+		"missing":     nil,
+	}
+	for key, expected := range tests {
+		values, err := feature.AttributeValues(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, values); diff != "" {
+			t.Errorf("unexpected %s values (-want +got):\n%s", key, diff)
+		}
+	}
+
+	// raw values are kept so Build writes them back untouched.
+	if feature.Attributes["Note"] != "foo%3Bbar,second note" {
+		t.Errorf("expected the raw Note value to be kept, got %q", feature.Attributes["Note"])
+	}
+	output, _ := gff.Build(sequence)
+	if !strings.Contains(string(output), "Note=foo%3Bbar,second note;") {
+		t.Errorf("Build did not write the encoded Note back. Got:\n%s", output)
+	}
+
+	feature.SetAttribute("Note", "50% done; see=docs", "tab\there")
+	feature.SetAttribute("Ontology_term", "GO:0046703", "GO:0005634")
+	expectedNote := "50%25 done%3B see%3Ddocs,tab%09here"
+	if feature.Attributes["Note"] != expectedNote {
+		t.Errorf("expected the Note to be encoded as %q, got %q", expectedNote, feature.Attributes["Note"])
+	}
+	if last := feature.AttributeOrder[len(feature.AttributeOrder)-1]; last != "Ontology_term" {
+		t.Errorf("expected new attributes to be written last, got %s last", last)
+	}
+	values, _ := feature.AttributeValues("Note")
+	if diff := cmp.Diff([]string{"50% done; see=docs", "tab\there"}, values); diff != "" {
+		t.Errorf("SetAttribute values don't round trip (-want +got):\n%s", diff)
+	}
+
+	sequence.Features[0] = feature
+	output, _ = gff.Build(sequence)
+	reparsed, err := gff.Parse(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, _ = reparsed.Features[0].AttributeValues("Ontology_term")
+	if diff := cmp.Diff([]string{"GO:0046703", "GO:0005634"}, values); diff != "" {
+		t.Errorf("Ontology_term values don't survive Build (-want +got):\n%s", diff)
+	}
+
+	var empty gff.Feature
+	empty.SetAttribute("ID", "new")
+	if empty.Attributes["ID"] != "new" {
+		t.Errorf("expected SetAttribute to work on a feature without attributes, got %v", empty.Attributes)
+	}
+	if _, err := (gff.Feature{Attributes: map[string]string{"Note": "100%"}}).AttributeValues("Note"); err == nil {
+		t.Error("expected an error decoding a malformed percent-encoding")
+	}
+}
+
+func TestFeatureTree(t *testing.T) {
+	sequence := "AAAAATGCCCAAAAAGGGTTTAAAAATAAAAA"
+	file := "##gff-version 3\n##sequence-region ctg 1 32\n" +
+		"ctg\t.\tgene\t1\t32\t.\t+\t.\tID=gene1\n" +
+		"ctg\t.\tmRNA\t1\t32\t.\t+\t.\tID=mRNA1;Parent=gene1\n" +
+		"ctg\t.\tmRNA\t1\t32\t.\t+\t.\tID=mRNA2;Parent=gene1\n" +
+		"ctg\t.\texon\t1\t32\t.\t+\t.\tID=exon1;Parent=mRNA1,mRNA2\n" +
+		"ctg\t.\tCDS\t16\t21\t.\t+\t0\tID=cds1;Parent=mRNA1\n" +
+		"ctg\t.\tCDS\t5\t10\t.\t+\t0\tID=cds1;Parent=mRNA1\n" +
+		"ctg\t.\tCDS\t27\t29\t.\t+\t0\tID=cds1;Parent=mRNA1\n" +
+		"ctg\t.\tCDS\t5\t10\t.\t+\t0\tID=cds2;Parent=mRNA2\n" +
+		"ctg\t.\tgene\t1\t3\t.\t-\t.\tID=orphan;Parent=missing\n" +
+		"##FASTA\n>ctg\n" + sequence + "\n"
+	gffSequence, err := gff.Parse([]byte(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots, err := gffSequence.FeatureTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 2 || roots[0].Feature.Attributes["ID"] != "gene1" || roots[1].Feature.Attributes["ID"] != "orphan" {
+		t.Fatalf("expected gene1 and orphan at the top level, got %d roots", len(roots))
+	}
+	mRNAs := roots[0].Children
+	if len(mRNAs) != 2 {
+		t.Fatalf("expected 2 mRNAs, got %d", len(mRNAs))
+	}
+	// exon1 has two parents so it's a child of both mRNAs.
+	for _, mRNA := range mRNAs {
+		if mRNA.Children[0].Feature.Attributes["ID"] != "exon1" {
+			t.Errorf("expected exon1 to be the first child of %s", mRNA.Feature.Attributes["ID"])
+		}
+	}
+	if len(mRNAs[0].Children) != 4 {
+		t.Errorf("expected mRNA1 to have an exon and 3 CDS parts, got %d children", len(mRNAs[0].Children))
+	}
+
+	cds, err := gffSequence.GetCDSForTranscript("mRNA1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cds != "ATGCCCGGGTTTTAA" {
+		t.Errorf("expected spliced CDS ATGCCCGGGTTTTAA, got %s", cds)
+	}
+	if _, err := gffSequence.GetCDSForTranscript("gene1"); err == nil {
+		t.Error("expected an error for a transcript without CDS features")
+	}
+
+	cycle := "##gff-version 3\n" +
+		"ctg\t.\tgene\t1\t32\t.\t+\t.\tID=a;Parent=b\n" +
+		"ctg\t.\tmRNA\t1\t32\t.\t+\t.\tID=b;Parent=a\n"
+	cycleSequence, _ := gff.Parse([]byte(cycle))
+	if _, err := cycleSequence.FeatureTree(); !errors.Is(err, gff.ErrInvalidFeature) {
+		t.Errorf("expected an invalid feature error for a cycle, got %v", err)
+	}
+}
+
+func TestOriginSpanningFeature(t *testing.T) {
+	sequence := "ATGCATGCAAAAAAAAAAAAAAAAAAAAAAAATTTT"
+	file := "##gff-version 3\n##sequence-region plasmid 1 36\n" +
+		"plasmid\t.\tregion\t1\t36\t.\t+\t.\tID=plasmid;Is_circular=true\n" +
+		"plasmid\t.\tgene\t33\t44\t.\t+\t.\tID=wrapping\n" +
+		"##FASTA\n>plasmid\n" + sequence + "\n"
+	gffSequence, err := gff.Parse([]byte(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gffSequence.Meta.Circular {
+		t.Fatalf("Is_circular=true should mark the sequence as circular")
+	}
+
+	// GFF3 writes features that wrap around the origin with an end past the end of the sequence.
+	featureSequence, err := gffSequence.Features[1].GetSequence()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if featureSequence != "TTTTATGCATGC" {
+		t.Errorf("expected TTTTATGCATGC, got %q", featureSequence)
+	}
+
+	// features that start after they end are also accepted on circular sequences.
+	gffSequence.Features[1].Location = gff.Location{Start: 32, End: 8}
+	if featureSequence, _ = gffSequence.Features[1].GetSequence(); featureSequence != "TTTTATGCATGC" {
+		t.Errorf("expected TTTTATGCATGC, got %q", featureSequence)
+	}
+
+	gffSequence.Features[1].ParentSequence.Meta.Circular = false
+	if _, err := gffSequence.Features[1].GetSequence(); err == nil {
+		t.Errorf("expected an error for a wrapping feature on a linear sequence")
+	}
+
+	// a CDS split over two lines either side of the origin should be joined starting from the piece before the origin.
+	splitFile := "##gff-version 3\n##sequence-region plasmid 1 36\n" +
+		"plasmid\t.\tregion\t1\t36\t.\t+\t.\tID=plasmid;Is_circular=true\n" +
+		"plasmid\t.\tmRNA\t1\t36\t.\t+\t.\tID=transcript\n" +
+		"plasmid\t.\tCDS\t1\t8\t.\t+\t0\tID=cds;Parent=transcript\n" +
+		"plasmid\t.\tCDS\t33\t36\t.\t+\t0\tID=cds;Parent=transcript\n" +
+		"##FASTA\n>plasmid\n" + sequence + "\n"
+	splitSequence, err := gff.Parse([]byte(splitFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index := range splitSequence.Features {
+		splitSequence.Features[index].ParentSequence = &splitSequence
+	}
+	cds, err := splitSequence.GetCDSForTranscript("transcript")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cds != "TTTTATGCATGC" {
+		t.Errorf("expected a CDS of TTTTATGCATGC across the origin, got %q", cds)
+	}
+
+	// a long intron is no reason to rotate pieces that don't meet at the origin,
+	// and pieces that do are joined in the order they're written.
+	for _, test := range []struct {
+		pieces   [][2]int
+		expected string
+	}{
+		{[][2]int{{1, 3}, {33, 35}}, "ATGTTT"},
+		{[][2]int{{33, 36}, {1, 3}, {20, 22}}, "TTTTATGAAA"},
+	} {
+		intronFile := "##gff-version 3\n##sequence-region plasmid 1 36\n" +
+			"plasmid\t.\tregion\t1\t36\t.\t+\t.\tID=plasmid;Is_circular=true\n" +
+			"plasmid\t.\tmRNA\t1\t36\t.\t+\t.\tID=transcript\n"
+		for _, piece := range test.pieces {
+			intronFile += fmt.Sprintf("plasmid\t.\tCDS\t%d\t%d\t.\t+\t0\tID=cds;Parent=transcript\n", piece[0], piece[1])
+		}
+		intronSequence, err := gff.Parse([]byte(intronFile + "##FASTA\n>plasmid\n" + sequence + "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for index := range intronSequence.Features {
+			intronSequence.Features[index].ParentSequence = &intronSequence
+		}
+		if cds, err := intronSequence.GetCDSForTranscript("transcript"); err != nil || cds != test.expected {
+			t.Errorf("expected a CDS of %s for pieces %v, got %q (%v)", test.expected, test.pieces, cds, err)
+		}
+	}
+
+	if _, err := (gff.Feature{Type: "gene"}).GetSequence(); err == nil {
+		t.Errorf("expected an error getting the sequence of a feature without a parent sequence")
+	}
+}
+
+func TestBuildLineWidth(t *testing.T) {
+	testSequence, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+	if testSequence.Meta.LineWidth != 70 {
+		t.Errorf("expected Parse to detect a line width of 70, got %d", testSequence.Meta.LineWidth)
+	}
+
+	for _, lineWidth := range []int{60, 70, 80} {
+		// check a sequence that ends on a partial line and one that is an exact multiple of the width.
+		for _, sequenceLength := range []int{lineWidth*3 + 7, lineWidth * 3} {
+			sequence := gff.Gff{Meta: gff.Meta{Name: "test", LineWidth: lineWidth}, Sequence: testSequence.Sequence[:sequenceLength]}
+			output, _ := gff.Build(sequence)
+
+			fastaSection := strings.SplitN(string(output), "##FASTA\n", 2)[1]
+			lines := strings.Split(strings.TrimSuffix(fastaSection, "\n"), "\n")[1:]
+			for index, line := range lines {
+				if len(line) == 0 || len(line) > lineWidth || (index < len(lines)-1 && len(line) != lineWidth) {
+					t.Errorf("width %d, length %d: badly wrapped line %d of length %d", lineWidth, sequenceLength, index, len(line))
+				}
+			}
+			if strings.HasSuffix(fastaSection, "\n\n") {
+				t.Errorf("width %d, length %d: output ends in a blank line", lineWidth, sequenceLength)
+			}
+
+			reparsed, _ := gff.Parse(output)
+			if reparsed.Sequence != sequence.Sequence || reparsed.Meta.LineWidth != lineWidth {
+				t.Errorf("width %d, length %d: sequence did not survive a round trip", lineWidth, sequenceLength)
+			}
+		}
+	}
+}
+
+func TestFeatureRemovalAndReplacement(t *testing.T) {
+	sequence, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+	featureCount := len(sequence.Features)
+
+	if err := sequence.RemoveFeature(featureCount); err == nil {
+		t.Errorf("RemoveFeature should error on an out of range index")
+	}
+	if err := sequence.ReplaceFeature(-1, gff.Feature{}); err == nil {
+		t.Errorf("ReplaceFeature should error on an out of range index")
+	}
+
+	if err := sequence.RemoveFeature(0); err != nil {
+		t.Error(err)
+	}
+	if len(sequence.Features) != featureCount-1 {
+		t.Errorf("expected %d features after removal, got %d", featureCount-1, len(sequence.Features))
+	}
+
+	// replaced features should still be able to get their sequence from the Gff struct.
+	if err := sequence.ReplaceFeature(0, gff.Feature{Location: gff.Location{Start: 0, End: 10}}); err != nil {
+		t.Error(err)
+	}
+	for _, feature := range sequence.Features {
+		if feature.ParentSequence != &sequence {
+			t.Fatalf("ParentSequence was not kept consistent after mutation")
+		}
+	}
+	featureSequence, _ := sequence.Features[0].GetSequence()
+	if featureSequence != sequence.Sequence[:10] {
+		t.Errorf("expected %q, got %q", sequence.Sequence[:10], featureSequence)
+	}
+
+	if indices := sequence.FindFeatures("not a gene"); len(indices) != 0 {
+		t.Errorf("expected no matches, got %v", indices)
+	}
+}
+
+func TestBuildValidatesStrandAndPhase(t *testing.T) {
+	invalidFeatures := map[string]gff.Feature{
+		"invalid strand":     {Type: "gene", Strand: "forward"},
+		"invalid phase":      {Type: "gene", Strand: "+", Phase: "2.5"},
+		"CDS without phase":  {Type: "CDS", Strand: "+"},
+		"CDS with . phase":   {Type: "CDS", Strand: "-", Phase: "."},
+		"out of range phase": {Type: "CDS", Strand: "-", Phase: "3"},
+	}
+	for name, feature := range invalidFeatures {
+		if _, err := gff.Build(gff.Gff{Features: []gff.Feature{feature}}); err == nil {
+			t.Errorf("expected Build to error on a feature with %s", name)
+		}
+	}
+
+	// empty strands and phases on non-CDS features default to ".".
+	output, err := gff.Build(gff.Gff{Features: []gff.Feature{{Name: "seq", Type: "gene", Location: gff.Location{Start: 0, End: 10}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(output), "seq\tfeature\tgene\t1\t10\t\t.\t.\t\n") {
+		t.Errorf("expected empty strand and phase to be written as \".\", got:\n%s", output)
+	}
+}
+
 func BenchmarkReadGff(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_, _ = gff.Read("../../data/ecoli-mg1655-short.gff")
@@ -116,3 +765,356 @@ func BenchmarkReadGff10000(b *testing.B) { BenchmarkReadGff(b) }
 Gff related tests and benchmarks end here.
 
 ******************************************************************************/
+
+func TestTranslateCDS(t *testing.T) {
+	sequence, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+	proteins, err := sequence.TranslateCDS(codon.GetCodonTable(11))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NCBI's gff files come with their own translations to check against.
+	var cdsCount int
+	for _, feature := range sequence.Features {
+		if feature.Type != "CDS" {
+			continue
+		}
+		cdsCount++
+		if protein := proteins[feature.Attributes["locus_tag"]]; protein != feature.Attributes["translation"]+"*" {
+			t.Errorf("CDS %s: expected %s*, got %s", feature.Attributes["locus_tag"], feature.Attributes["translation"], protein)
+		}
+	}
+	if len(proteins) != cdsCount {
+		t.Errorf("expected %d proteins, got %d", cdsCount, len(proteins))
+	}
+
+	// a spliced CDS on the - strand split over two lines that share an ID.
+	spliced := "##gff-version 3\n" +
+		"chr\t.\tgene\t1\t18\t.\t-\t.\tID=gene1\n" +
+		"chr\t.\tCDS\t1\t6\t.\t-\t0\tID=cds1;Parent=gene1\n" +
+		"chr\t.\tCDS\t13\t18\t.\t-\t0\tID=cds1;Parent=gene1\n" +
+		"chr\t.\tCDS\t1\t7\t.\t+\t1\tName=phased\n" +
+		"##FASTA\n>chr\n" + transform.ReverseComplement("ATGAAA"+"CCCCCC"+"TTTTAA") + "\n"
+	splicedSequence, _ := gff.Parse([]byte(spliced))
+	proteins, err = splicedSequence.TranslateCDS(codon.GetCodonTable(11))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"cds1": "MKF*", "phased": "*K"}
+	if diff := cmp.Diff(expected, proteins); diff != "" {
+		t.Errorf("TranslateCDS returned unexpected proteins (-want +got):\n%s", diff)
+	}
+
+	// errors name the CDS they came from.
+	splicedSequence.Features[1].Location.End = 100
+	if _, err := splicedSequence.TranslateCDS(codon.GetCodonTable(11)); err == nil || !strings.Contains(err.Error(), "cds1") {
+		t.Errorf("expected an error naming cds1, got %v", err)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	sequence, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+	path := filepath.Join(tmpDataDir, "ecoli-mg1655-short.json")
+	if err := gff.WriteJSON(sequence, path); err != nil {
+		t.Fatal(err)
+	}
+	jsonSequence, err := gff.ReadJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(sequence, jsonSequence, cmpopts.IgnoreFields(gff.Feature{}, "ParentSequence")); diff != "" {
+		t.Errorf("Reading the output of WriteJSON does not give the original sequence. Got this diff:\n%s", diff)
+	}
+
+	// features need their ParentSequence relinked to get their sequence.
+	expected, _ := sequence.Features[1].GetSequence()
+	got, err := jsonSequence.Features[1].GetSequence()
+	if err != nil || got != expected {
+		t.Errorf("GetSequence after ReadJSON got %q, %v. Expected %q", got, err, expected)
+	}
+
+	if _, err := gff.ParseJSON([]byte("{")); err == nil {
+		t.Errorf("expected an error parsing invalid JSON")
+	}
+}
+
+func TestFeaturesInRange(t *testing.T) {
+	sequence := gff.Gff{Sequence: strings.Repeat("A", 100)}
+	for _, feature := range []gff.Feature{
+		{Attributes: map[string]string{"ID": "short"}, Location: gff.Location{Start: 10, End: 20}},
+		{Attributes: map[string]string{"ID": "whole"}, Location: gff.Location{Start: 0, End: 100}},
+		{Attributes: map[string]string{"ID": "spliced"}, Location: gff.Location{SubLocations: []gff.Location{{Start: 5, End: 8}, {Start: 30, End: 40}}}},
+		{Attributes: map[string]string{"ID": "undefined"}, Location: gff.Location{UndefinedStart: true, UndefinedEnd: true}},
+		{Attributes: map[string]string{"ID": "wrapping"}, Location: gff.Location{Start: 95, End: 3}},
+	} {
+		feature := feature
+		_ = sequence.AddFeature(&feature)
+	}
+
+	ids := func(features []gff.Feature) string {
+		var ids []string
+		for _, feature := range features {
+			ids = append(ids, feature.Attributes["ID"])
+		}
+		return strings.Join(ids, " ")
+	}
+	tests := []struct {
+		start, end int
+		linear     string
+		circular   string
+	}{
+		{12, 15, "short whole", "short whole"}, // contained in short.
+		{18, 25, "short whole", "short whole"}, // partial overlap of the end of short.
+		{20, 30, "whole", "whole"},             // between short and the second exon.
+		{15, 35, "short whole spliced", "short whole spliced"},
+		{0, 2, "whole", "whole wrapping"}, // the wrapping feature covers the origin.
+		{96, 99, "whole", "whole wrapping"},
+		{10, 10, "", ""}, // empty range.
+	}
+	index := gff.NewFeatureIndex(sequence)
+	for _, test := range tests {
+		if got := ids(sequence.FeaturesInRange(test.start, test.end)); got != test.linear {
+			t.Errorf("FeaturesInRange(%d, %d): expected %q, got %q", test.start, test.end, test.linear, got)
+		}
+		if got := ids(index.FeaturesInRange(test.start, test.end)); got != test.linear {
+			t.Errorf("FeatureIndex.FeaturesInRange(%d, %d): expected %q, got %q", test.start, test.end, test.linear, got)
+		}
+	}
+
+	sequence.Meta.Circular = true
+	index = gff.NewFeatureIndex(sequence)
+	for _, test := range tests {
+		if got := ids(sequence.FeaturesInRange(test.start, test.end)); got != test.circular {
+			t.Errorf("circular FeaturesInRange(%d, %d): expected %q, got %q", test.start, test.end, test.circular, got)
+		}
+		if got := ids(index.FeaturesInRange(test.start, test.end)); got != test.circular {
+			t.Errorf("circular FeatureIndex.FeaturesInRange(%d, %d): expected %q, got %q", test.start, test.end, test.circular, got)
+		}
+	}
+
+	// the index should always agree with a linear scan.
+	ecoli, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
+	ecoliIndex := gff.NewFeatureIndex(ecoli)
+	for start := 0; start < len(ecoli.Sequence); start += 97 {
+		expected := ecoli.FeaturesInRange(start, start+500)
+		if diff := cmp.Diff(expected, ecoliIndex.FeaturesInRange(start, start+500), cmpopts.IgnoreFields(gff.Feature{}, "ParentSequence")); diff != "" {
+			t.Errorf("FeatureIndex disagrees with FeaturesInRange for %d to %d:\n%s", start, start+500, diff)
+		}
+	}
+}
+
+func TestNormalizeFeatureTypes(t *testing.T) {
+	sequence := gff.Gff{}
+	types := []string{"cds", "Gene", "5'UTR", "three_prime_utr", "mRNA", "Five-Prime-UTR", "tRNA", "my_custom_type", "CDS", "my_custom_type", "widget"}
+	for _, featureType := range types {
+		feature := gff.Feature{Type: featureType}
+		_ = sequence.AddFeature(&feature)
+	}
+
+	unknownTypes := sequence.NormalizeFeatureTypes()
+	if diff := cmp.Diff([]string{"my_custom_type", "widget"}, unknownTypes); diff != "" {
+		t.Errorf("unexpected unknown types (-want +got):\n%s", diff)
+	}
+
+	var normalizedTypes []string
+	for _, feature := range sequence.Features {
+		normalizedTypes = append(normalizedTypes, feature.Type)
+	}
+	expected := []string{"CDS", "gene", "five_prime_UTR", "three_prime_UTR", "mRNA", "five_prime_UTR", "tRNA", "my_custom_type", "CDS", "my_custom_type", "widget"}
+	if diff := cmp.Diff(expected, normalizedTypes); diff != "" {
+		t.Errorf("unexpected normalized types (-want +got):\n%s", diff)
+	}
+}
+
+func TestFeatureAlignment(t *testing.T) {
+	// example alignment from the GFF3 spec, plus a feature without an alignment.
+	file := "##gff-version 3\n" +
+		"##sequence-region ctg123 1 10000\n" +
+		"ctg123\t.\tnucleotide_match\t1\t23\t.\t.\t.\tID=match001;Target=EST%2023 1 21 +;Gap=M8 D3 M6 I1 M6\n" +
+		"ctg123\t.\tgene\t1000\t9000\t.\t+\t.\tID=gene00001\n"
+	sequence, err := gff.Parse([]byte(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alignment, err := sequence.Features[0].Alignment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &gff.Alignment{
+		TargetID:     "EST 23",
+		TargetStart:  0,
+		TargetEnd:    21,
+		TargetStrand: "+",
+		Gap:          []gff.GapOperation{{'M', 8}, {'D', 3}, {'M', 6}, {'I', 1}, {'M', 6}},
+	}
+	if diff := cmp.Diff(expected, alignment); diff != "" {
+		t.Errorf("unexpected alignment (-want +got):\n%s", diff)
+	}
+	if alignment, err := sequence.Features[1].Alignment(); alignment != nil || err != nil {
+		t.Errorf("expected no alignment for a feature without a Target. Got %v, %v", alignment, err)
+	}
+
+	built, err := gff.Build(sequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(built), "Target=EST%2023 1 21 +;Gap=M8 D3 M6 I1 M6") {
+		t.Errorf("Build did not write Target and Gap unchanged. Got:\n%s", built)
+	}
+
+	invalid := []map[string]string{
+		{"Gap": "M8"},
+		{"Target": "EST23 1"},
+		{"Target": "EST23 10 1"},
+		{"Target": "EST23 1 21 ?"},
+		{"Target": "EST23 1 21", "Gap": "M8 X3"},
+		{"Target": "EST23 1 21", "Gap": "M0"},
+	}
+	for _, attributes := range invalid {
+		if _, err := (gff.Feature{Attributes: attributes}).Alignment(); err == nil {
+			t.Errorf("expected an error for attributes %v", attributes)
+		}
+	}
+}
+
+func TestFeatureGeometry(t *testing.T) {
+	// a spliced gene covering 100..200 and 300..400 with an intron between.
+	spliced := gff.Feature{Strand: "+", Location: gff.Location{Start: 100, End: 400, Join: true, SubLocations: []gff.Location{{Start: 100, End: 200}, {Start: 300, End: 400}}}}
+	intronic := gff.Feature{Strand: "+", Location: gff.Location{Start: 220, End: 250}}
+	exonic := gff.Feature{Strand: "+", Location: gff.Location{Start: 150, End: 180}}
+	reverse := gff.Feature{Strand: "-", Location: gff.Location{Start: 150, End: 180}}
+	downstream := gff.Feature{Strand: "+", Location: gff.Location{Start: 410, End: 450}}
+	undefined := gff.Feature{Strand: "+", Location: gff.Location{UndefinedStart: true, UndefinedEnd: true}}
+	bySubLocation := gff.GeometryOptions{BySubLocation: true}
+	strandAware := gff.GeometryOptions{StrandAware: true}
+
+	tests := []struct {
+		name     string
+		got      interface{}
+		expected interface{}
+	}{
+		{"span overlaps intron", spliced.Overlaps(intronic), true},
+		{"sub-locations skip intron", spliced.Overlaps(intronic, bySubLocation), false},
+		{"overlap is symmetric", intronic.Overlaps(spliced), true},
+		{"span contains intron", spliced.Contains(intronic), true},
+		{"sub-locations don't contain intron", spliced.Contains(intronic, bySubLocation), false},
+		{"sub-locations contain exon", spliced.Contains(exonic, bySubLocation), true},
+		{"exon doesn't contain gene", exonic.Contains(spliced), false},
+		{"strand ignored by default", spliced.Overlaps(reverse), true},
+		{"strand aware overlap", spliced.Overlaps(reverse, strandAware), false},
+		{"strand aware contains", spliced.Contains(reverse, strandAware), false},
+		{"distance downstream", spliced.DistanceTo(downstream), 10},
+		{"distance is symmetric", downstream.DistanceTo(spliced), 10},
+		{"distance when overlapping", spliced.DistanceTo(exonic), 0},
+		{"distance to intron by span", spliced.DistanceTo(intronic), 0},
+		{"distance to intron by sub-location", spliced.DistanceTo(intronic, bySubLocation), 20},
+		{"distance across strands", spliced.DistanceTo(reverse, strandAware), -1},
+		{"distance to undefined", spliced.DistanceTo(undefined), -1},
+		{"undefined never overlaps", spliced.Overlaps(undefined), false},
+		{"undefined is never contained", spliced.Contains(undefined), false},
+	}
+	for _, test := range tests {
+		if test.got != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, test.got)
+		}
+	}
+}
+
+func TestTranslatePartialCDS(t *testing.T) {
+	// the first CDS is cut off by the start of the contig so its phase puts it
+	// back in frame, and its GTG is an internal valine rather than a start codon.
+	// the second is a complete CDS on the - strand, the third is 5' partial on the - strand.
+	file := "##gff-version 3\n" +
+		"ctg\t.\tCDS\t1\t11\t.\t+\t2\tID=partial;start_range=.,1\n" +
+		"ctg\t.\tCDS\t13\t21\t.\t-\t0\tID=complete\n" +
+		"ctg\t.\tCDS\t13\t21\t.\t-\t0\tID=reversePartial;end_range=21,.\n" +
+		"##FASTA\n>ctg\n" + "CAGTGAAATAA" + "C" + transform.ReverseComplement("GTGAAATAA") + "\n"
+	sequence, err := gff.Parse([]byte(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sequence.Features[0].Location.FivePrimePartial || !sequence.Features[2].Location.ThreePrimePartial {
+		t.Errorf("start_range and end_range attributes did not mark features as partial")
+	}
+
+	expected := []string{"VK*", "MK*", "VK*"}
+	for index, feature := range sequence.Features {
+		protein, err := feature.Translate(codon.GetCodonTable(11))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if protein != expected[index] {
+			t.Errorf("feature %s: expected %s, got %s", feature.Attributes["ID"], expected[index], protein)
+		}
+	}
+
+	proteins, err := sequence.TranslateCDS(codon.GetCodonTable(11))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]string{"partial": "VK*", "complete": "MK*", "reversePartial": "VK*"}, proteins); diff != "" {
+		t.Errorf("TranslateCDS returned unexpected proteins (-want +got):\n%s", diff)
+	}
+
+	built, _ := gff.Build(sequence)
+	if !strings.Contains(string(built), "start_range=.,1") || !strings.Contains(string(built), "end_range=21,.") {
+		t.Errorf("Build did not keep the partial attributes. Got:\n%s", built)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		line int
+		err  error
+	}{
+		{"too few fields", "##gff-version 3\nchr\t.\tgene\t1\n", 2, gff.ErrInvalidFeature},
+		{"non-numeric start", "##gff-version 3\n# comment\nchr\t.\tgene\tone\t10\t.\t+\t.\tID=a\n", 3, gff.ErrInvalidFeature},
+		{"non-numeric sequence-region", "##gff-version 3\n##sequence-region chr one 10\n", 2, gff.ErrInvalidDirective},
+	}
+	for _, test := range tests {
+		_, err := gff.Parse([]byte(test.file))
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+		var parseError *gff.ParseError
+		if !errors.As(err, &parseError) || parseError.Line != test.line {
+			t.Errorf("%s: expected a ParseError on line %d, got %v", test.name, test.line, err)
+		}
+	}
+
+	if _, err := gff.Read("../../data/does_not_exist.gff"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a file not found error, got %v", err)
+	}
+	invalid := gff.Gff{Features: []gff.Feature{{Type: "gene", Strand: "x"}}}
+	if _, err := gff.Build(invalid); !errors.Is(err, gff.ErrInvalidFeature) {
+		t.Errorf("expected an invalid feature error building a feature with an invalid strand, got %v", err)
+	}
+}
+
+func TestGffSeqhash(t *testing.T) {
+	sequence := gff.Gff{Sequence: "ATGAAACCC"}
+	linear, err := sequence.Seqhash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sequence.Meta.Circular = true
+	circular, _ := sequence.Seqhash()
+	if !strings.HasPrefix(linear, "v1_DLD_") || !strings.HasPrefix(circular, "v1_DCD_") {
+		t.Errorf("expected linear and circular double stranded DNA Seqhashes, got %s and %s", linear, circular)
+	}
+
+	// neither case nor strand change the Seqhash of double stranded DNA.
+	reverseComplement := gff.Gff{Sequence: transform.ReverseComplement("atgaaaccc")}
+	if hash, _ := reverseComplement.Seqhash(); hash != linear {
+		t.Errorf("expected the reverse complement to have Seqhash %s, got %s", linear, hash)
+	}
+}
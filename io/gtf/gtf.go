@@ -0,0 +1,333 @@
+/*
+Package gtf provides gtf parsers and writers.
+
+GTF stands for "gene transfer format". It is a stricter flavor of GFF2 that
+most RNA-seq tools, like STAR, featureCounts and StringTie, read and write
+instead of GFF3. Each line is a feature just like in GFF3, but attributes are
+written as space separated key "value" pairs:
+
+	chr1	HAVANA	exon	11869	12227	.	+	.	gene_id "ENSG00000223972.5"; transcript_id "ENST00000456328.2"; exon_number 1;
+
+Rather than using ID and Parent attributes to describe how features relate to
+each other, every feature carries the gene_id of its gene and the
+transcript_id of its transcript.
+
+This package converts gtf files to and from the same gff.Gff struct used by
+the gff package so that both formats can be worked with the same way. Parse
+adds GFF3 ID and Parent attributes derived from gene_id and transcript_id, so
+gff.Gff's FeatureTree and GetCDSForTranscript work on gtf files too.
+*/
+package gtf
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TimothyStiles/poly/io/gff"
+	"github.com/TimothyStiles/poly/io/internal/compress"
+)
+
+// ErrInvalidFeature is wrapped by errors about feature lines that can't be parsed and features that can't be built.
+var ErrInvalidFeature = errors.New("invalid feature")
+
+// ParseError is returned by Parse when a line can't be read. Use errors.As to
+// get the line number and errors.Is with ErrInvalidFeature to check for
+// malformed features.
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("gtf: line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// derivedKeys are the GFF3 attributes Parse adds and Build leaves out.
+var derivedKeys = map[string]bool{"ID": true, "Parent": true}
+
+// Parse parses a gtf file into a gff.Gff. Comment lines are kept in the Meta's
+// Directives and the Meta is named after the seqid of the first feature.
+//
+// Genes get an ID of their gene_id and transcripts an ID of their
+// transcript_id and a Parent of their gene_id. Every other feature, like exons
+// and CDS, gets a Parent of its transcript_id. Attributes that are repeated,
+// like GENCODE's tag attribute, become a single multi-value attribute.
+func Parse(file []byte) (gff.Gff, error) {
+	sequence := gff.Gff{Meta: gff.Meta{Version: "2"}}
+
+	scanner := bufio.NewScanner(bytes.NewReader(file))
+	// some annotation pipelines write very long attribute columns.
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case len(strings.TrimSpace(line)) == 0:
+			continue
+		case strings.HasPrefix(line, "#"):
+			sequence.Meta.Directives = append(sequence.Meta.Directives, line)
+			continue
+		}
+		feature, err := parseFeature(line)
+		if err != nil {
+			return gff.Gff{}, &ParseError{lineNumber, err}
+		}
+		if sequence.Meta.Name == "" {
+			sequence.Meta.Name = feature.Name
+		}
+		_ = sequence.AddFeature(&feature)
+	}
+	if err := scanner.Err(); err != nil {
+		return gff.Gff{}, &ParseError{lineNumber + 1, err}
+	}
+	return sequence, nil
+}
+
+// parseFeature parses a single tab separated gtf line.
+func parseFeature(line string) (gff.Feature, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) < 9 {
+		return gff.Feature{}, fmt.Errorf("%w: expected 9 tab separated fields, got %d", ErrInvalidFeature, len(fields))
+	}
+	feature := gff.Feature{
+		Name:   fields[0],
+		Source: fields[1],
+		Type:   fields[2],
+		Score:  fields[5],
+		Strand: fields[6],
+		Phase:  fields[7],
+	}
+
+	// Indexing starts at 1 for gtf so we need to shift down for Sequence 0 index.
+	start, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return gff.Feature{}, fmt.Errorf("%w: start %q is not a number", ErrInvalidFeature, fields[3])
+	}
+	end, err := strconv.Atoi(fields[4])
+	if err != nil {
+		return gff.Feature{}, fmt.Errorf("%w: end %q is not a number", ErrInvalidFeature, fields[4])
+	}
+	feature.Location = gff.Location{Start: start - 1, End: end}
+
+	keys, values, quoted, err := parseAttributes(fields[8])
+	if err != nil {
+		return gff.Feature{}, err
+	}
+
+	geneID := firstValue(values["gene_id"])
+	transcriptID := firstValue(values["transcript_id"])
+	switch {
+	case feature.Type == "gene" && geneID != "":
+		feature.SetAttribute("ID", geneID)
+	case feature.Type == "transcript" && transcriptID != "":
+		feature.SetAttribute("ID", transcriptID)
+		if geneID != "" {
+			feature.SetAttribute("Parent", geneID)
+		}
+	case transcriptID != "":
+		feature.SetAttribute("Parent", transcriptID)
+	}
+	// ID and Parent go first, following GFF3 convention.
+	attributeOrder := []string{"ID", "Parent"}
+	for _, key := range keys {
+		if derivedKeys[key] {
+			continue
+		}
+		feature.SetAttribute(key, values[key]...)
+		attributeOrder = append(attributeOrder, key)
+	}
+	feature.AttributeOrder = attributeOrder
+	feature.QuotedAttributes = quoted
+	return feature, nil
+}
+
+// firstValue returns the first value of a list or "" if it is empty.
+func firstValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// parseAttributes splits a gtf attribute column into its keys, in the order
+// they first appear, their values and whether their values were quoted.
+// Quoted values can contain spaces and semicolons.
+func parseAttributes(column string) ([]string, map[string][]string, map[string]bool, error) {
+	var keys []string
+	values := make(map[string][]string)
+	quoted := make(map[string]bool)
+	remaining := strings.TrimSpace(column)
+	for remaining != "" {
+		// attributes are separated by semicolons, but extra ones are sometimes left behind.
+		if remaining[0] == ';' {
+			remaining = strings.TrimSpace(remaining[1:])
+			continue
+		}
+		keyEnd := strings.IndexAny(remaining, " \t")
+		if keyEnd == -1 {
+			return nil, nil, nil, fmt.Errorf("%w: attribute %q has no value", ErrInvalidFeature, remaining)
+		}
+		key := remaining[:keyEnd]
+		remaining = strings.TrimSpace(remaining[keyEnd:])
+
+		var value string
+		isQuoted := strings.HasPrefix(remaining, "\"")
+		if isQuoted {
+			valueEnd := strings.IndexByte(remaining[1:], '"')
+			if valueEnd == -1 {
+				return nil, nil, nil, fmt.Errorf("%w: attribute %s has an unterminated quoted value", ErrInvalidFeature, key)
+			}
+			value = remaining[1 : valueEnd+1]
+			remaining = strings.TrimSpace(remaining[valueEnd+2:])
+		} else {
+			valueEnd := strings.IndexByte(remaining, ';')
+			if valueEnd == -1 {
+				valueEnd = len(remaining)
+			}
+			value = strings.TrimSpace(remaining[:valueEnd])
+			remaining = remaining[valueEnd:]
+		}
+
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+			quoted[key] = isQuoted
+		}
+		values[key] = append(values[key], value)
+	}
+	return keys, values, quoted, nil
+}
+
+// Build writes a gff.Gff out as a gtf file. Every feature must have a gene_id
+// attribute. The ID and Parent attributes Parse adds aren't written since
+// they're already described by gene_id and transcript_id. Multi-value
+// attributes are written as one key "value" pair per value. Attributes are
+// quoted the way they were when parsed. Otherwise whole numbers, like
+// exon_number, are written without quotes, except for gene_id and
+// transcript_id which gtf requires to always be quoted.
+func Build(sequence gff.Gff) ([]byte, error) {
+	var gtfBuffer bytes.Buffer
+	for _, directive := range sequence.Meta.Directives {
+		gtfBuffer.WriteString(directive)
+		gtfBuffer.WriteString("\n")
+	}
+
+	for featureIndex, feature := range sequence.Features {
+		if _, ok := feature.Attributes["gene_id"]; !ok {
+			return nil, fmt.Errorf("gtf: feature %d: %w: gtf features require a gene_id attribute", featureIndex, ErrInvalidFeature)
+		}
+		attributes, err := buildAttributes(feature)
+		if err != nil {
+			return nil, fmt.Errorf("gtf: feature %d: %w", featureIndex, err)
+		}
+
+		fields := []string{
+			feature.Name,
+			orDot(feature.Source),
+			feature.Type,
+			// Indexing starts at 1 for gtf so we need to shift up from Sequence 0 index.
+			strconv.Itoa(feature.Location.Start + 1),
+			strconv.Itoa(feature.Location.End),
+			orDot(feature.Score),
+			orDot(feature.Strand),
+			orDot(feature.Phase),
+			attributes,
+		}
+		gtfBuffer.WriteString(strings.Join(fields, "\t"))
+		gtfBuffer.WriteString("\n")
+	}
+	return gtfBuffer.Bytes(), nil
+}
+
+// buildAttributes writes a feature's attributes as gtf key "value" pairs with
+// gene_id and transcript_id first, as gtf requires.
+func buildAttributes(feature gff.Feature) (string, error) {
+	keys := []string{"gene_id", "transcript_id"}
+	for _, key := range feature.AttributeOrder {
+		if key != "gene_id" && key != "transcript_id" {
+			keys = append(keys, key)
+		}
+	}
+	// attributes added without an order are written after the rest, sorted so that Build is deterministic.
+	ordered := make(map[string]bool)
+	for _, key := range keys {
+		ordered[key] = true
+	}
+	var unorderedKeys []string
+	for key := range feature.Attributes {
+		if !ordered[key] {
+			unorderedKeys = append(unorderedKeys, key)
+		}
+	}
+	sort.Strings(unorderedKeys)
+	keys = append(keys, unorderedKeys...)
+
+	var attributes []string
+	written := make(map[string]bool)
+	for _, key := range keys {
+		if derivedKeys[key] || written[key] {
+			continue
+		}
+		values, err := feature.AttributeValues(key)
+		if err != nil {
+			return "", err
+		}
+		for _, value := range values {
+			if quoteAttribute(feature, key, value) {
+				attributes = append(attributes, key+" \""+value+"\";")
+			} else {
+				attributes = append(attributes, key+" "+value+";")
+			}
+		}
+		written[key] = true
+	}
+	return strings.Join(attributes, " "), nil
+}
+
+// quoteAttribute reports whether an attribute value should be written quoted.
+func quoteAttribute(feature gff.Feature, key, value string) bool {
+	if key == "gene_id" || key == "transcript_id" {
+		return true
+	}
+	if quoted, ok := feature.QuotedAttributes[key]; ok {
+		return quoted
+	}
+	_, err := strconv.Atoi(value)
+	return err != nil
+}
+
+// orDot returns "." for empty columns.
+func orDot(column string) string {
+	if column == "" {
+		return "."
+	}
+	return column
+}
+
+// Read reads a gtf file into a gff.Gff. Gzip and bzip2 compressed files are detected and decompressed automatically.
+func Read(path string) (gff.Gff, error) {
+	file, err := compress.ReadFile(path)
+	if err != nil {
+		return gff.Gff{}, err
+	}
+	return Parse(file)
+}
+
+// Write takes a gff.Gff and a path string and writes out a gtf file to that path.
+func Write(sequence gff.Gff, path string) error {
+	gtf, err := Build(sequence)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, gtf, 0644)
+}
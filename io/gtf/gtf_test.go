@@ -0,0 +1,177 @@
+package gtf_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TimothyStiles/poly/io/gff"
+	"github.com/TimothyStiles/poly/io/gtf"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func ExampleRead() {
+	sequence, _ := gtf.Read("../../data/example.gtf")
+	tags, _ := sequence.Features[1].AttributeValues("tag")
+
+	fmt.Println(sequence.Features[1].Attributes["transcript_id"], tags)
+	// Output: ENST00000000001.1 [basic CCDS]
+}
+
+func ExampleBuild() {
+	sequence, _ := gtf.Read("../../data/example.gtf")
+	gtfBytes, _ := gtf.Build(sequence)
+	lines := strings.Split(string(gtfBytes), "\n")
+
+	fmt.Println(lines[3])
+	// Output: chr1	HAVANA	exon	11	30	.	+	.	gene_id "ENSG00000000001.1"; transcript_id "ENST00000000001.1"; exon_number 1; level 2;
+}
+
+func TestGtfIO(t *testing.T) {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	testInputPath := "../../data/example.gtf"
+	tmpGtfFilePath := filepath.Join(tmpDataDir, "example.gtf")
+
+	sequence, err := gtf.Read(testInputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gtf.Write(sequence, tmpGtfFilePath); err != nil {
+		t.Fatal(err)
+	}
+
+	// the file should be written back exactly as it was read.
+	original, _ := ioutil.ReadFile(testInputPath)
+	builtOutput, _ := ioutil.ReadFile(tmpGtfFilePath)
+	if diff := cmp.Diff(string(original), string(builtOutput)); diff != "" {
+		t.Errorf("Build() does not output the same file as was input through Read(). Got this diff:\n%s", diff)
+	}
+
+	reparsed, _ := gtf.Read(tmpGtfFilePath)
+	if diff := cmp.Diff(sequence, reparsed, cmpopts.IgnoreFields(gff.Feature{}, "ParentSequence")); diff != "" {
+		t.Errorf("Parsing the output of Build() does not produce the same output as parsing the original file. Got this diff:\n%s", diff)
+	}
+}
+
+func TestHierarchy(t *testing.T) {
+	sequence, err := gtf.Read("../../data/example.gtf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sequence.Meta.Name != "chr1" || sequence.Features[3].Location.Start != 20 || sequence.Features[3].Location.End != 30 {
+		t.Errorf("unexpected name or coordinates: %s %v", sequence.Meta.Name, sequence.Features[3].Location)
+	}
+
+	roots, err := sequence.FeatureTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 1 || roots[0].Feature.Type != "gene" {
+		t.Fatalf("expected a single gene at the top level, got %d roots", len(roots))
+	}
+	transcripts := roots[0].Children
+	if len(transcripts) != 1 || len(transcripts[0].Children) != 6 {
+		t.Fatalf("expected one transcript with 6 children")
+	}
+
+	// gtf files have no sequence so it has to be added before features can be sliced out of it.
+	sequence.Sequence = strings.Repeat("A", 20) + "ATGCCCGGGT" + strings.Repeat("A", 20) + "TTAAACCCTAA" + strings.Repeat("A", 9)
+	for index := range sequence.Features {
+		sequence.Features[index].ParentSequence = &sequence
+	}
+	cds, err := sequence.GetCDSForTranscript("ENST00000000001.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cds != "ATGCCCGGGTTTAAACCC" {
+		t.Errorf("expected spliced CDS ATGCCCGGGTTTAAACCC, got %s", cds)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	badFiles := map[string]string{
+		"too few fields":     "chr1\tsrc\texon\t1\t10\n",
+		"non-numeric start":  "chr1\tsrc\texon\tone\t10\t.\t+\t.\tgene_id \"g\";\n",
+		"attribute no value": "chr1\tsrc\texon\t1\t10\t.\t+\t.\tgene_id\n",
+		"unterminated quote": "chr1\tsrc\texon\t1\t10\t.\t+\t.\tgene_id \"g;\n",
+	}
+	for name, file := range badFiles {
+		if _, err := gtf.Parse([]byte(file)); !errors.Is(err, gtf.ErrInvalidFeature) {
+			t.Errorf("expected an invalid feature error parsing a file with %s, got %v", name, err)
+		}
+	}
+
+	var parseError *gtf.ParseError
+	if _, err := gtf.Parse([]byte("# comment\nchr1\tsrc\texon\t1\t10\t.\t+\t.\tgene_id \"g\";\nchr1\n")); !errors.As(err, &parseError) || parseError.Line != 3 {
+		t.Errorf("expected a ParseError on line 3, got %v", err)
+	}
+	if _, err := gtf.Read("../../data/does_not_exist.gtf"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a file not found error, got %v", err)
+	}
+
+	// quoted values can hold semicolons, and extra whitespace and separators are ignored.
+	sequence, err := gtf.Parse([]byte("chr1\tsrc\texon\t1\t10\t.\t+\t.\t gene_id \"g1\" ;; note \"a; b\";level 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes, _ := sequence.Features[0].AttributeValues("note")
+	if diff := cmp.Diff([]string{"a; b"}, notes); diff != "" {
+		t.Errorf("unexpected note (-want +got):\n%s", diff)
+	}
+	if sequence.Features[0].Attributes["level"] != "2" {
+		t.Errorf("expected level 2, got %q", sequence.Features[0].Attributes["level"])
+	}
+
+	gff3, _ := gff.Parse([]byte("##gff-version 3\nctg\t.\tgene\t1\t10\t.\t+\t.\tID=gene1\n"))
+	if _, err := gtf.Build(gff3); !errors.Is(err, gtf.ErrInvalidFeature) {
+		t.Errorf("expected an invalid feature error building a feature without a gene_id, got %v", err)
+	}
+}
+
+func TestBuildQuoting(t *testing.T) {
+	// NCBI writes Entrez gene ids as gene_id values, which are numbers but still have to be quoted. Quoted numbers
+	// elsewhere, like Ensembl's exon_number "1", should be written back quoted too.
+	file := "chr17\tBestRefSeq\texon\t7668402\t7669690\t.\t-\t.\tgene_id \"7157\"; transcript_id \"NM_000546.6\"; exon_number \"1\"; level 2;\n"
+	sequence, err := gtf.Parse([]byte(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	built, err := gtf.Build(sequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(file, string(built)); diff != "" {
+		t.Errorf("Build() does not output the same file as was input through Parse(). Got this diff:\n%s", diff)
+	}
+
+	// features that weren't parsed from a gtf file still get a quoted gene_id.
+	feature := gff.Feature{Name: "chr17", Type: "gene", Location: gff.Location{Start: 0, End: 10}}
+	feature.SetAttribute("gene_id", "7157")
+	for _, key := range []string{"level", "gene_name", "tag", "gene_type", "source", "note"} {
+		feature.SetAttribute(key, key+"_value")
+	}
+	sequence = gff.Gff{}
+	_ = sequence.AddFeature(&feature)
+	first, _ := gtf.Build(sequence)
+	if !strings.Contains(string(first), `gene_id "7157";`) {
+		t.Errorf("expected a quoted gene_id, got %s", first)
+	}
+
+	// attributes without an order are sorted so that building the same feature always gives the same file.
+	for i := 0; i < 50; i++ {
+		if built, _ := gtf.Build(sequence); string(built) != string(first) {
+			t.Fatalf("Build() is not deterministic, got\n%s and\n%s", first, built)
+		}
+	}
+}
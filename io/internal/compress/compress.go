@@ -0,0 +1,107 @@
+/*
+Package compress transparently decompresses the files read by poly's io packages.
+
+Sequencing and annotation files are very often shipped gzip or bzip2
+compressed, so every Read function in poly's io packages goes through this
+package rather than reading the file directly. Compressed files are detected by
+their magic bytes instead of their file extension, so a plain file named .gz
+or a compressed file with no extension at all are both read correctly.
+
+Zstandard compressed files are detected too but can't be read, since the
+standard library has no zstd decompressor. They return ErrZstd instead of
+being parsed as garbage.
+*/
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// magic bytes that compressed files start with.
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ErrZstd is returned for zstd compressed files, which the standard library can't decompress.
+var ErrZstd = errors.New("zstd compressed files are not supported, decompress the file before reading it")
+
+// NewReader returns a reader that transparently decompresses gzip (including
+// BGZF) or bzip2 compressed data, or reads the data as is if it isn't compressed.
+// If the returned reader is an io.Closer it should be closed once read.
+func NewReader(r io.Reader) (io.Reader, error) {
+	bufferedReader := bufio.NewReader(r)
+	// files too short to hold the magic bytes can't be compressed, so io.EOF just means there's nothing to decompress.
+	magicBytes, err := bufferedReader.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(magicBytes, gzipMagic):
+		return gzip.NewReader(bufferedReader)
+	case isBzip2(magicBytes):
+		return bzip2.NewReader(bufferedReader), nil
+	case bytes.HasPrefix(magicBytes, zstdMagic):
+		return nil, ErrZstd
+	}
+	return bufferedReader, nil
+}
+
+// Open opens the file at path for reading, decompressing it if it is
+// compressed. Closing the returned reader closes the file as well. Errors from
+// opening the file are returned as is, so a missing file can be checked for
+// with errors.Is(err, fs.ErrNotExist).
+func Open(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &fileReader{Reader: reader, file: file}, nil
+}
+
+// ReadFile reads the whole file at path, decompressing it if it is compressed.
+func ReadFile(path string) ([]byte, error) {
+	reader, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// fileReader reads a possibly decompressed file and closes both the
+// decompressor and the file.
+type fileReader struct {
+	io.Reader
+	file *os.File
+}
+
+// Close closes the decompressor, if there is one, and then the file.
+func (reader *fileReader) Close() error {
+	if closer, ok := reader.Reader.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			reader.file.Close()
+			return err
+		}
+	}
+	return reader.file.Close()
+}
+
+// isBzip2 checks for the bzip2 magic bytes followed by the block size digit,
+// so that a plain text file that happens to start with "BZh" isn't mistaken for one.
+func isBzip2(file []byte) bool {
+	return len(file) >= 4 && bytes.HasPrefix(file, bzip2Magic) && file[3] >= '1' && file[3] <= '9'
+}
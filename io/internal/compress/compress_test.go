@@ -0,0 +1,93 @@
+package compress_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TimothyStiles/poly/io/internal/compress"
+)
+
+func TestNewReader(t *testing.T) {
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, _ = gzipWriter.Write([]byte(">seq\nATGC\n"))
+	_ = gzipWriter.Close()
+
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+	}{
+		{"plain", []byte(">seq\nATGC\n"), ">seq\nATGC\n"},
+		{"gzip", gzipped.Bytes(), ">seq\nATGC\n"},
+		// plain text that happens to start with the bzip2 magic bytes isn't decompressed.
+		{"bzip2 magic without block size", []byte("BZhx plain text"), "BZhx plain text"},
+		{"shorter than the magic bytes", []byte("AT"), "AT"},
+		{"empty", []byte{}, ""},
+	}
+	for _, test := range tests {
+		reader, err := compress.NewReader(bytes.NewReader(test.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", test.name, err)
+			continue
+		}
+		got, _ := ioutil.ReadAll(reader)
+		if string(got) != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	// zstd can't be decompressed with the standard library so it should error rather than be read as garbage.
+	if _, err := compress.NewReader(bytes.NewReader([]byte{0x28, 0xb5, 0x2f, 0xfd, 0x00})); !errors.Is(err, compress.ErrZstd) {
+		t.Errorf("expected ErrZstd, got %v", err)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	expected, _ := ioutil.ReadFile("../../../data/puc19.gbk")
+	got, err := compress.ReadFile("../../../data/puc19.gbk.bz2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Errorf("reading a bzip2 compressed file does not give the same result as the plain file")
+	}
+
+	if _, err := compress.ReadFile("../../../data/does_not_exist.gbk"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, _ = gzipWriter.Write([]byte(strings.Repeat("ATGC", 100)))
+	_ = gzipWriter.Close()
+	path := filepath.Join(tmpDataDir, "sequence.gz")
+	_ = ioutil.WriteFile(path, gzipped.Bytes(), 0644)
+
+	reader, err := compress.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadAll(reader)
+	if string(got) != strings.Repeat("ATGC", 100) {
+		t.Errorf("unexpected contents %q", got)
+	}
+	if err := reader.Close(); err != nil {
+		t.Errorf("unexpected error closing the reader: %s", err)
+	}
+}
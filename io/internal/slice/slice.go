@@ -0,0 +1,28 @@
+/*
+Package slice cuts the sequences of features out of the sequences they annotate.
+
+Features of circular sequences, like plasmids, may run across the origin. The
+genbank, gff and snapgene packages all read such features the same way, so
+they share this package rather than each keeping their own copy.
+*/
+package slice
+
+import "fmt"
+
+// Sequence returns sequence[start:end]. On circular sequences features may
+// wrap around the origin, either by starting after they end or by ending past
+// the end of the sequence, in which case the two pieces are spliced together.
+func Sequence(sequence string, start, end int, circular bool) (string, error) {
+	if circular && start >= 0 && start <= len(sequence) {
+		if start > end && end >= 0 {
+			return sequence[start:] + sequence[:end], nil
+		}
+		if end > len(sequence) && end-len(sequence) <= start {
+			return sequence[start:] + sequence[:end-len(sequence)], nil
+		}
+	}
+	if start < 0 || end > len(sequence) || start > end {
+		return "", fmt.Errorf("location %d..%d is out of bounds for a sequence of length %d", start+1, end, len(sequence))
+	}
+	return sequence[start:end], nil
+}
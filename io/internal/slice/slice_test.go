@@ -0,0 +1,30 @@
+package slice_test
+
+import (
+	"testing"
+
+	"github.com/TimothyStiles/poly/io/internal/slice"
+)
+
+func TestSequence(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end int
+		circular   bool
+		expected   string
+		fails      bool
+	}{
+		{name: "inside", start: 2, end: 5, expected: "GTA"},
+		{name: "starts after it ends", start: 6, end: 2, circular: true, expected: "GCAT"},
+		{name: "ends past the end", start: 6, end: 10, circular: true, expected: "GCAT"},
+		{name: "linear wrap", start: 6, end: 2, fails: true},
+		{name: "out of bounds", start: 2, end: 9, fails: true},
+		{name: "past the end of a circle", start: 2, end: 20, circular: true, fails: true},
+	}
+	for _, test := range tests {
+		got, err := slice.Sequence("ATGTACGC", test.start, test.end, test.circular)
+		if test.fails != (err != nil) || got != test.expected {
+			t.Errorf("%s: got %q and error %v, expected %q", test.name, got, err, test.expected)
+		}
+	}
+}
@@ -0,0 +1,830 @@
+/*
+Package sam provides SAM parsers and writers and a BAM parser.
+
+SAM (sequence alignment/map) is the format aligners like bwa, bowtie2 and
+minimap2 use to report where sequencing reads land on a reference. A SAM file
+starts with header lines that describe the reference sequences, read groups
+and programs used:
+
+	@HD	VN:1.6	SO:coordinate
+	@SQ	SN:chr1	LN:1000
+
+followed by one tab separated line per alignment with eleven required columns
+and any number of TAG:TYPE:VALUE optional fields:
+
+	read1	0	chr1	100	60	8M2I4M	*	0	0	ACGTACGTACGTAC	*	NM:i:2
+
+BAM is the binary, BGZF compressed version of the same records. Both are read
+into the same Sam struct so that code consuming alignments, like Pileup, doesn't
+need to care which one a file was.
+
+Like the rest of poly, positions are 0-based, so an alignment with POS 100 has
+a Position of 99.
+*/
+package sam
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TimothyStiles/poly/io/internal/compress"
+)
+
+// Sam is a struct that represents a SAM or BAM file.
+type Sam struct {
+	Header     Header      `json:"header"`
+	Alignments []Alignment `json:"alignments"`
+}
+
+// Header holds the header lines of a SAM file. Each line is stored as a map
+// of its TAG:VALUE fields.
+type Header struct {
+	HD         map[string]string   `json:"hd"` // the @HD line, like VN (the format version) and SO (the sort order).
+	References []Reference         `json:"references"`
+	ReadGroups []map[string]string `json:"read_groups"`
+	Programs   []map[string]string `json:"programs"`
+	Comments   []string            `json:"comments"`
+}
+
+// Reference is an @SQ header line describing one of the reference sequences
+// reads are aligned to.
+type Reference struct {
+	Name   string            `json:"name"`
+	Length int               `json:"length"`
+	Fields map[string]string `json:"fields"` // every other field of the line, like M5 or UR.
+}
+
+// Alignment is a struct that represents a single alignment record.
+type Alignment struct {
+	QueryName      string           `json:"query_name"`
+	Flag           int              `json:"flag"`
+	ReferenceName  string           `json:"reference_name"` // "*" if the read is unmapped.
+	Position       int              `json:"position"`       // 0-based position of the first aligned base, -1 if there is none.
+	MappingQuality int              `json:"mapping_quality"`
+	Cigar          []CigarOperation `json:"cigar"`
+	NextReference  string           `json:"next_reference"` // "=" if it's the same as ReferenceName, "*" if unknown.
+	NextPosition   int              `json:"next_position"`  // 0-based, -1 if unknown.
+	TemplateLength int              `json:"template_length"`
+	Sequence       string           `json:"sequence"` // "*" if not stored.
+	Quality        string           `json:"quality"`  // Phred+33 encoded, "*" if not stored.
+	Tags           []Tag            `json:"tags"`
+}
+
+// Tag is an optional TAG:TYPE:VALUE field of an alignment. Values are
+// stored as they're written in SAM files, so B arrays look like "c,1,-2".
+type Tag struct {
+	Tag   string `json:"tag"`
+	Type  byte   `json:"type"` // one of A, i, f, Z, H or B.
+	Value string `json:"value"`
+}
+
+// Flag bits of an Alignment.
+const (
+	FlagPaired        = 0x1   // the template has multiple segments.
+	FlagProperPair    = 0x2   // each segment is aligned properly.
+	FlagUnmapped      = 0x4   // the segment is unmapped.
+	FlagMateUnmapped  = 0x8   // the next segment is unmapped.
+	FlagReverse       = 0x10  // the sequence is reverse complemented.
+	FlagMateReverse   = 0x20  // the sequence of the next segment is reverse complemented.
+	FlagFirst         = 0x40  // the first segment of the template.
+	FlagLast          = 0x80  // the last segment of the template.
+	FlagSecondary     = 0x100 // a secondary alignment.
+	FlagQCFail        = 0x200 // the read didn't pass quality controls.
+	FlagDuplicate     = 0x400 // a PCR or optical duplicate.
+	FlagSupplementary = 0x800 // a supplementary alignment.
+)
+
+// ErrInvalidAlignment is wrapped by errors about alignment lines and BAM records that can't be parsed.
+var ErrInvalidAlignment = errors.New("invalid alignment")
+
+// ErrInvalidCigar is wrapped by errors about malformed CIGAR strings.
+var ErrInvalidCigar = errors.New("invalid CIGAR")
+
+// ParseError is returned by Parse when a line can't be parsed. Use errors.As to
+// get the line number and errors.Is with ErrInvalidAlignment or
+// ErrInvalidCigar to check its cause. For BAM files Line is the number of the record.
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("sam: line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+/******************************************************************************
+
+CIGAR functions begin here.
+
+******************************************************************************/
+
+// CigarOperation is a single length and operation pair of a CIGAR string,
+// like the 8M of 8M2I4M.
+type CigarOperation struct {
+	Length    int  `json:"length"`
+	Operation byte `json:"operation"` // one of MIDNSHP=X.
+}
+
+// cigarOperations are the CIGAR operations in the order BAM files number them.
+const cigarOperations = "MIDNSHP=X"
+
+// ParseCigar parses a CIGAR string like 8M2I4M. "*", the CIGAR of unmapped reads, returns no operations.
+func ParseCigar(cigar string) ([]CigarOperation, error) {
+	if cigar == "*" {
+		return nil, nil
+	}
+	var operations []CigarOperation
+	length := -1
+	for index := 0; index < len(cigar); index++ {
+		character := cigar[index]
+		if character >= '0' && character <= '9' {
+			if length < 0 {
+				length = 0
+			}
+			length = length*10 + int(character-'0')
+			continue
+		}
+		if !strings.ContainsRune(cigarOperations, rune(character)) {
+			return nil, fmt.Errorf("%w: unknown operation %q in %s", ErrInvalidCigar, character, cigar)
+		}
+		if length < 0 {
+			return nil, fmt.Errorf("%w: operation %q is missing a length in %s", ErrInvalidCigar, character, cigar)
+		}
+		operations = append(operations, CigarOperation{length, character})
+		length = -1
+	}
+	if length >= 0 || len(operations) == 0 {
+		return nil, fmt.Errorf("%w: %q doesn't end with an operation", ErrInvalidCigar, cigar)
+	}
+	return operations, nil
+}
+
+// CigarString writes CIGAR operations as a CIGAR string, or "*" if there aren't any.
+func CigarString(operations []CigarOperation) string {
+	if len(operations) == 0 {
+		return "*"
+	}
+	var cigar strings.Builder
+	for _, operation := range operations {
+		cigar.WriteString(strconv.Itoa(operation.Length))
+		cigar.WriteByte(operation.Operation)
+	}
+	return cigar.String()
+}
+
+// consumesReference returns whether a CIGAR operation moves along the reference.
+func consumesReference(operation byte) bool {
+	return operation == 'M' || operation == 'D' || operation == 'N' || operation == '=' || operation == 'X'
+}
+
+// consumesQuery returns whether a CIGAR operation moves along the read.
+func consumesQuery(operation byte) bool {
+	return operation == 'M' || operation == 'I' || operation == 'S' || operation == '=' || operation == 'X'
+}
+
+// End returns the 0-based, exclusive end of the alignment on its reference.
+func (alignment Alignment) End() int {
+	end := alignment.Position
+	for _, operation := range alignment.Cigar {
+		if consumesReference(operation.Operation) {
+			end += operation.Length
+		}
+	}
+	return end
+}
+
+// GetTag returns the optional field with the tag name, like "NM".
+func (alignment Alignment) GetTag(name string) (Tag, bool) {
+	for _, tag := range alignment.Tags {
+		if tag.Tag == name {
+			return tag, true
+		}
+	}
+	return Tag{}, false
+}
+
+/******************************************************************************
+
+SAM parser begins here.
+
+******************************************************************************/
+
+// Parse parses a SAM file.
+func Parse(file []byte) (Sam, error) {
+	var sam Sam
+	scanner := bufio.NewScanner(bytes.NewReader(file))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		if strings.HasPrefix(line, "@") {
+			if err := sam.Header.parseLine(line); err != nil {
+				return Sam{}, &ParseError{lineNumber, err}
+			}
+			continue
+		}
+		alignment, err := parseAlignment(line)
+		if err != nil {
+			return Sam{}, &ParseError{lineNumber, err}
+		}
+		sam.Alignments = append(sam.Alignments, alignment)
+	}
+	if err := scanner.Err(); err != nil {
+		return Sam{}, &ParseError{lineNumber + 1, err}
+	}
+	return sam, nil
+}
+
+// parseLine parses a single header line into the header.
+func (header *Header) parseLine(line string) error {
+	columns := strings.Split(line, "\t")
+	if columns[0] == "@CO" {
+		header.Comments = append(header.Comments, strings.TrimPrefix(strings.TrimPrefix(line, "@CO"), "\t"))
+		return nil
+	}
+	fields := make(map[string]string, len(columns)-1)
+	for _, column := range columns[1:] {
+		tag, value, found := strings.Cut(column, ":")
+		if !found || len(tag) != 2 {
+			return fmt.Errorf("%w: header field %q isn't a TAG:VALUE pair", ErrInvalidAlignment, column)
+		}
+		fields[tag] = value
+	}
+	switch columns[0] {
+	case "@HD":
+		header.HD = fields
+	case "@SQ":
+		reference := Reference{Name: fields["SN"]}
+		length, err := strconv.Atoi(fields["LN"])
+		if reference.Name == "" || err != nil {
+			return fmt.Errorf("%w: @SQ lines need an SN name and an LN length", ErrInvalidAlignment)
+		}
+		reference.Length = length
+		delete(fields, "SN")
+		delete(fields, "LN")
+		if len(fields) > 0 {
+			reference.Fields = fields
+		}
+		header.References = append(header.References, reference)
+	case "@RG":
+		header.ReadGroups = append(header.ReadGroups, fields)
+	case "@PG":
+		header.Programs = append(header.Programs, fields)
+	default:
+		return fmt.Errorf("%w: unknown header line %s", ErrInvalidAlignment, columns[0])
+	}
+	return nil
+}
+
+// parseAlignment parses a single alignment line.
+func parseAlignment(line string) (Alignment, error) {
+	columns := strings.Split(line, "\t")
+	if len(columns) < 11 {
+		return Alignment{}, fmt.Errorf("%w: expected at least 11 tab separated columns, got %d", ErrInvalidAlignment, len(columns))
+	}
+	var integers [5]int
+	for index, column := range []int{1, 3, 4, 7, 8} {
+		integer, err := strconv.Atoi(columns[column])
+		if err != nil {
+			return Alignment{}, fmt.Errorf("%w: column %d (%q) is not a number", ErrInvalidAlignment, column+1, columns[column])
+		}
+		integers[index] = integer
+	}
+	cigar, err := ParseCigar(columns[5])
+	if err != nil {
+		return Alignment{}, err
+	}
+	alignment := Alignment{
+		QueryName:     columns[0],
+		Flag:          integers[0],
+		ReferenceName: columns[2],
+		// Indexing starts at 1 for sam so we need to shift down for Sequence 0 index.
+		Position:       integers[1] - 1,
+		MappingQuality: integers[2],
+		Cigar:          cigar,
+		NextReference:  columns[6],
+		NextPosition:   integers[3] - 1,
+		TemplateLength: integers[4],
+		Sequence:       columns[9],
+		Quality:        columns[10],
+	}
+	if alignment.Quality != "*" && alignment.Sequence != "*" && len(alignment.Quality) != len(alignment.Sequence) {
+		return Alignment{}, fmt.Errorf("%w: %d quality scores for %d bases", ErrInvalidAlignment, len(alignment.Quality), len(alignment.Sequence))
+	}
+	for _, column := range columns[11:] {
+		fields := strings.SplitN(column, ":", 3)
+		if len(fields) != 3 || len(fields[0]) != 2 || len(fields[1]) != 1 || !strings.Contains("AifZHB", fields[1]) {
+			return Alignment{}, fmt.Errorf("%w: optional field %q isn't a TAG:TYPE:VALUE field", ErrInvalidAlignment, column)
+		}
+		alignment.Tags = append(alignment.Tags, Tag{Tag: fields[0], Type: fields[1][0], Value: fields[2]})
+	}
+	return alignment, nil
+}
+
+/******************************************************************************
+
+SAM parser ends here.
+
+******************************************************************************/
+
+/******************************************************************************
+
+BAM parser begins here.
+
+******************************************************************************/
+
+// bamMagic starts every decompressed BAM file.
+var bamMagic = []byte("BAM\x01")
+
+// ParseBAM parses a BAM file. BAM files are BGZF compressed, which is a
+// series of gzip blocks, so file can be either the compressed bytes of a BAM
+// file or the decompressed ones.
+func ParseBAM(file []byte) (Sam, error) {
+	if bytes.HasPrefix(file, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(file))
+		if err != nil {
+			return Sam{}, err
+		}
+		defer reader.Close()
+		if file, err = ioutil.ReadAll(reader); err != nil {
+			return Sam{}, err
+		}
+	}
+	if !bytes.HasPrefix(file, bamMagic) {
+		return Sam{}, fmt.Errorf("sam: %w: missing BAM magic number", ErrInvalidAlignment)
+	}
+
+	reader := &bamReader{data: file[len(bamMagic):]}
+	headerText := reader.bytes(reader.int32())
+	referenceCount := reader.int32()
+	if reader.err != nil {
+		return Sam{}, fmt.Errorf("sam: %w: truncated BAM header", ErrInvalidAlignment)
+	}
+	sam, err := Parse(bytes.TrimRight(headerText, "\x00"))
+	if err != nil {
+		return Sam{}, err
+	}
+	// the binary reference list is authoritative, the header text can leave @SQ lines out.
+	var referenceNames []string
+	var references []Reference
+	for index := 0; index < referenceCount; index++ {
+		name := string(bytes.TrimRight(reader.bytes(reader.int32()), "\x00"))
+		length := reader.int32()
+		referenceNames = append(referenceNames, name)
+		reference := Reference{Name: name, Length: length}
+		for _, headerReference := range sam.Header.References {
+			if headerReference.Name == name {
+				reference.Fields = headerReference.Fields
+			}
+		}
+		references = append(references, reference)
+	}
+	if reader.err != nil {
+		return Sam{}, fmt.Errorf("sam: %w: truncated BAM reference list", ErrInvalidAlignment)
+	}
+	sam.Header.References = references
+
+	for recordNumber := 1; len(reader.data) > 0; recordNumber++ {
+		blockSize := reader.int32()
+		record := &bamReader{data: reader.bytes(blockSize)}
+		if reader.err != nil {
+			return Sam{}, &ParseError{recordNumber, fmt.Errorf("%w: truncated record", ErrInvalidAlignment)}
+		}
+		alignment, err := record.alignment(referenceNames)
+		if err != nil {
+			return Sam{}, &ParseError{recordNumber, err}
+		}
+		sam.Alignments = append(sam.Alignments, alignment)
+	}
+	return sam, nil
+}
+
+// bamReader reads little-endian values from BAM data, recording the first
+// time it runs out of data in err rather than returning it from every call.
+type bamReader struct {
+	data []byte
+	err  error
+}
+
+func (reader *bamReader) bytes(length int) []byte {
+	if reader.err != nil || length < 0 || length > len(reader.data) {
+		reader.err = fmt.Errorf("%w: truncated record", ErrInvalidAlignment)
+		return nil
+	}
+	bytes := reader.data[:length]
+	reader.data = reader.data[length:]
+	return bytes
+}
+
+func (reader *bamReader) uint8() int {
+	if bytes := reader.bytes(1); bytes != nil {
+		return int(bytes[0])
+	}
+	return 0
+}
+
+func (reader *bamReader) uint16() int {
+	if bytes := reader.bytes(2); bytes != nil {
+		return int(binary.LittleEndian.Uint16(bytes))
+	}
+	return 0
+}
+
+func (reader *bamReader) uint32() uint32 {
+	if bytes := reader.bytes(4); bytes != nil {
+		return binary.LittleEndian.Uint32(bytes)
+	}
+	return 0
+}
+
+func (reader *bamReader) int32() int {
+	return int(int32(reader.uint32()))
+}
+
+// alignment reads a single BAM record, not including its block_size.
+func (reader *bamReader) alignment(referenceNames []string) (Alignment, error) {
+	referenceID := reader.int32()
+	position := reader.int32()
+	queryNameLength := reader.uint8()
+	mappingQuality := reader.uint8()
+	reader.uint16() // bin, which is only needed for indexing.
+	cigarLength := reader.uint16()
+	flag := reader.uint16()
+	sequenceLength := reader.int32()
+	nextReferenceID := reader.int32()
+	nextPosition := reader.int32()
+	templateLength := reader.int32()
+	queryName := reader.bytes(queryNameLength)
+
+	alignment := Alignment{
+		QueryName:      string(bytes.TrimRight(queryName, "\x00")),
+		Flag:           flag,
+		Position:       position,
+		MappingQuality: mappingQuality,
+		NextPosition:   nextPosition,
+		TemplateLength: templateLength,
+	}
+	var err error
+	if alignment.ReferenceName, err = referenceName(referenceNames, referenceID); err != nil {
+		return Alignment{}, err
+	}
+	if alignment.NextReference, err = referenceName(referenceNames, nextReferenceID); err != nil {
+		return Alignment{}, err
+	}
+	if nextReferenceID >= 0 && nextReferenceID == referenceID {
+		alignment.NextReference = "="
+	}
+
+	for index := 0; index < cigarLength; index++ {
+		operation := reader.uint32()
+		if int(operation&0xf) >= len(cigarOperations) {
+			return Alignment{}, fmt.Errorf("%w: unknown BAM operation %d", ErrInvalidCigar, operation&0xf)
+		}
+		alignment.Cigar = append(alignment.Cigar, CigarOperation{Length: int(operation >> 4), Operation: cigarOperations[operation&0xf]})
+	}
+
+	packedSequence := reader.bytes((sequenceLength + 1) / 2)
+	qualities := reader.bytes(sequenceLength)
+	if reader.err != nil {
+		return Alignment{}, reader.err
+	}
+	alignment.Sequence, alignment.Quality = "*", "*"
+	if sequenceLength > 0 {
+		const bases = "=ACMGRSVTWYHKDBN"
+		sequence := make([]byte, sequenceLength)
+		for index := range sequence {
+			packed := packedSequence[index/2]
+			if index%2 == 0 {
+				sequence[index] = bases[packed>>4]
+			} else {
+				sequence[index] = bases[packed&0xf]
+			}
+		}
+		alignment.Sequence = string(sequence)
+		// a first quality of 0xff means the qualities weren't stored.
+		if qualities[0] != 0xff {
+			quality := make([]byte, sequenceLength)
+			for index, score := range qualities {
+				quality[index] = score + 33
+			}
+			alignment.Quality = string(quality)
+		}
+	}
+
+	for len(reader.data) > 0 {
+		tag, err := reader.tag()
+		if err != nil {
+			return Alignment{}, err
+		}
+		alignment.Tags = append(alignment.Tags, tag)
+	}
+	return alignment, nil
+}
+
+// referenceName looks up a BAM reference ID, which is -1 for "*".
+func referenceName(referenceNames []string, referenceID int) (string, error) {
+	if referenceID == -1 {
+		return "*", nil
+	}
+	if referenceID < 0 || referenceID >= len(referenceNames) {
+		return "", fmt.Errorf("%w: reference ID %d is out of range", ErrInvalidAlignment, referenceID)
+	}
+	return referenceNames[referenceID], nil
+}
+
+// tag reads a single optional field of a BAM record, converting its value to SAM text.
+func (reader *bamReader) tag() (Tag, error) {
+	name := string(reader.bytes(2))
+	valueType := byte(reader.uint8())
+	tag := Tag{Tag: name, Type: valueType}
+	switch valueType {
+	case 'A':
+		tag.Value = string(reader.bytes(1))
+	case 'c', 'C', 's', 'S', 'i', 'I':
+		tag.Type = 'i'
+		tag.Value = strconv.FormatInt(reader.integer(valueType), 10)
+	case 'f':
+		tag.Value = strconv.FormatFloat(float64(math.Float32frombits(reader.uint32())), 'g', -1, 32)
+	case 'Z', 'H':
+		end := bytes.IndexByte(reader.data, 0)
+		if end < 0 {
+			return Tag{}, fmt.Errorf("%w: unterminated %s tag", ErrInvalidAlignment, name)
+		}
+		tag.Value = string(reader.bytes(end))
+		reader.bytes(1)
+	case 'B':
+		elementType := byte(reader.uint8())
+		count := reader.int32()
+		values := []string{string(elementType)}
+		for index := 0; index < count && reader.err == nil; index++ {
+			if elementType == 'f' {
+				values = append(values, strconv.FormatFloat(float64(math.Float32frombits(reader.uint32())), 'g', -1, 32))
+				continue
+			}
+			if !strings.ContainsRune("cCsSiI", rune(elementType)) {
+				return Tag{}, fmt.Errorf("%w: unknown array type %q in %s tag", ErrInvalidAlignment, elementType, name)
+			}
+			values = append(values, strconv.FormatInt(reader.integer(elementType), 10))
+		}
+		tag.Value = strings.Join(values, ",")
+	default:
+		return Tag{}, fmt.Errorf("%w: unknown type %q in %s tag", ErrInvalidAlignment, valueType, name)
+	}
+	if reader.err != nil {
+		return Tag{}, reader.err
+	}
+	return tag, nil
+}
+
+// integer reads a BAM integer of one of the types cCsSiI.
+func (reader *bamReader) integer(valueType byte) int64 {
+	switch valueType {
+	case 'c':
+		return int64(int8(reader.uint8()))
+	case 'C':
+		return int64(reader.uint8())
+	case 's':
+		return int64(int16(reader.uint16()))
+	case 'S':
+		return int64(reader.uint16())
+	case 'i':
+		return int64(int32(reader.uint32()))
+	}
+	return int64(reader.uint32())
+}
+
+/******************************************************************************
+
+BAM parser ends here.
+
+******************************************************************************/
+
+// Build writes a Sam struct out as a SAM file.
+func Build(sam Sam) ([]byte, error) {
+	var samBuffer bytes.Buffer
+	if len(sam.Header.HD) > 0 {
+		samBuffer.WriteString(buildHeaderLine("@HD", sam.Header.HD, "VN", "SO"))
+	}
+	for _, reference := range sam.Header.References {
+		fields := map[string]string{"SN": reference.Name, "LN": strconv.Itoa(reference.Length)}
+		for tag, value := range reference.Fields {
+			fields[tag] = value
+		}
+		samBuffer.WriteString(buildHeaderLine("@SQ", fields, "SN", "LN"))
+	}
+	for _, readGroup := range sam.Header.ReadGroups {
+		samBuffer.WriteString(buildHeaderLine("@RG", readGroup, "ID"))
+	}
+	for _, program := range sam.Header.Programs {
+		samBuffer.WriteString(buildHeaderLine("@PG", program, "ID"))
+	}
+	for _, comment := range sam.Header.Comments {
+		samBuffer.WriteString("@CO\t" + comment + "\n")
+	}
+
+	for index, alignment := range sam.Alignments {
+		if alignment.QueryName == "" {
+			return nil, fmt.Errorf("sam: alignment %d: %w: missing query name", index, ErrInvalidAlignment)
+		}
+		columns := []string{
+			alignment.QueryName,
+			strconv.Itoa(alignment.Flag),
+			missing(alignment.ReferenceName),
+			// Indexing starts at 1 for sam so we need to shift up from Sequence 0 index.
+			strconv.Itoa(alignment.Position + 1),
+			strconv.Itoa(alignment.MappingQuality),
+			CigarString(alignment.Cigar),
+			missing(alignment.NextReference),
+			strconv.Itoa(alignment.NextPosition + 1),
+			strconv.Itoa(alignment.TemplateLength),
+			missing(alignment.Sequence),
+			missing(alignment.Quality),
+		}
+		for _, tag := range alignment.Tags {
+			columns = append(columns, tag.Tag+":"+string(tag.Type)+":"+tag.Value)
+		}
+		samBuffer.WriteString(strings.Join(columns, "\t") + "\n")
+	}
+	return samBuffer.Bytes(), nil
+}
+
+// buildHeaderLine writes a header line with the first tags in order, followed by the rest alphabetically.
+func buildHeaderLine(recordType string, fields map[string]string, first ...string) string {
+	line := []string{recordType}
+	written := make(map[string]bool)
+	for _, tag := range first {
+		if value, ok := fields[tag]; ok {
+			line = append(line, tag+":"+value)
+			written[tag] = true
+		}
+	}
+	var rest []string
+	for tag := range fields {
+		if !written[tag] {
+			rest = append(rest, tag)
+		}
+	}
+	sort.Strings(rest)
+	for _, tag := range rest {
+		line = append(line, tag+":"+fields[tag])
+	}
+	return strings.Join(line, "\t") + "\n"
+}
+
+// missing returns the SAM missing value "*" for empty strings.
+func missing(value string) string {
+	if value == "" {
+		return "*"
+	}
+	return value
+}
+
+/******************************************************************************
+
+Pileup functions begin here.
+
+******************************************************************************/
+
+// PileupColumn counts what the reads aligned to a single reference position say is there.
+type PileupColumn struct {
+	Bases      map[byte]int   `json:"bases"`      // upper case bases of reads aligned to the position.
+	Deletions  int            `json:"deletions"`  // reads with a deletion at the position.
+	Insertions map[string]int `json:"insertions"` // upper case bases reads insert after the position.
+}
+
+// Depth returns the number of reads covering a column, including deletions.
+func (column PileupColumn) Depth() int {
+	depth := column.Deletions
+	for _, count := range column.Bases {
+		depth += count
+	}
+	return depth
+}
+
+// Pileup piles up the alignments to referenceName, returning a column for
+// each position of a reference of the given length. Unmapped, secondary,
+// QC failed and duplicate alignments, and bases with a quality below
+// minQuality, are skipped.
+func Pileup(alignments []Alignment, referenceName string, length int, minQuality int) []PileupColumn {
+	columns := make([]PileupColumn, length)
+	for index := range columns {
+		columns[index] = PileupColumn{Bases: map[byte]int{}, Insertions: map[string]int{}}
+	}
+	for _, alignment := range alignments {
+		if alignment.ReferenceName != referenceName || alignment.Sequence == "*" || alignment.Flag&(FlagUnmapped|FlagSecondary|FlagQCFail|FlagDuplicate) != 0 {
+			continue
+		}
+		referencePosition, queryPosition := alignment.Position, 0
+		for _, operation := range alignment.Cigar {
+			switch {
+			case operation.Operation == 'I' && referencePosition > 0 && referencePosition <= length && queryPosition+operation.Length <= len(alignment.Sequence):
+				inserted := strings.ToUpper(alignment.Sequence[queryPosition : queryPosition+operation.Length])
+				columns[referencePosition-1].Insertions[inserted]++
+			case operation.Operation == 'D':
+				for offset := 0; offset < operation.Length; offset++ {
+					if position := referencePosition + offset; position >= 0 && position < length {
+						columns[position].Deletions++
+					}
+				}
+			case consumesReference(operation.Operation) && consumesQuery(operation.Operation):
+				for offset := 0; offset < operation.Length; offset++ {
+					position, base := referencePosition+offset, queryPosition+offset
+					if position < 0 || position >= length || base >= len(alignment.Sequence) {
+						continue
+					}
+					if alignment.Quality != "*" && int(alignment.Quality[base])-33 < minQuality {
+						continue
+					}
+					columns[position].Bases[strings.ToUpper(alignment.Sequence[base : base+1])[0]]++
+				}
+			}
+			if consumesReference(operation.Operation) {
+				referencePosition += operation.Length
+			}
+			if consumesQuery(operation.Operation) {
+				queryPosition += operation.Length
+			}
+		}
+	}
+	return columns
+}
+
+// Consensus calls a majority consensus sequence from pileup columns. Columns
+// covered by fewer than minDepth reads are written as N. Columns where most
+// reads have a deletion are left out, and insertions that more than half of
+// the reads covering a column share are added after it.
+func Consensus(columns []PileupColumn, minDepth int) string {
+	var consensus strings.Builder
+	for _, column := range columns {
+		depth := column.Depth()
+		if depth == 0 || depth < minDepth {
+			consensus.WriteByte('N')
+			continue
+		}
+		base, count := mostCommonBase(column.Bases)
+		if column.Deletions <= count {
+			consensus.WriteByte(base)
+		}
+		for inserted, insertions := range column.Insertions {
+			if insertions*2 > depth {
+				consensus.WriteString(inserted)
+			}
+		}
+	}
+	return consensus.String()
+}
+
+// mostCommonBase returns the most common base, breaking ties alphabetically so that consensus calls are deterministic.
+func mostCommonBase(bases map[byte]int) (byte, int) {
+	best, bestCount := byte('N'), 0
+	for base, count := range bases {
+		if count > bestCount || (count == bestCount && base < best) {
+			best, bestCount = base, count
+		}
+	}
+	return best, bestCount
+}
+
+/******************************************************************************
+
+Pileup functions end here.
+
+******************************************************************************/
+
+// Read reads a SAM or BAM file. BAM files are detected automatically as are gzip and bzip2 compressed SAM files.
+func Read(path string) (Sam, error) {
+	file, err := compress.ReadFile(path)
+	if err != nil {
+		return Sam{}, err
+	}
+	if bytes.HasPrefix(file, bamMagic) {
+		return ParseBAM(file)
+	}
+	return Parse(file)
+}
+
+// Write takes a Sam struct and a path string and writes out a SAM file to that path.
+func Write(sam Sam, path string) error {
+	samBytes, err := Build(sam)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, samBytes, 0644)
+}
@@ -0,0 +1,189 @@
+package sam_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TimothyStiles/poly/io/sam"
+	"github.com/google/go-cmp/cmp"
+)
+
+// reference is the sequence the reads of data/example.sam are aligned to.
+const reference = "AGCATGTTAGATAAGATAGCTGTGCTAGTAGGCAGTCAGCGCCAT"
+
+func ExampleRead() {
+	alignments, _ := sam.Read("../../data/example.sam")
+	read := alignments.Alignments[0]
+
+	fmt.Println(read.QueryName, read.Position, sam.CigarString(read.Cigar), read.End())
+	// Output: r001 6 8M2I4M1D3M 22
+}
+
+func ExampleConsensus() {
+	alignments, _ := sam.Read("../../data/example.bam")
+	columns := sam.Pileup(alignments.Alignments, "ref", 45, 0)
+
+	fmt.Println(sam.Consensus(columns[6:22], 1))
+	// Output: TTAGATAAGATAGCTG
+}
+
+func TestParse(t *testing.T) {
+	alignments, err := sam.Read("../../data/example.sam")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedHeader := sam.Header{
+		HD: map[string]string{"VN": "1.6", "SO": "coordinate"},
+		References: []sam.Reference{
+			{Name: "ref", Length: 45},
+			{Name: "ref2", Length: 40, Fields: map[string]string{"M5": "1b22b98cdeb4a9304cb5d48026a85128"}},
+		},
+		ReadGroups: []map[string]string{{"ID": "sample1", "SM": "poly", "PL": "ILLUMINA"}},
+		Programs:   []map[string]string{{"ID": "bwa", "PN": "bwa", "VN": "0.7.17"}},
+		Comments:   []string{"made up reads for testing"},
+	}
+	if diff := cmp.Diff(expectedHeader, alignments.Header); diff != "" {
+		t.Errorf("unexpected header (-want +got):\n%s", diff)
+	}
+
+	expectedRead := sam.Alignment{
+		QueryName:      "r002",
+		ReferenceName:  "ref",
+		Position:       8,
+		MappingQuality: 30,
+		Cigar:          []sam.CigarOperation{{3, 'S'}, {6, 'M'}, {1, 'P'}, {1, 'I'}, {4, 'M'}},
+		NextReference:  "*",
+		NextPosition:   -1,
+		Sequence:       "AAAAGATAAGGATA",
+		Quality:        "IIIIIIIIIIIIII",
+		Tags:           []sam.Tag{{"RG", 'Z', "sample1"}, {"XB", 'B', "c,1,-2"}, {"XF", 'f', "3.5"}},
+	}
+	if diff := cmp.Diff(expectedRead, alignments.Alignments[1]); diff != "" {
+		t.Errorf("unexpected alignment (-want +got):\n%s", diff)
+	}
+
+	unmapped := alignments.Alignments[len(alignments.Alignments)-1]
+	if unmapped.Flag&sam.FlagUnmapped == 0 || unmapped.Cigar != nil || unmapped.Position != -1 {
+		t.Errorf("expected r005 to be unmapped, got %v", unmapped)
+	}
+	if tag, ok := alignments.Alignments[0].GetTag("NM"); !ok || tag.Value != "3" {
+		t.Errorf("expected r001 to have NM:i:3, got %v", tag)
+	}
+	if spliced := alignments.Alignments[3]; spliced.End() != 40 {
+		t.Errorf("expected the spliced read r004 to end at 40, got %d", spliced.End())
+	}
+}
+
+func TestParseBAM(t *testing.T) {
+	samAlignments, err := sam.Read("../../data/example.sam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bamAlignments, err := sam.Read("../../data/example.bam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// BAM files store every integer tag as the smallest type that fits, so they all come back as i.
+	if diff := cmp.Diff(samAlignments, bamAlignments); diff != "" {
+		t.Errorf("parsing data/example.bam doesn't give the same result as data/example.sam (-sam +bam):\n%s", diff)
+	}
+
+	if _, err := sam.ParseBAM([]byte("BAM\x01\x10\x00")); !errors.Is(err, sam.ErrInvalidAlignment) {
+		t.Errorf("expected an invalid alignment error for a truncated BAM file, got %v", err)
+	}
+	if _, err := sam.ParseBAM([]byte("@HD\tVN:1.6\n")); !errors.Is(err, sam.ErrInvalidAlignment) {
+		t.Errorf("expected an invalid alignment error for a SAM file, got %v", err)
+	}
+}
+
+func TestSamIO(t *testing.T) {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	alignments, _ := sam.Read("../../data/example.sam")
+	path := filepath.Join(tmpDataDir, "example.sam")
+	if err := sam.Write(alignments, path); err != nil {
+		t.Fatal(err)
+	}
+	written, _ := ioutil.ReadFile(path)
+	original, _ := ioutil.ReadFile("../../data/example.sam")
+	if string(written) != string(original) {
+		t.Errorf("Build() did not reproduce data/example.sam. Got:\n%s", written)
+	}
+}
+
+func TestParseCigar(t *testing.T) {
+	operations, err := sam.ParseCigar("10M2I3D")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]sam.CigarOperation{{10, 'M'}, {2, 'I'}, {3, 'D'}}, operations); diff != "" {
+		t.Errorf("unexpected operations (-want +got):\n%s", diff)
+	}
+	for _, cigar := range []string{"", "M", "10", "10Q", "3M4"} {
+		if _, err := sam.ParseCigar(cigar); !errors.Is(err, sam.ErrInvalidCigar) {
+			t.Errorf("expected an invalid CIGAR error for %q, got %v", cigar, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"too few columns", "r1\t0\tref\t1\t30\t4M\n"},
+		{"bad position", "r1\t0\tref\tone\t30\t4M\t*\t0\t0\tACGT\t*\n"},
+		{"bad cigar", "r1\t0\tref\t1\t30\t4Q\t*\t0\t0\tACGT\t*\n"},
+		{"short quality", "r1\t0\tref\t1\t30\t4M\t*\t0\t0\tACGT\tII\n"},
+		{"bad tag", "r1\t0\tref\t1\t30\t4M\t*\t0\t0\tACGT\t*\tNM3\n"},
+		{"bad reference", "@SQ\tSN:ref\n"},
+	}
+	for _, test := range tests {
+		_, err := sam.Parse([]byte(test.file))
+		var parseError *sam.ParseError
+		if !errors.As(err, &parseError) || parseError.Line != 1 {
+			t.Errorf("%s: expected a parse error on line 1, got %v", test.name, err)
+		}
+	}
+}
+
+func TestPileup(t *testing.T) {
+	alignments, err := sam.Read("../../data/example.sam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns := sam.Pileup(alignments.Alignments, "ref", len(reference), 0)
+
+	// r001, r002 and r003 cover position 9, while only the supplementary r003 covers 30 since r004 skips 21 through 34.
+	if depth := columns[9].Depth(); depth != 3 {
+		t.Errorf("expected a depth of 3 at position 9, got %d", depth)
+	}
+	if depth := columns[30].Depth(); depth != 1 {
+		t.Errorf("expected a depth of 1 at position 30, got %d", depth)
+	}
+	if columns[13].Insertions["AG"] != 1 || columns[18].Deletions != 1 {
+		t.Errorf("expected r001's insertion after position 13 and deletion at 18, got %v and %d", columns[13].Insertions, columns[18].Deletions)
+	}
+
+	// the reads agree with the reference everywhere they cover except for the
+	// insertions and r001's deletion, which a single read isn't enough to call.
+	consensus := sam.Consensus(columns, 2)
+	if consensus[:6] != "NNNNNN" || consensus[8:13] != reference[8:13] {
+		t.Errorf("unexpected consensus %s", consensus)
+	}
+
+	// quality filtering drops r002, which is the only read with qualities.
+	filtered := sam.Pileup(alignments.Alignments, "ref", len(reference), 41)
+	if depth := filtered[9].Depth(); depth != 2 {
+		t.Errorf("expected a depth of 2 at position 9 after quality filtering, got %d", depth)
+	}
+}
@@ -0,0 +1,782 @@
+/*
+Package sbol provides SBOL parsers and writers.
+
+SBOL (synthetic biology open language) is the data standard used by design
+repositories like SynBioHub and iGEM's parts registry and is one of the
+formats Benchling can export. Designs are stored as RDF/XML documents made of
+ComponentDefinitions, each describing a piece of DNA (or RNA, or protein) by
+its Sequence and the SequenceAnnotations placing parts, like promoters and
+CDSs, onto that sequence:
+
+	<sbol:ComponentDefinition rdf:about="https://example.com/pExample">
+		<sbol:displayId>pExample</sbol:displayId>
+		<sbol:sequence rdf:resource="https://example.com/pExample_sequence"/>
+		<sbol:sequenceAnnotation>
+			<sbol:SequenceAnnotation rdf:about="https://example.com/pExample/promoter">
+				<sbol:location>
+					<sbol:Range rdf:about="https://example.com/pExample/promoter/range">
+						<sbol:start>1</sbol:start>
+						<sbol:end>35</sbol:end>
+						<sbol:orientation rdf:resource="http://sbols.org/v2#inline"/>
+					</sbol:Range>
+				</sbol:location>
+				<sbol:role rdf:resource="http://identifiers.org/so/SO:0000167"/>
+			</sbol:SequenceAnnotation>
+		</sbol:sequenceAnnotation>
+	</sbol:ComponentDefinition>
+
+This package reads SBOL2 documents into one Sbol struct per
+ComponentDefinition, with a Feature for each of its SequenceAnnotations, so
+that SBOL designs can be worked with the same way as GenBank and GFF files.
+Feature types are the GenBank feature keys of the sequence ontology (SO) roles
+of the annotations, like "CDS" for SO:0000316, so that features can be moved
+between formats. SBOL3 documents aren't supported yet.
+*/
+package sbol
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/TimothyStiles/poly"
+	"github.com/TimothyStiles/poly/io/internal/compress"
+	"github.com/TimothyStiles/poly/transform"
+)
+
+// Sbol is a struct that represents a single ComponentDefinition of an SBOL document.
+type Sbol struct {
+	Meta     Meta      `json:"meta"`
+	Features []Feature `json:"features"`
+	Sequence string    `json:"sequence"`
+}
+
+// Meta holds meta information about a ComponentDefinition.
+type Meta struct {
+	URI         string   `json:"uri"`
+	DisplayID   string   `json:"display_id"`
+	Version     string   `json:"version"`
+	Name        string   `json:"name"` // the ComponentDefinition's dcterms:title.
+	Description string   `json:"description"`
+	Types       []string `json:"types"` // type URIs other than topology, like BioPAX's DnaRegion.
+	Roles       []string `json:"roles"` // role URIs, usually sequence ontology terms like SO:0000155 for plasmids.
+	Circular    bool     `json:"circular"`
+	SequenceURI string   `json:"sequence_uri"`
+}
+
+// Feature is a struct that represents a SequenceAnnotation.
+type Feature struct {
+	URI       string `json:"uri"`
+	DisplayID string `json:"display_id"`
+	Name      string `json:"name"` // the SequenceAnnotation's dcterms:title.
+	// Type is the GenBank feature key of the annotation's role, like "CDS",
+	// or the role's URI if it doesn't have one.
+	Type   string `json:"type"`
+	Strand string `json:"strand"` // "+" for inline, "-" for reverseComplement or "" if the annotation has no orientation.
+	// Attributes holds the annotation's description as "note" and, for
+	// annotations that place a sub-component, the URI of the sub-component's
+	// ComponentDefinition as "definition".
+	Attributes     map[string]string `json:"attributes"`
+	Location       Location          `json:"location"`
+	ParentSequence *Sbol             `json:"-"`
+}
+
+// Location is a 0-based, end exclusive location on a ComponentDefinition's
+// sequence. Annotations with more than one Range have a SubLocation for each
+// of them. Cuts are locations where Start and End are the same.
+type Location struct {
+	Start        int        `json:"start"`
+	End          int        `json:"end"`
+	SubLocations []Location `json:"sub_locations"`
+}
+
+// GetName returns the title of the ComponentDefinition, or its displayId if it doesn't have one.
+func (sequence Sbol) GetName() string {
+	if sequence.Meta.Name != "" {
+		return sequence.Meta.Name
+	}
+	return sequence.Meta.DisplayID
+}
+
+// GetSequence returns the sequence of the ComponentDefinition.
+func (sequence Sbol) GetSequence() string {
+	return sequence.Sequence
+}
+
+// GetFeatures returns the SequenceAnnotations of the ComponentDefinition as poly.Features.
+func (sequence Sbol) GetFeatures() []poly.Feature {
+	features := make([]poly.Feature, len(sequence.Features))
+	for index, feature := range sequence.Features {
+		features[index] = feature
+	}
+	return features
+}
+
+// GetMeta returns the name, description and topology of the ComponentDefinition.
+func (sequence Sbol) GetMeta() poly.Meta {
+	return poly.Meta{Name: sequence.GetName(), Description: sequence.Meta.Description, Circular: sequence.Meta.Circular}
+}
+
+// AddFeature takes a feature and adds it to the Sbol struct.
+func (sequence *Sbol) AddFeature(feature *Feature) error {
+	feature.ParentSequence = sequence
+	sequence.Features = append(sequence.Features, *feature)
+	return nil
+}
+
+// GetType returns the type of the feature.
+func (feature Feature) GetType() string {
+	return feature.Type
+}
+
+// GetAttributes returns the attributes of the feature.
+func (feature Feature) GetAttributes() map[string]string {
+	return feature.Attributes
+}
+
+// GetSequence returns the sequence of the feature, reverse complemented if it is on the - strand.
+func (feature Feature) GetSequence() (string, error) {
+	if feature.ParentSequence == nil {
+		return "", fmt.Errorf("feature %s has no parent sequence", feature.DisplayID)
+	}
+	parentSequence := feature.ParentSequence.Sequence
+	var sequenceBuffer strings.Builder
+	locations := feature.Location.SubLocations
+	if len(locations) == 0 {
+		locations = []Location{feature.Location}
+	}
+	for _, location := range locations {
+		if location.Start < 0 || location.End > len(parentSequence) || location.Start > location.End {
+			return "", fmt.Errorf("location %d..%d is out of bounds for a sequence of length %d", location.Start+1, location.End, len(parentSequence))
+		}
+		sequenceBuffer.WriteString(parentSequence[location.Start:location.End])
+	}
+	if feature.Strand == "-" {
+		return transform.ReverseComplement(sequenceBuffer.String()), nil
+	}
+	return sequenceBuffer.String(), nil
+}
+
+/******************************************************************************
+
+Sequence ontology functions begin here.
+
+******************************************************************************/
+
+// soPrefix is the identifiers.org prefix SBOL2 uses for sequence ontology terms.
+const soPrefix = "http://identifiers.org/so/"
+
+// soFeatureKeys maps sequence ontology terms to the GenBank feature keys they correspond to.
+var soFeatureKeys = map[string]string{
+	"SO:0000110": "misc_feature", // sequence_feature
+	"SO:0000139": "RBS",          // ribosome_entry_site
+	"SO:0000141": "terminator",
+	"SO:0000147": "exon",
+	"SO:0000165": "enhancer",
+	"SO:0000167": "promoter",
+	"SO:0000188": "intron",
+	"SO:0000234": "mRNA",
+	"SO:0000252": "rRNA",
+	"SO:0000253": "tRNA",
+	"SO:0000296": "rep_origin", // origin_of_replication
+	"SO:0000316": "CDS",
+	"SO:0000410": "protein_bind", // protein_binding_site
+	"SO:0000418": "sig_peptide",  // signal_peptide
+	"SO:0000553": "polyA_site",
+	"SO:0000627": "insulator",
+	"SO:0000657": "repeat_region",
+	"SO:0000704": "gene",
+	"SO:0005850": "primer_bind", // primer_binding_site
+}
+
+// featureKeyTerms maps GenBank feature keys back to sequence ontology terms.
+var featureKeyTerms = func() map[string]string {
+	terms := make(map[string]string, len(soFeatureKeys))
+	for term, key := range soFeatureKeys {
+		terms[key] = term
+	}
+	return terms
+}()
+
+// soTerm returns the sequence ontology term of a URI, like SO:0000316, or "" if it isn't one.
+// Both the SBOL2 style http://identifiers.org/so/SO:0000316 and the newer
+// https://identifiers.org/SO:0000316 are recognized.
+func soTerm(uri string) string {
+	term := uri[strings.LastIndex(uri, "/")+1:]
+	if !strings.HasPrefix(term, "SO:") {
+		return ""
+	}
+	return term
+}
+
+// featureType returns the GenBank feature key of a role URI, or the URI itself if it doesn't have one.
+func featureType(role string) string {
+	if key, ok := soFeatureKeys[soTerm(role)]; ok {
+		return key
+	}
+	return role
+}
+
+// roleURI returns the role URI of a feature type. Types that are already URIs
+// are returned as they are and types that aren't GenBank feature keys of a
+// sequence ontology term are written as a sequence_feature.
+func roleURI(featureType string) string {
+	switch {
+	case strings.Contains(featureType, "://"):
+		return featureType
+	case strings.HasPrefix(featureType, "SO:"):
+		return soPrefix + featureType
+	}
+	if term, ok := featureKeyTerms[featureType]; ok {
+		return soPrefix + term
+	}
+	return soPrefix + "SO:0000110"
+}
+
+/******************************************************************************
+
+SBOL parser begins here.
+
+******************************************************************************/
+
+// ErrUnsupportedVersion is returned when parsing documents of SBOL versions other than SBOL2.
+var ErrUnsupportedVersion = errors.New("unsupported SBOL version")
+
+// ErrInvalidLocation is wrapped by errors about SequenceAnnotation locations that can't be parsed.
+var ErrInvalidLocation = errors.New("invalid location")
+
+// URIs of the terms this package reads and writes.
+const (
+	sbol3Namespace        = "http://sbols.org/v3#"
+	inlineOrientation     = "http://sbols.org/v2#inline"
+	reverseComplement     = "http://sbols.org/v2#reverseComplement"
+	circularTopology      = "SO:0000988"
+	linearTopology        = "SO:0000987"
+	dnaRegionType         = "http://www.biopax.org/release/biopax-level3.owl#DnaRegion"
+	proteinType           = "http://www.biopax.org/release/biopax-level3.owl#Protein"
+	nucleicAcidEncoding   = "http://www.chem.qmul.ac.uk/iubmb/misc/naseq.html"
+	aminoAcidEncoding     = "http://www.chem.qmul.ac.uk/iupac/AminoAcid/"
+	defaultNamespace      = "https://github.com/TimothyStiles/poly/"
+	defaultSequenceSuffix = "_sequence"
+)
+
+// The rdf types below are used to unmarshal documents. They match elements by
+// namespace rather than by prefix since documents are free to pick their own
+// prefixes.
+
+type rdfDocument struct {
+	XMLName              xml.Name                 `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# RDF"`
+	ComponentDefinitions []rdfComponentDefinition `xml:"http://sbols.org/v2# ComponentDefinition"`
+	Sequences            []rdfSequence            `xml:"http://sbols.org/v2# Sequence"`
+}
+
+// rdfResource is a reference to another object by its URI.
+type rdfResource struct {
+	Resource string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# resource,attr"`
+}
+
+type rdfComponentDefinition struct {
+	About               string                  `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	DisplayID           string                  `xml:"http://sbols.org/v2# displayId"`
+	Version             string                  `xml:"http://sbols.org/v2# version"`
+	Title               string                  `xml:"http://purl.org/dc/terms/ title"`
+	Description         string                  `xml:"http://purl.org/dc/terms/ description"`
+	Types               []rdfResource           `xml:"http://sbols.org/v2# type"`
+	Roles               []rdfResource           `xml:"http://sbols.org/v2# role"`
+	Sequences           []rdfResource           `xml:"http://sbols.org/v2# sequence"`
+	Components          []rdfComponent          `xml:"http://sbols.org/v2# component>Component"`
+	SequenceAnnotations []rdfSequenceAnnotation `xml:"http://sbols.org/v2# sequenceAnnotation>SequenceAnnotation"`
+}
+
+type rdfComponent struct {
+	About      string      `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Definition rdfResource `xml:"http://sbols.org/v2# definition"`
+}
+
+type rdfSequenceAnnotation struct {
+	About       string        `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	DisplayID   string        `xml:"http://sbols.org/v2# displayId"`
+	Title       string        `xml:"http://purl.org/dc/terms/ title"`
+	Description string        `xml:"http://purl.org/dc/terms/ description"`
+	Roles       []rdfResource `xml:"http://sbols.org/v2# role"`
+	Component   rdfResource   `xml:"http://sbols.org/v2# component"`
+	Locations   []rdfLocation `xml:"http://sbols.org/v2# location"`
+}
+
+// rdfLocation holds one of the kinds of location an annotation can have.
+// GenericLocations have no coordinates so only their orientation is read.
+type rdfLocation struct {
+	Range           *rdfRange `xml:"http://sbols.org/v2# Range"`
+	Cut             *rdfRange `xml:"http://sbols.org/v2# Cut"`
+	GenericLocation *rdfRange `xml:"http://sbols.org/v2# GenericLocation"`
+}
+
+type rdfRange struct {
+	Start       int         `xml:"http://sbols.org/v2# start"`
+	End         int         `xml:"http://sbols.org/v2# end"`
+	At          int         `xml:"http://sbols.org/v2# at"`
+	Orientation rdfResource `xml:"http://sbols.org/v2# orientation"`
+}
+
+type rdfSequence struct {
+	About    string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Elements string `xml:"http://sbols.org/v2# elements"`
+}
+
+// Parse parses an SBOL2 RDF/XML document into an Sbol struct for each of its
+// ComponentDefinitions, in the order they appear. ComponentDefinitions whose
+// sequences aren't in the document are given an empty sequence.
+func Parse(file []byte) ([]Sbol, error) {
+	var document rdfDocument
+	if err := xml.Unmarshal(file, &document); err != nil {
+		return nil, fmt.Errorf("sbol: %w", err)
+	}
+	if len(document.ComponentDefinitions) == 0 && bytes.Contains(file, []byte(sbol3Namespace)) {
+		return nil, fmt.Errorf("%w: SBOL3 documents aren't supported, only SBOL2", ErrUnsupportedVersion)
+	}
+
+	elements := make(map[string]string, len(document.Sequences))
+	for _, sequence := range document.Sequences {
+		elements[sequence.About] = strings.Join(strings.Fields(sequence.Elements), "")
+	}
+
+	sequences := make([]Sbol, len(document.ComponentDefinitions))
+	for index, definition := range document.ComponentDefinitions {
+		sequence, err := parseComponentDefinition(definition, elements)
+		if err != nil {
+			return nil, fmt.Errorf("sbol: ComponentDefinition %s: %w", definition.About, err)
+		}
+		sequences[index] = sequence
+	}
+	for index := range sequences {
+		for featureIndex := range sequences[index].Features {
+			sequences[index].Features[featureIndex].ParentSequence = &sequences[index]
+		}
+	}
+	return sequences, nil
+}
+
+// parseComponentDefinition converts a ComponentDefinition and its SequenceAnnotations into an Sbol struct.
+func parseComponentDefinition(definition rdfComponentDefinition, elements map[string]string) (Sbol, error) {
+	sequence := Sbol{Meta: Meta{
+		URI:         definition.About,
+		DisplayID:   definition.DisplayID,
+		Version:     definition.Version,
+		Name:        definition.Title,
+		Description: definition.Description,
+	}}
+	for _, definitionType := range definition.Types {
+		switch soTerm(definitionType.Resource) {
+		case circularTopology:
+			sequence.Meta.Circular = true
+		case linearTopology:
+		default:
+			sequence.Meta.Types = append(sequence.Meta.Types, definitionType.Resource)
+		}
+	}
+	for _, role := range definition.Roles {
+		sequence.Meta.Roles = append(sequence.Meta.Roles, role.Resource)
+	}
+	if len(definition.Sequences) > 0 {
+		sequence.Meta.SequenceURI = definition.Sequences[0].Resource
+		sequence.Sequence = elements[sequence.Meta.SequenceURI]
+	}
+
+	componentDefinitions := make(map[string]string, len(definition.Components))
+	for _, component := range definition.Components {
+		componentDefinitions[component.About] = component.Definition.Resource
+	}
+
+	for _, annotation := range definition.SequenceAnnotations {
+		feature := Feature{
+			URI:        annotation.About,
+			DisplayID:  annotation.DisplayID,
+			Name:       annotation.Title,
+			Type:       soFeatureKeys["SO:0000110"],
+			Attributes: make(map[string]string),
+		}
+		if len(annotation.Roles) > 0 {
+			feature.Type = featureType(annotation.Roles[0].Resource)
+		}
+		if annotation.Description != "" {
+			feature.Attributes["note"] = annotation.Description
+		}
+		if annotation.Component.Resource != "" {
+			feature.Attributes["definition"] = componentDefinitions[annotation.Component.Resource]
+		}
+
+		location, strand, err := parseLocations(annotation.Locations)
+		if err != nil {
+			return Sbol{}, fmt.Errorf("SequenceAnnotation %s: %w", annotation.About, err)
+		}
+		feature.Location = location
+		feature.Strand = strand
+		sequence.Features = append(sequence.Features, feature)
+	}
+	return sequence, nil
+}
+
+// parseLocations converts the locations of an annotation into a Location
+// and the strand of its first location.
+func parseLocations(rdfLocations []rdfLocation) (Location, string, error) {
+	var locations []Location
+	strand := ""
+	for index, rdfLocation := range rdfLocations {
+		var location Location
+		var orientation string
+		switch {
+		case rdfLocation.Range != nil:
+			if rdfLocation.Range.Start < 1 || rdfLocation.Range.End < rdfLocation.Range.Start {
+				return Location{}, "", fmt.Errorf("%w: range %d..%d", ErrInvalidLocation, rdfLocation.Range.Start, rdfLocation.Range.End)
+			}
+			// Indexing starts at 1 for SBOL so we need to shift down for Sequence 0 index.
+			location = Location{Start: rdfLocation.Range.Start - 1, End: rdfLocation.Range.End}
+			orientation = rdfLocation.Range.Orientation.Resource
+		case rdfLocation.Cut != nil:
+			if rdfLocation.Cut.At < 0 {
+				return Location{}, "", fmt.Errorf("%w: cut at %d", ErrInvalidLocation, rdfLocation.Cut.At)
+			}
+			// a cut at n is between the nth and n+1th bases, which is the 0 length location n..n.
+			location = Location{Start: rdfLocation.Cut.At, End: rdfLocation.Cut.At}
+			orientation = rdfLocation.Cut.Orientation.Resource
+		case rdfLocation.GenericLocation != nil:
+			if index == 0 {
+				strand = parseOrientation(rdfLocation.GenericLocation.Orientation.Resource)
+			}
+			continue
+		default:
+			return Location{}, "", fmt.Errorf("%w: location %d isn't a Range, Cut or GenericLocation", ErrInvalidLocation, index)
+		}
+		if index == 0 {
+			strand = parseOrientation(orientation)
+		}
+		locations = append(locations, location)
+	}
+
+	switch len(locations) {
+	case 0:
+		return Location{}, strand, nil
+	case 1:
+		return locations[0], strand, nil
+	}
+	location := Location{Start: locations[0].Start, End: locations[0].End, SubLocations: locations}
+	for _, subLocation := range locations[1:] {
+		if subLocation.Start < location.Start {
+			location.Start = subLocation.Start
+		}
+		if subLocation.End > location.End {
+			location.End = subLocation.End
+		}
+	}
+	return location, strand, nil
+}
+
+// parseOrientation returns the strand of an orientation URI.
+func parseOrientation(orientation string) string {
+	switch orientation {
+	case inlineOrientation:
+		return "+"
+	case reverseComplement:
+		return "-"
+	}
+	return ""
+}
+
+/******************************************************************************
+
+SBOL parser ends here.
+
+******************************************************************************/
+
+/******************************************************************************
+
+SBOL writer begins here.
+
+******************************************************************************/
+
+// The xml types below are used to marshal documents with the prefixes SBOL
+// tools conventionally use.
+
+type xmlDocument struct {
+	XMLName              xml.Name                 `xml:"rdf:RDF"`
+	RDFNamespace         string                   `xml:"xmlns:rdf,attr"`
+	DCTermsNamespace     string                   `xml:"xmlns:dcterms,attr"`
+	SBOLNamespace        string                   `xml:"xmlns:sbol,attr"`
+	ComponentDefinitions []xmlComponentDefinition `xml:"sbol:ComponentDefinition"`
+	Sequences            []xmlSequence            `xml:"sbol:Sequence"`
+}
+
+type xmlResource struct {
+	Resource string `xml:"rdf:resource,attr"`
+}
+
+type xmlComponentDefinition struct {
+	About               string                  `xml:"rdf:about,attr"`
+	DisplayID           string                  `xml:"sbol:displayId"`
+	Version             string                  `xml:"sbol:version,omitempty"`
+	Title               string                  `xml:"dcterms:title,omitempty"`
+	Description         string                  `xml:"dcterms:description,omitempty"`
+	Types               []xmlResource           `xml:"sbol:type"`
+	Roles               []xmlResource           `xml:"sbol:role"`
+	Sequence            *xmlResource            `xml:"sbol:sequence"`
+	Components          []xmlComponentProperty  `xml:"sbol:component"`
+	SequenceAnnotations []xmlAnnotationProperty `xml:"sbol:sequenceAnnotation"`
+}
+
+// RDF/XML property elements can only hold a single object, so each Component
+// and SequenceAnnotation is wrapped in a property element of its own.
+
+type xmlComponentProperty struct {
+	Component xmlComponent `xml:"sbol:Component"`
+}
+
+type xmlAnnotationProperty struct {
+	SequenceAnnotation xmlSequenceAnnotation `xml:"sbol:SequenceAnnotation"`
+}
+
+type xmlComponent struct {
+	About      string      `xml:"rdf:about,attr"`
+	DisplayID  string      `xml:"sbol:displayId"`
+	Definition xmlResource `xml:"sbol:definition"`
+	Access     xmlResource `xml:"sbol:access"`
+}
+
+type xmlSequenceAnnotation struct {
+	About       string        `xml:"rdf:about,attr"`
+	DisplayID   string        `xml:"sbol:displayId"`
+	Title       string        `xml:"dcterms:title,omitempty"`
+	Description string        `xml:"dcterms:description,omitempty"`
+	Locations   []xmlLocation `xml:"sbol:location"`
+	Roles       []xmlResource `xml:"sbol:role"`
+	Component   *xmlResource  `xml:"sbol:component"`
+}
+
+type xmlLocation struct {
+	Range *xmlRange `xml:"sbol:Range"`
+	Cut   *xmlCut   `xml:"sbol:Cut"`
+}
+
+type xmlRange struct {
+	About       string       `xml:"rdf:about,attr"`
+	DisplayID   string       `xml:"sbol:displayId"`
+	Start       int          `xml:"sbol:start"`
+	End         int          `xml:"sbol:end"`
+	Orientation *xmlResource `xml:"sbol:orientation"`
+}
+
+type xmlCut struct {
+	About       string       `xml:"rdf:about,attr"`
+	DisplayID   string       `xml:"sbol:displayId"`
+	At          int          `xml:"sbol:at"`
+	Orientation *xmlResource `xml:"sbol:orientation"`
+}
+
+type xmlSequence struct {
+	About     string      `xml:"rdf:about,attr"`
+	DisplayID string      `xml:"sbol:displayId"`
+	Elements  string      `xml:"sbol:elements"`
+	Encoding  xmlResource `xml:"sbol:encoding"`
+}
+
+// Build writes Sbol structs out as an SBOL2 RDF/XML document. Sequences and
+// features without URIs or displayIds are given ones made from their names
+// and positions, under this package's namespace if their ComponentDefinition
+// doesn't have a URI either. Feature types that aren't sequence ontology
+// terms, GenBank feature keys of one or URIs are written as sequence_features.
+func Build(sequences []Sbol) ([]byte, error) {
+	document := xmlDocument{
+		RDFNamespace:     "http://www.w3.org/1999/02/22-rdf-syntax-ns#",
+		DCTermsNamespace: "http://purl.org/dc/terms/",
+		SBOLNamespace:    "http://sbols.org/v2#",
+	}
+	for index, sequence := range sequences {
+		definition, xmlSequence := buildComponentDefinition(sequence, index)
+		document.ComponentDefinitions = append(document.ComponentDefinitions, definition)
+		if xmlSequence != nil {
+			document.Sequences = append(document.Sequences, *xmlSequence)
+		}
+	}
+
+	sbol, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte(xml.Header), sbol...), '\n'), nil
+}
+
+// buildComponentDefinition converts an Sbol struct into a ComponentDefinition
+// and, if it has one, the Sequence it refers to.
+func buildComponentDefinition(sequence Sbol, index int) (xmlComponentDefinition, *xmlSequence) {
+	displayID := sequence.Meta.DisplayID
+	if displayID == "" {
+		displayID = buildDisplayID(sequence.Meta.Name, fmt.Sprintf("sequence%d", index))
+	}
+	uri := sequence.Meta.URI
+	if uri == "" {
+		uri = defaultNamespace + displayID
+	}
+	definition := xmlComponentDefinition{
+		About:       uri,
+		DisplayID:   displayID,
+		Version:     sequence.Meta.Version,
+		Title:       sequence.Meta.Name,
+		Description: sequence.Meta.Description,
+	}
+
+	types := sequence.Meta.Types
+	if len(types) == 0 {
+		types = []string{dnaRegionType}
+	}
+	for _, definitionType := range types {
+		definition.Types = append(definition.Types, xmlResource{definitionType})
+	}
+	if sequence.Meta.Circular {
+		definition.Types = append(definition.Types, xmlResource{soPrefix + circularTopology})
+	}
+	for _, role := range sequence.Meta.Roles {
+		definition.Roles = append(definition.Roles, xmlResource{role})
+	}
+
+	for featureIndex, feature := range sequence.Features {
+		annotation, component := buildSequenceAnnotation(feature, uri, featureIndex)
+		if component != nil {
+			definition.Components = append(definition.Components, xmlComponentProperty{*component})
+		}
+		definition.SequenceAnnotations = append(definition.SequenceAnnotations, xmlAnnotationProperty{annotation})
+	}
+
+	if sequence.Sequence == "" && sequence.Meta.SequenceURI == "" {
+		return definition, nil
+	}
+	sequenceURI := sequence.Meta.SequenceURI
+	if sequenceURI == "" {
+		sequenceURI = uri + defaultSequenceSuffix
+	}
+	definition.Sequence = &xmlResource{sequenceURI}
+	encoding := nucleicAcidEncoding
+	for _, definitionType := range types {
+		if definitionType == proteinType {
+			encoding = aminoAcidEncoding
+		}
+	}
+	return definition, &xmlSequence{
+		About:     sequenceURI,
+		DisplayID: displayID + defaultSequenceSuffix,
+		Elements:  sequence.Sequence,
+		Encoding:  xmlResource{encoding},
+	}
+}
+
+// buildSequenceAnnotation converts a Feature into a SequenceAnnotation and, if
+// it places a sub-component, the Component it refers to.
+func buildSequenceAnnotation(feature Feature, definitionURI string, index int) (xmlSequenceAnnotation, *xmlComponent) {
+	displayID := feature.DisplayID
+	if displayID == "" {
+		displayID = fmt.Sprintf("annotation%d", index)
+	}
+	uri := feature.URI
+	if uri == "" {
+		uri = definitionURI + "/" + displayID
+	}
+	annotation := xmlSequenceAnnotation{
+		About:       uri,
+		DisplayID:   displayID,
+		Title:       feature.Name,
+		Description: feature.Attributes["note"],
+		Roles:       []xmlResource{{roleURI(feature.Type)}},
+	}
+
+	var orientation *xmlResource
+	switch feature.Strand {
+	case "+":
+		orientation = &xmlResource{inlineOrientation}
+	case "-":
+		orientation = &xmlResource{reverseComplement}
+	}
+	locations := feature.Location.SubLocations
+	if len(locations) == 0 {
+		locations = []Location{feature.Location}
+	}
+	for locationIndex, location := range locations {
+		locationDisplayID := fmt.Sprintf("location%d", locationIndex)
+		if location.Start == location.End {
+			annotation.Locations = append(annotation.Locations, xmlLocation{Cut: &xmlCut{
+				About:       uri + "/" + locationDisplayID,
+				DisplayID:   locationDisplayID,
+				At:          location.Start,
+				Orientation: orientation,
+			}})
+			continue
+		}
+		// Indexing starts at 1 for SBOL so we need to shift up from Sequence 0 index.
+		annotation.Locations = append(annotation.Locations, xmlLocation{Range: &xmlRange{
+			About:       uri + "/" + locationDisplayID,
+			DisplayID:   locationDisplayID,
+			Start:       location.Start + 1,
+			End:         location.End,
+			Orientation: orientation,
+		}})
+	}
+
+	definition := feature.Attributes["definition"]
+	if definition == "" {
+		return annotation, nil
+	}
+	componentDisplayID := displayID + "_component"
+	component := xmlComponent{
+		About:      definitionURI + "/" + componentDisplayID,
+		DisplayID:  componentDisplayID,
+		Definition: xmlResource{definition},
+		Access:     xmlResource{"http://sbols.org/v2#public"},
+	}
+	annotation.Component = &xmlResource{component.About}
+	return annotation, &component
+}
+
+// buildDisplayID makes a valid displayId out of a name by replacing the
+// characters displayIds can't have with underscores. Empty names get the fallback.
+func buildDisplayID(name string, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	displayID := []byte(name)
+	for index, character := range displayID {
+		isLetter := (character >= 'a' && character <= 'z') || (character >= 'A' && character <= 'Z')
+		isDigit := character >= '0' && character <= '9'
+		if !isLetter && !isDigit && character != '_' {
+			displayID[index] = '_'
+		}
+	}
+	// displayIds can't start with a digit.
+	if displayID[0] >= '0' && displayID[0] <= '9' {
+		return "_" + string(displayID)
+	}
+	return string(displayID)
+}
+
+/******************************************************************************
+
+SBOL writer ends here.
+
+******************************************************************************/
+
+// Read reads an SBOL2 RDF/XML file into Sbol structs. Gzip and bzip2 compressed files are detected and decompressed automatically.
+func Read(path string) ([]Sbol, error) {
+	file, err := compress.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(file)
+}
+
+// Write takes Sbol structs and a path string and writes out an SBOL2 RDF/XML file to that path.
+func Write(sequences []Sbol, path string) error {
+	sbol, err := Build(sequences)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, sbol, 0644)
+}
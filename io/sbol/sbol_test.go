@@ -0,0 +1,138 @@
+package sbol_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TimothyStiles/poly/io/sbol"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func ExampleRead() {
+	sequences, _ := sbol.Read("../../data/example.sbol.xml")
+	plasmid := sequences[0]
+	cds, _ := plasmid.Features[1].GetSequence()
+
+	fmt.Println(plasmid.GetName(), plasmid.Meta.Circular, plasmid.Features[1].Type, cds)
+	// Output: pExample true CDS atggctagcaaataa
+}
+
+func ExampleBuild() {
+	sequence := sbol.Sbol{Meta: sbol.Meta{Name: "my part"}, Sequence: "atgtaa"}
+	_ = sequence.AddFeature(&sbol.Feature{Type: "CDS", Strand: "+", Location: sbol.Location{Start: 0, End: 6}})
+
+	sbolBytes, _ := sbol.Build([]sbol.Sbol{sequence})
+	sequences, _ := sbol.Parse(sbolBytes)
+
+	fmt.Println(sequences[0].Meta.URI, sequences[0].Features[0].URI)
+	// Output: https://github.com/TimothyStiles/poly/my_part https://github.com/TimothyStiles/poly/my_part/annotation0
+}
+
+func TestParse(t *testing.T) {
+	sequences, err := sbol.Read("../../data/example.sbol.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sequences) != 2 {
+		t.Fatalf("expected 2 ComponentDefinitions, got %d", len(sequences))
+	}
+
+	expectedMeta := sbol.Meta{
+		URI:         "https://synbiohub.org/user/poly/example/pExample/1",
+		DisplayID:   "pExample",
+		Version:     "1",
+		Name:        "pExample",
+		Description: "a small example plasmid",
+		Types:       []string{"http://www.biopax.org/release/biopax-level3.owl#DnaRegion"},
+		Roles:       []string{"http://identifiers.org/so/SO:0000155"},
+		Circular:    true,
+		SequenceURI: "https://synbiohub.org/user/poly/example/pExample_sequence/1",
+	}
+	if diff := cmp.Diff(expectedMeta, sequences[0].Meta); diff != "" {
+		t.Errorf("unexpected meta (-want +got):\n%s", diff)
+	}
+
+	features := sequences[0].Features
+	expectedTypes := []string{"promoter", "CDS", "terminator", "http://identifiers.org/so/SO:0000804", "misc_feature"}
+	expectedStrands := []string{"+", "+", "-", "+", ""}
+	for index, feature := range features {
+		if feature.Type != expectedTypes[index] || feature.Strand != expectedStrands[index] {
+			t.Errorf("feature %s: expected type %s on strand %q, got %s on strand %q", feature.DisplayID, expectedTypes[index], expectedStrands[index], feature.Type, feature.Strand)
+		}
+	}
+	expectedAttributes := map[string]string{"note": "a very short reporter", "definition": "https://synbiohub.org/user/poly/example/gfp/1"}
+	if diff := cmp.Diff(expectedAttributes, features[1].Attributes); diff != "" {
+		t.Errorf("unexpected attributes (-want +got):\n%s", diff)
+	}
+	expectedSplit := sbol.Location{Start: 35, End: 50, SubLocations: []sbol.Location{{Start: 35, End: 40}, {Start: 45, End: 50}}}
+	if diff := cmp.Diff(expectedSplit, features[3].Location); diff != "" {
+		t.Errorf("unexpected split location (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(sbol.Location{Start: 55, End: 55}, features[4].Location); diff != "" {
+		t.Errorf("unexpected cut location (-want +got):\n%s", diff)
+	}
+
+	for index, expected := range []string{"ttgacaatta", "atggctagcaaataa", "aagcggccgc", "aaaaaggggg", ""} {
+		featureSequence, err := features[index].GetSequence()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if featureSequence != expected {
+			t.Errorf("expected feature %s to have sequence %s, got %s", features[index].DisplayID, expected, featureSequence)
+		}
+	}
+
+	// the sub-component's sequence should match the part of the plasmid its annotation covers.
+	if sequences[1].Sequence != "atggctagcaaataa" || sequences[1].Features != nil {
+		t.Errorf("unexpected sub-component %v", sequences[1])
+	}
+}
+
+func TestSbolIO(t *testing.T) {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	sequences, _ := sbol.Read("../../data/example.sbol.xml")
+	path := filepath.Join(tmpDataDir, "example.sbol.xml")
+	if err := sbol.Write(sequences, path); err != nil {
+		t.Fatal(err)
+	}
+	reread, err := sbol.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(sequences, reread, cmpopts.IgnoreFields(sbol.Feature{}, "ParentSequence")); diff != "" {
+		t.Errorf("Parsing the output of Build() does not produce the same output as parsing the original file. Got this diff:\n%s", diff)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := sbol.Read("../../data/does_not_exist.xml"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a file not found error, got %v", err)
+	}
+	if _, err := sbol.Parse([]byte("LOCUS       puc19")); err == nil {
+		t.Error("expected an error parsing a file that isn't XML")
+	}
+
+	sbol3 := `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:sbol="http://sbols.org/v3#"><sbol:Component rdf:about="https://example.com/part"/></rdf:RDF>`
+	if _, err := sbol.Parse([]byte(sbol3)); !errors.Is(err, sbol.ErrUnsupportedVersion) {
+		t.Errorf("expected an unsupported version error for an SBOL3 document, got %v", err)
+	}
+
+	badRange := `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:sbol="http://sbols.org/v2#">
+		<sbol:ComponentDefinition rdf:about="https://example.com/part"><sbol:sequenceAnnotation><sbol:SequenceAnnotation rdf:about="https://example.com/part/bad">
+			<sbol:location><sbol:Range rdf:about="https://example.com/part/bad/range"><sbol:start>10</sbol:start><sbol:end>5</sbol:end></sbol:Range></sbol:location>
+		</sbol:SequenceAnnotation></sbol:sequenceAnnotation></sbol:ComponentDefinition></rdf:RDF>`
+	if _, err := sbol.Parse([]byte(badRange)); !errors.Is(err, sbol.ErrInvalidLocation) {
+		t.Errorf("expected an invalid location error for a range that ends before it starts, got %v", err)
+	}
+}
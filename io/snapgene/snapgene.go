@@ -0,0 +1,564 @@
+/*
+Package snapgene provides a parser and writer for SnapGene .dna files.
+
+SnapGene is the plasmid editor most synthetic biologists use day to day, so
+plasmids are very often shared as its binary .dna files rather than as GenBank
+files. A .dna file is a series of blocks, each made of a one byte block type,
+a four byte big endian length and then that many bytes of data:
+
+	0x09	file header: "SnapGene" followed by the file type and version
+	0x00	the sequence, after a byte of topology and strandedness flags
+	0x05	primers, as XML
+	0x06	notes, like the file's title and description, as XML
+	0x0A	features, as XML
+
+Every other block type holds things like the history of the file or display
+settings and is skipped.
+
+This package reads the sequence, features (with their colors) and primers of
+a .dna file and can write them back out in a form SnapGene opens.
+*/
+package snapgene
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TimothyStiles/poly"
+	"github.com/TimothyStiles/poly/io/internal/compress"
+	"github.com/TimothyStiles/poly/io/internal/slice"
+	"github.com/TimothyStiles/poly/transform"
+)
+
+// Snapgene is a struct that represents a SnapGene .dna file.
+type Snapgene struct {
+	Meta     Meta      `json:"meta"`
+	Features []Feature `json:"features"`
+	Primers  []Primer  `json:"primers"`
+	Sequence string    `json:"sequence"`
+}
+
+// Meta holds meta information about a SnapGene file.
+type Meta struct {
+	Name           string `json:"name"`        // the title from the file's notes, if it has one.
+	Description    string `json:"description"` // the description from the file's notes, if it has one.
+	Circular       bool   `json:"circular"`
+	DoubleStranded bool   `json:"double_stranded"`
+}
+
+// Feature is a struct that represents a feature in a SnapGene file.
+type Feature struct {
+	Name          string            `json:"name"`
+	Type          string            `json:"type"`
+	Strand        string            `json:"strand"`        // "+", "-" or "" if the feature has no direction.
+	Bidirectional bool              `json:"bidirectional"` // set for features SnapGene draws as pointing both ways.
+	Color         string            `json:"color"`         // the hex color SnapGene draws the feature in, like "#ff0000".
+	Attributes    map[string]string `json:"attributes"`
+	// RepeatedAttributes holds every value of qualifiers that have more than one value, such as several notes.
+	// Attributes only holds the last value of those qualifiers.
+	RepeatedAttributes map[string][]string `json:"repeated_attributes,omitempty"`
+	Location           Location            `json:"location"`
+	ParentSequence     *Snapgene           `json:"-"`
+}
+
+// Location is a 0-based, end exclusive location on a SnapGene sequence.
+// Features split into several segments, like a CDS broken up by an intron,
+// have a SubLocation for each segment. On circular sequences a location can
+// run across the origin, in which case Start is greater than End.
+type Location struct {
+	Start        int        `json:"start"`
+	End          int        `json:"end"`
+	SubLocations []Location `json:"sub_locations"`
+}
+
+// Primer is a primer saved in a SnapGene file along with the places it binds.
+type Primer struct {
+	Name         string        `json:"name"`
+	Sequence     string        `json:"sequence"`
+	Description  string        `json:"description"`
+	BindingSites []BindingSite `json:"binding_sites"`
+}
+
+// BindingSite is where a primer binds to the sequence, and on which strand.
+type BindingSite struct {
+	Location Location `json:"location"`
+	Strand   string   `json:"strand"` // "+" if the primer is the same as the top strand, "-" if it binds to it.
+}
+
+// GetName returns the title of the SnapGene file.
+func (sequence Snapgene) GetName() string {
+	return sequence.Meta.Name
+}
+
+// GetSequence returns the sequence of the SnapGene file.
+func (sequence Snapgene) GetSequence() string {
+	return sequence.Sequence
+}
+
+// GetFeatures returns the features of the SnapGene file as poly.Features.
+func (sequence Snapgene) GetFeatures() []poly.Feature {
+	features := make([]poly.Feature, len(sequence.Features))
+	for index, feature := range sequence.Features {
+		features[index] = feature
+	}
+	return features
+}
+
+// GetMeta returns the title, description and topology of the SnapGene file.
+func (sequence Snapgene) GetMeta() poly.Meta {
+	return poly.Meta{Name: sequence.Meta.Name, Description: sequence.Meta.Description, Circular: sequence.Meta.Circular}
+}
+
+// AddFeature takes a feature and adds it to the Snapgene struct.
+func (sequence *Snapgene) AddFeature(feature *Feature) error {
+	feature.ParentSequence = sequence
+	sequence.Features = append(sequence.Features, *feature)
+	return nil
+}
+
+// GetType returns the type of the feature.
+func (feature Feature) GetType() string {
+	return feature.Type
+}
+
+// GetAttributes returns the qualifiers of the feature.
+func (feature Feature) GetAttributes() map[string]string {
+	return feature.Attributes
+}
+
+// GetSequence returns the sequence of the feature, reverse complemented if it is on the - strand.
+func (feature Feature) GetSequence() (string, error) {
+	if feature.ParentSequence == nil {
+		return "", fmt.Errorf("feature %s has no parent sequence", feature.Name)
+	}
+	var sequenceBuffer strings.Builder
+	locations := feature.Location.SubLocations
+	if len(locations) == 0 {
+		locations = []Location{feature.Location}
+	}
+	for _, location := range locations {
+		sequence, err := slice.Sequence(feature.ParentSequence.Sequence, location.Start, location.End, feature.ParentSequence.Meta.Circular)
+		if err != nil {
+			return "", err
+		}
+		sequenceBuffer.WriteString(sequence)
+	}
+	if feature.Strand == "-" {
+		return transform.ReverseComplement(sequenceBuffer.String()), nil
+	}
+	return sequenceBuffer.String(), nil
+}
+
+/******************************************************************************
+
+SnapGene parser begins here.
+
+******************************************************************************/
+
+// ErrNotSnapgene is returned when a file doesn't start with the SnapGene header block.
+var ErrNotSnapgene = errors.New("not a SnapGene file")
+
+// block types of a SnapGene file.
+const (
+	dnaBlock      = 0x00
+	primersBlock  = 0x05
+	notesBlock    = 0x06
+	cookieBlock   = 0x09
+	featuresBlock = 0x0A
+)
+
+// dna block flags.
+const (
+	circularFlag       = 0x01
+	doubleStrandedFlag = 0x02
+)
+
+// cookie is the text every SnapGene file's header block starts with.
+const cookie = "SnapGene"
+
+// xmlFeatures is the XML stored in a features block.
+type xmlFeatures struct {
+	XMLName  xml.Name     `xml:"Features"`
+	Features []xmlFeature `xml:"Feature"`
+}
+
+type xmlFeature struct {
+	Name           string         `xml:"name,attr"`
+	Type           string         `xml:"type,attr"`
+	Directionality int            `xml:"directionality,attr,omitempty"`
+	Segments       []xmlSegment   `xml:"Segment"`
+	Qualifiers     []xmlQualifier `xml:"Q"`
+}
+
+type xmlSegment struct {
+	Range string `xml:"range,attr"`
+	Color string `xml:"color,attr,omitempty"`
+	Type  string `xml:"type,attr"`
+}
+
+type xmlQualifier struct {
+	Name   string     `xml:"name,attr"`
+	Values []xmlValue `xml:"V"`
+}
+
+// xmlValue is a qualifier value. SnapGene stores free text, whole numbers and
+// values picked from a list of choices in different attributes.
+type xmlValue struct {
+	Text   string `xml:"text,attr,omitempty"`
+	Int    string `xml:"int,attr,omitempty"`
+	Predef string `xml:"predef,attr,omitempty"`
+}
+
+// xmlPrimers is the XML stored in a primers block.
+type xmlPrimers struct {
+	XMLName xml.Name    `xml:"Primers"`
+	Primers []xmlPrimer `xml:"Primer"`
+}
+
+type xmlPrimer struct {
+	Name         string           `xml:"name,attr"`
+	Sequence     string           `xml:"sequence,attr"`
+	Description  string           `xml:"description,attr,omitempty"`
+	BindingSites []xmlBindingSite `xml:"BindingSite"`
+}
+
+type xmlBindingSite struct {
+	Location    string `xml:"location,attr"`
+	BoundStrand int    `xml:"boundStrand,attr"`
+}
+
+// xmlNotes is the XML stored in a notes block. Only the title and description are kept.
+type xmlNotes struct {
+	XMLName     xml.Name `xml:"Notes"`
+	Title       string   `xml:"Title,omitempty"`
+	Description string   `xml:"Description,omitempty"`
+}
+
+// Parse parses a SnapGene .dna file into a Snapgene struct. Files that don't
+// start with a SnapGene header return ErrNotSnapgene and files that end part
+// of the way through a block wrap io.ErrUnexpectedEOF.
+func Parse(file []byte) (Snapgene, error) {
+	var sequence Snapgene
+	reader := bytes.NewReader(file)
+	blockIndex := 0
+	foundDNA := false
+	for {
+		blockType, err := reader.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if blockIndex == 0 && blockType != cookieBlock {
+			return Snapgene{}, ErrNotSnapgene
+		}
+		var length uint32
+		if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+			return Snapgene{}, fmt.Errorf("block %d: %w", blockIndex, io.ErrUnexpectedEOF)
+		}
+		if int64(length) > int64(reader.Len()) {
+			return Snapgene{}, fmt.Errorf("block %d is %d bytes long but only %d are left: %w", blockIndex, length, reader.Len(), io.ErrUnexpectedEOF)
+		}
+		data := make([]byte, length)
+		_, _ = reader.Read(data)
+
+		if blockIndex == 0 && !bytes.HasPrefix(data, []byte(cookie)) {
+			return Snapgene{}, ErrNotSnapgene
+		}
+		switch blockType {
+		case dnaBlock:
+			if len(data) == 0 {
+				return Snapgene{}, fmt.Errorf("block %d: empty DNA block", blockIndex)
+			}
+			sequence.Meta.Circular = data[0]&circularFlag != 0
+			sequence.Meta.DoubleStranded = data[0]&doubleStrandedFlag != 0
+			sequence.Sequence = string(data[1:])
+			foundDNA = true
+		case featuresBlock:
+			features, err := parseFeatures(data)
+			if err != nil {
+				return Snapgene{}, fmt.Errorf("block %d: %w", blockIndex, err)
+			}
+			sequence.Features = features
+		case primersBlock:
+			primers, err := parsePrimers(data)
+			if err != nil {
+				return Snapgene{}, fmt.Errorf("block %d: %w", blockIndex, err)
+			}
+			sequence.Primers = primers
+		case notesBlock:
+			var notes xmlNotes
+			if err := xml.Unmarshal(data, &notes); err != nil {
+				return Snapgene{}, fmt.Errorf("block %d: invalid notes: %w", blockIndex, err)
+			}
+			sequence.Meta.Name = notes.Title
+			sequence.Meta.Description = notes.Description
+		}
+		blockIndex++
+	}
+	if blockIndex == 0 {
+		return Snapgene{}, ErrNotSnapgene
+	}
+	if !foundDNA {
+		return Snapgene{}, fmt.Errorf("SnapGene file has no DNA block, only DNA files are supported")
+	}
+
+	for index := range sequence.Features {
+		sequence.Features[index].ParentSequence = &sequence
+	}
+	return sequence, nil
+}
+
+// parseFeatures parses the XML of a features block.
+func parseFeatures(data []byte) ([]Feature, error) {
+	var parsed xmlFeatures
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid features: %w", err)
+	}
+	features := make([]Feature, 0, len(parsed.Features))
+	for _, xmlFeature := range parsed.Features {
+		feature := Feature{Name: xmlFeature.Name, Type: xmlFeature.Type, Attributes: make(map[string]string)}
+		switch xmlFeature.Directionality {
+		case 1:
+			feature.Strand = "+"
+		case 2:
+			feature.Strand = "-"
+		case 3:
+			feature.Bidirectional = true
+		}
+
+		var locations []Location
+		for _, segment := range xmlFeature.Segments {
+			// gap segments are drawn between the parts of a feature but aren't part of it.
+			if segment.Type == "gap" {
+				continue
+			}
+			location, err := parseRange(segment.Range)
+			if err != nil {
+				return nil, fmt.Errorf("feature %s: %w", xmlFeature.Name, err)
+			}
+			locations = append(locations, location)
+			if feature.Color == "" {
+				feature.Color = segment.Color
+			}
+		}
+		if len(locations) == 0 {
+			return nil, fmt.Errorf("feature %s has no segments", xmlFeature.Name)
+		}
+		feature.Location = Location{Start: locations[0].Start, End: locations[len(locations)-1].End}
+		if len(locations) > 1 {
+			feature.Location.SubLocations = locations
+		}
+
+		for _, qualifier := range xmlFeature.Qualifiers {
+			for _, value := range qualifier.Values {
+				text := value.Text
+				switch {
+				case value.Int != "":
+					text = value.Int
+				case value.Predef != "":
+					text = value.Predef
+				}
+				feature.Attributes[qualifier.Name] = text
+			}
+			if len(qualifier.Values) > 1 {
+				if feature.RepeatedAttributes == nil {
+					feature.RepeatedAttributes = make(map[string][]string)
+				}
+				for _, value := range qualifier.Values {
+					feature.RepeatedAttributes[qualifier.Name] = append(feature.RepeatedAttributes[qualifier.Name], value.Text+value.Int+value.Predef)
+				}
+			}
+		}
+		features = append(features, feature)
+	}
+	return features, nil
+}
+
+// parsePrimers parses the XML of a primers block.
+func parsePrimers(data []byte) ([]Primer, error) {
+	var parsed xmlPrimers
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid primers: %w", err)
+	}
+	primers := make([]Primer, 0, len(parsed.Primers))
+	for _, xmlPrimer := range parsed.Primers {
+		primer := Primer{Name: xmlPrimer.Name, Sequence: xmlPrimer.Sequence, Description: xmlPrimer.Description}
+		for _, site := range xmlPrimer.BindingSites {
+			location, err := parseRange(site.Location)
+			if err != nil {
+				return nil, fmt.Errorf("primer %s: %w", xmlPrimer.Name, err)
+			}
+			strand := "+"
+			if site.BoundStrand == 1 {
+				strand = "-"
+			}
+			primer.BindingSites = append(primer.BindingSites, BindingSite{Location: location, Strand: strand})
+		}
+		primers = append(primers, primer)
+	}
+	return primers, nil
+}
+
+// parseRange parses SnapGene's 1-based, end inclusive "start-end" ranges.
+func parseRange(rangeString string) (Location, error) {
+	startString, endString, found := strings.Cut(rangeString, "-")
+	start, startErr := strconv.Atoi(strings.TrimSpace(startString))
+	end, endErr := strconv.Atoi(strings.TrimSpace(endString))
+	if !found || startErr != nil || endErr != nil || start < 1 || end < 1 {
+		return Location{}, fmt.Errorf("invalid range %q", rangeString)
+	}
+	return Location{Start: start - 1, End: end}, nil
+}
+
+/******************************************************************************
+
+SnapGene writer begins here.
+
+******************************************************************************/
+
+// the file type and versions Build writes in the header block. Type 1 is a
+// DNA file and the versions are those of the SnapGene release the format
+// was documented from.
+const (
+	dnaFileType   = 1
+	exportVersion = 15
+	importVersion = 19
+)
+
+// Build writes a Snapgene struct out as a SnapGene .dna file. Only the blocks
+// this package parses are written, so display settings and history from a
+// parsed file are left out. Features without a color are drawn in SnapGene's
+// default color.
+func Build(sequence Snapgene) ([]byte, error) {
+	var file bytes.Buffer
+
+	header := bytes.NewBufferString(cookie)
+	_ = binary.Write(header, binary.BigEndian, []uint16{dnaFileType, exportVersion, importVersion})
+	writeBlock(&file, cookieBlock, header.Bytes())
+
+	var flags byte
+	if sequence.Meta.Circular {
+		flags |= circularFlag
+	}
+	if sequence.Meta.DoubleStranded {
+		flags |= doubleStrandedFlag
+	}
+	writeBlock(&file, dnaBlock, append([]byte{flags}, sequence.Sequence...))
+
+	if len(sequence.Primers) > 0 {
+		primers := xmlPrimers{}
+		for _, primer := range sequence.Primers {
+			xmlPrimer := xmlPrimer{Name: primer.Name, Sequence: primer.Sequence, Description: primer.Description}
+			for _, site := range primer.BindingSites {
+				boundStrand := 0
+				if site.Strand == "-" {
+					boundStrand = 1
+				}
+				xmlPrimer.BindingSites = append(xmlPrimer.BindingSites, xmlBindingSite{Location: buildRange(site.Location), BoundStrand: boundStrand})
+			}
+			primers.Primers = append(primers.Primers, xmlPrimer)
+		}
+		data, err := xml.Marshal(primers)
+		if err != nil {
+			return nil, err
+		}
+		writeBlock(&file, primersBlock, data)
+	}
+
+	if sequence.Meta.Name != "" || sequence.Meta.Description != "" {
+		data, err := xml.Marshal(xmlNotes{Title: sequence.Meta.Name, Description: sequence.Meta.Description})
+		if err != nil {
+			return nil, err
+		}
+		writeBlock(&file, notesBlock, data)
+	}
+
+	features := xmlFeatures{}
+	for _, feature := range sequence.Features {
+		features.Features = append(features.Features, buildFeature(feature))
+	}
+	data, err := xml.Marshal(features)
+	if err != nil {
+		return nil, err
+	}
+	writeBlock(&file, featuresBlock, data)
+
+	return file.Bytes(), nil
+}
+
+// buildFeature converts a Feature into the XML SnapGene stores it as.
+func buildFeature(feature Feature) xmlFeature {
+	xmlFeature := xmlFeature{Name: feature.Name, Type: feature.Type}
+	switch {
+	case feature.Bidirectional:
+		xmlFeature.Directionality = 3
+	case feature.Strand == "+":
+		xmlFeature.Directionality = 1
+	case feature.Strand == "-":
+		xmlFeature.Directionality = 2
+	}
+
+	locations := feature.Location.SubLocations
+	if len(locations) == 0 {
+		locations = []Location{feature.Location}
+	}
+	for _, location := range locations {
+		xmlFeature.Segments = append(xmlFeature.Segments, xmlSegment{Range: buildRange(location), Color: feature.Color, Type: "standard"})
+	}
+
+	// qualifiers are written in alphabetical order so that Build is deterministic.
+	keys := make([]string, 0, len(feature.Attributes))
+	for key := range feature.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		values, repeated := feature.RepeatedAttributes[key]
+		if !repeated {
+			values = []string{feature.Attributes[key]}
+		}
+		qualifier := xmlQualifier{Name: key}
+		for _, value := range values {
+			qualifier.Values = append(qualifier.Values, xmlValue{Text: value})
+		}
+		xmlFeature.Qualifiers = append(xmlFeature.Qualifiers, qualifier)
+	}
+	return xmlFeature
+}
+
+// buildRange writes a Location as a SnapGene "start-end" range.
+func buildRange(location Location) string {
+	return strconv.Itoa(location.Start+1) + "-" + strconv.Itoa(location.End)
+}
+
+// writeBlock writes a block type, its length and its data.
+func writeBlock(file *bytes.Buffer, blockType byte, data []byte) {
+	file.WriteByte(blockType)
+	_ = binary.Write(file, binary.BigEndian, uint32(len(data)))
+	file.Write(data)
+}
+
+// Read reads a SnapGene .dna file into a Snapgene struct. Gzip and bzip2 compressed files are detected and decompressed automatically.
+func Read(path string) (Snapgene, error) {
+	file, err := compress.ReadFile(path)
+	if err != nil {
+		return Snapgene{}, err
+	}
+	return Parse(file)
+}
+
+// Write takes a Snapgene struct and a path string and writes out a .dna file to that path.
+func Write(sequence Snapgene, path string) error {
+	file, err := Build(sequence)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, file, 0644)
+}
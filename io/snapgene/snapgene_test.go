@@ -0,0 +1,225 @@
+package snapgene_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TimothyStiles/poly/io/genbank"
+	"github.com/TimothyStiles/poly/io/snapgene"
+	"github.com/TimothyStiles/poly/synthesis/codon"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// block writes a single SnapGene block.
+func block(blockType byte, data string) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteByte(blockType)
+	_ = binary.Write(&buffer, binary.BigEndian, uint32(len(data)))
+	buffer.WriteString(data)
+	return buffer.Bytes()
+}
+
+// testFile is laid out like a file saved by SnapGene, with blocks this package
+// doesn't parse mixed in among the ones it does.
+func testFile() []byte {
+	var file bytes.Buffer
+	file.Write(block(0x09, "SnapGene\x00\x01\x00\x0f\x00\x13"))
+	file.Write(block(0x00, "\x03"+"ATGAAACCCGGGTTTTAAGGATCCAAAA"))
+	file.Write(block(0x08, "<AdditionalSequenceProperties><UpstreamStickiness>0</UpstreamStickiness></AdditionalSequenceProperties>"))
+	file.Write(block(0x05, `<Primers nextValidID="2"><HybridizationParams minContinuousMatchLen="10"/>`+
+		`<Primer recentID="0" name="fwd" sequence="ATGAAACCC" description="forward primer"><BindingSite location="1-9" boundStrand="0" annealedBases="ATGAAACCC"/></Primer>`+
+		`<Primer recentID="1" name="rev" sequence="TTGGATCC"><BindingSite location="19-26" boundStrand="1"/></Primer></Primers>`))
+	file.Write(block(0x06, `<Notes><UUID>0962493c-08f0-4964-9b4d-4a4a5fa2aebb</UUID><Type>Synthetic</Type><Title>test plasmid</Title><Description>a plasmid for testing</Description></Notes>`))
+	file.Write(block(0x0A, `<?xml version="1.0"?><Features nextValidID="3">`+
+		`<Feature recentID="0" name="orf" type="CDS" directionality="1" readingFrame="1"><Segment range="1-9" color="#993366" type="standard" translated="1"/><Segment range="10-12" type="gap"/><Segment range="13-18" color="#993366" type="standard" translated="1"/>`+
+		`<Q name="codon_start"><V int="1"/></Q><Q name="note"><V text="first note"/><V text="second note"/></Q><Q name="transl_table"><V int="11"/></Q><Q name="gene"><V predef="orf"/></Q></Feature>`+
+		`<Feature recentID="1" name="BamHI site" type="misc_feature" directionality="2"><Segment range="19-24" color="#ff0000" type="standard"/></Feature>`+
+		`<Feature recentID="2" name="origin spanning" type="misc_feature"><Segment range="25-3" color="#00ff00" type="standard"/></Feature>`+
+		`</Features>`))
+	file.Write(block(0x11, "display settings this package skips"))
+	return file.Bytes()
+}
+
+func ExampleParse() {
+	sequence, _ := snapgene.Parse(testFile())
+	cds, _ := sequence.Features[0].GetSequence()
+
+	fmt.Println(sequence.Meta.Name, sequence.Meta.Circular, cds)
+	// Output: test plasmid true ATGAAACCCTTTTAA
+}
+
+func TestParse(t *testing.T) {
+	sequence, err := snapgene.Parse(testFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedMeta := snapgene.Meta{Name: "test plasmid", Description: "a plasmid for testing", Circular: true, DoubleStranded: true}
+	if diff := cmp.Diff(expectedMeta, sequence.Meta); diff != "" {
+		t.Errorf("unexpected meta (-want +got):\n%s", diff)
+	}
+	if sequence.Sequence != "ATGAAACCCGGGTTTTAAGGATCCAAAA" {
+		t.Errorf("unexpected sequence %s", sequence.Sequence)
+	}
+
+	expectedFeatures := []snapgene.Feature{
+		{
+			Name: "orf", Type: "CDS", Strand: "+", Color: "#993366",
+			Attributes:         map[string]string{"codon_start": "1", "note": "second note", "transl_table": "11", "gene": "orf"},
+			RepeatedAttributes: map[string][]string{"note": {"first note", "second note"}},
+			Location:           snapgene.Location{Start: 0, End: 18, SubLocations: []snapgene.Location{{Start: 0, End: 9}, {Start: 12, End: 18}}},
+		},
+		{Name: "BamHI site", Type: "misc_feature", Strand: "-", Color: "#ff0000", Attributes: map[string]string{}, Location: snapgene.Location{Start: 18, End: 24}},
+		{Name: "origin spanning", Type: "misc_feature", Color: "#00ff00", Attributes: map[string]string{}, Location: snapgene.Location{Start: 24, End: 3}},
+	}
+	if diff := cmp.Diff(expectedFeatures, sequence.Features, cmpopts.IgnoreFields(snapgene.Feature{}, "ParentSequence")); diff != "" {
+		t.Errorf("unexpected features (-want +got):\n%s", diff)
+	}
+
+	expectedPrimers := []snapgene.Primer{
+		{Name: "fwd", Sequence: "ATGAAACCC", Description: "forward primer", BindingSites: []snapgene.BindingSite{{Location: snapgene.Location{Start: 0, End: 9}, Strand: "+"}}},
+		{Name: "rev", Sequence: "TTGGATCC", BindingSites: []snapgene.BindingSite{{Location: snapgene.Location{Start: 18, End: 26}, Strand: "-"}}},
+	}
+	if diff := cmp.Diff(expectedPrimers, sequence.Primers); diff != "" {
+		t.Errorf("unexpected primers (-want +got):\n%s", diff)
+	}
+
+	for index, expected := range []string{"ATGAAACCCTTTTAA", "GGATCC", "AAAAATG"} {
+		featureSequence, err := sequence.Features[index].GetSequence()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if featureSequence != expected {
+			t.Errorf("expected feature %s to have sequence %s, got %s", sequence.Features[index].Name, expected, featureSequence)
+		}
+	}
+}
+
+func TestSnapgeneIO(t *testing.T) {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	sequence, _ := snapgene.Parse(testFile())
+	path := filepath.Join(tmpDataDir, "test.dna")
+	if err := snapgene.Write(sequence, path); err != nil {
+		t.Fatal(err)
+	}
+	reread, err := snapgene.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(sequence, reread, cmpopts.IgnoreFields(snapgene.Feature{}, "ParentSequence")); diff != "" {
+		t.Errorf("Parsing the output of Build() does not produce the same output as parsing the original file. Got this diff:\n%s", diff)
+	}
+
+	// Build should be deterministic so that files don't change every time they're written.
+	first, _ := snapgene.Build(sequence)
+	second, _ := snapgene.Build(sequence)
+	if !bytes.Equal(first, second) {
+		t.Error("Build gave different output for the same sequence")
+	}
+}
+
+func TestPuc19(t *testing.T) {
+	// puc19.dna holds the same plasmid as puc19_snapgene.gb, which SnapGene exported as GenBank. SnapGene keeps primer_bind
+	// features as primers and leaves out the source feature.
+	dna, err := snapgene.Read("../../data/puc19.dna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gbk, err := genbank.Read("../../data/puc19_snapgene.gb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.EqualFold(dna.Sequence, gbk.Sequence) || !dna.Meta.Circular || dna.Meta.Description != gbk.Meta.Definition {
+		t.Errorf("puc19.dna's sequence or meta doesn't match puc19_snapgene.gb: %+v", dna.Meta)
+	}
+
+	var features, primers []genbank.Feature
+	for _, feature := range gbk.Features {
+		switch feature.Type {
+		case "source":
+		case "primer_bind":
+			primers = append(primers, feature)
+		default:
+			features = append(features, feature)
+		}
+	}
+	if len(dna.Features) != len(features) || len(dna.Primers) != len(primers) {
+		t.Fatalf("expected %d features and %d primers, got %d and %d", len(features), len(primers), len(dna.Features), len(dna.Primers))
+	}
+	for index, expected := range features {
+		feature := dna.Features[index]
+		expectedSequence, _ := expected.GetSequence()
+		featureSequence, err := feature.GetSequence()
+		if err != nil || feature.Name != expected.Attributes["label"] || feature.Type != expected.Type || !strings.EqualFold(featureSequence, expectedSequence) {
+			t.Errorf("feature %s doesn't match %s %s in puc19_snapgene.gb", feature.Name, expected.Type, expected.Attributes["label"])
+		}
+		if translation, ok := expected.Attributes["translation"]; ok {
+			protein, _ := codon.Translate(featureSequence, codon.GetCodonTable(11))
+			if strings.TrimSuffix(protein, "*") != strings.Join(strings.Fields(translation), "") {
+				t.Errorf("%s translates to %s", feature.Name, protein)
+			}
+		}
+	}
+	for index, expected := range primers {
+		primer := dna.Primers[index]
+		expectedSequence, _ := expected.GetSequence()
+		if primer.Name != expected.Attributes["label"] || !strings.EqualFold(primer.Sequence, expectedSequence) {
+			t.Errorf("primer %s %s doesn't match %s %s in puc19_snapgene.gb", primer.Name, primer.Sequence, expected.Attributes["label"], expectedSequence)
+		}
+	}
+
+	// compressed .dna files are read too.
+	file, _ := ioutil.ReadFile("../../data/puc19.dna")
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, _ = writer.Write(file)
+	_ = writer.Close()
+	path := filepath.Join(t.TempDir(), "puc19.dna.gz")
+	if err := ioutil.WriteFile(path, compressed.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if gzipped, err := snapgene.Read(path); err != nil || gzipped.Sequence != dna.Sequence {
+		t.Errorf("failed to read a gzipped .dna file: %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := snapgene.Read("../../data/does_not_exist.dna"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a file not found error, got %v", err)
+	}
+	for _, file := range [][]byte{nil, []byte("LOCUS       puc19"), block(0x09, "NotSnapGene")} {
+		if _, err := snapgene.Parse(file); !errors.Is(err, snapgene.ErrNotSnapgene) {
+			t.Errorf("expected ErrNotSnapgene for %q, got %v", file, err)
+		}
+	}
+
+	file := testFile()
+	if _, err := snapgene.Parse(file[:len(file)-5]); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected an unexpected EOF error for a truncated file, got %v", err)
+	}
+
+	header := block(0x09, "SnapGene\x00\x01\x00\x0f\x00\x13")
+	if _, err := snapgene.Parse(header); err == nil {
+		t.Error("expected an error for a file without a DNA block")
+	}
+	badRange := append(append(header, block(0x00, "\x00ATGC")...), block(0x0A, `<Features><Feature name="bad" type="CDS"><Segment range="one-4" type="standard"/></Feature></Features>`)...)
+	if _, err := snapgene.Parse(badRange); err == nil {
+		t.Error("expected an error for a feature with an invalid range")
+	}
+}
@@ -0,0 +1,815 @@
+/*
+Package vcf provides VCF parsers and writers.
+
+VCF (variant call format) is how variant callers like GATK, bcftools and
+freebayes report the differences they find between sequencing reads and a
+reference. A VCF file starts with meta lines describing the file, including
+the INFO and FORMAT fields its variants use and the types of their values:
+
+	##fileformat=VCFv4.3
+	##INFO=<ID=DP,Number=1,Type=Integer,Description="Total Depth">
+	##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+	#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	sample1
+	chr1	100	rs1	A	G	50	PASS	DP=14	0|1
+
+followed by a tab separated line for each variant with, if the file has any
+samples, a column of FORMAT values for each sample.
+
+This package parses VCF 4.x files, checking INFO and FORMAT values against the
+types their headers declare, and can write them back out. Apply and Derive
+convert between variants and the sequences they describe so that variants can
+be applied to a reference sequence or found between two aligned sequences.
+
+Like the rest of poly, positions are 0-based, so a variant at POS 100 has a
+Position of 99.
+*/
+package vcf
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TimothyStiles/poly/io/internal/compress"
+)
+
+// Vcf is a struct that represents a VCF file.
+type Vcf struct {
+	Header   Header    `json:"header"`
+	Variants []Variant `json:"variants"`
+}
+
+// Header holds the meta lines and sample names of a VCF file.
+type Header struct {
+	FileFormat string       `json:"file_format"` // like "VCFv4.3".
+	Info       []Definition `json:"info"`
+	Format     []Definition `json:"format"`
+	Filters    []Definition `json:"filters"` // only the ID and Description of filters are set.
+	Contigs    []Contig     `json:"contigs"`
+	Other      []string     `json:"other"` // every other meta line, without its leading "##".
+	Samples    []string     `json:"samples"`
+}
+
+// Definition describes an INFO field, FORMAT field or filter.
+type Definition struct {
+	ID string `json:"id"`
+	// Number is how many values the field has: a whole number, "A" for one per
+	// alternate allele, "R" for one per allele including the reference, "G" for
+	// one per genotype or "." if it varies.
+	Number      string `json:"number"`
+	Type        string `json:"type"` // Integer, Float, Flag, Character or String.
+	Description string `json:"description"`
+}
+
+// Contig describes one of the sequences variants are called against.
+type Contig struct {
+	ID     string `json:"id"`
+	Length int    `json:"length"` // 0 if the header doesn't say.
+}
+
+// Variant is a struct that represents a single line of a VCF file.
+type Variant struct {
+	Chrom    string   `json:"chrom"`
+	Position int      `json:"position"` // 0-based position of the first base of Ref.
+	IDs      []string `json:"ids"`
+	Ref      string   `json:"ref"`
+	Alt      []string `json:"alt"`
+	Quality  float64  `json:"quality"` // NaN if the quality is missing.
+	Filters  []string `json:"filters"` // ["PASS"] for variants that passed every filter, empty if filters weren't applied.
+	// Info holds the INFO fields of the variant as they're written in the
+	// file. Flags have an empty value. Use InfoInts, InfoFloats, InfoStrings
+	// and InfoFlag to get them as typed values.
+	Info      map[string]string `json:"info"`
+	InfoOrder []string          `json:"info_order"`
+	Format    []string          `json:"format"`
+	// Samples holds the FORMAT fields of each sample, in the same order as
+	// the Header's Samples.
+	Samples []map[string]string `json:"samples"`
+}
+
+// Genotype is the genotype of a sample at a variant.
+type Genotype struct {
+	Alleles []int `json:"alleles"` // 0 for the reference, 1 for the first alternate allele and so on, or -1 if the call is missing.
+	Phased  bool  `json:"phased"`
+}
+
+// MissingInt is the value given to missing (".") integers by InfoInts and SampleInts.
+// Missing floats are NaN.
+const MissingInt = math.MinInt32
+
+// ErrInvalidVariant is wrapped by errors about variant lines that can't be parsed and variants that can't be applied.
+var ErrInvalidVariant = errors.New("invalid variant")
+
+// ErrInvalidValue is wrapped by errors about INFO and FORMAT values that don't match the types declared for them.
+var ErrInvalidValue = errors.New("invalid value")
+
+// ParseError is returned by Parse when a line can't be parsed. Use errors.As
+// to get the line number and errors.Is with ErrInvalidVariant or
+// ErrInvalidValue to check its cause.
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("vcf: line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+/******************************************************************************
+
+Typed value functions begin here.
+
+******************************************************************************/
+
+// InfoStrings returns the comma separated values of an INFO field.
+func (variant Variant) InfoStrings(key string) ([]string, error) {
+	value, ok := variant.Info[key]
+	if !ok {
+		return nil, fmt.Errorf("variant at %s:%d has no %s INFO field", variant.Chrom, variant.Position+1, key)
+	}
+	return strings.Split(value, ","), nil
+}
+
+// InfoInts returns the values of an Integer INFO field. Missing values are MissingInt.
+func (variant Variant) InfoInts(key string) ([]int, error) {
+	values, err := variant.InfoStrings(key)
+	if err != nil {
+		return nil, err
+	}
+	return parseInts(values)
+}
+
+// InfoFloats returns the values of a Float INFO field. Missing values are NaN.
+func (variant Variant) InfoFloats(key string) ([]float64, error) {
+	values, err := variant.InfoStrings(key)
+	if err != nil {
+		return nil, err
+	}
+	return parseFloats(values)
+}
+
+// InfoFlag returns whether a variant has a Flag INFO field.
+func (variant Variant) InfoFlag(key string) bool {
+	_, ok := variant.Info[key]
+	return ok
+}
+
+// SampleStrings returns the comma separated values of a FORMAT field of the sample at sampleIndex.
+func (variant Variant) SampleStrings(sampleIndex int, key string) ([]string, error) {
+	if sampleIndex < 0 || sampleIndex >= len(variant.Samples) {
+		return nil, fmt.Errorf("variant at %s:%d has no sample %d", variant.Chrom, variant.Position+1, sampleIndex)
+	}
+	value, ok := variant.Samples[sampleIndex][key]
+	if !ok {
+		return nil, fmt.Errorf("sample %d of variant at %s:%d has no %s FORMAT field", sampleIndex, variant.Chrom, variant.Position+1, key)
+	}
+	return strings.Split(value, ","), nil
+}
+
+// SampleInts returns the values of an Integer FORMAT field of the sample at sampleIndex. Missing values are MissingInt.
+func (variant Variant) SampleInts(sampleIndex int, key string) ([]int, error) {
+	values, err := variant.SampleStrings(sampleIndex, key)
+	if err != nil {
+		return nil, err
+	}
+	return parseInts(values)
+}
+
+// SampleFloats returns the values of a Float FORMAT field of the sample at sampleIndex. Missing values are NaN.
+func (variant Variant) SampleFloats(sampleIndex int, key string) ([]float64, error) {
+	values, err := variant.SampleStrings(sampleIndex, key)
+	if err != nil {
+		return nil, err
+	}
+	return parseFloats(values)
+}
+
+// Genotype returns the GT field of the sample at sampleIndex.
+func (variant Variant) Genotype(sampleIndex int) (Genotype, error) {
+	values, err := variant.SampleStrings(sampleIndex, "GT")
+	if err != nil {
+		return Genotype{}, err
+	}
+	return parseGenotype(values[0])
+}
+
+// parseGenotype parses a genotype like "0/1", "1|0" or "./.".
+func parseGenotype(genotypeString string) (Genotype, error) {
+	genotype := Genotype{Phased: strings.Contains(genotypeString, "|")}
+	for _, allele := range strings.FieldsFunc(genotypeString, func(r rune) bool { return r == '/' || r == '|' }) {
+		if allele == "." {
+			genotype.Alleles = append(genotype.Alleles, -1)
+			continue
+		}
+		index, err := strconv.Atoi(allele)
+		if err != nil || index < 0 {
+			return Genotype{}, fmt.Errorf("%w: genotype %q", ErrInvalidValue, genotypeString)
+		}
+		genotype.Alleles = append(genotype.Alleles, index)
+	}
+	if len(genotype.Alleles) == 0 {
+		return Genotype{}, fmt.Errorf("%w: empty genotype", ErrInvalidValue)
+	}
+	return genotype, nil
+}
+
+// String writes a genotype the way it is written in VCF files, like "0|1".
+func (genotype Genotype) String() string {
+	separator := "/"
+	if genotype.Phased {
+		separator = "|"
+	}
+	alleles := make([]string, len(genotype.Alleles))
+	for index, allele := range genotype.Alleles {
+		if allele < 0 {
+			alleles[index] = "."
+		} else {
+			alleles[index] = strconv.Itoa(allele)
+		}
+	}
+	return strings.Join(alleles, separator)
+}
+
+// parseInts parses integer values, turning missing values into MissingInt.
+func parseInts(values []string) ([]int, error) {
+	integers := make([]int, len(values))
+	for index, value := range values {
+		if value == "." {
+			integers[index] = MissingInt
+			continue
+		}
+		integer, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not an integer", ErrInvalidValue, value)
+		}
+		integers[index] = integer
+	}
+	return integers, nil
+}
+
+// parseFloats parses float values, turning missing values into NaN.
+func parseFloats(values []string) ([]float64, error) {
+	floats := make([]float64, len(values))
+	for index, value := range values {
+		if value == "." {
+			floats[index] = math.NaN()
+			continue
+		}
+		float, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not a float", ErrInvalidValue, value)
+		}
+		floats[index] = float
+	}
+	return floats, nil
+}
+
+// checkValue checks a value against the Type and Number of its definition.
+// Values whose Number depends on the genotype or isn't fixed only have their
+// types checked.
+func checkValue(definition Definition, value string, alleles int) error {
+	if definition.Type == "Flag" {
+		if value != "" {
+			return fmt.Errorf("%w: flag %s has a value", ErrInvalidValue, definition.ID)
+		}
+		return nil
+	}
+	values := strings.Split(value, ",")
+	// a single "." is a missing value, whatever the Number of the field.
+	if value == "." {
+		return nil
+	}
+	expected := -1
+	switch definition.Number {
+	case "A":
+		expected = alleles - 1
+	case "R":
+		expected = alleles
+	case "G", ".":
+	default:
+		if number, err := strconv.Atoi(definition.Number); err == nil {
+			expected = number
+		}
+	}
+	if expected >= 0 && len(values) != expected {
+		return fmt.Errorf("%w: %s should have %d values, got %d", ErrInvalidValue, definition.ID, expected, len(values))
+	}
+
+	var err error
+	switch definition.Type {
+	case "Integer":
+		_, err = parseInts(values)
+	case "Float":
+		_, err = parseFloats(values)
+	case "Character":
+		for _, character := range values {
+			if len(character) != 1 {
+				err = fmt.Errorf("%w: %q is not a single character", ErrInvalidValue, character)
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", definition.ID, err)
+	}
+	return nil
+}
+
+/******************************************************************************
+
+VCF parser begins here.
+
+******************************************************************************/
+
+// Parse parses a VCF file. INFO and FORMAT values are checked against the
+// Type and Number of their header definitions and return an error wrapping
+// ErrInvalidValue if they don't match. Fields without a definition aren't checked.
+func Parse(file []byte) (Vcf, error) {
+	var vcf Vcf
+	infoDefinitions := make(map[string]Definition)
+	formatDefinitions := make(map[string]Definition)
+
+	scanner := bufio.NewScanner(bytes.NewReader(file))
+	// INFO columns of structural variants and large cohorts can get very long.
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	lineNumber := 0
+	foundColumns := false
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case len(strings.TrimSpace(line)) == 0:
+			continue
+		case strings.HasPrefix(line, "##"):
+			if err := vcf.Header.parseMetaLine(line[2:]); err != nil {
+				return Vcf{}, &ParseError{lineNumber, err}
+			}
+			continue
+		case strings.HasPrefix(line, "#"):
+			columns := strings.Split(line, "\t")
+			if len(columns) < 8 {
+				return Vcf{}, &ParseError{lineNumber, fmt.Errorf("%w: header line should have at least 8 columns, got %d", ErrInvalidVariant, len(columns))}
+			}
+			if len(columns) > 9 {
+				vcf.Header.Samples = columns[9:]
+			}
+			for _, definition := range vcf.Header.Info {
+				infoDefinitions[definition.ID] = definition
+			}
+			for _, definition := range vcf.Header.Format {
+				formatDefinitions[definition.ID] = definition
+			}
+			foundColumns = true
+			continue
+		}
+		if !foundColumns {
+			return Vcf{}, &ParseError{lineNumber, fmt.Errorf("%w: variant before the #CHROM header line", ErrInvalidVariant)}
+		}
+		variant, err := parseVariant(line, len(vcf.Header.Samples), infoDefinitions, formatDefinitions)
+		if err != nil {
+			return Vcf{}, &ParseError{lineNumber, err}
+		}
+		vcf.Variants = append(vcf.Variants, variant)
+	}
+	if err := scanner.Err(); err != nil {
+		return Vcf{}, &ParseError{lineNumber + 1, err}
+	}
+	return vcf, nil
+}
+
+// parseMetaLine parses a meta line, without its leading "##", into the header.
+func (header *Header) parseMetaLine(line string) error {
+	key, value, _ := strings.Cut(line, "=")
+	if key == "fileformat" {
+		header.FileFormat = value
+		return nil
+	}
+	if key != "INFO" && key != "FORMAT" && key != "FILTER" && key != "contig" {
+		header.Other = append(header.Other, line)
+		return nil
+	}
+	fields, err := parseStructuredValue(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	definition := Definition{ID: fields["ID"], Number: fields["Number"], Type: fields["Type"], Description: fields["Description"]}
+	if definition.ID == "" {
+		return fmt.Errorf("%w: %s line without an ID", ErrInvalidValue, key)
+	}
+	switch key {
+	case "INFO":
+		header.Info = append(header.Info, definition)
+	case "FORMAT":
+		header.Format = append(header.Format, definition)
+	case "FILTER":
+		header.Filters = append(header.Filters, Definition{ID: definition.ID, Description: definition.Description})
+	case "contig":
+		contig := Contig{ID: definition.ID}
+		if length, ok := fields["length"]; ok {
+			if contig.Length, err = strconv.Atoi(length); err != nil {
+				return fmt.Errorf("%w: contig %s has a length of %q", ErrInvalidValue, contig.ID, length)
+			}
+		}
+		header.Contigs = append(header.Contigs, contig)
+	}
+	return nil
+}
+
+// parseStructuredValue parses a meta line value like
+// <ID=DP,Number=1,Type=Integer,Description="Total Depth">. Quoted values can
+// hold commas and escaped quotes.
+func parseStructuredValue(value string) (map[string]string, error) {
+	if !strings.HasPrefix(value, "<") || !strings.HasSuffix(value, ">") {
+		return nil, fmt.Errorf("%w: %q should be enclosed in <>", ErrInvalidValue, value)
+	}
+	fields := make(map[string]string)
+	remaining := value[1 : len(value)-1]
+	for remaining != "" {
+		key, rest, found := strings.Cut(remaining, "=")
+		if !found {
+			return nil, fmt.Errorf("%w: %q has no value", ErrInvalidValue, key)
+		}
+		var fieldValue strings.Builder
+		index := 0
+		if strings.HasPrefix(rest, "\"") {
+			index = 1
+			for ; index < len(rest) && rest[index] != '"'; index++ {
+				if rest[index] == '\\' && index+1 < len(rest) {
+					index++
+				}
+				fieldValue.WriteByte(rest[index])
+			}
+			if index == len(rest) {
+				return nil, fmt.Errorf("%w: %s has an unterminated quoted value", ErrInvalidValue, key)
+			}
+			index++
+		} else {
+			for ; index < len(rest) && rest[index] != ','; index++ {
+				fieldValue.WriteByte(rest[index])
+			}
+		}
+		fields[strings.TrimSpace(key)] = fieldValue.String()
+		remaining = strings.TrimPrefix(rest[index:], ",")
+	}
+	return fields, nil
+}
+
+// parseVariant parses a single tab separated variant line.
+func parseVariant(line string, samples int, infoDefinitions, formatDefinitions map[string]Definition) (Variant, error) {
+	columns := strings.Split(line, "\t")
+	expectedColumns := 8
+	if samples > 0 {
+		expectedColumns = 9 + samples
+	}
+	if len(columns) != expectedColumns {
+		return Variant{}, fmt.Errorf("%w: expected %d tab separated columns, got %d", ErrInvalidVariant, expectedColumns, len(columns))
+	}
+
+	variant := Variant{
+		Chrom: columns[0],
+		Ref:   columns[3],
+		IDs:   splitMissing(columns[2], ";"),
+		Alt:   splitMissing(columns[4], ","),
+		Info:  make(map[string]string),
+	}
+	// Indexing starts at 1 for vcf so we need to shift down for Sequence 0 index.
+	position, err := strconv.Atoi(columns[1])
+	if err != nil || position < 0 {
+		return Variant{}, fmt.Errorf("%w: position %q is not a number", ErrInvalidVariant, columns[1])
+	}
+	variant.Position = position - 1
+	if variant.Ref == "" || variant.Ref == "." {
+		return Variant{}, fmt.Errorf("%w: missing reference allele", ErrInvalidVariant)
+	}
+	variant.Quality = math.NaN()
+	if columns[5] != "." {
+		if variant.Quality, err = strconv.ParseFloat(columns[5], 64); err != nil {
+			return Variant{}, fmt.Errorf("%w: quality %q is not a number", ErrInvalidVariant, columns[5])
+		}
+	}
+	variant.Filters = splitMissing(columns[6], ";")
+
+	alleles := 1 + len(variant.Alt)
+	for _, field := range splitMissing(columns[7], ";") {
+		key, value, _ := strings.Cut(field, "=")
+		if definition, ok := infoDefinitions[key]; ok {
+			if err := checkValue(definition, value, alleles); err != nil {
+				return Variant{}, fmt.Errorf("INFO %w", err)
+			}
+		}
+		if _, ok := variant.Info[key]; !ok {
+			variant.InfoOrder = append(variant.InfoOrder, key)
+		}
+		variant.Info[key] = value
+	}
+
+	if samples == 0 {
+		return variant, nil
+	}
+	variant.Format = splitMissing(columns[8], ":")
+	for sampleIndex, sampleColumn := range columns[9:] {
+		sample := make(map[string]string, len(variant.Format))
+		// trailing FORMAT fields can be left out of a sample.
+		values := strings.Split(sampleColumn, ":")
+		if len(values) > len(variant.Format) {
+			return Variant{}, fmt.Errorf("%w: sample %d has %d values for %d FORMAT fields", ErrInvalidVariant, sampleIndex, len(values), len(variant.Format))
+		}
+		for index, value := range values {
+			key := variant.Format[index]
+			if definition, ok := formatDefinitions[key]; ok && key != "GT" {
+				if err := checkValue(definition, value, alleles); err != nil {
+					return Variant{}, fmt.Errorf("sample %d FORMAT %w", sampleIndex, err)
+				}
+			}
+			sample[key] = value
+		}
+		if genotype, ok := sample["GT"]; ok && genotype != "." {
+			if _, err := parseGenotype(genotype); err != nil {
+				return Variant{}, fmt.Errorf("sample %d: %w", sampleIndex, err)
+			}
+		}
+		variant.Samples = append(variant.Samples, sample)
+	}
+	return variant, nil
+}
+
+// splitMissing splits a column on separator, returning nil for the missing value ".".
+func splitMissing(column, separator string) []string {
+	if column == "." || column == "" {
+		return nil
+	}
+	return strings.Split(column, separator)
+}
+
+/******************************************************************************
+
+VCF parser ends here.
+
+******************************************************************************/
+
+// Build writes a Vcf struct out as a VCF file. Meta lines are written in the
+// order fileformat, INFO, FILTER, FORMAT, contig and then every other line.
+// INFO fields are written in their InfoOrder followed by any others in
+// alphabetical order.
+func Build(vcf Vcf) ([]byte, error) {
+	var vcfBuffer bytes.Buffer
+	fileFormat := vcf.Header.FileFormat
+	if fileFormat == "" {
+		fileFormat = "VCFv4.3"
+	}
+	vcfBuffer.WriteString("##fileformat=" + fileFormat + "\n")
+	for _, definition := range vcf.Header.Info {
+		vcfBuffer.WriteString(buildDefinition("INFO", definition))
+	}
+	for _, definition := range vcf.Header.Filters {
+		vcfBuffer.WriteString(fmt.Sprintf("##FILTER=<ID=%s,Description=%s>\n", definition.ID, quote(definition.Description)))
+	}
+	for _, definition := range vcf.Header.Format {
+		vcfBuffer.WriteString(buildDefinition("FORMAT", definition))
+	}
+	for _, contig := range vcf.Header.Contigs {
+		if contig.Length > 0 {
+			vcfBuffer.WriteString(fmt.Sprintf("##contig=<ID=%s,length=%d>\n", contig.ID, contig.Length))
+		} else {
+			vcfBuffer.WriteString(fmt.Sprintf("##contig=<ID=%s>\n", contig.ID))
+		}
+	}
+	for _, line := range vcf.Header.Other {
+		vcfBuffer.WriteString("##" + line + "\n")
+	}
+
+	columns := []string{"#CHROM", "POS", "ID", "REF", "ALT", "QUAL", "FILTER", "INFO"}
+	if len(vcf.Header.Samples) > 0 {
+		columns = append(append(columns, "FORMAT"), vcf.Header.Samples...)
+	}
+	vcfBuffer.WriteString(strings.Join(columns, "\t") + "\n")
+
+	for variantIndex, variant := range vcf.Variants {
+		if len(variant.Samples) != len(vcf.Header.Samples) {
+			return nil, fmt.Errorf("vcf: variant %d: %w: %d samples for a header with %d", variantIndex, ErrInvalidVariant, len(variant.Samples), len(vcf.Header.Samples))
+		}
+		vcfBuffer.WriteString(strings.Join(buildColumns(variant), "\t") + "\n")
+	}
+	return vcfBuffer.Bytes(), nil
+}
+
+// buildDefinition writes an INFO or FORMAT meta line.
+func buildDefinition(key string, definition Definition) string {
+	return fmt.Sprintf("##%s=<ID=%s,Number=%s,Type=%s,Description=%s>\n", key, definition.ID, definition.Number, definition.Type, quote(definition.Description))
+}
+
+// quote quotes a meta line value, escaping quotes and backslashes inside it.
+func quote(value string) string {
+	return "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(value) + "\""
+}
+
+// buildColumns builds the columns of a variant line.
+func buildColumns(variant Variant) []string {
+	quality := "."
+	if !math.IsNaN(variant.Quality) {
+		quality = strconv.FormatFloat(variant.Quality, 'f', -1, 64)
+	}
+
+	keys := append([]string{}, variant.InfoOrder...)
+	ordered := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		ordered[key] = true
+	}
+	var unordered []string
+	for key := range variant.Info {
+		if !ordered[key] {
+			unordered = append(unordered, key)
+		}
+	}
+	sort.Strings(unordered)
+	var info []string
+	for _, key := range append(keys, unordered...) {
+		value, ok := variant.Info[key]
+		switch {
+		case !ok:
+			continue
+		case value == "":
+			info = append(info, key)
+		default:
+			info = append(info, key+"="+value)
+		}
+	}
+
+	columns := []string{
+		variant.Chrom,
+		// Indexing starts at 1 for vcf so we need to shift up from Sequence 0 index.
+		strconv.Itoa(variant.Position + 1),
+		joinMissing(variant.IDs, ";"),
+		variant.Ref,
+		joinMissing(variant.Alt, ","),
+		quality,
+		joinMissing(variant.Filters, ";"),
+		joinMissing(info, ";"),
+	}
+	if len(variant.Samples) == 0 {
+		return columns
+	}
+	columns = append(columns, joinMissing(variant.Format, ":"))
+	for _, sample := range variant.Samples {
+		values := make([]string, len(variant.Format))
+		for index, key := range variant.Format {
+			value, ok := sample[key]
+			if !ok {
+				value = "."
+			}
+			values[index] = value
+		}
+		columns = append(columns, strings.Join(values, ":"))
+	}
+	return columns
+}
+
+// joinMissing joins values with separator, returning the missing value "." if there aren't any.
+func joinMissing(values []string, separator string) string {
+	if len(values) == 0 {
+		return "."
+	}
+	return strings.Join(values, separator)
+}
+
+/******************************************************************************
+
+Sequence functions begin here.
+
+******************************************************************************/
+
+// Apply applies the first alternate allele of each variant to sequence and
+// returns the result. The variants should all be on the same sequence and
+// their Ref alleles must match it. Variants without an alternate allele are
+// skipped while symbolic alleles, like <DEL>, and overlapping variants return
+// an error wrapping ErrInvalidVariant.
+func Apply(sequence string, variants []Variant) (string, error) {
+	sorted := append([]Variant{}, variants...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Position < sorted[j].Position
+	})
+
+	var applied strings.Builder
+	position := 0
+	for _, variant := range sorted {
+		if len(variant.Alt) == 0 {
+			continue
+		}
+		alt := variant.Alt[0]
+		if strings.ContainsAny(alt, "<>[]*.") {
+			return "", fmt.Errorf("%w: can't apply symbolic allele %s at %s:%d", ErrInvalidVariant, alt, variant.Chrom, variant.Position+1)
+		}
+		end := variant.Position + len(variant.Ref)
+		if variant.Position < 0 || end > len(sequence) {
+			return "", fmt.Errorf("%w: variant at %s:%d is outside of a sequence of length %d", ErrInvalidVariant, variant.Chrom, variant.Position+1, len(sequence))
+		}
+		if variant.Position < position {
+			return "", fmt.Errorf("%w: variant at %s:%d overlaps the variant before it", ErrInvalidVariant, variant.Chrom, variant.Position+1)
+		}
+		if !strings.EqualFold(sequence[variant.Position:end], variant.Ref) {
+			return "", fmt.Errorf("%w: reference allele %s at %s:%d doesn't match the sequence %s", ErrInvalidVariant, variant.Ref, variant.Chrom, variant.Position+1, sequence[variant.Position:end])
+		}
+		applied.WriteString(sequence[position:variant.Position])
+		applied.WriteString(alt)
+		position = end
+	}
+	applied.WriteString(sequence[position:])
+	return applied.String(), nil
+}
+
+// Derive returns the variants that turn reference into alternate, where the
+// two are rows of a pairwise alignment of equal length with gaps written as
+// "-". Mismatches become single base variants while insertions and deletions
+// are anchored to the base before them, as VCF requires, or the base after
+// them if they're at the start of the reference.
+func Derive(chrom, reference, alternate string) ([]Variant, error) {
+	if len(reference) != len(alternate) {
+		return nil, fmt.Errorf("%w: aligned sequences have different lengths %d and %d", ErrInvalidVariant, len(reference), len(alternate))
+	}
+	ungapped := strings.ReplaceAll(reference, "-", "")
+
+	var variants []Variant
+	referencePosition := 0 // position in ungapped of the next reference base.
+	for column := 0; column < len(reference); {
+		if reference[column] == alternate[column] {
+			if reference[column] != '-' {
+				referencePosition++
+			}
+			column++
+			continue
+		}
+
+		// gather a run of differing columns.
+		start := referencePosition
+		var refBases, altBases strings.Builder
+		hasGap := false
+		end := column
+		for ; end < len(reference) && reference[end] != alternate[end]; end++ {
+			if reference[end] == '-' || alternate[end] == '-' {
+				hasGap = true
+			}
+			if reference[end] != '-' {
+				refBases.WriteByte(reference[end])
+				referencePosition++
+			}
+			if alternate[end] != '-' {
+				altBases.WriteByte(alternate[end])
+			}
+		}
+
+		switch {
+		case !hasGap:
+			for index := 0; index < refBases.Len(); index++ {
+				variants = append(variants, newVariant(chrom, start+index, refBases.String()[index:index+1], altBases.String()[index:index+1]))
+			}
+		case start > 0:
+			anchor := ungapped[start-1 : start]
+			variants = append(variants, newVariant(chrom, start-1, anchor+refBases.String(), anchor+altBases.String()))
+		case referencePosition < len(ungapped):
+			anchor := ungapped[referencePosition : referencePosition+1]
+			variants = append(variants, newVariant(chrom, start, refBases.String()+anchor, altBases.String()+anchor))
+		default:
+			return nil, fmt.Errorf("%w: the aligned sequences have no bases in common to anchor a variant to", ErrInvalidVariant)
+		}
+		column = end
+	}
+	return variants, nil
+}
+
+// newVariant makes a variant without any quality, filters or INFO fields.
+func newVariant(chrom string, position int, ref, alt string) Variant {
+	return Variant{Chrom: chrom, Position: position, Ref: ref, Alt: []string{alt}, Quality: math.NaN(), Info: map[string]string{}}
+}
+
+/******************************************************************************
+
+Sequence functions end here.
+
+******************************************************************************/
+
+// Read reads a VCF file. Gzip (including bgzip) and bzip2 compressed files are detected and decompressed automatically.
+func Read(path string) (Vcf, error) {
+	file, err := compress.ReadFile(path)
+	if err != nil {
+		return Vcf{}, err
+	}
+	return Parse(file)
+}
+
+// Write takes a Vcf struct and a path string and writes out a VCF file to that path.
+func Write(vcf Vcf, path string) error {
+	vcfBytes, err := Build(vcf)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, vcfBytes, 0644)
+}
@@ -0,0 +1,255 @@
+package vcf_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TimothyStiles/poly/io/vcf"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func ExampleRead() {
+	variants, _ := vcf.Read("../../data/example.vcf")
+	variant := variants.Variants[0]
+	depth, _ := variant.InfoInts("DP")
+	genotype, _ := variant.Genotype(1)
+
+	fmt.Println(variant.Position, variant.Ref, variant.Alt, depth, genotype)
+	// Output: 9 C [T] [14] 1|0
+}
+
+func ExampleApply() {
+	variants, _ := vcf.Derive("example", "atg-cgtaa", "atgacg-aa")
+	applied, _ := vcf.Apply("atgcgtaa", variants)
+
+	fmt.Println(applied)
+	// Output: atgacgaa
+}
+
+func TestParse(t *testing.T) {
+	variants, err := vcf.Read("../../data/example.vcf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedHeader := vcf.Header{
+		FileFormat: "VCFv4.3",
+		Info: []vcf.Definition{
+			{ID: "DP", Number: "1", Type: "Integer", Description: "Total Depth"},
+			{ID: "AF", Number: "A", Type: "Float", Description: "Allele Frequency"},
+			{ID: "DB", Number: "0", Type: "Flag", Description: "dbSNP membership, build 129"},
+		},
+		Format: []vcf.Definition{
+			{ID: "GT", Number: "1", Type: "String", Description: "Genotype"},
+			{ID: "GQ", Number: "1", Type: "Integer", Description: "Genotype Quality"},
+			{ID: "HQ", Number: "2", Type: "Integer", Description: "Haplotype Quality"},
+		},
+		Filters: []vcf.Definition{{ID: "q10", Description: "Quality below 10"}},
+		Contigs: []vcf.Contig{{ID: "chr1", Length: 100}},
+		Other:   []string{"source=poly"},
+		Samples: []string{"NA00001", "NA00002"},
+	}
+	if diff := cmp.Diff(expectedHeader, variants.Header); diff != "" {
+		t.Errorf("unexpected header (-want +got):\n%s", diff)
+	}
+
+	expectedFirst := vcf.Variant{
+		Chrom:     "chr1",
+		Position:  9,
+		IDs:       []string{"rs6054257"},
+		Ref:       "C",
+		Alt:       []string{"T"},
+		Quality:   29,
+		Filters:   []string{"PASS"},
+		Info:      map[string]string{"DP": "14", "AF": "0.5", "DB": ""},
+		InfoOrder: []string{"DP", "AF", "DB"},
+		Format:    []string{"GT", "GQ", "HQ"},
+		Samples: []map[string]string{
+			{"GT": "0|0", "GQ": "48", "HQ": "51,51"},
+			{"GT": "1|0", "GQ": "48", "HQ": "8,9"},
+		},
+	}
+	if diff := cmp.Diff(expectedFirst, variants.Variants[0]); diff != "" {
+		t.Errorf("unexpected variant (-want +got):\n%s", diff)
+	}
+
+	if !math.IsNaN(variants.Variants[2].Quality) || variants.Variants[2].Filters != nil {
+		t.Errorf("expected a missing quality and filters, got %v and %v", variants.Variants[2].Quality, variants.Variants[2].Filters)
+	}
+}
+
+func TestTypedValues(t *testing.T) {
+	variants, err := vcf.Read("../../data/example.vcf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	multiallelic := variants.Variants[1]
+
+	frequencies, err := multiallelic.InfoFloats("AF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]float64{0.017, 0.3}, frequencies); diff != "" {
+		t.Errorf("unexpected allele frequencies (-want +got):\n%s", diff)
+	}
+	if multiallelic.InfoFlag("DB") || !variants.Variants[0].InfoFlag("DB") {
+		t.Error("expected only the first variant to have the DB flag")
+	}
+	if _, err := multiallelic.InfoInts("missing"); err == nil {
+		t.Error("expected an error getting an INFO field the variant doesn't have")
+	}
+
+	qualities, err := multiallelic.SampleInts(1, "GQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]int{vcf.MissingInt}, qualities); diff != "" {
+		t.Errorf("unexpected genotype quality (-want +got):\n%s", diff)
+	}
+	haplotypeQualities, err := variants.Variants[0].SampleFloats(0, "HQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]float64{51, 51}, haplotypeQualities); diff != "" {
+		t.Errorf("unexpected haplotype qualities (-want +got):\n%s", diff)
+	}
+	if _, err := multiallelic.SampleStrings(2, "GT"); err == nil {
+		t.Error("expected an error getting a sample that doesn't exist")
+	}
+
+	expectedGenotypes := []vcf.Genotype{{Alleles: []int{-1, -1}}, {Alleles: []int{1, 1}}}
+	for sample, expected := range expectedGenotypes {
+		genotype, err := variants.Variants[2].Genotype(sample)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, genotype); diff != "" {
+			t.Errorf("unexpected genotype (-want +got):\n%s", diff)
+		}
+	}
+	if genotype := (vcf.Genotype{Alleles: []int{0, -1}, Phased: true}); genotype.String() != "0|." {
+		t.Errorf("expected genotype 0|., got %s", genotype)
+	}
+}
+
+func TestVcfIO(t *testing.T) {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	variants, _ := vcf.Read("../../data/example.vcf")
+	path := filepath.Join(tmpDataDir, "example.vcf")
+	if err := vcf.Write(variants, path); err != nil {
+		t.Fatal(err)
+	}
+	reread, err := vcf.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(variants, reread, cmpopts.EquateNaNs()); diff != "" {
+		t.Errorf("Parsing the output of Build() does not produce the same output as parsing the original file. Got this diff:\n%s", diff)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	header := "##fileformat=VCFv4.3\n##INFO=<ID=DP,Number=1,Type=Integer,Description=\"Total Depth\">\n##FORMAT=<ID=GT,Number=1,Type=String,Description=\"Genotype\">\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tsample\n"
+	tests := []struct {
+		name     string
+		line     string
+		expected error
+	}{
+		{"wrong column count", "chr1\t1\t.\tA\tT\t.\t.\t.", vcf.ErrInvalidVariant},
+		{"bad position", "chr1\tone\t.\tA\tT\t.\t.\t.\tGT\t0/1", vcf.ErrInvalidVariant},
+		{"bad quality", "chr1\t1\t.\tA\tT\thigh\t.\t.\tGT\t0/1", vcf.ErrInvalidVariant},
+		{"bad integer", "chr1\t1\t.\tA\tT\t.\t.\tDP=deep\tGT\t0/1", vcf.ErrInvalidValue},
+		{"wrong number", "chr1\t1\t.\tA\tT\t.\t.\tDP=1,2\tGT\t0/1", vcf.ErrInvalidValue},
+		{"bad genotype", "chr1\t1\t.\tA\tT\t.\t.\t.\tGT\tx/1", vcf.ErrInvalidValue},
+	}
+	for _, test := range tests {
+		_, err := vcf.Parse([]byte(header + test.line + "\n"))
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, err)
+		}
+		var parseError *vcf.ParseError
+		if !errors.As(err, &parseError) || parseError.Line != 5 {
+			t.Errorf("%s: expected a parse error on line 5, got %v", test.name, err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	reference := strings.Repeat("a", 9) + "c" + strings.Repeat("a", 29) + "c" + strings.Repeat("a", 19) + "ga" + strings.Repeat("a", 40)
+	variants, err := vcf.Read("../../data/example.vcf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := vcf.Apply(reference, variants.Variants)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Repeat("a", 9) + "T" + strings.Repeat("a", 29) + "CAT" + strings.Repeat("a", 19) + "G" + strings.Repeat("a", 40)
+	if applied != expected {
+		t.Errorf("expected %s, got %s", expected, applied)
+	}
+
+	if _, err := vcf.Apply(strings.Repeat("g", 100), variants.Variants); !errors.Is(err, vcf.ErrInvalidVariant) {
+		t.Errorf("expected an error applying variants whose reference doesn't match, got %v", err)
+	}
+	overlapping := []vcf.Variant{{Position: 1, Ref: "AA", Alt: []string{"A"}}, {Position: 2, Ref: "A", Alt: []string{"T"}}}
+	if _, err := vcf.Apply("aaaa", overlapping); !errors.Is(err, vcf.ErrInvalidVariant) {
+		t.Errorf("expected an error applying overlapping variants, got %v", err)
+	}
+	symbolic := []vcf.Variant{{Position: 0, Ref: "A", Alt: []string{"<DEL>"}}}
+	if _, err := vcf.Apply("aaaa", symbolic); !errors.Is(err, vcf.ErrInvalidVariant) {
+		t.Errorf("expected an error applying a symbolic allele, got %v", err)
+	}
+}
+
+func TestDerive(t *testing.T) {
+	tests := []struct {
+		reference string
+		alternate string
+		expected  [][3]interface{} // position, ref, alt
+	}{
+		{"acgt", "aggt", [][3]interface{}{{1, "c", "g"}}},
+		{"acgt", "tgca", [][3]interface{}{{0, "a", "t"}, {1, "c", "g"}, {2, "g", "c"}, {3, "t", "a"}}},
+		{"ac--gt", "acttgt", [][3]interface{}{{1, "c", "ctt"}}},
+		{"acgt", "a--t", [][3]interface{}{{0, "acg", "a"}}},
+		{"--acgt", "ttacgt", [][3]interface{}{{0, "a", "tta"}}},
+		{"acgt", "-cgt", [][3]interface{}{{0, "ac", "c"}}},
+	}
+	for _, test := range tests {
+		variants, err := vcf.Derive("chr1", test.reference, test.alternate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got [][3]interface{}
+		for _, variant := range variants {
+			got = append(got, [3]interface{}{variant.Position, variant.Ref, variant.Alt[0]})
+		}
+		if diff := cmp.Diff(test.expected, got); diff != "" {
+			t.Errorf("unexpected variants between %s and %s (-want +got):\n%s", test.reference, test.alternate, diff)
+		}
+
+		applied, err := vcf.Apply(strings.ReplaceAll(test.reference, "-", ""), variants)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := strings.ReplaceAll(test.alternate, "-", ""); applied != expected {
+			t.Errorf("applying the variants derived from %s to %s gave %s", test.reference, expected, applied)
+		}
+	}
+
+	if _, err := vcf.Derive("chr1", "acgt", "acg"); !errors.Is(err, vcf.ErrInvalidVariant) {
+		t.Errorf("expected an error deriving variants from sequences of different lengths, got %v", err)
+	}
+}
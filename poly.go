@@ -0,0 +1,38 @@
+package poly
+
+// AnnotatedSequence is implemented by the sequence structs of each io package
+// (genbank.Genbank, gff.Gff and fasta.Fasta) so that code can work with any
+// format without caring which file it was read from.
+type AnnotatedSequence interface {
+	GetName() string
+	GetSequence() string
+	GetFeatures() []Feature
+	GetMeta() Meta
+}
+
+// AnnotatableSequence is an AnnotatedSequence that features can be added to.
+// Each format adds features of its own type F, so it is implemented by
+// pointers to the annotated sequence structs, like *genbank.Genbank as an
+// AnnotatableSequence[genbank.Feature] and *gff.Gff as an
+// AnnotatableSequence[gff.Feature].
+type AnnotatableSequence[F Feature] interface {
+	AnnotatedSequence
+	AddFeature(feature *F) error
+}
+
+// Feature is implemented by the feature structs of the annotated io packages
+// (genbank.Feature and gff.Feature).
+type Feature interface {
+	GetType() string
+	GetAttributes() map[string]string
+	GetSequence() (string, error)
+}
+
+// Meta is the meta data every format can describe, taken from each format's
+// own Meta struct by GetMeta. Formats that don't have one of these leave it
+// empty.
+type Meta struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Circular    bool   `json:"circular"`
+}
@@ -0,0 +1,87 @@
+package poly_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/TimothyStiles/poly"
+	"github.com/TimothyStiles/poly/io/fasta"
+	"github.com/TimothyStiles/poly/io/genbank"
+	"github.com/TimothyStiles/poly/io/gff"
+)
+
+// codingRegions concatenates the sequences of every CDS in any annotated sequence.
+func codingRegions(sequence poly.AnnotatedSequence) string {
+	var codingRegionsBuilder strings.Builder
+	for _, feature := range sequence.GetFeatures() {
+		if feature.GetType() == "CDS" {
+			featureSequence, _ := feature.GetSequence()
+			codingRegionsBuilder.WriteString(featureSequence)
+		}
+	}
+	return codingRegionsBuilder.String()
+}
+
+// This example shows how to write a single function that works on sequences
+// read from any file format.
+func ExampleAnnotatedSequence() {
+	puc19, _ := genbank.Read("data/puc19.gbk")
+	ecoli, _ := gff.Read("data/ecoli-mg1655-short.gff")
+
+	for _, sequence := range []poly.AnnotatedSequence{puc19, ecoli} {
+		fmt.Println(sequence.GetName(), len(codingRegions(sequence)))
+	}
+	// Output:
+	// puc19.gbk 1185
+	// U00096.3 5046
+}
+
+func TestAnnotatedSequence(t *testing.T) {
+	fastas, _ := fasta.Read("io/fasta/data/base.fasta")
+
+	var sequence poly.AnnotatedSequence = fastas[0]
+	if sequence.GetName() != fastas[0].Name || sequence.GetSequence() != fastas[0].Sequence {
+		t.Errorf("fasta.Fasta does not return its own name and sequence")
+	}
+	if len(sequence.GetFeatures()) != 0 {
+		t.Errorf("fasta.Fasta should not have any features")
+	}
+
+	ecoli, _ := gff.Read("data/ecoli-mg1655-short.gff")
+	features := ecoli.GetFeatures()
+	if len(features) != len(ecoli.Features) {
+		t.Fatalf("expected %d features, got %d", len(ecoli.Features), len(features))
+	}
+	if features[1].GetAttributes()["gene"] != "thrL" {
+		t.Errorf("expected thrL, got %q", features[1].GetAttributes()["gene"])
+	}
+}
+
+// addFeature adds a feature to an annotated sequence of any format that has features.
+func addFeature[F poly.Feature](sequence poly.AnnotatableSequence[F], feature F) error {
+	return sequence.AddFeature(&feature)
+}
+
+func TestAnnotatableSequence(t *testing.T) {
+	puc19, _ := genbank.Read("data/puc19.gbk")
+	meta := puc19.GetMeta()
+	if meta.Name != "puc19.gbk" || !meta.Circular || meta.Description != puc19.Meta.Definition {
+		t.Errorf("unexpected meta %+v", meta)
+	}
+
+	_ = addFeature[genbank.Feature](&puc19, genbank.Feature{Type: "misc_feature", Location: genbank.Location{Start: 0, End: 6}})
+	features := puc19.GetFeatures()
+	if featureSequence, err := features[len(features)-1].GetSequence(); err != nil || featureSequence != puc19.Sequence[:6] {
+		t.Errorf("expected %s, got %s: %v", puc19.Sequence[:6], featureSequence, err)
+	}
+
+	ecoli, _ := gff.Read("data/ecoli-mg1655-short.gff")
+	_ = addFeature[gff.Feature](&ecoli, gff.Feature{Type: "gene", Strand: "+", Location: gff.Location{Start: 0, End: 6}})
+	if len(ecoli.GetFeatures()) != len(ecoli.Features) || ecoli.Features[len(ecoli.Features)-1].ParentSequence != &ecoli {
+		t.Errorf("feature was not added to the gff")
+	}
+	if meta := ecoli.GetMeta(); meta.Name != "U00096.3" || meta.Circular {
+		t.Errorf("unexpected meta %+v", meta)
+	}
+}
@@ -0,0 +1,164 @@
+package primers
+
+import (
+	"math"
+	"strings"
+)
+
+/******************************************************************************
+
+Dimer and hairpin prediction begins here.
+
+Primers that pair with each other or fold back on themselves are busy doing
+that instead of binding their template, and a dimer that leaves a 3' end
+paired gets extended by polymerase into primer dimer that outcompetes the real
+product. These functions find the most stable of those structures and score
+it by its free energy (dG) at 37°C, in kcal/mol, where more negative is more
+stable. As a rule of thumb, structures with dG above -6 kcal/mol, or above -3
+kcal/mol at the 3' end, are harmless.
+
+The model is simplified: structures are single runs of Watson-Crick pairs
+without mismatches or bulges, scored with SantaLucia's nearest neighbor
+parameters in 1 M salt, and hairpin loops are scored by their length alone.
+It's meant for filtering oligos before ordering them, not for predicting
+their structure in detail.
+
+******************************************************************************/
+
+// bodyTemperature is 37°C in kelvin, the temperature dGs are reported at.
+const bodyTemperature = 310.15
+
+// Dimer is the most stable duplex two oligos can form with each other.
+type Dimer struct {
+	DeltaG float64 // free energy of the duplex at 37°C in kcal/mol. 0 if the oligos can't pair.
+	Length int     // number of base pairs in the duplex.
+	// StartA and StartB are the positions of the 5'-most paired base of each oligo.
+	StartA int
+	StartB int
+	// ThreePrime is whether the duplex includes the 3' end of either oligo,
+	// which polymerase can extend.
+	ThreePrime bool
+}
+
+// Hairpin is the most stable hairpin an oligo can fold into.
+type Hairpin struct {
+	DeltaG     float64 // free energy of the hairpin at 37°C in kcal/mol. 0 if the oligo can't fold.
+	StemLength int     // number of base pairs in the stem.
+	LoopLength int     // number of unpaired bases in the loop.
+	Start      int     // position of the 5'-most paired base of the stem.
+}
+
+// SelfDimer returns the most stable duplex an oligo can form with another copy of itself.
+func SelfDimer(oligo string) Dimer {
+	return CrossDimer(oligo, oligo)
+}
+
+// CrossDimer returns the most stable duplex two oligos, both written 5' to 3',
+// can form with each other.
+func CrossDimer(a, b string) Dimer {
+	a, b = strings.ToUpper(a), strings.ToUpper(b)
+	var best Dimer
+	// a[i] pairs with b[j] and each next base of a pairs with the previous base of b.
+	for i := range a {
+		for j := range b {
+			// only start at the first pair of a run.
+			if !complementary(a[i], b[j]) || (i > 0 && j+1 < len(b) && complementary(a[i-1], b[j+1])) {
+				continue
+			}
+			length := 1
+			for i+length < len(a) && j-length >= 0 && complementary(a[i+length], b[j-length]) {
+				length++
+			}
+			if length < 2 {
+				continue
+			}
+			if deltaG := helixDeltaG(a[i : i+length]); deltaG < best.DeltaG {
+				best = Dimer{
+					DeltaG:     deltaG,
+					Length:     length,
+					StartA:     i,
+					StartB:     j - length + 1,
+					ThreePrime: i+length == len(a) || j == len(b)-1,
+				}
+			}
+		}
+	}
+	return best
+}
+
+// FindHairpin returns the most stable hairpin an oligo can fold into, with a
+// loop of at least 3 bases.
+func FindHairpin(oligo string) Hairpin {
+	oligo = strings.ToUpper(oligo)
+	var best Hairpin
+	// oligo[i] pairs with oligo[j] and the stem grows inwards from there.
+	for i := range oligo {
+		for j := len(oligo) - 1; j > i; j-- {
+			if !complementary(oligo[i], oligo[j]) || (i > 0 && j+1 < len(oligo) && complementary(oligo[i-1], oligo[j+1])) {
+				continue
+			}
+			for stem := 1; j-i+1-2*stem >= 3 && complementary(oligo[i+stem-1], oligo[j-stem+1]); stem++ {
+				if stem < 2 {
+					continue
+				}
+				loop := j - i + 1 - 2*stem
+				if deltaG := helixDeltaG(oligo[i:i+stem]) + hairpinLoopDeltaG(loop); deltaG < best.DeltaG {
+					best = Hairpin{DeltaG: deltaG, StemLength: stem, LoopLength: loop, Start: i}
+				}
+			}
+		}
+	}
+	return best
+}
+
+// complementary returns whether two bases form a Watson-Crick pair.
+func complementary(a, b byte) bool {
+	switch a {
+	case 'A':
+		return b == 'T'
+	case 'T':
+		return b == 'A'
+	case 'C':
+		return b == 'G'
+	case 'G':
+		return b == 'C'
+	}
+	return false
+}
+
+// helixDeltaG returns the free energy at 37°C of a perfect duplex of strand
+// with its complement, including helix initiation and terminal AT penalties.
+func helixDeltaG(strand string) float64 {
+	dH, dS := initialThermodynamicPenalty.H, initialThermodynamicPenalty.S
+	for _, end := range []byte{strand[0], strand[len(strand)-1]} {
+		if end == 'A' || end == 'T' {
+			dH += terminalATThermodynamicPenalty.H
+			dS += terminalATThermodynamicPenalty.S
+		}
+	}
+	for i := 0; i+1 < len(strand); i++ {
+		dT := nearestNeighborsThermodynamics[strand[i:i+2]]
+		dH += dT.H
+		dS += dT.S
+	}
+	return dH - bodyTemperature*dS/1000
+}
+
+// hairpinLoopDeltaGs are the free energies at 37°C of hairpin loops of 3 to 10 bases [SantaLucia J & Hicks D (2004) Annu Rev Biophys Biomol Struct, doi:10.1146/annurev.biophys.32.110601.141800].
+var hairpinLoopDeltaGs = []float64{3: 3.5, 4: 3.5, 5: 3.3, 6: 4.0, 7: 4.2, 8: 4.3, 9: 4.5, 10: 4.6}
+
+// hairpinLoopDeltaG returns the free energy of a hairpin loop, extrapolating
+// logarithmically for loops longer than 10 bases.
+func hairpinLoopDeltaG(length int) float64 {
+	if length < len(hairpinLoopDeltaGs) {
+		return hairpinLoopDeltaGs[length]
+	}
+	const gasConstant = 1.9872e-3 // gas constant (kcal / mol - K)
+	return hairpinLoopDeltaGs[10] + 2.44*gasConstant*bodyTemperature*math.Log(float64(length)/10)
+}
+
+/******************************************************************************
+
+Dimer and hairpin prediction ends here.
+
+******************************************************************************/
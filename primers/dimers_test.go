@@ -0,0 +1,80 @@
+package primers_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/TimothyStiles/poly/primers"
+)
+
+func ExampleSelfDimer() {
+	// an EcoRI site pairs with itself.
+	dimer := primers.SelfDimer("TTTGAATTCTTT")
+
+	fmt.Printf("%d bp, %.1f kcal/mol\n", dimer.Length, dimer.DeltaG)
+	// Output: 6 bp, -3.5 kcal/mol
+}
+
+func ExampleFindHairpin() {
+	hairpin := primers.FindHairpin("AAGCGCCTTTTGGCGCTT")
+
+	fmt.Printf("%d bp stem, %d base loop, %.1f kcal/mol\n", hairpin.StemLength, hairpin.LoopLength, hairpin.DeltaG)
+	// Output: 7 bp stem, 4 base loop, -5.2 kcal/mol
+}
+
+func TestCrossDimer(t *testing.T) {
+	// a primer and its reverse complement pair along their whole length, including both 3' ends.
+	dimer := primers.CrossDimer("GTAAAACGACGGCCAGT", "ACTGGCCGTCGTTTTAC")
+	if dimer.Length != 17 || !dimer.ThreePrime || dimer.StartA != 0 || dimer.StartB != 0 {
+		t.Errorf("expected a full length dimer, got %+v", dimer)
+	}
+
+	// only the 3' ends of these overlap.
+	dimer = primers.CrossDimer("AAAAAAAAAAGGCC", "CCCCCCCCCCCGGCC")
+	if dimer.Length != 4 || !dimer.ThreePrime || dimer.StartA != 10 || dimer.StartB != 11 {
+		t.Errorf("expected a 4 bp 3' dimer, got %+v", dimer)
+	}
+
+	// longer duplexes are more stable.
+	if long, short := primers.CrossDimer("GCGCGCGC", "GCGCGCGC"), primers.CrossDimer("GCGC", "GCGC"); long.DeltaG >= short.DeltaG {
+		t.Errorf("expected a longer duplex to be more stable, got %f and %f", long.DeltaG, short.DeltaG)
+	}
+
+	if dimer := primers.CrossDimer("AAAAAAA", "AAAAAAA"); dimer != (primers.Dimer{}) {
+		t.Errorf("expected no dimer, got %+v", dimer)
+	}
+}
+
+func TestFindHairpin(t *testing.T) {
+	hairpin := primers.FindHairpin("AAGCGCCTTTTGGCGCTT")
+	if hairpin.Start != 0 || hairpin.StemLength != 7 || hairpin.LoopLength != 4 {
+		t.Errorf("expected a 7 bp stem around a 4 base loop, got %+v", hairpin)
+	}
+
+	// the loop must be at least 3 bases long.
+	if hairpin := primers.FindHairpin("GGCCGGCC"); hairpin.StemLength > 2 {
+		t.Errorf("expected no hairpin with a loop under 3 bases, got %+v", hairpin)
+	}
+}
+
+func TestDesignPrimerPairsDimers(t *testing.T) {
+	gene := "aataattacaccgagataacacatcatggataaaccgatactcaaagattctatgaagctatttgaggcacttggtacgatcaagtcgcgctcaatgtttggtggcttcggacttttcgctgatgaaacgatgtttgcactggttgtgaatgatcaacttcacatacgagcagaccagcaaacttcatctaacttcgagaagcaagggctaaaaccgtacgtttataaaaagcgtggttttccagtcgttactaagtactacgcgatttccgacgacttgtgggaatccagtgaacgcttgatagaagtagcgaagaagtcgttagaacaagccaatttggaaaaaaagcaacaggcaagtagtaagcccgacaggttgaaagacctgcctaacttacgactagcgactgaacgaatgcttaagaaagctggtataaaatcagttgaacaacttgaagagaaaggtgcattgaatgcttacaaagcgatacgtgactctcactccgcaaaagtaagtattgagctactctgggctttagaaggagcgataaacggcacgcactggagcgtcgttcctcaatctcgcagagaagagctggaaaatgcgctttcttaa"
+	options := primers.PrimerOptions{MinSelfDimerDeltaG: -6, MinHairpinDeltaG: -2, MinCrossDimerDeltaG: -6, MaxPairs: 20}
+	pairs, err := primers.DesignPrimerPairs(gene, 200, 500, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pair := range pairs {
+		for _, primer := range []string{pair.Forward.Sequence, pair.Reverse.Sequence} {
+			if deltaG := primers.SelfDimer(primer).DeltaG; deltaG < -6 {
+				t.Errorf("primer %s has a self dimer of %f kcal/mol", primer, deltaG)
+			}
+			if deltaG := primers.FindHairpin(primer).DeltaG; deltaG < -2 {
+				t.Errorf("primer %s has a hairpin of %f kcal/mol", primer, deltaG)
+			}
+		}
+		if deltaG := primers.CrossDimer(pair.Forward.Sequence, pair.Reverse.Sequence).DeltaG; deltaG < -6 {
+			t.Errorf("primers %s and %s form a cross dimer of %f kcal/mol", pair.Forward.Sequence, pair.Reverse.Sequence, deltaG)
+		}
+	}
+}
@@ -16,12 +16,20 @@ https://en.wikipedia.org/wiki/Polymerase_chain_reaction
 You can read more about that at the link above but just know that an absolute huge
 number of protocols from diagnostics to plasmid cloning use these primers so they're
 super important.
+
+DesignPrimers picks a forward and reverse primer to amplify a target region of
+a longer sequence, while DesignPrimerPairs ranks several candidate pairs.
+SelfDimer, CrossDimer and FindHairpin score the structures that stop primers
+from working.
 */
 package primers
 
 import (
 	"bytes"
+	"container/heap"
+	"fmt"
 	"math"
+	"sort"
 	"strings"
 
 	"github.com/TimothyStiles/poly/checks"
@@ -127,6 +135,487 @@ func MeltingTemp(sequence string) float64 {
 	return meltingTemp
 }
 
+// Howley calculates the melting point of a DNA sequence from its GC content
+// and sodium concentration (molar) using the salt adjusted formula of Howley
+// et al. [Howley PM et al. (1979) J Biol Chem, doi:10.1016/S0021-9258(19)86536-4].
+// It ignores the order of bases, so it is rougher than SantaLucia, but it is
+// the formula many older protocols quote.
+func Howley(sequence string, saltConcentration float64) float64 {
+	sequence = strings.ToUpper(sequence)
+
+	gcPercent := 100 * float64(strings.Count(sequence, "G")+strings.Count(sequence, "C")) / float64(len(sequence))
+	return 81.5 + 16.6*math.Log10(saltConcentration) + 0.41*gcPercent - 600/float64(len(sequence))
+}
+
+// TmMethod picks how MeltingTempWithOptions calculates melting temperatures.
+type TmMethod int
+
+const (
+	NearestNeighborMethod TmMethod = iota // SantaLucia's nearest neighbor thermodynamics, the default.
+	WallaceMethod                         // the Wallace rule, 2 * (A+T) + 4 * (G+C).
+	GCContentMethod                       // Howley's salt adjusted GC content formula.
+)
+
+// TmOptions holds the reaction conditions used by MeltingTempWithOptions.
+// Concentrations are molar. A zero PrimerConcentration or SaltConcentration
+// falls back to the MeltingTemp defaults of 500 nM primer and 50 mM sodium.
+type TmOptions struct {
+	PrimerConcentration    float64
+	SaltConcentration      float64 // sodium (Na+) concentration
+	MagnesiumConcentration float64 // magnesium (Mg2+) concentration
+
+	// WallaceCutoff switches to the Wallace rule (2 * (A+T) + 4 * (G+C)) for
+	// oligos shorter than this many bases, where nearest neighbor parameters
+	// are unreliable. Leave at 0 to always use Method.
+	WallaceCutoff int
+
+	// Method is the formula used for oligos at least WallaceCutoff bases long.
+	// Only NearestNeighborMethod uses the primer and magnesium concentrations.
+	Method TmMethod
+}
+
+// MeltingTempWithOptions calculates the melting temperature of a DNA sequence
+// under the given reaction conditions using options.Method, or the Wallace
+// rule for oligos shorter than options.WallaceCutoff. It returns an error if
+// the sequence is shorter than 2 bases or contains anything other than ACGT.
+func MeltingTempWithOptions(sequence string, options TmOptions) (float64, error) {
+	sequence = strings.ToUpper(sequence)
+	if len(sequence) < 2 {
+		return 0, fmt.Errorf("sequence %q is too short to calculate a melting temperature, need at least 2 bases", sequence)
+	}
+	for index, base := range sequence {
+		if !strings.ContainsRune("ACGT", base) {
+			return 0, fmt.Errorf("invalid base %q at position %d: only A, C, G and T are allowed", base, index)
+		}
+	}
+
+	if len(sequence) < options.WallaceCutoff {
+		return Wallace(sequence), nil
+	}
+
+	if options.PrimerConcentration == 0 {
+		options.PrimerConcentration = 500e-9
+	}
+	if options.SaltConcentration == 0 {
+		options.SaltConcentration = 50e-3
+	}
+	switch options.Method {
+	case NearestNeighborMethod:
+		meltingTemp, _, _ := SantaLucia(sequence, options.PrimerConcentration, options.SaltConcentration, options.MagnesiumConcentration)
+		return meltingTemp, nil
+	case WallaceMethod:
+		return Wallace(sequence), nil
+	case GCContentMethod:
+		return Howley(sequence, options.SaltConcentration), nil
+	}
+	return 0, fmt.Errorf("unknown melting temperature method %d", options.Method)
+}
+
+// Wallace calculates the melting point of a very short DNA sequence (<14 bp) using the Wallace rule [Wallace RB et al. (1979) Nucleic Acids Res, doi:10.1093/nar/6.11.3543]
+func Wallace(sequence string) float64 {
+	sequence = strings.ToUpper(sequence)
+
+	atCount := float64(strings.Count(sequence, "A") + strings.Count(sequence, "T"))
+	gcCount := float64(strings.Count(sequence, "G") + strings.Count(sequence, "C"))
+
+	return 2*atCount + 4*gcCount
+}
+
+/******************************************************************************
+
+Primer design begins here.
+
+To amplify a region of DNA by PCR you need a forward primer that binds just
+upstream of it and a reverse primer that binds just downstream of it on the
+other strand. A good primer melts at about the same temperature as its partner,
+so that both bind at the annealing temperature, and can't pair with itself,
+either as a dimer with another copy of itself or by folding back into a hairpin.
+
+******************************************************************************/
+
+// PrimerOptions holds the constraints used by DesignPrimers and DesignPrimerPairs. Zero values fall
+// back to the defaults noted on each field.
+type PrimerOptions struct {
+	MinLength int     // shortest primer to consider. Defaults to 18.
+	MaxLength int     // longest primer to consider. Defaults to 30.
+	MinTm     float64 // lowest acceptable melting temperature. Defaults to 52.
+	MaxTm     float64 // highest acceptable melting temperature. Defaults to 65.
+	TargetTm  float64 // melting temperature to aim for. Defaults to halfway between MinTm and MaxTm.
+
+	// MaxSelfComplementarity is the longest stretch of a primer allowed to pair
+	// with another copy of itself. Defaults to 8.
+	MaxSelfComplementarity int
+	// MaxHairpinStem is the longest stem a primer is allowed to form by folding
+	// back on itself around a loop of at least 3 bases. Defaults to 4.
+	MaxHairpinStem int
+
+	// MinGC and MaxGC bound the fraction of a primer's bases that are G or C.
+	// They default to 0 and 1, which accepts any primer.
+	MinGC float64
+	MaxGC float64
+	// GCClamp is the number of G or C bases a primer must end with at its 3'
+	// end, which helps it stay bound where polymerase starts extending it.
+	// Defaults to 0.
+	GCClamp int
+	// MaxHomopolymer is the longest run of a single base allowed in a primer,
+	// since long runs make primers slip. Defaults to 0, which allows any run.
+	MaxHomopolymer int
+
+	// MinSelfDimerDeltaG and MinHairpinDeltaG are the most stable, that is
+	// most negative, self dimer and hairpin free energies in kcal/mol a primer
+	// may have, as scored by SelfDimer and FindHairpin. MinCrossDimerDeltaG is
+	// the same for the dimer between the two primers of a pair, which only
+	// DesignPrimerPairs checks. They default to 0, which skips the checks.
+	MinSelfDimerDeltaG  float64
+	MinHairpinDeltaG    float64
+	MinCrossDimerDeltaG float64
+
+	// SearchWindow is how many bases on either side of the target
+	// DesignPrimerPairs looks for primers in. Defaults to 100.
+	SearchWindow int
+	// MaxPairs is the number of primer pairs DesignPrimerPairs returns. Defaults to 5.
+	MaxPairs int
+
+	TmOptions TmOptions // reaction conditions melting temperatures are calculated with.
+}
+
+// withDefaults fills in any unset PrimerOptions.
+func (options PrimerOptions) withDefaults() PrimerOptions {
+	if options.MinLength == 0 {
+		options.MinLength = 18
+	}
+	if options.MaxLength == 0 {
+		options.MaxLength = 30
+	}
+	if options.MinTm == 0 {
+		options.MinTm = 52
+	}
+	if options.MaxTm == 0 {
+		options.MaxTm = 65
+	}
+	if options.TargetTm == 0 {
+		options.TargetTm = (options.MinTm + options.MaxTm) / 2
+	}
+	if options.MaxSelfComplementarity == 0 {
+		options.MaxSelfComplementarity = 8
+	}
+	if options.MaxHairpinStem == 0 {
+		options.MaxHairpinStem = 4
+	}
+	if options.MaxGC == 0 {
+		options.MaxGC = 1
+	}
+	if options.SearchWindow == 0 {
+		options.SearchWindow = 100
+	}
+	if options.MaxPairs == 0 {
+		options.MaxPairs = 5
+	}
+	return options
+}
+
+// check validates options once defaults have been filled in.
+func (options PrimerOptions) check() error {
+	if options.MinLength > options.MaxLength {
+		return fmt.Errorf("MinLength %d is greater than MaxLength %d", options.MinLength, options.MaxLength)
+	}
+	if options.MinTm > options.MaxTm {
+		return fmt.Errorf("MinTm %v is greater than MaxTm %v", options.MinTm, options.MaxTm)
+	}
+	if options.MinGC > options.MaxGC {
+		return fmt.Errorf("MinGC %v is greater than MaxGC %v", options.MinGC, options.MaxGC)
+	}
+	return nil
+}
+
+// acceptable returns a primer's melting temperature and whether it meets every constraint of options.
+func (options PrimerOptions) acceptable(primer string) (float64, bool) {
+	meltingTemp, err := MeltingTempWithOptions(primer, options.TmOptions)
+	if err != nil || meltingTemp < options.MinTm || meltingTemp > options.MaxTm {
+		return meltingTemp, false
+	}
+	if gc := checks.GcContent(primer); gc < options.MinGC || gc > options.MaxGC {
+		return meltingTemp, false
+	}
+	if options.GCClamp > len(primer) || strings.Trim(primer[len(primer)-options.GCClamp:], "GC") != "" {
+		return meltingTemp, false
+	}
+	if options.MaxHomopolymer > 0 && longestHomopolymer(primer) > options.MaxHomopolymer {
+		return meltingTemp, false
+	}
+	selfComplementarity, hairpinStem := selfComplementarity(primer)
+	if selfComplementarity > options.MaxSelfComplementarity || hairpinStem > options.MaxHairpinStem {
+		return meltingTemp, false
+	}
+	if options.MinSelfDimerDeltaG != 0 && SelfDimer(primer).DeltaG < options.MinSelfDimerDeltaG {
+		return meltingTemp, false
+	}
+	if options.MinHairpinDeltaG != 0 && FindHairpin(primer).DeltaG < options.MinHairpinDeltaG {
+		return meltingTemp, false
+	}
+	return meltingTemp, true
+}
+
+// longestHomopolymer returns the length of the longest run of a single base in a primer.
+func longestHomopolymer(primer string) int {
+	longest, run := 0, 0
+	for index := range primer {
+		if index > 0 && primer[index] == primer[index-1] {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+// DesignPrimers designs a pair of primers that amplify sequence[targetStart:targetEnd].
+// The forward primer is taken from the sequence upstream of the target and the
+// reverse primer is the reverse complement of the sequence downstream of it, so
+// the product covers the whole target plus both primers.
+//
+// Primers are placed as close to the target as possible. At each position every
+// length between MinLength and MaxLength is tried and the primer whose melting
+// temperature is closest to TargetTm is kept, as long as it is inside the Tm
+// window and passes the self-complementarity and hairpin checks. If no position
+// gives an acceptable primer an error is returned.
+func DesignPrimers(sequence string, targetStart, targetEnd int, options PrimerOptions) (forward, reverse string, err error) {
+	if targetStart < 0 || targetEnd > len(sequence) || targetStart >= targetEnd {
+		return "", "", fmt.Errorf("target %d to %d is out of bounds for a sequence of length %d", targetStart, targetEnd, len(sequence))
+	}
+	options = options.withDefaults()
+	if err := options.check(); err != nil {
+		return "", "", err
+	}
+
+	sequence = strings.ToUpper(sequence)
+	forward, err = pickPrimer(sequence[:targetStart], options)
+	if err != nil {
+		return "", "", fmt.Errorf("no forward primer: %w", err)
+	}
+	reverse, err = pickPrimer(transform.ReverseComplement(sequence[targetEnd:]), options)
+	if err != nil {
+		return "", "", fmt.Errorf("no reverse primer: %w", err)
+	}
+	return forward, reverse, nil
+}
+
+// pickPrimer picks a primer from template whose 3' end is as close to the end of template as possible.
+func pickPrimer(template string, options PrimerOptions) (string, error) {
+	for offset := 0; offset+options.MinLength <= len(template); offset++ {
+		end := len(template) - offset
+		var bestPrimer string
+		bestDistance := math.Inf(1)
+		for length := options.MinLength; length <= options.MaxLength && length <= end; length++ {
+			primer := template[end-length : end]
+			meltingTemp, ok := options.acceptable(primer)
+			if !ok {
+				continue
+			}
+			if distance := math.Abs(meltingTemp - options.TargetTm); distance < bestDistance {
+				bestPrimer, bestDistance = primer, distance
+			}
+		}
+		if bestPrimer != "" {
+			return bestPrimer, nil
+		}
+	}
+	return "", fmt.Errorf("none of the %d bases available give a primer meeting the constraints", len(template))
+}
+
+// Primer is a primer picked by DesignPrimerPairs.
+type Primer struct {
+	Sequence string
+	// Start and End are the 0-based, half-open region of the top strand the
+	// primer binds. Reverse primers are the reverse complement of that region.
+	Start       int
+	End         int
+	MeltingTemp float64
+	GCContent   float64 // fraction of the primer's bases that are G or C.
+}
+
+// PrimerPair is a forward and reverse primer that amplify a target together.
+type PrimerPair struct {
+	Forward     Primer
+	Reverse     Primer
+	ProductSize int
+	// Penalty is how far the pair is from ideal: the distance of each
+	// primer's melting temperature from TargetTm plus the difference between
+	// their melting temperatures, in degrees. Lower is better.
+	Penalty float64
+}
+
+// DesignPrimerPairs designs primer pairs that amplify sequence[targetStart:targetEnd]
+// and returns up to MaxPairs of them, ranked from the lowest to the highest
+// penalty. Ties go to the pair with the smaller product.
+//
+// Every primer that binds within SearchWindow bases of the target and meets
+// all of the constraints of options is considered, so unlike DesignPrimers
+// primers aren't always placed right next to the target. If no acceptable
+// forward or reverse primer can be found an error is returned.
+func DesignPrimerPairs(sequence string, targetStart, targetEnd int, options PrimerOptions) ([]PrimerPair, error) {
+	if targetStart < 0 || targetEnd > len(sequence) || targetStart >= targetEnd {
+		return nil, fmt.Errorf("target %d to %d is out of bounds for a sequence of length %d", targetStart, targetEnd, len(sequence))
+	}
+	options = options.withDefaults()
+	if err := options.check(); err != nil {
+		return nil, err
+	}
+	sequence = strings.ToUpper(sequence)
+
+	windowStart := targetStart - options.SearchWindow
+	if windowStart < 0 {
+		windowStart = 0
+	}
+	windowEnd := targetEnd + options.SearchWindow
+	if windowEnd > len(sequence) {
+		windowEnd = len(sequence)
+	}
+
+	var forwards, reverses []Primer
+	for start := windowStart; start < targetStart; start++ {
+		for length := options.MinLength; length <= options.MaxLength && start+length <= targetStart; length++ {
+			primer := sequence[start : start+length]
+			if meltingTemp, ok := options.acceptable(primer); ok {
+				forwards = append(forwards, Primer{Sequence: primer, Start: start, End: start + length, MeltingTemp: meltingTemp, GCContent: checks.GcContent(primer)})
+			}
+		}
+	}
+	for end := targetEnd + options.MinLength; end <= windowEnd; end++ {
+		for length := options.MinLength; length <= options.MaxLength && end-length >= targetEnd; length++ {
+			primer := transform.ReverseComplement(sequence[end-length : end])
+			if meltingTemp, ok := options.acceptable(primer); ok {
+				reverses = append(reverses, Primer{Sequence: primer, Start: end - length, End: end, MeltingTemp: meltingTemp, GCContent: checks.GcContent(primer)})
+			}
+		}
+	}
+	if len(forwards) == 0 {
+		return nil, fmt.Errorf("no forward primer: none of the %d bases available give a primer meeting the constraints", targetStart-windowStart)
+	}
+	if len(reverses) == 0 {
+		return nil, fmt.Errorf("no reverse primer: none of the %d bases available give a primer meeting the constraints", windowEnd-targetEnd)
+	}
+
+	// a pair's penalty is at least the distance of each of its primers from
+	// TargetTm, so with primers sorted by that distance the search can stop as
+	// soon as no remaining pair could beat the worst of the best pairs kept.
+	tmDistance := func(primer Primer) float64 {
+		return math.Abs(primer.MeltingTemp - options.TargetTm)
+	}
+	for _, candidates := range [][]Primer{forwards, reverses} {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return tmDistance(candidates[i]) < tmDistance(candidates[j])
+		})
+	}
+	best := &pairHeap{}
+	for _, forward := range forwards {
+		if best.Len() == options.MaxPairs && tmDistance(forward)+tmDistance(reverses[0]) > (*best)[0].Penalty {
+			break
+		}
+		for _, reverse := range reverses {
+			if best.Len() == options.MaxPairs && tmDistance(forward)+tmDistance(reverse) > (*best)[0].Penalty {
+				break
+			}
+			pair := PrimerPair{
+				Forward:     forward,
+				Reverse:     reverse,
+				ProductSize: reverse.End - forward.Start,
+				Penalty:     tmDistance(forward) + tmDistance(reverse) + math.Abs(forward.MeltingTemp-reverse.MeltingTemp),
+			}
+			if best.Len() == options.MaxPairs && !betterPair(pair, (*best)[0]) {
+				continue
+			}
+			// cross dimers are only checked for pairs good enough to keep, since checking every pair is slow.
+			if options.MinCrossDimerDeltaG != 0 && CrossDimer(pair.Forward.Sequence, pair.Reverse.Sequence).DeltaG < options.MinCrossDimerDeltaG {
+				continue
+			}
+			heap.Push(best, pair)
+			if best.Len() > options.MaxPairs {
+				heap.Pop(best)
+			}
+		}
+	}
+	if best.Len() == 0 {
+		return nil, fmt.Errorf("every pair of the %d forward and %d reverse primers forms a cross dimer", len(forwards), len(reverses))
+	}
+
+	ranked := make([]PrimerPair, best.Len())
+	for index := len(ranked) - 1; index >= 0; index-- {
+		ranked[index] = heap.Pop(best).(PrimerPair)
+	}
+	return ranked, nil
+}
+
+// betterPair returns whether pair a ranks above pair b: it has a lower
+// penalty, then a smaller product, then binds further upstream with shorter
+// primers.
+func betterPair(a, b PrimerPair) bool {
+	switch {
+	case a.Penalty != b.Penalty:
+		return a.Penalty < b.Penalty
+	case a.ProductSize != b.ProductSize:
+		return a.ProductSize < b.ProductSize
+	case a.Forward.Start != b.Forward.Start:
+		return a.Forward.Start < b.Forward.Start
+	case a.Forward.End != b.Forward.End:
+		return a.Forward.End < b.Forward.End
+	case a.Reverse.End != b.Reverse.End:
+		return a.Reverse.End < b.Reverse.End
+	}
+	return a.Reverse.Start > b.Reverse.Start
+}
+
+// pairHeap holds the best primer pairs found so far with the worst of them on top.
+type pairHeap []PrimerPair
+
+func (pairs pairHeap) Len() int           { return len(pairs) }
+func (pairs pairHeap) Less(i, j int) bool { return betterPair(pairs[j], pairs[i]) }
+func (pairs pairHeap) Swap(i, j int)      { pairs[i], pairs[j] = pairs[j], pairs[i] }
+
+func (pairs *pairHeap) Push(pair interface{}) {
+	*pairs = append(*pairs, pair.(PrimerPair))
+}
+
+func (pairs *pairHeap) Pop() interface{} {
+	old := *pairs
+	pair := old[len(old)-1]
+	*pairs = old[:len(old)-1]
+	return pair
+}
+
+// selfComplementarity returns the longest stretch of a primer that can pair
+// with another copy of the primer, and the longest stem it can form by folding
+// back on itself around a loop of at least 3 bases.
+func selfComplementarity(primer string) (longest, hairpinStem int) {
+	reverseComplement := transform.ReverseComplement(primer)
+	// matches[i][j] is the length of the longest common substring of primer and
+	// reverseComplement ending at primer[i-1] and reverseComplement[j-1].
+	matches := make([][]int, len(primer)+1)
+	for index := range matches {
+		matches[index] = make([]int, len(primer)+1)
+	}
+	for i := 1; i <= len(primer); i++ {
+		for j := 1; j <= len(primer); j++ {
+			if primer[i-1] != reverseComplement[j-1] {
+				continue
+			}
+			matches[i][j] = matches[i-1][j-1] + 1
+			if matches[i][j] > longest {
+				longest = matches[i][j]
+			}
+			// primer[i-k:i] pairs with the bases starting at primer[len(primer)-j],
+			// which makes a hairpin if at least 3 bases are left between them.
+			if i+3 <= len(primer)-j && matches[i][j] > hairpinStem {
+				hairpinStem = matches[i][j]
+			}
+		}
+	}
+	return longest, hairpinStem
+}
+
 /******************************************************************************
 May 23 2021
 
@@ -85,6 +85,185 @@ func TestMeltingTemp(t *testing.T) {
 	}
 }
 
+func ExampleHowley() {
+	sequenceString := "GTAAAACGACGGCCAGT" // M13 fwd
+
+	fmt.Printf("%.1f\n", primers.Howley(sequenceString, 50e-3))
+	// output: 46.3
+}
+
+func ExampleMeltingTempWithOptions() {
+	sequenceString := "GTAAAACGACGGCCAGT" // M13 fwd
+
+	// PCR buffer with 50 mM sodium and 1.5 mM magnesium.
+	options := primers.TmOptions{PrimerConcentration: 250e-9, SaltConcentration: 50e-3, MagnesiumConcentration: 1.5e-3}
+	meltingTemp, _ := primers.MeltingTempWithOptions(sequenceString, options)
+
+	fmt.Printf("%.1f\n", meltingTemp)
+	// output: 59.4
+}
+
+func TestMeltingTempWithOptions(t *testing.T) {
+	testSeq := "GTAAAACGACGGCCAGT" // M13 fwd
+
+	// the zero value should match MeltingTemp.
+	calcTM, err := primers.MeltingTempWithOptions(testSeq, primers.TmOptions{})
+	if err != nil {
+		t.Error(err)
+	}
+	if expectedTM := primers.MeltingTemp(testSeq); calcTM != expectedTM {
+		t.Errorf("MeltingTempWithOptions with default options got %f instead of %f", calcTM, expectedTM)
+	}
+
+	// more salt stabilizes the duplex.
+	saltyTM, _ := primers.MeltingTempWithOptions(testSeq, primers.TmOptions{SaltConcentration: 1})
+	if saltyTM <= calcTM {
+		t.Errorf("expected a higher melting temp at higher salt, got %f and %f", saltyTM, calcTM)
+	}
+
+	// short oligos should use the Wallace rule under the cutoff.
+	wallaceTM, _ := primers.MeltingTempWithOptions("ACGTCCGGACTT", primers.TmOptions{WallaceCutoff: 14})
+	if wallaceTM != 38 {
+		t.Errorf("expected the Wallace rule to give 38, got %f", wallaceTM)
+	}
+
+	// the simpler formulas can be picked for longer oligos too.
+	if wallaceTM, _ := primers.MeltingTempWithOptions(testSeq, primers.TmOptions{Method: primers.WallaceMethod}); wallaceTM != primers.Wallace(testSeq) {
+		t.Errorf("expected the Wallace rule to give %f, got %f", primers.Wallace(testSeq), wallaceTM)
+	}
+	if gcTM, _ := primers.MeltingTempWithOptions(testSeq, primers.TmOptions{Method: primers.GCContentMethod}); gcTM != primers.Howley(testSeq, 50e-3) {
+		t.Errorf("expected Howley's formula to give %f, got %f", primers.Howley(testSeq, 50e-3), gcTM)
+	}
+	if _, err := primers.MeltingTempWithOptions(testSeq, primers.TmOptions{Method: 42}); err == nil {
+		t.Errorf("expected an error for an unknown method")
+	}
+
+	for _, badSeq := range []string{"", "A", "ACGTNACGT", "ACGU"} {
+		if _, err := primers.MeltingTempWithOptions(badSeq, primers.TmOptions{}); err == nil {
+			t.Errorf("expected an error for sequence %q", badSeq)
+		}
+	}
+}
+
+func ExampleDesignPrimers() {
+	gene := "aataattacaccgagataacacatcatggataaaccgatactcaaagattctatgaagctatttgaggcacttggtacgatcaagtcgcgctcaatgtttggtggcttcggacttttcgctgatgaaacgatgtttgcactggttgtgaatgatcaacttcacatacgagcagaccagcaaacttcatctaacttcgagaagcaagggctaaaaccgtacgtttataaaaagcgtggttttccagtcgttactaagtactacgcgatttccgacgacttgtgggaatccagtgaacgcttgatagaagtagcgaagaagtcgttagaacaagccaatttggaaaaaaagcaacaggcaagtagtaagcccgacaggttgaaagacctgcctaacttacgactagcgactgaacgaatgcttaagaaagctggtataaaatcagttgaacaacttgaagagaaaggtgcattgaatgcttacaaagcgatacgtgactctcactccgcaaaagtaagtattgagctactctgggctttagaaggagcgataaacggcacgcactggagcgtcgttcctcaatctcgcagagaagagctggaaaatgcgctttcttaa"
+
+	// amplify bases 200 to 500 of the gene.
+	forward, reverse, _ := primers.DesignPrimers(gene, 200, 500, primers.PrimerOptions{})
+
+	fmt.Println(forward, reverse)
+	// Output: ACCAGCAAACTTCATCTAACTTCGAGA AGTAGCTCAATACTTACTTTTGCGGAGT
+}
+
+func TestDesignPrimers(t *testing.T) {
+	gene := strings.ToUpper("aataattacaccgagataacacatcatggataaaccgatactcaaagattctatgaagctatttgaggcacttggtacgatcaagtcgcgctcaatgtttggtggcttcggacttttcgctgatgaaacgatgtttgcactggttgtgaatgatcaacttcacatacgagcagaccagcaaacttcatctaacttcgagaagcaagggctaaaaccgtacgtttataaaaagcgtggttttccagtcgttactaagtactacgcgatttccgacgacttgtgggaatccagtgaacgcttgatagaagtagcgaagaagtcgttagaacaagccaatttggaaaaaaagcaacaggcaagtagtaagcccgacaggttgaaagacctgcctaacttacgactagcgactgaacgaatgcttaagaaagctggtataaaatcagttgaacaacttgaagagaaaggtgcattgaatgcttacaaagcgatacgtgactctcactccgcaaaagtaagtattgagctactctgggctttagaaggagcgataaacggcacgcactggagcgtcgttcctcaatctcgcagagaagagctggaaaatgcgctttcttaa")
+	options := primers.PrimerOptions{MinLength: 20, MaxLength: 25, MinTm: 55, MaxTm: 62}
+	forward, reverse, err := primers.DesignPrimers(gene, 100, 400, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the primers must flank the target without overlapping it.
+	if forwardIndex := strings.Index(gene, forward); forwardIndex == -1 || forwardIndex+len(forward) > 100 {
+		t.Errorf("forward primer %s doesn't bind upstream of the target", forward)
+	}
+	if reverseIndex := strings.Index(gene, transform.ReverseComplement(reverse)); reverseIndex < 400 {
+		t.Errorf("reverse primer %s doesn't bind downstream of the target", reverse)
+	}
+	for _, primer := range []string{forward, reverse} {
+		if len(primer) < 20 || len(primer) > 25 {
+			t.Errorf("primer %s is outside of the length limits", primer)
+		}
+		if meltingTemp, _ := primers.MeltingTempWithOptions(primer, primers.TmOptions{}); meltingTemp < 55 || meltingTemp > 62 {
+			t.Errorf("primer %s has a melting temp of %f, outside of the Tm window", primer, meltingTemp)
+		}
+	}
+
+	// sequence that is entirely self-complementary can't give a primer.
+	palindromic := strings.Repeat("GAATTC", 10) + gene
+	if _, _, err := primers.DesignPrimers(palindromic, 60, 400, options); err == nil {
+		t.Errorf("expected an error designing a primer in self-complementary sequence")
+	}
+
+	for _, badDesign := range []struct {
+		targetStart, targetEnd int
+		options                primers.PrimerOptions
+	}{
+		{-1, 400, options},
+		{400, 100, options},
+		{100, len(gene) + 1, options},
+		{10, 400, options}, // not enough room for a forward primer.
+		{100, 400, primers.PrimerOptions{MinLength: 30, MaxLength: 20}},
+		{100, 400, primers.PrimerOptions{MinTm: 70, MaxTm: 60}},
+	} {
+		if _, _, err := primers.DesignPrimers(gene, badDesign.targetStart, badDesign.targetEnd, badDesign.options); err == nil {
+			t.Errorf("expected an error designing primers for %d to %d with %+v", badDesign.targetStart, badDesign.targetEnd, badDesign.options)
+		}
+	}
+}
+
+func ExampleDesignPrimerPairs() {
+	gene := "aataattacaccgagataacacatcatggataaaccgatactcaaagattctatgaagctatttgaggcacttggtacgatcaagtcgcgctcaatgtttggtggcttcggacttttcgctgatgaaacgatgtttgcactggttgtgaatgatcaacttcacatacgagcagaccagcaaacttcatctaacttcgagaagcaagggctaaaaccgtacgtttataaaaagcgtggttttccagtcgttactaagtactacgcgatttccgacgacttgtgggaatccagtgaacgcttgatagaagtagcgaagaagtcgttagaacaagccaatttggaaaaaaagcaacaggcaagtagtaagcccgacaggttgaaagacctgcctaacttacgactagcgactgaacgaatgcttaagaaagctggtataaaatcagttgaacaacttgaagagaaaggtgcattgaatgcttacaaagcgatacgtgactctcactccgcaaaagtaagtattgagctactctgggctttagaaggagcgataaacggcacgcactggagcgtcgttcctcaatctcgcagagaagagctggaaaatgcgctttcttaa"
+
+	// amplify bases 200 to 500 of the gene with primers that end in a G or C.
+	pairs, _ := primers.DesignPrimerPairs(gene, 200, 500, primers.PrimerOptions{GCClamp: 1, MaxHomopolymer: 4, MaxPairs: 1})
+
+	fmt.Println(pairs[0].Forward.Sequence, pairs[0].Reverse.Sequence, pairs[0].ProductSize)
+	// Output: GAAACGATGTTTGCACTGGTTGTG CGTTTATCGCTCCTTCTAAAGCCC 430
+}
+
+func TestDesignPrimerPairs(t *testing.T) {
+	gene := strings.ToUpper("aataattacaccgagataacacatcatggataaaccgatactcaaagattctatgaagctatttgaggcacttggtacgatcaagtcgcgctcaatgtttggtggcttcggacttttcgctgatgaaacgatgtttgcactggttgtgaatgatcaacttcacatacgagcagaccagcaaacttcatctaacttcgagaagcaagggctaaaaccgtacgtttataaaaagcgtggttttccagtcgttactaagtactacgcgatttccgacgacttgtgggaatccagtgaacgcttgatagaagtagcgaagaagtcgttagaacaagccaatttggaaaaaaagcaacaggcaagtagtaagcccgacaggttgaaagacctgcctaacttacgactagcgactgaacgaatgcttaagaaagctggtataaaatcagttgaacaacttgaagagaaaggtgcattgaatgcttacaaagcgatacgtgactctcactccgcaaaagtaagtattgagctactctgggctttagaaggagcgataaacggcacgcactggagcgtcgttcctcaatctcgcagagaagagctggaaaatgcgctttcttaa")
+	options := primers.PrimerOptions{MinLength: 20, MaxLength: 25, MinTm: 55, MaxTm: 62, MinGC: 0.4, MaxGC: 0.6, GCClamp: 2, MaxHomopolymer: 3, MaxPairs: 10}
+	pairs, err := primers.DesignPrimerPairs(gene, 100, 400, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 10 {
+		t.Fatalf("expected 10 primer pairs, got %d", len(pairs))
+	}
+	for index, pair := range pairs {
+		if index > 0 && pair.Penalty < pairs[index-1].Penalty {
+			t.Errorf("primer pairs aren't ranked by penalty: %v before %v", pairs[index-1].Penalty, pair.Penalty)
+		}
+		if pair.Forward.End > 100 || pair.Reverse.Start < 400 || pair.ProductSize != pair.Reverse.End-pair.Forward.Start {
+			t.Errorf("primer pair %+v doesn't flank the target", pair)
+		}
+		if gene[pair.Forward.Start:pair.Forward.End] != pair.Forward.Sequence || transform.ReverseComplement(gene[pair.Reverse.Start:pair.Reverse.End]) != pair.Reverse.Sequence {
+			t.Errorf("primer pair %+v doesn't match its binding sites", pair)
+		}
+		for _, primer := range []primers.Primer{pair.Forward, pair.Reverse} {
+			if primer.GCContent < 0.4 || primer.GCContent > 0.6 {
+				t.Errorf("primer %s has a GC content of %f, outside of the GC window", primer.Sequence, primer.GCContent)
+			}
+			if strings.Trim(primer.Sequence[len(primer.Sequence)-2:], "GC") != "" {
+				t.Errorf("primer %s doesn't have a GC clamp", primer.Sequence)
+			}
+			for _, run := range []string{"AAAA", "CCCC", "GGGG", "TTTT"} {
+				if strings.Contains(primer.Sequence, run) {
+					t.Errorf("primer %s has a homopolymer longer than 3", primer.Sequence)
+				}
+			}
+		}
+	}
+
+	// asking for fewer pairs returns the top of the same ranking.
+	options.MaxPairs = 3
+	if top, _ := primers.DesignPrimerPairs(gene, 100, 400, options); fmt.Sprint(top) != fmt.Sprint(pairs[:3]) {
+		t.Errorf("expected the best 3 of 10 pairs, got %v", top)
+	}
+
+	if _, err := primers.DesignPrimerPairs(gene, 100, 400, primers.PrimerOptions{MinGC: 0.8, MaxGC: 0.2}); err == nil {
+		t.Errorf("expected an error for a GC window with MinGC above MaxGC")
+	}
+	if _, err := primers.DesignPrimerPairs(gene, 100, 400, primers.PrimerOptions{GCClamp: 30}); err == nil {
+		t.Errorf("expected an error when no primer can meet the constraints")
+	}
+	if _, err := primers.DesignPrimerPairs(gene, 400, 100, options); err == nil {
+		t.Errorf("expected an error for a target that ends before it starts")
+	}
+}
+
 func ExampleNucleobaseDeBruijnSequence() {
 	a := primers.NucleobaseDeBruijnSequence(4)
 
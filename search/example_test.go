@@ -0,0 +1,64 @@
+package search_test
+
+import (
+	"fmt"
+
+	"github.com/TimothyStiles/poly/io/genbank"
+	"github.com/TimothyStiles/poly/search"
+)
+
+func ExampleFind() {
+	locations, _ := search.Find("aaGGTCTCaGAGACCtt", "GGTCTCN", search.Options{})
+
+	for _, location := range locations {
+		fmt.Println(location.Start, location.End, location.Complement)
+	}
+	// Output:
+	// 2 9 false
+	// 8 15 true
+}
+
+// This example shows how to annotate every BsaI site in a plasmid.
+func ExampleFind_annotate() {
+	puc19, _ := genbank.Read("../data/puc19.gbk")
+
+	locations, _ := search.Find(puc19.Sequence, "GGTCTC", search.Options{Circular: puc19.Meta.Locus.Circular})
+	for _, location := range locations {
+		feature := genbank.Feature{Type: "misc_feature", Location: location, Attributes: map[string]string{"note": "BsaI site"}}
+		_ = puc19.AddFeature(&feature)
+	}
+
+	site, _ := puc19.Features[len(puc19.Features)-1].GetSequence()
+	fmt.Println(len(locations), site)
+	// Output: 1 ggtctc
+}
+
+// This example shows how to look for places a guide RNA could bind with a
+// mismatch or a bulge.
+func ExampleFindApproximate() {
+	sequence := "ttttGACGTAACCAGttttttGACGTTCCAGtttt"
+	matches, _ := search.FindApproximate(sequence, "GACGTACCAG", 1, search.Options{Indels: true})
+
+	for _, match := range matches {
+		fmt.Println(match.Location.Start, match.Location.End, match.Distance)
+	}
+	// Output:
+	// 4 15 1
+	// 21 31 1
+}
+
+// This example shows how to build an index of a sequence once and look up
+// several patterns in it.
+func ExampleNewIndex() {
+	puc19, _ := genbank.Read("../data/puc19.gbk")
+	index := search.NewIndex(puc19.Sequence, puc19.Meta.Locus.Circular)
+
+	for _, pattern := range []string{"GAATTC", "GGTCTC", "GCNGC"} {
+		locations, _ := index.Find(pattern, search.Options{})
+		fmt.Println(pattern, len(locations))
+	}
+	// Output:
+	// GAATTC 1
+	// GGTCTC 1
+	// GCNGC 19
+}
@@ -0,0 +1,215 @@
+package search
+
+import (
+	"encoding/binary"
+	"fmt"
+	"index/suffixarray"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/TimothyStiles/poly/io/genbank"
+	"github.com/TimothyStiles/poly/transform"
+)
+
+/******************************************************************************
+
+Indexed search begins here.
+
+Find scans the whole sequence for every pattern, which is fine for a plasmid
+but slow when thousands of motifs are looked up in the same chromosome. An
+Index is a suffix array of the sequence, every position sorted by the
+sequence that follows it, so all the places a pattern occurs are next to each
+other and can be found by binary search in O(m log n) time.
+
+A suffix array can only look up exact strings, so each pattern is looked up
+by its longest stretch of unambiguous bases and every hit is then checked
+against the whole pattern. Patterns without any unambiguous bases fall back
+to a scan of the sequence.
+
+Approximate matches are found the same way using the pigeonhole principle: if
+a pattern is cut into k+1 pieces, a match with k mismatches must match at
+least one of the pieces exactly, so each piece is looked up and the hits are
+checked against the whole pattern.
+
+******************************************************************************/
+
+// Index is a suffix array of a sequence for searching it many times.
+type Index struct {
+	length   int // length of the indexed sequence, which suffixes has a copy of the start of appended if it's circular.
+	suffixes *suffixarray.Index
+}
+
+// NewIndex builds an Index of a sequence. Building takes O(n) time and
+// around 5 times the sequence's length in memory for sequences under 2 GB.
+// Circular sequences are indexed so that matches across their origin are
+// found, like Options.Circular does for Find.
+func NewIndex(sequence string, circular bool) *Index {
+	indexed := strings.ReplaceAll(strings.ToUpper(sequence), "U", "T")
+	if circular && len(indexed) > 0 {
+		indexed += indexed[:len(indexed)-1]
+	}
+	return &Index{length: len(sequence), suffixes: suffixarray.New([]byte(indexed))}
+}
+
+// Find returns the location of every match of an IUPAC pattern in the indexed
+// sequence, the same matches Find would return for the sequence. Options.Circular
+// is set when the index is built and Options.Indels isn't supported, so both
+// are ignored.
+func (index *Index) Find(pattern string, options Options) ([]genbank.Location, error) {
+	forward, err := compilePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(forward) > index.length {
+		return nil, nil
+	}
+
+	locations := index.find(strings.ToUpper(pattern), forward, false)
+	if !options.ForwardOnly {
+		reverseComplement := transform.ReverseComplement(strings.ToUpper(pattern))
+		if reverseComplement != strings.ToUpper(pattern) {
+			reverse, _ := compilePattern(reverseComplement)
+			locations = append(locations, index.find(reverseComplement, reverse, true)...)
+		}
+	}
+	sort.SliceStable(locations, func(i, j int) bool {
+		return locations[i].Start < locations[j].Start
+	})
+	return locations, nil
+}
+
+// FindApproximate returns every match of an IUPAC pattern in the indexed
+// sequence with at most maxMismatches mismatches, the same matches
+// FindApproximate would return for the sequence without Options.Indels, which
+// isn't supported by an Index. Each of the maxMismatches+1 pieces the pattern
+// is cut into is looked up separately, so searches are fastest when the pieces
+// are long enough to be rare in the sequence.
+func (index *Index) FindApproximate(pattern string, maxMismatches int, options Options) ([]Match, error) {
+	if maxMismatches < 0 {
+		return nil, fmt.Errorf("maximum distance must not be negative, got %d", maxMismatches)
+	}
+	forward, err := compilePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(forward) > index.length {
+		return nil, nil
+	}
+
+	matches := index.findMismatches(strings.ToUpper(pattern), forward, maxMismatches, false)
+	if !options.ForwardOnly {
+		reverseComplement := transform.ReverseComplement(strings.ToUpper(pattern))
+		if reverseComplement != strings.ToUpper(pattern) {
+			reverse, _ := compilePattern(reverseComplement)
+			matches = append(matches, index.findMismatches(reverseComplement, reverse, maxMismatches, true)...)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Location.Start < matches[j].Location.Start
+	})
+	return matches, nil
+}
+
+// Sequence returns the uppercased sequence an Index was built from, with any U
+// replaced by T.
+func (index *Index) Sequence() string {
+	return string(index.suffixes.Bytes()[:index.length])
+}
+
+// find returns the locations of every match of one strand of an uppercased pattern.
+func (index *Index) find(pattern string, compiled []uint8, complement bool) []genbank.Location {
+	var locations []genbank.Location
+	for _, match := range index.findMismatches(pattern, compiled, 0, complement) {
+		locations = append(locations, match.Location)
+	}
+	return locations
+}
+
+// findMismatches returns every match of one strand of an uppercased pattern
+// with at most maxMismatches mismatches.
+func (index *Index) findMismatches(pattern string, compiled []uint8, maxMismatches int, complement bool) []Match {
+	sequence := string(index.suffixes.Bytes())
+	pieces := maxMismatches + 1
+	if pieces > len(pattern) {
+		pieces = len(pattern)
+	}
+
+	// every start that one of the pieces matches at.
+	starts := make(map[int]bool)
+	for piece := 0; piece < pieces; piece++ {
+		pieceStart, pieceEnd := piece*len(pattern)/pieces, (piece+1)*len(pattern)/pieces
+		seedStart, seedEnd := longestUnambiguous(pattern[pieceStart:pieceEnd])
+		if seedStart == seedEnd {
+			return findMismatches(sequence, compiled, index.length, maxMismatches, complement)
+		}
+		seedStart, seedEnd = seedStart+pieceStart, seedEnd+pieceStart
+		seed := strings.ReplaceAll(pattern[seedStart:seedEnd], "U", "T")
+		for _, position := range index.suffixes.Lookup([]byte(seed), -1) {
+			if start := position - seedStart; start >= 0 && start < index.length && start+len(compiled) <= len(sequence) {
+				starts[start] = true
+			}
+		}
+	}
+
+	var found []Match
+	for start := range starts {
+		mismatches := 0
+		for offset, allowed := range compiled {
+			if !matches(sequence[start+offset], allowed) {
+				mismatches++
+			}
+		}
+		if mismatches <= maxMismatches {
+			found = append(found, Match{genbank.Location{Start: start, End: start + len(compiled), Complement: complement}, mismatches})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Location.Start < found[j].Location.Start
+	})
+	return found
+}
+
+// longestUnambiguous returns the start and end of the longest stretch of A, C,
+// G, T and U in an uppercased pattern.
+func longestUnambiguous(pattern string) (start, end int) {
+	runStart := 0
+	for position := 0; position <= len(pattern); position++ {
+		if position < len(pattern) && strings.IndexByte("ACGTU", pattern[position]) >= 0 {
+			continue
+		}
+		if position-runStart > end-start {
+			start, end = runStart, position
+		}
+		runStart = position + 1
+	}
+	return start, end
+}
+
+// Write writes an Index to w so it can be read back with ReadIndex instead of
+// being built again.
+func (index *Index) Write(w io.Writer) error {
+	if err := binary.Write(w, binary.LittleEndian, int64(index.length)); err != nil {
+		return err
+	}
+	return index.suffixes.Write(w)
+}
+
+// ReadIndex reads an Index written by Index.Write.
+func ReadIndex(r io.Reader) (*Index, error) {
+	var length int64
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return nil, fmt.Errorf("reading index length: %w", err)
+	}
+	suffixes := new(suffixarray.Index)
+	if err := suffixes.Read(r); err != nil {
+		return nil, fmt.Errorf("reading suffix array: %w", err)
+	}
+	return &Index{length: int(length), suffixes: suffixes}, nil
+}
+
+/******************************************************************************
+
+Indexed search ends here.
+
+******************************************************************************/
@@ -0,0 +1,108 @@
+package search_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/TimothyStiles/poly/search"
+)
+
+func TestIndexFind(t *testing.T) {
+	// the index should find exactly what Find finds, for patterns with and
+	// without ambiguity codes and in sequences with ambiguous bases.
+	random := rand.New(rand.NewSource(1))
+	randomSequence := func(length int, alphabet string) string {
+		sequence := make([]byte, length)
+		for position := range sequence {
+			sequence[position] = alphabet[random.Intn(len(alphabet))]
+		}
+		return string(sequence)
+	}
+
+	for _, circular := range []bool{false, true} {
+		sequence := randomSequence(2000, "ACGTACGTACGTacgtNR")
+		index := search.NewIndex(sequence, circular)
+		for trial := 0; trial < 200; trial++ {
+			pattern := randomSequence(1+random.Intn(6), "ACGTACGTNRYW")
+			options := search.Options{Circular: circular, ForwardOnly: trial%2 == 0}
+			expected, _ := search.Find(sequence, pattern, options)
+			locations, err := index.Find(pattern, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(locations) != fmt.Sprint(expected) {
+				t.Fatalf("pattern %s circular %t: expected %v, got %v", pattern, circular, expected, locations)
+			}
+		}
+	}
+
+	index := search.NewIndex("ACGT", true)
+	if locations, _ := index.Find("ACGTA", search.Options{}); len(locations) != 0 {
+		t.Errorf("expected no matches for a pattern longer than the sequence, got %v", locations)
+	}
+	if _, err := index.Find("AXG", search.Options{}); err == nil {
+		t.Errorf("expected an error for an invalid pattern")
+	}
+}
+
+func TestIndexWrite(t *testing.T) {
+	index := search.NewIndex("TCTCaaaaaaGG", true)
+	var buffer bytes.Buffer
+	if err := index.Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	read, err := search.ReadIndex(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := index.Find("GGTCTC", search.Options{})
+	locations, _ := read.Find("GGTCTC", search.Options{})
+	if len(locations) != 1 || fmt.Sprint(locations) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, locations)
+	}
+
+	if _, err := search.ReadIndex(bytes.NewReader([]byte{1, 2})); err == nil {
+		t.Errorf("expected an error for a truncated index")
+	}
+}
+
+func TestIndexFindApproximate(t *testing.T) {
+	// like TestIndexFind, the index should agree with FindApproximate.
+	random := rand.New(rand.NewSource(2))
+	randomSequence := func(length int, alphabet string) string {
+		sequence := make([]byte, length)
+		for position := range sequence {
+			sequence[position] = alphabet[random.Intn(len(alphabet))]
+		}
+		return string(sequence)
+	}
+
+	for _, circular := range []bool{false, true} {
+		sequence := randomSequence(2000, "ACGTACGTACGTacgtN")
+		index := search.NewIndex(sequence, circular)
+		for trial := 0; trial < 100; trial++ {
+			pattern := randomSequence(6+random.Intn(10), "ACGTACGTACGTN")
+			maxMismatches := random.Intn(4)
+			options := search.Options{Circular: circular, ForwardOnly: trial%2 == 0}
+			expected, _ := search.FindApproximate(sequence, pattern, maxMismatches, options)
+			matches, err := index.FindApproximate(pattern, maxMismatches, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(matches) != fmt.Sprint(expected) {
+				t.Fatalf("pattern %s with %d mismatches circular %t: expected %v, got %v", pattern, maxMismatches, circular, expected, matches)
+			}
+		}
+		if index.Sequence() != strings.ToUpper(sequence) {
+			t.Errorf("expected the index's sequence to be the uppercased sequence")
+		}
+	}
+
+	if _, err := search.NewIndex("ACGT", false).FindApproximate("ACG", -1, search.Options{}); err == nil {
+		t.Errorf("expected an error for a negative distance")
+	}
+}
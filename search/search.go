@@ -0,0 +1,332 @@
+/*
+Package search finds motifs in DNA sequences.
+
+Motifs are written as IUPAC patterns, where ambiguity codes like N (any base)
+or R (A or G) stand for every base they could be. That's how recognition
+sites are usually written down, like GGTCTCN for BsaI with the base it cuts
+after, or CCWGG for EcoRII.
+
+Both strands are searched and overlapping matches are all reported, so
+AAA found in AAAA gives two matches. Matches are returned as genbank.Location
+structs, so they can go straight into a genbank.Feature to annotate the
+sequence they were found in.
+
+FindApproximate also finds matches with a few differences from the pattern,
+which is what you need to check where else a primer could bind or where a
+guide RNA could cut off target. By default only mismatches are allowed, the
+Hamming distance, while Options.Indels allows insertions and deletions too,
+the edit distance, using Myers' bit-parallel algorithm [Myers G (1999) J ACM,
+doi:10.1145/316542.316550].
+
+For looking up many patterns in the same large sequence, like a chromosome,
+NewIndex builds a suffix array of it once that can be searched in time that
+depends on the length of the pattern rather than the sequence, and can be
+written to disk and read back.
+*/
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TimothyStiles/poly/io/genbank"
+	"github.com/TimothyStiles/poly/transform"
+)
+
+// iupacBits maps each IUPAC code onto the set of bases it stands for, with one
+// bit each for A, C, G and T. U is the same as T.
+var iupacBits = map[byte]uint8{
+	'A': 0b0001,
+	'C': 0b0010,
+	'G': 0b0100,
+	'T': 0b1000,
+	'U': 0b1000,
+	'R': 0b0101, // A or G
+	'Y': 0b1010, // C or T
+	'S': 0b0110, // G or C
+	'W': 0b1001, // A or T
+	'K': 0b1100, // G or T
+	'M': 0b0011, // A or C
+	'B': 0b1110, // not A
+	'D': 0b1101, // not C
+	'H': 0b1011, // not G
+	'V': 0b0111, // not T
+	'N': 0b1111, // any base
+}
+
+// Options changes how Find searches a sequence.
+type Options struct {
+	// Circular finds matches that run across the origin of a circular
+	// sequence. Their Location.End is past the end of the sequence, the way
+	// genbank.Location marks features spanning the origin.
+	Circular bool
+
+	// ForwardOnly skips the reverse complement strand.
+	ForwardOnly bool
+
+	// Indels lets FindApproximate count insertions and deletions as well as
+	// mismatches. Find ignores it.
+	Indels bool
+}
+
+// Match is a match found by FindApproximate.
+type Match struct {
+	Location genbank.Location
+	Distance int // mismatches, or edits if Options.Indels is set, between the match and the pattern.
+}
+
+// Find returns the location of every match of an IUPAC pattern in a sequence,
+// sorted by where they start. Matches on the reverse complement strand have
+// Location.Complement set, except for patterns that are their own reverse
+// complement, which are only reported once. A base in the sequence matches a
+// code in the pattern if the code stands for it, so ambiguous bases in the
+// sequence like N only match codes that cover all of their bases. Case is
+// ignored. An error is returned if the pattern is empty or has anything other
+// than IUPAC codes in it.
+func Find(sequence, pattern string, options Options) ([]genbank.Location, error) {
+	matches, err := find(sequence, pattern, 0, Options{Circular: options.Circular, ForwardOnly: options.ForwardOnly})
+	if err != nil {
+		return nil, err
+	}
+	var locations []genbank.Location
+	for _, match := range matches {
+		locations = append(locations, match.Location)
+	}
+	return locations, nil
+}
+
+// FindApproximate returns every match of an IUPAC pattern in a sequence with
+// at most maxDistance differences from it, sorted by where they start. Bases
+// are matched and strands searched like Find. Without Options.Indels every
+// overlapping match is reported. With it, a single site usually matches at
+// several neighbouring ends with one more edit each, so only the closest match
+// of each run of neighbouring ends is reported, and patterns can be at most 64
+// bases long.
+func FindApproximate(sequence, pattern string, maxDistance int, options Options) ([]Match, error) {
+	if maxDistance < 0 {
+		return nil, fmt.Errorf("maximum distance must not be negative, got %d", maxDistance)
+	}
+	if options.Indels && len(pattern) > 64 {
+		return nil, fmt.Errorf("patterns searched with indels can be at most 64 bases long, got %d", len(pattern))
+	}
+	return find(sequence, pattern, maxDistance, options)
+}
+
+// find searches both strands of a sequence for a pattern.
+func find(sequence, pattern string, maxDistance int, options Options) ([]Match, error) {
+	forward, err := compilePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(forward) > len(sequence)+maxDistance || (!options.Indels && len(forward) > len(sequence)) {
+		return nil, nil
+	}
+
+	// matches over the origin of a circular sequence are found by searching
+	// past the end of it into a copy of its start.
+	searched := strings.ToUpper(sequence)
+	if options.Circular {
+		overhang := len(forward) - 1
+		if options.Indels {
+			overhang += maxDistance
+		}
+		if overhang > len(searched) {
+			overhang = len(searched)
+		}
+		searched += searched[:overhang]
+	}
+
+	search := findMismatches
+	if options.Indels {
+		search = findEdits
+	}
+	matches := search(searched, forward, len(sequence), maxDistance, false)
+	if !options.ForwardOnly {
+		reverseComplement := transform.ReverseComplement(strings.ToUpper(pattern))
+		if reverseComplement != strings.ToUpper(pattern) {
+			reverse, _ := compilePattern(reverseComplement)
+			matches = append(matches, search(searched, reverse, len(sequence), maxDistance, true)...)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Location.Start < matches[j].Location.Start
+	})
+	return matches, nil
+}
+
+// compilePattern converts an IUPAC pattern into the sets of bases allowed at
+// each position.
+func compilePattern(pattern string) ([]uint8, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("pattern is empty")
+	}
+	compiled := make([]uint8, len(pattern))
+	for index, code := range []byte(strings.ToUpper(pattern)) {
+		bits, ok := iupacBits[code]
+		if !ok {
+			return nil, fmt.Errorf("invalid IUPAC code %q at position %d of pattern %q", pattern[index], index, pattern)
+		}
+		compiled[index] = bits
+	}
+	return compiled, nil
+}
+
+// matches returns whether an uppercased base in a sequence matches a position of a compiled pattern.
+func matches(base byte, allowed uint8) bool {
+	bits, ok := iupacBits[base]
+	return ok && bits&^allowed == 0
+}
+
+// findMismatches returns every match of a compiled pattern with at most
+// maxMismatches mismatches that starts before length in an uppercased sequence.
+func findMismatches(sequence string, pattern []uint8, length, maxMismatches int, complement bool) []Match {
+	var found []Match
+	for start := 0; start < length && start+len(pattern) <= len(sequence); start++ {
+		mismatches := 0
+		for offset, allowed := range pattern {
+			if !matches(sequence[start+offset], allowed) {
+				mismatches++
+				if mismatches > maxMismatches {
+					break
+				}
+			}
+		}
+		if mismatches <= maxMismatches {
+			found = append(found, Match{genbank.Location{Start: start, End: start + len(pattern), Complement: complement}, mismatches})
+		}
+	}
+	return found
+}
+
+/******************************************************************************
+
+Approximate matching with indels begins here.
+
+Myers' algorithm fills in the same dynamic programming table as the usual
+edit distance algorithm, with the pattern down the side and the sequence
+across the top, but stores each column as bit vectors of whether every cell
+is one more or one less than the cell above it. A whole column can then be
+worked out from the last one with a handful of bitwise operations, so for a
+pattern that fits into a machine word the search takes linear time.
+
+The table only gives the end of each match and its distance, so the start is
+found afterwards by aligning the pattern backwards from the end.
+
+******************************************************************************/
+
+// findEdits returns the closest match of each run of neighbouring match ends of
+// a compiled pattern with at most maxEdits edits that starts before length in
+// an uppercased sequence.
+func findEdits(sequence string, pattern []uint8, length, maxEdits int, complement bool) []Match {
+	// equal[base] has a bit set for every position of the pattern that base matches.
+	var equal [256]uint64
+	for base := range iupacBits {
+		for index, allowed := range pattern {
+			if matches(base, allowed) {
+				equal[base] |= 1 << uint(index)
+			}
+		}
+	}
+
+	mask := ^uint64(0) >> uint(64-len(pattern))
+	last := uint64(1) << uint(len(pattern)-1)
+	positive, negative := mask, uint64(0)
+	distance := len(pattern)
+
+	var found []Match
+	best := Match{Distance: -1}
+	for index := 0; index < len(sequence); index++ {
+		equalBits := equal[sequence[index]]
+		verticalX := equalBits | negative
+		horizontalX := (((equalBits & positive) + positive) ^ positive) | equalBits
+		horizontalPositive := negative | ^(horizontalX | positive)
+		horizontalNegative := positive & horizontalX
+		if horizontalPositive&last != 0 {
+			distance++
+		} else if horizontalNegative&last != 0 {
+			distance--
+		}
+		horizontalPositive <<= 1
+		horizontalNegative <<= 1
+		positive = (horizontalNegative | ^(verticalX | horizontalPositive)) & mask
+		negative = horizontalPositive & verticalX & mask
+
+		if distance <= maxEdits {
+			if best.Distance < 0 || distance < best.Distance {
+				end := index + 1
+				start := matchStart(sequence[:end], pattern, distance)
+				best = Match{genbank.Location{Start: start, End: end, Complement: complement}, distance}
+			}
+			continue
+		}
+		if best.Distance >= 0 && best.Location.Start < length {
+			found = append(found, best)
+		}
+		best = Match{Distance: -1}
+	}
+	if best.Distance >= 0 && best.Location.Start < length {
+		found = append(found, best)
+	}
+	return found
+}
+
+// matchStart returns where a match of a compiled pattern with the given
+// distance that ends at the end of a sequence starts, preferring the match
+// closest in length to the pattern.
+func matchStart(sequence string, pattern []uint8, distance int) int {
+	// previous[length] is the edit distance between the end of the pattern and
+	// the last length bases of the sequence, going backwards one base of the
+	// pattern at a time.
+	maxLength := len(pattern) + distance
+	if maxLength > len(sequence) {
+		maxLength = len(sequence)
+	}
+	previous := make([]int, maxLength+1)
+	current := make([]int, maxLength+1)
+	for length := range previous {
+		previous[length] = length
+	}
+	for patternIndex := len(pattern) - 1; patternIndex >= 0; patternIndex-- {
+		current[0] = len(pattern) - patternIndex
+		for length := 1; length <= maxLength; length++ {
+			cost := 1
+			if matches(sequence[len(sequence)-length], pattern[patternIndex]) {
+				cost = 0
+			}
+			current[length] = minimum(previous[length-1]+cost, previous[length]+1, current[length-1]+1)
+		}
+		previous, current = current, previous
+	}
+
+	bestLength := -1
+	for length, edits := range previous {
+		if edits == distance && (bestLength < 0 || abs(length-len(pattern)) < abs(bestLength-len(pattern))) {
+			bestLength = length
+		}
+	}
+	return len(sequence) - bestLength
+}
+
+func minimum(values ...int) int {
+	smallest := values[0]
+	for _, value := range values[1:] {
+		if value < smallest {
+			smallest = value
+		}
+	}
+	return smallest
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+/******************************************************************************
+
+Approximate matching with indels ends here.
+
+******************************************************************************/
@@ -0,0 +1,165 @@
+package search_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/TimothyStiles/poly/io/genbank"
+	"github.com/TimothyStiles/poly/search"
+)
+
+func TestFind(t *testing.T) {
+	// BsaI on both strands, with an N.
+	sequence := "aaGGTCTCaGAGACCtt"
+	locations, err := search.Find(sequence, "GGTCTCN", search.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []genbank.Location{{Start: 2, End: 9}, {Start: 8, End: 15, Complement: true}}
+	if fmt.Sprint(locations) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, locations)
+	}
+
+	forward, _ := search.Find(sequence, "GGTCTCN", search.Options{ForwardOnly: true})
+	if len(forward) != 1 || forward[0].Complement {
+		t.Errorf("expected only the forward match, got %v", forward)
+	}
+
+	// overlapping matches are all found.
+	if overlapping, _ := search.Find("AAAA", "AAA", search.Options{ForwardOnly: true}); len(overlapping) != 2 {
+		t.Errorf("expected 2 overlapping matches, got %v", overlapping)
+	}
+
+	// palindromes are only reported once.
+	if palindromes, _ := search.Find("GAATTCGAATTC", "GAATTC", search.Options{}); len(palindromes) != 2 {
+		t.Errorf("expected 2 EcoRI sites, got %v", palindromes)
+	}
+
+	// an N in the sequence only matches an N in the pattern.
+	if ambiguous, _ := search.Find("ACNT", "ACGT", search.Options{}); len(ambiguous) != 0 {
+		t.Errorf("expected no matches for an ambiguous base, got %v", ambiguous)
+	}
+	if ambiguous, _ := search.Find("ACNT", "ACNT", search.Options{ForwardOnly: true}); len(ambiguous) != 1 {
+		t.Errorf("expected a match for a pattern N, got %v", ambiguous)
+	}
+	if ambiguous, _ := search.Find("ACRT", "ACRT", search.Options{ForwardOnly: true}); len(ambiguous) != 1 {
+		t.Errorf("expected R to match R, got %v", ambiguous)
+	}
+
+	// RNA sequences match DNA patterns.
+	if rna, _ := search.Find("GGUCUC", "GGTCTC", search.Options{}); len(rna) != 1 {
+		t.Errorf("expected U to match T, got %v", rna)
+	}
+
+	if none, _ := search.Find("ACG", "ACGT", search.Options{Circular: true}); len(none) != 0 {
+		t.Errorf("expected no matches for a pattern longer than the sequence, got %v", none)
+	}
+
+	for _, pattern := range []string{"", "GGXC", "GG-C"} {
+		if _, err := search.Find(sequence, pattern, search.Options{}); err == nil {
+			t.Errorf("expected an error for pattern %q", pattern)
+		}
+	}
+}
+
+func TestFindCircular(t *testing.T) {
+	// a BsaI site split across the origin.
+	sequence := "TCTCaaaaaaGG"
+	if linear, _ := search.Find(sequence, "GGTCTC", search.Options{}); len(linear) != 0 {
+		t.Errorf("expected no matches in a linear sequence, got %v", linear)
+	}
+
+	circular, err := search.Find(sequence, "GGTCTC", search.Options{Circular: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []genbank.Location{{Start: 10, End: 16}}
+	if fmt.Sprint(circular) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v, got %v", expected, circular)
+	}
+
+	// the location slices out of a circular genbank sequence.
+	plasmid := genbank.Genbank{Sequence: sequence}
+	plasmid.Meta.Locus.Circular = true
+	feature := genbank.Feature{Type: "misc_feature", Location: circular[0]}
+	_ = plasmid.AddFeature(&feature)
+	site, err := plasmid.Features[0].GetSequence()
+	if err != nil || site != "GGTCTC" {
+		t.Errorf("expected GGTCTC, got %q and %v", site, err)
+	}
+}
+
+func TestFindApproximate(t *testing.T) {
+	// a guide with one mismatch to the first site and two to the second.
+	sequence := "ttGACGTTCCAGttttGACGAACCTGtt"
+	matches, err := search.FindApproximate(sequence, "GACGTACCAG", 1, search.Options{ForwardOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []search.Match{{genbank.Location{Start: 2, End: 12}, 1}}
+	if fmt.Sprint(matches) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, matches)
+	}
+	if matches, _ := search.FindApproximate(sequence, "GACGTACCAG", 2, search.Options{ForwardOnly: true}); len(matches) != 2 || matches[1].Distance != 2 {
+		t.Errorf("expected a second match with 2 mismatches, got %v", matches)
+	}
+
+	// a distance of 0 finds the same matches as Find.
+	exact, _ := search.Find("aaGGTCTCaGAGACCtt", "GGTCTCN", search.Options{})
+	approximate, _ := search.FindApproximate("aaGGTCTCaGAGACCtt", "GGTCTCN", 0, search.Options{})
+	if len(exact) != len(approximate) {
+		t.Fatalf("expected %d matches, got %d", len(exact), len(approximate))
+	}
+	for index := range exact {
+		if approximate[index].Location.Start != exact[index].Start || approximate[index].Location.Complement != exact[index].Complement {
+			t.Errorf("expected %v, got %v", exact[index], approximate[index].Location)
+		}
+	}
+
+	// mismatches on the reverse strand.
+	if reverse, _ := search.FindApproximate("CTGGTACGTC", "GACGTTCCAG", 1, search.Options{}); len(reverse) != 1 || !reverse[0].Location.Complement {
+		t.Errorf("expected a reverse strand match, got %v", reverse)
+	}
+
+	if _, err := search.FindApproximate(sequence, "GACG", -1, search.Options{}); err == nil {
+		t.Errorf("expected an error for a negative distance")
+	}
+}
+
+func TestFindApproximateIndels(t *testing.T) {
+	// the first site has an extra base and the second is missing one.
+	sequence := "ttttGACGTAACCAGttttttGACGACCAGtttt"
+	pattern := "GACGTACCAG"
+
+	if mismatches, _ := search.FindApproximate(sequence, pattern, 1, search.Options{ForwardOnly: true}); len(mismatches) != 0 {
+		t.Errorf("expected no matches without indels, got %v", mismatches)
+	}
+
+	matches, err := search.FindApproximate(sequence, pattern, 1, search.Options{ForwardOnly: true, Indels: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []search.Match{{genbank.Location{Start: 4, End: 15}, 1}, {genbank.Location{Start: 21, End: 30}, 1}}
+	if fmt.Sprint(matches) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, matches)
+	}
+
+	// an exact match is reported once rather than at every end within the distance.
+	exact, _ := search.FindApproximate("ttttGACGTACCAGtttt", pattern, 2, search.Options{ForwardOnly: true, Indels: true})
+	expected = []search.Match{{genbank.Location{Start: 4, End: 14}, 0}}
+	if fmt.Sprint(exact) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, exact)
+	}
+
+	// a match over the origin of a circular sequence.
+	circular, _ := search.FindApproximate("ACCAGttttGACGT", pattern, 1, search.Options{Circular: true, ForwardOnly: true, Indels: true})
+	expected = []search.Match{{genbank.Location{Start: 9, End: 19}, 0}}
+	if fmt.Sprint(circular) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, circular)
+	}
+
+	if _, err := search.FindApproximate(sequence, strings.Repeat("A", 65), 1, search.Options{Indels: true}); err == nil {
+		t.Errorf("expected an error for a pattern over 64 bases")
+	}
+}
@@ -0,0 +1,39 @@
+package seqhash_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/TimothyStiles/poly/io/genbank"
+	"github.com/TimothyStiles/poly/seqhash"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// TestLeastRotation is in the external test package since it reads a genbank
+// file and the genbank package imports seqhash.
+func TestLeastRotation(t *testing.T) {
+	sequence, _ := genbank.Read("../data/puc19.gbk")
+	var sequenceBuffer bytes.Buffer
+
+	sequenceBuffer.WriteString(sequence.Sequence)
+	bufferLength := sequenceBuffer.Len()
+
+	var rotatedSequence string
+	for elementIndex := 0; elementIndex < bufferLength; elementIndex++ {
+		bufferElement, _, _ := sequenceBuffer.ReadRune()
+		sequenceBuffer.WriteRune(bufferElement)
+		if elementIndex == 0 {
+			rotatedSequence = seqhash.RotateSequence(sequenceBuffer.String())
+		} else {
+			newRotatedSequence := seqhash.RotateSequence(sequenceBuffer.String())
+			if rotatedSequence != newRotatedSequence {
+				dmp := diffmatchpatch.New()
+				diffs := dmp.DiffMain(rotatedSequence, newRotatedSequence, false)
+				t.Errorf("TestLeastRotation() has failed. rotationSequence mutated.")
+				fmt.Println(dmp.DiffPrettyText(diffs))
+			}
+		}
+	}
+
+}
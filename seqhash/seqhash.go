@@ -53,6 +53,11 @@ hash of the sequence (once rotated and complemented, as stated above).
 Seqhash is a simple algorithm that allows for much better indexing of genetic sequences than what is
 currently available.
 
+Sequences read with the genbank, gff and fasta packages can be hashed with their Seqhash methods,
+which fill in the sequence type, circularity and strandedness from the file where it says what they
+are. They all go through FromSequence, so the same sequence gets the same Seqhash whichever format
+it came from.
+
 */
 package seqhash
 
@@ -66,70 +71,17 @@ import (
 	"lukechampine.com/blake3"
 )
 
-// boothLeastRotation gets the least rotation of a circular string.
-func boothLeastRotation(sequence string) int {
-
-	// https://en.wikipedia.org/wiki/Lexicographically_minimal_string_rotation
-	// this is generally over commented but I'm keeping it this way for now. - Tim
-
-	// first concatenate the sequence to itself to avoid modular arithmateic
-	sequence += sequence // maybe do this as a buffer just for speed? May get annoying with larger sequences.
-	leastRotationIndex := 0
-
-	//initializing failure slice.
-	failureSlice := make([]int, len(sequence))
-	for i := range failureSlice {
-		failureSlice[i] = -1
-	}
-	// iterate through each character in the doubled over sequence
-	for characterIndex := 1; characterIndex < len(sequence); characterIndex++ {
-		// get character
-		character := sequence[characterIndex]
-		// get failure
-		failure := failureSlice[characterIndex-leastRotationIndex-1]
-		// while failure does not equal -1 and character does not equal the character found at the least rotation + failure + 1 <- why this?
-		for failure != -1 && character != sequence[leastRotationIndex+failure+1] {
-
-			// if character is lexically less than whatever is at the leastRotationIndex index update leastRotation index
-			if character < sequence[leastRotationIndex+failure+1] {
-				leastRotationIndex = characterIndex - failure - 1
-			}
-			// update failure using previous failure as index?
-			failure = failureSlice[failure]
-		}
-
-		// if character does not equal whatever character is at leastRotationIndex plus failure.
-		if character != sequence[leastRotationIndex+failure+1] {
-
-			// if character is lexically less then what is rotated least leastRotatationIndex gets value of character index.
-			if character < sequence[leastRotationIndex] {
-				leastRotationIndex = characterIndex
-			}
-			// assign -1 to whatever is at the index of difference between character and rotation indeces.
-			failureSlice[characterIndex-leastRotationIndex] = -1
-
-			// if character does equal whatever character is at leastRotationIndex plus failure.
-		} else {
-			// assign failure + 1 at the index of difference between character and rotation indeces.
-			failureSlice[characterIndex-leastRotationIndex] = failure + 1
-		}
-	} // end loop
-
-	return leastRotationIndex
-}
+// Sequence types accepted by Hash.
+const (
+	DNA     = "DNA"
+	RNA     = "RNA"
+	PROTEIN = "PROTEIN"
+)
 
-// RotateSequence rotates circular sequences to deterministic point.
+// RotateSequence rotates circular sequences to deterministic point. It is
+// transform.RotateToCanonical, which Seqhash uses to rotate circular sequences.
 func RotateSequence(sequence string) string {
-	rotationIndex := boothLeastRotation(sequence)
-	var sequenceBuilder strings.Builder
-
-	// writing the same sequence twice. using build incase of very long circular genome.
-	sequenceBuilder.WriteString(sequence)
-	sequenceBuilder.WriteString(sequence)
-
-	concatenatedSequence := sequenceBuilder.String()
-	sequence = concatenatedSequence[rotationIndex : rotationIndex+len(sequence)]
-	return sequence
+	return transform.RotateToCanonical(sequence)
 }
 
 // Hash is a function to create Seqhashes, a specific kind of identifier.
@@ -138,22 +90,22 @@ func Hash(sequence string, sequenceType string, circular bool, doubleStranded bo
 	sequence = strings.ToUpper(sequence)
 	// If RNA, convert to a DNA sequence. The hash itself between a DNA and RNA sequence will not
 	// be different, but their Seqhash will have a different metadata string (R vs D)
-	if sequenceType == "RNA" {
+	if sequenceType == RNA {
 		sequence = strings.ReplaceAll(sequence, "U", "T")
 	}
 
 	// Run checks on the input
-	if sequenceType != "DNA" && sequenceType != "RNA" && sequenceType != "PROTEIN" {
+	if sequenceType != DNA && sequenceType != RNA && sequenceType != PROTEIN {
 		return "", errors.New("Only sequenceTypes of DNA, RNA, or PROTEIN allowed. Got sequenceType: " + sequenceType)
 	}
-	if sequenceType == "DNA" || sequenceType == "RNA" {
+	if sequenceType == DNA || sequenceType == RNA {
 		for _, char := range sequence {
 			if !strings.Contains("ATUGCYRSWKMBDHVNZ", string(char)) {
 				return "", errors.New("Only letters ATUGCYRSWKMBDHVNZ are allowed for DNA/RNA. Got letter: " + string(char))
 			}
 		}
 	}
-	if sequenceType == "PROTEIN" {
+	if sequenceType == PROTEIN {
 		for _, char := range sequence {
 			// Selenocysteine (Sec; U) and pyrrolysine (Pyl; O) are added
 			// in accordance with https://www.uniprot.org/help/sequences
@@ -166,7 +118,7 @@ func Hash(sequence string, sequenceType string, circular bool, doubleStranded bo
 		}
 	}
 	// There is no check for circular proteins since proteins can be circular
-	if sequenceType == "PROTEIN" && doubleStranded {
+	if sequenceType == PROTEIN && doubleStranded {
 		return "", errors.New("Proteins cannot be double stranded")
 	}
 
@@ -174,11 +126,11 @@ func Hash(sequence string, sequenceType string, circular bool, doubleStranded bo
 	var deterministicSequence string
 	switch {
 	case circular && doubleStranded:
-		potentialSequences := []string{RotateSequence(sequence), RotateSequence(transform.ReverseComplement(sequence))}
+		potentialSequences := []string{transform.RotateToCanonical(sequence), transform.RotateToCanonical(transform.ReverseComplement(sequence))}
 		sort.Strings(potentialSequences)
 		deterministicSequence = potentialSequences[0]
 	case circular && !doubleStranded:
-		deterministicSequence = RotateSequence(sequence)
+		deterministicSequence = transform.RotateToCanonical(sequence)
 	case !circular && doubleStranded:
 		potentialSequences := []string{sequence, transform.ReverseComplement(sequence)}
 		sort.Strings(potentialSequences)
@@ -193,11 +145,11 @@ func Hash(sequence string, sequenceType string, circular bool, doubleStranded bo
 	var doubleStrandedLetter string
 	// Get first letter. D for DNA, R for RNA, and P for Protein
 	switch sequenceType {
-	case "DNA":
+	case DNA:
 		sequenceTypeLetter = "D"
-	case "RNA":
+	case RNA:
 		sequenceTypeLetter = "R"
-	case "PROTEIN":
+	case PROTEIN:
 		sequenceTypeLetter = "P"
 	}
 	// Get 2nd letter. C for circular, L for Linear
@@ -218,3 +170,22 @@ func Hash(sequence string, sequenceType string, circular bool, doubleStranded bo
 	return seqhash, nil
 
 }
+
+// FromSequence returns the Seqhash of a sequence read from a file, filling in
+// what Hash needs to know from the molecule type the file gives it so that
+// every format hashes the same sequence the same way. moleculeType is either
+// one of DNA, RNA or PROTEIN or a GenBank molecule type like "mRNA", "ss-DNA"
+// or "ds-RNA". An empty molecule type is taken to be DNA. DNA is double
+// stranded and RNA and proteins single stranded, unless the molecule type
+// starts with "ss-" or "ds-".
+func FromSequence(sequence string, moleculeType string, circular bool) (string, error) {
+	switch {
+	case moleculeType == PROTEIN:
+		return Hash(sequence, PROTEIN, circular, false)
+	case strings.Contains(moleculeType, RNA):
+		return Hash(sequence, RNA, circular, strings.HasPrefix(moleculeType, "ds-"))
+	case moleculeType == "" || strings.Contains(moleculeType, DNA):
+		return Hash(sequence, DNA, circular, !strings.HasPrefix(moleculeType, "ss-"))
+	}
+	return "", errors.New("Only molecule types of DNA, RNA or PROTEIN allowed. Got molecule type: " + moleculeType)
+}
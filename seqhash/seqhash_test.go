@@ -1,12 +1,8 @@
 package seqhash
 
 import (
-	"bytes"
 	"fmt"
 	"testing"
-
-	"github.com/TimothyStiles/poly/io/genbank"
-	"github.com/sergi/go-diff/diffmatchpatch"
 )
 
 func TestHash(t *testing.T) {
@@ -66,28 +62,28 @@ func TestHash(t *testing.T) {
 
 }
 
-func TestLeastRotation(t *testing.T) {
-	sequence, _ := genbank.Read("../data/puc19.gbk")
-	var sequenceBuffer bytes.Buffer
-
-	sequenceBuffer.WriteString(sequence.Sequence)
-	bufferLength := sequenceBuffer.Len()
-
-	var rotatedSequence string
-	for elementIndex := 0; elementIndex < bufferLength; elementIndex++ {
-		bufferElement, _, _ := sequenceBuffer.ReadRune()
-		sequenceBuffer.WriteRune(bufferElement)
-		if elementIndex == 0 {
-			rotatedSequence = RotateSequence(sequenceBuffer.String())
-		} else {
-			newRotatedSequence := RotateSequence(sequenceBuffer.String())
-			if rotatedSequence != newRotatedSequence {
-				dmp := diffmatchpatch.New()
-				diffs := dmp.DiffMain(rotatedSequence, newRotatedSequence, false)
-				t.Errorf("TestLeastRotation() has failed. rotationSequence mutated.")
-				fmt.Println(dmp.DiffPrettyText(diffs))
-			}
+func TestFromSequence(t *testing.T) {
+	for _, test := range []struct {
+		moleculeType string
+		circular     bool
+		prefix       string
+	}{
+		{DNA, false, "v1_DLD_"},
+		{"", true, "v1_DCD_"},
+		{"ss-DNA", true, "v1_DCS_"},
+		{RNA, false, "v1_RLS_"},
+		{"mRNA", false, "v1_RLS_"},
+		{"ds-RNA", false, "v1_RLD_"},
+		{PROTEIN, false, "v1_PLS_"},
+	} {
+		seqhash, err := FromSequence("ATGAAA", test.moleculeType, test.circular)
+		if err != nil || seqhash[:len(test.prefix)] != test.prefix {
+			t.Errorf("expected a %s Seqhash for %q, got %s and %v", test.prefix, test.moleculeType, seqhash, err)
 		}
 	}
 
+	// molecule types are case sensitive like Hash's sequence types.
+	if _, err := FromSequence("ATGAAA", "dna", false); err == nil {
+		t.Errorf("expected an error for the molecule type dna")
+	}
 }
@@ -20,11 +20,17 @@ package codon
 import (
 	"errors"
 	"fmt"
+	"math"
 
 	"math/rand"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/TimothyStiles/poly/transform"
 	weightedRand "github.com/mroth/weightedrand"
 
 	"encoding/json"
@@ -81,12 +87,16 @@ type AminoAcid struct {
 
 // Table holds information for a codon table.
 type Table struct {
-	StartCodons []string    `json:"start_codons"`
-	StopCodons  []string    `json:"stop_codons"`
-	AminoAcids  []AminoAcid `json:"amino_acids"`
+	StartCodons []string       `json:"start_codons"`
+	StopCodons  []string       `json:"stop_codons"`
+	AminoAcids  []AminoAcid    `json:"amino_acids"`
+	CodonPairs  map[string]int `json:"codon_pairs,omitempty"` // counts of adjacent codon pairs, keyed by the six bases of the pair. Set by OptimizeTable.
 }
 
-// Translate translates a codon sequence to an amino acid sequence
+// Translate translates a codon sequence to an amino acid sequence. Codons may
+// contain IUPAC ambiguity codes, which are translated to the amino acid
+// encoded by every codon they could stand for, like GCN to A, or to X when
+// there isn't one, like NNN. RNA codons are translated the same as DNA.
 func Translate(sequence string, codonTable Table) (string, error) {
 	if len(codonTable.StartCodons) == 0 && len(codonTable.StopCodons) == 0 && len(codonTable.AminoAcids) == 0 {
 		return "", errEmtpyCodonTable
@@ -106,7 +116,7 @@ func Translate(sequence string, codonTable Table) (string, error) {
 
 		// if current nucleotide is the third in a codon translate to aminoAcid write to aminoAcids and reset currentCodon.
 		if currentCodon.Len() == 3 {
-			aminoAcids.WriteString(translationTable[strings.ToUpper(currentCodon.String())])
+			aminoAcids.WriteString(translateCodon(translationTable, strings.ToUpper(currentCodon.String())))
 
 			// reset codon string builder for next codon.
 			currentCodon.Reset()
@@ -115,44 +125,891 @@ func Translate(sequence string, codonTable Table) (string, error) {
 	return aminoAcids.String(), nil
 }
 
-// Optimize takes an amino acid sequence and Table and returns an optimized codon sequence. Takes an optional random seed as last argument.
-func Optimize(aminoAcids string, codonTable Table, randomState ...int) (string, error) {
+// TranslateOptions changes how TranslateWithOptions translates a sequence.
+type TranslateOptions struct {
+	// StartCodon translates the first codon as methionine when it's one of the
+	// table's start codons, the way ribosomes read alternative starts like GTG
+	// and TTG in bacteria. Without it they're translated as valine and leucine.
+	StartCodon bool
+	// StopAtStop ends the translation at the first stop codon, which is
+	// included as a trailing "*".
+	StopAtStop bool
+}
+
+// partialCodonError is returned alongside a translation when the sequence ended with an incomplete codon, which was left out.
+type partialCodonError struct {
+	Bases string
+}
+
+func (e partialCodonError) Error() string {
+	return fmt.Sprintf("sequence ends with the partial codon %q, which was not translated", e.Bases)
+}
+
+// TranslateWithOptions translates a coding sequence like Translate with
+// extra control over its start and end. Translate silently ignores bases left
+// over at the end of a sequence whose length isn't a multiple of three, while
+// TranslateWithOptions returns the translation of the whole codons alongside
+// a partialCodonError so partial CDSs can still be translated but don't go
+// unnoticed. Bases after the first stop aren't checked when StopAtStop is set.
+func TranslateWithOptions(sequence string, codonTable Table, options TranslateOptions) (string, error) {
 	if len(codonTable.StartCodons) == 0 && len(codonTable.StopCodons) == 0 && len(codonTable.AminoAcids) == 0 {
 		return "", errEmtpyCodonTable
 	}
-	if len(aminoAcids) == 0 {
-		return "", errEmtpyAminoAcidString
+	if len(sequence) == 0 {
+		return "", errEmtpySequenceString
 	}
 
-	// weightedRand library insisted setting seed like this. Not sure what environmental side effects exist.
+	sequence = strings.ToUpper(sequence)
+	translationTable := codonTable.generateTranslationTable()
+	startCodons := make(map[string]bool)
+	for _, startCodon := range codonTable.StartCodons {
+		startCodons[strings.ToUpper(startCodon)] = true
+	}
+
+	var aminoAcids strings.Builder
+	for index := 0; index+3 <= len(sequence); index += 3 {
+		codon := sequence[index : index+3]
+		aminoAcid := translateCodon(translationTable, codon)
+		if index == 0 && options.StartCodon && startCodons[codon] {
+			aminoAcid = "M"
+		}
+		aminoAcids.WriteString(aminoAcid)
+		if options.StopAtStop && aminoAcid == "*" {
+			return aminoAcids.String(), nil
+		}
+	}
+	if leftOver := len(sequence) % 3; leftOver != 0 {
+		return aminoAcids.String(), partialCodonError{sequence[len(sequence)-leftOver:]}
+	}
+	return aminoAcids.String(), nil
+}
+
+// TranslateRange translates the subsequence sequence[start:end] starting frame
+// (0, 1 or 2) bases into it. Coordinates are 0-based and end is exclusive, the
+// same as slicing a string.
+func TranslateRange(sequence string, codonTable Table, start, end, frame int) (string, error) {
+	if start < 0 || end > len(sequence) || start > end {
+		return "", fmt.Errorf("range %d to %d is out of bounds for a sequence of length %d", start, end, len(sequence))
+	}
+	if frame < 0 || frame > 2 {
+		return "", fmt.Errorf("invalid frame %d, must be 0, 1 or 2", frame)
+	}
+	if start+frame > end {
+		return "", errEmtpySequenceString
+	}
+	return Translate(sequence[start+frame:end], codonTable)
+}
+
+// TranslateChecked translates a coding sequence like Translate and also returns
+// the 0-based nucleotide positions of any internal stop codons. A stop codon in
+// the final codon is expected and is never reported, so an empty slice means the
+// sequence has no premature stops.
+func TranslateChecked(sequence string, codonTable Table) (string, []int, error) {
+	aminoAcids, err := Translate(sequence, codonTable)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var internalStops []int
+	for index := 0; index < len(aminoAcids)-1; index++ {
+		if aminoAcids[index] == '*' {
+			internalStops = append(internalStops, index*3)
+		}
+	}
+	return aminoAcids, internalStops, nil
+}
+
+// OptimizeOptions holds optional constraints for Optimize. The zero value only
+// leaves out codons used DefaultMinCodonFrequency of the time or less.
+type OptimizeOptions struct {
+	// MinGC and MaxGC are the bounds (from 0 to 1) of the GC content window
+	// the optimized sequence should stay inside of. Leave both at 0 to disable.
+	MinGC float64
+	MaxGC float64
+
+	// GCWindow is the length of the windows checked against MinWindowGC and
+	// MaxWindowGC, which bound the GC content of every stretch of that many
+	// bases of the optimized sequence, like the 40% to 60% in any 50 bases
+	// that many synthesis vendors ask for. Leave at 0 to disable. Sequences
+	// shorter than GCWindow are only held to MinGC and MaxGC.
+	GCWindow    int
+	MinWindowGC float64
+	MaxWindowGC float64
+
+	// MinCodonFrequency excludes synonymous codons used this fraction (from 0
+	// to 1) of the time for their amino acid or less, so that rare codons which
+	// slow down translation are never picked. Leave at 0 to use
+	// DefaultMinCodonFrequency. If no codon for an amino acid passes the
+	// threshold its most frequent codon is always used.
+	MinCodonFrequency float64
+
+	// DisableMinCodonFrequency allows every codon the table uses, no matter
+	// how rarely, and ignores MinCodonFrequency.
+	DisableMinCodonFrequency bool
+
+	// CodonPairs chooses codons by how well they pair with their neighbours
+	// instead of one at a time. It needs a table weighted by OptimizeTable and
+	// can't be combined with a GC content window. The result doesn't depend on
+	// the random seed.
+	CodonPairs CodonPairMode
+
+	// Strategy is how codons are picked from the synonymous codons allowed for
+	// each residue. The default picks them at random, so use one of the
+	// deterministic strategies to get the same sequence every time without a
+	// seed. It can't be combined with CodonPairs.
+	Strategy OptimizeStrategy
+
+	// ForbiddenMotifs are sequences the optimized sequence mustn't contain on
+	// either strand, like the restriction sites of the enzymes it will be
+	// cloned with or homopolymer runs such as "AAAAAAAA" that are hard to
+	// synthesize. Codons overlapping a forbidden motif are swapped for
+	// synonymous ones, one at a time, until none are left. Motifs that can't be
+	// removed that way are listed in a forbiddenMotifError returned alongside
+	// the sequence.
+	ForbiddenMotifs []string
+}
+
+// CodonPairMode is how Optimize treats codon pair bias.
+type CodonPairMode int
+
+const (
+	// CodonPairsIgnored picks every codon independently of its neighbours.
+	CodonPairsIgnored CodonPairMode = iota
+	// CodonPairsOptimized picks codons so the codon pair score of the sequence
+	// is as high as possible, avoiding pairs the organism rarely uses.
+	CodonPairsOptimized
+	// CodonPairsDeoptimized picks codons so the codon pair score is as low as
+	// possible. This is how codon pair deoptimized (attenuated) viruses are
+	// designed, since the protein is unchanged but expressed poorly.
+	CodonPairsDeoptimized
+)
+
+// OptimizeStrategy is how Optimize picks each codon.
+type OptimizeStrategy int
+
+const (
+	// StrategyWeightedRandom picks codons at random, weighted by how often the
+	// organism uses them. This is what Optimize does.
+	StrategyWeightedRandom OptimizeStrategy = iota
+	// StrategyMostFrequent always picks the codon the organism uses most for
+	// each amino acid, the "one amino acid, one codon" method many other codon
+	// optimization tools use.
+	StrategyMostFrequent
+	// StrategyCyclicWeighted picks codons in turn so that each amino acid's
+	// codons are used in the same proportions as the organism uses them,
+	// starting with the most used. A protein with ten alanines and a table
+	// using GCG 60% and GCC 40% of the time gets six GCGs and four GCCs, every
+	// time.
+	StrategyCyclicWeighted
+)
+
+// DefaultMinCodonFrequency is the MinCodonFrequency used by Optimize.
+const DefaultMinCodonFrequency = 0.10
+
+// gcContentError is returned when an optimized sequence could not be kept inside of the requested GC content window.
+type gcContentError struct {
+	GCContent float64
+	MinGC     float64
+	MaxGC     float64
+}
+
+func (e gcContentError) Error() string {
+	return fmt.Sprintf("optimized sequence has a GC content of %.3f which is outside of the requested window of %.3f to %.3f", e.GCContent, e.MinGC, e.MaxGC)
+}
+
+// windowGCContentError is returned when a window of an optimized sequence could not be kept inside of the requested window GC content range.
+type windowGCContentError struct {
+	Start     int // where the first window outside of the range starts.
+	GCContent float64
+	MinGC     float64
+	MaxGC     float64
+}
+
+func (e windowGCContentError) Error() string {
+	return fmt.Sprintf("optimized sequence has a GC content of %.3f in the window starting at %d which is outside of the requested range of %.3f to %.3f", e.GCContent, e.Start, e.MinGC, e.MaxGC)
+}
+
+// forbiddenMotifError is returned when forbidden motifs could not be removed from an optimized sequence.
+type forbiddenMotifError struct {
+	Motifs    []string // the forbidden motif at each position.
+	Positions []int    // where each forbidden motif starts in the optimized sequence.
+}
+
+func (e forbiddenMotifError) Error() string {
+	sites := make([]string, len(e.Motifs))
+	for index, motif := range e.Motifs {
+		sites[index] = fmt.Sprintf("%s at %d", motif, e.Positions[index])
+	}
+	return fmt.Sprintf("optimized sequence still contains forbidden motifs: %s", strings.Join(sites, ", "))
+}
+
+// Optimize takes an amino acid sequence, Table and OptimizeOptions and returns
+// an optimized codon sequence. Takes an optional random seed as last argument.
+//
+// When a GC content window is given the GC content of the sequence built so far
+// is tracked and, for every residue, synonymous codons that keep the running GC
+// content inside of the window are preferred. If no codon for a residue can do
+// that the codons that move the GC content closest to the window are used
+// instead. Later residues can usually make up for this, but if the finished
+// sequence still falls outside of the window it is returned alongside a
+// gcContentError so the caller can decide what to do with it. Windowed GC
+// content is handled the same way, preferring codons that keep the window
+// ending with them inside of the range, after which codons inside of windows
+// that are still outside of the range are swapped for synonymous ones that
+// fix them. A windowGCContentError is returned if that isn't enough.
+func Optimize(aminoAcids string, codonTable Table, options OptimizeOptions, randomState ...int) (string, error) {
+	optimizer, err := newOptimizer(codonTable, options)
+	if err != nil {
+		return "", err
+	}
+
+	// each call gets its own random source so that seeded calls are reproducible even when run concurrently.
+	var seed int64
+	if len(randomState) > 0 {
+		seed = int64(randomState[0])
+	} else {
+		seed = time.Now().UTC().UnixNano()
+	}
+	return optimizer.optimize(aminoAcids, rand.New(rand.NewSource(seed)))
+}
+
+// OptimizeBatch optimizes many amino acid sequences against the same Table
+// and OptimizeOptions, spreading the work over up to GOMAXPROCS goroutines.
+// The returned sequences and errors are in the same order as aminoAcidSeqs and
+// errors[i] is nil unless optimizing aminoAcidSeqs[i] failed.
+//
+// If a random seed is given the sequence at index i is optimized with the seed
+// plus i, so every result is reproducible no matter how the work is scheduled
+// and is the same as calling Optimize with that seed.
+func OptimizeBatch(aminoAcidSeqs []string, codonTable Table, options OptimizeOptions, randomState ...int) ([]string, []error) {
+	sequences := make([]string, len(aminoAcidSeqs))
+	errs := make([]error, len(aminoAcidSeqs))
+	optimizer, err := newOptimizer(codonTable, options)
+	if err != nil {
+		for index := range errs {
+			errs[index] = err
+		}
+		return sequences, errs
+	}
+
+	var seed int64
 	if len(randomState) > 0 {
-		rand.Seed(int64(randomState[0]))
+		seed = int64(randomState[0])
 	} else {
-		rand.Seed(time.Now().UTC().UnixNano())
+		seed = time.Now().UTC().UnixNano()
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(aminoAcidSeqs) {
+		workers = len(aminoAcidSeqs)
+	}
+	jobs := make(chan int)
+	var waitGroup sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for index := range jobs {
+				random := rand.New(rand.NewSource(seed + int64(index)))
+				sequences[index], errs[index] = optimizer.optimize(aminoAcidSeqs[index], random)
+			}
+		}()
+	}
+	for index := range aminoAcidSeqs {
+		jobs <- index
+	}
+	close(jobs)
+	waitGroup.Wait()
+	return sequences, errs
+}
+
+// optimizer holds everything about a codon table and set of options that can
+// be worked out once and then shared by many calls to optimize.
+type optimizer struct {
+	options       OptimizeOptions
+	gcConstrained bool // whether there is a global or windowed GC content constraint.
+	codonChoices  map[string][]weightedRand.Choice
+	codonChooser  map[string]weightedRand.Chooser
+	pairScorer    codonPairScorer
+	forbidden     []forbiddenPattern
+	maxForbidden  int // length of the longest forbidden motif.
+}
+
+// newOptimizer checks a codon table and options and builds the codon choosers for them.
+func newOptimizer(codonTable Table, options OptimizeOptions) (optimizer, error) {
+	if len(codonTable.StartCodons) == 0 && len(codonTable.StopCodons) == 0 && len(codonTable.AminoAcids) == 0 {
+		return optimizer{}, errEmtpyCodonTable
+	}
+	if options.MinGC < 0 || options.MaxGC > 1 || options.MinGC > options.MaxGC {
+		return optimizer{}, fmt.Errorf("invalid GC content window of %.3f to %.3f", options.MinGC, options.MaxGC)
+	}
+	if options.GCWindow < 0 {
+		return optimizer{}, fmt.Errorf("invalid GC window length of %d", options.GCWindow)
+	}
+	if options.GCWindow > 0 && (options.MinWindowGC < 0 || options.MaxWindowGC > 1 || options.MaxWindowGC == 0 || options.MinWindowGC > options.MaxWindowGC) {
+		return optimizer{}, fmt.Errorf("invalid window GC content range of %.3f to %.3f", options.MinWindowGC, options.MaxWindowGC)
+	}
+	if options.MinCodonFrequency < 0 || options.MinCodonFrequency > 1 {
+		return optimizer{}, fmt.Errorf("invalid minimum codon frequency of %.3f", options.MinCodonFrequency)
+	}
+	minCodonFrequency := options.MinCodonFrequency
+	if options.DisableMinCodonFrequency {
+		minCodonFrequency = 0
+	} else if minCodonFrequency == 0 {
+		minCodonFrequency = DefaultMinCodonFrequency
+	}
+
+	if options.Strategy < StrategyWeightedRandom || options.Strategy > StrategyCyclicWeighted {
+		return optimizer{}, fmt.Errorf("invalid optimization strategy %d", options.Strategy)
+	}
+
+	gcConstrained := options.MinGC != 0 || options.MaxGC != 0 || options.GCWindow > 0
+	var pairScorer codonPairScorer
+	if options.CodonPairs != CodonPairsIgnored {
+		if gcConstrained {
+			return optimizer{}, errors.New("codon pair optimization can't be combined with a GC content window")
+		}
+		if options.Strategy != StrategyWeightedRandom {
+			return optimizer{}, errors.New("codon pair optimization can't be combined with an optimization strategy")
+		}
+		var err error
+		if pairScorer, err = newCodonPairScorer(codonTable); err != nil {
+			return optimizer{}, err
+		}
+	}
+
+	var forbidden []forbiddenPattern
+	seen := make(map[string]bool)
+	maxForbidden := 0
+	for _, motif := range options.ForbiddenMotifs {
+		if motif == "" {
+			return optimizer{}, errors.New("empty forbidden motif")
+		}
+		motif = strings.ToUpper(motif)
+		for _, pattern := range []string{motif, transform.ReverseComplement(motif)} {
+			if !seen[pattern] {
+				seen[pattern] = true
+				forbidden = append(forbidden, forbiddenPattern{pattern, motif})
+			}
+		}
+		if len(motif) > maxForbidden {
+			maxForbidden = len(motif)
+		}
 	}
 
-	var codons strings.Builder
-	codonChooser, err := codonTable.chooser()
+	codonChoices := codonTable.codonChoices(minCodonFrequency)
+	codonChooser, err := chooser(codonChoices)
+	if err != nil {
+		return optimizer{}, err
+	}
+	return optimizer{
+		options:       options,
+		gcConstrained: gcConstrained,
+		codonChoices:  codonChoices,
+		codonChooser:  codonChooser,
+		pairScorer:    pairScorer,
+		forbidden:     forbidden,
+		maxForbidden:  maxForbidden,
+	}, nil
+}
+
+// optimize picks codons for an amino acid sequence. It only reads from the
+// optimizer so it's safe to call concurrently as long as every call has its
+// own random source.
+func (optimizer optimizer) optimize(aminoAcids string, random *rand.Rand) (string, error) {
+	if len(aminoAcids) == 0 {
+		return "", errEmtpyAminoAcidString
+	}
+
+	var codons []string
+	var err error
+	if optimizer.options.CodonPairs != CodonPairsIgnored {
+		codons, err = optimizer.optimizePairs(aminoAcids)
+	} else {
+		codons, err = optimizer.pickCodons(aminoAcids, random)
+	}
 	if err != nil {
 		return "", err
 	}
 
+	if optimizer.options.GCWindow > 0 {
+		optimizer.balanceGCWindows(aminoAcids, codons)
+	}
+	var unavoidable error
+	if len(optimizer.forbidden) > 0 {
+		unavoidable = optimizer.removeForbiddenMotifs(aminoAcids, codons)
+	}
+	sequence := strings.Join(codons, "")
+	options := optimizer.options
+	if optimizer.gcOutside(countGC(sequence), len(sequence)) {
+		gcContent := float64(countGC(sequence)) / float64(len(sequence))
+		return sequence, gcContentError{gcContent, options.MinGC, options.MaxGC}
+	}
+	if options.GCWindow > 0 {
+		if start, gcContent := gcWindowOutside([]byte(sequence), 0, len(sequence), options); start >= 0 {
+			return sequence, windowGCContentError{start, gcContent, options.MinWindowGC, options.MaxWindowGC}
+		}
+	}
+	return sequence, unavoidable
+}
+
+// pickCodons picks a codon for every residue of an amino acid sequence with the options' strategy.
+func (optimizer optimizer) pickCodons(aminoAcids string, random *rand.Rand) ([]string, error) {
+	var codons []string
+	var sequence []byte
+	gcCount := 0
+	used := make(map[string]int)   // how many times each codon has been picked.
+	residues := make(map[rune]int) // how many times each amino acid has come up.
 	for _, aminoAcid := range aminoAcids {
-		chooser, ok := codonChooser[string(aminoAcid)]
+		chooser, ok := optimizer.codonChooser[string(aminoAcid)]
+		if !ok {
+			return nil, invalidAminoAcidError{aminoAcid}
+		}
+		residues[aminoAcid]++
+
+		allowed := optimizer.codonChoices[string(aminoAcid)]
+		choices := allowed
+		if optimizer.gcConstrained {
+			choices = gcChoices(choices, sequence, gcCount, optimizer.options)
+		}
+		var codon string
+		switch optimizer.options.Strategy {
+		case StrategyMostFrequent:
+			codon = mostFrequentCodon(choices)
+		case StrategyCyclicWeighted:
+			codon = cyclicWeightedCodon(allowed, choices, residues[aminoAcid], used)
+		default:
+			if !optimizer.gcConstrained {
+				codon = chooser.PickSource(random).(string)
+				break
+			}
+			gcChooser, err := weightedRand.NewChooser(choices...)
+			if err != nil {
+				return nil, fmt.Errorf("weightedRand.NewChooser() error: %s", err)
+			}
+			codon = gcChooser.PickSource(random).(string)
+		}
+		used[codon]++
+		gcCount += countGC(codon)
+		sequence = append(sequence, codon...)
+		codons = append(codons, codon)
+	}
+	return codons, nil
+}
+
+// mostFrequentCodon returns the codon with the highest weight, the first of them if there's a tie.
+func mostFrequentCodon(choices []weightedRand.Choice) string {
+	var best weightedRand.Choice
+	for _, choice := range choices {
+		if _, ok := choice.Item.(string); ok && (best.Item == nil || choice.Weight > best.Weight) {
+			best = choice
+		}
+	}
+	return best.Item.(string)
+}
+
+// cyclicWeightedCodon returns the codon from choices that has fallen furthest
+// behind its share of allowed, the codons allowed for an amino acid that has
+// come up count times, given how many times each codon has been used. Ties go
+// to the codon with the highest weight.
+func cyclicWeightedCodon(allowed, choices []weightedRand.Choice, count int, used map[string]int) string {
+	total := 0
+	for _, choice := range allowed {
+		total += int(choice.Weight)
+	}
+	var best weightedRand.Choice
+	bestDeficit := math.Inf(-1)
+	for _, choice := range choices {
+		triplet, ok := choice.Item.(string)
 		if !ok {
-			return "", invalidAminoAcidError{aminoAcid}
+			continue
+		}
+		// deficit is how many more times the codon should have been used by now.
+		deficit := float64(choice.Weight)*float64(count)/float64(total) - float64(used[triplet])
+		if deficit > bestDeficit+1e-9 || (deficit > bestDeficit-1e-9 && choice.Weight > best.Weight) {
+			best, bestDeficit = choice, deficit
+		}
+	}
+	return best.Item.(string)
+}
+
+// forbiddenPattern is a sequence that mustn't be in an optimized sequence: a forbidden motif or its reverse complement.
+type forbiddenPattern struct {
+	pattern string
+	motif   string // the forbidden motif the pattern came from.
+}
+
+// forbiddenSite is where a forbidden motif, or its reverse complement, was found.
+type forbiddenSite struct {
+	position int
+	motif    string
+}
+
+// removeForbiddenMotifs swaps codons for synonymous ones until no forbidden
+// motifs are left. Each swap is the one that removes the most forbidden sites
+// overlapping its codon, preferring more frequently used codons, so every swap
+// leaves fewer sites than before. Swaps that would take the GC content, or a
+// window of it, outside of the options' ranges aren't made. Sites left when no swap helps are
+// returned in a forbiddenMotifError.
+func (optimizer optimizer) removeForbiddenMotifs(aminoAcids string, codons []string) error {
+	residues := []rune(aminoAcids)
+	sequence := []byte(strings.Join(codons, ""))
+	for {
+		sites := optimizer.forbiddenSites(sequence, 0, len(sequence))
+		if len(sites) == 0 {
+			return nil
+		}
+		swapped := false
+		for _, site := range sites {
+			if optimizer.swapCodon(residues, codons, sequence, site) {
+				swapped = true
+				break
+			}
+		}
+		if !swapped {
+			var unavoidable forbiddenMotifError
+			for _, site := range sites {
+				unavoidable.Motifs = append(unavoidable.Motifs, site.motif)
+				unavoidable.Positions = append(unavoidable.Positions, site.position)
+			}
+			return unavoidable
+		}
+	}
+}
+
+// swapCodon makes the best swap of a codon overlapping a forbidden site,
+// updating both codons and sequence, and returns whether there was one that
+// removed more sites than it created.
+func (optimizer optimizer) swapCodon(residues []rune, codons []string, sequence []byte, site forbiddenSite) bool {
+	bestResidue, bestCodon, bestRemoved, bestWeight := -1, "", 0, uint(0)
+	gcCount := countGC(string(sequence))
+	last := (site.position + len(site.motif) - 1) / 3
+	for residue := site.position / 3; residue <= last; residue++ {
+		start, end := 3*residue, 3*residue+3
+		current := codons[residue]
+		before := len(optimizer.forbiddenSites(sequence, start, end))
+		windowsBefore := windowGCDistance(sequence, start, end, optimizer.options)
+		for _, choice := range optimizer.codonChoices[string(residues[residue])] {
+			triplet, ok := choice.Item.(string)
+			if !ok || choice.Weight == 0 || triplet == current {
+				continue
+			}
+			if swappedGCCount := gcCount - countGC(current) + countGC(triplet); optimizer.gcOutside(swappedGCCount, len(sequence)) && !optimizer.gcOutside(gcCount, len(sequence)) {
+				continue
+			}
+			copy(sequence[start:end], triplet)
+			if windowGCDistance(sequence, start, end, optimizer.options) > windowsBefore {
+				continue
+			}
+			removed := before - len(optimizer.forbiddenSites(sequence, start, end))
+			if removed > bestRemoved || (removed == bestRemoved && removed > 0 && choice.Weight > bestWeight) {
+				bestResidue, bestCodon, bestRemoved, bestWeight = residue, triplet, removed, choice.Weight
+			}
+		}
+		copy(sequence[start:end], current)
+	}
+	if bestResidue < 0 {
+		return false
+	}
+	codons[bestResidue] = bestCodon
+	copy(sequence[3*bestResidue:3*bestResidue+3], bestCodon)
+	return true
+}
+
+// balanceGCWindows swaps codons for synonymous ones to bring windows of the
+// sequence back inside of the options' window GC content range. Each swap is
+// the one inside of a window outside of the range that brings the windows it
+// overlaps closest to the range, without creating forbidden sites or taking
+// the global GC content outside of its range. Windows that can't be fixed that
+// way are left for optimize to report.
+func (optimizer optimizer) balanceGCWindows(aminoAcids string, codons []string) {
+	residues := []rune(aminoAcids)
+	sequence := []byte(strings.Join(codons, ""))
+	window := optimizer.options.GCWindow
+	for {
+		gcCount := countGC(string(sequence))
+		bestResidue, bestCodon, bestImprovement, bestWeight := -1, "", 0.0, uint(0)
+		for windowStart := 0; windowStart+window <= len(sequence) && bestResidue < 0; windowStart++ {
+			gcContent := float64(countGC(string(sequence[windowStart:windowStart+window]))) / float64(window)
+			if gcContent >= optimizer.options.MinWindowGC && gcContent <= optimizer.options.MaxWindowGC {
+				continue
+			}
+			for residue := windowStart / 3; residue <= (windowStart+window-1)/3; residue++ {
+				start, end := 3*residue, 3*residue+3
+				current := codons[residue]
+				before := windowGCDistance(sequence, start, end, optimizer.options)
+				sitesBefore := len(optimizer.forbiddenSites(sequence, start, end))
+				for _, choice := range optimizer.codonChoices[string(residues[residue])] {
+					triplet, ok := choice.Item.(string)
+					if !ok || choice.Weight == 0 || triplet == current {
+						continue
+					}
+					if swappedGCCount := gcCount - countGC(current) + countGC(triplet); optimizer.gcOutside(swappedGCCount, len(sequence)) && !optimizer.gcOutside(gcCount, len(sequence)) {
+						continue
+					}
+					copy(sequence[start:end], triplet)
+					if len(optimizer.forbiddenSites(sequence, start, end)) > sitesBefore {
+						continue
+					}
+					improvement := before - windowGCDistance(sequence, start, end, optimizer.options)
+					if improvement > bestImprovement+1e-9 || (improvement > 1e-9 && math.Abs(improvement-bestImprovement) <= 1e-9 && choice.Weight > bestWeight) {
+						bestResidue, bestCodon, bestImprovement, bestWeight = residue, triplet, improvement, choice.Weight
+					}
+				}
+				copy(sequence[start:end], current)
+			}
+		}
+		if bestResidue < 0 {
+			return
+		}
+		codons[bestResidue] = bestCodon
+		copy(sequence[3*bestResidue:3*bestResidue+3], bestCodon)
+	}
+}
+
+// forbiddenSites returns the forbidden sites overlapping sequence[start:end], from left to right.
+func (optimizer optimizer) forbiddenSites(sequence []byte, start, end int) []forbiddenSite {
+	var sites []forbiddenSite
+	first := start - optimizer.maxForbidden + 1
+	if first < 0 {
+		first = 0
+	}
+	for position := first; position < end; position++ {
+		for _, forbidden := range optimizer.forbidden {
+			pattern := forbidden.pattern
+			if position+len(pattern) > start && position+len(pattern) <= len(sequence) && string(sequence[position:position+len(pattern)]) == pattern {
+				sites = append(sites, forbiddenSite{position, forbidden.motif})
+			}
+		}
+	}
+	return sites
+}
+
+// optimizePairs picks the codons for an amino acid sequence with the best (or
+// worst, when deoptimizing) total codon pair score. Every codon only pairs with
+// its neighbours so the best choice can be found exactly, one residue at a
+// time, by remembering the best way of reaching each codon of the last residue.
+func (optimizer optimizer) optimizePairs(aminoAcids string) ([]string, error) {
+	direction := 1.0
+	if optimizer.options.CodonPairs == CodonPairsDeoptimized {
+		direction = -1
+	}
+
+	var residueCodons [][]string // the codons that may be used for each residue.
+	var backtrack [][]int        // backtrack[i][j] is the codon of residue i-1 on the best path to codon j of residue i.
+	var scores []float64         // best total score of a path ending in each codon of the last residue.
+	for _, aminoAcid := range aminoAcids {
+		var codons []string
+		for _, choice := range optimizer.codonChoices[string(aminoAcid)] {
+			if triplet, ok := choice.Item.(string); ok && choice.Weight > 0 {
+				codons = append(codons, triplet)
+			}
+		}
+		if len(codons) == 0 {
+			return nil, invalidAminoAcidError{aminoAcid}
+		}
+
+		nextScores := make([]float64, len(codons))
+		previous := make([]int, len(codons))
+		if len(residueCodons) > 0 {
+			lastCodons := residueCodons[len(residueCodons)-1]
+			for codonIndex, codon := range codons {
+				nextScores[codonIndex] = math.Inf(-1)
+				for lastIndex, lastCodon := range lastCodons {
+					// pairs the table has no data for are neutral.
+					pairScore, _ := optimizer.pairScorer.score(lastCodon, codon)
+					if score := scores[lastIndex] + direction*pairScore; score > nextScores[codonIndex] {
+						nextScores[codonIndex] = score
+						previous[codonIndex] = lastIndex
+					}
+				}
+			}
+		}
+		residueCodons = append(residueCodons, codons)
+		backtrack = append(backtrack, previous)
+		scores = nextScores
+	}
+
+	best := 0
+	for codonIndex, score := range scores {
+		if score > scores[best] {
+			best = codonIndex
+		}
+	}
+	chosen := make([]string, len(residueCodons))
+	for residue := len(residueCodons) - 1; residue >= 0; residue-- {
+		chosen[residue] = residueCodons[residue][best]
+		best = backtrack[residue][best]
+	}
+	return chosen, nil
+}
+
+// gcChoices returns the codons from choices that keep the running GC content
+// and the GC content of the window ending with the codon inside of the
+// options' ranges if they follow sequence, or those that come closest if none
+// do.
+func gcChoices(choices []weightedRand.Choice, sequence []byte, gcCount int, options OptimizeOptions) []weightedRand.Choice {
+	var acceptable []weightedRand.Choice
+	var closest []weightedRand.Choice
+	closestDistance := math.Inf(1)
+
+	for _, choice := range choices {
+		triplet, ok := choice.Item.(string)
+		if !ok || choice.Weight == 0 {
+			continue
+		}
+		// distance is how far outside of the ranges this codon would leave the running and windowed GC content.
+		distance := 0.0
+		if options.MinGC != 0 || options.MaxGC != 0 {
+			gcContent := float64(gcCount+countGC(triplet)) / float64(len(sequence)+len(triplet))
+			distance = math.Max(options.MinGC-gcContent, gcContent-options.MaxGC)
+		}
+		if length := len(sequence) + len(triplet); options.GCWindow > 0 && length >= options.GCWindow {
+			windowGCContent := float64(countGC(string(sequence[length-options.GCWindow:]))+countGC(triplet)) / float64(options.GCWindow)
+			distance = math.Max(distance, math.Max(options.MinWindowGC-windowGCContent, windowGCContent-options.MaxWindowGC))
+		}
+		if distance <= 0 {
+			acceptable = append(acceptable, choice)
+			continue
+		}
+		if distance < closestDistance {
+			closestDistance = distance
+			closest = closest[:0]
+		}
+		if distance == closestDistance {
+			closest = append(closest, choice)
 		}
-		codons.WriteString(chooser.Pick().(string))
 	}
-	return codons.String(), nil
+
+	if len(acceptable) == 0 {
+		return closest
+	}
+	return acceptable
+}
+
+// gcOutside returns whether gcCount G and C bases in a sequence of length
+// bases is outside of the options' global GC content range, if there is one.
+func (optimizer optimizer) gcOutside(gcCount, length int) bool {
+	options := optimizer.options
+	if options.MinGC == 0 && options.MaxGC == 0 {
+		return false
+	}
+	gcContent := float64(gcCount) / float64(length)
+	return gcContent < options.MinGC || gcContent > options.MaxGC
+}
+
+// windowGCDistance returns how far outside of the options' window GC content
+// range the windows overlapping sequence[start:end] are, added up, or 0 if
+// there is no window range.
+func windowGCDistance(sequence []byte, start, end int, options OptimizeOptions) float64 {
+	window := options.GCWindow
+	if window == 0 {
+		return 0
+	}
+	first, last := start-window+1, end-1
+	if first < 0 {
+		first = 0
+	}
+	if last > len(sequence)-window {
+		last = len(sequence) - window
+	}
+	if first > last {
+		return 0
+	}
+	distance := 0.0
+	gcCount := countGC(string(sequence[first : first+window]))
+	for windowStart := first; ; windowStart++ {
+		gcContent := float64(gcCount) / float64(window)
+		distance += math.Max(0, math.Max(options.MinWindowGC-gcContent, gcContent-options.MaxWindowGC))
+		if windowStart == last {
+			return distance
+		}
+		gcCount += countGC(string(sequence[windowStart+window])) - countGC(string(sequence[windowStart]))
+	}
+}
+
+// gcWindowOutside returns the start and GC content of the first window of
+// options.GCWindow bases overlapping sequence[start:end] with a GC content
+// outside of the options' window range, or -1 if there isn't one.
+func gcWindowOutside(sequence []byte, start, end int, options OptimizeOptions) (int, float64) {
+	window := options.GCWindow
+	first, last := start-window+1, end-1
+	if first < 0 {
+		first = 0
+	}
+	if last > len(sequence)-window {
+		last = len(sequence) - window
+	}
+	if first > last {
+		return -1, 0
+	}
+	gcCount := countGC(string(sequence[first : first+window]))
+	for windowStart := first; ; windowStart++ {
+		gcContent := float64(gcCount) / float64(window)
+		if gcContent < options.MinWindowGC || gcContent > options.MaxWindowGC {
+			return windowStart, gcContent
+		}
+		if windowStart == last {
+			return -1, 0
+		}
+		gcCount += countGC(string(sequence[windowStart+window])) - countGC(string(sequence[windowStart]))
+	}
+}
+
+// countGC returns the number of G and C bases in a sequence.
+func countGC(sequence string) int {
+	count := 0
+	for _, base := range sequence {
+		switch base {
+		case 'G', 'C', 'g', 'c', 'S', 's':
+			count++
+		}
+	}
+	return count
 }
 
 // OptimizeTable weights each codon in a codon table according to input string codon frequency.
+// It also counts the adjacent codon pairs of the input for CodonPairScore, so
+// to weight a table with several genes use OptimizeTableFromSequences rather
+// than joining them, which would count pairs across the joins.
 // This function actually mutates the Table struct itself.
 func (codonTable Table) OptimizeTable(sequence string) Table {
-
 	sequence = strings.ToUpper(sequence)
-	codonFrequencyMap := getCodonFrequency(sequence)
+	return codonTable.weightCodons(getCodonFrequency(sequence), getCodonPairFrequency(sequence))
+}
+
+// OptimizeTableFromSequences weights a codon table using several coding
+// sequences, such as every CDS of a genome, in one go. Codons are counted as
+// if the sequences were joined and passed to OptimizeTable, but codon pairs are
+// only counted within each sequence so that the stop codon of one gene and the
+// start codon of the next aren't counted as a pair. Sequences whose length
+// isn't a multiple of three are skipped since their codons can't be read in
+// frame. The indices of skipped sequences are returned alongside the table.
+// Like OptimizeTable this mutates the Table.
+func (codonTable Table) OptimizeTableFromSequences(sequences []string) (Table, []int) {
+	codonFrequencyMap := map[string]int{}
+	codonPairFrequencyMap := map[string]int{}
+	var skipped []int
+	for index, sequence := range sequences {
+		if len(sequence)%3 != 0 {
+			skipped = append(skipped, index)
+			continue
+		}
+		sequence = strings.ToUpper(sequence)
+		for codon, count := range getCodonFrequency(sequence) {
+			codonFrequencyMap[codon] += count
+		}
+		for pair, count := range getCodonPairFrequency(sequence) {
+			codonPairFrequencyMap[pair] += count
+		}
+	}
+	return codonTable.weightCodons(codonFrequencyMap, codonPairFrequencyMap), skipped
+}
+
+// weightCodons sets the weight of each codon in a codon table to its count and
+// the table's codon pairs to the given pair counts.
+func (codonTable Table) weightCodons(codonFrequencyMap, codonPairFrequencyMap map[string]int) Table {
+	codonTable.CodonPairs = codonPairFrequencyMap
 
 	for aminoAcidIndex, aminoAcid := range codonTable.AminoAcids {
 		// apply weights to codonTable
@@ -164,6 +1021,331 @@ func (codonTable Table) OptimizeTable(sequence string) Table {
 	return codonTable
 }
 
+// CodonStat holds usage statistics for a single codon in a Table.
+type CodonStat struct {
+	Count    int     `json:"count"`    // raw number of times the codon was observed.
+	Fraction float64 `json:"fraction"` // share of its amino acid's codons that use this codon.
+	RSCU     float64 `json:"rscu"`     // relative synonymous codon usage. 1.0 means no bias.
+}
+
+// Stats returns usage statistics for every codon in the table, keyed by triplet.
+// After OptimizeTable the codon weights are the observed counts, so this is where
+// you get raw counts, within amino acid fractions and RSCU values from.
+//
+// RSCU is the observed count of a codon divided by the count expected if all
+// synonymous codons for its amino acid were used equally (Sharp & Li, 1986).
+func (codonTable Table) Stats() map[string]CodonStat {
+	stats := make(map[string]CodonStat)
+	for _, aminoAcid := range codonTable.AminoAcids {
+		aminoAcidCount := 0
+		for _, codon := range aminoAcid.Codons {
+			aminoAcidCount += codon.Weight
+		}
+
+		for _, codon := range aminoAcid.Codons {
+			stat := CodonStat{Count: codon.Weight}
+			if aminoAcidCount > 0 {
+				stat.Fraction = float64(codon.Weight) / float64(aminoAcidCount)
+				stat.RSCU = stat.Fraction * float64(len(aminoAcid.Codons))
+			}
+			stats[codon.Triplet] = stat
+		}
+	}
+	return stats
+}
+
+// RelativeAdaptiveness returns the relative adaptiveness of every codon in the
+// table, keyed by triplet: its weight divided by the weight of the most used
+// codon for the same amino acid, so the organism's preferred codons have a
+// relative adaptiveness of 1. Codons with a weight of 0 are given half a count
+// instead, as Sharp & Li suggest, so that a single unobserved codon doesn't
+// make the CAI of a whole sequence 0. Amino acids with no weighted codons are
+// left out.
+func (codonTable Table) RelativeAdaptiveness() map[string]float64 {
+	adaptiveness := make(map[string]float64)
+	for _, aminoAcid := range codonTable.AminoAcids {
+		maxWeight := 0
+		for _, codon := range aminoAcid.Codons {
+			if codon.Weight > maxWeight {
+				maxWeight = codon.Weight
+			}
+		}
+		if maxWeight == 0 {
+			continue
+		}
+		for _, codon := range aminoAcid.Codons {
+			weight := float64(codon.Weight)
+			if weight == 0 {
+				weight = 0.5
+			}
+			adaptiveness[strings.ToUpper(codon.Triplet)] = weight / float64(maxWeight)
+		}
+	}
+	return adaptiveness
+}
+
+// CAI returns the codon adaptation index of a coding sequence against a table
+// weighted by OptimizeTable [Sharp PM & Li WH (1987) Nucleic Acids Res,
+// doi:10.1093/nar/15.3.1281]: the geometric mean of the relative adaptiveness
+// of its codons. It goes from 0 to 1, where 1 means every codon is the one the
+// organism uses most for its amino acid, and is a quick measure of how well
+// suited a gene is to being expressed in that organism.
+//
+// Stop codons and the codons of amino acids with only one codon, like ATG and
+// TGG in the standard code, are skipped since they tell you nothing about
+// codon choice.
+func CAI(sequence string, codonTable Table) (float64, error) {
+	if len(sequence) == 0 {
+		return 0, errEmtpySequenceString
+	}
+	if len(sequence)%3 != 0 {
+		return 0, fmt.Errorf("sequence of length %d is not a whole number of codons", len(sequence))
+	}
+
+	adaptiveness := codonTable.RelativeAdaptiveness()
+	skipped := make(map[string]bool)
+	for _, aminoAcid := range codonTable.AminoAcids {
+		if len(aminoAcid.Codons) == 1 || aminoAcid.Letter == "*" {
+			for _, codon := range aminoAcid.Codons {
+				skipped[strings.ToUpper(codon.Triplet)] = true
+			}
+		}
+	}
+	for _, stopCodon := range codonTable.StopCodons {
+		skipped[strings.ToUpper(stopCodon)] = true
+	}
+
+	sequence = strings.ToUpper(sequence)
+	var logSum float64
+	var codons int
+	for index := 0; index+3 <= len(sequence); index += 3 {
+		triplet := sequence[index : index+3]
+		if skipped[triplet] {
+			continue
+		}
+		weight, ok := adaptiveness[triplet]
+		if !ok {
+			return 0, fmt.Errorf("codon %s at position %d is missing from the weighted codon table", triplet, index)
+		}
+		logSum += math.Log(weight)
+		codons++
+	}
+	if codons == 0 {
+		return 0, errors.New("sequence has no codons with synonymous alternatives")
+	}
+	return math.Exp(logSum / float64(codons)), nil
+}
+
+// Harmonize recodes a coding sequence from one organism for expression in
+// another by codon harmonization [Angov E et al. (2008) PLoS One,
+// doi:10.1371/journal.pone.0002189]. Instead of always using the target's
+// favourite codons like Optimize, every codon is replaced with the synonymous
+// codon whose share of its amino acid's usage in targetTable is closest to
+// the original codon's share in sourceTable. Rare codons stay rare, which
+// keeps the pauses in translation that some proteins need to fold properly.
+//
+// Both tables need to be weighted, by OptimizeTable or a codon usage file.
+// Ties go to the target codon used most.
+func Harmonize(sequence string, sourceTable Table, targetTable Table) (string, error) {
+	if len(sequence) == 0 {
+		return "", errEmtpySequenceString
+	}
+	if len(sequence)%3 != 0 {
+		return "", fmt.Errorf("sequence of length %d is not a whole number of codons", len(sequence))
+	}
+
+	sourceStats, targetStats := sourceTable.Stats(), targetTable.Stats()
+	sourceCounts, targetCounts := make(map[string]int), make(map[string]int)
+	for _, aminoAcid := range sourceTable.AminoAcids {
+		for _, codon := range aminoAcid.Codons {
+			sourceCounts[aminoAcid.Letter] += codon.Weight
+		}
+	}
+	targetCodons := make(map[string][]Codon)
+	for _, aminoAcid := range targetTable.AminoAcids {
+		targetCodons[aminoAcid.Letter] = append(targetCodons[aminoAcid.Letter], aminoAcid.Codons...)
+		for _, codon := range aminoAcid.Codons {
+			targetCounts[aminoAcid.Letter] += codon.Weight
+		}
+	}
+	translationTable := sourceTable.generateTranslationTable()
+
+	sequence = strings.ToUpper(sequence)
+	var harmonized strings.Builder
+	for index := 0; index+3 <= len(sequence); index += 3 {
+		triplet := sequence[index : index+3]
+		aminoAcid, ok := translationTable[triplet]
+		if !ok {
+			return "", fmt.Errorf("codon %s at position %d is missing from the source codon table", triplet, index)
+		}
+		if sourceCounts[aminoAcid] == 0 {
+			return "", fmt.Errorf("source codon table has no usage data for amino acid %s", aminoAcid)
+		}
+		if targetCounts[aminoAcid] == 0 {
+			return "", fmt.Errorf("target codon table has no usage data for amino acid %s", aminoAcid)
+		}
+
+		var best Codon
+		bestDistance := math.Inf(1)
+		for _, codon := range targetCodons[aminoAcid] {
+			distance := math.Abs(targetStats[codon.Triplet].Fraction - sourceStats[triplet].Fraction)
+			if distance < bestDistance || (distance == bestDistance && codon.Weight > best.Weight) {
+				best, bestDistance = codon, distance
+			}
+		}
+		harmonized.WriteString(strings.ToUpper(best.Triplet))
+	}
+	return harmonized.String(), nil
+}
+
+// CodonDiff holds the difference in usage of each codon between two tables, keyed by triplet.
+type CodonDiff map[string]float64
+
+// Diff compares codon usage between two weighted tables. For every codon it
+// returns the fraction of its amino acid's codons it makes up in this table
+// minus that fraction in the other table, so a positive value means the codon
+// is used more in this table. Codons missing from a table count as unused.
+func (codonTable Table) Diff(other Table) CodonDiff {
+	stats, otherStats := codonTable.Stats(), other.Stats()
+	diff := make(CodonDiff)
+	for triplet, stat := range stats {
+		diff[triplet] = stat.Fraction - otherStats[triplet].Fraction
+	}
+	for triplet, otherStat := range otherStats {
+		if _, ok := stats[triplet]; !ok {
+			diff[triplet] = -otherStat.Fraction
+		}
+	}
+	return diff
+}
+
+// MostDivergentCodons returns up to n codons with the largest absolute
+// difference in usage, most divergent first. Ties are sorted by triplet.
+func (diff CodonDiff) MostDivergentCodons(n int) []string {
+	triplets := make([]string, 0, len(diff))
+	for triplet := range diff {
+		triplets = append(triplets, triplet)
+	}
+	sort.Slice(triplets, func(i, j int) bool {
+		first, second := math.Abs(diff[triplets[i]]), math.Abs(diff[triplets[j]])
+		if first != second {
+			return first > second
+		}
+		return triplets[i] < triplets[j]
+	})
+	if n < 0 {
+		n = 0
+	}
+	if n < len(triplets) {
+		triplets = triplets[:n]
+	}
+	return triplets
+}
+
+// CodonPairScore returns the codon pair bias of a coding sequence: the average
+// codon pair score of its adjacent codons, as described by Coleman et al. 2008
+// (https://doi.org/10.1126/science.1155761). The score of a pair is the log of
+// how often the table saw it compared to how often it would be seen if codons
+// paired up at random, so sequences made of pairs the organism prefers score
+// above 0 and sequences made of pairs it avoids score below 0.
+//
+// The table needs codon pair counts from OptimizeTable. Pairs of amino acids
+// the table never saw are skipped, and codon pairs it never saw are counted as
+// half a pair so that they score as strongly underrepresented.
+func (codonTable Table) CodonPairScore(sequence string) (float64, error) {
+	scorer, err := newCodonPairScorer(codonTable)
+	if err != nil {
+		return 0, err
+	}
+	if len(sequence)%3 != 0 {
+		return 0, fmt.Errorf("sequence of length %d is not a whole number of codons", len(sequence))
+	}
+
+	sequence = strings.ToUpper(sequence)
+	var total float64
+	var pairs int
+	for index := 3; index+3 <= len(sequence); index += 3 {
+		if score, ok := scorer.score(sequence[index-3:index], sequence[index:index+3]); ok {
+			total += score
+			pairs++
+		}
+	}
+	if pairs == 0 {
+		return 0, errors.New("sequence has no codon pairs the table has data for")
+	}
+	return total / float64(pairs), nil
+}
+
+// codonPairScorer holds the counts needed to score codon pairs.
+type codonPairScorer struct {
+	pairCounts          map[string]int
+	codonCounts         map[string]int
+	aminoAcids          map[string]string // codon -> amino acid
+	aminoAcidCounts     map[string]int
+	aminoAcidPairCounts map[string]int
+}
+
+// newCodonPairScorer counts up everything a Table's codon pairs can be scored with.
+func newCodonPairScorer(codonTable Table) (codonPairScorer, error) {
+	if len(codonTable.CodonPairs) == 0 {
+		return codonPairScorer{}, errors.New("codon table has no codon pair counts, weight it with OptimizeTable first")
+	}
+	scorer := codonPairScorer{
+		pairCounts:          codonTable.CodonPairs,
+		codonCounts:         make(map[string]int),
+		aminoAcids:          codonTable.generateTranslationTable(),
+		aminoAcidCounts:     make(map[string]int),
+		aminoAcidPairCounts: make(map[string]int),
+	}
+	for _, aminoAcid := range codonTable.AminoAcids {
+		for _, codon := range aminoAcid.Codons {
+			scorer.codonCounts[codon.Triplet] += codon.Weight
+			scorer.aminoAcidCounts[aminoAcid.Letter] += codon.Weight
+		}
+	}
+	for pair, count := range codonTable.CodonPairs {
+		scorer.aminoAcidPairCounts[scorer.aminoAcids[pair[:3]]+scorer.aminoAcids[pair[3:]]] += count
+	}
+	return scorer, nil
+}
+
+// score returns the codon pair score of two codons, or false if the table
+// doesn't have enough data to score them.
+func (scorer codonPairScorer) score(first, second string) (float64, bool) {
+	firstAminoAcid, ok := scorer.aminoAcids[first]
+	if !ok {
+		return 0, false
+	}
+	secondAminoAcid, ok := scorer.aminoAcids[second]
+	if !ok {
+		return 0, false
+	}
+	aminoAcidPairs := scorer.aminoAcidPairCounts[firstAminoAcid+secondAminoAcid]
+	aminoAcidProduct := scorer.aminoAcidCounts[firstAminoAcid] * scorer.aminoAcidCounts[secondAminoAcid]
+	if aminoAcidPairs == 0 || aminoAcidProduct == 0 {
+		return 0, false
+	}
+
+	expected := float64(scorer.codonCounts[first]) * float64(scorer.codonCounts[second]) / float64(aminoAcidProduct) * float64(aminoAcidPairs)
+	if expected == 0 {
+		return 0, false
+	}
+	observed := float64(scorer.pairCounts[first+second])
+	if observed == 0 {
+		observed = 0.5
+	}
+	return math.Log(observed / expected), true
+}
+
+// getCodonPairFrequency takes a DNA sequence and returns a hashmap of its adjacent in frame codon pairs and their frequencies.
+func getCodonPairFrequency(sequence string) map[string]int {
+	codonPairFrequencyHashMap := map[string]int{}
+	for index := 0; index+6 <= len(sequence); index += 3 {
+		codonPairFrequencyHashMap[sequence[index:index+6]]++
+	}
+	return codonPairFrequencyHashMap
+}
+
 // getCodonFrequency takes a DNA sequence and returns a hashmap of its codons and their frequencies.
 func getCodonFrequency(sequence string) map[string]int {
 
@@ -193,12 +1375,32 @@ func getCodonFrequency(sequence string) map[string]int {
 	return codonFrequencyHashMap
 }
 
-// chooser is a Table method to convert a codon table to a chooser
-func (codonTable Table) chooser() (map[string]weightedRand.Chooser, error) {
+// chooser converts the codon choices of a codon table to choosers
+func chooser(choices map[string][]weightedRand.Choice) (map[string]weightedRand.Chooser, error) {
 
 	// This maps codon tables structure to weightRand.NewChooser structure
 	codonChooser := make(map[string]weightedRand.Chooser)
 
+	// iterate over every amino acid's thresholded codon choices
+	for aminoAcid, codonChoices := range choices {
+
+		// add this chooser set to the codonChooser map under the name of the aminoAcid it represents.
+		chooser, err := weightedRand.NewChooser(codonChoices...)
+		if err != nil {
+			return nil, fmt.Errorf("weightedRand.NewChooser() error: %s", err)
+		}
+
+		codonChooser[aminoAcid] = *chooser
+	}
+	return codonChooser, nil
+}
+
+// codonChoices is a Table method that maps every amino acid to the weighted codons that may be chosen for it.
+// Codons used minCodonFrequency of the time for their amino acid or less are left out,
+// so a minCodonFrequency of 0 only leaves out codons that are never used.
+func (codonTable Table) codonChoices(minCodonFrequency float64) map[string][]weightedRand.Choice {
+	choices := make(map[string][]weightedRand.Choice)
+
 	// iterate over every amino acid in the codonTable
 	for _, aminoAcid := range codonTable.AminoAcids {
 
@@ -211,25 +1413,30 @@ func (codonTable Table) chooser() (map[string]weightedRand.Chooser, error) {
 			codonOccurenceSum += codon.Weight
 		}
 
-		// Threshold codons that occur less than 10% for coding a particular amino acid
+		// Threshold codons that occur minCodonFrequency or less for coding a particular amino acid
+		var mostFrequentCodon Codon
+		var thresholded bool
 		for _, codon := range aminoAcid.Codons {
 			codonPercentage := float64(codon.Weight) / float64(codonOccurenceSum)
 
-			if codonPercentage > 0.10 {
+			if codonPercentage > minCodonFrequency {
 				// for every codon related to current amino acid append its Triplet and Weight to codonChoices after thresholding
 				codonChoices = append(codonChoices, weightedRand.Choice{Item: codon.Triplet, Weight: uint(codon.Weight)})
+				thresholded = true
+			}
+			if mostFrequentCodon.Triplet == "" || codon.Weight > mostFrequentCodon.Weight {
+				mostFrequentCodon = codon
 			}
 		}
 
-		// add this chooser set to the codonChooser map under the name of the aminoAcid it represents.
-		chooser, err := weightedRand.NewChooser(codonChoices...)
-		if err != nil {
-			return nil, fmt.Errorf("weightedRand.NewChooser() error: %s", err)
+		// if no codon passed the threshold fall back on the most frequent one.
+		if !thresholded && mostFrequentCodon.Triplet != "" {
+			codonChoices = append(codonChoices, weightedRand.Choice{Item: mostFrequentCodon.Triplet, Weight: 1})
 		}
 
-		codonChooser[aminoAcid.Letter] = *chooser
+		choices[aminoAcid.Letter] = codonChoices
 	}
-	return codonChooser, nil
+	return choices
 }
 
 // Generate map of codons -> amino acid
@@ -297,7 +1504,7 @@ func generateCodonTable(aminoAcids, starts string) Table {
 	for k, v := range aminoAcidMap {
 		aminoAcidSlice = append(aminoAcidSlice, AminoAcid{string(k), v})
 	}
-	return Table{startCodons, stopCodons, aminoAcidSlice}
+	return Table{StartCodons: startCodons, StopCodons: stopCodons, AminoAcids: aminoAcidSlice}
 }
 
 // GetCodonTable takes the index of desired NCBI codon table and returns it.
@@ -409,6 +1616,105 @@ func WriteCodonJSON(codontable Table, path string) {
 	_ = ioutil.WriteFile(path, file, 0644)
 }
 
+// codonTSVHeader is the first line of a codon table TSV file.
+const codonTSVHeader = "amino_acid\tcodon\tcount\tfrequency\tstart"
+
+// BuildCodonTSV builds a tab separated table with a row for every codon in a
+// Table, giving its amino acid, count (the codon's weight), frequency within its
+// amino acid and whether it's a start codon. Rows are sorted by amino acid with
+// the stop codons grouped at the end, then from the most to least used codon.
+func BuildCodonTSV(codontable Table) []byte {
+	startCodons := make(map[string]bool)
+	for _, startCodon := range codontable.StartCodons {
+		startCodons[strings.ToUpper(startCodon)] = true
+	}
+
+	aminoAcids := make([]AminoAcid, len(codontable.AminoAcids))
+	copy(aminoAcids, codontable.AminoAcids)
+	sort.SliceStable(aminoAcids, func(i, j int) bool {
+		if (aminoAcids[i].Letter == "*") != (aminoAcids[j].Letter == "*") {
+			return aminoAcids[j].Letter == "*"
+		}
+		return aminoAcids[i].Letter < aminoAcids[j].Letter
+	})
+
+	stats := codontable.Stats()
+	var tsv strings.Builder
+	tsv.WriteString(codonTSVHeader + "\n")
+	for _, aminoAcid := range aminoAcids {
+		codons := make([]Codon, len(aminoAcid.Codons))
+		copy(codons, aminoAcid.Codons)
+		sort.SliceStable(codons, func(i, j int) bool {
+			if codons[i].Weight != codons[j].Weight {
+				return codons[i].Weight > codons[j].Weight
+			}
+			return codons[i].Triplet < codons[j].Triplet
+		})
+		for _, codon := range codons {
+			fmt.Fprintf(&tsv, "%s\t%s\t%d\t%.4f\t%t\n", aminoAcid.Letter, codon.Triplet, codon.Weight, stats[codon.Triplet].Fraction, startCodons[strings.ToUpper(codon.Triplet)])
+		}
+	}
+	return []byte(tsv.String())
+}
+
+// ParseCodonTSV parses a Table from the output of BuildCodonTSV. Frequencies
+// are worked out from the counts so the frequency column is ignored. Codons of
+// the "*" amino acid become the table's stop codons.
+func ParseCodonTSV(file []byte) (Table, error) {
+	var codontable Table
+	aminoAcidIndices := make(map[string]int)
+	for lineNumber, line := range strings.Split(strings.ReplaceAll(string(file), "\r\n", "\n"), "\n") {
+		if line == "" || (lineNumber == 0 && line == codonTSVHeader) {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			return Table{}, fmt.Errorf("line %d: expected 5 tab separated fields, got %d", lineNumber+1, len(fields))
+		}
+		letter, triplet := fields[0], strings.ToUpper(fields[1])
+		if len(triplet) != 3 {
+			return Table{}, fmt.Errorf("line %d: invalid codon %q", lineNumber+1, fields[1])
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil || count < 0 {
+			return Table{}, fmt.Errorf("line %d: invalid count %q", lineNumber+1, fields[2])
+		}
+		start, err := strconv.ParseBool(fields[4])
+		if err != nil {
+			return Table{}, fmt.Errorf("line %d: invalid start %q", lineNumber+1, fields[4])
+		}
+
+		aminoAcidIndex, ok := aminoAcidIndices[letter]
+		if !ok {
+			aminoAcidIndex = len(codontable.AminoAcids)
+			aminoAcidIndices[letter] = aminoAcidIndex
+			codontable.AminoAcids = append(codontable.AminoAcids, AminoAcid{Letter: letter})
+		}
+		codontable.AminoAcids[aminoAcidIndex].Codons = append(codontable.AminoAcids[aminoAcidIndex].Codons, Codon{triplet, count})
+		if start {
+			codontable.StartCodons = append(codontable.StartCodons, triplet)
+		}
+		if letter == "*" {
+			codontable.StopCodons = append(codontable.StopCodons, triplet)
+		}
+	}
+	return codontable, nil
+}
+
+// ReadCodonTSV reads a Table TSV file.
+func ReadCodonTSV(path string) (Table, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Table{}, err
+	}
+	return ParseCodonTSV(file)
+}
+
+// WriteCodonTSV writes a Table out as a TSV file that opens cleanly in a spreadsheet.
+func WriteCodonTSV(codontable Table, path string) error {
+	return ioutil.WriteFile(path, BuildCodonTSV(codontable), 0644)
+}
+
 /******************************************************************************
 Dec, 17, 2020
 
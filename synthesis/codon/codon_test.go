@@ -1,12 +1,16 @@
 package codon
 
 import (
+	"io/ioutil"
+	"math"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/TimothyStiles/poly/io/genbank"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -19,6 +23,154 @@ func TestTranslation(t *testing.T) {
 	}
 
 }
+
+func TestTranslationOrganelleTables(t *testing.T) {
+	// each of these codons is reassigned in at least one of the mitochondrial codes.
+	sequence := "ATGATATGAAGAAGGCTTTAA"
+	translations := map[int]string{
+		1:  "MI*RRL*", // standard
+		2:  "MMW**L*", // vertebrate mitochondrial
+		3:  "MMWRRT*", // yeast mitochondrial
+		4:  "MIWRRL*", // mold, protozoan and coelenterate mitochondrial
+		5:  "MMWSSL*", // invertebrate mitochondrial
+		11: "MI*RRL*", // bacterial, archaeal and plant plastid
+	}
+	for tableNumber, expected := range translations {
+		if got, _ := Translate(sequence, GetCodonTable(tableNumber)); got != expected {
+			t.Errorf("Translate with table %d returned %q, want %q", tableNumber, got, expected)
+		}
+	}
+
+	for tableNumber, table := range defaultCodonTablesByNumber {
+		var codonCount int
+		for _, aminoAcid := range table.AminoAcids {
+			codonCount += len(aminoAcid.Codons)
+		}
+		if codonCount != 64 {
+			t.Errorf("table %d has %d codons, want 64", tableNumber, codonCount)
+		}
+	}
+}
+
+// humanCOX1 is the coding sequence of human MT-CO1 (NC_012920.1 5904..7445) and
+// humanCOX1Protein is its published translation (YP_003024028.1).
+const humanCOX1 = "ATGTTCGCCGACCGTTGACTATTCTCTACAAACCACAAAGACATTGGAACACTATACCTATTATTCGGCGCATGAGCTGGAGTCCTAGGCACAGCTCTAAGCCTCCTTATTCGAGCCGAGCTGGGCCAGCCAGGCAACCTTCTAGGTAACGACCACATCTACAACGTTATCGTCACAGCCCATGCATTTGTAATAATCTTCTTCATAGTAATACCCATCATAATCGGAGGCTTTGGCAACTGACTAGTTCCCCTAATAATCGGTGCCCCCGATATGGCGTTTCCCCGCATAAACAACATAAGCTTCTGACTCTTACCTCCCTCTCTCCTACTCCTGCTCGCATCTGCTATAGTGGAGGCCGGAGCAGGAACAGGTTGAACAGTCTACCCTCCCTTAGCAGGGAACTACTCCCACCCTGGAGCCTCCGTAGACCTAACCATCTTCTCCTTACACCTAGCAGGTGTCTCCTCTATCTTAGGGGCCATCAATTTCATCACAACAATTATCAATATAAAACCCCCTGCCATAACCCAATACCAAACGCCCCTCTTCGTCTGATCCGTCCTAATCACAGCAGTCCTACTTCTCCTATCTCTCCCAGTCCTAGCTGCTGGCATCACTATACTACTAACAGACCGCAACCTCAACACCACCTTCTTCGACCCCGCCGGAGGAGGAGACCCCATTCTATACCAACACCTATTCTGATTTTTCGGTCACCCTGAAGTTTATATTCTTATCCTACCAGGCTTCGGAATAATCTCCCATATTGTAACTTACTACTCCGGAAAAAAAGAACCATTTGGATACATAGGTATGGTCTGAGCTATGATATCAATTGGCTTCCTAGGGTTTATCGTGTGAGCACACCATATATTTACAGTAGGAATAGACGTAGACACACGAGCATATTTCACCTCCGCTACCATAATCATCGCTATCCCCACCGGCGTCAAAGTATTTAGCTGACTCGCCACACTCCACGGAAGCAATATGAAATGATCTGCTGCAGTGCTCTGAGCCCTAGGATTCATCTTTCTTTTCACCGTAGGTGGCCTGACTGGCATTGTATTAGCAAACTCATCACTAGACATCGTACTACACGACACGTACTACGTTGTAGCCCACTTCCACTATGTCCTATCAATAGGAGCTGTATTTGCCATCATAGGAGGCTTCATTCACTGATTTCCCCTATTCTCAGGCTACACCCTAGACCAAACCTACGCCAAAATCCATTTCACTATCATATTCATCGGCGTAAATCTAACTTTCTTCCCACAACACTTTCTCGGCCTATCCGGAATGCCCCGACGTTACTCGGACTACCCCGATGCATACACCACATGAAACATCCTATCATCTGTAGGCTCATTCATTTCTCTAACAGCAGTAATATTAATAATTTTCATGATTTGAGAAGCCTTCGCTTCGAAGCGAAAAGTCCTAATAGTAGAAGAACCCTCCATAAACCTGGAGTGACTATATGGATGCCCCCCACCCTACCACACATTCGAAGAACCCGTATACATAAAATCTAGA"
+const humanCOX1Protein = "MFADRWLFSTNHKDIGTLYLLFGAWAGVLGTALSLLIRAELGQPGNLLGNDHIYNVIVTAHAFVMIFFMVMPIMIGGFGNWLVPLMIGAPDMAFPRMNNMSFWLLPPSLLLLLASAMVEAGAGTGWTVYPPLAGNYSHPGASVDLTIFSLHLAGVSSILGAINFITTIINMKPPAMTQYQTPLFVWSVLITAVLLLLSLPVLAAGITMLLTDRNLNTTFFDPAGGGDPILYQHLFWFFGHPEVYILILPGFGMISHIVTYYSGKKEPFGYMGMVWAMMSIGFLGFIVWAHHMFTVGMDVDTRAYFTSATMIIAIPTGVKVFSWLATLHGSNMKWSAAVLWALGFIFLFTVGGLTGIVLANSSLDIVLHDTYYVVAHFHYVLSMGAVFAIMGGFIHWFPLFSGYTLDQTYAKIHFTIMFIGVNLTFFPQHFLGLSGMPRRYSDYPDAYTTWNILSSVGSFISLTAVMLMIFMIWEAFASKRKVLMVEEPSMNLEWLYGCPPPYHTFEEPVYMKS"
+
+func TestTranslationHumanMitochondrialCOX1(t *testing.T) {
+	// MT-CO1 reads its TGA codons as tryptophan and ends on an AGA stop, which only the vertebrate mitochondrial code gets right.
+	got, err := Translate(humanCOX1, GetCodonTable(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != humanCOX1Protein+"*" {
+		t.Errorf("MT-CO1 translated with table 2 to\n%s\ninstead of\n%s*", got, humanCOX1Protein)
+	}
+	if standard, _ := Translate(humanCOX1, GetCodonTable(1)); strings.TrimSuffix(standard, "*") == humanCOX1Protein {
+		t.Errorf("MT-CO1 shouldn't translate to its protein on the standard code")
+	}
+}
+
+func TestTranslateRange(t *testing.T) {
+	codonTable := GetCodonTable(11)
+	sequence := "CCATGAAATAAGG"
+
+	for _, test := range []struct {
+		start, end, frame int
+		expected          string
+	}{
+		{2, 11, 0, "MK*"},
+		{0, 13, 2, "MK*"},
+		{0, 11, 1, "HEI"}, // the trailing partial codon is dropped
+		{2, 5, 0, "M"},
+	} {
+		got, err := TranslateRange(sequence, codonTable, test.start, test.end, test.frame)
+		if err != nil {
+			t.Error(err)
+		}
+		if got != test.expected {
+			t.Errorf("TranslateRange(%d, %d, %d) = %q, want %q", test.start, test.end, test.frame, got, test.expected)
+		}
+	}
+
+	for _, bad := range [][3]int{{-1, 5, 0}, {0, 14, 0}, {6, 5, 0}, {0, 9, 3}, {0, 9, -1}, {4, 5, 2}} {
+		if _, err := TranslateRange(sequence, codonTable, bad[0], bad[1], bad[2]); err == nil {
+			t.Errorf("TranslateRange(%d, %d, %d) should have returned an error", bad[0], bad[1], bad[2])
+		}
+	}
+}
+
+func TestTranslateChecked(t *testing.T) {
+	codonTable := GetCodonTable(11)
+
+	// a final stop codon is not internal.
+	aminoAcids, internalStops, err := TranslateChecked("ATGAAATAA", codonTable)
+	if err != nil {
+		t.Error(err)
+	}
+	if aminoAcids != "MK*" || len(internalStops) != 0 {
+		t.Errorf("expected MK* with no internal stops, got %q and %v", aminoAcids, internalStops)
+	}
+
+	_, internalStops, _ = TranslateChecked("ATGTAGAAATGATAA", codonTable)
+	if diff := cmp.Diff([]int{3, 9}, internalStops); diff != "" {
+		t.Errorf("TranslateChecked returned the wrong internal stop positions. Got this diff:\n%s", diff)
+	}
+
+	// table 4 reads TGA as tryptophan.
+	_, internalStops, _ = TranslateChecked("ATGTAGAAATGATAA", GetCodonTable(4))
+	if diff := cmp.Diff([]int{3}, internalStops); diff != "" {
+		t.Errorf("TranslateChecked returned the wrong internal stop positions. Got this diff:\n%s", diff)
+	}
+
+	if _, _, err := TranslateChecked("", codonTable); err != errEmtpySequenceString {
+		t.Errorf("TranslateChecked should return an error if given an empty sequence")
+	}
+}
+
+func TestTranslateWithOptions(t *testing.T) {
+	codonTable := GetCodonTable(11)
+	// GTG is a bacterial start codon, followed by KK, a stop and then an incomplete codon.
+	sequence := "gtgaaaaagtaaccctt"
+
+	for _, test := range []struct {
+		options     TranslateOptions
+		translation string
+		err         error
+	}{
+		{TranslateOptions{}, "VKK*P", partialCodonError{"TT"}},
+		{TranslateOptions{StartCodon: true}, "MKK*P", partialCodonError{"TT"}},
+		{TranslateOptions{StartCodon: true, StopAtStop: true}, "MKK*", nil},
+	} {
+		translation, err := TranslateWithOptions(sequence, codonTable, test.options)
+		if translation != test.translation || err != test.err {
+			t.Errorf("TranslateWithOptions(%q, %+v) = %q, %v, want %q, %v", sequence, test.options, translation, err, test.translation, test.err)
+		}
+	}
+
+	// GTG is only a start codon at the start.
+	if translation, _ := TranslateWithOptions("ATGGTG", codonTable, TranslateOptions{StartCodon: true}); translation != "MV" {
+		t.Errorf("TranslateWithOptions should only translate the first codon as a start, got %q", translation)
+	}
+	if _, err := TranslateWithOptions("", codonTable, TranslateOptions{}); err != errEmtpySequenceString {
+		t.Errorf("expected %v, got %v", errEmtpySequenceString, err)
+	}
+}
+
+func TestTranslationAmbiguousCodons(t *testing.T) {
+	codonTable := GetCodonTable(11)
+	// GCN is always alanine, TTY phenylalanine, MGR arginine and TAR a stop, but NNN, TTN and AT- could be anything.
+	for codons, expected := range map[string]string{"GCNTTYMGRTAR": "AFR*", "NNNTTNAT-": "XXX", "AUGgcu": "MA"} {
+		translation, err := Translate(codons, codonTable)
+		if err != nil {
+			t.Error(err)
+		}
+		if translation != expected {
+			t.Errorf("Translate(%q) = %q, want %q", codons, translation, expected)
+		}
+	}
+}
+
 func TestTranslationErrorsOnEmptyCodonTable(t *testing.T) {
 	emtpyCodonTable := Table{}
 	_, err := Translate("A", emtpyCodonTable)
@@ -61,24 +213,19 @@ func TestOptimize(t *testing.T) {
 	sequence, _ := genbank.Read("../../data/puc19.gbk")
 	codonTable := GetCodonTable(11)
 
-	// a string builder to build a single concatenated string of all coding regions
-	var codingRegionsBuilder strings.Builder
-
-	// iterate through the features of the genbank file and if the feature is a coding region, append the sequence to the string builder
+	// collect the sequence of every coding region in the genbank file
+	var codingRegions []string
 	for _, feature := range sequence.Features {
 		if feature.Type == "CDS" {
 			sequence, _ := feature.GetSequence()
-			codingRegionsBuilder.WriteString(sequence)
+			codingRegions = append(codingRegions, sequence)
 		}
 	}
 
-	// get the concatenated sequence string of the coding regions
-	codingRegions := codingRegionsBuilder.String()
-
 	// weight our codon optimization table using the regions we collected from the genbank file above
-	optimizationTable := codonTable.OptimizeTable(codingRegions)
+	optimizationTable, _ := codonTable.OptimizeTableFromSequences(codingRegions)
 
-	optimizedSequence, _ := Optimize(gfpTranslation, optimizationTable)
+	optimizedSequence, _ := Optimize(gfpTranslation, optimizationTable, OptimizeOptions{})
 	optimizedSequenceTranslation, _ := Translate(optimizedSequence, optimizationTable)
 
 	if optimizedSequenceTranslation != gfpTranslation {
@@ -91,25 +238,20 @@ func TestOptimizeSameSeed(t *testing.T) {
 	var sequence, _ = genbank.Read("../../data/puc19.gbk")
 	var codonTable = GetCodonTable(11)
 
-	// a string builder to build a single concatenated string of all coding regions
-	var codingRegionsBuilder strings.Builder
-
-	// iterate through the features of the genbank file and if the feature is a coding region, append the sequence to the string builder
+	// collect the sequence of every coding region in the genbank file
+	var codingRegions []string
 	for _, feature := range sequence.Features {
 		if feature.Type == "CDS" {
 			sequence, _ := feature.GetSequence()
-			codingRegionsBuilder.WriteString(sequence)
+			codingRegions = append(codingRegions, sequence)
 		}
 	}
 
-	// get the concatenated sequence string of the coding regions
-	codingRegions := codingRegionsBuilder.String()
-
-	var optimizationTable = codonTable.OptimizeTable(codingRegions)
+	optimizationTable, _ := codonTable.OptimizeTableFromSequences(codingRegions)
 	randomSeed := 10
 
-	optimizedSequence, _ := Optimize(gfpTranslation, optimizationTable, randomSeed)
-	otherOptimizedSequence, _ := Optimize(gfpTranslation, optimizationTable, randomSeed)
+	optimizedSequence, _ := Optimize(gfpTranslation, optimizationTable, OptimizeOptions{}, randomSeed)
+	otherOptimizedSequence, _ := Optimize(gfpTranslation, optimizationTable, OptimizeOptions{}, randomSeed)
 
 	if optimizedSequence != otherOptimizedSequence {
 		t.Error("Optimized sequence with the same random seed are not the same")
@@ -121,33 +263,75 @@ func TestOptimizeDifferentSeed(t *testing.T) {
 	var sequence, _ = genbank.Read("../../data/puc19.gbk")
 	var codonTable = GetCodonTable(11)
 
-	// a string builder to build a single concatenated string of all coding regions
-	var codingRegionsBuilder strings.Builder
-
-	// iterate through the features of the genbank file and if the feature is a coding region, append the sequence to the string builder
+	// collect the sequence of every coding region in the genbank file
+	var codingRegions []string
 	for _, feature := range sequence.Features {
 		if feature.Type == "CDS" {
 			sequence, _ := feature.GetSequence()
-			codingRegionsBuilder.WriteString(sequence)
+			codingRegions = append(codingRegions, sequence)
 		}
 	}
 
-	// get the concatenated sequence string of the coding regions
-	codingRegions := codingRegionsBuilder.String()
-
-	var optimizationTable = codonTable.OptimizeTable(codingRegions)
+	optimizationTable, _ := codonTable.OptimizeTableFromSequences(codingRegions)
 
-	optimizedSequence, _ := Optimize(gfpTranslation, optimizationTable)
-	otherOptimizedSequence, _ := Optimize(gfpTranslation, optimizationTable)
+	optimizedSequence, _ := Optimize(gfpTranslation, optimizationTable, OptimizeOptions{})
+	otherOptimizedSequence, _ := Optimize(gfpTranslation, optimizationTable, OptimizeOptions{})
 
 	if optimizedSequence == otherOptimizedSequence {
 		t.Error("Optimized sequence with different random seed have the same result")
 	}
 }
 
+func TestOptimizeTableFromSequences(t *testing.T) {
+	sequence, _ := genbank.Read("../../data/phix174.gb")
+	var codingRegions []string
+	var codingRegionsBuilder strings.Builder
+	for _, feature := range sequence.Features {
+		if feature.Type == "CDS" {
+			sequence, _ := feature.GetSequence()
+			codingRegions = append(codingRegions, sequence)
+			if len(sequence)%3 == 0 {
+				codingRegionsBuilder.WriteString(sequence)
+			}
+		}
+	}
+	// a sequence that would shift everything after it out of frame.
+	codingRegions = append([]string{"ATGC"}, codingRegions...)
+
+	fromSequences, skipped := GetCodonTable(1).OptimizeTableFromSequences(codingRegions)
+	codonFrequencies := getCodonFrequency(strings.ToUpper(codingRegionsBuilder.String()))
+	for _, aminoAcid := range fromSequences.AminoAcids {
+		for _, codon := range aminoAcid.Codons {
+			if codon.Weight != codonFrequencies[codon.Triplet] {
+				t.Errorf("codon %s has weight %d, want %d from concatenating manually", codon.Triplet, codon.Weight, codonFrequencies[codon.Triplet])
+			}
+		}
+	}
+	if len(skipped) == 0 || skipped[0] != 0 {
+		t.Errorf("expected the first sequence to be skipped. Got %v", skipped)
+	}
+	for _, index := range skipped {
+		if len(codingRegions[index])%3 == 0 {
+			t.Errorf("skipped sequence %d has a length that is a multiple of three", index)
+		}
+	}
+
+	// codon pairs are only counted within each gene, so the stop codon of one gene and the start codon of the next
+	// aren't a pair. An empty table is used since weighting a default table changes it for every other test.
+	genes := []string{"ATGAAATAA", "atgCCCTGA"}
+	table, _ := Table{}.OptimizeTableFromSequences(genes)
+	expectedPairs := map[string]int{"ATGAAA": 1, "AAATAA": 1, "ATGCCC": 1, "CCCTGA": 1}
+	if diff := cmp.Diff(expectedPairs, table.CodonPairs); diff != "" {
+		t.Errorf("unexpected codon pairs (-want +got):\n%s", diff)
+	}
+	if _, ok := table.CodonPairs["TAAATG"]; ok {
+		t.Errorf("the junction between the two genes was counted as a codon pair")
+	}
+}
+
 func TestOptimizeErrorsOnEmptyCodonTable(t *testing.T) {
 	emtpyCodonTable := Table{}
-	_, err := Optimize("A", emtpyCodonTable)
+	_, err := Optimize("A", emtpyCodonTable, OptimizeOptions{})
 
 	if err != errEmtpyCodonTable {
 		t.Error("Optimize should return an error if given an empty codon table")
@@ -156,7 +340,7 @@ func TestOptimizeErrorsOnEmptyCodonTable(t *testing.T) {
 
 func TestOptimizeErrorsOnEmptyAminoAcidString(t *testing.T) {
 	nonEmptyCodonTable := GetCodonTable(1)
-	_, err := Optimize("", nonEmptyCodonTable)
+	_, err := Optimize("", nonEmptyCodonTable, OptimizeOptions{})
 
 	if err != errEmtpyAminoAcidString {
 		t.Error("Optimize should return an error if given an empty amino acid string")
@@ -166,10 +350,327 @@ func TestOptimizeErrorsOnInvalidAminoAcid(t *testing.T) {
 	aminoAcids := "TOP"
 	table := GetCodonTable(1) // does not contain 'O'
 
-	_, optimizeErr := Optimize(aminoAcids, table)
+	_, optimizeErr := Optimize(aminoAcids, table, OptimizeOptions{})
 	assert.EqualError(t, optimizeErr, invalidAminoAcidError{'O'}.Error())
 }
 
+func TestOptimizeGCWindow(t *testing.T) {
+	gfpTranslation := "MASKGEELFTGVVPILVELDGDVNGHKFSVSGEGEGDATYGKLTLKFICTTGKLPVPWPTLVTTFSYGVQCFSRYPDHMKRHDFFKSAMPEGYVQERTISFKDDGNYKTRAEVKFEGDTLVNRIELKGIDFKEDGNILGHKLEYNYNSHNVYITADKQKNGIKANFKIRHNIEDGSVQLADHYQQNTPIGDGPVLLPDNHYLSTQSALSKDPNEKRDHMVLLEFVTAAGITHGMDELYK*"
+	codonTable := GetCodonTable(11)
+
+	for _, window := range []OptimizeOptions{{MinGC: 0.35, MaxGC: 0.40}, {MinGC: 0.55, MaxGC: 0.60}} {
+		optimizedSequence, err := Optimize(gfpTranslation, codonTable, window, 1)
+		if err != nil {
+			t.Fatalf("Optimize returned an error for window %+v: %s", window, err)
+		}
+
+		gcContent := float64(countGC(optimizedSequence)) / float64(len(optimizedSequence))
+		if gcContent < window.MinGC || gcContent > window.MaxGC {
+			t.Errorf("GC content %f is outside of window %+v", gcContent, window)
+		}
+
+		translation, _ := Translate(optimizedSequence, codonTable)
+		if translation != gfpTranslation {
+			t.Errorf("GC constrained optimization changed the protein. Got %q, want %q", translation, gfpTranslation)
+		}
+	}
+}
+
+func TestOptimizeGCWindowInfeasible(t *testing.T) {
+	// lysine and phenylalanine codons are at most 1/3 GC so a 60% to 70% window can't be reached.
+	optimizedSequence, err := Optimize("KKKKKFFFFF", GetCodonTable(11), OptimizeOptions{MinGC: 0.6, MaxGC: 0.7})
+	if _, ok := err.(gcContentError); !ok {
+		t.Errorf("Expected a gcContentError, got %v", err)
+	}
+	if len(optimizedSequence) != 30 {
+		t.Errorf("Expected the best effort sequence to be returned alongside the error, got %q", optimizedSequence)
+	}
+
+	if _, err := Optimize("KKK", GetCodonTable(11), OptimizeOptions{MinGC: 0.7, MaxGC: 0.6}); err == nil {
+		t.Errorf("Expected an error for an inverted GC window")
+	}
+}
+
+func TestOptimizeGCWindows(t *testing.T) {
+	gfpTranslation := "MASKGEELFTGVVPILVELDGDVNGHKFSVSGEGEGDATYGKLTLKFICTTGKLPVPWPTLVTTFSYGVQCFSRYPDHMKRHDFFKSAMPEGYVQERTISFKDDGNYKTRAEVKFEGDTLVNRIELKGIDFKEDGNILGHKLEYNYNSHNVYITADKQKNGIKANFKIRHNIEDGSVQLADHYQQNTPIGDGPVLLPDNHYLSTQSALSKDPNEKRDHMVLLEFVTAAGITHGMDELYK*"
+	codonTable := GetCodonTable(11)
+	options := OptimizeOptions{MinGC: 0.45, MaxGC: 0.55, GCWindow: 50, MinWindowGC: 0.35, MaxWindowGC: 0.65, ForbiddenMotifs: []string{"GGTCTC", "AAAAA"}}
+
+	for seed := 0; seed < 10; seed++ {
+		optimizedSequence, err := Optimize(gfpTranslation, codonTable, options, seed)
+		if err != nil {
+			t.Fatalf("Optimize returned an error for seed %d: %s", seed, err)
+		}
+		for start := 0; start+50 <= len(optimizedSequence); start++ {
+			gcContent := float64(countGC(optimizedSequence[start:start+50])) / 50
+			if gcContent < 0.35 || gcContent > 0.65 {
+				t.Errorf("GC content %f of the window starting at %d is outside of the range for seed %d", gcContent, start, seed)
+			}
+		}
+		translation, _ := Translate(optimizedSequence, codonTable)
+		if translation != gfpTranslation {
+			t.Errorf("GC window constrained optimization changed the protein. Got %q, want %q", translation, gfpTranslation)
+		}
+	}
+
+	// lysine and phenylalanine codons are at most 1/3 GC so no window of 30 bases can reach 50%.
+	optimizedSequence, err := Optimize("MKKKKKKKKKKFFFFFFFFFF", codonTable, OptimizeOptions{GCWindow: 30, MinWindowGC: 0.5, MaxWindowGC: 0.7})
+	if windowErr, ok := err.(windowGCContentError); !ok || windowErr.Start != 0 {
+		t.Errorf("Expected a windowGCContentError for the first window, got %v", err)
+	}
+	if len(optimizedSequence) != 63 {
+		t.Errorf("Expected the best effort sequence to be returned alongside the error, got %q", optimizedSequence)
+	}
+
+	for _, invalid := range []OptimizeOptions{{GCWindow: -1}, {GCWindow: 50}, {GCWindow: 50, MinWindowGC: 0.6, MaxWindowGC: 0.4}} {
+		if _, err := Optimize("KKK", codonTable, invalid); err == nil {
+			t.Errorf("Expected an error for invalid options %+v", invalid)
+		}
+	}
+}
+
+func TestOptimizeForbiddenMotifs(t *testing.T) {
+	gfpTranslation := "MASKGEELFTGVVPILVELDGDVNGHKFSVSGEGEGDATYGKLTLKFICTTGKLPVPWPTLVTTFSYGVQCFSRYPDHMKRHDFFKSAMPEGYVQERTISFKDDGNYKTRAEVKFEGDTLVNRIELKGIDFKEDGNILGHKLEYNYNSHNVYITADKQKNGIKANFKIRHNIEDGSVQLADHYQQNTPIGDGPVLLPDNHYLSTQSALSKDPNEKRDHMVLLEFVTAAGITHGMDELYK*"
+	codonTable := GetCodonTable(11)
+	// BsaI and BsmBI sites, an EcoRI site and homopolymer runs.
+	forbidden := []string{"GGTCTC", "CGTCTC", "GAATTC", "AAAAA", "TTTTT", "GGGGG", "CCCCC"}
+
+	for _, options := range []OptimizeOptions{{ForbiddenMotifs: forbidden}, {ForbiddenMotifs: forbidden, MinGC: 0.45, MaxGC: 0.55}} {
+		for seed := 0; seed < 10; seed++ {
+			optimizedSequence, err := Optimize(gfpTranslation, codonTable, options, seed)
+			if err != nil {
+				t.Fatalf("Optimize returned an error for seed %d: %s", seed, err)
+			}
+			for _, motif := range forbidden {
+				if strings.Contains(optimizedSequence, motif) {
+					t.Errorf("optimized sequence for seed %d contains forbidden motif %s", seed, motif)
+				}
+			}
+			translation, _ := Translate(optimizedSequence, codonTable)
+			if translation != gfpTranslation {
+				t.Errorf("removing forbidden motifs changed the protein. Got %q, want %q", translation, gfpTranslation)
+			}
+		}
+	}
+
+	// methionine only has one codon, so ATGATG can't be avoided. Its reverse
+	// complement is CATCAT, which is found too.
+	optimizedSequence, err := Optimize("MMKHH", codonTable, OptimizeOptions{ForbiddenMotifs: []string{"atgatg"}, DisableMinCodonFrequency: true})
+	want := forbiddenMotifError{Motifs: []string{"ATGATG"}, Positions: []int{0}}
+	if diff := cmp.Diff(want, err); diff != "" {
+		t.Errorf("unexpected error (-want +got):\n%s", diff)
+	}
+	if !strings.HasPrefix(optimizedSequence, "ATGATGAA") || strings.Contains(optimizedSequence, "CATCAT") {
+		t.Errorf("expected the avoidable site to be removed alongside the error, got %q", optimizedSequence)
+	}
+
+	if _, err := Optimize("MMK", codonTable, OptimizeOptions{ForbiddenMotifs: []string{""}}); err == nil {
+		t.Errorf("Expected an error for an empty forbidden motif")
+	}
+}
+
+func TestOptimizeStrategies(t *testing.T) {
+	gfpTranslation := "MASKGEELFTGVVPILVELDGDVNGHKFSVSGEGEGDATYGKLTLKFICTTGKLPVPWPTLVTTFSYGVQCFSRYPDHMKRHDFFKSAMPEGYVQERTISFKDDGNYKTRAEVKFEGDTLVNRIELKGIDFKEDGNILGHKLEYNYNSHNVYITADKQKNGIKANFKIRHNIEDGSVQLADHYQQNTPIGDGPVLLPDNHYLSTQSALSKDPNEKRDHMVLLEFVTAAGITHGMDELYK*"
+	codonTable := ReadCodonJSON("../../data/bsub_codon_test.json")
+	mostFrequent := make(map[string]Codon)
+	for _, aminoAcid := range codonTable.AminoAcids {
+		for _, codon := range aminoAcid.Codons {
+			if codon.Weight > mostFrequent[aminoAcid.Letter].Weight {
+				mostFrequent[aminoAcid.Letter] = codon
+			}
+		}
+	}
+
+	// no seed is given, so only deterministic strategies give the same sequence twice.
+	for _, strategy := range []OptimizeStrategy{StrategyMostFrequent, StrategyCyclicWeighted} {
+		options := OptimizeOptions{Strategy: strategy}
+		first, err := Optimize(gfpTranslation, codonTable, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, _ := Optimize(gfpTranslation, codonTable, options)
+		if first != second {
+			t.Errorf("strategy %d gave different sequences: %q and %q", strategy, first, second)
+		}
+		translation, _ := Translate(first, codonTable)
+		if translation != gfpTranslation {
+			t.Errorf("strategy %d changed the protein. Got %q, want %q", strategy, translation, gfpTranslation)
+		}
+		if strategy == StrategyMostFrequent {
+			for index, aminoAcid := range gfpTranslation {
+				if codon := first[3*index : 3*index+3]; codon != mostFrequent[string(aminoAcid)].Triplet {
+					t.Errorf("expected the most frequent codon %s for %c at residue %d, got %s", mostFrequent[string(aminoAcid)].Triplet, aminoAcid, index, codon)
+				}
+			}
+		}
+	}
+
+	// six GCGs and four GCCs, spread out as evenly as possible.
+	alanineTable := Table{AminoAcids: []AminoAcid{{"A", []Codon{{"GCC", 40}, {"GCG", 60}}}}}
+	cyclic, err := Optimize("AAAAAAAAAA", alanineTable, OptimizeOptions{Strategy: StrategyCyclicWeighted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "GCGGCCGCGGCCGCGGCGGCCGCGGCCGCG"; cyclic != want {
+		t.Errorf("cyclic weighted optimization: got %q, want %q", cyclic, want)
+	}
+
+	// with a GC content window the strategy picks from the codons that keep inside of it.
+	gcWindow := OptimizeOptions{Strategy: StrategyMostFrequent, MinGC: 0.45, MaxGC: 0.5}
+	optimizedSequence, err := Optimize(gfpTranslation, codonTable, gcWindow)
+	if err != nil {
+		t.Fatalf("GC constrained most frequent optimization failed: %s", err)
+	}
+	if gcContent := float64(countGC(optimizedSequence)) / float64(len(optimizedSequence)); gcContent < 0.45 || gcContent > 0.5 {
+		t.Errorf("GC content %f is outside of the window", gcContent)
+	}
+
+	for _, invalid := range []OptimizeOptions{{Strategy: -1}, {Strategy: 3}, {Strategy: StrategyMostFrequent, CodonPairs: CodonPairsOptimized}} {
+		if _, err := Optimize("AAA", alanineTable, invalid); err == nil {
+			t.Errorf("Expected an error for invalid options %+v", invalid)
+		}
+	}
+}
+
+func TestOptimizeBatch(t *testing.T) {
+	codonTable := GetCodonTable(11)
+	proteins := []string{"MASKGEELFTGVV", "MKRISTTITTTITITTGNGAG", "", "MAJ", "MVKVYAPASSANMSVGFDVLGAAV"}
+	for index := 0; index < 50; index++ {
+		proteins = append(proteins, strings.Repeat("MKLV", index+1))
+	}
+
+	sequences, errs := OptimizeBatch(proteins, codonTable, OptimizeOptions{}, 7)
+	if len(sequences) != len(proteins) || len(errs) != len(proteins) {
+		t.Fatalf("expected %d results, got %d sequences and %d errors", len(proteins), len(sequences), len(errs))
+	}
+	for index, protein := range proteins {
+		// every result should be the same as optimizing that protein alone with the seed plus its index.
+		expected, expectedErr := Optimize(protein, codonTable, OptimizeOptions{}, 7+index)
+		if sequences[index] != expected || errs[index] != expectedErr {
+			t.Errorf("protein %d: got %q, %v. Expected %q, %v", index, sequences[index], errs[index], expected, expectedErr)
+		}
+	}
+	if errs[2] != errEmtpyAminoAcidString {
+		t.Errorf("expected %v for an empty protein, got %v", errEmtpyAminoAcidString, errs[2])
+	}
+	if _, ok := errs[3].(invalidAminoAcidError); !ok {
+		t.Errorf("expected an invalidAminoAcidError, got %v", errs[3])
+	}
+
+	// invalid options fail every protein.
+	_, errs = OptimizeBatch(proteins[:2], codonTable, OptimizeOptions{MinGC: 0.6, MaxGC: 0.4})
+	for index, err := range errs {
+		if err == nil {
+			t.Errorf("protein %d: expected an error for an invalid GC window", index)
+		}
+	}
+	if sequences, errs := OptimizeBatch(nil, codonTable, OptimizeOptions{}); len(sequences) != 0 || len(errs) != 0 {
+		t.Errorf("expected no results for no proteins")
+	}
+}
+
+func TestOptimizeMinCodonFrequency(t *testing.T) {
+	leucine := AminoAcid{"L", []Codon{{"CTG", 90}, {"TTA", 6}, {"CTC", 4}}}
+	codonTable := Table{StartCodons: []string{"ATG"}, StopCodons: []string{"TAA"}, AminoAcids: []AminoAcid{leucine}}
+	protein := strings.Repeat("L", 200)
+
+	usedCodons := func(options OptimizeOptions) map[string]bool {
+		optimizedSequence, err := Optimize(protein, codonTable, options, 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		used := make(map[string]bool)
+		for index := 0; index < len(optimizedSequence); index += 3 {
+			used[optimizedSequence[index:index+3]] = true
+		}
+		return used
+	}
+
+	// the default threshold of 10% drops both rare codons.
+	if used := usedCodons(OptimizeOptions{}); len(used) != 1 || !used["CTG"] {
+		t.Errorf("Expected only CTG with the default threshold, got %v", used)
+	}
+	// a 5% threshold keeps TTA.
+	if used := usedCodons(OptimizeOptions{MinCodonFrequency: 0.05}); len(used) != 2 || used["CTC"] {
+		t.Errorf("Expected CTG and TTA with a 5%% threshold, got %v", used)
+	}
+	// disabling the threshold keeps every codon.
+	if used := usedCodons(OptimizeOptions{MinCodonFrequency: 0.05, DisableMinCodonFrequency: true}); len(used) != 3 {
+		t.Errorf("Expected every codon without a threshold, got %v", used)
+	}
+	// if nothing passes the threshold the most frequent codon is used.
+	if used := usedCodons(OptimizeOptions{MinCodonFrequency: 0.95}); len(used) != 1 || !used["CTG"] {
+		t.Errorf("Expected only the most frequent codon, got %v", used)
+	}
+
+	// codons used exactly as often as the threshold are left out.
+	leucine.Codons = []Codon{{"CTG", 90}, {"TTA", 10}}
+	codonTable.AminoAcids = []AminoAcid{leucine}
+	if used := usedCodons(OptimizeOptions{}); len(used) != 1 || !used["CTG"] {
+		t.Errorf("Expected a codon used 10%% of the time to be left out by the default threshold, got %v", used)
+	}
+
+	for _, invalid := range []float64{-0.1, 1.5} {
+		if _, err := Optimize(protein, codonTable, OptimizeOptions{MinCodonFrequency: invalid}); err == nil {
+			t.Errorf("Expected an error for a minimum codon frequency of %.1f", invalid)
+		}
+	}
+}
+
+func TestCodonPairScore(t *testing.T) {
+	gfpTranslation := "MASKGEELFTGVVPILVELDGDVNGHKFSVSGEGEGDATYGKLTLKFICTTGKLPVPWPTLVTTFSYGVQCFSRYPDHMKRHDFFKSAMPEGYVQERTISFKDDGNYKTRAEVKFEGDTLVNRIELKGIDFKEDGNILGHKLEYNYNSHNVYITADKQKNGIKANFKIRHNIEDGSVQLADHYQQNTPIGDGPVLLPDNHYLSTQSALSKDPNEKRDHMVLLEFVTAAGITHGMDELYK*"
+	sequence, _ := genbank.Read("../../data/puc19.gbk")
+	var codingRegions []string
+	for _, feature := range sequence.Features {
+		if feature.Type == "CDS" {
+			sequence, _ := feature.GetSequence()
+			codingRegions = append(codingRegions, sequence)
+		}
+	}
+	codonTable, _ := GetCodonTable(11).OptimizeTableFromSequences(codingRegions)
+	if len(codonTable.CodonPairs) == 0 {
+		t.Fatal("OptimizeTable did not count codon pairs")
+	}
+
+	optimized, err := Optimize(gfpTranslation, codonTable, OptimizeOptions{CodonPairs: CodonPairsOptimized}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deoptimized, err := Optimize(gfpTranslation, codonTable, OptimizeOptions{CodonPairs: CodonPairsDeoptimized}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, optimizedSequence := range []string{optimized, deoptimized} {
+		if translation, _ := Translate(optimizedSequence, codonTable); translation != gfpTranslation {
+			t.Errorf("codon pair optimization changed the protein to %s", translation)
+		}
+	}
+	if otherSeed, _ := Optimize(gfpTranslation, codonTable, OptimizeOptions{CodonPairs: CodonPairsOptimized}, 2); otherSeed != optimized {
+		t.Errorf("codon pair optimization should not depend on the random seed")
+	}
+
+	randomSequence, _ := Optimize(gfpTranslation, codonTable, OptimizeOptions{}, 1)
+	optimizedScore, _ := codonTable.CodonPairScore(optimized)
+	deoptimizedScore, _ := codonTable.CodonPairScore(deoptimized)
+	randomScore, err := codonTable.CodonPairScore(randomSequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(optimizedScore > randomScore && randomScore > deoptimizedScore) {
+		t.Errorf("expected optimized > random > deoptimized codon pair scores, got %f, %f and %f", optimizedScore, randomScore, deoptimizedScore)
+	}
+
+	if _, err := GetCodonTable(4).CodonPairScore("ATGAAATAA"); err == nil {
+		t.Error("expected an error for a table without codon pair counts")
+	}
+	if _, err := codonTable.CodonPairScore("ATGAAAT"); err == nil {
+		t.Error("expected an error for a sequence that isn't a whole number of codons")
+	}
+	if _, err := Optimize(gfpTranslation, codonTable, OptimizeOptions{MinGC: 0.4, MaxGC: 0.6, CodonPairs: CodonPairsOptimized}); err == nil {
+		t.Error("expected an error when combining codon pairs with a GC content window")
+	}
+}
+
 func TestGetCodonFrequency(t *testing.T) {
 
 	translationTable := GetCodonTable(11).generateTranslationTable()
@@ -216,6 +717,284 @@ func TestGetCodonFrequency(t *testing.T) {
 
 }
 
+func TestStats(t *testing.T) {
+	// GCT twice, GCC and GCA once each and GCG never for alanine, plus a single tryptophan.
+	codonTable := GetCodonTable(11).OptimizeTable("GCTGCTGCCGCATGG")
+	stats := codonTable.Stats()
+
+	if len(stats) != 64 {
+		t.Errorf("Expected stats for all 64 codons, got %d", len(stats))
+	}
+
+	expectedStats := map[string]CodonStat{
+		"GCT": {Count: 2, Fraction: 0.5, RSCU: 2.0},
+		"GCC": {Count: 1, Fraction: 0.25, RSCU: 1.0},
+		"GCA": {Count: 1, Fraction: 0.25, RSCU: 1.0},
+		"GCG": {Count: 0, Fraction: 0, RSCU: 0},
+		"TGG": {Count: 1, Fraction: 1.0, RSCU: 1.0},
+		"TTT": {Count: 0, Fraction: 0, RSCU: 0}, // phenylalanine was never observed.
+	}
+	for triplet, expected := range expectedStats {
+		if stats[triplet] != expected {
+			t.Errorf("Stats for %s: got %+v, want %+v", triplet, stats[triplet], expected)
+		}
+	}
+}
+
+func TestCAI(t *testing.T) {
+	// GCT twice, GCC and GCA once each and GCG never for alanine, plus a single tryptophan.
+	codonTable := GetCodonTable(11).OptimizeTable("GCTGCTGCCGCATGG")
+
+	adaptiveness := codonTable.RelativeAdaptiveness()
+	expectedAdaptiveness := map[string]float64{"GCT": 1, "GCC": 0.5, "GCA": 0.5, "GCG": 0.25, "TGG": 1}
+	for triplet, expected := range expectedAdaptiveness {
+		if adaptiveness[triplet] != expected {
+			t.Errorf("Relative adaptiveness of %s: got %f, want %f", triplet, adaptiveness[triplet], expected)
+		}
+	}
+	if _, ok := adaptiveness["TTT"]; ok {
+		t.Errorf("Expected no relative adaptiveness for phenylalanine, which was never observed")
+	}
+
+	// ATG, TGG and TAA are skipped, leaving GCT and GCC.
+	cai, err := CAI("atgGCTGCCTGGTAA", codonTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(cai-math.Sqrt(0.5)) > 1e-9 {
+		t.Errorf("CAI: got %f, want %f", cai, math.Sqrt(0.5))
+	}
+
+	for _, badSequence := range []string{"", "GCTG", "GCTTTT", "ATGTGGTAA"} {
+		if _, err := CAI(badSequence, codonTable); err == nil {
+			t.Errorf("Expected an error for the CAI of %q", badSequence)
+		}
+	}
+}
+
+func TestHarmonize(t *testing.T) {
+	sourceTable := Table{AminoAcids: []AminoAcid{
+		{"A", []Codon{{"GCT", 60}, {"GCC", 28}, {"GCA", 12}, {"GCG", 0}}},
+		{"K", []Codon{{"AAA", 0}, {"AAG", 0}}},
+	}}
+	targetTable := Table{AminoAcids: []AminoAcid{
+		{"A", []Codon{{"GCT", 5}, {"GCC", 25}, {"GCA", 15}, {"GCG", 55}}},
+	}}
+
+	// the most, second most, third most and least used alanine codons keep their ranks.
+	harmonized, err := Harmonize("GCTGCCgcaGCG", sourceTable, targetTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if harmonized != "GCGGCCGCAGCT" {
+		t.Errorf("Harmonize: got %q, want %q", harmonized, "GCGGCCGCAGCT")
+	}
+
+	// AAA has no counts in the source table, and is missing from the target table.
+	for _, badSequence := range []string{"", "GCTG", "TTT", "AAA"} {
+		if _, err := Harmonize(badSequence, sourceTable, targetTable); err == nil {
+			t.Errorf("Expected an error harmonizing %q", badSequence)
+		}
+	}
+	sourceTable.AminoAcids[1].Codons[0].Weight = 1
+	if _, err := Harmonize("AAA", sourceTable, targetTable); err == nil {
+		t.Errorf("Expected an error harmonizing an amino acid the target table has no codons for")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	// OptimizeTable weights a table in place so the two tables need to come from different default tables.
+	firstTable := GetCodonTable(1).OptimizeTable("GCTGCTGCCGCATGGTTT")
+	secondTable := GetCodonTable(11).OptimizeTable("GCAGCAGCAGCGTGGTTC")
+	diff := firstTable.Diff(secondTable)
+
+	if len(diff) != 64 {
+		t.Errorf("Expected a difference for all 64 codons, got %d", len(diff))
+	}
+	expectedDiff := map[string]float64{
+		"GCT": 0.5,
+		"GCC": 0.25,
+		"GCA": -0.5,
+		"GCG": -0.25,
+		"TGG": 0,
+		"TTT": 1,
+		"TTC": -1,
+	}
+	for triplet, expected := range expectedDiff {
+		if diff[triplet] != expected {
+			t.Errorf("Diff for %s: got %v, want %v", triplet, diff[triplet], expected)
+		}
+	}
+
+	// a table compared against itself shouldn't differ at all.
+	for triplet, difference := range firstTable.Diff(firstTable) {
+		if difference != 0 {
+			t.Errorf("Diff of a table with itself for %s: got %v, want 0", triplet, difference)
+		}
+	}
+
+	mostDivergent := diff.MostDivergentCodons(4)
+	if strings.Join(mostDivergent, " ") != "TTC TTT GCA GCT" {
+		t.Errorf("MostDivergentCodons: got %v", mostDivergent)
+	}
+	if len(diff.MostDivergentCodons(100)) != 64 || len(diff.MostDivergentCodons(-1)) != 0 {
+		t.Errorf("MostDivergentCodons should return at most n and at most every codon")
+	}
+}
+
+func TestFindORFs(t *testing.T) {
+	codonTable := GetCodonTable(11)
+
+	// MK* on the forward strand followed by the reverse complement of MP*.
+	orfs, err := FindORFs("ATGAAATAGTTAGGGCAT", codonTable, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedORFs := []ORF{
+		{Start: 0, End: 9, Strand: 1, Frame: 0, Protein: "MK*"},
+		{Start: 9, End: 18, Strand: -1, Frame: 0, Protein: "MP*"},
+	}
+	if diff := cmp.Diff(expectedORFs, orfs); diff != "" {
+		t.Errorf("FindORFs returned unexpected ORFs (-want +got):\n%s", diff)
+	}
+
+	forwardORFs, _ := FindORFs("ATGAAATAGTTAGGGCAT", codonTable, 0, false)
+	if len(forwardORFs) != 1 || forwardORFs[0].Strand != 1 {
+		t.Errorf("FindORFs should only search the forward strand when bothStrands is false, got %v", forwardORFs)
+	}
+
+	// start codons that share a stop only give the longest ORF.
+	nestedORFs, _ := FindORFs("CATGATGTAAC", codonTable, 0, false)
+	if len(nestedORFs) != 1 || nestedORFs[0] != (ORF{Start: 1, End: 10, Strand: 1, Frame: 1, Protein: "MM*"}) {
+		t.Errorf("FindORFs should merge ORFs sharing a stop codon, got %v", nestedORFs)
+	}
+
+	// ORFs without a stop codon run off the end of the sequence and aren't reported.
+	if openORFs, _ := FindORFs("ATGAAAAAA", codonTable, 0, false); len(openORFs) != 0 {
+		t.Errorf("FindORFs should not report ORFs without a stop, got %v", openORFs)
+	}
+
+	// without start codons every stretch between stops is an ORF.
+	startlessORFs, _ := FindORFsWithOptions("CCCTAAGGGGGGTGA", codonTable, ORFOptions{IgnoreStartCodons: true})
+	expectedStartlessORFs := []ORF{
+		{Start: 0, End: 6, Strand: 1, Frame: 0, Protein: "P*"},
+		{Start: 6, End: 15, Strand: 1, Frame: 0, Protein: "GG*"},
+	}
+	if diff := cmp.Diff(expectedStartlessORFs, startlessORFs); diff != "" {
+		t.Errorf("FindORFsWithOptions returned unexpected ORFs (-want +got):\n%s", diff)
+	}
+	longORFs, _ := FindORFsWithOptions("CCCTAAGGGGGGTGA", codonTable, ORFOptions{MinLength: 9, IgnoreStartCodons: true})
+	if len(longORFs) != 1 || longORFs[0].Start != 6 {
+		t.Errorf("FindORFsWithOptions should filter ORFs shorter than MinLength, got %v", longORFs)
+	}
+
+	// ATG AAA TAA runs across the origin of a circular sequence.
+	circularORFs, _ := FindORFsWithOptions("AAATAAGGGGGGATG", codonTable, ORFOptions{BothStrands: true, Circular: true})
+	if diff := cmp.Diff([]ORF{{Start: 12, End: 21, Strand: 1, Frame: 0, Protein: "MK*"}}, circularORFs); diff != "" {
+		t.Errorf("FindORFsWithOptions returned unexpected circular ORFs (-want +got):\n%s", diff)
+	}
+	if linearORFs, _ := FindORFs("AAATAAGGGGGGATG", codonTable, 0, true); len(linearORFs) != 0 {
+		t.Errorf("FindORFs should not find ORFs across the origin of a linear sequence, got %v", linearORFs)
+	}
+	// the same ORF on the reverse strand.
+	reverseCircularORFs, _ := FindORFsWithOptions("CATCCCCCCTTATTT", codonTable, ORFOptions{BothStrands: true, Circular: true})
+	if diff := cmp.Diff([]ORF{{Start: 9, End: 18, Strand: -1, Frame: 0, Protein: "MK*"}}, reverseCircularORFs); diff != "" {
+		t.Errorf("FindORFsWithOptions returned unexpected reverse circular ORFs (-want +got):\n%s", diff)
+	}
+	// without start codons the stretch between the only stop and itself is the only ORF.
+	startlessCircularORFs, _ := FindORFsWithOptions("TAAGGGCCC", codonTable, ORFOptions{IgnoreStartCodons: true, Circular: true})
+	if diff := cmp.Diff([]ORF{{Start: 3, End: 12, Strand: 1, Frame: 0, Protein: "GP*"}}, startlessCircularORFs); diff != "" {
+		t.Errorf("FindORFsWithOptions returned unexpected startless circular ORFs (-want +got):\n%s", diff)
+	}
+
+	if _, err := FindORFs("", codonTable, 0, true); err != errEmtpySequenceString {
+		t.Errorf("expected %v, got %v", errEmtpySequenceString, err)
+	}
+	if _, err := FindORFs("ATGTAA", Table{}, 0, true); err != errEmtpyCodonTable {
+		t.Errorf("expected %v, got %v", errEmtpyCodonTable, err)
+	}
+}
+
+func TestSixFrameTranslate(t *testing.T) {
+	translations, err := SixFrameTranslate("ATGAAATAG", GetCodonTable(11))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedTranslations := []SixFrameTranslation{
+		{Strand: 1, Frame: 0, Protein: "MK*"},
+		{Strand: 1, Frame: 1, Protein: "*N"},
+		{Strand: 1, Frame: 2, Protein: "EI"},
+		{Strand: -1, Frame: 0, Protein: "LFH"},
+		{Strand: -1, Frame: 1, Protein: "YF"},
+		{Strand: -1, Frame: 2, Protein: "IS"},
+	}
+	if diff := cmp.Diff(expectedTranslations, translations); diff != "" {
+		t.Errorf("SixFrameTranslate returned unexpected translations (-want +got):\n%s", diff)
+	}
+
+	shortTranslations, _ := SixFrameTranslate("ATGA", GetCodonTable(11))
+	if shortTranslations[1].Protein != "*" || shortTranslations[2].Protein != "" {
+		t.Errorf("SixFrameTranslate should leave frames without a whole codon empty, got %v", shortTranslations)
+	}
+	if _, err := SixFrameTranslate("", GetCodonTable(11)); err != errEmtpySequenceString {
+		t.Errorf("expected %v, got %v", errEmtpySequenceString, err)
+	}
+}
+
+func TestDegenerateBacktranslate(t *testing.T) {
+	codonTable := GetCodonTable(11)
+	degenerateCodons := map[string]string{
+		"M": "ATG",
+		"W": "TGG",
+		"F": "TTY",
+		"K": "AAR",
+		"I": "ATH",
+		"A": "GCN",
+		"L": "YTN",
+		"S": "WSN",
+		"R": "MGN",
+		"*": "TRR",
+	}
+	for aminoAcid, expected := range degenerateCodons {
+		got, err := DegenerateBacktranslate(aminoAcid, codonTable)
+		if err != nil {
+			t.Error(err)
+		}
+		if got != expected {
+			t.Errorf("DegenerateBacktranslate(%q) = %q, want %q", aminoAcid, got, expected)
+		}
+	}
+
+	_, err := DegenerateBacktranslate("MOP", codonTable)
+	assert.EqualError(t, err, invalidAminoAcidError{'O'}.Error())
+
+	if _, err := DegenerateBacktranslate("", codonTable); err != errEmtpyAminoAcidString {
+		t.Error("DegenerateBacktranslate should return an error if given an empty amino acid string")
+	}
+}
+
+func TestDegenerateBacktranslateWithThreshold(t *testing.T) {
+	codonTable := Table{AminoAcids: []AminoAcid{
+		{"L", []Codon{{"CTG", 50}, {"TTA", 14}, {"TTG", 13}, {"CTT", 10}, {"CTC", 10}, {"CTA", 3}}},
+		{"K", []Codon{{"AAA", 0}, {"AAG", 0}}},
+	}}
+	for minCodonFrequency, expected := range map[float64]string{0: "YTNAAR", 0.11: "YTRAAR", 0.2: "CTGAAR", 1: "CTGAAR"} {
+		got, err := DegenerateBacktranslateWithThreshold("LK", codonTable, minCodonFrequency)
+		if err != nil {
+			t.Error(err)
+		}
+		if got != expected {
+			t.Errorf("DegenerateBacktranslateWithThreshold(%q, %.2f) = %q, want %q", "LK", minCodonFrequency, got, expected)
+		}
+	}
+
+	for _, minCodonFrequency := range []float64{-0.1, 1.1} {
+		if _, err := DegenerateBacktranslateWithThreshold("LK", codonTable, minCodonFrequency); err == nil {
+			t.Errorf("Expected an error for a minimum codon frequency of %.2f", minCodonFrequency)
+		}
+	}
+}
+
 /******************************************************************************
 
 JSON related tests begin here.
@@ -236,6 +1015,105 @@ func TestWriteCodonJSON(t *testing.T) {
 
 }
 
+func TestCodonTSV(t *testing.T) {
+	testCodonTable := ReadCodonJSON("../../data/bsub_codon_test.json")
+	tmpDir, err := ioutil.TempDir("", "codon_tsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "bsub.tsv")
+	if err := WriteCodonTSV(testCodonTable, path); err != nil {
+		t.Fatal(err)
+	}
+	readTestCodonTable, err := ReadCodonTSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rows are sorted so amino acids and codons come back in a different order.
+	sortTable := cmp.Options{
+		cmpopts.SortSlices(func(a, b string) bool { return a < b }),
+		cmpopts.SortSlices(func(a, b AminoAcid) bool { return a.Letter < b.Letter }),
+		cmpopts.SortSlices(func(a, b Codon) bool { return a.Triplet < b.Triplet }),
+	}
+	if diff := cmp.Diff(testCodonTable, readTestCodonTable, sortTable); diff != "" {
+		t.Errorf("TSV round trip mismatch (-want +got):\n%s", diff)
+	}
+
+	lines := strings.Split(string(BuildCodonTSV(testCodonTable)), "\n")
+	if lines[0] != "amino_acid\tcodon\tcount\tfrequency\tstart" {
+		t.Errorf("unexpected header %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[len(lines)-2], "*\t") {
+		t.Errorf("expected stop codons at the end, got %q", lines[len(lines)-2])
+	}
+
+	for _, badTSV := range []string{"A\tGCT\t1\n", "A\tGC\t1\t0.5\tfalse\n", "A\tGCT\tmany\t0.5\tfalse\n", "A\tGCT\t1\t0.5\tmaybe\n"} {
+		if _, err := ParseCodonTSV([]byte(badTSV)); err == nil {
+			t.Errorf("expected an error parsing %q", badTSV)
+		}
+	}
+}
+
+func TestCodonUsageTables(t *testing.T) {
+	testCodonTable := ReadCodonJSON("../../data/bsub_codon_test.json")
+	sortTable := cmp.Options{
+		cmpopts.SortSlices(func(a, b string) bool { return a < b }),
+		cmpopts.SortSlices(func(a, b AminoAcid) bool { return a.Letter < b.Letter }),
+		cmpopts.SortSlices(func(a, b Codon) bool { return a.Triplet < b.Triplet }),
+	}
+
+	// both files were written from the counts in bsub_codon_test.json.
+	kazusaCodonTable, err := ReadCodonKazusa("../../data/bsub_kazusa.txt", 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(testCodonTable, kazusaCodonTable, sortTable); diff != "" {
+		t.Errorf("Kazusa table mismatch (-want +got):\n%s", diff)
+	}
+	cutCodonTable, err := ReadCodonCut("../../data/bsub.cut", 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(testCodonTable, cutCodonTable, sortTable); diff != "" {
+		t.Errorf("cut table mismatch (-want +got):\n%s", diff)
+	}
+
+	gcg := `AmAcid  Codon     Number    /1000     Fraction   ..
+
+Gly     GGG     3.00     16.41      0.25
+Gly     GGA     9.00     19.65      0.75
+End     TGA     1.00      1.30      1.00
+`
+	gcgCodonTable, err := ParseCodonCut([]byte(gcg), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Table{
+		StopCodons: []string{"TGA"},
+		AminoAcids: []AminoAcid{{"*", []Codon{{"TGA", 1}}}, {"G", []Codon{{"GGA", 9}, {"GGG", 3}}}},
+	}
+	if diff := cmp.Diff(want, gcgCodonTable); diff != "" {
+		t.Errorf("GCG table mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, badKazusa := range []string{"UUU 17.6(714298)\n", "UUU 17.6(714298)  UUU 17.6(714298)\n"} {
+		if _, err := ParseCodonKazusa([]byte(badKazusa), 11); err == nil {
+			t.Errorf("expected an error parsing %q", badKazusa)
+		}
+	}
+	if _, err := ReadCodonKazusa("../../data/bsub_kazusa.txt", 99); err == nil {
+		t.Error("expected an error for an unknown genetic code")
+	}
+	for _, badCut := range []string{"", "GGG G 0.25 16.41 many\n", "Xyz GGG 3.00 16.41 0.25\n", "GGG G 0.25 16.41 3\nGGG G 0.25 16.41 3\n"} {
+		if _, err := ParseCodonCut([]byte(badCut), 11); err == nil {
+			t.Errorf("expected an error parsing %q", badCut)
+		}
+	}
+}
+
 /******************************************************************************
 
 Codon Compromise + Add related tests begin here.
@@ -0,0 +1,186 @@
+package codon
+
+import (
+	"fmt"
+	"strings"
+)
+
+/******************************************************************************
+
+Degenerate back translation begins here.
+
+Optimize picks a single codon for every amino acid which is what you want when
+you're synthesizing a gene. When you're designing degenerate primers you want
+the opposite: a single DNA sequence that covers every codon that could have
+encoded a protein. IUPAC ambiguity codes let us write that down one position at
+a time, so each amino acid turns into a codon of ambiguity codes.
+
+******************************************************************************/
+
+// iupacBits maps each unambiguous base onto a bit so that sets of bases can be combined with a bitwise or.
+var iupacBits = map[byte]uint8{
+	'A': 1,
+	'C': 2,
+	'G': 4,
+	'T': 8,
+	'U': 8,
+}
+
+// iupacCodes maps a set of bases (as a combination of iupacBits) onto its IUPAC ambiguity code.
+var iupacCodes = [16]byte{
+	0:  '-',
+	1:  'A',
+	2:  'C',
+	3:  'M', // A or C
+	4:  'G',
+	5:  'R', // A or G
+	6:  'S', // C or G
+	7:  'V', // A, C or G
+	8:  'T',
+	9:  'W', // A or T
+	10: 'Y', // C or T
+	11: 'H', // A, C or T
+	12: 'K', // G or T
+	13: 'D', // A, G or T
+	14: 'B', // C, G or T
+	15: 'N', // any base
+}
+
+// iupacBases returns the bases an IUPAC code stands for, or "" if it isn't one.
+func iupacBases(code byte) string {
+	if code == 'U' {
+		return "T"
+	}
+	var bases []byte
+	for bits, iupacCode := range iupacCodes {
+		if iupacCode != code || bits == 0 {
+			continue
+		}
+		for _, base := range []byte("ACGT") {
+			if uint8(bits)&iupacBits[base] != 0 {
+				bases = append(bases, base)
+			}
+		}
+	}
+	return string(bases)
+}
+
+// translateCodon translates an uppercase codon, which may contain IUPAC
+// ambiguity codes. An ambiguous codon is translated to the amino acid encoded
+// by every codon it could stand for, like GCN to alanine, or to X if there
+// isn't one.
+func translateCodon(translationTable map[string]string, codon string) string {
+	if aminoAcid, ok := translationTable[codon]; ok {
+		return aminoAcid
+	}
+	aminoAcid := ""
+	for _, first := range []byte(iupacBases(codon[0])) {
+		for _, second := range []byte(iupacBases(codon[1])) {
+			for _, third := range []byte(iupacBases(codon[2])) {
+				translated, ok := translationTable[string([]byte{first, second, third})]
+				if !ok || (aminoAcid != "" && translated != aminoAcid) {
+					return "X"
+				}
+				aminoAcid = translated
+			}
+		}
+	}
+	if aminoAcid == "" {
+		return "X"
+	}
+	return aminoAcid
+}
+
+// DegenerateBacktranslate takes an amino acid sequence and a Table and returns a
+// single degenerate DNA sequence that covers every synonymous codon of every
+// residue. Each residue becomes the smallest codon of IUPAC ambiguity codes that
+// contains all of its codons.
+//
+// Codons are combined one position at a time, so amino acids with codons in two
+// different blocks of the genetic code are over covered. Leucine, for example,
+// has the codons TTA, TTG and CTN which become YTN, and YTN also includes the
+// phenylalanine codons TTT and TTC. There is no single degenerate codon for
+// leucine that doesn't, so keep this in mind when picking primer regions.
+func DegenerateBacktranslate(aminoAcids string, codonTable Table) (string, error) {
+	return DegenerateBacktranslateWithThreshold(aminoAcids, codonTable, 0)
+}
+
+// DegenerateBacktranslateWithThreshold is DegenerateBacktranslate for a
+// weighted Table that only covers the codons used at least minCodonFrequency
+// (from 0 to 1) of the time for their amino acid. Leaving out rare codons
+// gives much less degenerate primers that still bind almost every gene in
+// the organism. A minCodonFrequency of 0 covers every codon. If no codon for
+// an amino acid passes the threshold its most used codons are covered instead.
+func DegenerateBacktranslateWithThreshold(aminoAcids string, codonTable Table, minCodonFrequency float64) (string, error) {
+	if len(codonTable.StartCodons) == 0 && len(codonTable.StopCodons) == 0 && len(codonTable.AminoAcids) == 0 {
+		return "", errEmtpyCodonTable
+	}
+	if len(aminoAcids) == 0 {
+		return "", errEmtpyAminoAcidString
+	}
+
+	if minCodonFrequency < 0 || minCodonFrequency > 1 {
+		return "", fmt.Errorf("invalid minimum codon frequency of %.3f", minCodonFrequency)
+	}
+
+	degenerateCodons := make(map[string]string)
+	for _, aminoAcid := range codonTable.AminoAcids {
+		degenerateCodons[aminoAcid.Letter] = degenerateCodon(frequentCodons(aminoAcid.Codons, minCodonFrequency))
+	}
+
+	var sequence strings.Builder
+	for _, aminoAcid := range strings.ToUpper(aminoAcids) {
+		codon, ok := degenerateCodons[string(aminoAcid)]
+		if !ok || codon == "" {
+			return "", invalidAminoAcidError{aminoAcid}
+		}
+		sequence.WriteString(codon)
+	}
+	return sequence.String(), nil
+}
+
+// frequentCodons returns the codons used at least minCodonFrequency of the
+// time, or the most used codons if none are.
+func frequentCodons(codons []Codon, minCodonFrequency float64) []Codon {
+	if minCodonFrequency == 0 {
+		return codons
+	}
+	total, maxWeight := 0, 0
+	for _, codon := range codons {
+		total += codon.Weight
+		if codon.Weight > maxWeight {
+			maxWeight = codon.Weight
+		}
+	}
+	var frequent, mostUsed []Codon
+	for _, codon := range codons {
+		if total > 0 && float64(codon.Weight)/float64(total) >= minCodonFrequency {
+			frequent = append(frequent, codon)
+		}
+		if codon.Weight == maxWeight {
+			mostUsed = append(mostUsed, codon)
+		}
+	}
+	if len(frequent) == 0 {
+		return mostUsed
+	}
+	return frequent
+}
+
+// degenerateCodon returns the smallest codon of IUPAC ambiguity codes that covers every given codon.
+func degenerateCodon(codons []Codon) string {
+	var positions [3]uint8
+	for _, codon := range codons {
+		triplet := strings.ToUpper(codon.Triplet)
+		if len(triplet) != 3 {
+			continue
+		}
+		for position := 0; position < 3; position++ {
+			positions[position] |= iupacBits[triplet[position]]
+		}
+	}
+	if positions[0] == 0 || positions[1] == 0 || positions[2] == 0 {
+		return ""
+	}
+	return string([]byte{iupacCodes[positions[0]], iupacCodes[positions[1]], iupacCodes[positions[2]]})
+}
@@ -20,6 +20,33 @@ func ExampleTranslate() {
 	// output: true
 }
 
+func ExampleTranslateWithOptions() {
+	// a CDS starting with the alternative start codon GTG, read through to its stop.
+	options := codon.TranslateOptions{StartCodon: true, StopAtStop: true}
+	translation, _ := codon.TranslateWithOptions("GTGAAAAAGTAACCC", codon.GetCodonTable(11), options)
+
+	fmt.Println(translation)
+	// Output: MKK*
+}
+
+func ExampleTranslateRange() {
+	// a coding sequence with some untranslated sequence around it.
+	sequence := "GGGATGGCTAGCAAAGGATAAGGG"
+
+	translation, _ := codon.TranslateRange(sequence, codon.GetCodonTable(11), 3, 21, 0)
+	fmt.Println(translation)
+	// Output: MASKG*
+}
+
+func ExampleTranslateChecked() {
+	// a frameshift has introduced a premature TAG stop codon.
+	sequence := "ATGGCTAGCAAAGGATAGAACTTTTCACTGGAGTTGTCCCAATTCTTGTTGAATTAG"
+
+	_, internalStops, _ := codon.TranslateChecked(sequence, codon.GetCodonTable(11))
+	fmt.Println(internalStops)
+	// Output: [15]
+}
+
 func ExampleOptimize() {
 
 	gfpTranslation := "MASKGEELFTGVVPILVELDGDVNGHKFSVSGEGEGDATYGKLTLKFICTTGKLPVPWPTLVTTFSYGVQCFSRYPDHMKRHDFFKSAMPEGYVQERTISFKDDGNYKTRAEVKFEGDTLVNRIELKGIDFKEDGNILGHKLEYNYNSHNVYITADKQKNGIKANFKIRHNIEDGSVQLADHYQQNTPIGDGPVLLPDNHYLSTQSALSKDPNEKRDHMVLLEFVTAAGITHGMDELYK*"
@@ -27,30 +54,214 @@ func ExampleOptimize() {
 	sequence, _ := genbank.Read("../../data/puc19.gbk")
 	codonTable := codon.GetCodonTable(11)
 
-	// a string builder to build a single concatenated string of all coding regions
-	var codingRegionsBuilder strings.Builder
-
-	// iterate through the features of the genbank file and if the feature is a coding region, append the sequence to the string builder
+	// collect the sequence of every coding region in the genbank file
+	var codingRegions []string
 	for _, feature := range sequence.Features {
 		if feature.Type == "CDS" {
 			sequence, _ := feature.GetSequence()
-			codingRegionsBuilder.WriteString(sequence)
+			codingRegions = append(codingRegions, sequence)
 		}
 	}
 
-	// get the concatenated sequence string of the coding regions
-	codingRegions := codingRegionsBuilder.String()
-
 	// weight our codon optimization table using the regions we collected from the genbank file above
-	optimizationTable := codonTable.OptimizeTable(codingRegions)
+	optimizationTable, _ := codonTable.OptimizeTableFromSequences(codingRegions)
 
-	optimizedSequence, _ := codon.Optimize(gfpTranslation, optimizationTable)
+	optimizedSequence, _ := codon.Optimize(gfpTranslation, optimizationTable, codon.OptimizeOptions{})
 	optimizedSequenceTranslation, _ := codon.Translate(optimizedSequence, optimizationTable)
 
 	fmt.Println(optimizedSequenceTranslation == gfpTranslation)
 	// output: true
 }
 
+func ExampleOptimize_gcContent() {
+	gfpTranslation := "MASKGEELFTGVVPILVELDGDVNGHKFSVSGEGEGDATYGKLTLKFICTTGKLPVPWPTLVTTFSYGVQCFSRYPDHMKRHDFFKSAMPEGYVQERTISFKDDGNYKTRAEVKFEGDTLVNRIELKGIDFKEDGNILGHKLEYNYNSHNVYITADKQKNGIKANFKIRHNIEDGSVQLADHYQQNTPIGDGPVLLPDNHYLSTQSALSKDPNEKRDHMVLLEFVTAAGITHGMDELYK*"
+	codonTable := codon.GetCodonTable(11)
+
+	// keep the GC content of the optimized sequence between 45% and 55%, and
+	// between 35% and 65% in every 50 bases.
+	options := codon.OptimizeOptions{MinGC: 0.45, MaxGC: 0.55, GCWindow: 50, MinWindowGC: 0.35, MaxWindowGC: 0.65}
+	optimizedSequence, err := codon.Optimize(gfpTranslation, codonTable, options)
+
+	fmt.Println(err == nil, len(optimizedSequence) == len(gfpTranslation)*3)
+	// Output: true true
+}
+
+func ExampleOptimize_forbiddenMotifs() {
+	gfpTranslation := "MASKGEELFTGVVPILVELDGDVNGHKFSVSGEGEGDATYGKLTLKFICTTGKLPVPWPTLVTTFSYGVQCFSRYPDHMKRHDFFKSAMPEGYVQERTISFKDDGNYKTRAEVKFEGDTLVNRIELKGIDFKEDGNILGHKLEYNYNSHNVYITADKQKNGIKANFKIRHNIEDGSVQLADHYQQNTPIGDGPVLLPDNHYLSTQSALSKDPNEKRDHMVLLEFVTAAGITHGMDELYK*"
+	codonTable := codon.GetCodonTable(11)
+
+	// keep BsaI sites out of the gene so it can be GoldenGate cloned.
+	options := codon.OptimizeOptions{ForbiddenMotifs: []string{"GGTCTC"}}
+	optimizedSequence, err := codon.Optimize(gfpTranslation, codonTable, options)
+
+	fmt.Println(err == nil, strings.Contains(optimizedSequence, "GGTCTC"), strings.Contains(optimizedSequence, "GAGACC"))
+	// Output: true false false
+}
+
+func ExampleOptimize_mostFrequent() {
+	codonTable := codon.ReadCodonJSON("../../data/bsub_codon_test.json")
+
+	// always use Bacillus subtilis' favourite codons, so no seed is needed to get the same sequence every time.
+	options := codon.OptimizeOptions{Strategy: codon.StrategyMostFrequent}
+	optimizedSequence, _ := codon.Optimize("MASKGEELF*", codonTable, options)
+
+	fmt.Println(optimizedSequence)
+	// Output: ATGGCATCAAAAGGCGAAGAACTGTTTTAA
+}
+
+func ExampleDegenerateBacktranslate() {
+	// the N-terminus of GFP
+	degenerateSequence, _ := codon.DegenerateBacktranslate("MASKGEE", codon.GetCodonTable(11))
+
+	fmt.Println(degenerateSequence)
+	// Output: ATGGCNWSNAARGGNGARGAR
+}
+
+func ExampleDegenerateBacktranslateWithThreshold() {
+	// the N-terminus of GFP, only covering codons Bacillus subtilis uses at least 20% of the time.
+	codonTable := codon.ReadCodonJSON("../../data/bsub_codon_test.json")
+	degenerateSequence, _ := codon.DegenerateBacktranslateWithThreshold("MASKGEE", codonTable, 0.2)
+
+	fmt.Println(degenerateSequence)
+	// Output: ATGGCNWSHAARGGMGARGAR
+}
+
+func ExampleTable_Stats() {
+	// weight a codon table using a short stretch of alanine codons.
+	codonTable := codon.GetCodonTable(11).OptimizeTable("GCTGCTGCCGCA")
+	stats := codonTable.Stats()
+
+	fmt.Println(stats["GCT"].Count, stats["GCT"].Fraction, stats["GCT"].RSCU)
+	// Output: 2 0.5 2
+}
+
+func ExampleCAI() {
+	// weight a codon table using a short stretch of alanine codons.
+	codonTable := codon.GetCodonTable(11).OptimizeTable("GCTGCTGCCGCA")
+	cai, _ := codon.CAI("ATGGCTGCCGCGTAA", codonTable)
+
+	fmt.Printf("%.3f %.2f\n", cai, codonTable.RelativeAdaptiveness()["GCG"])
+	// Output: 0.500 0.25
+}
+
+func ExampleHarmonize() {
+	// alanine codon usage in the organism a gene came from and the one it will be expressed in.
+	sourceTable := codon.Table{AminoAcids: []codon.AminoAcid{{Letter: "A", Codons: []codon.Codon{{Triplet: "GCT", Weight: 60}, {Triplet: "GCC", Weight: 28}, {Triplet: "GCA", Weight: 12}, {Triplet: "GCG", Weight: 0}}}}}
+	targetTable := codon.Table{AminoAcids: []codon.AminoAcid{{Letter: "A", Codons: []codon.Codon{{Triplet: "GCT", Weight: 5}, {Triplet: "GCC", Weight: 25}, {Triplet: "GCA", Weight: 15}, {Triplet: "GCG", Weight: 55}}}}}
+
+	// the source's rare GCG becomes the target's rare GCT, instead of its favourite GCG.
+	harmonized, _ := codon.Harmonize("GCTGCG", sourceTable, targetTable)
+	fmt.Println(harmonized)
+	// Output: GCGGCT
+}
+
+func ExampleOptimizeBatch() {
+	proteins := []string{"MASKGEE", "MKRIST", "MVKVYAP"}
+
+	// seeding the batch makes every sequence reproducible.
+	sequences, errs := codon.OptimizeBatch(proteins, codon.GetCodonTable(11), codon.OptimizeOptions{}, 42)
+	for index, sequence := range sequences {
+		translation, _ := codon.Translate(sequence, codon.GetCodonTable(11))
+		fmt.Println(translation == proteins[index], errs[index])
+	}
+	// Output:
+	// true <nil>
+	// true <nil>
+	// true <nil>
+}
+
+func ExampleTable_CodonPairScore() {
+	sequence, _ := genbank.Read("../../data/puc19.gbk")
+	var codingRegions []string
+	for _, feature := range sequence.Features {
+		if feature.Type == "CDS" {
+			sequence, _ := feature.GetSequence()
+			codingRegions = append(codingRegions, sequence)
+		}
+	}
+	codonTable, _ := codon.GetCodonTable(11).OptimizeTableFromSequences(codingRegions)
+
+	// design two sequences for the same protein, one using the codon pairs puc19
+	// prefers and one using the pairs it avoids.
+	protein := "MSKGEELFTGVVPILVELDGDVNGHKFSVSGEGEGDATYGK*"
+	optimized, _ := codon.Optimize(protein, codonTable, codon.OptimizeOptions{CodonPairs: codon.CodonPairsOptimized})
+	deoptimized, _ := codon.Optimize(protein, codonTable, codon.OptimizeOptions{CodonPairs: codon.CodonPairsDeoptimized})
+
+	optimizedScore, _ := codonTable.CodonPairScore(optimized)
+	deoptimizedScore, _ := codonTable.CodonPairScore(deoptimized)
+	fmt.Println(optimizedScore > deoptimizedScore)
+	// Output: true
+}
+
+func ExampleTable_Diff() {
+	// compare codon usage in Bacillus subtilis and Pichia pastoris.
+	bsubTable := codon.ReadCodonJSON("../../data/bsub_codon_test.json")
+	pichiaTable := codon.ReadCodonJSON("../../data/pichiaTable.json")
+
+	diff := bsubTable.Diff(pichiaTable)
+	for _, triplet := range diff.MostDivergentCodons(3) {
+		fmt.Printf("%s %+.2f\n", triplet, diff[triplet])
+	}
+	// Output:
+	// CCG +0.33
+	// TAA +0.23
+	// ACT -0.20
+}
+
+func ExampleFindORFs() {
+	puc19, _ := genbank.Read("../../data/puc19.gbk")
+
+	// the only ORF longer than 600 bases is bla, the beta-lactamase gene that gives pUC19 its ampicillin resistance.
+	orfs, _ := codon.FindORFs(puc19.Sequence, codon.GetCodonTable(11), 600, true)
+	for _, orf := range orfs {
+		fmt.Println(orf.Start, orf.End, orf.Strand, len(orf.Protein))
+	}
+	// Output: 1283 2144 1 287
+}
+
+func ExampleSixFrameTranslate() {
+	translations, _ := codon.SixFrameTranslate("ATGAAATAG", codon.GetCodonTable(11))
+	for _, translation := range translations {
+		fmt.Println(translation.Strand, translation.Frame, translation.Protein)
+	}
+	// Output:
+	// 1 0 MK*
+	// 1 1 *N
+	// 1 2 EI
+	// -1 0 LFH
+	// -1 1 YF
+	// -1 2 IS
+}
+
+func ExampleBuildCodonTSV() {
+	// weight a codon table using a short stretch of alanine codons.
+	codonTable := codon.GetCodonTable(11).OptimizeTable("GCTGCTGCCGCA")
+	tsv := strings.Split(string(codon.BuildCodonTSV(codonTable)), "\n")
+
+	fmt.Println(strings.Join(tsv[:5], "\n"))
+	// Output:
+	// amino_acid	codon	count	frequency	start
+	// A	GCT	2	0.5000	false
+	// A	GCA	1	0.2500	false
+	// A	GCC	1	0.2500	false
+	// A	GCG	0	0.0000	false
+}
+
+func ExampleReadCodonKazusa() {
+	// Kazusa tables don't list amino acids, so they come from the bacterial genetic code.
+	codontable, _ := codon.ReadCodonKazusa("../../data/bsub_kazusa.txt", 11)
+
+	fmt.Println(codontable.AminoAcids[0].Letter, codontable.AminoAcids[0].Codons[0])
+	// Output: * {TAA 2712}
+}
+
+func ExampleReadCodonCut() {
+	codontable, _ := codon.ReadCodonCut("../../data/bsub.cut", 11)
+
+	fmt.Println(codontable.AminoAcids[1].Letter, codontable.AminoAcids[1].Codons[0])
+	// Output: A {GCA 26889}
+}
+
 func ExampleReadCodonJSON() {
 	codontable := codon.ReadCodonJSON("../../data/bsub_codon_test.json")
 
@@ -0,0 +1,201 @@
+package codon
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/TimothyStiles/poly/transform"
+)
+
+/******************************************************************************
+
+Open reading frame finding begins here.
+
+An open reading frame (ORF) is a stretch of sequence that starts with a start
+codon and runs without interruption until a stop codon in the same frame. Long
+ORFs are unlikely to happen by chance so finding them is the first step of most
+gene prediction, and it's also a handy way of checking that a part you've
+designed doesn't hide a second protein on the other strand.
+
+******************************************************************************/
+
+// ORF is an open reading frame found by FindORFs.
+type ORF struct {
+	Start   int    `json:"start"`   // 0-based start on the forward strand.
+	End     int    `json:"end"`     // exclusive end on the forward strand, including the stop codon.
+	Strand  int    `json:"strand"`  // 1 for the forward strand and -1 for the reverse strand.
+	Frame   int    `json:"frame"`   // 0, 1 or 2 bases from the start of the strand the ORF was read from.
+	Protein string `json:"protein"` // translation of the ORF including its trailing stop.
+}
+
+// ORFOptions changes how FindORFsWithOptions searches for ORFs.
+type ORFOptions struct {
+	MinLength   int  // minimum length of an ORF in nucleotides, including its stop codon.
+	BothStrands bool // also search the reverse complement of the sequence.
+	// IgnoreStartCodons lets an ORF begin at the first codon after the previous
+	// in-frame stop (or the beginning of the sequence) instead of at a start
+	// codon. This is useful for finding ORFs in fragments of genes.
+	IgnoreStartCodons bool
+	// Circular treats the sequence as a plasmid or circular genome, so ORFs
+	// can run across the origin. Their End is then past the end of the
+	// sequence and the ORF is sequence[Start:] + sequence[:End-len(sequence)].
+	Circular bool
+}
+
+// FindORFs finds every open reading frame at least minLength nucleotides long
+// that begins with one of the table's start codons. See FindORFsWithOptions.
+func FindORFs(sequence string, codonTable Table, minLength int, bothStrands bool) ([]ORF, error) {
+	return FindORFsWithOptions(sequence, codonTable, ORFOptions{MinLength: minLength, BothStrands: bothStrands})
+}
+
+// FindORFsWithOptions finds open reading frames in all three frames of a
+// sequence, and of its reverse complement if options.BothStrands is set. An ORF
+// runs from a start codon to the next in-frame stop codon. When several start
+// codons share a stop only the longest ORF is reported, but ORFs in different
+// frames or on different strands may overlap freely. ORFs that run off the end
+// of a linear sequence without a stop are not reported, and neither are ORFs of
+// circular sequences that go all the way around without one.
+//
+// ORFs are sorted by their position on the forward strand. Coordinates are
+// always on the forward strand, so an ORF on the reverse strand is the reverse
+// complement of sequence[Start:End].
+func FindORFsWithOptions(sequence string, codonTable Table, options ORFOptions) ([]ORF, error) {
+	if len(codonTable.StartCodons) == 0 && len(codonTable.StopCodons) == 0 && len(codonTable.AminoAcids) == 0 {
+		return nil, errEmtpyCodonTable
+	}
+	if len(sequence) == 0 {
+		return nil, errEmtpySequenceString
+	}
+
+	sequence = strings.ToUpper(sequence)
+	orfs, err := findStrandORFs(sequence, codonTable, options, 1)
+	if err != nil {
+		return nil, err
+	}
+	if options.BothStrands {
+		reverseORFs, err := findStrandORFs(transform.ReverseComplement(sequence), codonTable, options, -1)
+		if err != nil {
+			return nil, err
+		}
+		orfs = append(orfs, reverseORFs...)
+	}
+
+	sort.SliceStable(orfs, func(i, j int) bool {
+		if orfs[i].Start != orfs[j].Start {
+			return orfs[i].Start < orfs[j].Start
+		}
+		return orfs[i].End < orfs[j].End
+	})
+	return orfs, nil
+}
+
+// SixFrameTranslation is the translation of one of the six reading frames of a sequence.
+type SixFrameTranslation struct {
+	Strand  int    `json:"strand"`  // 1 for the forward strand and -1 for the reverse strand.
+	Frame   int    `json:"frame"`   // 0, 1 or 2 bases from the start of the strand.
+	Protein string `json:"protein"` // translation of every whole codon in the frame.
+}
+
+// SixFrameTranslate translates all three reading frames of a sequence and of
+// its reverse complement, in the order +1, +2, +3, -1, -2, -3. Bases left over
+// at the end of a frame are ignored, and frames without a whole codon have an
+// empty translation.
+func SixFrameTranslate(sequence string, codonTable Table) ([]SixFrameTranslation, error) {
+	if len(codonTable.StartCodons) == 0 && len(codonTable.StopCodons) == 0 && len(codonTable.AminoAcids) == 0 {
+		return nil, errEmtpyCodonTable
+	}
+	if len(sequence) == 0 {
+		return nil, errEmtpySequenceString
+	}
+
+	var translations []SixFrameTranslation
+	for _, strand := range []int{1, -1} {
+		strandSequence := sequence
+		if strand == -1 {
+			strandSequence = transform.ReverseComplement(strings.ToUpper(sequence))
+		}
+		for frame := 0; frame < 3; frame++ {
+			translation := SixFrameTranslation{Strand: strand, Frame: frame}
+			if frame+3 <= len(strandSequence) {
+				protein, err := Translate(strandSequence[frame:], codonTable)
+				if err != nil {
+					return nil, err
+				}
+				translation.Protein = protein
+			}
+			translations = append(translations, translation)
+		}
+	}
+	return translations, nil
+}
+
+// findStrandORFs finds the ORFs in all three frames of a single strand and maps them onto forward strand coordinates.
+func findStrandORFs(sequence string, codonTable Table, options ORFOptions, strand int) ([]ORF, error) {
+	startCodons := make(map[string]bool)
+	for _, startCodon := range codonTable.StartCodons {
+		startCodons[strings.ToUpper(startCodon)] = true
+	}
+	stopCodons := make(map[string]bool)
+	for _, stopCodon := range codonTable.StopCodons {
+		stopCodons[strings.ToUpper(stopCodon)] = true
+	}
+
+	// circular sequences are read twice over so ORFs can cross the origin.
+	length := len(sequence)
+	if options.Circular {
+		sequence += sequence
+	}
+	orfsByEnd := make(map[int]int) // index of the ORF ending at each position of a circular sequence.
+
+	var orfs []ORF
+	for frame := 0; frame < 3; frame++ {
+		orfStart := -1
+		if options.IgnoreStartCodons {
+			orfStart = frame
+		}
+		for index := frame; index+3 <= len(sequence); index += 3 {
+			// on the second time around every ORF has already been found.
+			if options.Circular && (orfStart >= length || (orfStart == -1 && index >= length)) {
+				break
+			}
+			codon := sequence[index : index+3]
+			if orfStart == -1 && startCodons[codon] {
+				orfStart = index
+			}
+			if !stopCodons[codon] {
+				continue
+			}
+
+			if orfStart != -1 && index+3-orfStart >= options.MinLength && index+3-orfStart <= length {
+				protein, err := Translate(sequence[orfStart:index+3], codonTable)
+				if err != nil {
+					return nil, err
+				}
+				orf := ORF{Start: orfStart, End: index + 3, Strand: strand, Frame: frame, Protein: protein}
+				if strand == -1 {
+					orf.Start, orf.End = length-orf.End, length-orf.Start
+					if orf.Start < 0 {
+						orf.Start, orf.End = orf.Start+length, orf.End+length
+					}
+				}
+
+				// ORFs of circular sequences can be found once on each side
+				// of the origin with the same stop, so only keep the longest.
+				if !options.Circular {
+					orfs = append(orfs, orf)
+				} else if previous, ok := orfsByEnd[(index+3)%length]; !ok {
+					orfsByEnd[(index+3)%length] = len(orfs)
+					orfs = append(orfs, orf)
+				} else if len(orf.Protein) > len(orfs[previous].Protein) {
+					orfs[previous] = orf
+				}
+			}
+
+			orfStart = -1
+			if options.IgnoreStartCodons {
+				orfStart = index + 3
+			}
+		}
+	}
+	return orfs, nil
+}
@@ -0,0 +1,210 @@
+package codon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/******************************************************************************
+
+Codon usage table parsing begins here.
+
+Weighting a Table with OptimizeTable needs the coding sequences of an
+organism, but for most organisms someone has already done the counting and
+published a codon usage table. The two formats you'll run into are:
+
+- The Kazusa codon usage database (https://www.kazusa.or.jp/codon/) format,
+  which CoCoPUTs can also export. It lists all 64 codons in a grid along with
+  their frequency per thousand codons and their count in brackets:
+
+	UUU 17.6(714298)  UCU 15.2(618711)  UAU 12.2(495699)  UGU 10.6(430311)
+
+  Some exports put the amino acid and its fraction after the codon as well.
+
+- GCG codon frequency tables and the EMBOSS "cut" files derived from them,
+  with one codon per line. EMBOSS writes the codon, the one letter amino acid,
+  fraction, frequency per thousand and count, while GCG writes the three
+  letter amino acid, codon, count, frequency per thousand and fraction.
+
+Both give counts, which become codon weights. Kazusa tables don't say which
+amino acid each codon encodes, so like the start codons of both formats that
+comes from the NCBI genetic code the organism uses.
+
+******************************************************************************/
+
+// kazusaCodonRegex matches a codon and its count in a Kazusa codon usage
+// table, with or without the amino acid and fraction columns.
+var kazusaCodonRegex = regexp.MustCompile(`([ACGTU]{3})\s+(?:[A-Z*]\s+[\d.]+\s+)?[\d.]+\s*\(\s*(\d+)\s*\)`)
+
+// threeLetterAminoAcids maps the three letter amino acid codes used by GCG to one letter codes.
+var threeLetterAminoAcids = map[string]string{
+	"ALA": "A", "ARG": "R", "ASN": "N", "ASP": "D", "CYS": "C", "GLN": "Q", "GLU": "E",
+	"GLY": "G", "HIS": "H", "ILE": "I", "LEU": "L", "LYS": "K", "MET": "M", "PHE": "F",
+	"PRO": "P", "SER": "S", "THR": "T", "TRP": "W", "TYR": "Y", "VAL": "V", "SEC": "U",
+	"PYL": "O", "END": "*", "TER": "*", "STP": "*", "STOP": "*",
+}
+
+// ParseCodonKazusa parses a codon usage table in the Kazusa format, weighting
+// each codon by its count. Amino acids and start and stop codons come from the
+// NCBI genetic code numbered geneticCode, 11 for bacteria. Codons may be
+// written with U or T and every one of the 64 must be in the table.
+func ParseCodonKazusa(file []byte, geneticCode int) (Table, error) {
+	counts := make(map[string]int)
+	for _, match := range kazusaCodonRegex.FindAllStringSubmatch(string(file), -1) {
+		triplet := strings.ReplaceAll(match[1], "U", "T")
+		if _, ok := counts[triplet]; ok {
+			return Table{}, fmt.Errorf("codon %s is in the table more than once", match[1])
+		}
+		count, err := strconv.Atoi(match[2])
+		if err != nil {
+			return Table{}, fmt.Errorf("invalid count %q for codon %s", match[2], match[1])
+		}
+		counts[triplet] = count
+	}
+	if len(counts) != 64 {
+		return Table{}, fmt.Errorf("expected 64 codons, found %d", len(counts))
+	}
+
+	geneticCodeTable, ok := defaultCodonTablesByNumber[geneticCode]
+	if !ok {
+		return Table{}, fmt.Errorf("unknown genetic code %d", geneticCode)
+	}
+	codontable := Table{
+		StartCodons: append([]string{}, geneticCodeTable.StartCodons...),
+		StopCodons:  append([]string{}, geneticCodeTable.StopCodons...),
+	}
+	for _, aminoAcid := range geneticCodeTable.AminoAcids {
+		weighted := AminoAcid{Letter: aminoAcid.Letter}
+		for _, codon := range aminoAcid.Codons {
+			weighted.Codons = append(weighted.Codons, Codon{codon.Triplet, counts[codon.Triplet]})
+		}
+		codontable.AminoAcids = append(codontable.AminoAcids, weighted)
+	}
+	sortAminoAcids(codontable.AminoAcids)
+	return codontable, nil
+}
+
+// ReadCodonKazusa reads a Kazusa codon usage table file.
+func ReadCodonKazusa(path string, geneticCode int) (Table, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Table{}, err
+	}
+	return ParseCodonKazusa(file, geneticCode)
+}
+
+// ParseCodonCut parses a codon usage table in the EMBOSS cut or GCG codon
+// frequency format, weighting each codon by its count. Amino acids come from
+// the file, with codons of stop amino acids ("*", "End", "Ter" or "Stp")
+// becoming the table's stop codons, and the start codons are those of the
+// NCBI genetic code numbered geneticCode. Comment lines starting with "#" and
+// lines that don't describe a codon, like GCG's header, are skipped.
+func ParseCodonCut(file []byte, geneticCode int) (Table, error) {
+	geneticCodeTable, ok := defaultCodonTablesByNumber[geneticCode]
+	if !ok {
+		return Table{}, fmt.Errorf("unknown genetic code %d", geneticCode)
+	}
+
+	var codontable Table
+	aminoAcidIndices := make(map[string]int)
+	seen := make(map[string]bool)
+	for lineNumber, line := range strings.Split(strings.ReplaceAll(string(file), "\r\n", "\n"), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		// EMBOSS lines start with the codon, GCG lines with the amino acid.
+		var letter, triplet, countField string
+		switch {
+		case isTriplet(fields[0]) && len(fields) >= 5:
+			letter, triplet, countField = strings.ToUpper(fields[1]), fields[0], fields[4]
+		case isTriplet(fields[1]):
+			three, ok := threeLetterAminoAcids[strings.ToUpper(fields[0])]
+			if !ok {
+				return Table{}, fmt.Errorf("line %d: unknown amino acid %q", lineNumber+1, fields[0])
+			}
+			letter, triplet, countField = three, fields[1], fields[2]
+		default:
+			continue
+		}
+		triplet = strings.ReplaceAll(strings.ToUpper(triplet), "U", "T")
+		if seen[triplet] {
+			return Table{}, fmt.Errorf("line %d: codon %s is in the table more than once", lineNumber+1, triplet)
+		}
+		seen[triplet] = true
+		count, err := strconv.ParseFloat(countField, 64)
+		if err != nil || count < 0 {
+			return Table{}, fmt.Errorf("line %d: invalid count %q", lineNumber+1, countField)
+		}
+
+		aminoAcidIndex, ok := aminoAcidIndices[letter]
+		if !ok {
+			aminoAcidIndex = len(codontable.AminoAcids)
+			aminoAcidIndices[letter] = aminoAcidIndex
+			codontable.AminoAcids = append(codontable.AminoAcids, AminoAcid{Letter: letter})
+		}
+		codontable.AminoAcids[aminoAcidIndex].Codons = append(codontable.AminoAcids[aminoAcidIndex].Codons, Codon{triplet, int(math.Round(count))})
+		if letter == "*" {
+			codontable.StopCodons = append(codontable.StopCodons, triplet)
+		}
+	}
+	if len(seen) == 0 {
+		return Table{}, errEmtpyCodonTable
+	}
+
+	for _, startCodon := range geneticCodeTable.StartCodons {
+		if seen[startCodon] {
+			codontable.StartCodons = append(codontable.StartCodons, startCodon)
+		}
+	}
+	sortAminoAcids(codontable.AminoAcids)
+	return codontable, nil
+}
+
+// ReadCodonCut reads an EMBOSS cut or GCG codon frequency file.
+func ReadCodonCut(path string, geneticCode int) (Table, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Table{}, err
+	}
+	return ParseCodonCut(file, geneticCode)
+}
+
+// isTriplet returns whether a field is a codon written in DNA or RNA.
+func isTriplet(field string) bool {
+	if len(field) != 3 {
+		return false
+	}
+	for _, base := range strings.ToUpper(field) {
+		if !strings.ContainsRune("ACGTU", base) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortAminoAcids sorts amino acids by letter and their codons by triplet, so
+// parsed tables come out the same every time.
+func sortAminoAcids(aminoAcids []AminoAcid) {
+	sort.Slice(aminoAcids, func(i, j int) bool {
+		return aminoAcids[i].Letter < aminoAcids[j].Letter
+	})
+	for _, aminoAcid := range aminoAcids {
+		codons := aminoAcid.Codons
+		sort.Slice(codons, func(i, j int) bool {
+			return codons[i].Triplet < codons[j].Triplet
+		})
+	}
+}
+
+/******************************************************************************
+
+Codon usage table parsing ends here.
+
+******************************************************************************/
@@ -0,0 +1,73 @@
+package protein_test
+
+import (
+	"fmt"
+
+	"github.com/TimothyStiles/poly/synthesis/codon"
+	"github.com/TimothyStiles/poly/synthesis/protein"
+)
+
+// This example shows how to find the weight, isoelectric point and other
+// properties of a protein after translating it.
+func Example_basic() {
+	gfp := "ATGGCTAGCAAAGGAGAAGAACTTTTCACTGGAGTTGTCCCAATTCTTGTTGAATTAGATGGTGATGTTAATGGGCACAAATTTTCTGTCAGTGGAGAGGGTGAAGGTGATGCTACATACGGAAAGCTTACCCTTAAATTTATTTGCACTACTGGAAAACTACCTGTTCCATGGCCAACACTTGTCACTACTTTCTCTTATGGTGTTCAATGCTTTTCCCGTTATCCGGATCATATGAAACGGCATGACTTTTTCAAGAGTGCCATGCCCGAAGGTTATGTACAGGAACGCACTATATCTTTCAAAGATGACGGGAACTACAAGACGCGTGCTGAAGTCAAGTTTGAAGGTGATACCCTTGTTAATCGTATCGAGTTAAAAGGTATTGATTTTAAAGAAGATGGAAACATTCTCGGACACAAACTCGAGTACAACTATAACTCACACAATGTATACATCACGGCAGACAAACAAAAGAATGGAATCAAAGCTAACTTCAAAATTCGCCACAACATTGAAGATGGATCCGTTCAACTAGCAGACCATTATCAACAAAATACTCCAATTGGCGATGGCCCTGTCCTTTTACCAGACAACCATTACCTGTCGACACAATCTGCCCTTTCGAAAGATCCCAACGAAAAGCGTGACCACATGGTCCTTCTTGAGTTTGTAACTGCTGCTGGGATTACACATGGCATGGATGAGCTCTACAAATAA"
+	gfpProtein, _ := codon.Translate(gfp, codon.GetCodonTable(11))
+
+	weight, _ := protein.MolecularWeight(gfpProtein)
+	isoelectricPoint, _ := protein.IsoelectricPoint(gfpProtein)
+	extinction, _, _ := protein.ExtinctionCoefficient(gfpProtein)
+	gravy, _ := protein.Gravy(gfpProtein)
+	instability, _ := protein.InstabilityIndex(gfpProtein)
+
+	fmt.Printf("%.0f Da, pI %.1f\n", weight, isoelectricPoint)
+	fmt.Printf("e280 %.0f, GRAVY %.2f, instability %.1f\n", extinction, gravy, instability)
+	// Output:
+	// 26867 Da, pI 6.2
+	// e280 21890, GRAVY -0.55, instability 30.7
+}
+
+func ExampleAminoAcidComposition() {
+	composition := protein.AminoAcidComposition("MKKLL*")
+
+	fmt.Println(composition['K'], composition['L'], composition['*'])
+	// Output: 2 2 0
+}
+
+func ExampleMolecularWeight() {
+	// glycine on its own.
+	weight, _ := protein.MolecularWeight("G")
+
+	fmt.Printf("%.2f\n", weight)
+	// Output: 75.07
+}
+
+func ExampleIsoelectricPoint() {
+	// unknown residues are an error unless Lenient is set.
+	_, err := protein.IsoelectricPoint("MKXKK")
+	isoelectricPoint, _ := protein.IsoelectricPoint("MKXKK", protein.Options{Lenient: true})
+
+	fmt.Println(err != nil, isoelectricPoint > 10)
+	// Output: true true
+}
+
+func ExampleExtinctionCoefficient() {
+	// one tryptophan, one tyrosine and a pair of cysteines.
+	reduced, cystines, _ := protein.ExtinctionCoefficient("MCWYCK")
+
+	fmt.Println(reduced, cystines)
+	// Output: 6990 7115
+}
+
+func ExampleGravy() {
+	gravy, _ := protein.Gravy("MKVLA")
+
+	fmt.Printf("%.2f\n", gravy)
+	// Output: 1.56
+}
+
+func ExampleInstabilityIndex() {
+	index, _ := protein.InstabilityIndex("MHSPQ")
+
+	fmt.Printf("%.1f stable: %t\n", index, index <= 40)
+	// Output: 249.0 stable: false
+}
@@ -0,0 +1,322 @@
+/*
+Package protein calculates basic physical properties of protein sequences.
+
+Once you've translated a gene you usually want to know a little about the
+protein it makes before you go and express it: how heavy it is so you can find
+it on a gel, where its isoelectric point is so you can pick a buffer for
+purification, how strongly it absorbs at 280 nm so you can measure its
+concentration, and whether it's likely to be hydrophobic or unstable in a test
+tube. These are the same numbers EMBOSS pepstats and ExPASy ProtParam report.
+This package works on plain one letter amino acid strings like
+the ones returned by codon.Translate. A trailing "*" stop symbol is ignored.
+*/
+package protein
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Options changes how the functions in this package handle their input.
+type Options struct {
+	// Lenient skips residues that aren't one of the 22 proteinogenic amino
+	// acids (such as X or B) instead of returning an error.
+	Lenient bool
+}
+
+// averageResidueMasses are the average masses (in daltons) of amino acids once
+// they've lost a water to a peptide bond. Values are from ExPASy
+// https://web.expasy.org/findmod/findmod_masses.html
+var averageResidueMasses = map[rune]float64{
+	'A': 71.0788,
+	'R': 156.1875,
+	'N': 114.1038,
+	'D': 115.0886,
+	'C': 103.1388,
+	'E': 129.1155,
+	'Q': 128.1307,
+	'G': 57.0519,
+	'H': 137.1411,
+	'I': 113.1594,
+	'L': 113.1594,
+	'K': 128.1741,
+	'M': 131.1926,
+	'F': 147.1766,
+	'P': 97.1167,
+	'S': 87.0782,
+	'T': 101.1051,
+	'W': 186.2132,
+	'Y': 163.1760,
+	'V': 99.1326,
+	'U': 150.0388, // selenocysteine
+	'O': 237.3018, // pyrrolysine
+}
+
+// waterMass is the average mass of the water added back at the termini of a peptide.
+const waterMass = 18.01524
+
+// AminoAcidComposition counts how many times each residue occurs in a protein.
+// Residues are uppercased and stop symbols are not counted.
+func AminoAcidComposition(protein string) map[rune]int {
+	composition := make(map[rune]int)
+	for _, residue := range strings.ToUpper(protein) {
+		if residue == '*' {
+			continue
+		}
+		composition[residue]++
+	}
+	return composition
+}
+
+// MolecularWeight returns the average molecular weight of a protein in daltons.
+func MolecularWeight(protein string, options ...Options) (float64, error) {
+	residues, err := cleanProtein(protein, options)
+	if err != nil {
+		return 0, err
+	}
+	if len(residues) == 0 {
+		return 0, nil
+	}
+
+	weight := waterMass
+	for _, residue := range residues {
+		weight += averageResidueMasses[residue]
+	}
+	return weight, nil
+}
+
+// pKa values of the ionizable groups of a protein, from EMBOSS
+// https://emboss.sourceforge.net/apps/cvs/emboss/apps/iep.html
+const (
+	nTerminusPKa = 8.6
+	cTerminusPKa = 3.6
+)
+
+var positivePKas = map[rune]float64{
+	'K': 10.8,
+	'R': 12.5,
+	'H': 6.5,
+}
+
+var negativePKas = map[rune]float64{
+	'D': 3.9,
+	'E': 4.1,
+	'C': 8.5,
+	'Y': 10.1,
+}
+
+// IsoelectricPoint returns the pH at which a protein carries no net charge.
+func IsoelectricPoint(protein string, options ...Options) (float64, error) {
+	residues, err := cleanProtein(protein, options)
+	if err != nil {
+		return 0, err
+	}
+	if len(residues) == 0 {
+		return 0, fmt.Errorf("protein has no residues")
+	}
+
+	// net charge only ever falls as pH rises so we can bisect for the point where it crosses zero.
+	low, high := 0.0, 14.0
+	for high-low > 0.0001 {
+		pH := (low + high) / 2
+		if netCharge(residues, pH) > 0 {
+			low = pH
+		} else {
+			high = pH
+		}
+	}
+	return (low + high) / 2, nil
+}
+
+// netCharge calculates the net charge of a protein at a given pH using the Henderson-Hasselbalch equation.
+func netCharge(residues []rune, pH float64) float64 {
+	charge := positiveCharge(nTerminusPKa, pH) - negativeCharge(cTerminusPKa, pH)
+	for _, residue := range residues {
+		if pKa, ok := positivePKas[residue]; ok {
+			charge += positiveCharge(pKa, pH)
+		}
+		if pKa, ok := negativePKas[residue]; ok {
+			charge -= negativeCharge(pKa, pH)
+		}
+	}
+	return charge
+}
+
+func positiveCharge(pKa, pH float64) float64 {
+	return 1 / (1 + math.Pow(10, pH-pKa))
+}
+
+func negativeCharge(pKa, pH float64) float64 {
+	return 1 / (1 + math.Pow(10, pKa-pH))
+}
+
+// Molar extinction coefficients at 280 nm of the residues that absorb there, from
+// Pace et al. (1995) Protein Sci. doi:10.1002/pro.5560041120
+const (
+	tryptophanExtinction = 5500
+	tyrosineExtinction   = 1490
+	cystineExtinction    = 125
+)
+
+// ExtinctionCoefficient returns the molar extinction coefficients of a protein at
+// 280 nm in M^-1 cm^-1, first assuming all its cysteines are reduced and then
+// assuming every pair of them forms a cystine.
+func ExtinctionCoefficient(protein string, options ...Options) (reduced float64, cystines float64, err error) {
+	residues, err := cleanProtein(protein, options)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var tryptophans, tyrosines, cysteines int
+	for _, residue := range residues {
+		switch residue {
+		case 'W':
+			tryptophans++
+		case 'Y':
+			tyrosines++
+		case 'C':
+			cysteines++
+		}
+	}
+	reduced = float64(tryptophans*tryptophanExtinction + tyrosines*tyrosineExtinction)
+	return reduced, reduced + float64(cysteines/2*cystineExtinction), nil
+}
+
+// kyteDoolittle is the hydropathy of each amino acid from Kyte J & Doolittle RF
+// (1982) J Mol Biol. doi:10.1016/0022-2836(82)90515-0
+var kyteDoolittle = map[rune]float64{
+	'A': 1.8,
+	'R': -4.5,
+	'N': -3.5,
+	'D': -3.5,
+	'C': 2.5,
+	'Q': -3.5,
+	'E': -3.5,
+	'G': -0.4,
+	'H': -3.2,
+	'I': 4.5,
+	'L': 3.8,
+	'K': -3.9,
+	'M': 1.9,
+	'F': 2.8,
+	'P': -1.6,
+	'S': -0.8,
+	'T': -0.7,
+	'W': -0.9,
+	'Y': -1.3,
+	'V': 4.2,
+}
+
+// Gravy returns the grand average of hydropathy (GRAVY) of a protein, the mean
+// Kyte-Doolittle hydropathy of its residues. Positive values are hydrophobic
+// and negative values hydrophilic. Selenocysteine and pyrrolysine have no
+// hydropathy value so are an error unless Lenient is set, which skips them.
+func Gravy(protein string, options ...Options) (float64, error) {
+	residues, err := standardResidues(protein, options)
+	if err != nil {
+		return 0, err
+	}
+
+	var total float64
+	for _, residue := range residues {
+		total += kyteDoolittle[residue]
+	}
+	return total / float64(len(residues)), nil
+}
+
+// dipeptideInstability is the dipeptide instability weight value (DIWV) of each
+// pair of amino acids, indexed by the first then the second residue, from
+// Guruprasad K, Reddy BV & Pandit MW (1990) Protein Eng.
+// doi:10.1093/protein/4.2.155
+var dipeptideInstability = map[rune]map[rune]float64{
+	'A': {'A': 1.0, 'C': 44.94, 'D': -7.49, 'E': 1.0, 'F': 1.0, 'G': 1.0, 'H': -7.49, 'I': 1.0, 'K': 1.0, 'L': 1.0, 'M': 1.0, 'N': 1.0, 'P': 20.26, 'Q': 1.0, 'R': 1.0, 'S': 1.0, 'T': 1.0, 'V': 1.0, 'W': 1.0, 'Y': 1.0},
+	'C': {'A': 1.0, 'C': 1.0, 'D': 20.26, 'E': 1.0, 'F': 1.0, 'G': 1.0, 'H': 33.6, 'I': 1.0, 'K': 1.0, 'L': 20.26, 'M': 33.6, 'N': 1.0, 'P': 20.26, 'Q': -6.54, 'R': 1.0, 'S': 1.0, 'T': 33.6, 'V': -6.54, 'W': 24.68, 'Y': 1.0},
+	'D': {'A': 1.0, 'C': 1.0, 'D': 1.0, 'E': 1.0, 'F': -6.54, 'G': 1.0, 'H': 1.0, 'I': 1.0, 'K': -7.49, 'L': 1.0, 'M': 1.0, 'N': 1.0, 'P': 1.0, 'Q': 1.0, 'R': -6.54, 'S': 20.26, 'T': -14.03, 'V': 1.0, 'W': 1.0, 'Y': 1.0},
+	'E': {'A': 1.0, 'C': 44.94, 'D': 20.26, 'E': 33.6, 'F': 1.0, 'G': 1.0, 'H': -6.54, 'I': 20.26, 'K': 1.0, 'L': 1.0, 'M': 1.0, 'N': 1.0, 'P': 20.26, 'Q': 20.26, 'R': 1.0, 'S': 20.26, 'T': 1.0, 'V': 1.0, 'W': -14.03, 'Y': 1.0},
+	'F': {'A': 1.0, 'C': 1.0, 'D': 13.34, 'E': 1.0, 'F': 1.0, 'G': 1.0, 'H': 1.0, 'I': 1.0, 'K': -14.03, 'L': 1.0, 'M': 1.0, 'N': 1.0, 'P': 20.26, 'Q': 1.0, 'R': 1.0, 'S': 1.0, 'T': 1.0, 'V': 1.0, 'W': 1.0, 'Y': 33.601},
+	'G': {'A': -7.49, 'C': 1.0, 'D': 1.0, 'E': -6.54, 'F': 1.0, 'G': 13.34, 'H': 1.0, 'I': -7.49, 'K': -7.49, 'L': 1.0, 'M': 1.0, 'N': -7.49, 'P': 1.0, 'Q': 1.0, 'R': 1.0, 'S': 1.0, 'T': -7.49, 'V': 1.0, 'W': 13.34, 'Y': -7.49},
+	'H': {'A': 1.0, 'C': 1.0, 'D': 1.0, 'E': 1.0, 'F': -9.37, 'G': -9.37, 'H': 1.0, 'I': 44.94, 'K': 24.68, 'L': 1.0, 'M': 1.0, 'N': 24.68, 'P': -1.88, 'Q': 1.0, 'R': 1.0, 'S': 1.0, 'T': -6.54, 'V': 1.0, 'W': -1.88, 'Y': 44.94},
+	'I': {'A': 1.0, 'C': 1.0, 'D': 1.0, 'E': 44.94, 'F': 1.0, 'G': 1.0, 'H': 13.34, 'I': 1.0, 'K': -7.49, 'L': 20.26, 'M': 1.0, 'N': 1.0, 'P': -1.88, 'Q': 1.0, 'R': 1.0, 'S': 1.0, 'T': 1.0, 'V': -7.49, 'W': 1.0, 'Y': 1.0},
+	'K': {'A': 1.0, 'C': 1.0, 'D': 1.0, 'E': 1.0, 'F': 1.0, 'G': -7.49, 'H': 1.0, 'I': -7.49, 'K': 1.0, 'L': -7.49, 'M': 33.6, 'N': 1.0, 'P': -6.54, 'Q': 24.64, 'R': 33.6, 'S': 1.0, 'T': 1.0, 'V': -7.49, 'W': 1.0, 'Y': 1.0},
+	'L': {'A': 1.0, 'C': 1.0, 'D': 1.0, 'E': 1.0, 'F': 1.0, 'G': 1.0, 'H': 1.0, 'I': 1.0, 'K': -7.49, 'L': 1.0, 'M': 1.0, 'N': 1.0, 'P': 20.26, 'Q': 33.6, 'R': 20.26, 'S': 1.0, 'T': 1.0, 'V': 1.0, 'W': 24.68, 'Y': 1.0},
+	'M': {'A': 13.34, 'C': 1.0, 'D': 1.0, 'E': 1.0, 'F': 1.0, 'G': 1.0, 'H': 58.28, 'I': 1.0, 'K': 1.0, 'L': 1.0, 'M': -1.88, 'N': 1.0, 'P': 44.94, 'Q': -6.54, 'R': -6.54, 'S': 44.94, 'T': -1.88, 'V': 1.0, 'W': 1.0, 'Y': 24.68},
+	'N': {'A': 1.0, 'C': -1.88, 'D': 1.0, 'E': 1.0, 'F': -14.03, 'G': -14.03, 'H': 1.0, 'I': 44.94, 'K': 24.68, 'L': 1.0, 'M': 1.0, 'N': 1.0, 'P': -1.88, 'Q': -6.54, 'R': 1.0, 'S': 1.0, 'T': -7.49, 'V': 1.0, 'W': -9.37, 'Y': 1.0},
+	'P': {'A': 20.26, 'C': -6.54, 'D': -6.54, 'E': 18.38, 'F': 20.26, 'G': 1.0, 'H': 1.0, 'I': 1.0, 'K': 1.0, 'L': 1.0, 'M': -6.54, 'N': 1.0, 'P': 20.26, 'Q': 20.26, 'R': -6.54, 'S': 20.26, 'T': 1.0, 'V': 20.26, 'W': -1.88, 'Y': 1.0},
+	'Q': {'A': 1.0, 'C': -6.54, 'D': 20.26, 'E': 20.26, 'F': -6.54, 'G': 1.0, 'H': 1.0, 'I': 1.0, 'K': 1.0, 'L': 1.0, 'M': 1.0, 'N': 1.0, 'P': 20.26, 'Q': 20.26, 'R': 1.0, 'S': 44.94, 'T': 1.0, 'V': -6.54, 'W': 1.0, 'Y': -6.54},
+	'R': {'A': 1.0, 'C': 1.0, 'D': 1.0, 'E': 1.0, 'F': 1.0, 'G': -7.49, 'H': 20.26, 'I': 1.0, 'K': 1.0, 'L': 1.0, 'M': 1.0, 'N': 13.34, 'P': 20.26, 'Q': 20.26, 'R': 58.28, 'S': 44.94, 'T': 1.0, 'V': 1.0, 'W': 58.28, 'Y': -6.54},
+	'S': {'A': 1.0, 'C': 33.6, 'D': 1.0, 'E': 20.26, 'F': 1.0, 'G': 1.0, 'H': 1.0, 'I': 1.0, 'K': 1.0, 'L': 1.0, 'M': 1.0, 'N': 1.0, 'P': 44.94, 'Q': 20.26, 'R': 20.26, 'S': 20.26, 'T': 1.0, 'V': 1.0, 'W': 1.0, 'Y': 1.0},
+	'T': {'A': 1.0, 'C': 1.0, 'D': 1.0, 'E': 20.26, 'F': 13.34, 'G': -7.49, 'H': 1.0, 'I': 1.0, 'K': 1.0, 'L': 1.0, 'M': 1.0, 'N': -14.03, 'P': 1.0, 'Q': -6.54, 'R': 1.0, 'S': 1.0, 'T': 1.0, 'V': 1.0, 'W': -14.03, 'Y': 1.0},
+	'V': {'A': 1.0, 'C': 1.0, 'D': -14.03, 'E': 1.0, 'F': 1.0, 'G': -7.49, 'H': 1.0, 'I': 1.0, 'K': -1.88, 'L': 1.0, 'M': 1.0, 'N': 1.0, 'P': 20.26, 'Q': 1.0, 'R': 1.0, 'S': 1.0, 'T': -7.49, 'V': 1.0, 'W': 1.0, 'Y': -6.54},
+	'W': {'A': -14.03, 'C': 1.0, 'D': 1.0, 'E': 1.0, 'F': 1.0, 'G': -9.37, 'H': 24.68, 'I': 1.0, 'K': 1.0, 'L': 13.34, 'M': 24.68, 'N': 13.34, 'P': 1.0, 'Q': 1.0, 'R': 1.0, 'S': 1.0, 'T': -14.03, 'V': -7.49, 'W': 1.0, 'Y': 1.0},
+	'Y': {'A': 24.68, 'C': 1.0, 'D': 24.68, 'E': -6.54, 'F': 1.0, 'G': -7.49, 'H': 13.34, 'I': 1.0, 'K': 1.0, 'L': 1.0, 'M': 44.94, 'N': 1.0, 'P': 13.34, 'Q': 1.0, 'R': -15.91, 'S': 1.0, 'T': -7.49, 'V': 1.0, 'W': -9.37, 'Y': 13.34},
+}
+
+// InstabilityIndex estimates how stable a protein is in a test tube from the
+// dipeptides it contains. Proteins scoring above 40 are predicted to be
+// unstable. Like Gravy, selenocysteine and pyrrolysine are an error unless
+// Lenient is set.
+func InstabilityIndex(protein string, options ...Options) (float64, error) {
+	residues, err := standardResidues(protein, options)
+	if err != nil {
+		return 0, err
+	}
+
+	var total float64
+	for index := 0; index+1 < len(residues); index++ {
+		total += dipeptideInstability[residues[index]][residues[index+1]]
+	}
+	return 10 / float64(len(residues)) * total, nil
+}
+
+// standardResidues is cleanProtein for calculations that only have values for
+// the 20 standard amino acids, and errors if there are no residues left.
+func standardResidues(protein string, options []Options) ([]rune, error) {
+	residues, err := cleanProtein(protein, options)
+	if err != nil {
+		return nil, err
+	}
+
+	standard := residues[:0]
+	for index, residue := range residues {
+		if _, ok := kyteDoolittle[residue]; !ok {
+			if lenient(options) {
+				continue
+			}
+			return nil, fmt.Errorf("no value for non-standard amino acid %q at residue %d", residue, index)
+		}
+		standard = append(standard, residue)
+	}
+	if len(standard) == 0 {
+		return nil, fmt.Errorf("protein has no residues")
+	}
+	return standard, nil
+}
+
+// cleanProtein uppercases a protein, drops stop symbols and checks every residue is known.
+func cleanProtein(protein string, options []Options) ([]rune, error) {
+	residues := make([]rune, 0, len(protein))
+	for index, residue := range strings.ToUpper(protein) {
+		if residue == '*' {
+			continue
+		}
+		if _, ok := averageResidueMasses[residue]; !ok {
+			if lenient(options) {
+				continue
+			}
+			return nil, fmt.Errorf("unknown amino acid %q at position %d", residue, index)
+		}
+		residues = append(residues, residue)
+	}
+	return residues, nil
+}
+
+// lenient returns whether any of the options passed to a function set Lenient.
+func lenient(options []Options) bool {
+	for _, option := range options {
+		if option.Lenient {
+			return true
+		}
+	}
+	return false
+}
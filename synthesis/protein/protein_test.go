@@ -0,0 +1,122 @@
+package protein_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/TimothyStiles/poly/synthesis/protein"
+)
+
+func TestMolecularWeight(t *testing.T) {
+	// stop symbols and case shouldn't change the weight.
+	weight, _ := protein.MolecularWeight("MASKGEE")
+	stopWeight, _ := protein.MolecularWeight("maskgee*")
+	if weight != stopWeight {
+		t.Errorf("expected %f, got %f", weight, stopWeight)
+	}
+
+	// a dipeptide loses one water to its peptide bond.
+	glycine, _ := protein.MolecularWeight("G")
+	diglycine, _ := protein.MolecularWeight("GG")
+	if math.Abs(2*glycine-diglycine-18.01524) > 1e-9 {
+		t.Errorf("expected diglycine to weigh %f, got %f", 2*glycine-18.01524, diglycine)
+	}
+
+	if weight, err := protein.MolecularWeight(""); err != nil || weight != 0 {
+		t.Errorf("expected an empty protein to weigh 0, got %f and %v", weight, err)
+	}
+
+	if _, err := protein.MolecularWeight("MAJ"); err == nil {
+		t.Errorf("expected an error for an unknown residue")
+	}
+	// lenient mode should skip unknown residues entirely.
+	lenientWeight, err := protein.MolecularWeight("MXASXKGEE*", protein.Options{Lenient: true})
+	if err != nil {
+		t.Error(err)
+	}
+	if lenientWeight != weight {
+		t.Errorf("expected %f, got %f", weight, lenientWeight)
+	}
+}
+
+func TestIsoelectricPoint(t *testing.T) {
+	acidic, _ := protein.IsoelectricPoint("DDEEDE")
+	basic, _ := protein.IsoelectricPoint("KKRRKR")
+	if acidic > 4 || basic < 10 {
+		t.Errorf("expected an acidic pI below 4 and a basic pI above 10, got %f and %f", acidic, basic)
+	}
+
+	if _, err := protein.IsoelectricPoint("*"); err == nil {
+		t.Errorf("expected an error for a protein with no residues")
+	}
+	if _, err := protein.IsoelectricPoint("MKB"); err == nil {
+		t.Errorf("expected an error for an unknown residue")
+	}
+}
+
+func TestExtinctionCoefficient(t *testing.T) {
+	// two tryptophans, a tyrosine and three cysteines, only two of which can pair.
+	reduced, cystines, err := protein.ExtinctionCoefficient("MWCYCWC*")
+	if err != nil {
+		t.Error(err)
+	}
+	if reduced != 12490 || cystines != 12615 {
+		t.Errorf("expected 12490 and 12615, got %f and %f", reduced, cystines)
+	}
+
+	if reduced, cystines, _ := protein.ExtinctionCoefficient("MKK"); reduced != 0 || cystines != 0 {
+		t.Errorf("expected a protein without W, Y or C to not absorb, got %f and %f", reduced, cystines)
+	}
+	if _, _, err := protein.ExtinctionCoefficient("MWJ"); err == nil {
+		t.Errorf("expected an error for an unknown residue")
+	}
+}
+
+func TestGravy(t *testing.T) {
+	hydrophobic, _ := protein.Gravy("IIVVLL")
+	hydrophilic, _ := protein.Gravy("RRKKDD")
+	if hydrophobic <= 0 || hydrophilic >= 0 {
+		t.Errorf("expected a positive and a negative GRAVY, got %f and %f", hydrophobic, hydrophilic)
+	}
+
+	// the mean of isoleucine (4.5) and arginine (-4.5).
+	if gravy, _ := protein.Gravy("ir*"); gravy != 0 {
+		t.Errorf("expected 0, got %f", gravy)
+	}
+
+	if _, err := protein.Gravy("MUK"); err == nil {
+		t.Errorf("expected an error for selenocysteine")
+	}
+	lenientGravy, err := protein.Gravy("IUR", protein.Options{Lenient: true})
+	if err != nil || lenientGravy != 0 {
+		t.Errorf("expected selenocysteine to be skipped, got %f and %v", lenientGravy, err)
+	}
+	if _, err := protein.Gravy("*"); err == nil {
+		t.Errorf("expected an error for a protein with no residues")
+	}
+}
+
+func TestInstabilityIndex(t *testing.T) {
+	// a single residue has no dipeptides.
+	if index, _ := protein.InstabilityIndex("M"); index != 0 {
+		t.Errorf("expected 0, got %f", index)
+	}
+
+	// MH (58.28) and HI (44.94) over three residues.
+	index, err := protein.InstabilityIndex("MHI")
+	if err != nil {
+		t.Error(err)
+	}
+	if math.Abs(index-344.0667) > 1e-4 {
+		t.Errorf("expected 344.0667, got %f", index)
+	}
+
+	// order matters, IH is 13.34 and HM is 1.
+	if reversed, _ := protein.InstabilityIndex("IHM"); math.Abs(reversed-47.8) > 1e-9 {
+		t.Errorf("expected 47.8, got %f", reversed)
+	}
+
+	if _, err := protein.InstabilityIndex("MOK"); err == nil {
+		t.Errorf("expected an error for pyrrolysine")
+	}
+}
@@ -8,11 +8,57 @@ Reverse takes the reverse of a sequence.
 (literally just reverses a string. Exists in stdlib but hey why not have it here too?)
 
 ReverseComplement takes the reverse complement of a sequence.
-(Reverses the sequence string and returns the complement of the reversed sequence.)
+(Reverses the sequence string and returns the complement of the reversed sequence.
+IUPAC codes, case and RNA are all handled, and ReverseComplementStrict errors on anything else.)
+
+RotateToCanonical rotates a circular sequence to a deterministic starting point.
+(so the same plasmid compares equal no matter where its origin was put.)
+
+ValidateDNA checks that a sequence only contains DNA bases.
+(IUPAC ambiguity codes like N and R and the gaps - and . are allowed. Shorthand for Validate(sequence, DNA).)
+
+DetectAlphabet guesses whether a sequence is DNA, RNA or protein.
+(Validate then checks a sequence against an alphabet and reports every bad character, a good guard before translating.)
+
+Transcribe turns a DNA sequence into its RNA equivalent.
+(swaps every T for a U. Case is preserved and everything else is left alone.)
+
+ReverseTranscribe turns an RNA sequence into its DNA equivalent.
+(swaps every U for a T. Case is preserved and everything else is left alone.)
+
+UnmaskRepeats removes soft-masking from a sequence.
+(Genomic sequences often use lowercase to mark repeats. This uppercases everything.)
+
+MaskRegions soft-masks regions of a sequence.
+(lowercases the given regions so they can be carried around like any other repeat.)
+
+Normalize puts a sequence into a canonical form.
+(uppercases, strips whitespace and gaps and so on so that equal sequences compare and hash equal.)
+
+HammingDistance counts the mismatches between two sequences of the same length.
+(useful for matching barcodes, which are designed to never have indels between them.)
+
+LevenshteinDistance counts the edits needed to turn one sequence into another.
+(substitutions, insertions and deletions all count as one edit.)
+
+KmerProfile counts every k-mer in a sequence.
+(optionally collapsing each k-mer with its reverse complement so both strands count the same.)
+
+KmerDistance compares two k-mer profiles.
+(a quick, alignment free way of telling how similar two sequences are.)
+
+ApplyCIGAR lines up a query against a reference using a SAM CIGAR string.
+(returns both sequences with "-" gaps so they can be printed one above the other.)
 */
 package transform
 
-import "strings"
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
 
 // complementBaseRuneMap provides 1:1 mapping between bases and their complements
 var complementBaseRuneMap = map[rune]rune{
@@ -36,7 +82,7 @@ var complementBaseRuneMap = map[rune]rune{
 	98:  118, // b -> v
 	99:  103, // c -> g
 	100: 104, // d -> h
-	103: 99,  // g -> a
+	103: 99,  // g -> c
 	104: 100, // h -> d
 	107: 109, // k -> m
 	109: 107, // m -> k
@@ -50,22 +96,44 @@ var complementBaseRuneMap = map[rune]rune{
 	121: 114, // y -> r
 }
 
-// ReverseComplement takes the reverse complement of a sequence.
+// ReverseComplement takes the reverse complement of a sequence. IUPAC ambiguity
+// codes are complemented, case is kept and characters without a complement, like
+// gaps, are left as they are. Sequences with U and no T are taken to be RNA, so A
+// complements to U rather than T. Use ReverseComplementStrict to catch characters
+// that aren't nucleotides.
 func ReverseComplement(sequence string) string {
-	complementString := strings.Map(ComplementBase, sequence)
+	complementString := Complement(sequence)
 	length := len(complementString)
 	newString := make([]rune, length)
 	for _, base := range complementString {
 		length--
 		newString[length] = base
 	}
-	return string(newString)
+	return string(newString[length:])
+}
+
+// ReverseComplementStrict is ReverseComplement but returns an error, reporting
+// the first bad character and its 0-based position, if the sequence has anything
+// other than DNA or RNA bases and IUPAC ambiguity codes in it or mixes T and U.
+func ReverseComplementStrict(sequence string) (string, error) {
+	for index, base := range sequence {
+		if _, ok := complementBaseRuneMap[base]; !ok {
+			return "", fmt.Errorf("invalid base %q at position %d", base, index)
+		}
+	}
+	if strings.ContainsAny(sequence, "Tt") && strings.ContainsAny(sequence, "Uu") {
+		return "", fmt.Errorf("sequence has both T and U in it")
+	}
+	return ReverseComplement(sequence), nil
 }
 
-// Complement takes the complement of a sequence.
+// Complement takes the complement of a sequence, handling IUPAC ambiguity codes,
+// case and RNA the same way as ReverseComplement.
 func Complement(sequence string) string {
-	complementString := strings.Map(ComplementBase, sequence)
-	return complementString
+	rna := isRNA(sequence)
+	return strings.Map(func(base rune) rune {
+		return complementRune(base, rna)
+	}, sequence)
 }
 
 // Reverse takes the reverse of a sequence.
@@ -79,7 +147,523 @@ func Reverse(sequence string) string {
 	return string(newString)
 }
 
-// ComplementBase accepts a base pair and returns its complement base pair
+// RotateToCanonical rotates a circular sequence to start at its lexicographically
+// least rotation, found with Booth's algorithm in linear time, so that two copies
+// of a plasmid with their origins at different places come out the same. Case is
+// ignored when picking the rotation but kept in the sequence returned. Strands
+// aren't compared, so to compare double stranded sequences also rotate the
+// ReverseComplement and take whichever sorts first.
+func RotateToCanonical(sequence string) string {
+	rotation := leastRotation(strings.ToUpper(sequence))
+	return sequence[rotation:] + sequence[:rotation]
+}
+
+// leastRotation returns the index a sequence's lexicographically least rotation
+// starts at using Booth's algorithm, a variant of the Knuth-Morris-Pratt failure
+// function run over the sequence concatenated to itself.
+// https://en.wikipedia.org/wiki/Lexicographically_minimal_string_rotation
+func leastRotation(sequence string) int {
+	doubled := sequence + sequence
+	leastRotationIndex := 0
+
+	// failure is the Knuth-Morris-Pratt failure function of the least rotation found so far.
+	failure := make([]int, len(doubled))
+	for index := range failure {
+		failure[index] = -1
+	}
+	for characterIndex := 1; characterIndex < len(doubled); characterIndex++ {
+		character := doubled[characterIndex]
+		matched := failure[characterIndex-leastRotationIndex-1]
+		for matched != -1 && character != doubled[leastRotationIndex+matched+1] {
+			// a smaller character means a rotation starting further along is smaller.
+			if character < doubled[leastRotationIndex+matched+1] {
+				leastRotationIndex = characterIndex - matched - 1
+			}
+			matched = failure[matched]
+		}
+		if character != doubled[leastRotationIndex+matched+1] {
+			if character < doubled[leastRotationIndex] {
+				leastRotationIndex = characterIndex
+			}
+			failure[characterIndex-leastRotationIndex] = -1
+		} else {
+			failure[characterIndex-leastRotationIndex] = matched + 1
+		}
+	}
+	return leastRotationIndex
+}
+
+// ComplementBase accepts a base pair and returns its complement base pair. U
+// complements to A and anything without a complement is returned as it is.
 func ComplementBase(basePair rune) rune {
-	return complementBaseRuneMap[basePair]
+	return complementRune(basePair, false)
+}
+
+// isRNA returns whether a sequence has U and no T in it.
+func isRNA(sequence string) bool {
+	return strings.ContainsAny(sequence, "Uu") && !strings.ContainsAny(sequence, "Tt")
+}
+
+// complementRune complements a single base. Anything without a complement is
+// left as it is, and A complements to U in RNA.
+func complementRune(base rune, rna bool) rune {
+	complement, ok := complementBaseRuneMap[base]
+	if !ok {
+		return base
+	}
+	if rna {
+		switch complement {
+		case 'T':
+			return 'U'
+		case 't':
+			return 'u'
+		}
+	}
+	return complement
+}
+
+// ValidateDNA returns an error if a sequence contains anything other than DNA
+// bases, IUPAC ambiguity codes or the gap characters '-' and '.', in either
+// case. It is Validate(sequence, DNA), so the error is an *AlphabetError
+// reporting every invalid character and its 0-based position.
+func ValidateDNA(sequence string) error {
+	return Validate(sequence, DNA)
+}
+
+// Alphabet is the kind of residues a sequence is made of.
+type Alphabet int
+
+// Alphabets that sequences can be validated against. UnknownAlphabet is what
+// DetectAlphabet returns for a sequence that fits none of the others.
+const (
+	UnknownAlphabet Alphabet = iota
+	DNA
+	RNA
+	Protein
+)
+
+// String returns the name of an alphabet.
+func (alphabet Alphabet) String() string {
+	switch alphabet {
+	case DNA:
+		return "DNA"
+	case RNA:
+		return "RNA"
+	case Protein:
+		return "protein"
+	}
+	return "unknown"
+}
+
+// nucleotideAmbiguityCodes are the IUPAC codes shared by DNA and RNA.
+const nucleotideAmbiguityCodes = "RYSWKMBDHVN"
+
+// alphabetCharacters are the valid characters of each alphabet, in uppercase.
+// This is the one place alphabets are defined. The alignment gap characters
+// '-' and '.' are valid in every alphabet and stops in proteins.
+var alphabetCharacters = map[Alphabet]string{
+	DNA:     "ACGT" + nucleotideAmbiguityCodes + "-.",
+	RNA:     "ACGU" + nucleotideAmbiguityCodes + "-.",
+	Protein: "ACDEFGHIKLMNPQRSTVWY" + "BZJXUO" + "*-.",
+}
+
+// DetectAlphabet guesses whether a sequence is DNA, RNA or protein, in either
+// case. IUPAC ambiguity codes are letters amino acids use too, so a sequence
+// is only called DNA or RNA if every character is valid in that alphabet and
+// at least 90% of its residues are plain bases or N. RNA is told apart from
+// DNA by having a U and no T. Sequences that aren't valid in any alphabet,
+// including empty ones, are UnknownAlphabet.
+func DetectAlphabet(sequence string) Alphabet {
+	var residues, bases, thymines, uracils int
+	for _, character := range strings.ToUpper(sequence) {
+		switch character {
+		case '-', '.':
+			continue
+		case 'A', 'C', 'G', 'N':
+			bases++
+		case 'T':
+			thymines++
+		case 'U':
+			uracils++
+		}
+		residues++
+	}
+	if residues == 0 {
+		return UnknownAlphabet
+	}
+
+	nucleotide := DNA
+	if uracils > 0 && thymines == 0 {
+		nucleotide = RNA
+	}
+	if float64(bases+thymines+uracils) >= 0.9*float64(residues) && len(InvalidPositions(sequence, nucleotide)) == 0 {
+		return nucleotide
+	}
+	if len(InvalidPositions(sequence, Protein)) == 0 {
+		return Protein
+	}
+	return UnknownAlphabet
+}
+
+// InvalidPositions returns the 0-based positions of every character in a
+// sequence that isn't valid in an alphabet, ignoring case. Every position is
+// invalid in UnknownAlphabet.
+func InvalidPositions(sequence string, alphabet Alphabet) []int {
+	valid := alphabetCharacters[alphabet]
+	var positions []int
+	for index, character := range sequence {
+		if !strings.ContainsRune(valid, unicode.ToUpper(character)) {
+			positions = append(positions, index)
+		}
+	}
+	return positions
+}
+
+// AlphabetError is returned by Validate for sequences with characters that
+// aren't in the alphabet they were validated against.
+type AlphabetError struct {
+	Alphabet   Alphabet
+	Characters []rune // the invalid characters, in order.
+	Positions  []int  // 0-based positions of the invalid characters.
+}
+
+func (err *AlphabetError) Error() string {
+	return fmt.Sprintf("%d invalid %s character(s), the first %q at position %d", len(err.Positions), err.Alphabet, err.Characters[0], err.Positions[0])
+}
+
+// Validate returns an *AlphabetError listing every invalid character of a
+// sequence and its position if the sequence doesn't fit an alphabet, which
+// makes it a useful guard before translating or optimizing a sequence. Every
+// alphabet accepts the gap characters '-' and '.' so that aligned sequences
+// validate, so remove them first if a sequence must not be gapped.
+func Validate(sequence string, alphabet Alphabet) error {
+	positions := InvalidPositions(sequence, alphabet)
+	if len(positions) == 0 {
+		return nil
+	}
+	characters := make([]rune, len(positions))
+	for index, position := range positions {
+		characters[index], _ = utf8.DecodeRuneInString(sequence[position:])
+	}
+	return &AlphabetError{Alphabet: alphabet, Characters: characters, Positions: positions}
+}
+
+// Transcribe takes a DNA sequence and returns its RNA equivalent by swapping T for U.
+func Transcribe(sequence string) string {
+	return strings.Map(func(base rune) rune {
+		switch base {
+		case 'T':
+			return 'U'
+		case 't':
+			return 'u'
+		}
+		return base
+	}, sequence)
+}
+
+// ReverseTranscribe takes an RNA sequence and returns its DNA equivalent by swapping U for T.
+func ReverseTranscribe(sequence string) string {
+	return strings.Map(func(base rune) rune {
+		switch base {
+		case 'U':
+			return 'T'
+		case 'u':
+			return 't'
+		}
+		return base
+	}, sequence)
+}
+
+// UnmaskRepeats removes soft-masking (lowercase bases) from a sequence by uppercasing it.
+func UnmaskRepeats(sequence string) string {
+	return strings.ToUpper(sequence)
+}
+
+// MaskRegions soft-masks a sequence by lowercasing the given regions. Each region
+// is a 0-based, half-open [start, end) pair, which is the same shape returned by
+// regexp's FindAllStringIndex. Regions may overlap.
+func MaskRegions(sequence string, regions [][]int) (string, error) {
+	maskedSequence := []byte(sequence)
+	for _, region := range regions {
+		if len(region) != 2 {
+			return "", fmt.Errorf("region %v should have a start and an end", region)
+		}
+		start, end := region[0], region[1]
+		if start < 0 || end > len(sequence) || start > end {
+			return "", fmt.Errorf("region [%d, %d) is out of bounds for a sequence of length %d", start, end, len(sequence))
+		}
+		copy(maskedSequence[start:end], strings.ToLower(sequence[start:end]))
+	}
+	return string(maskedSequence), nil
+}
+
+// NormalizeOptions chooses what Normalize does to a sequence.
+type NormalizeOptions struct {
+	Uppercase       bool // uppercase every base, removing soft-masking.
+	StripWhitespace bool // remove spaces, tabs and newlines.
+	StripGaps       bool // remove "-" and "." alignment gaps.
+	ToDNA           bool // swap every U for a T.
+	ToRNA           bool // swap every T for a U. Ignored if ToDNA is set.
+	// Canonical returns whichever of the sequence and its reverse complement
+	// comes first alphabetically, so both strands of a sequence normalize to
+	// the same string.
+	Canonical bool
+}
+
+// Normalize puts a sequence into a canonical form so that sequences that only
+// differ in how they were written compare equal, which is what you want before
+// hashing or deduplicating them. Every option is applied in a single pass over
+// the sequence, with a second pass for Canonical.
+func Normalize(sequence string, options NormalizeOptions) string {
+	var normalized strings.Builder
+	normalized.Grow(len(sequence))
+	for index := 0; index < len(sequence); index++ {
+		base := sequence[index]
+		switch {
+		case options.StripWhitespace && (base == ' ' || base == '\t' || base == '\n' || base == '\r' || base == '\v' || base == '\f'):
+			continue
+		case options.StripGaps && (base == '-' || base == '.'):
+			continue
+		}
+		if options.Uppercase && 'a' <= base && base <= 'z' {
+			base -= 'a' - 'A'
+		}
+		if options.ToDNA && (base == 'U' || base == 'u') {
+			base -= 'U' - 'T'
+		} else if !options.ToDNA && options.ToRNA && (base == 'T' || base == 't') {
+			base += 'U' - 'T'
+		}
+		normalized.WriteByte(base)
+	}
+	if !options.Canonical {
+		return normalized.String()
+	}
+
+	// compare the sequence against its reverse complement base by base so the
+	// reverse complement only needs building if it's the one we return.
+	forward := normalized.String()
+	rna := !options.ToDNA && options.ToRNA
+	for index := 0; index < len(forward); index++ {
+		complement := normalizeComplement(forward[len(forward)-1-index], rna)
+		if complement == forward[index] {
+			continue
+		}
+		if complement > forward[index] {
+			return forward
+		}
+		reverseComplement := make([]byte, len(forward))
+		for position := range forward {
+			reverseComplement[len(forward)-1-position] = normalizeComplement(forward[position], rna)
+		}
+		return string(reverseComplement)
+	}
+	return forward
+}
+
+// normalizeComplement complements a single byte of a sequence for Normalize.
+func normalizeComplement(base byte, rna bool) byte {
+	return byte(complementRune(rune(base), rna))
+}
+
+// HammingDistance returns the number of positions at which two sequences of
+// equal length differ. Comparisons ignore case unless the optional
+// caseSensitive argument is true.
+func HammingDistance(a, b string, caseSensitive ...bool) (int, error) {
+	if len(caseSensitive) == 0 || !caseSensitive[0] {
+		a, b = strings.ToUpper(a), strings.ToUpper(b)
+	}
+	aRunes, bRunes := []rune(a), []rune(b)
+	if len(aRunes) != len(bRunes) {
+		return 0, fmt.Errorf("sequences of length %d and %d can't be compared by hamming distance", len(aRunes), len(bRunes))
+	}
+
+	var distance int
+	for index := range aRunes {
+		if aRunes[index] != bRunes[index] {
+			distance++
+		}
+	}
+	return distance, nil
+}
+
+// LevenshteinDistance returns the minimum number of substitutions, insertions
+// and deletions needed to turn sequence a into sequence b. Comparisons ignore
+// case unless the optional caseSensitive argument is true.
+func LevenshteinDistance(a, b string, caseSensitive ...bool) int {
+	if len(caseSensitive) == 0 || !caseSensitive[0] {
+		a, b = strings.ToUpper(a), strings.ToUpper(b)
+	}
+	aRunes, bRunes := []rune(a), []rune(b)
+
+	// only the previous row of the dynamic programming matrix is needed to fill in the current one.
+	previousRow := make([]int, len(bRunes)+1)
+	currentRow := make([]int, len(bRunes)+1)
+	for column := range previousRow {
+		previousRow[column] = column
+	}
+	for row := 1; row <= len(aRunes); row++ {
+		currentRow[0] = row
+		for column := 1; column <= len(bRunes); column++ {
+			substitutionCost := 1
+			if aRunes[row-1] == bRunes[column-1] {
+				substitutionCost = 0
+			}
+			deletion := previousRow[column] + 1
+			insertion := currentRow[column-1] + 1
+			substitution := previousRow[column-1] + substitutionCost
+
+			currentRow[column] = deletion
+			if insertion < currentRow[column] {
+				currentRow[column] = insertion
+			}
+			if substitution < currentRow[column] {
+				currentRow[column] = substitution
+			}
+		}
+		previousRow, currentRow = currentRow, previousRow
+	}
+	return previousRow[len(bRunes)]
+}
+
+// KmerProfile counts how many times each k-mer (substring of length k) occurs
+// in a sequence. K-mers are uppercased. If the optional canonical argument is
+// true each k-mer is counted under whichever of itself and its reverse
+// complement sorts first, so a sequence and its reverse complement have the
+// same profile.
+func KmerProfile(sequence string, k int, canonical ...bool) (map[string]int, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be positive, got %d", k)
+	}
+	if k > len(sequence) {
+		return nil, fmt.Errorf("k of %d is longer than the sequence of length %d", k, len(sequence))
+	}
+	sequence = strings.ToUpper(sequence)
+	profile := make(map[string]int)
+	for start := 0; start+k <= len(sequence); start++ {
+		kmer := sequence[start : start+k]
+		if len(canonical) > 0 && canonical[0] {
+			if reverseComplement := ReverseComplement(kmer); reverseComplement < kmer {
+				kmer = reverseComplement
+			}
+		}
+		profile[kmer]++
+	}
+	return profile, nil
+}
+
+// KmerDistance returns the cosine distance between two k-mer profiles, from 0
+// for profiles with the same k-mers in the same proportions to 1 for profiles
+// that don't share any k-mers. Both profiles should use the same k.
+func KmerDistance(a, b map[string]int) float64 {
+	var dotProduct, aNorm, bNorm float64
+	for kmer, aCount := range a {
+		dotProduct += float64(aCount * b[kmer])
+		aNorm += float64(aCount * aCount)
+	}
+	for _, bCount := range b {
+		bNorm += float64(bCount * bCount)
+	}
+	if aNorm == 0 && bNorm == 0 {
+		return 0
+	}
+	if aNorm == 0 || bNorm == 0 {
+		return 1
+	}
+	return 1 - dotProduct/(math.Sqrt(aNorm)*math.Sqrt(bNorm))
+}
+
+// cigarOperation is a single length and operation pair from a CIGAR string.
+type cigarOperation struct {
+	length    int
+	operation rune
+}
+
+// parseCIGAR splits a CIGAR string such as 8M2I4M into its operations.
+func parseCIGAR(cigar string) ([]cigarOperation, error) {
+	if cigar == "" || cigar == "*" {
+		return nil, fmt.Errorf("CIGAR string is empty")
+	}
+	var operations []cigarOperation
+	length := 0
+	hasLength := false
+	for _, character := range cigar {
+		if character >= '0' && character <= '9' {
+			length = length*10 + int(character-'0')
+			hasLength = true
+			continue
+		}
+		if !strings.ContainsRune("MIDNSHP=X", character) {
+			return nil, fmt.Errorf("invalid operation %q in CIGAR string %s", character, cigar)
+		}
+		if !hasLength || length == 0 {
+			return nil, fmt.Errorf("operation %q is missing a length in CIGAR string %s", character, cigar)
+		}
+		operations = append(operations, cigarOperation{length, character})
+		length, hasLength = 0, false
+	}
+	if hasLength {
+		return nil, fmt.Errorf("CIGAR string %s ends without an operation", cigar)
+	}
+	return operations, nil
+}
+
+// ApplyCIGAR reconstructs an alignment from a SAM style CIGAR string. The query
+// is aligned against the reference starting at the 0-based position refStart
+// (SAM files use 1-based positions, so subtract one from POS) and the aligned
+// reference and query are returned as equal length strings with "-" for gaps.
+//
+// M, = and X align a base of each sequence. I inserts query bases opposite gaps
+// in the reference while D and N skip reference bases opposite gaps in the
+// query. Soft clipped (S) query bases aren't part of the alignment so they are
+// left out, hard clips (H) are ignored and padding (P) adds a gap to both.
+//
+// An error is returned if the CIGAR string is malformed, if it runs past the end
+// of the reference or if it doesn't account for exactly every base of the query.
+func ApplyCIGAR(reference, query, cigar string, refStart int) (alignedRef, alignedQuery string, err error) {
+	operations, err := parseCIGAR(cigar)
+	if err != nil {
+		return "", "", err
+	}
+	if refStart < 0 || refStart > len(reference) {
+		return "", "", fmt.Errorf("start %d is out of bounds for a reference of length %d", refStart, len(reference))
+	}
+
+	var refBuilder, queryBuilder strings.Builder
+	refPosition, queryPosition := refStart, 0
+	for _, operation := range operations {
+		consumesRef := strings.ContainsRune("MDN=X", operation.operation)
+		consumesQuery := strings.ContainsRune("MIS=X", operation.operation)
+		if consumesRef && refPosition+operation.length > len(reference) {
+			return "", "", fmt.Errorf("%d%c runs past the end of a reference of length %d", operation.length, operation.operation, len(reference))
+		}
+		if consumesQuery && queryPosition+operation.length > len(query) {
+			return "", "", fmt.Errorf("%d%c runs past the end of a query of length %d", operation.length, operation.operation, len(query))
+		}
+
+		gap := strings.Repeat("-", operation.length)
+		switch {
+		case consumesRef && consumesQuery:
+			refBuilder.WriteString(reference[refPosition : refPosition+operation.length])
+			queryBuilder.WriteString(query[queryPosition : queryPosition+operation.length])
+		case consumesRef:
+			refBuilder.WriteString(reference[refPosition : refPosition+operation.length])
+			queryBuilder.WriteString(gap)
+		case operation.operation == 'I':
+			refBuilder.WriteString(gap)
+			queryBuilder.WriteString(query[queryPosition : queryPosition+operation.length])
+		case operation.operation == 'P':
+			refBuilder.WriteString(gap)
+			queryBuilder.WriteString(gap)
+		}
+		if consumesRef {
+			refPosition += operation.length
+		}
+		if consumesQuery {
+			queryPosition += operation.length
+		}
+	}
+	if queryPosition != len(query) {
+		return "", "", fmt.Errorf("CIGAR string %s covers %d bases but the query has %d", cigar, queryPosition, len(query))
+	}
+	return refBuilder.String(), queryBuilder.String(), nil
 }
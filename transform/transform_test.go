@@ -1,7 +1,12 @@
 package transform_test
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
 
 	"github.com/TimothyStiles/poly/transform"
 )
@@ -14,6 +19,51 @@ func ExampleReverseComplement() {
 	// Output: TGTAATC
 }
 
+func ExampleReverseComplementStrict() {
+	rna, _ := transform.ReverseComplementStrict("AUGgcn")
+	_, err := transform.ReverseComplementStrict("GATTACA!")
+
+	fmt.Println(rna)
+	fmt.Println(err)
+	// Output:
+	// ngcCAU
+	// invalid base '!' at position 7
+}
+
+func TestReverseComplement(t *testing.T) {
+	for _, test := range []struct{ sequence, reverseComplement string }{
+		{"GATTACA", "TGTAATC"},
+		{"gaTTaca", "tgtAAtc"},
+		{"ACGTRYSWKMBDHVN", "NBDHVKMWSRYACGT"},
+		{"acgtryswkmbdhvn", "nbdhvkmwsryacgt"},
+		// RNA complements A to U.
+		{"AUGGCU", "AGCCAU"},
+		// anything without a complement is kept, like gaps.
+		{"AC-GT.", ".AC-GT"},
+		{"", ""},
+	} {
+		if reverseComplement := transform.ReverseComplement(test.sequence); reverseComplement != test.reverseComplement {
+			t.Errorf("expected %s to reverse complement to %s, got %s", test.sequence, test.reverseComplement, reverseComplement)
+		}
+		if twice := transform.ReverseComplement(transform.ReverseComplement(test.sequence)); twice != test.sequence {
+			t.Errorf("expected reverse complementing %s twice to give it back, got %s", test.sequence, twice)
+		}
+	}
+
+	if complement := transform.Complement("AUGC"); complement != "UACG" {
+		t.Errorf("expected UACG, got %s", complement)
+	}
+
+	for _, sequence := range []string{"ACGTX", "AC GT", "ACGU-", "ACGTU"} {
+		if _, err := transform.ReverseComplementStrict(sequence); err == nil {
+			t.Errorf("expected an error for %q", sequence)
+		}
+	}
+	if reverseComplement, err := transform.ReverseComplementStrict("ACGTN"); err != nil || reverseComplement != "NACGT" {
+		t.Errorf("expected NACGT, got %s and %v", reverseComplement, err)
+	}
+}
+
 func ExampleComplement() {
 	sequence := "GATTACA"
 	complement := transform.Complement(sequence)
@@ -29,3 +79,440 @@ func ExampleReverse() {
 
 	// Output: ACATTAG
 }
+
+func ExampleRotateToCanonical() {
+	// the same plasmid with its origin in two different places.
+	fmt.Println(transform.RotateToCanonical("GATTACA"))
+	fmt.Println(transform.RotateToCanonical("TTACAGA"))
+	// Output:
+	// ACAGATT
+	// ACAGATT
+}
+
+func TestRotateToCanonical(t *testing.T) {
+	// every rotation of a sequence should give the same smallest rotation as checking them all.
+	random := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 200; trial++ {
+		sequence := make([]byte, 1+random.Intn(30))
+		for index := range sequence {
+			sequence[index] = "ACGT"[random.Intn(2+trial%3)]
+		}
+		expected := string(sequence)
+		for rotation := range sequence {
+			if rotated := string(sequence[rotation:]) + string(sequence[:rotation]); rotated < expected {
+				expected = rotated
+			}
+		}
+		for rotation := range sequence {
+			if canonical := transform.RotateToCanonical(string(sequence[rotation:]) + string(sequence[:rotation])); canonical != expected {
+				t.Fatalf("expected %s to rotate to %s, got %s", sequence, expected, canonical)
+			}
+		}
+	}
+
+	// case is kept but doesn't change the rotation.
+	if canonical := transform.RotateToCanonical("ttacaGA"); canonical != "acaGAtt" {
+		t.Errorf("expected acaGAtt, got %s", canonical)
+	}
+	if canonical := transform.RotateToCanonical(""); canonical != "" {
+		t.Errorf("expected an empty sequence, got %q", canonical)
+	}
+}
+
+func ExampleValidateDNA() {
+	fmt.Println(transform.ValidateDNA("GATTACA"))
+	fmt.Println(transform.ValidateDNA("GATXACA"))
+
+	// Output:
+	// <nil>
+	// 1 invalid DNA character(s), the first 'X' at position 3
+}
+
+func TestValidateDNA(t *testing.T) {
+	tests := []struct {
+		sequence string
+		wantErr  bool
+	}{
+		{"", false},
+		{"ACGTacgt", false},
+		{"NRYSWKMBDHVnryswkmbdhv", false},
+		{"ACGU", true},
+		{"ACG-T.", false},
+		{"ACG T", true},
+	}
+	for _, test := range tests {
+		err := transform.ValidateDNA(test.sequence)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ValidateDNA(%q) returned %v", test.sequence, err)
+		}
+	}
+
+	// ambiguity codes should complement to their own IUPAC complement and back again.
+	ambiguous := "RYKMBVDHNSW"
+	if complement := transform.Complement(ambiguous); complement != "YRMKVBHDNSW" {
+		t.Errorf("Complement(%q) returned %q", ambiguous, complement)
+	}
+	if transform.Complement(transform.Complement(ambiguous)) != ambiguous {
+		t.Errorf("Complement is not its own inverse for %q", ambiguous)
+	}
+}
+
+func ExampleDetectAlphabet() {
+	fmt.Println(transform.DetectAlphabet("GATTACA"))
+	fmt.Println(transform.DetectAlphabet("gauuaca"))
+	fmt.Println(transform.DetectAlphabet("MKRISTTITTTITITTGNGAG*"))
+
+	err := transform.Validate("ATG-CGX TAA", transform.DNA)
+	var alphabetError *transform.AlphabetError
+	if errors.As(err, &alphabetError) {
+		fmt.Println(alphabetError.Positions)
+	}
+	fmt.Println(err)
+
+	// Output:
+	// DNA
+	// RNA
+	// protein
+	// [6 7]
+	// 2 invalid DNA character(s), the first 'X' at position 6
+}
+
+func TestDetectAlphabet(t *testing.T) {
+	tests := []struct {
+		sequence string
+		want     transform.Alphabet
+	}{
+		{"", transform.UnknownAlphabet},
+		{"--", transform.UnknownAlphabet},
+		{"ACGT", transform.DNA},
+		{"ACGTNNNNacgtn-", transform.DNA},
+		{"AAAAAAAAAR", transform.DNA},
+		{"ACGU", transform.RNA},
+		{"acgu.acgu", transform.RNA},
+		{"ACGTU", transform.Protein},
+		// mostly ambiguity codes is more likely a protein.
+		{"MKRSWYDHV", transform.Protein},
+		{"AAAAAAAAE", transform.Protein},
+		{"MKV*", transform.Protein},
+		{"ACGT1", transform.UnknownAlphabet},
+		{"MKV LLA", transform.UnknownAlphabet},
+	}
+	for _, test := range tests {
+		if got := transform.DetectAlphabet(test.sequence); got != test.want {
+			t.Errorf("DetectAlphabet(%q) = %s, expected %s", test.sequence, got, test.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		sequence  string
+		alphabet  transform.Alphabet
+		positions []int
+	}{
+		{"", transform.DNA, nil},
+		{"ACGTRYN-", transform.DNA, nil},
+		{"ACGU", transform.DNA, []int{3}},
+		{"ACGT", transform.RNA, []int{3}},
+		{"acgurn", transform.RNA, nil},
+		{"MKVLE*", transform.Protein, nil},
+		{"MKV1E!", transform.Protein, []int{3, 5}},
+		{"é-E", transform.Protein, []int{0}},
+		{"Eé1", transform.Protein, []int{1, 3}},
+		{"ACGT", transform.UnknownAlphabet, []int{0, 1, 2, 3}},
+	}
+	for _, test := range tests {
+		err := transform.Validate(test.sequence, test.alphabet)
+		if test.positions == nil {
+			if err != nil {
+				t.Errorf("Validate(%q, %s) returned %v", test.sequence, test.alphabet, err)
+			}
+			continue
+		}
+		var alphabetError *transform.AlphabetError
+		if !errors.As(err, &alphabetError) {
+			t.Errorf("Validate(%q, %s) returned %v, expected an *AlphabetError", test.sequence, test.alphabet, err)
+			continue
+		}
+		if !reflect.DeepEqual(alphabetError.Positions, test.positions) {
+			t.Errorf("Validate(%q, %s) reported positions %v, expected %v", test.sequence, test.alphabet, alphabetError.Positions, test.positions)
+		}
+		for index, position := range alphabetError.Positions {
+			if character := []rune(test.sequence[position:])[0]; alphabetError.Characters[index] != character {
+				t.Errorf("Validate(%q, %s) reported %q at position %d, expected %q", test.sequence, test.alphabet, alphabetError.Characters[index], position, character)
+			}
+		}
+	}
+}
+
+func ExampleTranscribe() {
+	sequence := "GATTACA"
+	rna := transform.Transcribe(sequence)
+	fmt.Println(rna)
+
+	// Output: GAUUACA
+}
+
+func ExampleReverseTranscribe() {
+	sequence := "GAUUACA"
+	dna := transform.ReverseTranscribe(sequence)
+	fmt.Println(dna)
+
+	// Output: GATTACA
+}
+
+func TestTranscribe(t *testing.T) {
+	// case should be preserved and anything that isn't a T/U left alone.
+	dna := "ATGcttNNN-tga"
+	rna := transform.Transcribe(dna)
+	if rna != "AUGcuuNNN-uga" {
+		t.Errorf("Transcribe returned %q", rna)
+	}
+	if transform.ReverseTranscribe(rna) != dna {
+		t.Errorf("ReverseTranscribe did not undo Transcribe. Got %q", transform.ReverseTranscribe(rna))
+	}
+}
+
+func ExampleUnmaskRepeats() {
+	sequence := "GATTACAgattacaGATTACA"
+	fmt.Println(transform.UnmaskRepeats(sequence))
+
+	// Output: GATTACAGATTACAGATTACA
+}
+
+func ExampleMaskRegions() {
+	sequence := "GATTACAGATTACAGATTACA"
+	maskedSequence, _ := transform.MaskRegions(sequence, [][]int{{7, 14}})
+	fmt.Println(maskedSequence)
+
+	// Output: GATTACAgattacaGATTACA
+}
+
+func TestMaskRegions(t *testing.T) {
+	sequence := "GATTACAGATTACA"
+
+	// overlapping regions and regions that are already masked should be fine.
+	maskedSequence, err := transform.MaskRegions("GAttACAGATTACA", [][]int{{0, 4}, {2, 6}, {13, 14}})
+	if err != nil {
+		t.Error(err)
+	}
+	if maskedSequence != "gattacAGATTACa" {
+		t.Errorf("MaskRegions returned %q", maskedSequence)
+	}
+
+	if transform.UnmaskRepeats(maskedSequence) != sequence {
+		t.Errorf("UnmaskRepeats did not undo MaskRegions")
+	}
+
+	for _, badRegion := range [][]int{{-1, 2}, {2, 15}, {5, 4}, {1}} {
+		if _, err := transform.MaskRegions(sequence, [][]int{badRegion}); err == nil {
+			t.Errorf("Expected an error for region %v", badRegion)
+		}
+	}
+}
+
+func ExampleNormalize() {
+	options := transform.NormalizeOptions{Uppercase: true, StripWhitespace: true, StripGaps: true, Canonical: true}
+	fmt.Println(transform.Normalize("ttt aaa-ggg\n", options))
+	fmt.Println(transform.Normalize("CCCTTTAAA", options))
+
+	// Output:
+	// CCCTTTAAA
+	// CCCTTTAAA
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		sequence string
+		options  transform.NormalizeOptions
+		expected string
+	}{
+		{"acgT\tAC -GT.", transform.NormalizeOptions{}, "acgT\tAC -GT."},
+		{"acgT\tAC -GT.", transform.NormalizeOptions{Uppercase: true}, "ACGT\tAC -GT."},
+		{"acgT\tAC -GT.", transform.NormalizeOptions{StripWhitespace: true}, "acgTAC-GT."},
+		{"acgT\tAC -GT.", transform.NormalizeOptions{StripGaps: true}, "acgT\tAC GT"},
+		{"ACGUacgu", transform.NormalizeOptions{ToDNA: true}, "ACGTacgt"},
+		{"ACGTacgt", transform.NormalizeOptions{ToRNA: true}, "ACGUacgu"},
+		{"ACGUT", transform.NormalizeOptions{ToDNA: true, ToRNA: true}, "ACGTT"},
+		{"TTTAAAGGG", transform.NormalizeOptions{Canonical: true}, "CCCTTTAAA"},
+		{"AAACCC", transform.NormalizeOptions{Canonical: true}, "AAACCC"},
+		{"UUUAAAGGG", transform.NormalizeOptions{ToRNA: true, Canonical: true}, "CCCUUUAAA"},
+		{"ACGT", transform.NormalizeOptions{Canonical: true}, "ACGT"}, // its own reverse complement.
+		{"", transform.NormalizeOptions{Uppercase: true, Canonical: true}, ""},
+	}
+	for _, test := range tests {
+		if got := transform.Normalize(test.sequence, test.options); got != test.expected {
+			t.Errorf("Normalize(%q, %+v) = %q, want %q", test.sequence, test.options, got, test.expected)
+		}
+	}
+
+	// both strands of a sequence should normalize to the same string.
+	options := transform.NormalizeOptions{Uppercase: true, Canonical: true}
+	sequence := "GATTACAgattacaCCGGTTNRY"
+	if transform.Normalize(sequence, options) != transform.Normalize(transform.ReverseComplement(sequence), options) {
+		t.Errorf("Normalize gave different results for the two strands of %q", sequence)
+	}
+}
+
+func ExampleHammingDistance() {
+	distance, _ := transform.HammingDistance("ACGTACGT", "acgtTCGA")
+
+	fmt.Println(distance)
+	// Output: 2
+}
+
+func TestHammingDistance(t *testing.T) {
+	if _, err := transform.HammingDistance("ACGT", "ACG"); err == nil {
+		t.Errorf("HammingDistance should error on sequences of different lengths")
+	}
+	if distance, _ := transform.HammingDistance("ACGT", "acgt", true); distance != 4 {
+		t.Errorf("expected a case sensitive distance of 4, got %d", distance)
+	}
+	if distance, _ := transform.HammingDistance("", ""); distance != 0 {
+		t.Errorf("expected a distance of 0 between empty sequences, got %d", distance)
+	}
+}
+
+func ExampleLevenshteinDistance() {
+	// one substitution and one insertion.
+	distance := transform.LevenshteinDistance("GATTACA", "GTTTACAG")
+
+	fmt.Println(distance)
+	// Output: 2
+}
+
+func TestLevenshteinDistance(t *testing.T) {
+	for _, test := range []struct {
+		a, b          string
+		caseSensitive bool
+		distance      int
+	}{
+		{"", "", false, 0},
+		{"ACGT", "", false, 4},
+		{"", "ACGT", false, 4},
+		{"ACGT", "acgt", false, 0},
+		{"ACGT", "acgt", true, 4},
+		{"kitten", "sitting", false, 3},
+		{"ACGTACGT", "ACGACGT", false, 1},
+		{"ACGTACGT", "TACGTACG", false, 2},
+	} {
+		if distance := transform.LevenshteinDistance(test.a, test.b, test.caseSensitive); distance != test.distance {
+			t.Errorf("LevenshteinDistance(%q, %q) = %d, want %d", test.a, test.b, distance, test.distance)
+		}
+	}
+}
+
+func ExampleKmerProfile() {
+	profile, _ := transform.KmerProfile("GATTACA", 2)
+
+	fmt.Println(profile["AT"], profile["TT"], profile["TA"])
+	// Output: 1 1 1
+}
+
+func TestKmerProfile(t *testing.T) {
+	profile, err := transform.KmerProfile("aaAAT", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profile) != 2 || profile["AA"] != 3 || profile["AT"] != 1 {
+		t.Errorf("unexpected profile %v", profile)
+	}
+
+	// canonical k-mers are the same on both strands.
+	forward, _ := transform.KmerProfile("ATGCCGTAAGG", 3, true)
+	reverse, _ := transform.KmerProfile(transform.ReverseComplement("ATGCCGTAAGG"), 3, true)
+	if fmt.Sprint(forward) != fmt.Sprint(reverse) {
+		t.Errorf("canonical profiles of a sequence and its reverse complement differ: %v and %v", forward, reverse)
+	}
+	if canonical, _ := transform.KmerProfile("TTT", 3, true); canonical["AAA"] != 1 {
+		t.Errorf("expected TTT to be counted as AAA, got %v", canonical)
+	}
+
+	for _, k := range []int{0, -1, 6} {
+		if _, err := transform.KmerProfile("ACGTA", k); err == nil {
+			t.Errorf("expected an error for k = %d", k)
+		}
+	}
+}
+
+func ExampleKmerDistance() {
+	a, _ := transform.KmerProfile("ATGCATGCATGC", 3)
+	b, _ := transform.KmerProfile("ATGCATGCATGG", 3)
+	c, _ := transform.KmerProfile("TTTTTTTTTTTT", 3)
+
+	fmt.Printf("%.2f %.2f\n", transform.KmerDistance(a, b), transform.KmerDistance(a, c))
+	// Output: 0.04 1.00
+}
+
+func TestKmerDistance(t *testing.T) {
+	profile, _ := transform.KmerProfile("ATGCATGC", 2)
+	if distance := transform.KmerDistance(profile, profile); math.Abs(distance) > 1e-9 {
+		t.Errorf("expected a distance of 0 between identical profiles, got %f", distance)
+	}
+	// the same k-mers in the same proportions are 0 apart no matter how many there are.
+	if distance := transform.KmerDistance(map[string]int{"AA": 2, "AT": 4}, map[string]int{"AA": 1, "AT": 2}); math.Abs(distance) > 1e-9 {
+		t.Errorf("expected a distance of 0 between proportional profiles, got %f", distance)
+	}
+	if distance := transform.KmerDistance(map[string]int{}, map[string]int{}); distance != 0 {
+		t.Errorf("expected a distance of 0 between empty profiles, got %f", distance)
+	}
+	if distance := transform.KmerDistance(profile, nil); distance != 1 {
+		t.Errorf("expected a distance of 1 to an empty profile, got %f", distance)
+	}
+}
+
+func ExampleApplyCIGAR() {
+	// the first two query bases are soft clipped, then there's a 1 base insertion and a 2 base deletion.
+	alignedRef, alignedQuery, _ := transform.ApplyCIGAR("TTGATTACAGATTACA", "CCGATTACTAGTAC", "2S6M1I2M2D3M", 2)
+
+	fmt.Println(alignedRef)
+	fmt.Println(alignedQuery)
+	// Output:
+	// GATTAC-AGATTAC
+	// GATTACTAG--TAC
+}
+
+func TestApplyCIGAR(t *testing.T) {
+	for _, test := range []struct {
+		reference, query, cigar  string
+		refStart                 int
+		alignedRef, alignedQuery string
+	}{
+		{"ACGT", "ACGT", "4M", 0, "ACGT", "ACGT"},
+		{"ACGT", "ACGT", "2=1X1=", 0, "ACGT", "ACGT"},
+		{"ACGTACGT", "GTAC", "4M", 2, "GTAC", "GTAC"},
+		{"ACGTACGT", "ACGT", "2M4N2M", 0, "ACGTACGT", "AC----GT"},
+		{"ACGT", "ACGT", "5H4M", 0, "ACGT", "ACGT"},
+		{"ACGT", "ACGGT", "3M1P1I1M", 0, "ACG--T", "ACG-GT"},
+	} {
+		alignedRef, alignedQuery, err := transform.ApplyCIGAR(test.reference, test.query, test.cigar, test.refStart)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.cigar, err)
+			continue
+		}
+		if alignedRef != test.alignedRef || alignedQuery != test.alignedQuery {
+			t.Errorf("%s: expected %s/%s, got %s/%s", test.cigar, test.alignedRef, test.alignedQuery, alignedRef, alignedQuery)
+		}
+	}
+
+	for _, test := range []struct {
+		reference, query, cigar string
+		refStart                int
+	}{
+		{"ACGT", "ACGT", "", 0},
+		{"ACGT", "ACGT", "*", 0},
+		{"ACGT", "ACGT", "4", 0},
+		{"ACGT", "ACGT", "M", 0},
+		{"ACGT", "ACGT", "0M4M", 0},
+		{"ACGT", "ACGT", "4Q", 0},
+		{"ACGT", "ACGT", "4M", 1},     // runs past the reference.
+		{"ACGT", "ACGT", "2M3D2M", 0}, // deletion runs past the reference.
+		{"ACGT", "ACGT", "3M", 0},     // doesn't cover the whole query.
+		{"ACGT", "ACGT", "5I", 0},     // runs past the query.
+		{"ACGT", "ACGT", "4M", -1},
+	} {
+		if _, _, err := transform.ApplyCIGAR(test.reference, test.query, test.cigar, test.refStart); err == nil {
+			t.Errorf("%s starting at %d: expected an error", test.cigar, test.refStart)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package variants
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TimothyStiles/poly/io/genbank"
+	"github.com/TimothyStiles/poly/io/vcf"
+)
+
+/******************************************************************************
+
+Variant patching begins here.
+
+Apply turns a reference sequence and a list of variants into the alternate
+sequence they describe, along with a CoordinateMap that converts positions on
+the reference to positions on the alternate sequence so that annotations can
+be carried through the edits.
+
+******************************************************************************/
+
+// Variant is a single change to a sequence: an SNV, an insertion, a deletion
+// or a mix of the three, written the same way as VCF variants.
+type Variant struct {
+	Position int    `json:"position"` // 0-based position of the first base of Ref.
+	Ref      string `json:"ref"`
+	Alt      string `json:"alt"`
+}
+
+// ErrInvalidVariant is returned when a variant doesn't match the sequence it's applied to.
+var ErrInvalidVariant = errors.New("invalid variant")
+
+// FromVCF converts VCF variants into Variants using their first alternate
+// allele. Variants without an alternate allele and symbolic alleles, like
+// <DEL>, are skipped.
+func FromVCF(vcfVariants []vcf.Variant) []Variant {
+	var variants []Variant
+	for _, variant := range vcfVariants {
+		if len(variant.Alt) == 0 || strings.ContainsAny(variant.Alt[0], "<>[]*.") {
+			continue
+		}
+		variants = append(variants, Variant{Position: variant.Position, Ref: variant.Ref, Alt: variant.Alt[0]})
+	}
+	return variants
+}
+
+// CoordinateMap maps positions on a reference sequence to positions on the
+// alternate sequence made by applying variants to it.
+type CoordinateMap struct {
+	edits           []edit
+	referenceLength int
+}
+
+// edit is a variant that has been applied, with its position on both sequences.
+type edit struct {
+	start, end       int // the bases of the reference that were replaced.
+	altStart, altEnd int // the bases of the alternate sequence that replaced them.
+}
+
+// Apply applies variants to sequence, returning the alternate sequence and a
+// CoordinateMap from the reference to it. The Ref of every variant must match
+// the sequence, ignoring case, and variants can't overlap.
+func Apply(sequence string, variants []Variant) (string, CoordinateMap, error) {
+	sorted := append([]Variant{}, variants...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Position < sorted[j].Position
+	})
+
+	coordinateMap := CoordinateMap{referenceLength: len(sequence)}
+	var alternate strings.Builder
+	position := 0
+	for _, variant := range sorted {
+		end := variant.Position + len(variant.Ref)
+		switch {
+		case variant.Position < 0 || end > len(sequence):
+			return "", CoordinateMap{}, fmt.Errorf("%w: %s>%s at %d is outside of a sequence of length %d", ErrInvalidVariant, variant.Ref, variant.Alt, variant.Position, len(sequence))
+		case variant.Position < position:
+			return "", CoordinateMap{}, fmt.Errorf("%w: %s>%s at %d overlaps the variant before it", ErrInvalidVariant, variant.Ref, variant.Alt, variant.Position)
+		case !strings.EqualFold(sequence[variant.Position:end], variant.Ref):
+			return "", CoordinateMap{}, fmt.Errorf("%w: %s>%s at %d doesn't match the sequence %s", ErrInvalidVariant, variant.Ref, variant.Alt, variant.Position, sequence[variant.Position:end])
+		}
+		alternate.WriteString(sequence[position:variant.Position])
+		altStart := alternate.Len()
+		alternate.WriteString(variant.Alt)
+		coordinateMap.edits = append(coordinateMap.edits, edit{start: variant.Position, end: end, altStart: altStart, altEnd: alternate.Len()})
+		position = end
+	}
+	alternate.WriteString(sequence[position:])
+	return alternate.String(), coordinateMap, nil
+}
+
+// Lift returns the position on the alternate sequence of a base on the
+// reference, or false if the base was deleted. Bases replaced by a variant
+// are matched to the variant's alternate bases from left to right, so the
+// anchor base of an insertion or deletion keeps its place.
+func (coordinateMap CoordinateMap) Lift(position int) (int, bool) {
+	if position < 0 || position >= coordinateMap.referenceLength {
+		return 0, false
+	}
+	// find the last edit that starts at or before position.
+	index := sort.Search(len(coordinateMap.edits), func(i int) bool {
+		return coordinateMap.edits[i].start > position
+	}) - 1
+	if index < 0 {
+		return position, true
+	}
+	edit := coordinateMap.edits[index]
+	if position >= edit.end {
+		return edit.altEnd + position - edit.end, true
+	}
+	if offset := position - edit.start; offset < edit.altEnd-edit.altStart {
+		return edit.altStart + offset, true
+	}
+	return 0, false
+}
+
+// LiftRange lifts the half-open range [start, end) on the reference to the
+// alternate sequence. Bases inserted inside the range are included in it. It
+// returns false if every base of the range was deleted.
+func (coordinateMap CoordinateMap) LiftRange(start, end int) (int, int, bool) {
+	liftedStart, liftedEnd := -1, -1
+	for position := start; position < end; position++ {
+		if lifted, ok := coordinateMap.Lift(position); ok {
+			liftedStart = lifted
+			break
+		}
+	}
+	for position := end - 1; position >= start; position-- {
+		if lifted, ok := coordinateMap.Lift(position); ok {
+			liftedEnd = lifted + 1
+			break
+		}
+	}
+	if liftedStart < 0 || liftedEnd < 0 {
+		return 0, 0, false
+	}
+	return liftedStart, liftedEnd, true
+}
+
+// ApplyGenbank applies variants to a Genbank sequence and lifts its features
+// onto the result. Features whose bases were all deleted are dropped and the
+// location strings of the rest are rebuilt from their lifted locations.
+func ApplyGenbank(sequence genbank.Genbank, variants []Variant) (genbank.Genbank, CoordinateMap, error) {
+	alternate, coordinateMap, err := Apply(sequence.Sequence, variants)
+	if err != nil {
+		return genbank.Genbank{}, CoordinateMap{}, err
+	}
+
+	patched := sequence
+	patched.Sequence = alternate
+	patched.Meta.Locus.SequenceLength = strconv.Itoa(len(alternate))
+	patched.Features = nil
+	for _, feature := range sequence.Features {
+		location, ok := coordinateMap.liftLocation(feature.Location)
+		if !ok {
+			continue
+		}
+		feature.Location = location
+		feature.Sequence = ""
+		patched.Features = append(patched.Features, feature)
+	}
+	// ParentSequence has to point at the patched copy rather than the original.
+	for index := range patched.Features {
+		patched.Features[index].ParentSequence = &patched
+	}
+	return patched, coordinateMap, nil
+}
+
+// liftLocation lifts a genbank location and its sub-locations, dropping sub-locations that were deleted.
+func (coordinateMap CoordinateMap) liftLocation(location genbank.Location) (genbank.Location, bool) {
+	lifted := location
+	lifted.GbkLocationString = ""
+	if len(location.SubLocations) == 0 && location.Start > location.End {
+		// locations like 2315..217 span the origin of circular sequences.
+		start, originEnd, startOK := coordinateMap.LiftRange(location.Start, coordinateMap.referenceLength)
+		originStart, end, endOK := coordinateMap.LiftRange(0, location.End)
+		switch {
+		case startOK && endOK:
+			lifted.Start, lifted.End = start, end
+		case startOK:
+			lifted.Start, lifted.End = start, originEnd
+		case endOK:
+			lifted.Start, lifted.End = originStart, end
+		}
+		return lifted, startOK || endOK
+	}
+	if len(location.SubLocations) == 0 {
+		start, end, ok := coordinateMap.LiftRange(location.Start, location.End)
+		lifted.Start, lifted.End = start, end
+		return lifted, ok
+	}
+
+	lifted.SubLocations = nil
+	for _, subLocation := range location.SubLocations {
+		if liftedSubLocation, ok := coordinateMap.liftLocation(subLocation); ok {
+			lifted.SubLocations = append(lifted.SubLocations, liftedSubLocation)
+		}
+	}
+	if len(lifted.SubLocations) == 0 {
+		return genbank.Location{}, false
+	}
+	lifted.Start, lifted.End, _ = coordinateMap.LiftRange(location.Start, location.End)
+	return lifted, true
+}
@@ -1,10 +1,14 @@
 /*
-Package variants contains a function for generating all variants of a sequence.
+Package variants contains functions for generating and applying variants of a sequence.
 
 Sometimes sequencers will only give you an *estimate* of what the basepair at
 a given position is. This package provides a function for generating all
 possible deterministic variants of a sequence given a sequence
 with ambiguous bases.
+
+It also provides Apply, which applies SNVs, insertions and deletions (such as
+those read from a VCF file) to a sequence and returns a CoordinateMap for
+lifting annotations from the original sequence onto the new one.
 */
 package variants
 
@@ -1,12 +1,16 @@
 package variants_test
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"sort"
 	"testing"
 
+	"github.com/TimothyStiles/poly/io/genbank"
+	"github.com/TimothyStiles/poly/io/vcf"
 	"github.com/TimothyStiles/poly/transform/variants"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestIUPAC(t *testing.T) {
@@ -37,3 +41,113 @@ func ExampleAllVariantsIUPAC() {
 	// Output: [ATGGAGAATGATGAGCTG ATGGAGAATGATGAGCTA ATGGAGAATGATGAGCTT ATGGAGAATGATGAGCTC ATGGAGAATGATGAACTG ATGGAGAATGATGAACTA ATGGAGAATGATGAACTT ATGGAGAATGATGAACTC ATGGAGAATGACGAGCTG ATGGAGAATGACGAGCTA ATGGAGAATGACGAGCTT ATGGAGAATGACGAGCTC ATGGAGAATGACGAACTG ATGGAGAATGACGAACTA ATGGAGAATGACGAACTT ATGGAGAATGACGAACTC ATGGAGAACGATGAGCTG ATGGAGAACGATGAGCTA ATGGAGAACGATGAGCTT ATGGAGAACGATGAGCTC ATGGAGAACGATGAACTG ATGGAGAACGATGAACTA ATGGAGAACGATGAACTT ATGGAGAACGATGAACTC ATGGAGAACGACGAGCTG ATGGAGAACGACGAGCTA ATGGAGAACGACGAGCTT ATGGAGAACGACGAGCTC ATGGAGAACGACGAACTG ATGGAGAACGACGAACTA ATGGAGAACGACGAACTT ATGGAGAACGACGAACTC ATGGAAAATGATGAGCTG ATGGAAAATGATGAGCTA ATGGAAAATGATGAGCTT ATGGAAAATGATGAGCTC ATGGAAAATGATGAACTG ATGGAAAATGATGAACTA ATGGAAAATGATGAACTT ATGGAAAATGATGAACTC ATGGAAAATGACGAGCTG ATGGAAAATGACGAGCTA ATGGAAAATGACGAGCTT ATGGAAAATGACGAGCTC ATGGAAAATGACGAACTG ATGGAAAATGACGAACTA ATGGAAAATGACGAACTT ATGGAAAATGACGAACTC ATGGAAAACGATGAGCTG ATGGAAAACGATGAGCTA ATGGAAAACGATGAGCTT ATGGAAAACGATGAGCTC ATGGAAAACGATGAACTG ATGGAAAACGATGAACTA ATGGAAAACGATGAACTT ATGGAAAACGATGAACTC ATGGAAAACGACGAGCTG ATGGAAAACGACGAGCTA ATGGAAAACGACGAGCTT ATGGAAAACGACGAGCTC ATGGAAAACGACGAACTG ATGGAAAACGACGAACTA ATGGAAAACGACGAACTT ATGGAAAACGACGAACTC]
 
 }
+
+func ExampleApply() {
+	// an SNV, an insertion after the G at position 2 and a deletion of the final TA.
+	patches := []variants.Variant{{Position: 0, Ref: "A", Alt: "C"}, {Position: 2, Ref: "G", Alt: "GCC"}, {Position: 5, Ref: "GTA", Alt: "G"}}
+	alternate, coordinateMap, _ := variants.Apply("ATGAAGTA", patches)
+	start, end, _ := coordinateMap.LiftRange(3, 6)
+
+	fmt.Println(alternate, start, end)
+	// Output: CTGCCAAG 5 8
+}
+
+func TestLift(t *testing.T) {
+	patches := []variants.Variant{{Position: 1, Ref: "T", Alt: "TCC"}, {Position: 4, Ref: "AGT", Alt: "A"}}
+	alternate, coordinateMap, err := variants.Apply("ATGAAGTA", patches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alternate != "ATCCGAAA" {
+		t.Errorf("expected ATCCGAAA, got %s", alternate)
+	}
+
+	// -1 marks a deleted base.
+	expected := []int{0, 1, 4, 5, 6, -1, -1, 7}
+	for position, expectedLift := range expected {
+		lifted, ok := coordinateMap.Lift(position)
+		if expectedLift < 0 {
+			if ok {
+				t.Errorf("expected position %d to be deleted, got %d", position, lifted)
+			}
+			continue
+		}
+		if !ok || lifted != expectedLift {
+			t.Errorf("expected position %d to lift to %d, got %d (%v)", position, expectedLift, lifted, ok)
+		}
+	}
+
+	if _, _, ok := coordinateMap.LiftRange(5, 7); ok {
+		t.Error("expected a range whose bases were all deleted not to lift")
+	}
+	if start, end, ok := coordinateMap.LiftRange(0, 3); !ok || start != 0 || end != 5 {
+		t.Errorf("expected range [0, 3) to grow around its insertion to [0, 5), got [%d, %d)", start, end)
+	}
+
+	invalid := [][]variants.Variant{
+		{{Position: 0, Ref: "G", Alt: "A"}},
+		{{Position: 7, Ref: "AA", Alt: "A"}},
+		{{Position: 1, Ref: "TG", Alt: "T"}, {Position: 2, Ref: "G", Alt: "C"}},
+	}
+	for _, patches := range invalid {
+		if _, _, err := variants.Apply("ATGAAGTA", patches); !errors.Is(err, variants.ErrInvalidVariant) {
+			t.Errorf("expected an invalid variant error applying %v, got %v", patches, err)
+		}
+	}
+}
+
+func TestApplyGenbank(t *testing.T) {
+	sequence, err := genbank.Read("../../data/puc19.gbk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lacZ genbank.Feature
+	for _, feature := range sequence.Features {
+		if feature.Attributes["label"] == "lacZ-alpha" {
+			lacZ = feature
+		}
+	}
+	before, err := lacZ.GetSequence()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// insert three bases at the start of the plasmid and delete one near the end.
+	patches := []variants.Variant{
+		{Position: 0, Ref: sequence.Sequence[:1], Alt: sequence.Sequence[:1] + "ccc"},
+		{Position: 2600, Ref: sequence.Sequence[2600:2602], Alt: sequence.Sequence[2600:2601]},
+	}
+	patched, _, err := variants.ApplyGenbank(sequence, patches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patched.Sequence) != len(sequence.Sequence)+2 || patched.Meta.Locus.SequenceLength != fmt.Sprint(len(patched.Sequence)) {
+		t.Errorf("unexpected patched sequence length %d (%s)", len(patched.Sequence), patched.Meta.Locus.SequenceLength)
+	}
+	if len(patched.Features) != len(sequence.Features) {
+		t.Errorf("expected %d features, got %d", len(sequence.Features), len(patched.Features))
+	}
+	for _, feature := range patched.Features {
+		if feature.Attributes["label"] == "lacZ-alpha" {
+			after, err := feature.GetSequence()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if after != before {
+				t.Errorf("expected the lifted lacZ to keep its sequence %s, got %s", before, after)
+			}
+		}
+	}
+}
+
+func TestFromVCF(t *testing.T) {
+	vcfVariants := []vcf.Variant{
+		{Position: 3, Ref: "A", Alt: []string{"G", "T"}},
+		{Position: 5, Ref: "C"},
+		{Position: 8, Ref: "A", Alt: []string{"<DEL>"}},
+	}
+	expected := []variants.Variant{{Position: 3, Ref: "A", Alt: "G"}}
+	if diff := cmp.Diff(expected, variants.FromVCF(vcfVariants)); diff != "" {
+		t.Errorf("unexpected variants (-want +got):\n%s", diff)
+	}
+}